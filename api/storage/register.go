@@ -40,6 +40,18 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 
 		&v1alpha1.VulnerabilityReport{},
 		&v1alpha1.VulnerabilityReportList{},
+
+		&v1alpha1.ClusterVulnerabilitySummary{},
+		&v1alpha1.ClusterVulnerabilitySummaryList{},
+
+		&v1alpha1.CVEImpact{},
+		&v1alpha1.CVEImpactList{},
+
+		&v1alpha1.ImagePackage{},
+		&v1alpha1.ImagePackageList{},
+
+		&v1alpha1.RegistryImageCount{},
+		&v1alpha1.RegistryImageCountList{},
 	)
 	return nil
 }
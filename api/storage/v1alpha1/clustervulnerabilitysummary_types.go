@@ -0,0 +1,51 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterVulnerabilitySummaryName is the name of the singleton ClusterVulnerabilitySummary object.
+const ClusterVulnerabilitySummaryName = "cluster"
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterVulnerabilitySummaryList contains a list of ClusterVulnerabilitySummary
+type ClusterVulnerabilitySummaryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+	Items           []ClusterVulnerabilitySummary `json:"items" protobuf:"bytes,2,rep,name=items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:onlyVerbs=get,list
+// +kubebuilder:resource:scope=Cluster,shortName=vulnsummary,categories=sbomscanner
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterVulnerabilitySummary is a read-only, cluster-scoped aggregate of all the VulnerabilityReports.
+// It is computed by the storage and is not persisted.
+type ClusterVulnerabilitySummary struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+
+	// ReportsCount is the number of VulnerabilityReports aggregated in this summary
+	ReportsCount int `json:"reportsCount" protobuf:"varint,2,req,name=reportsCount,casttype=int"`
+
+	// Summary of the vulnerabilities found across all the VulnerabilityReports
+	Summary Summary `json:"summary" protobuf:"bytes,3,req,name=summary"`
+
+	// TopCVEs lists the CVEs affecting the highest number of images
+	TopCVEs []CVEOccurrence `json:"topCVEs,omitempty" protobuf:"bytes,4,rep,name=topCVEs"`
+}
+
+// CVEOccurrence counts how many images are affected by a CVE.
+type CVEOccurrence struct {
+	// CVE identifier
+	CVE string `json:"cve" protobuf:"bytes,1,req,name=cve"`
+
+	// Severity rating (e.g., "HIGH", "MEDIUM")
+	Severity string `json:"severity" protobuf:"bytes,2,req,name=severity"`
+
+	// Images is the number of VulnerabilityReports containing the CVE
+	Images int `json:"images" protobuf:"varint,3,req,name=images,casttype=int"`
+}
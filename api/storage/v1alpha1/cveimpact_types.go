@@ -0,0 +1,55 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CVEImpactList contains a list of CVEImpact
+type CVEImpactList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+	Items           []CVEImpact `json:"items" protobuf:"bytes,2,rep,name=items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:onlyVerbs=get
+// +kubebuilder:resource:scope=Cluster
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CVEImpact is a read-only, cluster-scoped view of the images affected by a CVE.
+// Its name is the CVE identifier. It is computed by the storage and is not persisted.
+type CVEImpact struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+
+	// AffectedImages lists the images whose VulnerabilityReport contains the CVE
+	AffectedImages []AffectedImage `json:"affectedImages" protobuf:"bytes,2,rep,name=affectedImages"`
+}
+
+// AffectedImage is an image affected by a CVE.
+type AffectedImage struct {
+	// Namespace of the VulnerabilityReport
+	Namespace string `json:"namespace" protobuf:"bytes,1,req,name=namespace"`
+
+	// Name of the VulnerabilityReport
+	Name string `json:"name" protobuf:"bytes,2,req,name=name"`
+
+	// ImageMetadata of the affected image
+	ImageMetadata ImageMetadata `json:"imageMetadata" protobuf:"bytes,3,req,name=imageMetadata"`
+
+	// Severity is the highest severity of the CVE in the image
+	Severity string `json:"severity" protobuf:"bytes,4,req,name=severity"`
+
+	// Fixable is true when a fixed version is available for at least one affected package
+	Fixable bool `json:"fixable" protobuf:"varint,5,req,name=fixable"`
+
+	// Suppressed is true when the CVE is suppressed by a VEX statement for every affected package
+	Suppressed bool `json:"suppressed" protobuf:"varint,6,req,name=suppressed"`
+}
+
+func (a *AffectedImage) GetImageMetadata() ImageMetadata {
+	return a.ImageMetadata
+}
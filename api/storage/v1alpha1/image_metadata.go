@@ -6,6 +6,15 @@ const (
 	IndexImageMetadataDigest   = "imageMetadata.digest"
 )
 
+// The annotations recording which worker produced the SBOMs and the VulnerabilityReports, and when.
+// They are set on every write by a worker, to debug the multi-worker deployments.
+const (
+	// AnnotationGeneratedByKey records the identity of the worker, by default the name of its pod.
+	AnnotationGeneratedByKey = "sbomscanner.kubewarden.io/generated-by"
+	// AnnotationGeneratedAtKey records the time of the write, in RFC 3339 format.
+	AnnotationGeneratedAtKey = "sbomscanner.kubewarden.io/generated-at"
+)
+
 // ImageMetadata contains the metadata details of an image.
 type ImageMetadata struct {
 	// Registry specifies the name of the Registry object in the same namespace where the image is stored.
@@ -17,9 +26,18 @@ type ImageMetadata struct {
 	// Tag specifies the tag of the image. Example: "latest".
 	Tag string `json:"tag" protobuf:"bytes,4,req,name=tag"`
 	// Platform specifies the platform of the image. Example "linux/amd64".
+	// The OS version, when set, is appended to the platform. Example "windows/amd64:10.0.17763.1234".
 	Platform string `json:"platform" protobuf:"bytes,5,req,name=platform"`
 	// Digest specifies the sha256 digest of the image.
 	Digest string `json:"digest" protobuf:"bytes,6,req,name=digest"`
+	// OSVersion specifies the version of the operating system of the image, set for the Windows images.
+	// Example: "10.0.17763.1234".
+	// +optional
+	OSVersion string `json:"osVersion,omitempty" protobuf:"bytes,7,opt,name=osVersion"`
+	// OSFeatures specifies the features of the operating system required by the image, sorted.
+	// Example: ["win32k"].
+	// +optional
+	OSFeatures []string `json:"osFeatures,omitempty" protobuf:"bytes,8,rep,name=osFeatures"`
 }
 
 type ImageMetadataAccessor interface {
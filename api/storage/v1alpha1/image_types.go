@@ -1,9 +1,54 @@
 package v1alpha1
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// AnnotationImageRemovedAtKey records when the image was removed from the registry.
+// It is set on the images kept by the retention policy of their registry.
+const AnnotationImageRemovedAtKey = "sbomscanner.kubewarden.io/removed-at"
+
+// AnnotationImageScanPriorityKey sets the priority of the scans of the image, without changing its registry.
+// The accepted values are "high" and "normal": the images with the high priority are scanned first.
+// The normal priority is used when the annotation is missing or invalid.
+const AnnotationImageScanPriorityKey = "sbomscanner.kubewarden.io/scan-priority"
+
+// AnnotationImageTTLKey sets the time to live of the image, e.g. "72h" for the ephemeral CI images.
+// The image is deleted, together with its SBOM and VulnerabilityReport, once the duration has elapsed since its creation.
+const AnnotationImageTTLKey = "sbomscanner.kubewarden.io/ttl"
+
+// FieldImageStale is the field selector matching the images whose last scan is older than
+// the staleness threshold of the storage, or which have never been scanned.
+// The accepted values are "true" and "false".
+const FieldImageStale = "status.stale"
+
+// FieldImageScanPhase is the field selector matching the images by the outcome of their scans,
+// e.g. "status.phase=Failed" to triage the failed scans. The accepted values are the ImageScanPhases.
+const FieldImageScanPhase = "status.phase"
+
+// ImageScanPhase is the outcome of the scans of an image, computed from its status.
+type ImageScanPhase string
+
+const (
+	// ImageScanPhasePending means that the image has never been scanned.
+	ImageScanPhasePending ImageScanPhase = "Pending"
+	// ImageScanPhaseScanned means that the last scan of the image succeeded.
+	ImageScanPhaseScanned ImageScanPhase = "Scanned"
+	// ImageScanPhaseFailed means that the last scan of the image failed, its error is in status.lastError.
+	ImageScanPhaseFailed ImageScanPhase = "Failed"
+)
+
+// ImageScanPhases are the supported phases of the scans of an image.
+var ImageScanPhases = []ImageScanPhase{
+	ImageScanPhasePending,
+	ImageScanPhaseScanned,
+	ImageScanPhaseFailed,
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // ImageList contains a list of Image
@@ -14,7 +59,9 @@ type ImageList struct {
 }
 
 // +genclient
+// +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=img,categories={all,sbomscanner}
 // +kubebuilder:selectablefield:JSONPath=`.imageMetadata.registry`
 // +kubebuilder:selectablefield:JSONPath=`.imageMetadata.registryURI`
 // +kubebuilder:selectablefield:JSONPath=`.imageMetadata.repository`
@@ -31,6 +78,72 @@ type Image struct {
 	ImageMetadata `json:"imageMetadata" protobuf:"bytes,2,req,name=imageMetadata"`
 	// List of the layers that make the image
 	Layers []ImageLayer `json:"layers,omitempty" protobuf:"bytes,3,rep,name=layers"`
+	// Status of the image
+	Status ImageStatus `json:"status,omitempty" protobuf:"bytes,4,opt,name=status"`
+}
+
+// ImageStatus defines the observed state of an Image
+type ImageStatus struct {
+	// lastScannedAt is the time of the last vulnerability scan of the image.
+	// It is not set when the image has never been scanned.
+	// +optional
+	LastScannedAt *metav1.Time `json:"lastScannedAt,omitempty" protobuf:"bytes,1,opt,name=lastScannedAt"`
+	// lastError is the error of the last failed scan of the image.
+	// It is cleared by the next successful scan.
+	// +optional
+	LastError *ImageScanError `json:"lastError,omitempty" protobuf:"bytes,2,opt,name=lastError"`
+}
+
+// ScanPhase returns the outcome of the scans of the image.
+func (s *ImageStatus) ScanPhase() ImageScanPhase {
+	switch {
+	case s.LastError != nil:
+		return ImageScanPhaseFailed
+	case s.LastScannedAt != nil:
+		return ImageScanPhaseScanned
+	default:
+		return ImageScanPhasePending
+	}
+}
+
+// ImageScanErrorReason is the machine-readable reason of a failed scan.
+// +enum
+type ImageScanErrorReason string
+
+const (
+	// ImageScanErrorReasonAuthFailed means that the registry rejected the credentials, or that none were provided.
+	ImageScanErrorReasonAuthFailed ImageScanErrorReason = "AuthFailed"
+	// ImageScanErrorReasonNotFound means that the image, or one of its blobs, is not in the registry anymore.
+	ImageScanErrorReasonNotFound ImageScanErrorReason = "NotFound"
+	// ImageScanErrorReasonRateLimited means that the registry throttled the requests.
+	ImageScanErrorReasonRateLimited ImageScanErrorReason = "RateLimited"
+	// ImageScanErrorReasonTLSError means that the TLS connection to the registry could not be established,
+	// e.g. because its certificate is not trusted.
+	ImageScanErrorReasonTLSError ImageScanErrorReason = "TLSError"
+	// ImageScanErrorReasonTimeout means that the scan, or a request to the registry, timed out.
+	ImageScanErrorReasonTimeout ImageScanErrorReason = "Timeout"
+	// ImageScanErrorReasonGeneratorError means that the SBOM generator failed for another reason.
+	ImageScanErrorReasonGeneratorError ImageScanErrorReason = "GeneratorError"
+)
+
+// ImageScanErrorReasons are the supported reasons of a failed scan.
+var ImageScanErrorReasons = []ImageScanErrorReason{
+	ImageScanErrorReasonAuthFailed,
+	ImageScanErrorReasonNotFound,
+	ImageScanErrorReasonRateLimited,
+	ImageScanErrorReasonTLSError,
+	ImageScanErrorReasonTimeout,
+	ImageScanErrorReasonGeneratorError,
+}
+
+// ImageScanError describes the failure of a scan of an image.
+type ImageScanError struct {
+	// reason is the machine-readable reason of the failure.
+	Reason ImageScanErrorReason `json:"reason" protobuf:"bytes,1,req,name=reason,casttype=ImageScanErrorReason"`
+	// message is the human-readable description of the failure.
+	Message string `json:"message" protobuf:"bytes,2,req,name=message"`
+	// occurredAt is the time of the failure.
+	OccurredAt metav1.Time `json:"occurredAt" protobuf:"bytes,3,req,name=occurredAt"`
 }
 
 // ImageLayer define a layer part of an OCI Image
@@ -44,6 +157,19 @@ type ImageLayer struct {
 	DiffID string `json:"diffID" protobuf:"bytes,3,req,name=diffID"`
 }
 
+// ParseImageTTL parses the value of the TTL annotation, a positive Go duration, e.g. "90m" or "72h".
+func ParseImageTTL(value string) (time.Duration, error) {
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid TTL %q: %w", value, err)
+	}
+	if ttl <= 0 {
+		return 0, errors.New("the TTL must be greater than 0")
+	}
+
+	return ttl, nil
+}
+
 func (i *Image) GetImageMetadata() ImageMetadata {
 	return i.ImageMetadata
 }
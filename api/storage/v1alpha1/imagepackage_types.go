@@ -0,0 +1,58 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// FieldPackageName is the field selector matching the name of the package.
+	FieldPackageName = "package.name"
+	// FieldPackageVersion is the field selector matching the version of the package.
+	FieldPackageVersion = "package.version"
+	// FieldPackagePURL is the field selector matching the package URL of the package.
+	FieldPackagePURL = "package.purl"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImagePackageList contains a list of ImagePackage
+type ImagePackageList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+	Items           []ImagePackage `json:"items" protobuf:"bytes,2,rep,name=items"`
+}
+
+// +genclient
+// +genclient:onlyVerbs=list
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=imgpkg
+// +kubebuilder:selectablefield:JSONPath=`.package.name`
+// +kubebuilder:selectablefield:JSONPath=`.package.version`
+// +kubebuilder:selectablefield:JSONPath=`.package.purl`
+
+// ImagePackage is a read-only search result, matching a package found in the SBOM of an image.
+// Its name and namespace are the ones of the SBOM. It is computed by the storage and is not persisted.
+type ImagePackage struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+	ImageMetadata     ImageMetadata `json:"imageMetadata" protobuf:"bytes,2,req,name=imageMetadata"`
+
+	// Package found in the SBOM
+	Package Package `json:"package" protobuf:"bytes,3,req,name=package"`
+}
+
+// Package is a package listed in an SBOM.
+type Package struct {
+	// Name of the package
+	Name string `json:"name" protobuf:"bytes,1,req,name=name"`
+
+	// Version of the package
+	Version string `json:"version" protobuf:"bytes,2,req,name=version"`
+
+	// PURL is the package URL, without the qualifiers and the subpath
+	PURL string `json:"purl,omitempty" protobuf:"bytes,3,opt,name=purl"`
+}
+
+func (i *ImagePackage) GetImageMetadata() ImageMetadata {
+	return i.ImageMetadata
+}
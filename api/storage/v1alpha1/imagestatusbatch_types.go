@@ -0,0 +1,64 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageStatusBatchList contains a list of ImageStatusBatch
+type ImageStatusBatchList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+	Items           []ImageStatusBatch `json:"items" protobuf:"bytes,2,rep,name=items"`
+}
+
+// +genclient
+// +genclient:onlyVerbs=create
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageStatusBatch updates the status of many Images of its namespace in a single transaction.
+// It is create-only and is not persisted: the result of each item is returned in its status.
+type ImageStatusBatch struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+
+	// Spec lists the status updates to apply
+	Spec ImageStatusBatchSpec `json:"spec" protobuf:"bytes,2,req,name=spec"`
+	// Status reports the result of each status update
+	Status ImageStatusBatchStatus `json:"status,omitempty" protobuf:"bytes,3,opt,name=status"`
+}
+
+// ImageStatusBatchSpec defines the status updates of an ImageStatusBatch
+type ImageStatusBatchSpec struct {
+	// Items are the status updates, at most one per image
+	Items []ImageStatusUpdate `json:"items" protobuf:"bytes,1,rep,name=items"`
+}
+
+// ImageStatusUpdate updates the status of an Image
+type ImageStatusUpdate struct {
+	// Name of the Image, in the namespace of the ImageStatusBatch
+	Name string `json:"name" protobuf:"bytes,1,req,name=name"`
+	// Status is the new status of the Image
+	Status ImageStatus `json:"status" protobuf:"bytes,2,req,name=status"`
+}
+
+// ImageStatusBatchStatus defines the results of an ImageStatusBatch
+type ImageStatusBatchStatus struct {
+	// Results of the status updates, in the order of the items
+	Results []ImageStatusUpdateResult `json:"results,omitempty" protobuf:"bytes,1,rep,name=results"`
+}
+
+// ImageStatusUpdateResult is the result of the status update of an Image
+type ImageStatusUpdateResult struct {
+	// Name of the Image
+	Name string `json:"name" protobuf:"bytes,1,req,name=name"`
+	// Succeeded is true when the status of the Image was updated
+	Succeeded bool `json:"succeeded" protobuf:"varint,2,req,name=succeeded"`
+	// Reason is the machine-readable reason of the failure, e.g. NotFound
+	// +optional
+	Reason metav1.StatusReason `json:"reason,omitempty" protobuf:"bytes,3,opt,name=reason,casttype=k8s.io/apimachinery/pkg/apis/meta/v1.StatusReason"`
+	// Message is the human-readable description of the failure
+	// +optional
+	Message string `json:"message,omitempty" protobuf:"bytes,4,opt,name=message"`
+}
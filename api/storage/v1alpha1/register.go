@@ -2,6 +2,7 @@ package v1alpha1
 
 import (
 	"fmt"
+	"slices"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -43,6 +44,23 @@ func AddKnownTypes(scheme *runtime.Scheme) error {
 		&VulnerabilityReport{},
 		&VulnerabilityReportList{},
 
+		&ClusterVulnerabilitySummary{},
+		&ClusterVulnerabilitySummaryList{},
+
+		&CVEImpact{},
+		&CVEImpactList{},
+
+		&ImagePackage{},
+		&ImagePackageList{},
+
+		&ImageStatusBatch{},
+		&ImageStatusBatchList{},
+
+		&RegistryImageCount{},
+		&RegistryImageCountList{},
+
+		&SBOMExport{},
+
 		&metav1.GetOptions{},
 		&metav1.CreateOptions{},
 		&metav1.UpdateOptions{},
@@ -52,7 +70,7 @@ func AddKnownTypes(scheme *runtime.Scheme) error {
 
 	err := scheme.AddFieldLabelConversionFunc(
 		SchemeGroupVersion.WithKind("Image"),
-		imageMetadataFieldSelectorConversion,
+		imageFieldSelectorConversion,
 	)
 	if err != nil {
 		return fmt.Errorf("unable to add field selector conversion function to Image: %w", err)
@@ -70,9 +88,53 @@ func AddKnownTypes(scheme *runtime.Scheme) error {
 	if err != nil {
 		return fmt.Errorf("unable to add field selector conversion function to VulnerabilityReport: %w", err)
 	}
+
+	err = scheme.AddFieldLabelConversionFunc(SchemeGroupVersion.WithKind("ImagePackage"), packageFieldSelectorConversion)
+	if err != nil {
+		return fmt.Errorf("unable to add field selector conversion function to ImagePackage: %w", err)
+	}
 	return nil
 }
 
+func packageFieldSelectorConversion(label, value string) (string, string, error) {
+	switch label {
+	case "metadata.namespace":
+		return label, value, nil
+	case FieldPackageName:
+		return label, value, nil
+	case FieldPackageVersion:
+		return label, value, nil
+	case FieldPackagePURL:
+		return label, value, nil
+	default:
+		return "", "", fmt.Errorf(
+			"%q is not a known field selector: only %q, %q, %q",
+			label,
+			FieldPackageName,
+			FieldPackageVersion,
+			FieldPackagePURL,
+		)
+	}
+}
+
+func imageFieldSelectorConversion(label, value string) (string, string, error) {
+	switch label {
+	case FieldImageStale:
+		if value != "true" && value != "false" {
+			return "", "", fmt.Errorf("invalid value %q for field selector %q: only %q, %q", value, label, "true", "false")
+		}
+		return label, value, nil
+	case FieldImageScanPhase:
+		if !slices.Contains(ImageScanPhases, ImageScanPhase(value)) {
+			return "", "", fmt.Errorf("invalid value %q for field selector %q: only %q, %q, %q",
+				value, label, ImageScanPhasePending, ImageScanPhaseScanned, ImageScanPhaseFailed)
+		}
+		return label, value, nil
+	default:
+		return imageMetadataFieldSelectorConversion(label, value)
+	}
+}
+
 func imageMetadataFieldSelectorConversion(label, value string) (string, string, error) {
 	switch label {
 	case "metadata.name":
@@ -91,6 +153,8 @@ func imageMetadataFieldSelectorConversion(label, value string) (string, string,
 		return label, value, nil
 	case "imageMetadata.digest":
 		return label, value, nil
+	case "imageMetadata.osVersion":
+		return label, value, nil
 	default:
 		return "", "", fmt.Errorf(
 			"%q is not a known field selector: only %q, %q, %q",
@@ -0,0 +1,31 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RegistryImageCountList contains a list of RegistryImageCount
+type RegistryImageCountList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+	Items           []RegistryImageCount `json:"items" protobuf:"bytes,2,rep,name=items"`
+}
+
+// +genclient
+// +genclient:onlyVerbs=get,list
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RegistryImageCount is a read-only count of the images stored for a registry.
+// Its name and namespace are the ones of the Registry. It is computed by the storage and is not persisted.
+type RegistryImageCount struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+
+	// RegistryURI is the URI of the registry, as recorded in the metadata of its images
+	RegistryURI string `json:"registryURI" protobuf:"bytes,2,req,name=registryURI"`
+
+	// Images is the number of images of the registry
+	Images int `json:"images" protobuf:"varint,3,req,name=images,casttype=int"`
+}
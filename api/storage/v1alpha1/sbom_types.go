@@ -5,6 +5,24 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+const (
+	// AnnotationSBOMSourceKey records where the SBOM comes from, when it is not generated by SBOMscanner.
+	AnnotationSBOMSourceKey = "sbomscanner.kubewarden.io/source"
+	// AnnotationSBOMReferrerDigestKey records the digest of the referrer manifest the SBOM was found in.
+	AnnotationSBOMReferrerDigestKey = "sbomscanner.kubewarden.io/referrer-digest"
+	// AnnotationSBOMScopeKey records the scope of the generated SBOM, e.g. "os" when only the OS packages were analyzed,
+	// or "all:jar,npm" when the language packages were restricted to some ecosystems.
+	// The SBOMs without the annotation include all the packages.
+	AnnotationSBOMScopeKey = "sbomscanner.kubewarden.io/scope"
+	// AnnotationSBOMSchemaVersionKey records the schema version of the SPDX document of the SBOM, e.g. "SPDX-2.3",
+	// which the worker storing the SBOM was pinned to.
+	AnnotationSBOMSchemaVersionKey = "sbomscanner.kubewarden.io/schema-version"
+
+	// SBOMSourceReferrer is the source of the SBOMs attached to the images in the registry,
+	// found with the OCI referrers API.
+	SBOMSourceReferrer = "referrer"
+)
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // SBOMList contains a list of Software Bill of Materials
@@ -16,6 +34,7 @@ type SBOMList struct {
 
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:categories=sbomscanner
 // +kubebuilder:selectablefield:JSONPath=`.imageMetadata.registry`
 // +kubebuilder:selectablefield:JSONPath=`.imageMetadata.registryURI`
 // +kubebuilder:selectablefield:JSONPath=`.imageMetadata.repository`
@@ -30,6 +49,9 @@ type SBOM struct {
 	ImageMetadata     ImageMetadata `json:"imageMetadata" protobuf:"bytes,2,req,name=imageMetadata"`
 	// SPDX contains the SPDX document of the SBOM in JSON format
 	SPDX runtime.RawExtension `json:"spdx" protobuf:"bytes,3,req,name=spdx"`
+	// PackageCount is the number of packages of the SPDX document, set by the storage.
+	// +optional
+	PackageCount int `json:"packageCount,omitempty" protobuf:"varint,4,opt,name=packageCount,casttype=int"`
 }
 
 func (s *SBOM) GetImageMetadata() ImageMetadata {
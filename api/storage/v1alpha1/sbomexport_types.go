@@ -0,0 +1,18 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SBOMExportContentType is the content type of the archives streamed by the get requests of the SBOMExports.
+const SBOMExportContentType = "application/gzip"
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SBOMExport is the archive of all the SBOMs of its namespace, streamed as a gzip-compressed tar by its get requests.
+// It is get-only and is not persisted: its name is the directory of the files of the archive.
+// The archive holds the SPDX document of each SBOM under sboms/, followed by a manifest.json listing them.
+type SBOMExport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+}
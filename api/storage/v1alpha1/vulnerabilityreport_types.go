@@ -4,6 +4,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// AnnotationRawDocumentKey is set by the storage on the VulnerabilityReports whose raw results were not retained,
+// with the RawDocumentNotRetained value. Only their summary and their normalized findings are stored.
+const AnnotationRawDocumentKey = "sbomscanner.kubewarden.io/raw-document"
+
+// RawDocumentNotRetained is the value of AnnotationRawDocumentKey.
+const RawDocumentNotRetained = "NotRetained"
+
 type Class string
 
 // Enumeration of supported package classes
@@ -27,6 +34,7 @@ type VulnerabilityReportList struct {
 
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName={vulnreport,vr},categories=sbomscanner
 // +kubebuilder:selectablefield:JSONPath=`.imageMetadata.registry`
 // +kubebuilder:selectablefield:JSONPath=`.imageMetadata.registryURI`
 // +kubebuilder:selectablefield:JSONPath=`.imageMetadata.repository`
@@ -53,27 +61,65 @@ type Report struct {
 
 	// Results per target (e.g., layer, package type)
 	Results []Result `json:"results" protobuf:"bytes,2,rep,name=results"`
+
+	// VulnerabilityDB identifies the vulnerability database used by the scan
+	// +optional
+	VulnerabilityDB *VulnerabilityDB `json:"vulnerabilityDB,omitempty" protobuf:"bytes,3,opt,name=vulnerabilityDB"`
+
+	// Evaluation describes how the SBOM was evaluated to produce the report
+	// +optional
+	Evaluation *Evaluation `json:"evaluation,omitempty" protobuf:"bytes,4,opt,name=evaluation"`
+}
+
+// Evaluation describes the evaluation of a SBOM against the vulnerability database.
+type Evaluation struct {
+	// Reevaluation is true when a stored SBOM was evaluated again, without pulling the image,
+	// e.g. by a rescan ScanJob
+	// +optional
+	Reevaluation bool `json:"reevaluation,omitempty" protobuf:"varint,1,opt,name=reevaluation"`
+
+	// SBOMUID is the UID of the evaluated SBOM, which has the same name as the report
+	SBOMUID string `json:"sbomUID" protobuf:"bytes,2,req,name=sbomUID"`
+
+	// SBOMCreationTimestamp is the time the evaluated SBOM was generated
+	SBOMCreationTimestamp metav1.Time `json:"sbomCreationTimestamp" protobuf:"bytes,3,req,name=sbomCreationTimestamp"`
+}
+
+// VulnerabilityDB identifies a version of the vulnerability database.
+type VulnerabilityDB struct {
+	// Source is the OCI reference the database was downloaded from,
+	// including the pinned tag or digest if any
+	Source string `json:"source" protobuf:"bytes,1,req,name=source"`
+
+	// SchemaVersion is the version of the schema of the database
+	SchemaVersion int `json:"schemaVersion" protobuf:"varint,2,req,name=schemaVersion,casttype=int"`
+
+	// UpdatedAt is the time the database was built, identifying its version
+	UpdatedAt metav1.Time `json:"updatedAt" protobuf:"bytes,3,req,name=updatedAt"`
+
+	// DownloadedAt is the time the database was downloaded by the worker
+	DownloadedAt metav1.Time `json:"downloadedAt" protobuf:"bytes,4,req,name=downloadedAt"`
 }
 
 // Summary provides a high-level overview of the vulnerabilities found.
 type Summary struct {
 	// Critical vulnerabilities count
-	Critical int `json:"critical" protobuf:"varint,1,req,name=critical"`
+	Critical int `json:"critical" protobuf:"varint,1,req,name=critical,casttype=int"`
 
 	// High vulnerabilities count
-	High int `json:"high" protobuf:"varint,2,req,name=high"`
+	High int `json:"high" protobuf:"varint,2,req,name=high,casttype=int"`
 
 	// Medium vulnerabilities count
-	Medium int `json:"medium" protobuf:"varint,3,req,name=medium"`
+	Medium int `json:"medium" protobuf:"varint,3,req,name=medium,casttype=int"`
 
 	// Low vulnerabilities count
-	Low int `json:"low" protobuf:"varint,4,req,name=low"`
+	Low int `json:"low" protobuf:"varint,4,req,name=low,casttype=int"`
 
 	// Unknown vulnerabilities count
-	Unknown int `json:"unknown" protobuf:"varint,5,req,name=unknown"`
+	Unknown int `json:"unknown" protobuf:"varint,5,req,name=unknown,casttype=int"`
 
 	// Suppressed vulnerabilities count
-	Suppressed int `json:"suppressed" protobuf:"varint,6,req,name=suppressed"`
+	Suppressed int `json:"suppressed" protobuf:"varint,6,req,name=suppressed,casttype=int"`
 }
 
 // Result represents scan findings for a specific target and class of packages
@@ -111,6 +157,11 @@ type VEXStatus struct {
 
 	// Statement optionally explain statement from the VEX document
 	Statement string `json:"statement" protobuf:"bytes,3,req,name=statement"`
+
+	// ExpiresAt is the time when a time-bounded suppression of the CVE allowlist lapses.
+	// The vulnerability is no longer suppressed by the scans run after it.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty" protobuf:"bytes,4,opt,name=expiresAt"`
 }
 
 // Vulnerability contains detailed information about a single vulnerability
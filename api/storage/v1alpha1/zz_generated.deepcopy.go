@@ -9,6 +9,105 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AffectedImage) DeepCopyInto(out *AffectedImage) {
+	*out = *in
+	in.ImageMetadata.DeepCopyInto(&out.ImageMetadata)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AffectedImage.
+func (in *AffectedImage) DeepCopy() *AffectedImage {
+	if in == nil {
+		return nil
+	}
+	out := new(AffectedImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CVEImpact) DeepCopyInto(out *CVEImpact) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.AffectedImages != nil {
+		in, out := &in.AffectedImages, &out.AffectedImages
+		*out = make([]AffectedImage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CVEImpact.
+func (in *CVEImpact) DeepCopy() *CVEImpact {
+	if in == nil {
+		return nil
+	}
+	out := new(CVEImpact)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CVEImpact) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CVEImpactList) DeepCopyInto(out *CVEImpactList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CVEImpact, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CVEImpactList.
+func (in *CVEImpactList) DeepCopy() *CVEImpactList {
+	if in == nil {
+		return nil
+	}
+	out := new(CVEImpactList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CVEImpactList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CVEOccurrence) DeepCopyInto(out *CVEOccurrence) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CVEOccurrence.
+func (in *CVEOccurrence) DeepCopy() *CVEOccurrence {
+	if in == nil {
+		return nil
+	}
+	out := new(CVEOccurrence)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CVSS) DeepCopyInto(out *CVSS) {
 	*out = *in
@@ -25,17 +124,100 @@ func (in *CVSS) DeepCopy() *CVSS {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterVulnerabilitySummary) DeepCopyInto(out *ClusterVulnerabilitySummary) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Summary = in.Summary
+	if in.TopCVEs != nil {
+		in, out := &in.TopCVEs, &out.TopCVEs
+		*out = make([]CVEOccurrence, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterVulnerabilitySummary.
+func (in *ClusterVulnerabilitySummary) DeepCopy() *ClusterVulnerabilitySummary {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterVulnerabilitySummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterVulnerabilitySummary) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterVulnerabilitySummaryList) DeepCopyInto(out *ClusterVulnerabilitySummaryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterVulnerabilitySummary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterVulnerabilitySummaryList.
+func (in *ClusterVulnerabilitySummaryList) DeepCopy() *ClusterVulnerabilitySummaryList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterVulnerabilitySummaryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterVulnerabilitySummaryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Evaluation) DeepCopyInto(out *Evaluation) {
+	*out = *in
+	in.SBOMCreationTimestamp.DeepCopyInto(&out.SBOMCreationTimestamp)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Evaluation.
+func (in *Evaluation) DeepCopy() *Evaluation {
+	if in == nil {
+		return nil
+	}
+	out := new(Evaluation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Image) DeepCopyInto(out *Image) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.ImageMetadata = in.ImageMetadata
+	in.ImageMetadata.DeepCopyInto(&out.ImageMetadata)
 	if in.Layers != nil {
 		in, out := &in.Layers, &out.Layers
 		*out = make([]ImageLayer, len(*in))
 		copy(*out, *in)
 	}
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -109,6 +291,11 @@ func (in *ImageList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageMetadata) DeepCopyInto(out *ImageMetadata) {
 	*out = *in
+	if in.OSFeatures != nil {
+		in, out := &in.OSFeatures, &out.OSFeatures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -122,6 +309,322 @@ func (in *ImageMetadata) DeepCopy() *ImageMetadata {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePackage) DeepCopyInto(out *ImagePackage) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.ImageMetadata.DeepCopyInto(&out.ImageMetadata)
+	out.Package = in.Package
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePackage.
+func (in *ImagePackage) DeepCopy() *ImagePackage {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePackage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImagePackage) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePackageList) DeepCopyInto(out *ImagePackageList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImagePackage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePackageList.
+func (in *ImagePackageList) DeepCopy() *ImagePackageList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePackageList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImagePackageList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanError) DeepCopyInto(out *ImageScanError) {
+	*out = *in
+	in.OccurredAt.DeepCopyInto(&out.OccurredAt)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanError.
+func (in *ImageScanError) DeepCopy() *ImageScanError {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanError)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageStatus) DeepCopyInto(out *ImageStatus) {
+	*out = *in
+	if in.LastScannedAt != nil {
+		in, out := &in.LastScannedAt, &out.LastScannedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.LastError != nil {
+		in, out := &in.LastError, &out.LastError
+		*out = new(ImageScanError)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageStatus.
+func (in *ImageStatus) DeepCopy() *ImageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageStatusBatch) DeepCopyInto(out *ImageStatusBatch) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageStatusBatch.
+func (in *ImageStatusBatch) DeepCopy() *ImageStatusBatch {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageStatusBatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageStatusBatch) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageStatusBatchList) DeepCopyInto(out *ImageStatusBatchList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageStatusBatch, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageStatusBatchList.
+func (in *ImageStatusBatchList) DeepCopy() *ImageStatusBatchList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageStatusBatchList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageStatusBatchList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageStatusBatchSpec) DeepCopyInto(out *ImageStatusBatchSpec) {
+	*out = *in
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageStatusUpdate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageStatusBatchSpec.
+func (in *ImageStatusBatchSpec) DeepCopy() *ImageStatusBatchSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageStatusBatchSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageStatusBatchStatus) DeepCopyInto(out *ImageStatusBatchStatus) {
+	*out = *in
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make([]ImageStatusUpdateResult, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageStatusBatchStatus.
+func (in *ImageStatusBatchStatus) DeepCopy() *ImageStatusBatchStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageStatusBatchStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageStatusUpdate) DeepCopyInto(out *ImageStatusUpdate) {
+	*out = *in
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageStatusUpdate.
+func (in *ImageStatusUpdate) DeepCopy() *ImageStatusUpdate {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageStatusUpdate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageStatusUpdateResult) DeepCopyInto(out *ImageStatusUpdateResult) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageStatusUpdateResult.
+func (in *ImageStatusUpdateResult) DeepCopy() *ImageStatusUpdateResult {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageStatusUpdateResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Package) DeepCopyInto(out *Package) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Package.
+func (in *Package) DeepCopy() *Package {
+	if in == nil {
+		return nil
+	}
+	out := new(Package)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryImageCount) DeepCopyInto(out *RegistryImageCount) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryImageCount.
+func (in *RegistryImageCount) DeepCopy() *RegistryImageCount {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryImageCount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RegistryImageCount) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryImageCountList) DeepCopyInto(out *RegistryImageCountList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RegistryImageCount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryImageCountList.
+func (in *RegistryImageCountList) DeepCopy() *RegistryImageCountList {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryImageCountList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RegistryImageCountList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Report) DeepCopyInto(out *Report) {
 	*out = *in
@@ -133,6 +636,16 @@ func (in *Report) DeepCopyInto(out *Report) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.VulnerabilityDB != nil {
+		in, out := &in.VulnerabilityDB, &out.VulnerabilityDB
+		*out = new(VulnerabilityDB)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Evaluation != nil {
+		in, out := &in.Evaluation, &out.Evaluation
+		*out = new(Evaluation)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -174,7 +687,7 @@ func (in *SBOM) DeepCopyInto(out *SBOM) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.ImageMetadata = in.ImageMetadata
+	in.ImageMetadata.DeepCopyInto(&out.ImageMetadata)
 	in.SPDX.DeepCopyInto(&out.SPDX)
 	return
 }
@@ -197,6 +710,32 @@ func (in *SBOM) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SBOMExport) DeepCopyInto(out *SBOMExport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SBOMExport.
+func (in *SBOMExport) DeepCopy() *SBOMExport {
+	if in == nil {
+		return nil
+	}
+	out := new(SBOMExport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SBOMExport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SBOMList) DeepCopyInto(out *SBOMList) {
 	*out = *in
@@ -249,6 +788,10 @@ func (in *Summary) DeepCopy() *Summary {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VEXStatus) DeepCopyInto(out *VEXStatus) {
 	*out = *in
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -285,7 +828,7 @@ func (in *Vulnerability) DeepCopyInto(out *Vulnerability) {
 	if in.VEXStatus != nil {
 		in, out := &in.VEXStatus, &out.VEXStatus
 		*out = new(VEXStatus)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	return
 }
@@ -300,12 +843,30 @@ func (in *Vulnerability) DeepCopy() *Vulnerability {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VulnerabilityDB) DeepCopyInto(out *VulnerabilityDB) {
+	*out = *in
+	in.UpdatedAt.DeepCopyInto(&out.UpdatedAt)
+	in.DownloadedAt.DeepCopyInto(&out.DownloadedAt)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VulnerabilityDB.
+func (in *VulnerabilityDB) DeepCopy() *VulnerabilityDB {
+	if in == nil {
+		return nil
+	}
+	out := new(VulnerabilityDB)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VulnerabilityReport) DeepCopyInto(out *VulnerabilityReport) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.ImageMetadata = in.ImageMetadata
+	in.ImageMetadata.DeepCopyInto(&out.ImageMetadata)
 	in.Report.DeepCopyInto(&out.Report)
 	return
 }
@@ -2,6 +2,8 @@ package v1alpha1
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -13,6 +15,20 @@ const (
 	CatalogTypeOCIDistribution = "OCIDistribution"
 )
 
+const (
+	// RevocationCheckWarn logs a warning when a certificate of the registry is revoked.
+	RevocationCheckWarn = "Warn"
+	// RevocationCheckEnforce fails the connections to the registry when one of its certificates is revoked.
+	RevocationCheckEnforce = "Enforce"
+)
+
+const (
+	// SBOMScopePackagesAll analyzes the OS packages and the language packages of the images.
+	SBOMScopePackagesAll = "all"
+	// SBOMScopePackagesOS analyzes the OS packages only, skipping the analysis of the application dependencies.
+	SBOMScopePackagesOS = "os"
+)
+
 // RegistrySpec defines the desired state of Registry
 type RegistrySpec struct {
 	// URI is the URI of the container registry
@@ -27,13 +43,106 @@ type RegistrySpec struct {
 	// ScanInterval is the interval at which the registry is scanned.
 	// If not set, automatic scanning is disabled.
 	ScanInterval *metav1.Duration `json:"scanInterval,omitempty"`
+	// ScanWindows restricts the scans of the registry to the given daily time windows, e.g. at night when the registry is idle.
+	// The ScanJobs of the registry are deferred until one of the windows opens, the scans already started are not interrupted.
+	// The windows may overlap: the registry can be scanned while any of them is open.
+	// If not set, the registry can be scanned at any time.
+	ScanWindows []ScanWindow `json:"scanWindows,omitempty"`
 	// CABundle is the CA bundle to use when connecting to the registry.
 	CABundle string `json:"caBundle,omitempty"`
 	// Insecure allows insecure connections to the registry when set to true.
 	Insecure bool `json:"insecure,omitempty"`
+	// RevocationCheck enables the OCSP and CRL revocation checking of the registry TLS certificates.
+	// Warn logs a warning when a certificate is revoked, Enforce fails the scan.
+	// If not set, the revocation of the certificates is not checked.
+	RevocationCheck string `json:"revocationCheck,omitempty"`
 	// Platforms allows to specify the list of platform to scan.
 	// If not set, all the available platforms of a container image will be scanned.
 	Platforms []Platform `json:"platforms,omitempty"`
+	// DefaultPlatform is the platform scanned for the multi-architecture images when Platforms is not set.
+	// The single-architecture images are scanned whatever their platform, and all the platforms
+	// of a multi-architecture image are scanned when it does not provide the default platform.
+	// If not set, the default platform configured in the worker is used.
+	DefaultPlatform *Platform `json:"defaultPlatform,omitempty"`
+	// DiscoverSBOMReferrers enables the discovery of the SBOMs attached to the images, using the OCI referrers API.
+	// When a valid SPDX or CycloneDX SBOM is attached to an image, it is stored instead of generating a new one.
+	// The attached SBOMs are not verified, so the discovery also requires the workers to allow it.
+	DiscoverSBOMReferrers bool `json:"discoverSBOMReferrers,omitempty"`
+	// ScanTimeout is the maximum time allowed to pull and analyze a single image of the registry.
+	// If not set, the scan timeout configured in the worker is used.
+	ScanTimeout *metav1.Duration `json:"scanTimeout,omitempty"`
+	// Retention keeps the scan results of the images removed from the registry,
+	// e.g. when a tag is pushed again with a new digest.
+	// If not set, the images removed from the registry are deleted with their scan results.
+	Retention *RetentionPolicy `json:"retention,omitempty"`
+	// SBOMScope defines what is analyzed when generating the SBOMs of the images.
+	// If not set, all the packages are analyzed.
+	SBOMScope *SBOMScope `json:"sbomScope,omitempty"`
+}
+
+// ScanWindow is a daily time window during which the registry can be scanned.
+type ScanWindow struct {
+	// Start is the time of day the window opens, in the HH:MM 24-hour format, e.g. "00:00".
+	Start string `json:"start"`
+	// End is the time of day the window closes, in the HH:MM 24-hour format, e.g. "06:00".
+	// A window ending before it starts spans midnight, e.g. from "22:00" to "04:00",
+	// and a window ending when it starts is open the whole day.
+	End string `json:"end"`
+	// Days restricts the window to the days of the week it opens, e.g. ["Saturday", "Sunday"].
+	// If not set, the window opens every day.
+	Days []string `json:"days,omitempty"`
+	// TimeZone is the IANA time zone of Start and End, e.g. "Europe/Berlin".
+	// If not set, the times are in UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// RetentionPolicy defines which scan results of the images removed from the registry are kept.
+// An image removed from the registry is pruned when it exceeds any of the limits.
+// The most recent image of every repository, tag and platform is always kept.
+type RetentionPolicy struct {
+	// KeepLast is the number of images removed from the registry kept per repository, tag and platform.
+	// If not set, the number of images is not limited.
+	// +kubebuilder:validation:Minimum=0
+	KeepLast int `json:"keepLast,omitempty"`
+	// MaxAge is how long the images removed from the registry are kept.
+	// If not set, the images are kept regardless of their age.
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// SBOMScope defines what is analyzed when generating the SBOMs.
+// The scope is recorded in the SBOMs, so that their consumers know which packages were analyzed.
+type SBOMScope struct {
+	// Packages is the kind of packages analyzed: "all" or "os" for the OS packages only.
+	// The analysis of the OS packages only is faster, since the application dependencies are not searched.
+	// If not set, all the packages are analyzed.
+	Packages string `json:"packages,omitempty"`
+	// Ecosystems restricts the language packages recorded in the SBOMs to the given ecosystems,
+	// named after the Trivy package types, for example `jar`, `npm`, `node-pkg`, `gobinary` or `python-pkg`.
+	// If not set, the packages of all the ecosystems are recorded.
+	// It cannot be set when Packages is "os".
+	Ecosystems []string `json:"ecosystems,omitempty"`
+}
+
+// OSPackagesOnly returns true when the scope only includes the OS packages.
+func (s *SBOMScope) OSPackagesOnly() bool {
+	return s != nil && s.Packages == SBOMScopePackagesOS
+}
+
+// String returns the canonical representation of the scope recorded in the SBOMs:
+// "all", "os", or "all:" followed by the sorted ecosystems, for example "all:jar,npm".
+// A nil scope includes all the packages.
+func (s *SBOMScope) String() string {
+	if s.OSPackagesOnly() {
+		return SBOMScopePackagesOS
+	}
+	if s == nil || len(s.Ecosystems) == 0 {
+		return SBOMScopePackagesAll
+	}
+
+	ecosystems := slices.Clone(s.Ecosystems)
+	slices.Sort(ecosystems)
+
+	return SBOMScopePackagesAll + ":" + strings.Join(slices.Compact(ecosystems), ",")
 }
 
 // RegistryStatus defines the observed state of Registry
@@ -48,6 +157,23 @@ type RegistryStatus struct {
 	// For further information see: https://github.com/kubernetes/community/blob/master/contributors/devel/sig-architecture/api-conventions.md#typical-status-properties
 
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// DiscoveryCheckpoint records the progress of the discovery of the registry.
+	// It is cleared when the discovery completes.
+	// +optional
+	DiscoveryCheckpoint *DiscoveryCheckpoint `json:"discoveryCheckpoint,omitempty"`
+}
+
+// DiscoveryCheckpoint records the last repository cataloged by a ScanJob,
+// so that the discovery resumes after it when the worker is restarted.
+type DiscoveryCheckpoint struct {
+	// ScanJobUID is the UID of the ScanJob performing the discovery.
+	ScanJobUID string `json:"scanJobUID"`
+	// Repository is the last repository cataloged.
+	// The repositories are cataloged in lexical order.
+	Repository string `json:"repository"`
+	// UpdateTime is when the checkpoint was last updated.
+	UpdateTime metav1.Time `json:"updateTime"`
 }
 
 // Platform describes the platform which the image in the manifest runs on.
@@ -60,20 +186,32 @@ type Platform struct {
 	// Variant is an optional field specifying a variant of the CPU, for
 	// example `v7` to specify ARMv7 when architecture is `arm`.
 	Variant string `json:"variant,omitempty"`
+	// OSVersion is an optional field specifying the version of the operating system,
+	// for example `10.0.17763` to select the Windows Server 2019 images.
+	// It matches the builds of the version, and all the versions when not set.
+	OSVersion string `json:"osVersion,omitempty"`
+	// OSFeatures is an optional field specifying the features required by the images,
+	// for example `win32k` to select the Windows images requiring the win32k.sys driver.
+	// It matches the images declaring all the features, and all the images when not set.
+	OSFeatures []string `json:"osFeatures,omitempty"`
 }
 
 // String returns the expected platform string in the following format:
-// <os>/<arch>[/<variant>]
+// <os>/<arch>[/<variant>][:<os version>]
 func (p *Platform) String() string {
 	platform := fmt.Sprintf("%s/%s", p.OS, p.Architecture)
 	if p.Variant != "" {
 		platform += fmt.Sprintf("/%s", p.Variant)
 	}
+	if p.OSVersion != "" {
+		platform += ":" + p.OSVersion
+	}
 	return platform
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=reg,categories={all,sbomscanner}
 
 // Registry is the Schema for the registries API
 type Registry struct {
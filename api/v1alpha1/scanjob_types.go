@@ -1,6 +1,9 @@
 package v1alpha1
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -31,6 +34,14 @@ type ScanJobSpec struct {
 	// Registry is the registry in the same namespace to scan.
 	// +kubebuilder:validation:Required
 	Registry string `json:"registry"`
+	// Rescan re-evaluates the SBOMs already stored for the images of the registry against a refreshed
+	// vulnerability database, without discovering the images and without pulling them again.
+	// +optional
+	Rescan bool `json:"rescan,omitempty"`
+	// SBOMScope overrides the SBOM scope of the registry for this scan.
+	// It cannot be set when Rescan is true, since the stored SBOMs are not generated again.
+	// +optional
+	SBOMScope *SBOMScope `json:"sbomScope,omitempty"`
 }
 
 const (
@@ -47,12 +58,33 @@ const (
 	ReasonCatalogCreationInProgress = "CatalogCreationInProgress"
 	ReasonSBOMGenerationInProgress  = "SBOMGenerationInProgress"
 	ReasonImageScanInProgress       = "ImageScanInProgress"
+	ReasonRescanInProgress          = "RescanInProgress"
 	ReasonComplete                  = "Complete"
 	ReasonFailed                    = "Failed"
 	ReasonNoImagesToScan            = "NoImagesToScan"
+	ReasonNoSBOMsToRescan           = "NoSBOMsToRescan"
 	ReasonAllImagesScanned          = "AllImagesScanned"
 	ReasonRegistryNotFound          = "RegistryNotFound"
 	ReasonInternalError             = "InternalError"
+	ReasonScanTimeout               = "ScanTimeout"
+	ReasonBlobDigestMismatch        = "BlobDigestMismatch"
+	ReasonRegistryNotAllowed        = "RegistryNotAllowed"
+	ReasonImageLimitExceeded        = "ImageLimitExceeded"
+	ReasonImageNotFound             = "ImageNotFound"
+	ReasonRegistryAuthFailed        = "RegistryAuthFailed"
+	ReasonInvalidManifest           = "InvalidManifest"
+	ReasonOutsideScanWindow         = "OutsideScanWindow"
+)
+
+// Reasons of the Events recorded on the Registries and the Images, for kubectl describe.
+const (
+	EventReasonScanStarted                = "ScanStarted"
+	EventReasonScanCompleted              = "ScanCompleted"
+	EventReasonScanFailed                 = "ScanFailed"
+	EventReasonSBOMGenerated              = "SBOMGenerated"
+	EventReasonImageScanned               = "ImageScanned"
+	EventReasonNewCriticalVulnerabilities = "NewCriticalVulnerabilities"
+	EventReasonScanDeferred               = "ScanDeferred"
 )
 
 const (
@@ -86,6 +118,7 @@ type ScanJobStatus struct {
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=sj,categories={all,sbomscanner}
 // +kubebuilder:selectablefield:JSONPath=`.spec.registry`
 // +kubebuilder:printcolumn:name="Registry",type="string",JSONPath=".spec.registry",description="Target registry"
 // +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.status=='True')].type",description="Current status"
@@ -117,6 +150,36 @@ func (s *ScanJob) GetCreationTimestampFromAnnotation() time.Time {
 	return s.CreationTimestamp.Time
 }
 
+// GetRegistryFromAnnotation returns the snapshot of the Registry stored in the RegistryAnnotation of the ScanJob.
+// An error is returned when the annotation is missing or malformed, or when the Registry is not in the namespace
+// of the ScanJob, so that a ScanJob cannot reference the credentials of another namespace.
+func (s *ScanJob) GetRegistryFromAnnotation() (*Registry, error) {
+	registryData, ok := s.Annotations[AnnotationScanJobRegistryKey]
+	if !ok {
+		return nil, errors.New("the registry annotation is missing")
+	}
+
+	registry := &Registry{}
+	if err := json.Unmarshal([]byte(registryData), registry); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal the registry annotation: %w", err)
+	}
+	if registry.Namespace != s.Namespace {
+		return nil, fmt.Errorf("the registry %s/%s of the annotation is not in the namespace %s of the ScanJob",
+			registry.Namespace, registry.Name, s.Namespace)
+	}
+
+	return registry, nil
+}
+
+// EffectiveSBOMScope returns the SBOM scope of the scan: the one of the ScanJob if set, otherwise the one of the registry.
+func (s *ScanJob) EffectiveSBOMScope(registry *Registry) *SBOMScope {
+	if s.Spec.SBOMScope != nil {
+		return s.Spec.SBOMScope
+	}
+
+	return registry.Spec.SBOMScope
+}
+
 // InitializeConditions initializes status fields and conditions.
 func (s *ScanJob) InitializeConditions() {
 	s.Status.Conditions = []metav1.Condition{}
@@ -183,6 +246,18 @@ func (s *ScanJob) MarkScheduled(reason, message string) {
 	})
 }
 
+// MarkDeferred marks the job as not scheduled yet, e.g. until the scan window of the registry opens.
+// The job stays pending, so that it is scheduled by a later reconciliation.
+func (s *ScanJob) MarkDeferred(reason, message string) {
+	meta.SetStatusCondition(&s.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeScheduled,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: s.Generation,
+	})
+}
+
 // MarkInProgress marks the job as in progress.
 func (s *ScanJob) MarkInProgress(reason, message string) {
 	now := metav1.Now()
@@ -302,6 +377,15 @@ func (s *ScanJob) IsScheduled() bool {
 	return scheduledCond.Status == metav1.ConditionTrue
 }
 
+// IsDeferred returns true if the job is pending until the scan window of the registry opens.
+func (s *ScanJob) IsDeferred() bool {
+	scheduledCond := meta.FindStatusCondition(s.Status.Conditions, ConditionTypeScheduled)
+	if scheduledCond == nil {
+		return false
+	}
+	return scheduledCond.Status == metav1.ConditionFalse && scheduledCond.Reason == ReasonOutsideScanWindow
+}
+
 // IsInProgress returns true if the job is currently in progress.
 func (s *ScanJob) IsInProgress() bool {
 	inProgressCond := meta.FindStatusCondition(s.Status.Conditions, ConditionTypeInProgress)
@@ -18,7 +18,7 @@ type VEXHubStatus struct {
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
-// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:resource:scope=Cluster,categories=sbomscanner
 
 // VEXHub is the Schema for the vexhubs API
 type VEXHub struct {
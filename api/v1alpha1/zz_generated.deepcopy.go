@@ -9,9 +9,30 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiscoveryCheckpoint) DeepCopyInto(out *DiscoveryCheckpoint) {
+	*out = *in
+	in.UpdateTime.DeepCopyInto(&out.UpdateTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiscoveryCheckpoint.
+func (in *DiscoveryCheckpoint) DeepCopy() *DiscoveryCheckpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(DiscoveryCheckpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Platform) DeepCopyInto(out *Platform) {
 	*out = *in
+	if in.OSFeatures != nil {
+		in, out := &in.OSFeatures, &out.OSFeatures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Platform.
@@ -96,10 +117,39 @@ func (in *RegistrySpec) DeepCopyInto(out *RegistrySpec) {
 		*out = new(v1.Duration)
 		**out = **in
 	}
+	if in.ScanWindows != nil {
+		in, out := &in.ScanWindows, &out.ScanWindows
+		*out = make([]ScanWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Platforms != nil {
 		in, out := &in.Platforms, &out.Platforms
 		*out = make([]Platform, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DefaultPlatform != nil {
+		in, out := &in.DefaultPlatform, &out.DefaultPlatform
+		*out = new(Platform)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScanTimeout != nil {
+		in, out := &in.ScanTimeout, &out.ScanTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(RetentionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SBOMScope != nil {
+		in, out := &in.SBOMScope, &out.SBOMScope
+		*out = new(SBOMScope)
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -123,6 +173,11 @@ func (in *RegistryStatus) DeepCopyInto(out *RegistryStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DiscoveryCheckpoint != nil {
+		in, out := &in.DiscoveryCheckpoint, &out.DiscoveryCheckpoint
+		*out = new(DiscoveryCheckpoint)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryStatus.
@@ -135,12 +190,52 @@ func (in *RegistryStatus) DeepCopy() *RegistryStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetentionPolicy) DeepCopyInto(out *RetentionPolicy) {
+	*out = *in
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetentionPolicy.
+func (in *RetentionPolicy) DeepCopy() *RetentionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetentionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SBOMScope) DeepCopyInto(out *SBOMScope) {
+	*out = *in
+	if in.Ecosystems != nil {
+		in, out := &in.Ecosystems, &out.Ecosystems
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SBOMScope.
+func (in *SBOMScope) DeepCopy() *SBOMScope {
+	if in == nil {
+		return nil
+	}
+	out := new(SBOMScope)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScanJob) DeepCopyInto(out *ScanJob) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -197,6 +292,11 @@ func (in *ScanJobList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScanJobSpec) DeepCopyInto(out *ScanJobSpec) {
 	*out = *in
+	if in.SBOMScope != nil {
+		in, out := &in.SBOMScope, &out.SBOMScope
+		*out = new(SBOMScope)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScanJobSpec.
@@ -239,6 +339,26 @@ func (in *ScanJobStatus) DeepCopy() *ScanJobStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScanWindow) DeepCopyInto(out *ScanWindow) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScanWindow.
+func (in *ScanWindow) DeepCopy() *ScanWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(ScanWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VEXHub) DeepCopyInto(out *VEXHub) {
 	*out = *in
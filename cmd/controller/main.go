@@ -17,10 +17,15 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -62,8 +67,16 @@ type Config struct {
 	NatsCertFile         string
 	NatsKeyFile          string
 	NatsCAFile           string
+	NatsStreamReplicas   int
 	Init                 bool
 	LogLevel             string
+	LogRedactPatterns    []string
+	ReachabilityCheck    string
+	DeprecatedPlatforms  []v1alpha1.Platform
+	RegistryQuota        webhookv1alpha1.RegistryQuota
+	WorkQueueDepth       uint64
+	StorageWait          cmdutil.RetryConfig
+	ValidateManifests    string
 }
 
 func parseFlags() Config {
@@ -83,9 +96,59 @@ func parseFlags() Config {
 	flag.StringVar(&cfg.NatsCertFile, "nats-cert-file", "/nats/tls/tls.crt", "The path to the NATS client certificate.")
 	flag.StringVar(&cfg.NatsKeyFile, "nats-key-file", "/nats/tls/tls.key", "The path to the NATS client key.")
 	flag.StringVar(&cfg.NatsCAFile, "nats-ca-file", "/nats/tls/ca.crt", "The path to the NATS CA certificate.")
+	flag.IntVar(&cfg.NatsStreamReplicas, "nats-stream-replicas", 1,
+		"Number of replicas of the JetStream streams. It must not exceed the number of NATS servers.")
 	flag.BoolVar(&cfg.Init, "init", false, "Run initialization tasks and exit.")
 	flag.StringVar(&cfg.LogLevel, "log-level", slog.LevelInfo.String(), "Log level")
-
+	flag.Func("log-redact-pattern", cmdutil.LogRedactPatternUsage, func(value string) error {
+		cfg.LogRedactPatterns = append(cfg.LogRedactPatterns, value)
+		return nil
+	})
+	flag.StringVar(&cfg.ReachabilityCheck, "registry-reachability-check", webhookv1alpha1.ReachabilityCheckWarn,
+		"Ping the registry when a Registry is created or its URI is changed. "+
+			"One of: "+strings.Join(webhookv1alpha1.ReachabilityCheckModes, ", ")+". "+
+			"Unreachable registries are reported with a warning, or rejected when set to enforce.")
+	flag.Func("deprecated-platform",
+		"A platform, in the <os>/<arch>[/<variant>][:<os version>] format, accepted with a warning when used by a Registry, "+
+			"to guide the users to migrate. Without a variant, all the variants of the architecture are deprecated, "+
+			"and without an OS version, all the versions of the OS. Can be repeated.",
+		func(value string) error {
+			platform, err := webhookv1alpha1.ParsePlatform(value)
+			if err != nil {
+				return err
+			}
+			cfg.DeprecatedPlatforms = append(cfg.DeprecatedPlatforms, platform)
+			return nil
+		})
+	flag.IntVar(&cfg.RegistryQuota.MaxPerNamespace, "max-registries-per-namespace", 0,
+		"The maximum number of Registries per namespace, enforced when a Registry is created. 0 is unlimited.")
+	flag.Func("max-registries-namespace-override",
+		"The maximum number of Registries of a namespace, in the <namespace>=<limit> format, "+
+			"overriding -max-registries-per-namespace. 0 is unlimited. Can be repeated.",
+		func(value string) error {
+			namespace, limit, err := webhookv1alpha1.ParseRegistryQuotaOverride(value)
+			if err != nil {
+				return err
+			}
+			if cfg.RegistryQuota.Overrides == nil {
+				cfg.RegistryQuota.Overrides = map[string]int{}
+			}
+			cfg.RegistryQuota.Overrides[namespace] = limit
+			return nil
+		})
+	flag.Uint64Var(&cfg.WorkQueueDepth, "work-queue-depth", 10,
+		"The maximum number of messages queued to the workers. "+
+			"The images to scan are moved to the queue in a round-robin fashion across the registries.")
+
+	flag.UintVar(&cfg.StorageWait.Attempts, "storage-wait-attempts", cmdutil.DefaultRetryConfig.Attempts,
+		"Maximum number of checks of the storage types availability by the init task. 0 waits until the process is stopped.")
+	flag.DurationVar(&cfg.StorageWait.Delay, "storage-wait-delay", cmdutil.DefaultRetryConfig.Delay,
+		"Delay before checking the storage types availability again, doubled after each check.")
+	flag.DurationVar(&cfg.StorageWait.MaxDelay, "storage-wait-max-delay", cmdutil.DefaultRetryConfig.MaxDelay,
+		"Maximum delay between two checks of the storage types availability.")
+	flag.StringVar(&cfg.ValidateManifests, "validate-manifests", "",
+		"Validate the Registry manifests of the given file, or of the standard input when set to -, with the checks of the admission webhooks, and exit. "+
+			"The registries are not contacted.")
 	flag.Parse()
 	return cfg
 }
@@ -94,6 +157,10 @@ func main() {
 	var tlsOpts []func(*tls.Config)
 	cfg := parseFlags()
 
+	if cfg.ValidateManifests != "" {
+		os.Exit(validateManifests(cfg.ValidateManifests, os.Stdout))
+	}
+
 	slogLevel, err := cmdutil.ParseLogLevel(cfg.LogLevel)
 	if err != nil {
 		//nolint:sloglint // Use the global logger since the logger is not yet initialized
@@ -104,8 +171,19 @@ func main() {
 		)
 		os.Exit(1)
 	}
+	redactor, err := cmdutil.NewRedactor(cfg.LogRedactPatterns)
+	if err != nil {
+		//nolint:sloglint // Use the global logger since the logger is not yet initialized
+		slog.Error(
+			"error parsing log redact patterns",
+			"error",
+			err,
+		)
+		os.Exit(1)
+	}
 	opts := slog.HandlerOptions{
-		Level: slogLevel,
+		Level:       slogLevel,
+		ReplaceAttr: redactor.ReplaceAttr,
 	}
 	slogHandler := slog.NewJSONHandler(os.Stdout, &opts)
 	slogger := slog.New(slogHandler)
@@ -113,6 +191,32 @@ func main() {
 	ctrl.SetLogger(logger)
 	setupLog := logger.WithName("setup")
 
+	if !slices.Contains(webhookv1alpha1.ReachabilityCheckModes, cfg.ReachabilityCheck) {
+		setupLog.Error(nil, "invalid registry reachability check", "registryReachabilityCheck", cfg.ReachabilityCheck)
+		os.Exit(1)
+	}
+
+	if cfg.RegistryQuota.MaxPerNamespace < 0 {
+		setupLog.Error(nil, "invalid maximum number of registries per namespace, must not be negative",
+			"maxRegistriesPerNamespace", cfg.RegistryQuota.MaxPerNamespace)
+		os.Exit(1)
+	}
+
+	if cfg.NatsStreamReplicas < 1 {
+		setupLog.Error(nil, "invalid number of stream replicas, must be greater than 0", "natsStreamReplicas", cfg.NatsStreamReplicas)
+		os.Exit(1)
+	}
+
+	if err = cfg.StorageWait.Validate(); err != nil {
+		setupLog.Error(err, "invalid storage wait configuration")
+		os.Exit(1)
+	}
+
+	if cfg.WorkQueueDepth == 0 {
+		setupLog.Error(nil, "invalid work queue depth, must be greater than 0", "workQueueDepth", cfg.WorkQueueDepth)
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -174,7 +278,7 @@ func main() {
 	if cfg.Init {
 		slogger = slogger.With("task", "init")
 
-		if err := cmdutil.WaitForStorageTypes(signalHandler, ctrl.GetConfigOrDie(), slogger); err != nil {
+		if err := cmdutil.WaitForStorageTypes(signalHandler, ctrl.GetConfigOrDie(), cfg.StorageWait, slogger); err != nil {
 			slogger.Error("Storage types are not available.", "error", err)
 			os.Exit(1)
 		}
@@ -184,6 +288,11 @@ func main() {
 			os.Exit(1)
 		}
 
+		if err := ensureJetStreamStreams(signalHandler, cfg.NatsURL, natsOpts, cfg.NatsStreamReplicas, slogger); err != nil {
+			slogger.Error("JetStream streams could not be configured.", "error", err)
+			os.Exit(1)
+		}
+
 		slogger.Info("Initialization tasks completed successfully.")
 		os.Exit(0)
 	}
@@ -194,12 +303,19 @@ func main() {
 		os.Exit(1)
 	}
 
-	publisher, err := messaging.NewNatsPublisher(signalHandler, nc, slogger)
+	publisher, err := messaging.NewNatsPublisher(signalHandler, nc, cfg.NatsStreamReplicas, slogger)
 	if err != nil {
 		setupLog.Error(err, "unable to create NATS publisher")
 		os.Exit(1)
 	}
 
+	for _, stream := range []string{messaging.StreamName, messaging.BacklogStreamName} {
+		if err := cmdutil.WaitForStream(signalHandler, nc, stream, cfg.NatsStreamReplicas, slogger); err != nil {
+			setupLog.Error(err, "JetStream stream is not available", "stream", stream)
+			os.Exit(1)
+		}
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsServerOptions,
@@ -278,14 +394,16 @@ func main() {
 		Client:    mgr.GetClient(),
 		Scheme:    mgr.GetScheme(),
 		Publisher: publisher,
+		Recorder:  mgr.GetEventRecorderFor("sbomscanner-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ScanJob")
 		os.Exit(1)
 	}
 
 	if err = (&controller.VulnerabilityReportReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("sbomscanner-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "VulnerabilityReport")
 		os.Exit(1)
@@ -298,7 +416,31 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = webhookv1alpha1.SetupRegistryWebhookWithManager(mgr); err != nil {
+	if err = (&controller.RetentionRunner{
+		Client: mgr.GetClient(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create runner", "runner", "RetentionRunner")
+		os.Exit(1)
+	}
+
+	if err = (&controller.TTLRunner{
+		Client: mgr.GetClient(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create runner", "runner", "TTLRunner")
+		os.Exit(1)
+	}
+
+	dispatcher, err := messaging.NewFairDispatcher(nc, cfg.WorkQueueDepth, slogger)
+	if err != nil {
+		setupLog.Error(err, "unable to create fair dispatcher")
+		os.Exit(1)
+	}
+	if err = mgr.Add(dispatcher); err != nil {
+		setupLog.Error(err, "unable to create runner", "runner", "FairDispatcher")
+		os.Exit(1)
+	}
+
+	if err = webhookv1alpha1.SetupRegistryWebhookWithManager(mgr, cfg.ReachabilityCheck, cfg.DeprecatedPlatforms, cfg.RegistryQuota); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "Registry")
 		os.Exit(1)
 	}
@@ -308,6 +450,8 @@ func main() {
 		os.Exit(1)
 	}
 
+	webhookv1alpha1.SetupSupportedPlatformsEndpointWithManager(mgr)
+
 	// +kubebuilder:scaffold:builder
 
 	if err = mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -325,3 +469,59 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// validateManifests prints the validation results of the Registry manifests of the file, and returns the exit code:
+// 1 when a manifest is invalid or the file cannot be read, 0 otherwise.
+func validateManifests(path string, out io.Writer) int {
+	reader := os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(out, "cannot open %s: %v\n", path, err)
+			return 1
+		}
+		defer func() { _ = file.Close() }()
+		reader = file
+	}
+
+	results, err := webhookv1alpha1.ValidateManifests(reader)
+	exitCode := 0
+	for _, result := range results {
+		name := result.Name
+		if result.Namespace != "" {
+			name = result.Namespace + "/" + name
+		}
+		switch {
+		case result.Skipped:
+			fmt.Fprintf(out, "%s %s: skipped\n", result.Kind, name)
+		case result.Err != nil:
+			exitCode = 1
+			fmt.Fprintf(out, "%s %s: invalid\n", result.Kind, name)
+			for _, cause := range result.Causes() {
+				fmt.Fprintf(out, "  - %s\n", cause)
+			}
+		default:
+			fmt.Fprintf(out, "%s %s: valid\n", result.Kind, name)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(out, "%v\n", err)
+		return 1
+	}
+
+	return exitCode
+}
+
+// ensureJetStreamStreams creates or updates the streams with the configured replicas,
+// so that they exist before the controller publishes to them.
+func ensureJetStreamStreams(ctx context.Context, natsURL string, natsOpts []nats.Option, replicas int, logger *slog.Logger) error {
+	nc, err := nats.Connect(natsURL, natsOpts...)
+	if err != nil {
+		return fmt.Errorf("connecting to NATS: %w", err)
+	}
+	defer nc.Close()
+
+	return cmdutil.EnsureJetStream(ctx, nc, cmdutil.JetStreamTopology{
+		Streams: messaging.StreamConfigs(replicas),
+	}, cmdutil.DefaultRetryConfig, logger)
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/kubewarden/sbomscanner/internal/storage"
+)
+
+// addDeleteBatchFlags registers the flags configuring the bulk deletes.
+func addDeleteBatchFlags(flags *flag.FlagSet, options *storage.DeleteBatchOptions) {
+	flags.IntVar(&options.Size, "delete-batch-size", storage.DefaultDeleteBatchSize, "Maximum number of rows deleted by a single statement during the bulk deletes, e.g. when pruning the expired vulnerability snapshots or the orphaned rows.")
+	flags.DurationVar(&options.Pause, "delete-batch-pause", storage.DefaultDeleteBatchPause, "Pause between two batches of a bulk delete.")
+	flags.IntVar(&options.Workers, "delete-batch-workers", storage.DefaultDeleteBatchWorkers, "Number of batches of a bulk delete run concurrently, each in its own transaction. The concurrent batches delete disjoint rows.")
+}
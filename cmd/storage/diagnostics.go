@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/kubewarden/sbomscanner/internal/cmdutil"
+	"github.com/kubewarden/sbomscanner/internal/storage"
+)
+
+// storagePreconditions returns the preconditions of the storage startup, checked to diagnose a fatal error.
+// db is nil when the connection pool could not be created.
+// The serving certificate is not checked by the init task, which does not serve requests.
+func storagePreconditions(init bool, certFile, keyFile, pgURIFile, pgTLSCAFile string, db *pgxpool.Pool) []cmdutil.Precondition {
+	var preconditions []cmdutil.Precondition
+	if !init {
+		preconditions = append(preconditions, cmdutil.Precondition{
+			Name: "servingCertificate",
+			Check: func(context.Context) error {
+				if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+					return fmt.Errorf("loading serving certificate: %w", err)
+				}
+				return checkCertificateFile(certFile)
+			},
+		})
+	}
+
+	return append(preconditions, []cmdutil.Precondition{
+		{
+			Name: "databaseURI",
+			Check: func(context.Context) error {
+				connString, err := os.ReadFile(pgURIFile)
+				if err != nil {
+					return fmt.Errorf("reading database URI: %w", err)
+				}
+				if _, err := pgxpool.ParseConfig(string(connString)); err != nil {
+					return fmt.Errorf("parsing database URI: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name:  "databaseCA",
+			Check: func(context.Context) error { return checkCertificateFile(pgTLSCAFile) },
+		},
+		{
+			Name:      "database",
+			DependsOn: []string{"databaseURI", "databaseCA"},
+			Check: func(ctx context.Context) error {
+				if db == nil {
+					return errors.New("database connection pool not created")
+				}
+				if err := db.Ping(ctx); err != nil {
+					return fmt.Errorf("database not reachable: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name:      "migrations",
+			DependsOn: []string{"database"},
+			Check: func(ctx context.Context) error {
+				pending, err := storage.PendingMigrations(ctx, db)
+				if err != nil {
+					return fmt.Errorf("listing pending migrations: %w", err)
+				}
+				if len(pending) > 0 {
+					return fmt.Errorf("%d pending migrations: %s", len(pending), strings.Join(pending, ", "))
+				}
+				return nil
+			},
+		},
+	}...)
+}
+
+// checkCertificateFile verifies that the file is readable and contains certificates valid now.
+func checkCertificateFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading certificate: %w", err)
+	}
+	if err := cmdutil.CheckCertificates(data, time.Now()); err != nil {
+		return fmt.Errorf("checking certificate %s: %w", path, err)
+	}
+
+	return nil
+}
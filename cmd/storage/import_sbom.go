@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/registry/generic/registry"
+	"k8s.io/apiserver/pkg/registry/rest"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+
+	"github.com/kubewarden/sbomscanner/api"
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	"github.com/kubewarden/sbomscanner/internal/apiserver"
+	"github.com/kubewarden/sbomscanner/internal/cmdutil"
+	"github.com/kubewarden/sbomscanner/internal/sbomimport"
+	"github.com/kubewarden/sbomscanner/internal/storage"
+)
+
+const importSBOMUsage = `Usage: storage import-sbom [flags] FILE...
+
+Import SPDX or CycloneDX JSON documents generated elsewhere as SBOM resources,
+so that the images are not scanned again.
+The image metadata is derived from the document name when it references the image by digest
+(e.g. "ghcr.io/repo:tag@sha256:..."), and can be overridden with the flags.
+
+Flags:
+`
+
+// runImportSBOM runs the import-sbom subcommand.
+func runImportSBOM(args []string) error {
+	var (
+		pg                pgOptions
+		logLevel          string
+		logRedactPatterns []string
+		namespace         string
+		sbomName          string
+		metadata          storagev1alpha1.ImageMetadata
+		objectStorage     storage.ObjectStorageOptions
+	)
+
+	flags := flag.NewFlagSet("import-sbom", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), importSBOMUsage)
+		flags.PrintDefaults()
+	}
+	addPGFlags(flags, &pg, "sbomscanner-storage-import-sbom")
+	flags.StringVar(&logLevel, "log-level", slog.LevelInfo.String(), "Log level.")
+	flags.Func("log-redact-pattern", cmdutil.LogRedactPatternUsage, func(value string) error {
+		logRedactPatterns = append(logRedactPatterns, value)
+		return nil
+	})
+	flags.StringVar(&namespace, "namespace", "", "Namespace of the imported SBOMs. Required.")
+	flags.StringVar(&sbomName, "name", "", "Name of the imported SBOM. Defaults to a name derived from the image. Only allowed when importing a single file.")
+	flags.StringVar(&metadata.Registry, "registry", "", "Name of the Registry the image belongs to.")
+	flags.StringVar(&metadata.RegistryURI, "registry-uri", "", "URI of the registry where the image is stored.")
+	flags.StringVar(&metadata.Repository, "repository", "", "Repository of the image.")
+	flags.StringVar(&metadata.Tag, "tag", "", "Tag of the image.")
+	flags.StringVar(&metadata.Platform, "platform", "", "Platform of the image, e.g. linux/amd64.")
+	flags.StringVar(&metadata.Digest, "digest", "", "Digest of the image. Only allowed when importing a single file.")
+	addObjectStorageFlags(flags, &objectStorage)
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("parsing flags: %w", err)
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		flags.Usage()
+		return errors.New("at least one file must be provided")
+	}
+	if namespace == "" {
+		return errors.New("namespace is required")
+	}
+	if (sbomName != "" || metadata.Digest != "") && len(files) > 1 {
+		return errors.New("name and digest can only be set when importing a single file")
+	}
+	if err := objectStorage.Validate(); err != nil {
+		return fmt.Errorf("validating object storage options: %w", err)
+	}
+
+	if err := pg.Validate(); err != nil {
+		return err
+	}
+
+	slogLevel, err := cmdutil.ParseLogLevel(logLevel)
+	if err != nil {
+		return fmt.Errorf("parsing log level: %w", err)
+	}
+	redactor, err := cmdutil.NewRedactor(logRedactPatterns)
+	if err != nil {
+		return fmt.Errorf("parsing log redact patterns: %w", err)
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slogLevel, ReplaceAttr: redactor.ReplaceAttr})).
+		With("component", "storage", "task", "import-sbom")
+	// The fatal errors are logged by the default logger, which must redact them as well.
+	slog.SetDefault(logger)
+
+	ctx := genericapiserver.SetupSignalContext()
+
+	db, err := newDB(ctx, pg, nil)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer db.Close()
+
+	documents, err := newDocumentStorage(ctx, objectStorage, logger)
+	if err != nil {
+		return err
+	}
+
+	sbomStore, err := storage.NewSBOMStore(apiserver.Scheme, &apiserver.RestOptionsGetter{}, db, nil, documents, nil, 0, 0, logger)
+	if err != nil {
+		return fmt.Errorf("creating SBOM store: %w", err)
+	}
+
+	var failed int
+	for _, file := range files {
+		if err := importSBOM(ctx, sbomStore, file, namespace, sbomName, metadata); err != nil {
+			logger.ErrorContext(ctx, "Failed to import SBOM", "file", file, "error", err)
+			failed++
+			continue
+		}
+		logger.InfoContext(ctx, "SBOM imported", "file", file)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to import %d of %d SBOMs", failed, len(files))
+	}
+
+	return nil
+}
+
+// importSBOM validates the SBOM document in the given file and writes it through the SBOM store.
+func importSBOM(
+	ctx context.Context,
+	sbomStore *registry.Store,
+	file, namespace, sbomName string,
+	overrides storagev1alpha1.ImageMetadata,
+) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+
+	document, err := sbomimport.Parse(data)
+	if err != nil {
+		return err //nolint:wrapcheck // The error already describes the problem in the document.
+	}
+
+	metadata, err := sbomimport.ImageMetadataFromName(document.Name)
+	if err != nil {
+		// The metadata can still be provided with the flags.
+		metadata = storagev1alpha1.ImageMetadata{}
+	}
+	mergeImageMetadata(&metadata, overrides)
+	if metadata.RegistryURI == "" || metadata.Repository == "" || metadata.Digest == "" {
+		return fmt.Errorf("cannot derive the image registry URI, repository and digest from the document name %q, please provide them with the flags", document.Name)
+	}
+
+	if sbomName == "" {
+		sbomName = computeSBOMName(metadata)
+	}
+
+	sbom := &storagev1alpha1.SBOM{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sbomName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				api.LabelPartOfKey: api.LabelPartOfValue,
+			},
+		},
+		ImageMetadata: metadata,
+		SPDX:          runtime.RawExtension{Raw: document.SPDX},
+	}
+
+	ctx = genericapirequest.WithNamespace(ctx, namespace)
+	if _, err := sbomStore.Create(ctx, sbom, rest.ValidateAllObjectFunc, &metav1.CreateOptions{}); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("SBOM %s/%s already exists", namespace, sbomName)
+		}
+		return fmt.Errorf("creating SBOM: %w", err)
+	}
+
+	return nil
+}
+
+// mergeImageMetadata overrides the metadata fields with the non-empty fields of overrides.
+func mergeImageMetadata(metadata *storagev1alpha1.ImageMetadata, overrides storagev1alpha1.ImageMetadata) {
+	for _, field := range []struct {
+		target   *string
+		override string
+	}{
+		{&metadata.Registry, overrides.Registry},
+		{&metadata.RegistryURI, overrides.RegistryURI},
+		{&metadata.Repository, overrides.Repository},
+		{&metadata.Tag, overrides.Tag},
+		{&metadata.Platform, overrides.Platform},
+		{&metadata.Digest, overrides.Digest},
+	} {
+		if field.override != "" {
+			*field.target = field.override
+		}
+	}
+}
+
+// computeSBOMName returns a unique name for the SBOM of the image.
+func computeSBOMName(metadata storagev1alpha1.ImageMetadata) string {
+	sha := sha256.New()
+	fmt.Fprintf(sha, "%s/%s@%s", metadata.RegistryURI, metadata.Repository, metadata.Digest)
+	return hex.EncodeToString(sha.Sum(nil))
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/kubewarden/sbomscanner/internal/storage"
+)
+
+// addObjectStorageFlags registers the flags configuring the object storage where the documents are offloaded.
+func addObjectStorageFlags(flags *flag.FlagSet, options *storage.ObjectStorageOptions) {
+	flags.StringVar(&options.Bucket, "object-storage-bucket", "", "Bucket of the S3-compatible object storage where the SBOM documents and the vulnerability report results are stored, the database keeping only a reference and a checksum. The documents are stored in the database when empty.")
+	flags.StringVar(&options.Endpoint, "object-storage-endpoint", "", "URL of the S3-compatible object storage, e.g. https://minio.example.com. Defaults to the AWS S3 endpoint of the region.")
+	flags.StringVar(&options.Region, "object-storage-region", "us-east-1", "Region of the object storage bucket.")
+	flags.StringVar(&options.Prefix, "object-storage-prefix", "", "Prefix of the keys of the documents in the object storage bucket.")
+	flags.BoolVar(&options.UsePathStyle, "object-storage-use-path-style", false, "Address the object storage bucket in the path of the URLs, as required by most of the S3-compatible services.")
+}
+
+// newDocumentStorage returns the storage of the offloaded documents, nil when the documents are stored in the database.
+// The credentials are read from the default AWS credential chain, e.g. the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+// environment variables.
+func newDocumentStorage(ctx context.Context, options storage.ObjectStorageOptions, logger *slog.Logger) (storage.DocumentStorage, error) {
+	if !options.Enabled() {
+		logger.InfoContext(ctx, "Documents stored in the database")
+		return nil, nil
+	}
+
+	documents, err := storage.NewS3DocumentStorage(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("creating object storage client: %w", err)
+	}
+	logger.InfoContext(ctx, "Documents stored in the object storage",
+		"endpoint", options.Endpoint,
+		"bucket", options.Bucket,
+		"region", options.Region,
+		"prefix", options.Prefix,
+	)
+
+	return documents, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// pgTCPOptions tune the TCP connections to PostgreSQL, so that the idle connections silently dropped
+// by a load balancer or a NAT gateway are detected and replaced, instead of failing the next query.
+type pgTCPOptions struct {
+	// KeepAliveIdle is the idle time before the first keepalive probe, negative to disable the keepalives.
+	KeepAliveIdle time.Duration
+	// KeepAliveInterval is the interval between the keepalive probes, 0 for the default of Go.
+	KeepAliveInterval time.Duration
+	// KeepAliveCount is the number of unanswered probes closing the connection, 0 for the default of Go.
+	KeepAliveCount int
+	// UserTimeout is the maximum time the sent data can remain unacknowledged, 0 for the default of the kernel.
+	UserTimeout time.Duration
+}
+
+// addPGTCPFlags registers the flags tuning the TCP connections to PostgreSQL.
+func addPGTCPFlags(flags *flag.FlagSet, options *pgTCPOptions) {
+	flags.DurationVar(&options.KeepAliveIdle, "pg-tcp-keepalive-idle", 5*time.Minute, "Idle time of a PostgreSQL connection before the first TCP keepalive probe is sent. Set it below the idle timeout of the load balancers between the storage and PostgreSQL. A negative value disables the keepalives.")
+	flags.DurationVar(&options.KeepAliveInterval, "pg-tcp-keepalive-interval", 0, "Interval between the TCP keepalive probes of the PostgreSQL connections. 0 uses the default, 15s.")
+	flags.IntVar(&options.KeepAliveCount, "pg-tcp-keepalive-count", 0, "Number of unanswered TCP keepalive probes after which a PostgreSQL connection is closed. 0 uses the default, 9.")
+	flags.DurationVar(&options.UserTimeout, "pg-tcp-user-timeout", 0, "Maximum time the data sent on a PostgreSQL connection can remain unacknowledged before the connection is closed, as the tcp_user_timeout of libpq. 0 uses the default of the operating system. Only supported on Linux.")
+}
+
+// Validate checks the TCP options.
+func (o pgTCPOptions) Validate() error {
+	if o.KeepAliveInterval < 0 {
+		return errors.New("pg-tcp-keepalive-interval must be greater than or equal to zero")
+	}
+	if o.KeepAliveCount < 0 {
+		return errors.New("pg-tcp-keepalive-count must be greater than or equal to zero")
+	}
+	if o.UserTimeout < 0 {
+		return errors.New("pg-tcp-user-timeout must be greater than or equal to zero")
+	}
+	if o.UserTimeout > 0 && !tcpUserTimeoutSupported {
+		return errors.New("pg-tcp-user-timeout is only supported on Linux")
+	}
+
+	return nil
+}
+
+// dialFunc returns the function dialing the PostgreSQL connections with the TCP options.
+// The connect_timeout of the connection URI is still enforced, by the context given to the function.
+func (o pgTCPOptions) dialFunc() pgconn.DialFunc {
+	dialer := &net.Dialer{
+		KeepAliveConfig: net.KeepAliveConfig{
+			Enable:   o.KeepAliveIdle >= 0,
+			Idle:     o.KeepAliveIdle,
+			Interval: o.KeepAliveInterval,
+			Count:    o.KeepAliveCount,
+		},
+	}
+	if o.KeepAliveIdle < 0 {
+		dialer.KeepAlive = -1
+	}
+	if o.UserTimeout > 0 {
+		dialer.Control = func(network, _ string, conn syscall.RawConn) error {
+			// The connections to a Unix socket have no TCP options.
+			if !strings.HasPrefix(network, "tcp") {
+				return nil
+			}
+			if err := setTCPUserTimeout(conn, o.UserTimeout); err != nil {
+				return fmt.Errorf("setting TCP user timeout: %w", err)
+			}
+
+			return nil
+		}
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
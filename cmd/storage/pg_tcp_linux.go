@@ -0,0 +1,24 @@
+//go:build linux
+
+package main
+
+import (
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const tcpUserTimeoutSupported = true
+
+// setTCPUserTimeout sets the TCP_USER_TIMEOUT option of the socket.
+func setTCPUserTimeout(conn syscall.RawConn, timeout time.Duration) error {
+	var sockErr error
+	if err := conn.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_USER_TIMEOUT, int(timeout.Milliseconds()))
+	}); err != nil {
+		return err
+	}
+
+	return sockErr
+}
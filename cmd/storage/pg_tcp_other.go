@@ -0,0 +1,16 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+const tcpUserTimeoutSupported = false
+
+// setTCPUserTimeout fails, the TCP_USER_TIMEOUT option is only supported on Linux.
+func setTCPUserTimeout(_ syscall.RawConn, _ time.Duration) error {
+	return errors.New("TCP_USER_TIMEOUT is only supported on Linux")
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	genericapiserver "k8s.io/apiserver/pkg/server"
+
+	"github.com/kubewarden/sbomscanner/internal/storage"
+)
+
+const schemaDumpUsage = `Usage: storage schema-dump [flags]
+
+Print the applied migrations, the table definitions and the row counts of the database as JSON.
+The database is only read, so the command is safe to run against production.
+
+Flags:
+`
+
+// runSchemaDump runs the schema-dump subcommand.
+func runSchemaDump(args []string) error {
+	var (
+		pg pgOptions
+	)
+
+	flags := flag.NewFlagSet("schema-dump", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), schemaDumpUsage)
+		flags.PrintDefaults()
+	}
+	addPGFlags(flags, &pg, "sbomscanner-storage-schema-dump")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("parsing flags: %w", err)
+	}
+
+	if err := pg.Validate(); err != nil {
+		return err
+	}
+
+	ctx := genericapiserver.SetupSignalContext()
+
+	db, err := newDB(ctx, pg, nil)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer db.Close()
+
+	dump, err := storage.DumpSchema(ctx, db)
+	if err != nil {
+		return fmt.Errorf("dumping schema: %w", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(dump); err != nil {
+		return fmt.Errorf("encoding schema dump: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	genericapiserver "k8s.io/apiserver/pkg/server"
+
+	"github.com/kubewarden/sbomscanner/internal/cmdutil"
+	"github.com/kubewarden/sbomscanner/internal/storage"
+)
+
+const verifyUsage = `Usage: storage verify [flags]
+
+Check the integrity of the database: the SBOMs and the VulnerabilityReports whose owner was deleted,
+the scanned Images without SBOM or VulnerabilityReport, and the offloaded documents which cannot be read,
+do not match their checksum, or are not referenced by any row. The issues are printed as JSON, and the command fails when some are not repaired.
+The database is only read, unless -fix is set: the orphaned rows are then deleted in batches, see -delete-batch-workers.
+
+Flags:
+`
+
+// runVerify runs the verify subcommand.
+func runVerify(args []string) error {
+	var (
+		pg                pgOptions
+		logLevel          string
+		logRedactPatterns []string
+		fix               bool
+		deleteBatch       = storage.NewDeleteBatchOptions()
+		objectStorage     storage.ObjectStorageOptions
+	)
+
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), verifyUsage)
+		flags.PrintDefaults()
+	}
+	addPGFlags(flags, &pg, "sbomscanner-storage-verify")
+	flags.StringVar(&logLevel, "log-level", slog.LevelInfo.String(), "Log level.")
+	flags.Func("log-redact-pattern", cmdutil.LogRedactPatternUsage, func(value string) error {
+		logRedactPatterns = append(logRedactPatterns, value)
+		return nil
+	})
+	flags.BoolVar(&fix, "fix", false, "Delete the orphaned SBOMs and VulnerabilityReports, with their offloaded documents, and the unreferenced documents. The other issues require to scan the images again.")
+	addObjectStorageFlags(flags, &objectStorage)
+	addDeleteBatchFlags(flags, &deleteBatch)
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("parsing flags: %w", err)
+	}
+
+	if err := objectStorage.Validate(); err != nil {
+		return fmt.Errorf("validating object storage options: %w", err)
+	}
+	if err := deleteBatch.Validate(); err != nil {
+		return fmt.Errorf("validating delete batch options: %w", err)
+	}
+	if err := pg.Validate(); err != nil {
+		return err
+	}
+
+	slogLevel, err := cmdutil.ParseLogLevel(logLevel)
+	if err != nil {
+		return fmt.Errorf("parsing log level: %w", err)
+	}
+	redactor, err := cmdutil.NewRedactor(logRedactPatterns)
+	if err != nil {
+		return fmt.Errorf("parsing log redact patterns: %w", err)
+	}
+	// The logs are written to stderr, so that the report printed to stdout can be parsed.
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slogLevel, ReplaceAttr: redactor.ReplaceAttr})).
+		With("component", "storage", "task", "verify")
+	// The fatal errors are logged by the default logger, which must redact them as well.
+	slog.SetDefault(logger)
+
+	ctx := genericapiserver.SetupSignalContext()
+
+	db, err := newDB(ctx, pg, nil)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer db.Close()
+
+	documents, err := newDocumentStorage(ctx, objectStorage, logger)
+	if err != nil {
+		return err
+	}
+
+	report, err := storage.VerifyIntegrity(ctx, db, documents, fix, deleteBatch, logger)
+	if err != nil {
+		return fmt.Errorf("verifying integrity: %w", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("encoding integrity report: %w", err)
+	}
+
+	if unrepaired := report.Unrepaired(); unrepaired > 0 {
+		return fmt.Errorf("%d integrity issues found", unrepaired)
+	}
+	logger.InfoContext(ctx, "Integrity verified", "repaired", len(report.Issues))
+
+	return nil
+}
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,35 +21,125 @@ import (
 
 	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
 	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+	"github.com/kubewarden/sbomscanner/internal/cachedir"
 	"github.com/kubewarden/sbomscanner/internal/cmdutil"
 	"github.com/kubewarden/sbomscanner/internal/handlers"
+	"github.com/kubewarden/sbomscanner/internal/handlers/dockerauth"
+	"github.com/kubewarden/sbomscanner/internal/handlers/notification"
 	"github.com/kubewarden/sbomscanner/internal/handlers/registry"
+	vulnreport "github.com/kubewarden/sbomscanner/internal/handlers/vulnerabilityreport"
 	"github.com/kubewarden/sbomscanner/internal/messaging"
+	"github.com/kubewarden/sbomscanner/internal/vulndb"
+	webhookv1alpha1 "github.com/kubewarden/sbomscanner/internal/webhook/v1alpha1"
+	"github.com/kubewarden/sbomscanner/internal/writebuffer"
 	"github.com/kubewarden/sbomscanner/pkg/generated/clientset/versioned/scheme"
 	"github.com/nats-io/nats.go"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
 	k8sscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 )
 
+// workerConsumer is the durable name of the consumer shared by the workers.
+const workerConsumer = "worker"
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "smoke-test" {
+		if err := runSmokeTest(os.Args[2:]); err != nil {
+			//nolint:sloglint // Use the global logger since the logger of the subcommand might not be initialized
+			slog.Error("fatal error", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var natsURL string
 	var natsCertFile string
 	var natsKeyFile string
 	var natsCAFile string
+	var natsStreamReplicas int
 	var runDir string
+	var cacheDir string
+	var cacheMaxSize string
 	var trivyDBRepository string
+	var trivyDBVersion string
+	var trivyDBCacheDir string
+	var trivyDBRefreshInterval time.Duration
 	var trivyJavaDBRepository string
+	var scanTimeout time.Duration
+	var minScanInterval time.Duration
+	var allowSBOMReferrers bool
+	var ackWait time.Duration
+	var layerDownloadConcurrency int
+	var imageLimits handlers.ImageLimits
+	var maxImageSize string
+	var sbomSchemaVersion string
+	var defaultPlatformValue string
+	var cveAllowlistFile string
+	var allowedRegistryHosts []string
+	var disabledCredentialProviders []string
+	var notificationURLFile string
+	var notificationSigningSecretFile string
+	var notificationOptions notification.Options
+	var writeBufferOptions writebuffer.Options
+	var storageWait cmdutil.RetryConfig
+	var workerID string
 	var init bool
 	var logLevel string
+	var logRedactPatterns []string
 
 	flag.StringVar(&natsURL, "nats-url", "localhost:4222", "The URL of the NATS server.")
 	flag.StringVar(&natsCertFile, "nats-cert-file", "/nats/tls/tls.crt", "The path to the NATS client certificate.")
 	flag.StringVar(&natsKeyFile, "nats-key-file", "/nats/tls/tls.key", "The path to the NATS client key.")
 	flag.StringVar(&natsCAFile, "nats-ca-file", "/nats/tls/ca.crt", "The path to the NATS CA certificate.")
+	flag.IntVar(&natsStreamReplicas, "nats-stream-replicas", 1, "Number of replicas of the JetStream streams. It must not exceed the number of NATS servers.")
 	flag.StringVar(&runDir, "run-dir", "/var/run/worker", "Directory to store temporary files.")
-	flag.StringVar(&trivyDBRepository, "trivy-db-repository", "public.ecr.aws/aquasecurity/trivy-db", "OCI repository to retrieve trivy-db.")
+	flag.StringVar(&cacheDir, "cache-dir", "/var/cache/worker", "Directory where the image layers are downloaded.")
+	flag.StringVar(&cacheMaxSize, "cache-max-size", "10Gi", "Maximum size of the cache directory, e.g. 10Gi. The least recently used files are evicted when it is exceeded. 0 disables the eviction.")
+	flag.StringVar(&trivyDBRepository, "trivy-db-repository", "public.ecr.aws/aquasecurity/trivy-db", "OCI repository to retrieve trivy-db, e.g. a mirror reachable by air-gapped workers.")
+	flag.StringVar(&trivyDBVersion, "trivy-db-version", "", "Tag or digest of trivy-db to use, e.g. sha256:<digest>, for reproducible scans. The latest version is used when empty.")
+	flag.StringVar(&trivyDBCacheDir, "trivy-db-cache-dir", "", "Directory where trivy-db is downloaded. It can be a volume holding a pre-populated database. Defaults to the run directory.")
+	flag.DurationVar(&trivyDBRefreshInterval, "trivy-db-refresh-interval", 0, "Interval between the downloads of trivy-db by the worker. The scans do not update the database when set. 0 means the database is downloaded before a scan when it is outdated.")
 	flag.StringVar(&trivyJavaDBRepository, "trivy-java-db-repository", "public.ecr.aws/aquasecurity/trivy-java-db", "OCI repository to retrieve trivy-java-db.")
+	flag.DurationVar(&scanTimeout, "scan-timeout", 0, "Maximum time allowed to pull and analyze a single image. Can be overridden per Registry. 0 means no timeout.")
+	flag.DurationVar(&minScanInterval, "min-scan-interval", 0, "Minimum time between two scans of the same image digest, e.g. 10m, measured from the last scan time of the image. The scans triggered again within it are skipped, and count the VulnerabilityReport of the previous scan. 0 means every trigger is scanned.")
+	flag.DurationVar(&ackWait, "ack-wait", messaging.DefaultAckWait, "Time JetStream waits for the acknowledgement of a message before delivering it again, e.g. to another worker when the worker processing it crashed. The worker extends it while the message is processed, so that the long scans are not delivered again. Must be at least "+messaging.MinAckWait.String()+".")
+	flag.IntVar(&layerDownloadConcurrency, "layer-download-concurrency", handlers.DefaultLayerDownloadConcurrency, "Maximum number of layers of a single image downloaded concurrently. Lower it to limit the bandwidth used by a scan.")
+	flag.IntVar(&imageLimits.MaxLayers, "max-image-layers", 0, "Maximum number of layers of a scanned image, checked from its manifest before downloading the layers. 0 means no limit.")
+	flag.StringVar(&maxImageSize, "max-image-size", "0", "Maximum total uncompressed size of the layers of a scanned image, e.g. 5Gi, checked while downloading the layers. The images whose manifest declares a larger compressed size are rejected before downloading them. 0 means no limit.")
+	flag.BoolVar(&allowSBOMReferrers, "allow-sbom-referrers", false, "Allow the Registries enabling discoverSBOMReferrers to store the SBOMs attached to their images instead of generating them. The attached SBOMs are not verified: anyone able to push to the repositories can attach a SBOM hiding vulnerabilities.")
+	flag.StringVar(&sbomSchemaVersion, "sbom-schema-version", handlers.DefaultSBOMSchemaVersion, "Schema version of the stored SBOMs, one of "+strings.Join(handlers.SupportedSBOMSchemaVersions, ", ")+". The generated SBOMs and the SBOMs attached to the images are converted to it, and it is recorded in their "+storagev1alpha1.AnnotationSBOMSchemaVersionKey+" annotation.")
+	flag.StringVar(&cveAllowlistFile, "cve-allowlist-file", "", "Path to the YAML file listing the CVEs suppressed from all the VulnerabilityReports, with the reason of their suppression. The file is read by every scan.")
+	flag.StringVar(&defaultPlatformValue, "default-platform", "", "Platform cataloged for the multi-architecture images when the Registry does not specify any platform, e.g. linux/amd64. Can be overridden per Registry. All the platforms are cataloged when empty.")
+	flag.Func("allowed-registry-host", "Registry host the worker may contact, e.g. registry.example.com, registry.example.com:5000 or *.example.com. Can be repeated. The scans of the other registries are failed before any connection. All the hosts are allowed when not set.", func(value string) error {
+		allowedRegistryHosts = append(allowedRegistryHosts, value)
+		return nil
+	})
+	flag.Func("disable-credential-provider", "Credential provider not used to authenticate to the registries without an authSecret, one of "+strings.Join(dockerauth.CredentialProviderNames, ", ")+". Can be repeated. The registries matching a disabled provider are accessed anonymously.", func(value string) error {
+		disabledCredentialProviders = append(disabledCredentialProviders, value)
+		return nil
+	})
+	flag.StringVar(&notificationURLFile, "notification-url-file", "", "Path to the file containing the URL of the webhook notified of the new vulnerabilities found by the scans, e.g. a Slack incoming webhook. The notifications are disabled when empty.")
+	flag.StringVar(&notificationSigningSecretFile, "notification-signing-secret-file", "", "Path to the file containing the key of the HMAC-SHA256 signature of the notifications. The notifications are not signed when empty.")
+	flag.StringVar(&notificationOptions.MinSeverity, "notification-min-severity", notification.DefaultMinSeverity, "Minimum severity of the notified vulnerabilities, one of UNKNOWN, LOW, MEDIUM, HIGH, CRITICAL.")
+	flag.UintVar(&notificationOptions.Attempts, "notification-attempts", notification.DefaultAttempts, "Maximum number of attempts to send a notification, with an exponential backoff between them.")
+	flag.StringVar(&writeBufferOptions.Dir, "write-buffer-dir", "", "Directory where the SBOMs and the VulnerabilityReports produced by the scans are buffered before being written to the storage, so that the bursts of scans are not slowed down by the storage. The buffered writes survive the restarts of the worker. They are written right away when empty.")
+	flag.IntVar(&writeBufferOptions.MaxEntries, "write-buffer-max-entries", writebuffer.DefaultMaxEntries, "Maximum number of buffered writes. The scans wait for the buffered writes when it is reached.")
+	flag.IntVar(&writeBufferOptions.BatchSize, "write-buffer-batch-size", writebuffer.DefaultBatchSize, "Maximum number of buffered writes sent to the storage concurrently.")
+	flag.Float64Var(&writeBufferOptions.Rate, "write-buffer-rate", writebuffer.DefaultRate, "Maximum number of buffered writes sent to the storage per second. 0 means no limit.")
+	flag.IntVar(&writeBufferOptions.MaxAttempts, "write-buffer-max-attempts", writebuffer.DefaultMaxAttempts, "Maximum number of attempts of a buffered write, which is dropped once they all failed. 0 means no limit.")
+	flag.UintVar(&storageWait.Attempts, "storage-wait-attempts", cmdutil.DefaultRetryConfig.Attempts, "Maximum number of checks of the storage types availability by the init task. 0 waits until the process is stopped.")
+	flag.DurationVar(&storageWait.Delay, "storage-wait-delay", cmdutil.DefaultRetryConfig.Delay, "Delay before checking the storage types availability again, doubled after each check.")
+	flag.DurationVar(&storageWait.MaxDelay, "storage-wait-max-delay", cmdutil.DefaultRetryConfig.MaxDelay, "Maximum delay between two checks of the storage types availability.")
+	flag.StringVar(&workerID, "worker-id", defaultWorkerID(), "Identity of the worker recorded in the SBOMs and the VulnerabilityReports it produces, e.g. the name of its pod. Defaults to the hostname.")
 	flag.BoolVar(&init, "init", false, "Run initialization tasks and exit.")
 	flag.StringVar(&logLevel, "log-level", slog.LevelInfo.String(), "Log level.")
+	flag.Func("log-redact-pattern", cmdutil.LogRedactPatternUsage, func(value string) error {
+		logRedactPatterns = append(logRedactPatterns, value)
+		return nil
+	})
 	flag.Parse()
 
 	slogLevel, err := cmdutil.ParseLogLevel(logLevel)
@@ -57,11 +152,108 @@ func main() {
 		)
 		os.Exit(1)
 	}
+	redactor, err := cmdutil.NewRedactor(logRedactPatterns)
+	if err != nil {
+		//nolint:sloglint // Use the global logger since the logger is not yet initialized
+		slog.Error(
+			"error initializing the logger",
+			"error",
+			err,
+		)
+		os.Exit(1)
+	}
 	opts := slog.HandlerOptions{
-		Level: slogLevel,
+		Level:       slogLevel,
+		ReplaceAttr: redactor.ReplaceAttr,
 	}
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &opts)).With("component", "worker")
-	logger.Info("Starting worker")
+	logger.Info("Starting worker", "workerID", workerID)
+
+	if err = storageWait.Validate(); err != nil {
+		logger.Error("Invalid storage wait configuration", "error", err)
+		os.Exit(1)
+	}
+
+	if ackWait < messaging.MinAckWait {
+		logger.Error("Invalid ack wait, must be at least "+messaging.MinAckWait.String(), "ackWait", ackWait)
+		os.Exit(1)
+	}
+
+	if layerDownloadConcurrency < 1 {
+		logger.Error("Invalid layer download concurrency, must be at least 1", "layerDownloadConcurrency", layerDownloadConcurrency)
+		os.Exit(1)
+	}
+	logger.Info("Layer downloads configured", "layerDownloadConcurrency", layerDownloadConcurrency)
+
+	var defaultPlatform *v1alpha1.Platform
+	if defaultPlatformValue != "" {
+		var platform v1alpha1.Platform
+		platform, err = webhookv1alpha1.ParsePlatform(defaultPlatformValue)
+		if err != nil {
+			logger.Error("Invalid default platform", "error", err)
+			os.Exit(1)
+		}
+		defaultPlatform = &platform
+		logger.Info("Default platform configured", "defaultPlatform", defaultPlatform.String())
+	}
+
+	if imageLimits.MaxLayers < 0 {
+		logger.Error("Invalid max image layers, it must not be negative", "maxImageLayers", imageLimits.MaxLayers)
+		os.Exit(1)
+	}
+	imageMaxSize, err := resource.ParseQuantity(maxImageSize)
+	if err != nil || imageMaxSize.Sign() < 0 {
+		logger.Error("Invalid max image size, it must be a non-negative quantity", "error", err, "maxImageSize", maxImageSize)
+		os.Exit(1)
+	}
+	imageLimits.MaxSize = imageMaxSize.Value()
+	if imageLimits.Enabled() {
+		logger.Info("Image limits enabled", "maxImageLayers", imageLimits.MaxLayers, "maxImageSize", imageMaxSize.String())
+	}
+
+	if err = handlers.ValidateSBOMSchemaVersion(sbomSchemaVersion); err != nil {
+		logger.Error("Invalid SBOM schema version", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("SBOM schema version configured", "sbomSchemaVersion", sbomSchemaVersion)
+
+	registryAllowlist, err := registry.NewHostAllowlist(allowedRegistryHosts)
+	if err != nil {
+		logger.Error("Invalid allowed registry hosts", "error", err)
+		os.Exit(1)
+	}
+	if registryAllowlist != nil {
+		logger.Info("Registry hosts restricted", "allowedRegistryHosts", registryAllowlist.String())
+	}
+
+	if cveAllowlistFile != "" {
+		// The allowlist is read again by every scan, it is loaded here to report the errors at startup.
+		var allowlist vulnreport.Allowlist
+		allowlist, err = vulnreport.LoadAllowlist(cveAllowlistFile)
+		if err != nil {
+			logger.Error("Invalid CVE allowlist", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("CVE allowlist configured", "cveAllowlistFile", cveAllowlistFile, "cves", len(allowlist))
+	}
+
+	var notifier *notification.Notifier
+	if notificationURLFile != "" {
+		notificationOptions, err = loadNotificationOptions(notificationOptions, notificationURLFile, notificationSigningSecretFile)
+		if err != nil {
+			logger.Error("Invalid notification configuration", "error", err)
+			os.Exit(1)
+		}
+		notifier = notification.NewNotifier(notificationOptions, logger)
+		logger.Info("Vulnerability notifications configured",
+			"minSeverity", notificationOptions.MinSeverity,
+			"signed", len(notificationOptions.SigningSecret) > 0)
+	}
+
+	if err = writeBufferOptions.Validate(); err != nil {
+		logger.Error("Invalid write buffer configuration", "error", err)
+		os.Exit(1)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	signalChan := make(chan os.Signal, 1)
@@ -78,10 +270,15 @@ func main() {
 		nats.ClientCert(natsCertFile, natsKeyFile),
 	}
 
+	if natsStreamReplicas < 1 {
+		logger.Error("Invalid number of stream replicas, must be greater than 0", "natsStreamReplicas", natsStreamReplicas)
+		os.Exit(1)
+	}
+
 	if init {
 		logger = logger.With("task", "init")
 
-		if err := cmdutil.WaitForStorageTypes(ctx, config, logger); err != nil {
+		if err := cmdutil.WaitForStorageTypes(ctx, config, storageWait, logger); err != nil {
 			logger.Error("Error waiting for storage types", "error", err)
 			os.Exit(1)
 		}
@@ -91,10 +288,52 @@ func main() {
 			os.Exit(1)
 		}
 
+		if err := ensureJetStream(ctx, natsURL, natsOpts, natsStreamReplicas, ackWait, logger); err != nil {
+			logger.Error("Error ensuring JetStream streams and consumers", "error", err)
+			os.Exit(1)
+		}
+
 		logger.Info("Initialization tasks completed successfully.")
 		os.Exit(0)
 	}
 
+	maxSize, err := resource.ParseQuantity(cacheMaxSize)
+	if err != nil {
+		logger.Error("Error parsing the cache max size", "error", err, "cacheMaxSize", cacheMaxSize)
+		os.Exit(1)
+	}
+	cache, err := cachedir.New(cacheDir, maxSize.Value(), logger)
+	if err != nil {
+		logger.Error("Error setting up the cache directory", "error", err, "cacheDir", cacheDir)
+		os.Exit(1)
+	}
+	// Trivy downloads the image layers to the temporary directory.
+	if err = os.Setenv("TMPDIR", cache.Dir()); err != nil {
+		logger.Error("Error setting the temporary directory", "error", err)
+		os.Exit(1)
+	}
+
+	if trivyDBCacheDir == "" {
+		trivyDBCacheDir = runDir
+	}
+	vulnDB, err := vulndb.New(vulndb.Options{
+		Repository:      trivyDBRepository,
+		Version:         trivyDBVersion,
+		CacheDir:        trivyDBCacheDir,
+		RefreshInterval: trivyDBRefreshInterval,
+	}, logger)
+	if err != nil {
+		logger.Error("Error setting up the vulnerability database", "error", err)
+		os.Exit(1)
+	}
+	go vulnDB.Start(ctx)
+
+	// Wait for JetStream before creating the subscriber, the readiness check then verifies the connection periodically.
+	if err = cmdutil.WaitForJetStream(ctx, natsURL, natsOpts, logger); err != nil {
+		logger.Error("Error waiting for JetStream", "error", err)
+		os.Exit(1)
+	}
+
 	nc, err := nats.Connect(natsURL,
 		natsOpts...,
 	)
@@ -103,12 +342,19 @@ func main() {
 		os.Exit(1)
 	}
 
-	publisher, err := messaging.NewNatsPublisher(ctx, nc, logger)
+	publisher, err := messaging.NewNatsPublisher(ctx, nc, natsStreamReplicas, logger)
 	if err != nil {
 		logger.Error("Error creating NATS publisher", "error", err)
 		os.Exit(1)
 	}
 
+	for _, stream := range []string{messaging.StreamName, messaging.BacklogStreamName} {
+		if err = cmdutil.WaitForStream(ctx, nc, stream, natsStreamReplicas, logger); err != nil {
+			logger.Error("Error waiting for JetStream stream", "error", err, "stream", stream)
+			os.Exit(1)
+		}
+	}
+
 	scheme := scheme.Scheme
 	if err = v1alpha1.AddToScheme(scheme); err != nil {
 		logger.Error("Error adding v1alpha1 to scheme", "error", err)
@@ -127,29 +373,97 @@ func main() {
 		logger.Error("Error creating k8s client", "error", err)
 		os.Exit(1)
 	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		logger.Error("Error creating kubernetes clientset", "error", err)
+		os.Exit(1)
+	}
+	// The outcome of the scans is recorded as Events on the images and the registries.
+	eventBroadcaster := record.NewBroadcaster(record.WithContext(ctx))
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+	defer eventBroadcaster.Shutdown()
+	recorder := eventBroadcaster.NewRecorder(scheme, corev1.EventSource{Component: "sbomscanner-worker"})
+
 	registryClientFactory := func(transport http.RoundTripper) registry.Client {
 		return registry.NewClient(transport, logger)
 	}
+	// Registries without an authSecret are authenticated with the workload identity of the worker,
+	// when their host matches one of the cloud providers.
+	credentialProviders, err := dockerauth.NewCredentialProviders(disabledCredentialProviders, logger)
+	if err != nil {
+		logger.Error("Error setting up the credential providers", "error", err)
+		os.Exit(1)
+	}
+
+	var writeBuffer *writebuffer.Buffer
+	if writeBufferOptions.Enabled() {
+		writeBuffer, err = writebuffer.New(writeBufferOptions, logger)
+		if err != nil {
+			logger.Error("Error setting up the write buffer", "error", err, "writeBufferDir", writeBufferOptions.Dir)
+			os.Exit(1)
+		}
+		logger.Info("Write buffer enabled",
+			"writeBufferDir", writeBufferOptions.Dir,
+			"maxEntries", writeBufferOptions.MaxEntries,
+			"batchSize", writeBufferOptions.BatchSize,
+			"rate", writeBufferOptions.Rate,
+			"maxAttempts", writeBufferOptions.MaxAttempts)
+	}
+	generateSBOMHandler := handlers.NewGenerateSBOMHandler(k8sClient, scheme, runDir, trivyJavaDBRepository, publisher, handlers.GenerateSBOMHandlerOptions{
+		ScanTimeout:              scanTimeout,
+		LayerDownloadConcurrency: layerDownloadConcurrency,
+		ImageLimits:              imageLimits,
+		SBOMSchemaVersion:        sbomSchemaVersion,
+		CredentialProviders:      credentialProviders,
+		RegistryAllowlist:        registryAllowlist,
+		WriteBuffer:              writeBuffer,
+		Recorder:                 recorder,
+		WorkerID:                 workerID,
+		AllowSBOMReferrers:       allowSBOMReferrers,
+	}, logger)
+	// The last scan times of the images are written in batches.
+	imageStatusBatcher := handlers.NewImageStatusBatcher(k8sClient, logger)
+	imageStatusBatcherDone := make(chan struct{})
+	go func() {
+		defer close(imageStatusBatcherDone)
+		imageStatusBatcher.Run(ctx)
+	}()
+	scanSBOMHandler := handlers.NewScanSBOMHandler(k8sClient, scheme, runDir, vulnDB, trivyJavaDBRepository, imageStatusBatcher, handlers.ScanSBOMHandlerOptions{
+		CVEAllowlistFile: cveAllowlistFile,
+		Notifier:         notifier,
+		WriteBuffer:      writeBuffer,
+		MinScanInterval:  minScanInterval,
+		Recorder:         recorder,
+		WorkerID:         workerID,
+	}, logger)
+	if writeBuffer != nil {
+		// The buffered writes left by the previous run are written first, then the ones of the scans.
+		go writeBuffer.Run(ctx, writebuffer.Writers{
+			handlers.WriteKindSBOM:                generateSBOMHandler.WriteSBOM,
+			handlers.WriteKindVulnerabilityReport: scanSBOMHandler.WriteVulnerabilityReport,
+		})
+	}
 
 	registry := messaging.HandlerRegistry{
-		handlers.CreateCatalogSubject: handlers.NewCreateCatalogHandler(registryClientFactory, k8sClient, scheme, publisher, logger),
-		handlers.GenerateSBOMSubject:  handlers.NewGenerateSBOMHandler(k8sClient, scheme, runDir, trivyJavaDBRepository, publisher, logger),
-		handlers.ScanSBOMSubject:      handlers.NewScanSBOMHandler(k8sClient, scheme, runDir, trivyDBRepository, trivyJavaDBRepository, logger),
+		handlers.CreateCatalogSubject: handlers.NewCreateCatalogHandler(registryClientFactory, k8sClient, scheme, defaultPlatform, publisher, credentialProviders, registryAllowlist, logger),
+		handlers.GenerateSBOMSubject:  cache.WrapHandler(generateSBOMHandler),
+		handlers.ScanSBOMSubject:      scanSBOMHandler,
+		handlers.RescanSBOMsSubject:   handlers.NewRescanSBOMsHandler(k8sClient, publisher, logger),
 	}
-	failureHandler := handlers.NewScanJobFailureHandler(k8sClient, logger)
+	failureHandler := handlers.NewScanJobFailureHandler(k8sClient, recorder, logger)
 	retryConfig := &messaging.RetryConfig{
 		BaseDelay:   5 * time.Second,
 		Jitter:      0.2,
 		MaxAttempts: 5,
 	}
 
-	subscriber, err := messaging.NewNatsSubscriber(ctx, nc, "worker", registry, failureHandler, retryConfig, logger)
+	subscriber, err := messaging.NewNatsSubscriber(ctx, nc, workerConsumer, ackWait, registry, failureHandler, retryConfig, logger)
 	if err != nil {
 		logger.Error("Error creating NATS subscriber", "error", err)
 		os.Exit(1)
 	}
 
-	healthServer := runHealthServer(logger)
+	healthServer := runHealthServer(messaging.NewJetStreamChecker(nc, subscriber, logger), logger)
 
 	err = subscriber.Run(ctx)
 	if err != nil {
@@ -157,6 +471,10 @@ func main() {
 		os.Exit(1)
 	}
 
+	// The statuses of the last scanned images are written before exiting.
+	cancel()
+	<-imageStatusBatcherDone
+
 	logger.Debug("Shutting down health server")
 	if err := healthServer.Close(); err != nil {
 		logger.Error("Error shutting down health check server", "error", err)
@@ -164,12 +482,28 @@ func main() {
 	}
 }
 
-func runHealthServer(logger *slog.Logger) *http.Server {
-	handler := &healthz.Handler{}
+// defaultWorkerID returns the hostname, which is the name of the pod in Kubernetes.
+func defaultWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+
+	return hostname
+}
+
+func runHealthServer(jetStreamChecker *messaging.JetStreamChecker, logger *slog.Logger) *http.Server {
+	livezHandler := &healthz.Handler{}
+	// The worker is not ready while it cannot receive messages from JetStream.
+	readyzHandler := &healthz.Handler{
+		Checks: map[string]healthz.Checker{
+			jetStreamChecker.Name(): jetStreamChecker.Check,
+		},
+	}
 
 	mux := http.NewServeMux()
-	mux.Handle("/livez/", http.StripPrefix("/livez", handler))
-	mux.Handle("/readyz/", http.StripPrefix("/readyz", handler))
+	mux.Handle("/livez/", http.StripPrefix("/livez", livezHandler))
+	mux.Handle("/readyz/", http.StripPrefix("/readyz", readyzHandler))
 
 	server := &http.Server{
 		Addr:        ":8081",
@@ -186,3 +520,48 @@ func runHealthServer(logger *slog.Logger) *http.Server {
 
 	return server
 }
+
+// ensureJetStream creates or updates the streams and the durable consumer of the workers,
+// so that they exist with the configured replicas before the workers start.
+func ensureJetStream(ctx context.Context, natsURL string, natsOpts []nats.Option, replicas int, ackWait time.Duration, logger *slog.Logger) error {
+	nc, err := nats.Connect(natsURL, natsOpts...)
+	if err != nil {
+		return fmt.Errorf("connecting to NATS: %w", err)
+	}
+	defer nc.Close()
+
+	return cmdutil.EnsureJetStream(ctx, nc, cmdutil.JetStreamTopology{
+		Streams: messaging.StreamConfigs(replicas),
+		Consumers: []cmdutil.JetStreamConsumer{
+			{Stream: messaging.StreamName, Config: messaging.ConsumerConfig(workerConsumer, handlers.WorkerSubjects, ackWait)},
+		},
+	}, cmdutil.DefaultRetryConfig, logger)
+}
+
+// loadNotificationOptions reads the URL and the signing secret of the notifications from their files,
+// which are usually mounted from a Secret, and validates the options.
+func loadNotificationOptions(options notification.Options, urlFile, signingSecretFile string) (notification.Options, error) {
+	webhookURL, err := os.ReadFile(urlFile)
+	if err != nil {
+		return options, fmt.Errorf("reading notification URL: %w", err)
+	}
+	options.URL = strings.TrimSpace(string(webhookURL))
+
+	if signingSecretFile != "" {
+		// A missing file is allowed, since the signing secret is an optional key of the mounted Secret.
+		signingSecret, err := os.ReadFile(signingSecretFile)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return options, fmt.Errorf("reading notification signing secret: %w", err)
+		}
+		options.SigningSecret = bytes.TrimSpace(signingSecret)
+	}
+
+	if !options.Enabled() {
+		return options, fmt.Errorf("notification URL file %s is empty", urlFile)
+	}
+	if err := options.Validate(); err != nil {
+		return options, err
+	}
+
+	return options, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	cranev1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/kubewarden/sbomscanner/internal/cmdutil"
+	"github.com/kubewarden/sbomscanner/internal/smoketest"
+)
+
+const smokeTestUsage = `Usage: worker smoke-test [flags]
+
+Run an end-to-end scan of a small public image: pull it, generate its SBOM and match it
+against the vulnerability database, then print a summary as JSON.
+No Registry resource nor NATS server is needed, so the command validates a deployment,
+e.g. from a Helm post-install hook. It exits with a non-zero status when any step fails.
+
+Flags:
+`
+
+// runSmokeTest runs the smoke-test subcommand.
+func runSmokeTest(args []string) error {
+	var (
+		options       smoketest.Options
+		platformValue string
+		timeout       time.Duration
+		logLevel      string
+	)
+
+	flags := flag.NewFlagSet("smoke-test", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), smokeTestUsage)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&options.Image, "image", smoketest.DefaultImage, "Image scanned by the smoke test.")
+	flags.StringVar(&platformValue, "platform", "linux/amd64", "Platform scanned when the image is a multi-architecture image, e.g. linux/arm64.")
+	flags.StringVar(&options.WorkDir, "run-dir", "/var/run/worker", "Directory to store temporary files and the image layers.")
+	flags.StringVar(&options.VulnDB.Repository, "trivy-db-repository", "public.ecr.aws/aquasecurity/trivy-db", "OCI repository to retrieve trivy-db.")
+	flags.StringVar(&options.VulnDB.Version, "trivy-db-version", "", "Tag or digest of trivy-db to use. The latest version is used when empty.")
+	flags.StringVar(&options.VulnDB.CacheDir, "trivy-db-cache-dir", "", "Directory where trivy-db is downloaded. Defaults to the run directory.")
+	flags.StringVar(&options.TrivyJavaDBRepository, "trivy-java-db-repository", "public.ecr.aws/aquasecurity/trivy-java-db", "OCI repository to retrieve trivy-java-db.")
+	flags.DurationVar(&timeout, "timeout", 10*time.Minute, "Maximum duration of the smoke test.")
+	flags.StringVar(&logLevel, "log-level", slog.LevelInfo.String(), "Log level.")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("parsing flags: %w", err)
+	}
+
+	slogLevel, err := cmdutil.ParseLogLevel(logLevel)
+	if err != nil {
+		return fmt.Errorf("parsing log level: %w", err)
+	}
+	// The logs go to stderr, so that stdout only holds the summary.
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slogLevel})).
+		With("component", "worker", "task", "smoke-test")
+
+	if platformValue != "" {
+		options.Platform, err = cranev1.ParsePlatform(platformValue)
+		if err != nil {
+			return fmt.Errorf("invalid platform %q: %w", platformValue, err)
+		}
+	}
+	if options.VulnDB.CacheDir == "" {
+		options.VulnDB.CacheDir = options.WorkDir
+	}
+	// Trivy downloads the image layers to the temporary directory.
+	if err = os.Setenv("TMPDIR", options.WorkDir); err != nil {
+		return fmt.Errorf("setting the temporary directory: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	summary, err := smoketest.Run(ctx, options, logger)
+	if err != nil {
+		return fmt.Errorf("smoke test of image %s failed: %w", options.Image, err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(summary); err != nil {
+		return fmt.Errorf("encoding the smoke test summary: %w", err)
+	}
+
+	return nil
+}
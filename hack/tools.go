@@ -3,9 +3,12 @@
 
 // This project uses `go run` instead of the `tools.go` pattern to pin tool versions in the Makefile.
 //
-//	However, we need to import `k8s.io/code-generator` as it is required by the build scripts `update-codegen.sh` and `verify-codegen.sh`.
+//	However, we need to import `k8s.io/code-generator` and `goimports` as they are required by the build scripts `update-codegen.sh` and `verify-codegen.sh`.
 package tools
 
 import (
+	_ "golang.org/x/tools/cmd/goimports"
 	_ "k8s.io/code-generator"
+	_ "k8s.io/code-generator/cmd/go-to-protobuf"
+	_ "k8s.io/code-generator/cmd/go-to-protobuf/protoc-gen-gogo"
 )
@@ -34,6 +34,26 @@ kube::codegen::gen_helpers \
     --boilerplate "${SCRIPT_ROOT}/hack/boilerplate.go.txt" \
     "${SCRIPT_ROOT}/api/storage"
 
+# The protobuf code of the storage types is generated by go-to-protobuf, which requires protoc in the PATH.
+# go-to-protobuf reads the packages and the .proto files they import from a GOPATH-like tree, created in a temporary directory.
+PROTO_ROOT=$(mktemp -d)
+trap 'rm -rf "${PROTO_ROOT}"' EXIT
+mkdir -p "${PROTO_ROOT}/src/github.com/kubewarden" "${PROTO_ROOT}/src/k8s.io" "${PROTO_ROOT}/include/github.com/gogo"
+ln -s "$(cd "${SCRIPT_ROOT}" && pwd -P)" "${PROTO_ROOT}/src/${THIS_PKG}"
+ln -s "$(go list -m -f '{{.Dir}}' k8s.io/apimachinery)" "${PROTO_ROOT}/src/k8s.io/apimachinery"
+ln -s "$(go list -m -f '{{.Dir}}' github.com/gogo/protobuf)" "${PROTO_ROOT}/include/github.com/gogo/protobuf"
+GOBIN="${PROTO_ROOT}/bin" go install \
+    k8s.io/code-generator/cmd/go-to-protobuf \
+    k8s.io/code-generator/cmd/go-to-protobuf/protoc-gen-gogo \
+    golang.org/x/tools/cmd/goimports
+
+PATH="${PROTO_ROOT}/bin:${PATH}" go-to-protobuf \
+    --go-header-file "${SCRIPT_ROOT}/hack/boilerplate.go.txt" \
+    --packages "${THIS_PKG}/api/storage/v1alpha1" \
+    --apimachinery-packages "-k8s.io/apimachinery/pkg/util/intstr,-k8s.io/apimachinery/pkg/api/resource,-k8s.io/apimachinery/pkg/runtime/schema,-k8s.io/apimachinery/pkg/runtime,-k8s.io/apimachinery/pkg/apis/meta/v1" \
+    --proto-import "${PROTO_ROOT}/include" \
+    --output-dir "${PROTO_ROOT}/src"
+
 if [[ -n "${API_KNOWN_VIOLATIONS_DIR:-}" ]]; then
     report_filename="${API_KNOWN_VIOLATIONS_DIR}/sample_apiserver_violation_exceptions.list"
     if [[ "${UPDATE_API_KNOWN_VIOLATIONS:-}" == "true" ]]; then
@@ -0,0 +1,67 @@
+package apiserver
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apiserver/pkg/audit/policy"
+	genericoptions "k8s.io/apiserver/pkg/server/options"
+)
+
+// AuditOptions configures the audit logs of the API server, which record who did what to the resources:
+// the user, the verb, the resource and the response status of every request matched by the policy.
+// The audit is disabled when no policy file is configured.
+type AuditOptions struct {
+	// PolicyFile is the path of the audit policy, in the audit.k8s.io/v1 Policy format.
+	PolicyFile string
+	// LogPath is the path of the file the audit events are written to, "-" for the standard output.
+	LogPath string
+	// LogMaxAge is the maximum number of days the rotated log files are retained. 0 retains them forever.
+	LogMaxAge int
+	// LogMaxBackups is the maximum number of rotated log files retained. 0 retains them all.
+	LogMaxBackups int
+	// LogMaxSize is the maximum size in megabytes of the log file before it is rotated. 0 disables the rotation.
+	LogMaxSize int
+	// WebhookConfigFile is the path of the kubeconfig file of the webhook the audit events are sent to.
+	WebhookConfigFile string
+}
+
+// Enabled returns true when an audit policy is configured.
+func (o AuditOptions) Enabled() bool {
+	return o.PolicyFile != ""
+}
+
+// Validate returns an error if a backend is configured without a policy, or the policy without a backend,
+// or if the policy cannot be loaded.
+func (o AuditOptions) Validate() error {
+	hasBackend := o.LogPath != "" || o.WebhookConfigFile != ""
+	if !o.Enabled() {
+		if hasBackend {
+			return errors.New("the audit backends require an audit policy file")
+		}
+		return nil
+	}
+	if !hasBackend {
+		return errors.New("the audit policy requires an audit log path or an audit webhook config file")
+	}
+	if o.LogMaxAge < 0 || o.LogMaxBackups < 0 || o.LogMaxSize < 0 {
+		return errors.New("the audit log retention must not be negative")
+	}
+	if _, err := policy.LoadPolicyFromFile(o.PolicyFile); err != nil {
+		return fmt.Errorf("invalid audit policy: %w", err)
+	}
+
+	return nil
+}
+
+// applyTo configures the audit options of the generic API server.
+// The audit log events are written synchronously, so that no event is lost when the server stops,
+// while the webhook events are sent in batches, so that a slow webhook does not delay the requests.
+func (o AuditOptions) applyTo(options *genericoptions.AuditOptions) {
+	options.PolicyFile = o.PolicyFile
+	options.LogOptions.Path = o.LogPath
+	options.LogOptions.MaxAge = o.LogMaxAge
+	options.LogOptions.MaxBackups = o.LogMaxBackups
+	options.LogOptions.MaxSize = o.LogMaxSize
+	options.WebhookOptions.ConfigFile = o.WebhookConfigFile
+}
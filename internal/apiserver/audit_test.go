@@ -0,0 +1,136 @@
+package apiserver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	auditinternal "k8s.io/apiserver/pkg/apis/audit"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	genericoptions "k8s.io/apiserver/pkg/server/options"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+const testAuditPolicy = `apiVersion: audit.k8s.io/v1
+kind: Policy
+omitStages:
+  - RequestReceived
+rules:
+  - level: Metadata
+    resources:
+      - group: storage.sbomscanner.kubewarden.io
+        resources: ["images", "sboms", "vulnerabilityreports"]
+  - level: None
+`
+
+func writeTestAuditPolicy(t *testing.T, policy string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(policy), 0o600))
+
+	return path
+}
+
+func TestAuditOptionsValidate(t *testing.T) {
+	policyFile := writeTestAuditPolicy(t, testAuditPolicy)
+	invalidPolicyFile := writeTestAuditPolicy(t, "kind: Policy\nrules: invalid\n")
+
+	tests := []struct {
+		name          string
+		options       AuditOptions
+		expectedError string
+	}{
+		{
+			name:    "disabled",
+			options: AuditOptions{},
+		},
+		{
+			name:    "log backend",
+			options: AuditOptions{PolicyFile: policyFile, LogPath: "-"},
+		},
+		{
+			name:    "webhook backend",
+			options: AuditOptions{PolicyFile: policyFile, WebhookConfigFile: "/etc/audit/webhook.kubeconfig"},
+		},
+		{
+			name:          "backend without policy",
+			options:       AuditOptions{LogPath: "-"},
+			expectedError: "the audit backends require an audit policy file",
+		},
+		{
+			name:          "policy without backend",
+			options:       AuditOptions{PolicyFile: policyFile},
+			expectedError: "the audit policy requires an audit log path or an audit webhook config file",
+		},
+		{
+			name:          "negative retention",
+			options:       AuditOptions{PolicyFile: policyFile, LogPath: "-", LogMaxAge: -1},
+			expectedError: "the audit log retention must not be negative",
+		},
+		{
+			name:          "invalid policy",
+			options:       AuditOptions{PolicyFile: invalidPolicyFile, LogPath: "-"},
+			expectedError: "invalid audit policy",
+		},
+		{
+			name:          "missing policy",
+			options:       AuditOptions{PolicyFile: filepath.Join(t.TempDir(), "missing.yaml"), LogPath: "-"},
+			expectedError: "invalid audit policy",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.options.Validate()
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAuditOptionsApplyTo(t *testing.T) {
+	options := AuditOptions{
+		PolicyFile: writeTestAuditPolicy(t, testAuditPolicy),
+		LogPath:    filepath.Join(t.TempDir(), "audit.log"),
+		LogMaxAge:  7,
+	}
+	genericAuditOptions := genericoptions.NewAuditOptions()
+	options.applyTo(genericAuditOptions)
+	require.Empty(t, genericAuditOptions.Validate())
+
+	config := &genericapiserver.Config{}
+	require.NoError(t, genericAuditOptions.ApplyTo(config))
+	require.NotNil(t, config.AuditBackend)
+	require.NotNil(t, config.AuditPolicyRuleEvaluator)
+
+	// The requests to the scan resources are recorded with their metadata: user, verb, resource and response status.
+	for _, resource := range []string{"images", "sboms", "vulnerabilityreports"} {
+		auditConfig := config.AuditPolicyRuleEvaluator.EvaluatePolicyRule(authorizer.AttributesRecord{
+			User:            &user.DefaultInfo{Name: "system:serviceaccount:sbomscanner:sbomscanner-worker"},
+			Verb:            "create",
+			APIGroup:        v1alpha1.GroupName,
+			APIVersion:      "v1alpha1",
+			Resource:        resource,
+			ResourceRequest: true,
+		})
+		assert.Equal(t, auditinternal.LevelMetadata, auditConfig.Level, resource)
+	}
+
+	auditConfig := config.AuditPolicyRuleEvaluator.EvaluatePolicyRule(authorizer.AttributesRecord{
+		User:            &user.DefaultInfo{Name: "admin"},
+		Verb:            "get",
+		APIGroup:        v1alpha1.GroupName,
+		APIVersion:      "v1alpha1",
+		Resource:        "cveimpacts",
+		ResourceRequest: true,
+	})
+	assert.Equal(t, auditinternal.LevelNone, auditConfig.Level)
+}
@@ -0,0 +1,149 @@
+package apiserver
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	// certificateExpiryCheckInterval is how often the certificates are checked.
+	certificateExpiryCheckInterval = 10 * time.Minute
+
+	// ServingCertificate is the name of the API server serving certificate.
+	ServingCertificate = "serving"
+	// PostgresCACertificate is the name of the PostgreSQL server CA certificate.
+	PostgresCACertificate = "postgres_ca"
+)
+
+var (
+	certificateExpiryGauge = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "sbomscanner",
+			Name:           "certificate_expiry_seconds",
+			Help:           "Number of seconds until the certificate expires. Negative when the certificate is already expired.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"certificate"},
+	)
+
+	registerCertificateMetricsOnce sync.Once
+)
+
+// certificateSource loads the PEM encoded certificates to check.
+type certificateSource struct {
+	name string
+	load func() ([]byte, error)
+}
+
+// CertificateExpiryChecker periodically checks the expiry of the certificates,
+// exposes the time left as a Prometheus gauge and logs a warning
+// when a certificate expires within the warning threshold.
+type CertificateExpiryChecker struct {
+	threshold time.Duration
+	sources   []certificateSource
+	logger    *slog.Logger
+}
+
+// NewCertificateExpiryChecker creates a new CertificateExpiryChecker.
+func NewCertificateExpiryChecker(threshold time.Duration, logger *slog.Logger) *CertificateExpiryChecker {
+	registerCertificateMetricsOnce.Do(func() {
+		legacyregistry.MustRegister(certificateExpiryGauge)
+	})
+
+	return &CertificateExpiryChecker{
+		threshold: threshold,
+		logger:    logger.With("component", "certificate_expiry_checker"),
+	}
+}
+
+// AddSource adds a certificate to check.
+// The load function is called on every check, so that rotated certificates are picked up.
+func (c *CertificateExpiryChecker) AddSource(name string, load func() ([]byte, error)) {
+	c.sources = append(c.sources, certificateSource{name: name, load: load})
+}
+
+// Start checks the certificates immediately and then periodically, until the context is canceled.
+func (c *CertificateExpiryChecker) Start(ctx context.Context) {
+	c.logger.InfoContext(ctx, "Starting certificate expiry checker", "threshold", c.threshold)
+
+	ticker := time.NewTicker(certificateExpiryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		c.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			c.logger.InfoContext(ctx, "Stopping certificate expiry checker")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check updates the expiry metric of every certificate and logs a warning
+// for the certificates expiring within the threshold.
+func (c *CertificateExpiryChecker) Check(ctx context.Context) {
+	for _, source := range c.sources {
+		logger := c.logger.With("certificate", source.name)
+
+		data, err := source.load()
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to load certificate", "error", err)
+			continue
+		}
+
+		notAfter, err := earliestExpiry(data)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to parse certificate", "error", err)
+			continue
+		}
+
+		timeLeft := time.Until(notAfter)
+		certificateExpiryGauge.WithLabelValues(source.name).Set(timeLeft.Seconds())
+
+		switch {
+		case timeLeft <= 0:
+			logger.WarnContext(ctx, "Certificate expired", "notAfter", notAfter)
+		case timeLeft <= c.threshold:
+			logger.WarnContext(ctx, "Certificate expires soon", "notAfter", notAfter, "timeLeft", timeLeft.Round(time.Second).String())
+		default:
+			logger.DebugContext(ctx, "Certificate checked", "notAfter", notAfter)
+		}
+	}
+}
+
+// earliestExpiry returns the earliest expiry of the PEM encoded certificates,
+// e.g. the leaf or an intermediate CA of a certificate chain.
+func earliestExpiry(data []byte) (time.Time, error) {
+	var notAfter time.Time
+
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parsing certificate: %w", err)
+		}
+		if notAfter.IsZero() || certificate.NotAfter.Before(notAfter) {
+			notAfter = certificate.NotAfter
+		}
+	}
+
+	if notAfter.IsZero() {
+		return time.Time{}, errors.New("no certificate found")
+	}
+
+	return notAfter, nil
+}
@@ -0,0 +1,86 @@
+package apiserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateCertificatePEM(t *testing.T, notAfter time.Time) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestEarliestExpiry(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	leaf := generateCertificatePEM(t, now.Add(24*time.Hour))
+	ca := generateCertificatePEM(t, now.Add(365*24*time.Hour))
+	key := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("key")})
+
+	tests := []struct {
+		name             string
+		data             []byte
+		expectedNotAfter time.Time
+		expectedError    string
+	}{
+		{
+			name:             "single certificate",
+			data:             ca,
+			expectedNotAfter: now.Add(365 * 24 * time.Hour),
+		},
+		{
+			name:             "certificate chain",
+			data:             append(append([]byte{}, ca...), leaf...),
+			expectedNotAfter: now.Add(24 * time.Hour),
+		},
+		{
+			name:             "non certificate blocks are skipped",
+			data:             append(append([]byte{}, key...), leaf...),
+			expectedNotAfter: now.Add(24 * time.Hour),
+		},
+		{
+			name:          "no certificate",
+			data:          key,
+			expectedError: "no certificate found",
+		},
+		{
+			name:          "invalid certificate",
+			data:          pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("invalid")}),
+			expectedError: "parsing certificate",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notAfter, err := earliestExpiry(test.data)
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, test.expectedNotAfter.Equal(notAfter), "expected %s, got %s", test.expectedNotAfter, notAfter)
+		})
+	}
+}
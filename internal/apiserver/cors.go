@@ -0,0 +1,98 @@
+package apiserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	genericfilters "k8s.io/apiserver/pkg/server/filters"
+)
+
+var (
+	// DefaultCORSAllowedMethods are the methods allowed by default in the cross-origin requests.
+	DefaultCORSAllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	// DefaultCORSAllowedHeaders are the headers allowed by default in the cross-origin requests.
+	DefaultCORSAllowedHeaders = []string{"Content-Type", "Content-Length", "Accept", "Accept-Encoding", "Authorization", "X-Requested-With"}
+)
+
+// httpMethodPattern matches a valid HTTP method token.
+var httpMethodPattern = regexp.MustCompile(`^[A-Z]+$`)
+
+// CORSOptions configures the Cross-Origin Resource Sharing policy of the API server.
+// CORS is disabled when no allowed origin is configured.
+type CORSOptions struct {
+	// AllowedOrigins are regular expressions matching the allowed origins, e.g. `^https://dashboard\.example\.com$`.
+	AllowedOrigins []string
+	// AllowedMethods are the HTTP methods allowed in the cross-origin requests.
+	AllowedMethods []string
+	// AllowedHeaders are the HTTP headers allowed in the cross-origin requests.
+	AllowedHeaders []string
+}
+
+// NewCORSOptions returns the default CORSOptions, with CORS disabled.
+func NewCORSOptions() CORSOptions {
+	return CORSOptions{
+		AllowedMethods: append([]string{}, DefaultCORSAllowedMethods...),
+		AllowedHeaders: append([]string{}, DefaultCORSAllowedHeaders...),
+	}
+}
+
+// Enabled returns true when at least one allowed origin is configured.
+func (o CORSOptions) Enabled() bool {
+	return len(o.AllowedOrigins) > 0
+}
+
+// Validate returns an error if an origin pattern is not a valid regular expression anchored to the origin host,
+// or if a method or a header is not valid.
+func (o CORSOptions) Validate() error {
+	for _, origin := range o.AllowedOrigins {
+		if err := validateCORSOrigin(origin); err != nil {
+			return fmt.Errorf("invalid CORS allowed origin %q: %w", origin, err)
+		}
+	}
+	for _, method := range o.AllowedMethods {
+		if !httpMethodPattern.MatchString(method) {
+			return fmt.Errorf("invalid CORS allowed method %q", method)
+		}
+	}
+	for _, header := range o.AllowedHeaders {
+		if header == "" || strings.ContainsAny(header, " \t,:") {
+			return fmt.Errorf("invalid CORS allowed header %q", header)
+		}
+	}
+
+	return nil
+}
+
+// validateCORSOrigin checks that the origin pattern is a valid regular expression
+// pinned to the start and to the end of the host, so that it cannot match unexpected origins,
+// e.g. `example\.com` would also match `https://example.com.attacker.io`.
+func validateCORSOrigin(origin string) error {
+	if _, err := regexp.Compile(origin); err != nil {
+		return fmt.Errorf("invalid regular expression: %w", err)
+	}
+	if !strings.HasPrefix(origin, "^") && !strings.Contains(origin, "//") {
+		return errors.New("the pattern must match the start of the host, using '^' or '//'")
+	}
+	if !strings.HasSuffix(origin, "$") && !strings.Contains(origin, "(:|$)") {
+		return errors.New("the pattern must match the end of the host, using '$' or '(:|$)'")
+	}
+
+	return nil
+}
+
+// withCORS wraps the handler with the CORS policy.
+// The preflight OPTIONS requests from the allowed origins are answered directly,
+// without being authenticated, as the browsers do not send credentials with them.
+func withCORS(handler http.Handler, options CORSOptions) http.Handler {
+	return genericfilters.WithCORS(
+		handler,
+		options.AllowedOrigins,
+		options.AllowedMethods,
+		options.AllowedHeaders,
+		nil,
+		"true",
+	)
+}
@@ -0,0 +1,137 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORSOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       CORSOptions
+		expectedError string
+	}{
+		{
+			name:    "defaults",
+			options: NewCORSOptions(),
+		},
+		{
+			name: "anchored origins",
+			options: CORSOptions{
+				AllowedOrigins: []string{`^https://dashboard\.example\.com$`, `//localhost(:|$)`},
+			},
+		},
+		{
+			name: "invalid regular expression",
+			options: CORSOptions{
+				AllowedOrigins: []string{`^https://(dashboard$`},
+			},
+			expectedError: "invalid regular expression",
+		},
+		{
+			name: "origin not pinned to the start of the host",
+			options: CORSOptions{
+				AllowedOrigins: []string{`example\.com$`},
+			},
+			expectedError: "must match the start of the host",
+		},
+		{
+			name: "origin not pinned to the end of the host",
+			options: CORSOptions{
+				AllowedOrigins: []string{`^https://example\.com`},
+			},
+			expectedError: "must match the end of the host",
+		},
+		{
+			name: "invalid method",
+			options: CORSOptions{
+				AllowedMethods: []string{"GET", "get"},
+			},
+			expectedError: `invalid CORS allowed method "get"`,
+		},
+		{
+			name: "invalid header",
+			options: CORSOptions{
+				AllowedHeaders: []string{"Content Type"},
+			},
+			expectedError: `invalid CORS allowed header "Content Type"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.options.Validate()
+			if test.expectedError == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, test.expectedError)
+		})
+	}
+}
+
+func TestWithCORS(t *testing.T) {
+	options := NewCORSOptions()
+	options.AllowedOrigins = []string{`^https://dashboard\.example\.com$`}
+
+	handler := withCORS(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// Simulate the authentication filter rejecting the requests without credentials.
+		w.WriteHeader(http.StatusUnauthorized)
+	}), options)
+
+	tests := []struct {
+		name                string
+		method              string
+		origin              string
+		expectedStatus      int
+		expectedAllowOrigin string
+	}{
+		{
+			name:                "preflight request from an allowed origin",
+			method:              http.MethodOptions,
+			origin:              "https://dashboard.example.com",
+			expectedStatus:      http.StatusNoContent,
+			expectedAllowOrigin: "https://dashboard.example.com",
+		},
+		{
+			name:                "request from an allowed origin",
+			method:              http.MethodGet,
+			origin:              "https://dashboard.example.com",
+			expectedStatus:      http.StatusUnauthorized,
+			expectedAllowOrigin: "https://dashboard.example.com",
+		},
+		{
+			name:           "preflight request from a disallowed origin",
+			method:         http.MethodOptions,
+			origin:         "https://dashboard.example.com.attacker.io",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "request without origin",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, "/apis/storage.sbomscanner.kubewarden.io/v1alpha1/images", nil)
+			if test.origin != "" {
+				request.Header.Set("Origin", test.origin)
+			}
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedAllowOrigin, recorder.Header().Get("Access-Control-Allow-Origin"))
+			if test.expectedAllowOrigin != "" {
+				assert.Equal(t, "GET, POST, PUT, PATCH, DELETE, OPTIONS", recorder.Header().Get("Access-Control-Allow-Methods"))
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package apiserver
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/endpoints/responsewriter"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	"github.com/kubewarden/sbomscanner/internal/storage"
+)
+
+const (
+	// DefaultMaxRequestsInFlight is the default maximum number of concurrent read-only requests,
+	// the same as the Kubernetes API server.
+	DefaultMaxRequestsInFlight = 400
+	// DefaultMaxMutatingRequestsInFlight is the default maximum number of concurrent mutating requests,
+	// the same as the Kubernetes API server.
+	DefaultMaxMutatingRequestsInFlight = 200
+	// DefaultRetryAfter is the default delay after which the clients retry the rejected requests,
+	// the same as the Kubernetes API server.
+	DefaultRetryAfter = time.Second
+)
+
+// InFlightOptions limits the number of requests served concurrently by the API server.
+// The requests over the limits are rejected with 429 Too Many Requests,
+// so that a burst of heavy requests is shed instead of piling onto the database.
+// The long-running requests, i.e. the watches, are not limited.
+type InFlightOptions struct {
+	// MaxRequestsInFlight is the maximum number of concurrent read-only requests. 0 means no limit.
+	MaxRequestsInFlight int
+	// MaxMutatingRequestsInFlight is the maximum number of concurrent mutating requests. 0 means no limit.
+	MaxMutatingRequestsInFlight int
+	// RetryAfter is the delay after which the clients are asked to retry the requests rejected
+	// with 429 Too Many Requests or 503 Service Unavailable, rounded up to whole seconds.
+	RetryAfter time.Duration
+}
+
+// NewInFlightOptions returns the default InFlightOptions.
+func NewInFlightOptions() InFlightOptions {
+	return InFlightOptions{
+		MaxRequestsInFlight:         DefaultMaxRequestsInFlight,
+		MaxMutatingRequestsInFlight: DefaultMaxMutatingRequestsInFlight,
+		RetryAfter:                  DefaultRetryAfter,
+	}
+}
+
+// Validate returns an error if a limit is negative, or if the retry-after delay is shorter than a second.
+func (o InFlightOptions) Validate() error {
+	if o.MaxRequestsInFlight < 0 {
+		return errors.New("the maximum number of requests in flight must not be negative")
+	}
+	if o.MaxMutatingRequestsInFlight < 0 {
+		return errors.New("the maximum number of mutating requests in flight must not be negative")
+	}
+	if o.RetryAfter < time.Second {
+		return errors.New("the retry-after delay must be at least 1s, the Retry-After header is in whole seconds")
+	}
+
+	return nil
+}
+
+// withShedRequestLogging wraps the handler to log the requests rejected by the in-flight limits.
+// The limits are enforced by the default handler chain, which answers with 429 Too Many Requests.
+func withShedRequestLogging(handler http.Handler, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w}
+		handler.ServeHTTP(responsewriter.WrapForHTTP1Or2(recorder), req)
+
+		if recorder.status == http.StatusTooManyRequests {
+			logger.WarnContext(req.Context(), "Request shed, too many requests in flight",
+				"method", req.Method,
+				"path", req.URL.Path,
+				"remoteAddr", req.RemoteAddr,
+			)
+		}
+	})
+}
+
+// withRetryAfter wraps the handler to ask the clients to retry the requests rejected with 429 Too Many Requests
+// or 503 Service Unavailable after the retry-after delay, instead of the delay set by the default handler chain.
+// The delay is passed to the stores in the request context, so that the status of their errors agrees with the header.
+func withRetryAfter(handler http.Handler, retryAfter time.Duration) http.Handler {
+	retryAfterSeconds := strconv.FormatInt(int64((retryAfter+time.Second-1)/time.Second), 10)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writer := &retryAfterWriter{ResponseWriter: w, retryAfterSeconds: retryAfterSeconds}
+		handler.ServeHTTP(responsewriter.WrapForHTTP1Or2(writer), req.WithContext(storage.WithRetryAfter(req.Context(), retryAfter)))
+	})
+}
+
+// retryAfterWriter sets the Retry-After header of the 429 Too Many Requests and 503 Service Unavailable responses.
+type retryAfterWriter struct {
+	http.ResponseWriter
+	retryAfterSeconds string
+	wroteHeader       bool
+}
+
+// WriteHeader sets the Retry-After header of the rejected requests and writes the status code
+// to the underlying response writer.
+func (w *retryAfterWriter) WriteHeader(status int) {
+	if !w.wroteHeader && (status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable) {
+		w.Header().Set("Retry-After", w.retryAfterSeconds)
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap implements the responsewriter.UserProvidedDecorator interface.
+func (w *retryAfterWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusRecorder records the status code of the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code and writes it to the underlying response writer.
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap implements the responsewriter.UserProvidedDecorator interface.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// withSBOMExportLongRunning extends the long-running request check with the gets of the SBOM exports,
+// which stream an archive for as long as the SBOMs of the namespace take to read.
+func withSBOMExportLongRunning(longRunningFunc apirequest.LongRunningRequestCheck) apirequest.LongRunningRequestCheck {
+	return func(r *http.Request, requestInfo *apirequest.RequestInfo) bool {
+		if requestInfo.IsResourceRequest &&
+			requestInfo.APIGroup == v1alpha1.GroupName &&
+			requestInfo.Resource == "sbomexports" &&
+			requestInfo.Subresource == "" &&
+			requestInfo.Verb == "get" {
+			return true
+		}
+
+		return longRunningFunc != nil && longRunningFunc(r, requestInfo)
+	}
+}
@@ -0,0 +1,183 @@
+package apiserver
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+func TestInFlightOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       InFlightOptions
+		expectedError string
+	}{
+		{
+			name:    "defaults",
+			options: NewInFlightOptions(),
+		},
+		{
+			name:    "no limits",
+			options: InFlightOptions{RetryAfter: DefaultRetryAfter},
+		},
+		{
+			name: "negative limit",
+			options: InFlightOptions{
+				MaxRequestsInFlight: -1,
+				RetryAfter:          DefaultRetryAfter,
+			},
+			expectedError: "the maximum number of requests in flight must not be negative",
+		},
+		{
+			name: "negative mutating limit",
+			options: InFlightOptions{
+				MaxMutatingRequestsInFlight: -1,
+				RetryAfter:                  DefaultRetryAfter,
+			},
+			expectedError: "the maximum number of mutating requests in flight must not be negative",
+		},
+		{
+			name: "retry-after shorter than a second",
+			options: InFlightOptions{
+				RetryAfter: 500 * time.Millisecond,
+			},
+			expectedError: "the retry-after delay must be at least 1s",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.options.Validate()
+			if test.expectedError == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, test.expectedError)
+		})
+	}
+}
+
+func TestWithShedRequestLogging(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	handler := withShedRequestLogging(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// The wrapped response writer must keep supporting the streaming of the responses.
+		_, ok := w.(http.Flusher)
+		assert.True(t, ok)
+
+		if req.URL.Path == "/shed" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}), logger)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(path string) int {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, get("/served"))
+	assert.Empty(t, logs.String())
+
+	assert.Equal(t, http.StatusTooManyRequests, get("/shed"))
+	assert.Contains(t, logs.String(), "Request shed, too many requests in flight")
+	assert.Contains(t, logs.String(), "path=/shed")
+}
+
+func TestWithRetryAfter(t *testing.T) {
+	handler := withRetryAfter(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/shed":
+			// The delay set by the default handler chain is overridden.
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}), 2500*time.Millisecond)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(path string) *http.Response {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		return resp
+	}
+
+	resp := get("/shed")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "3", resp.Header.Get("Retry-After"))
+
+	resp = get("/unavailable")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "3", resp.Header.Get("Retry-After"))
+
+	resp = get("/served")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Retry-After"))
+}
+
+func TestWithSBOMExportLongRunning(t *testing.T) {
+	watchIsLongRunning := func(_ *http.Request, requestInfo *apirequest.RequestInfo) bool {
+		return requestInfo.Verb == "watch"
+	}
+	longRunningFunc := withSBOMExportLongRunning(watchIsLongRunning)
+
+	tests := []struct {
+		name                string
+		requestInfo         *apirequest.RequestInfo
+		expectedLongRunning bool
+	}{
+		{
+			name:                "get of an SBOM export",
+			requestInfo:         &apirequest.RequestInfo{IsResourceRequest: true, APIGroup: v1alpha1.GroupName, Resource: "sbomexports", Verb: "get"},
+			expectedLongRunning: true,
+		},
+		{
+			name:                "watch",
+			requestInfo:         &apirequest.RequestInfo{IsResourceRequest: true, APIGroup: v1alpha1.GroupName, Resource: "sboms", Verb: "watch"},
+			expectedLongRunning: true,
+		},
+		{
+			name:        "get of an SBOM",
+			requestInfo: &apirequest.RequestInfo{IsResourceRequest: true, APIGroup: v1alpha1.GroupName, Resource: "sboms", Verb: "get"},
+		},
+		{
+			name:        "SBOM exports of another group",
+			requestInfo: &apirequest.RequestInfo{IsResourceRequest: true, APIGroup: "example.com", Resource: "sbomexports", Verb: "get"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			assert.Equal(t, test.expectedLongRunning, longRunningFunc(req, test.requestInfo))
+		})
+	}
+}
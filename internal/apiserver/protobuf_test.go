@@ -0,0 +1,208 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/endpoints/handlers/negotiation"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+const protobufMediaType = runtime.ContentTypeProtobuf
+
+func testImageMetadata() v1alpha1.ImageMetadata {
+	return v1alpha1.ImageMetadata{
+		Registry:    "test-registry",
+		RegistryURI: "ghcr.io",
+		Repository:  "kubewarden/sbomscanner",
+		Tag:         "latest",
+		Platform:    "linux/amd64",
+		Digest:      "sha256:4b0a4b0b6a9e8f1b2c3d4e5f60718293a4b5c6d7e8f90123456789abcdef0123",
+	}
+}
+
+func testObjectMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:              name,
+		Namespace:         "default",
+		UID:               "3f1c8f3e-1d2b-4a5c-9e8f-7a6b5c4d3e2f",
+		ResourceVersion:   "42",
+		CreationTimestamp: metav1.NewTime(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)),
+		Labels:            map[string]string{"app.kubernetes.io/managed-by": "sbomscanner"},
+		Annotations:       map[string]string{v1alpha1.AnnotationSBOMScopeKey: "os"},
+	}
+}
+
+func testVulnerabilityReport() *v1alpha1.VulnerabilityReport {
+	updatedAt := metav1.NewTime(time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC))
+	expiresAt := metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	return &v1alpha1.VulnerabilityReport{
+		ObjectMeta:    testObjectMeta("test-report"),
+		ImageMetadata: testImageMetadata(),
+		Report: v1alpha1.Report{
+			Summary: v1alpha1.Summary{Critical: 1, High: 2, Suppressed: 1},
+			Results: []v1alpha1.Result{
+				{
+					Target: "alpine 3.20",
+					Class:  v1alpha1.ClassOSPackages,
+					Type:   "alpine",
+					Vulnerabilities: []v1alpha1.Vulnerability{
+						{
+							CVE:              "CVE-2024-0001",
+							Title:            "Heap overflow",
+							PackageName:      "musl",
+							PURL:             "pkg:apk/alpine/musl@1.2.5-r0",
+							InstalledVersion: "1.2.5-r0",
+							FixedVersions:    []string{"1.2.5-r1", "1.2.6-r0"},
+							DiffID:           "sha256:diff",
+							Severity:         "CRITICAL",
+							References:       []string{"https://nvd.nist.gov/vuln/detail/CVE-2024-0001"},
+							CVSS: map[string]v1alpha1.CVSS{
+								"nvd":    {V3Vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", V3Score: "9.8"},
+								"redhat": {V3Score: "8.1"},
+							},
+						},
+						{
+							CVE:              "CVE-2024-0002",
+							PURL:             "pkg:apk/alpine/busybox@1.36.1-r29",
+							InstalledVersion: "1.36.1-r29",
+							Severity:         "HIGH",
+							Suppressed:       true,
+							VEXStatus: &v1alpha1.VEXStatus{
+								Repository: "cve-allowlist",
+								Status:     "not_affected",
+								Statement:  "The vulnerable feature is disabled",
+								ExpiresAt:  &expiresAt,
+							},
+						},
+					},
+				},
+			},
+			VulnerabilityDB: &v1alpha1.VulnerabilityDB{
+				Source:        "public.ecr.aws/aquasecurity/trivy-db",
+				SchemaVersion: 2,
+				UpdatedAt:     updatedAt,
+				DownloadedAt:  updatedAt,
+			},
+			Evaluation: &v1alpha1.Evaluation{
+				Reevaluation:          true,
+				SBOMUID:               "1c2d3e4f-5a6b-7c8d-9e0f-a1b2c3d4e5f6",
+				SBOMCreationTimestamp: updatedAt,
+			},
+		},
+	}
+}
+
+// negotiate writes the object as the API server does for a request with the given Accept header.
+func negotiate(t *testing.T, accept string, obj runtime.Object) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/apis/storage.sbomscanner.kubewarden.io/v1alpha1/namespaces/default/test", nil)
+	req.Header.Set("Accept", accept)
+	recorder := httptest.NewRecorder()
+	responsewriters.WriteObjectNegotiated(Codecs, negotiation.DefaultEndpointRestrictions, v1alpha1.SchemeGroupVersion, recorder, req, http.StatusOK, obj, false)
+
+	return recorder
+}
+
+func TestProtobufNegotiation(t *testing.T) {
+	lastScannedAt := metav1.NewTime(time.Date(2025, 6, 2, 8, 30, 0, 0, time.UTC))
+	report := testVulnerabilityReport()
+
+	tests := []struct {
+		name string
+		obj  runtime.Object
+	}{
+		{
+			name: "image",
+			obj: &v1alpha1.Image{
+				ObjectMeta:    testObjectMeta("test-image"),
+				ImageMetadata: testImageMetadata(),
+				Layers: []v1alpha1.ImageLayer{
+					{Command: "QURE", Digest: "sha256:layer1", DiffID: "sha256:diff1"},
+					{Command: "UlVO", Digest: "sha256:layer2", DiffID: "sha256:diff2"},
+				},
+				Status: v1alpha1.ImageStatus{
+					LastScannedAt: &lastScannedAt,
+					LastError: &v1alpha1.ImageScanError{
+						Reason:     v1alpha1.ImageScanErrorReasonRateLimited,
+						Message:    "429 Too Many Requests",
+						OccurredAt: lastScannedAt,
+					},
+				},
+			},
+		},
+		{
+			name: "sbom",
+			obj: &v1alpha1.SBOM{
+				ObjectMeta:    testObjectMeta("test-sbom"),
+				ImageMetadata: testImageMetadata(),
+				SPDX:          runtime.RawExtension{Raw: []byte(`{"spdxVersion":"SPDX-2.3","packages":[{"name":"musl"}]}`)},
+				PackageCount:  1,
+			},
+		},
+		{
+			name: "vulnerability report",
+			obj:  report,
+		},
+		{
+			name: "vulnerability report list",
+			obj: &v1alpha1.VulnerabilityReportList{
+				ListMeta: metav1.ListMeta{ResourceVersion: "43", Continue: "token"},
+				Items:    []v1alpha1.VulnerabilityReport{*report, *report},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := negotiate(t, protobufMediaType, test.obj)
+			require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+			assert.Equal(t, protobufMediaType, response.Header().Get("Content-Type"))
+
+			decoded, gvk, err := Codecs.UniversalDeserializer().Decode(response.Body.Bytes(), nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, v1alpha1.SchemeGroupVersion.Group, gvk.Group)
+
+			expected := test.obj.DeepCopyObject()
+			expected.GetObjectKind().SetGroupVersionKind(*gvk)
+			assert.True(t, apiequality.Semantic.DeepEqual(expected, decoded), cmp.Diff(expected, decoded))
+		})
+	}
+}
+
+func TestProtobufNegotiationPayloadSize(t *testing.T) {
+	report := testVulnerabilityReport()
+
+	jsonResponse := negotiate(t, runtime.ContentTypeJSON, report)
+	require.Equal(t, http.StatusOK, jsonResponse.Code)
+	protobufResponse := negotiate(t, protobufMediaType, report)
+	require.Equal(t, http.StatusOK, protobufResponse.Code)
+
+	assert.Less(t, protobufResponse.Body.Len(), jsonResponse.Body.Len())
+}
+
+func TestProtobufUnknownFields(t *testing.T) {
+	data, err := testVulnerabilityReport().Marshal()
+	require.NoError(t, err)
+
+	// A field added by a newer version of the types is skipped.
+	data = append(data, 0xf8, 0x01, 0x2a) // field 31, varint 42
+
+	decoded := &v1alpha1.VulnerabilityReport{}
+	require.NoError(t, decoded.Unmarshal(data))
+	assert.True(t, apiequality.Semantic.DeepEqual(testVulnerabilityReport(), decoded), cmp.Diff(testVulnerabilityReport(), decoded))
+
+	require.Error(t, decoded.Unmarshal([]byte{0x0a, 0xff}), "a truncated message is rejected")
+}
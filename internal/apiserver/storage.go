@@ -4,21 +4,25 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/apiserver/pkg/endpoints/openapi"
 	"k8s.io/apiserver/pkg/registry/rest"
 	genericapiserver "k8s.io/apiserver/pkg/server"
 	"k8s.io/apiserver/pkg/server/dynamiccertificates"
 	"k8s.io/apiserver/pkg/server/healthz"
 	genericoptions "k8s.io/apiserver/pkg/server/options"
+	"k8s.io/apiserver/pkg/server/routes"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
-	basecompatibility "k8s.io/component-base/compatibility"
-	baseversion "k8s.io/component-base/version"
+	openapicommon "k8s.io/kube-openapi/pkg/common"
 
 	"github.com/kubewarden/sbomscanner/api/storage/install"
 	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
@@ -26,6 +30,19 @@ import (
 	storageopenapi "github.com/kubewarden/sbomscanner/pkg/generated/openapi"
 )
 
+// DefaultMaxRequestBodySize is the default maximum size of the body of the write requests.
+// It is larger than the Kubernetes default of 3MB, since the SBOMs of large images often exceed it.
+const DefaultMaxRequestBodySize = "32Mi"
+
+// OpenAPIOptions selects the OpenAPI endpoints served by the storage API server.
+// Both endpoints are served by default, since kubectl and the client generators rely on them.
+type OpenAPIOptions struct {
+	// DisableV2 disables the /openapi/v2 endpoint.
+	DisableV2 bool
+	// DisableV3 disables the /openapi/v3 endpoints.
+	DisableV3 bool
+}
+
 var (
 	Scheme = runtime.NewScheme()
 	Codecs = serializer.NewCodecFactory(Scheme)
@@ -51,18 +68,59 @@ type StorageAPIServer struct {
 	logger                    *slog.Logger
 	server                    *genericapiserver.GenericAPIServer
 	dynamicCertKeyPairContent *dynamiccertificates.DynamicCertKeyPairContent
+	certificateExpiryChecker  *CertificateExpiryChecker
+	// openAPIV2Config is set when the OpenAPI v2 endpoint must be installed by Start,
+	// see the OpenAPI v3 options in NewStorageAPIServer.
+	openAPIV2Config *openapicommon.Config
 }
 
-func NewStorageAPIServer(db *pgxpool.Pool, certFile, keyFile string, logger *slog.Logger) (*StorageAPIServer, error) {
+// StorageAPIServerOptions configures the storage API server.
+type StorageAPIServerOptions struct {
+	// CertFile and KeyFile are the paths of the serving certificate and key, reloaded when they change.
+	CertFile string
+	KeyFile  string
+	TLS      TLSOptions
+	CORS     CORSOptions
+	// MaxRequestBodyBytes is the maximum size of the body of the write requests.
+	MaxRequestBodyBytes int64
+	InFlight            InFlightOptions
+	OpenAPI             OpenAPIOptions
+	Audit               AuditOptions
+	// EnablePprof serves the pprof handlers under /debug/pprof.
+	EnablePprof bool
+	// CertificateExpiryChecker is the checker the serving certificate is added to.
+	CertificateExpiryChecker *CertificateExpiryChecker
+	ReadOnly                 *storage.ReadOnlyMode
+	Documents                storage.DocumentStorage
+	// RetainReportResults keeps the raw results of the VulnerabilityReports once normalized in the findings table.
+	RetainReportResults bool
+	// ImageStaleAfter is the age of the last vulnerability scan after which an image is reported as stale.
+	ImageStaleAfter time.Duration
+	// QueryCacheTTL is the duration the results of the aggregate queries are cached. 0 disables the cache.
+	QueryCacheTTL time.Duration
+	// SBOMPackageCountWarningThreshold is the number of packages above which the clients storing an SBOM are warned.
+	// 0 disables the warnings.
+	SBOMPackageCountWarningThreshold int
+	// MaxListResponseBytes is the maximum size of the lists returned without the limit parameter, and of the pages of the paginated lists. 0 means no limit.
+	MaxListResponseBytes int64
+	// KubeCompatVersion is the Kubernetes version emulated by the server, the newest supported one when nil.
+	KubeCompatVersion *version.Version
+}
+
+func NewStorageAPIServer(db *pgxpool.Pool, opts StorageAPIServerOptions, logger *slog.Logger) (*StorageAPIServer, error) {
 	// Setup dynamic certs
 	dynamicCertKeyPairContent, err := dynamiccertificates.NewDynamicServingContentFromFiles(
 		"storage-serving-certs",
-		certFile,
-		keyFile,
+		opts.CertFile,
+		opts.KeyFile,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error creating dynamic certificate content provider: %w", err)
 	}
+	opts.CertificateExpiryChecker.AddSource(ServingCertificate, func() ([]byte, error) {
+		cert, _ := dynamicCertKeyPairContent.CurrentCertKeyContent()
+		return cert, nil
+	})
 
 	// Setup recommended options with defaults
 	recommendedOptions := genericoptions.NewRecommendedOptions(
@@ -72,7 +130,16 @@ func NewStorageAPIServer(db *pgxpool.Pool, certFile, keyFile string, logger *slo
 	recommendedOptions.Etcd = nil
 	recommendedOptions.Admission = nil
 	recommendedOptions.Features.EnablePriorityAndFairness = false
+	// The pprof handlers are served under /debug/pprof behind the same authentication and authorization as the API,
+	// so the callers must be allowed to get the /debug/pprof/* non-resource URLs.
+	recommendedOptions.Features.EnableProfiling = opts.EnablePprof
 	recommendedOptions.SecureServing.ServerCert.GeneratedCert = dynamicCertKeyPairContent
+	recommendedOptions.SecureServing.MinTLSVersion = opts.TLS.MinVersion
+	recommendedOptions.SecureServing.CipherSuites = opts.TLS.CipherSuites
+	opts.Audit.applyTo(recommendedOptions.Audit)
+	if errs := recommendedOptions.Audit.Validate(); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid audit options: %w", utilerrors.NewAggregate(errs))
+	}
 
 	// Create server config
 	serverConfig := genericapiserver.NewRecommendedConfig(Codecs)
@@ -90,20 +157,51 @@ func NewStorageAPIServer(db *pgxpool.Pool, certFile, keyFile string, logger *slo
 	serverConfig.OpenAPIV3Config.Info.Title = "SBOM Scanner Storage"
 	serverConfig.OpenAPIV3Config.Info.Version = "v1alpha1"
 
+	if opts.OpenAPI.DisableV2 {
+		serverConfig.OpenAPIConfig = nil
+	}
+	var openAPIV2Config *openapicommon.Config
+	if opts.OpenAPI.DisableV3 {
+		// The OpenAPI v3 config cannot be unset, since the server-side apply models of the resources are built from it.
+		// The installation of the OpenAPI endpoints is skipped instead, and the v2 endpoint is installed by Start if enabled.
+		serverConfig.SkipOpenAPIInstallation = true
+		openAPIV2Config = serverConfig.OpenAPIConfig
+	}
+
 	// Disable WatchList for now
 	// TODO: remove this once we implement WatchList in the storage.
 	mutableFeatureGate := utilfeature.DefaultMutableFeatureGate
 	if err = mutableFeatureGate.Set("WatchList=false"); err != nil {
 		return nil, fmt.Errorf("failed to set feature gate: %w", err)
 	}
+	serverConfig.EffectiveVersion = newEffectiveVersion(opts.KubeCompatVersion)
+	// The features are aligned with the emulated Kubernetes version.
+	if err = mutableFeatureGate.SetEmulationVersion(serverConfig.EffectiveVersion.EmulationVersion()); err != nil {
+		return nil, fmt.Errorf("failed to set the feature gate emulation version: %w", err)
+	}
 	serverConfig.FeatureGate = mutableFeatureGate
-	serverConfig.EffectiveVersion = basecompatibility.NewEffectiveVersionFromString(
-		baseversion.DefaultKubeBinaryVersion,
-		"",
-		"",
-	)
 
 	serverConfig.RESTOptionsGetter = &RestOptionsGetter{}
+	// The write handlers stop reading the request body past this limit and answer with 413 Request Entity Too Large,
+	// so that an oversized document is never buffered or decoded.
+	serverConfig.MaxRequestBodyBytes = opts.MaxRequestBodyBytes
+
+	// The default handler chain enforces the in-flight limits, since the priority and fairness is disabled.
+	serverConfig.MaxRequestsInFlight = opts.InFlight.MaxRequestsInFlight
+	serverConfig.MaxMutatingRequestsInFlight = opts.InFlight.MaxMutatingRequestsInFlight
+	// The SBOM exports stream the archives of whole namespaces, so they are not bound by the request timeout
+	// and the in-flight limits, as the watches.
+	serverConfig.LongRunningFunc = withSBOMExportLongRunning(serverConfig.LongRunningFunc)
+
+	serverConfig.BuildHandlerChainFunc = func(apiHandler http.Handler, c *genericapiserver.Config) http.Handler {
+		handler := withShedRequestLogging(withRetryAfter(genericapiserver.DefaultBuildHandlerChain(apiHandler, c), opts.InFlight.RetryAfter), logger)
+		if opts.CORS.Enabled() {
+			// Wrap the whole handler chain, so that the preflight requests are answered before the authentication.
+			handler = withCORS(handler, opts.CORS)
+		}
+
+		return handler
+	}
 
 	if err := recommendedOptions.ApplyTo(serverConfig); err != nil {
 		return nil, fmt.Errorf("error applying options to server config: %w", err)
@@ -122,12 +220,15 @@ func NewStorageAPIServer(db *pgxpool.Pool, certFile, keyFile string, logger *slo
 	// Create API group and storage
 	apiGroupInfo := genericapiserver.NewDefaultAPIGroupInfo(v1alpha1.GroupName, Scheme, metav1.ParameterCodec, Codecs)
 
-	imageStore, err := storage.NewImageStore(Scheme, serverConfig.RESTOptionsGetter, db, logger)
+	// The aggregate queries are not cached when queryCacheTTL is 0.
+	queryCache := storage.NewQueryCache(opts.QueryCacheTTL)
+
+	imageStore, err := storage.NewImageStore(Scheme, serverConfig.RESTOptionsGetter, db, opts.ReadOnly, opts.ImageStaleAfter, queryCache, opts.MaxListResponseBytes, logger)
 	if err != nil {
 		return nil, fmt.Errorf("error creating Image store: %w", err)
 	}
 
-	sbomStore, err := storage.NewSBOMStore(Scheme, serverConfig.RESTOptionsGetter, db, logger)
+	sbomStore, err := storage.NewSBOMStore(Scheme, serverConfig.RESTOptionsGetter, db, opts.ReadOnly, opts.Documents, queryCache, opts.SBOMPackageCountWarningThreshold, opts.MaxListResponseBytes, logger)
 	if err != nil {
 		return nil, fmt.Errorf("error creating SBOM store: %w", err)
 	}
@@ -136,16 +237,47 @@ func NewStorageAPIServer(db *pgxpool.Pool, certFile, keyFile string, logger *slo
 		Scheme,
 		serverConfig.RESTOptionsGetter,
 		db,
+		opts.ReadOnly,
+		opts.Documents,
+		opts.RetainReportResults,
+		queryCache,
+		opts.MaxListResponseBytes,
 		logger,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error creating VulnerabilityReport store: %w", err)
 	}
 
+	imageStatusBatchStore, err := storage.NewImageStatusBatchStore(imageStore, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating ImageStatusBatch store: %w", err)
+	}
+
+	sbomExportStore := storage.NewSBOMExportStore(sbomStore, serverConfig.Authorization.Authorizer, logger)
+
+	clusterVulnerabilitySummaryStore := storage.NewClusterVulnerabilitySummaryStore(db, queryCache, logger)
+	cveImpactStore := storage.NewCVEImpactStore(db, queryCache, logger)
+	imagePackageStore := storage.NewImagePackageStore(db, queryCache, logger)
+	registryImageCountStore := storage.NewRegistryImageCountStore(db, queryCache, logger)
+
 	v1alpha1storage := map[string]rest.Storage{
-		"images":               imageStore,
-		"sboms":                sbomStore,
-		"vulnerabilityreports": vulnerabilityReportStore,
+		"images": storage.WithDiscovery(
+			imageStore,
+			[]string{"img"},
+			[]string{storage.CategoryAll, storage.CategorySBOMScanner},
+		),
+		"sboms": storage.WithDiscovery(sbomStore, nil, []string{storage.CategorySBOMScanner}),
+		"vulnerabilityreports": storage.WithDiscovery(
+			vulnerabilityReportStore,
+			[]string{"vulnreport", "vr"},
+			[]string{storage.CategorySBOMScanner},
+		),
+		"clustervulnerabilitysummaries": clusterVulnerabilitySummaryStore,
+		"cveimpacts":                    cveImpactStore,
+		"imagepackages":                 imagePackageStore,
+		"imagestatusbatches":            imageStatusBatchStore,
+		"registryimagecounts":           registryImageCountStore,
+		"sbomexports":                   sbomExportStore,
 	}
 	apiGroupInfo.VersionedResourcesStorageMap["v1alpha1"] = v1alpha1storage
 
@@ -158,6 +290,8 @@ func NewStorageAPIServer(db *pgxpool.Pool, certFile, keyFile string, logger *slo
 		logger:                    logger,
 		server:                    genericServer,
 		dynamicCertKeyPairContent: dynamicCertKeyPairContent,
+		certificateExpiryChecker:  opts.CertificateExpiryChecker,
+		openAPIV2Config:           openAPIV2Config,
 	}, nil
 }
 
@@ -167,7 +301,18 @@ func (s *StorageAPIServer) Start(ctx context.Context) error {
 	s.logger.DebugContext(ctx, "Starting dynamic certificate controller")
 	go s.dynamicCertKeyPairContent.Run(ctx, 1)
 
-	if err := s.server.PrepareRun().RunWithContext(ctx); err != nil {
+	s.logger.DebugContext(ctx, "Starting certificate expiry checker")
+	go s.certificateExpiryChecker.Start(ctx)
+
+	preparedServer := s.server.PrepareRun()
+	if s.openAPIV2Config != nil {
+		s.logger.DebugContext(ctx, "Installing OpenAPI v2 endpoint")
+		routes.OpenAPI{
+			Config: s.openAPIV2Config,
+		}.InstallV2(s.server.Handler.GoRestfulContainer, s.server.Handler.NonGoRestfulMux)
+	}
+
+	if err := preparedServer.RunWithContext(ctx); err != nil {
 		return fmt.Errorf("error running server: %w", err)
 	}
 
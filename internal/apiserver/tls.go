@@ -0,0 +1,50 @@
+package apiserver
+
+import (
+	"fmt"
+
+	cliflag "k8s.io/component-base/cli/flag"
+)
+
+// DefaultTLSMinVersion is the default minimum TLS version accepted by the API server.
+const DefaultTLSMinVersion = "VersionTLS12"
+
+// DefaultTLSCipherSuites are the cipher suites accepted by default by the API server.
+// Only ECDHE key exchanges with AEAD ciphers are allowed.
+// The TLS 1.3 cipher suites are not configurable and are always enabled.
+var DefaultTLSCipherSuites = []string{
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+}
+
+// TLSOptions constrains the TLS configuration of the API server secure serving.
+type TLSOptions struct {
+	// MinVersion is the minimum TLS version, e.g. "VersionTLS12".
+	MinVersion string
+	// CipherSuites are the names of the allowed cipher suites, as defined by crypto/tls.
+	CipherSuites []string
+}
+
+// NewTLSOptions returns the default TLSOptions.
+func NewTLSOptions() TLSOptions {
+	return TLSOptions{
+		MinVersion:   DefaultTLSMinVersion,
+		CipherSuites: append([]string{}, DefaultTLSCipherSuites...),
+	}
+}
+
+// Validate returns an error if the minimum version or any of the cipher suites is unknown.
+func (o TLSOptions) Validate() error {
+	if _, err := cliflag.TLSVersion(o.MinVersion); err != nil {
+		return fmt.Errorf("invalid TLS minimum version, allowed values are %v: %w", cliflag.TLSPossibleVersions(), err)
+	}
+	if _, err := cliflag.TLSCipherSuites(o.CipherSuites); err != nil {
+		return fmt.Errorf("invalid TLS cipher suites: %w", err)
+	}
+
+	return nil
+}
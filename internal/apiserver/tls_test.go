@@ -0,0 +1,52 @@
+package apiserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       TLSOptions
+		expectedError string
+	}{
+		{
+			name:    "defaults",
+			options: NewTLSOptions(),
+		},
+		{
+			name: "TLS 1.3",
+			options: TLSOptions{
+				MinVersion: "VersionTLS13",
+			},
+		},
+		{
+			name: "unknown version",
+			options: TLSOptions{
+				MinVersion: "VersionTLS14",
+			},
+			expectedError: "invalid TLS minimum version",
+		},
+		{
+			name: "unknown cipher suite",
+			options: TLSOptions{
+				MinVersion:   DefaultTLSMinVersion,
+				CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_UNKNOWN"},
+			},
+			expectedError: "invalid TLS cipher suites",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.options.Validate()
+			if test.expectedError == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, test.expectedError)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package apiserver
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/version"
+	basecompatibility "k8s.io/component-base/compatibility"
+	baseversion "k8s.io/component-base/version"
+)
+
+// maxKubeCompatVersionSkew is the number of the minor versions older than the Kubernetes libraries
+// the storage API server can be aligned with, as the Kubernetes control plane components.
+const maxKubeCompatVersionSkew = 3
+
+// KubeCompatVersionRange returns the oldest and the newest Kubernetes versions
+// the storage API server can be aligned with.
+func KubeCompatVersionRange() (*version.Version, *version.Version) {
+	binaryVersion := version.MustParse(baseversion.DefaultKubeBinaryVersion)
+
+	return binaryVersion.SubtractMinor(maxKubeCompatVersionSkew), binaryVersion
+}
+
+// ParseKubeCompatVersion parses a Kubernetes version, e.g. 1.32, and returns an error if it is out of the supported range.
+// The patch version, e.g. of the version reported by the cluster, is ignored.
+func ParseKubeCompatVersion(value string) (*version.Version, error) {
+	compatVersion, err := version.ParseMajorMinor(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Kubernetes compatibility version %q: %w", value, err)
+	}
+	compatVersion = version.MajorMinor(compatVersion.Major(), compatVersion.Minor())
+
+	minVersion, maxVersion := KubeCompatVersionRange()
+	if compatVersion.LessThan(minVersion) || compatVersion.GreaterThan(maxVersion) {
+		return nil, fmt.Errorf("unsupported Kubernetes compatibility version %s, must be between %s and %s",
+			compatVersion, minVersion, maxVersion)
+	}
+
+	return compatVersion, nil
+}
+
+// newEffectiveVersion returns the effective version of the storage API server.
+// The APIs and the features emulate the given Kubernetes version, or the version of the Kubernetes libraries when nil.
+func newEffectiveVersion(kubeCompatVersion *version.Version) basecompatibility.EffectiveVersion {
+	minVersion, _ := KubeCompatVersionRange()
+	effectiveVersion := basecompatibility.NewEffectiveVersionFromString(
+		baseversion.DefaultKubeBinaryVersion,
+		minVersion.String(),
+		"",
+	)
+	if kubeCompatVersion != nil {
+		effectiveVersion.SetEmulationVersion(kubeCompatVersion)
+	}
+
+	return effectiveVersion
+}
@@ -0,0 +1,83 @@
+package apiserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/version"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	baseversion "k8s.io/component-base/version"
+)
+
+func TestParseKubeCompatVersion(t *testing.T) {
+	minVersion, maxVersion := KubeCompatVersionRange()
+
+	tests := []struct {
+		name            string
+		value           string
+		expectedVersion *version.Version
+		expectedError   string
+	}{
+		{
+			name:            "oldest supported version",
+			value:           minVersion.String(),
+			expectedVersion: minVersion,
+		},
+		{
+			name:            "version of the Kubernetes libraries",
+			value:           baseversion.DefaultKubeBinaryVersion,
+			expectedVersion: maxVersion,
+		},
+		{
+			name:          "too old",
+			value:         minVersion.SubtractMinor(1).String(),
+			expectedError: "unsupported Kubernetes compatibility version",
+		},
+		{
+			name:          "too recent",
+			value:         maxVersion.AddMinor(1).String(),
+			expectedError: "unsupported Kubernetes compatibility version",
+		},
+		{
+			name:            "patch version ignored",
+			value:           minVersion.WithPatch(4).String(),
+			expectedVersion: minVersion,
+		},
+		{
+			name:          "malformed",
+			value:         "latest",
+			expectedError: "invalid Kubernetes compatibility version",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			compatVersion, err := ParseKubeCompatVersion(test.value)
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, test.expectedVersion.EqualTo(compatVersion))
+		})
+	}
+}
+
+func TestNewEffectiveVersion(t *testing.T) {
+	binaryVersion := version.MustParse(baseversion.DefaultKubeBinaryVersion)
+
+	effectiveVersion := newEffectiveVersion(nil)
+	assert.True(t, binaryVersion.EqualTo(effectiveVersion.EmulationVersion()))
+	assert.Empty(t, effectiveVersion.Validate())
+
+	minVersion, _ := KubeCompatVersionRange()
+	effectiveVersion = newEffectiveVersion(minVersion)
+	assert.True(t, binaryVersion.EqualTo(effectiveVersion.BinaryVersion()))
+	assert.True(t, minVersion.EqualTo(effectiveVersion.EmulationVersion()))
+	assert.Empty(t, effectiveVersion.Validate())
+
+	// The feature gates can emulate the oldest supported version.
+	featureGate := utilfeature.DefaultMutableFeatureGate.DeepCopy()
+	require.NoError(t, featureGate.SetEmulationVersion(minVersion))
+}
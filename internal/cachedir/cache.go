@@ -0,0 +1,210 @@
+package cachedir
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kubewarden/sbomscanner/internal/messaging"
+)
+
+// dirPermissions restricts the cache directory to the worker user,
+// since the downloaded layers can contain the content of private images.
+const dirPermissions = 0o700
+
+// trivyTempDirPrefix is the prefix of the per-process temporary directories created by Trivy,
+// where the image layers are downloaded.
+const trivyTempDirPrefix = "trivy-"
+
+// Cache is the directory where the image layers are downloaded.
+// When its size exceeds the maximum size, the least recently used files are evicted.
+type Cache struct {
+	dir     string
+	maxSize int64
+	logger  *slog.Logger
+}
+
+// New creates the cache directory with restricted permissions, and removes the temporary directories
+// left by the previous worker processes, which were interrupted before cleaning them up.
+// A maxSize of 0 disables the eviction.
+func New(dir string, maxSize int64, logger *slog.Logger) (*Cache, error) {
+	if err := os.MkdirAll(dir, dirPermissions); err != nil {
+		return nil, fmt.Errorf("cannot create cache directory %s: %w", dir, err)
+	}
+	// MkdirAll does not change the permissions of an existing directory.
+	if err := os.Chmod(dir, dirPermissions); err != nil {
+		return nil, fmt.Errorf("cannot set the permissions of cache directory %s: %w", dir, err)
+	}
+
+	cache := &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		logger:  logger.With("component", "cachedir"),
+	}
+
+	if err := cache.removeStaleTempDirs(); err != nil {
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+// Dir returns the path of the cache directory.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// removeStaleTempDirs removes the Trivy temporary directories of the previous worker processes.
+func (c *Cache) removeStaleTempDirs() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("cannot read cache directory %s: %w", c.dir, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), trivyTempDirPrefix) {
+			continue
+		}
+		path := filepath.Join(c.dir, entry.Name())
+		c.logger.Info("Removing stale temporary directory", "path", path)
+		if err = os.RemoveAll(path); err != nil {
+			return fmt.Errorf("cannot remove stale temporary directory %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// cachedFile is a file of the cache directory.
+type cachedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// files returns the regular files of the cache directory.
+func (c *Cache) files() ([]cachedFile, error) {
+	var files []cachedFile
+	err := filepath.WalkDir(c.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Files can be removed by a running scan while the directory is walked.
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return fmt.Errorf("cannot stat %s: %w", path, err)
+		}
+		files = append(files, cachedFile{path: path, size: info.Size(), modTime: info.ModTime()})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot walk cache directory %s: %w", c.dir, err)
+	}
+
+	return files, nil
+}
+
+// RemoveSince removes the files written since the given time,
+// i.e. the partial downloads left by a failed scan.
+func (c *Cache) RemoveSince(since time.Time) error {
+	files, err := c.files()
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.modTime.Before(since) {
+			continue
+		}
+		if err = os.Remove(file.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("cannot remove %s: %w", file.path, err)
+		}
+	}
+
+	return nil
+}
+
+// Evict removes the least recently used files until the size of the cache directory
+// is within the maximum size.
+func (c *Cache) Evict() error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+
+	files, err := c.files()
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	for _, file := range files {
+		size += file.size
+	}
+	if size <= c.maxSize {
+		return nil
+	}
+
+	slices.SortFunc(files, func(a, b cachedFile) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	for _, file := range files {
+		if size <= c.maxSize {
+			break
+		}
+		c.logger.Debug("Evicting file from the cache", "path", file.path, "size", file.size)
+		if err = os.Remove(file.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("cannot evict %s: %w", file.path, err)
+		}
+		size -= file.size
+	}
+
+	return nil
+}
+
+// WrapHandler returns a handler which cleans up the cache directory after the wrapped handler.
+// The files written by a failing handler are removed, then the cache is evicted.
+// The messages are handled one at a time, so that the files written since the message was received
+// all belong to it.
+func (c *Cache) WrapHandler(handler messaging.Handler) messaging.Handler {
+	return &cleanupHandler{cache: c, handler: handler}
+}
+
+type cleanupHandler struct {
+	cache   *Cache
+	handler messaging.Handler
+}
+
+func (h *cleanupHandler) Handle(ctx context.Context, message messaging.Message) error {
+	start := time.Now()
+
+	err := h.handler.Handle(ctx, message)
+	if err != nil {
+		if removeErr := h.cache.RemoveSince(start); removeErr != nil {
+			h.cache.logger.ErrorContext(ctx, "Failed to remove the files of the failed scan", "error", removeErr)
+		}
+	}
+
+	if evictErr := h.cache.Evict(); evictErr != nil {
+		h.cache.logger.ErrorContext(ctx, "Failed to evict the cache", "error", evictErr)
+	}
+
+	return err
+}
@@ -0,0 +1,122 @@
+package cachedir
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kubewarden/sbomscanner/internal/messaging"
+)
+
+func writeFile(t *testing.T, path string, size int, modTime time.Time) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestNew(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	writeFile(t, filepath.Join(dir, "trivy-1", "image-layers-123", "layer.tar"), 10, time.Now())
+	writeFile(t, filepath.Join(dir, "other", "file"), 10, time.Now())
+
+	cache, err := New(dir, 0, slog.Default())
+	require.NoError(t, err)
+	assert.Equal(t, dir, cache.Dir())
+
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(dirPermissions), info.Mode().Perm())
+
+	assert.NoDirExists(t, filepath.Join(dir, "trivy-1"))
+	assert.FileExists(t, filepath.Join(dir, "other", "file"))
+}
+
+func TestEvict(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	writeFile(t, filepath.Join(dir, "oldest"), 40, now.Add(-3*time.Hour))
+	writeFile(t, filepath.Join(dir, "sub", "older"), 40, now.Add(-2*time.Hour))
+	writeFile(t, filepath.Join(dir, "newest"), 40, now.Add(-time.Hour))
+
+	cache, err := New(dir, 100, slog.Default())
+	require.NoError(t, err)
+
+	require.NoError(t, cache.Evict())
+
+	assert.NoFileExists(t, filepath.Join(dir, "oldest"))
+	assert.FileExists(t, filepath.Join(dir, "sub", "older"))
+	assert.FileExists(t, filepath.Join(dir, "newest"))
+}
+
+func TestEvict_Disabled(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "file"), 40, time.Now())
+
+	cache, err := New(dir, 0, slog.Default())
+	require.NoError(t, err)
+
+	require.NoError(t, cache.Evict())
+	assert.FileExists(t, filepath.Join(dir, "file"))
+}
+
+type handlerFunc func(ctx context.Context, message messaging.Message) error
+
+func (f handlerFunc) Handle(ctx context.Context, message messaging.Message) error {
+	return f(ctx, message)
+}
+
+func TestWrapHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		handlerErr   error
+		expectRemove bool
+	}{
+		{
+			name:         "successful scan",
+			handlerErr:   nil,
+			expectRemove: false,
+		},
+		{
+			name:         "failed scan",
+			handlerErr:   errors.New("scan failed"),
+			expectRemove: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			previous := filepath.Join(dir, "previous")
+			writeFile(t, previous, 10, time.Now().Add(-time.Hour))
+
+			cache, err := New(dir, 0, slog.Default())
+			require.NoError(t, err)
+
+			partial := filepath.Join(dir, "trivy-1", "layer.tar")
+			handler := cache.WrapHandler(handlerFunc(func(context.Context, messaging.Message) error {
+				writeFile(t, partial, 10, time.Now().Add(time.Second))
+				return test.handlerErr
+			}))
+
+			err = handler.Handle(t.Context(), nil)
+			assert.Equal(t, test.handlerErr, err)
+
+			assert.FileExists(t, previous)
+			if test.expectRemove {
+				assert.NoFileExists(t, partial)
+			} else {
+				assert.FileExists(t, partial)
+			}
+		})
+	}
+}
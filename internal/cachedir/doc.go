@@ -0,0 +1,2 @@
+// Package cachedir manages the size-bounded directory where the worker downloads the image layers.
+package cachedir
@@ -2,21 +2,57 @@ package cmdutil
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"time"
 
 	"github.com/avast/retry-go/v4"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/rest"
 
 	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
 )
 
+// RetryConfig configures the retries while waiting for a dependency to be available.
+type RetryConfig struct {
+	// Attempts is the maximum number of attempts. 0 retries until the context is canceled.
+	Attempts uint
+	// Delay is the delay before the first retry, doubled after each attempt.
+	Delay time.Duration
+	// MaxDelay is the maximum delay between two attempts.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryConfig is the retry configuration used to wait for the dependencies.
+var DefaultRetryConfig = RetryConfig{
+	Attempts: 20,
+	Delay:    2 * time.Second,
+	MaxDelay: 10 * time.Second,
+}
+
+// Validate checks that the delays are positive.
+func (c RetryConfig) Validate() error {
+	if c.Delay <= 0 {
+		return errors.New("the retry delay must be greater than 0")
+	}
+	if c.MaxDelay < c.Delay {
+		return errors.New("the maximum retry delay must not be lower than the retry delay")
+	}
+
+	return nil
+}
+
 // WaitForStorageTypes waits until the storage types resources are available in the cluster.
-func WaitForStorageTypes(ctx context.Context, config *rest.Config, logger *slog.Logger) error {
+// On cold-start clusters the aggregated API can take a while to be registered,
+// so the retries can be tuned, or made to last until ctx is canceled.
+// The authentication and authorization errors are not retried.
+func WaitForStorageTypes(ctx context.Context, config *rest.Config, retryConfig RetryConfig, logger *slog.Logger) error {
 	httpClient, err := rest.HTTPClientFor(config)
 	if err != nil {
 		return fmt.Errorf("failed to create http client: %w", err)
@@ -33,15 +69,24 @@ func WaitForStorageTypes(ctx context.Context, config *rest.Config, logger *slog.
 			logger.Info("Checking for storage types availability", "groupVersion", gv)
 			_, err := discoveryClient.ServerResourcesForGroupVersion(gv)
 			if err != nil {
-				return fmt.Errorf("group version not available: %s: %w", gv, err)
+				err = fmt.Errorf("group version not available: %s: %w", gv, err)
+				if isAuthError(err) {
+					// Retrying does not help until the credentials or the RBAC rules are fixed.
+					return retry.Unrecoverable(err)
+				}
+				return err
 			}
 			return nil
 		},
-		retryOptions(ctx, func(n uint, err error) {
-			logger.InfoContext(ctx, "Checking for storage types failed, retrying", "attempt", n+1, "error", err)
+		retryOptions(ctx, retryConfig, func(n uint, err error) {
+			reason, code := discoveryErrorDetails(err)
+			logger.InfoContext(ctx, "Checking for storage types failed, retrying", "attempt", n+1, "reason", reason, "code", code, "error", err)
 		})...,
 	)
 	if err != nil {
+		if isAuthError(err) {
+			return fmt.Errorf("not allowed to discover the storage types, check the credentials and the RBAC rules: %w", err)
+		}
 		return fmt.Errorf("timeout while waiting for storage types: %w", err)
 	}
 
@@ -49,6 +94,26 @@ func WaitForStorageTypes(ctx context.Context, config *rest.Config, logger *slog.
 	return nil
 }
 
+// isAuthError returns true for the authentication and authorization errors of the API server.
+// The other discovery errors, e.g. NotFound while the API service is not registered yet,
+// or ServiceUnavailable while the storage is starting, are transient.
+func isAuthError(err error) bool {
+	return apierrors.IsUnauthorized(err) || apierrors.IsForbidden(err)
+}
+
+// discoveryErrorDetails returns the reason and the HTTP status code of a discovery error,
+// to tell apart e.g. the authorization errors from an API service that is not registered yet.
+// The reason is empty and the code is 0 when the API server was not reached.
+func discoveryErrorDetails(err error) (string, int32) {
+	var code int32
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		code = status.Status().Code
+	}
+
+	return string(apierrors.ReasonForError(err)), code
+}
+
 // WaitForJetStream waits until JetStream is available on the NATS server.
 func WaitForJetStream(ctx context.Context, url string, opts []nats.Option, logger *slog.Logger) error {
 	err := retry.Do(
@@ -72,7 +137,7 @@ func WaitForJetStream(ctx context.Context, url string, opts []nats.Option, logge
 
 			return nil
 		},
-		retryOptions(ctx, func(n uint, err error) {
+		retryOptions(ctx, DefaultRetryConfig, func(n uint, err error) {
 			logger.InfoContext(ctx, "Checking for JetStream failed, retrying", "attempt", n+1, "error", err)
 		})...,
 	)
@@ -84,6 +149,154 @@ func WaitForJetStream(ctx context.Context, url string, opts []nats.Option, logge
 	return nil
 }
 
+// WaitForStream waits until the JetStream stream has elected a leader and all of its replicas are current.
+// Streams of a NATS server without clustering are ready as soon as they exist.
+func WaitForStream(ctx context.Context, nc *nats.Conn, stream string, replicas int, logger *slog.Logger) error {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	err = retry.Do(
+		func() error {
+			logger.InfoContext(ctx, "Checking for stream replicas", "stream", stream, "replicas", replicas)
+			s, err := js.Stream(ctx, stream)
+			if err != nil {
+				return fmt.Errorf("failed to get stream %s: %w", stream, err)
+			}
+			info, err := s.Info(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get stream %s info: %w", stream, err)
+			}
+
+			return streamReady(info, replicas)
+		},
+		retryOptions(ctx, DefaultRetryConfig, func(n uint, err error) {
+			logger.InfoContext(ctx, "Checking for stream replicas failed, retrying", "stream", stream, "attempt", n+1, "error", err)
+		})...,
+	)
+	if err != nil {
+		return fmt.Errorf("timeout while waiting for stream %s: %w", stream, err)
+	}
+
+	logger.InfoContext(ctx, "Stream is available, continuing.", "stream", stream)
+	return nil
+}
+
+// streamReady returns an error if the stream has no leader or less than the given number of current replicas.
+func streamReady(info *jetstream.StreamInfo, replicas int) error {
+	if info.Cluster == nil {
+		if replicas > 1 {
+			return fmt.Errorf("stream %s has no cluster information, expected %d replicas", info.Config.Name, replicas)
+		}
+		return nil
+	}
+	if info.Cluster.Leader == "" {
+		return fmt.Errorf("stream %s has no leader", info.Config.Name)
+	}
+
+	// The leader is not listed in the replicas.
+	current := 1
+	for _, peer := range info.Cluster.Replicas {
+		if peer.Current && !peer.Offline {
+			current++
+		}
+	}
+	if current < replicas {
+		return fmt.Errorf("stream %s has %d current replicas, expected %d", info.Config.Name, current, replicas)
+	}
+
+	return nil
+}
+
+// JetStreamConsumer is a durable consumer of a JetStream stream.
+type JetStreamConsumer struct {
+	// Stream is the name of the consumed stream.
+	Stream string
+	// Config is the configuration of the consumer, identified by its durable name.
+	Config jetstream.ConsumerConfig
+}
+
+// JetStreamTopology is the desired configuration of the JetStream streams and of their durable consumers.
+type JetStreamTopology struct {
+	Streams   []jetstream.StreamConfig
+	Consumers []JetStreamConsumer
+}
+
+// jetStreamSetupAttemptTimeout bounds each request to create or update a stream or a consumer,
+// since the JetStream API does not answer while a replicated stream has no leader.
+const jetStreamSetupAttemptTimeout = 5 * time.Second
+
+// EnsureJetStream creates the streams and then the durable consumers of the topology,
+// or updates the existing ones to their desired configuration.
+// Running it again is safe, and converges to the same configuration.
+// The requests are retried while JetStream is unavailable, e.g. while a replicated stream elects its leader.
+// The rejected configurations, e.g. a change of the retention policy of an existing stream, are not retried.
+func EnsureJetStream(ctx context.Context, nc *nats.Conn, topology JetStreamTopology, retryConfig RetryConfig, logger *slog.Logger) error {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	for _, config := range topology.Streams {
+		err = retryJetStreamSetup(ctx, retryConfig, func(attemptCtx context.Context) error {
+			logger.InfoContext(ctx, "Ensuring stream", "stream", config.Name, "subjects", config.Subjects, "replicas", config.Replicas)
+			_, err := js.CreateOrUpdateStream(attemptCtx, config)
+			return err
+		}, func(n uint, err error) {
+			logger.InfoContext(ctx, "Ensuring stream failed, retrying", "stream", config.Name, "attempt", n+1, "error", err)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to ensure stream %s: %w", config.Name, err)
+		}
+	}
+
+	for _, consumer := range topology.Consumers {
+		err = retryJetStreamSetup(ctx, retryConfig, func(attemptCtx context.Context) error {
+			logger.InfoContext(ctx, "Ensuring consumer", "stream", consumer.Stream, "consumer", consumer.Config.Durable)
+			_, err := js.CreateOrUpdateConsumer(attemptCtx, consumer.Stream, consumer.Config)
+			return err
+		}, func(n uint, err error) {
+			logger.InfoContext(ctx, "Ensuring consumer failed, retrying", "stream", consumer.Stream, "consumer", consumer.Config.Durable, "attempt", n+1, "error", err)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to ensure consumer %s of stream %s: %w", consumer.Config.Durable, consumer.Stream, err)
+		}
+	}
+
+	logger.InfoContext(ctx, "JetStream streams and consumers are configured, continuing.")
+	return nil
+}
+
+// retryJetStreamSetup runs the setup request with a timeout per attempt, retrying it while JetStream is unavailable.
+func retryJetStreamSetup(ctx context.Context, retryConfig RetryConfig, setup func(context.Context) error, onRetry retry.OnRetryFunc) error {
+	options := append(retryOptions(ctx, retryConfig, onRetry), retry.RetryIf(isJetStreamUnavailable))
+
+	return retry.Do(
+		func() error {
+			attemptCtx, cancel := context.WithTimeout(ctx, jetStreamSetupAttemptTimeout)
+			defer cancel()
+
+			return setup(attemptCtx)
+		},
+		options...,
+	)
+}
+
+// isJetStreamUnavailable returns true if the error is transient.
+// The JetStream API answers with 503 Service Unavailable, or does not answer at all,
+// while JetStream is starting or a stream has no leader.
+func isJetStreamUnavailable(err error) bool {
+	var apiErr *jetstream.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusServiceUnavailable
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, nats.ErrTimeout) ||
+		errors.Is(err, nats.ErrNoResponders)
+}
+
 func WaitForPostgres(ctx context.Context, db *pgxpool.Pool, logger *slog.Logger) error {
 	err := retry.Do(
 		func() error {
@@ -94,7 +307,7 @@ func WaitForPostgres(ctx context.Context, db *pgxpool.Pool, logger *slog.Logger)
 			}
 			return nil
 		},
-		retryOptions(ctx, func(n uint, err error) {
+		retryOptions(ctx, DefaultRetryConfig, func(n uint, err error) {
 			logger.Info("Checking for Postgres failed, retrying", "attempt", n+1, "error", err)
 		})...,
 	)
@@ -106,13 +319,13 @@ func WaitForPostgres(ctx context.Context, db *pgxpool.Pool, logger *slog.Logger)
 	return nil
 }
 
-func retryOptions(ctx context.Context, onRetry retry.OnRetryFunc) []retry.Option {
+func retryOptions(ctx context.Context, config RetryConfig, onRetry retry.OnRetryFunc) []retry.Option {
 	return []retry.Option{
 		retry.Context(ctx),
-		retry.Attempts(20),
-		retry.Delay(2 * time.Second),
+		retry.Attempts(config.Attempts),
+		retry.Delay(config.Delay),
 		retry.DelayType(retry.BackOffDelay),
-		retry.MaxDelay(10 * time.Second),
+		retry.MaxDelay(config.MaxDelay),
 		retry.LastErrorOnly(true),
 		retry.OnRetry(onRetry),
 	}
@@ -0,0 +1,191 @@
+package cmdutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+func TestRetryConfigValidate(t *testing.T) {
+	require.NoError(t, DefaultRetryConfig.Validate())
+	require.NoError(t, RetryConfig{Attempts: 0, Delay: time.Second, MaxDelay: time.Second}.Validate())
+	require.ErrorContains(t, RetryConfig{Attempts: 1, MaxDelay: time.Second}.Validate(), "greater than 0")
+	require.ErrorContains(t, RetryConfig{Attempts: 1, Delay: time.Minute, MaxDelay: time.Second}.Validate(), "must not be lower")
+}
+
+func TestDiscoveryErrorDetails(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedReason string
+		expectedCode   int32
+	}{
+		{
+			name:           "not registered yet",
+			err:            apierrors.NewNotFound(schema.GroupResource{}, ""),
+			expectedReason: "NotFound",
+			expectedCode:   http.StatusNotFound,
+		},
+		{
+			name:           "forbidden",
+			err:            fmt.Errorf("wrapped: %w", apierrors.NewForbidden(schema.GroupResource{}, "", errors.New("denied"))),
+			expectedReason: "Forbidden",
+			expectedCode:   http.StatusForbidden,
+		},
+		{
+			name:           "API server not reached",
+			err:            errors.New("connection refused"),
+			expectedReason: "",
+			expectedCode:   0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reason, code := discoveryErrorDetails(test.err)
+			assert.Equal(t, test.expectedReason, reason)
+			assert.Equal(t, test.expectedCode, code)
+		})
+	}
+}
+
+func TestWaitForStorageTypes(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, nil)
+	}))
+	defer server.Close()
+
+	config := &rest.Config{Host: server.URL}
+	retryConfig := RetryConfig{Attempts: 3, Delay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	err := WaitForStorageTypes(t.Context(), config, retryConfig, slog.Default())
+	require.ErrorContains(t, err, "timeout while waiting for storage types")
+	assert.Equal(t, int32(3), requests.Load())
+
+	// Without a maximum number of attempts, the wait lasts until the context is canceled.
+	requests.Store(0)
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	retryConfig.Attempts = 0
+
+	err = WaitForStorageTypes(ctx, config, retryConfig, slog.Default())
+	require.Error(t, err)
+	assert.Greater(t, requests.Load(), int32(3))
+}
+
+func TestWaitForStorageTypes_AuthError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		expected   string
+	}{
+		{name: "unauthorized", statusCode: http.StatusUnauthorized, expected: "not allowed to discover the storage types"},
+		{name: "forbidden", statusCode: http.StatusForbidden, expected: "not allowed to discover the storage types"},
+		{name: "service unavailable", statusCode: http.StatusServiceUnavailable, expected: "timeout while waiting for storage types"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(test.statusCode)
+			}))
+			defer server.Close()
+
+			retryConfig := RetryConfig{Attempts: 3, Delay: time.Millisecond, MaxDelay: time.Millisecond}
+			err := WaitForStorageTypes(t.Context(), &rest.Config{Host: server.URL}, retryConfig, slog.Default())
+			require.ErrorContains(t, err, test.expected)
+			if test.statusCode == http.StatusServiceUnavailable {
+				assert.Equal(t, int32(3), requests.Load(), "transient errors are retried")
+			} else {
+				assert.Equal(t, int32(1), requests.Load(), "authentication and authorization errors are not retried")
+			}
+		})
+	}
+}
+
+func TestEnsureJetStream(t *testing.T) {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1 // Use a random port
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	ns := natstest.RunServer(&opts)
+	defer ns.Shutdown()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	retryConfig := RetryConfig{Attempts: 1, Delay: time.Millisecond, MaxDelay: time.Millisecond}
+	topology := JetStreamTopology{
+		Streams: []jetstream.StreamConfig{
+			{Name: "TEST", Retention: jetstream.WorkQueuePolicy, Subjects: []string{"test.>"}, Replicas: 1},
+		},
+		Consumers: []JetStreamConsumer{
+			{Stream: "TEST", Config: jetstream.ConsumerConfig{Durable: "worker", FilterSubjects: []string{"test.a"}, AckWait: time.Minute}},
+		},
+	}
+
+	// Running it again must be safe.
+	for range 2 {
+		require.NoError(t, EnsureJetStream(t.Context(), nc, topology, retryConfig, slog.Default()))
+	}
+
+	// The existing stream and consumer converge to the updated configuration.
+	topology.Streams[0].Subjects = []string{"test.>", "other.>"}
+	topology.Consumers[0].Config.FilterSubjects = []string{"test.a", "test.b"}
+	require.NoError(t, EnsureJetStream(t.Context(), nc, topology, retryConfig, slog.Default()))
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	stream, err := js.Stream(t.Context(), "TEST")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test.>", "other.>"}, stream.CachedInfo().Config.Subjects)
+	consumer, err := js.Consumer(t.Context(), "TEST", "worker")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test.a", "test.b"}, consumer.CachedInfo().Config.FilterSubjects)
+	assert.Equal(t, time.Minute, consumer.CachedInfo().Config.AckWait)
+}
+
+func TestEnsureJetStream_RejectedConfig(t *testing.T) {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1 // Use a random port
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	ns := natstest.RunServer(&opts)
+	defer ns.Shutdown()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	topology := JetStreamTopology{
+		Streams: []jetstream.StreamConfig{{Name: "TEST", Retention: jetstream.WorkQueuePolicy, Subjects: []string{"test.>"}}},
+	}
+	require.NoError(t, EnsureJetStream(t.Context(), nc, topology, DefaultRetryConfig, slog.Default()))
+
+	// The retention policy of a stream cannot be changed, the error is returned without retrying.
+	topology.Streams[0].Retention = jetstream.LimitsPolicy
+	start := time.Now()
+	err = EnsureJetStream(t.Context(), nc, topology, DefaultRetryConfig, slog.Default())
+	require.ErrorContains(t, err, "failed to ensure stream TEST")
+	assert.Less(t, time.Since(start), DefaultRetryConfig.Delay)
+}
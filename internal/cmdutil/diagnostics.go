@@ -0,0 +1,107 @@
+package cmdutil
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// preconditionTimeout is the maximum time allowed to check a single precondition.
+const preconditionTimeout = 5 * time.Second
+
+// Precondition is a named startup precondition, checked to diagnose a fatal error.
+type Precondition struct {
+	Name string
+	// DependsOn lists the names of the preconditions which must pass for this one to be checked.
+	DependsOn []string
+	Check     func(ctx context.Context) error
+}
+
+// PreconditionResult is the outcome of the check of a Precondition.
+type PreconditionResult struct {
+	Name    string
+	Skipped bool
+	Err     error
+}
+
+// Passed returns whether the precondition was checked successfully.
+func (r PreconditionResult) Passed() bool {
+	return !r.Skipped && r.Err == nil
+}
+
+// CheckPreconditions checks the preconditions in order.
+// The preconditions depending on a precondition which did not pass are skipped.
+// The checks are not interrupted by the cancellation of ctx, since they usually run while shutting down.
+func CheckPreconditions(ctx context.Context, preconditions []Precondition) []PreconditionResult {
+	ctx = context.WithoutCancel(ctx)
+	passed := make(map[string]bool, len(preconditions))
+	results := make([]PreconditionResult, 0, len(preconditions))
+
+	for _, precondition := range preconditions {
+		result := PreconditionResult{Name: precondition.Name}
+		if slices.ContainsFunc(precondition.DependsOn, func(name string) bool { return !passed[name] }) {
+			result.Skipped = true
+		} else {
+			checkCtx, cancel := context.WithTimeout(ctx, preconditionTimeout)
+			result.Err = precondition.Check(checkCtx)
+			cancel()
+		}
+		passed[precondition.Name] = result.Passed()
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// LogPreconditions logs a structured summary of the checked preconditions,
+// e.g. {"failed":["database"],"preconditions":{"database":{"status":"failed","error":"..."}}}.
+func LogPreconditions(ctx context.Context, logger *slog.Logger, results []PreconditionResult) {
+	failed := []string{}
+	attrs := make([]any, 0, len(results))
+	for _, result := range results {
+		switch {
+		case result.Skipped:
+			attrs = append(attrs, slog.Group(result.Name, "status", "skipped"))
+		case result.Err != nil:
+			failed = append(failed, result.Name)
+			attrs = append(attrs, slog.Group(result.Name, "status", "failed", "error", result.Err))
+		default:
+			attrs = append(attrs, slog.Group(result.Name, "status", "passed"))
+		}
+	}
+
+	logger.ErrorContext(ctx, "Startup diagnostics", "failed", failed, slog.Group("preconditions", attrs...))
+}
+
+// CheckCertificates verifies that the PEM encoded data contains certificates, all valid at the given time.
+func CheckCertificates(data []byte, now time.Time) error {
+	found := false
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("parsing certificate: %w", err)
+		}
+		if now.Before(certificate.NotBefore) {
+			return fmt.Errorf("certificate %q is not valid before %s", certificate.Subject, certificate.NotBefore.Format(time.RFC3339))
+		}
+		if now.After(certificate.NotAfter) {
+			return fmt.Errorf("certificate %q expired at %s", certificate.Subject, certificate.NotAfter.Format(time.RFC3339))
+		}
+		found = true
+	}
+
+	if !found {
+		return errors.New("no certificate found")
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package cmdutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateCertificatePEM(t *testing.T, notAfter time.Time) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	preconditions := []Precondition{
+		{
+			Name:  "certificate",
+			Check: func(context.Context) error { return nil },
+		},
+		{
+			Name:  "database",
+			Check: func(context.Context) error { return errors.New("connection refused") },
+		},
+		{
+			Name:      "migrations",
+			DependsOn: []string{"database"},
+			Check: func(context.Context) error {
+				t.Fatal("the migrations must not be checked when the database is not reachable")
+				return nil
+			},
+		},
+	}
+
+	results := CheckPreconditions(t.Context(), preconditions)
+	require.Len(t, results, 3)
+	assert.True(t, results[0].Passed())
+	assert.False(t, results[1].Passed())
+	assert.EqualError(t, results[1].Err, "connection refused")
+	assert.False(t, results[2].Passed())
+	assert.True(t, results[2].Skipped)
+
+	var logs bytes.Buffer
+	LogPreconditions(t.Context(), slog.New(slog.NewJSONHandler(&logs, nil)), results)
+
+	assert.Contains(t, logs.String(), `"msg":"Startup diagnostics"`)
+	assert.Contains(t, logs.String(), `"failed":["database"]`)
+	assert.Contains(t, logs.String(), `"preconditions":{`+
+		`"certificate":{"status":"passed"},`+
+		`"database":{"status":"failed","error":"connection refused"},`+
+		`"migrations":{"status":"skipped"}}`)
+}
+
+func TestCheckCertificates(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	valid := generateCertificatePEM(t, now.Add(24*time.Hour))
+	expired := generateCertificatePEM(t, now.Add(-time.Hour))
+
+	require.NoError(t, CheckCertificates(valid, now))
+	require.ErrorContains(t, CheckCertificates(append(valid, expired...), now), "expired")
+	require.ErrorContains(t, CheckCertificates(valid, now.Add(-48*time.Hour)), "not valid before")
+	require.EqualError(t, CheckCertificates([]byte("not a certificate"), now), "no certificate found")
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+const retentionInterval = 10 * time.Minute
+
+// RetentionRunner periodically enforces the retention policy of the registries,
+// by pruning the images removed from the registry, together with their SBOMs and VulnerabilityReports.
+type RetentionRunner struct {
+	client.Client
+}
+
+// Start implements the Runnable interface.
+func (r *RetentionRunner) Start(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	log.Info("Starting retention runner")
+
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Stopping retention runner")
+
+			return nil
+		case <-ticker.C:
+			if err := r.enforceRetention(ctx, time.Now()); err != nil {
+				log.Error(err, "Failed to enforce retention policies")
+			}
+		}
+	}
+}
+
+// enforceRetention prunes the images exceeding the retention policy of every registry.
+func (r *RetentionRunner) enforceRetention(ctx context.Context, now time.Time) error {
+	log := log.FromContext(ctx)
+
+	var registries v1alpha1.RegistryList
+	if err := r.List(ctx, &registries); err != nil {
+		return fmt.Errorf("failed to list registries: %w", err)
+	}
+
+	for _, registry := range registries.Items {
+		if registry.Spec.Retention == nil {
+			continue
+		}
+
+		if err := r.enforceRegistryRetention(ctx, &registry, now); err != nil {
+			log.Error(err, "Failed to enforce retention policy", "registry", registry.Name, "namespace", registry.Namespace)
+
+			continue
+		}
+	}
+
+	return nil
+}
+
+// enforceRegistryRetention prunes the images of the registry exceeding its retention policy.
+func (r *RetentionRunner) enforceRegistryRetention(ctx context.Context, registry *v1alpha1.Registry, now time.Time) error {
+	log := log.FromContext(ctx)
+
+	var images storagev1alpha1.ImageList
+	listOpts := []client.ListOption{
+		client.InNamespace(registry.Namespace),
+		client.MatchingFields{storagev1alpha1.IndexImageMetadataRegistry: registry.Name},
+	}
+	if err := r.List(ctx, &images, listOpts...); err != nil {
+		return fmt.Errorf("failed to list images: %w", err)
+	}
+
+	for _, image := range imagesToPrune(images.Items, registry.Spec.Retention, now) {
+		// The SBOM and the VulnerabilityReport are garbage collected with the image.
+		if err := r.Delete(ctx, &image); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete image %s: %w", image.Name, err)
+		}
+
+		metadata := image.GetImageMetadata()
+		log.Info("Pruned image removed from the registry",
+			"registry", registry.Name,
+			"namespace", registry.Namespace,
+			"image", image.Name,
+			"repository", metadata.Repository,
+			"tag", metadata.Tag,
+			"platform", metadata.Platform,
+			"digest", metadata.Digest,
+			"removedAt", image.Annotations[storagev1alpha1.AnnotationImageRemovedAtKey])
+	}
+
+	return nil
+}
+
+// imagesToPrune returns the images removed from the registry exceeding the retention policy.
+// The images are grouped by repository, tag and platform, and the most recent image of every group,
+// either still in the registry or the last one removed, is always kept.
+func imagesToPrune(images []storagev1alpha1.Image, policy *v1alpha1.RetentionPolicy, now time.Time) []storagev1alpha1.Image {
+	type removedImage struct {
+		image     storagev1alpha1.Image
+		removedAt time.Time
+	}
+
+	removedImages := map[string][]removedImage{}
+	for _, image := range images {
+		value, ok := image.Annotations[storagev1alpha1.AnnotationImageRemovedAtKey]
+		if !ok {
+			continue
+		}
+		removedAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			// Do not prune the images with an invalid annotation.
+			continue
+		}
+
+		metadata := image.GetImageMetadata()
+		key := metadata.Repository + ":" + metadata.Tag + "@" + metadata.Platform
+		removedImages[key] = append(removedImages[key], removedImage{image: image, removedAt: removedAt})
+	}
+
+	var prune []storagev1alpha1.Image
+	for _, group := range removedImages {
+		// Sort by removal time (most recent first)
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].removedAt.After(group[j].removedAt)
+		})
+
+		// The most recent removed image is only subject to the policy
+		// when a newer image with the same repository, tag and platform is still in the registry.
+		for i, removed := range group {
+			if i == 0 && !hasCurrentImage(images, removed.image) {
+				continue
+			}
+
+			exceedsKeepLast := policy.KeepLast > 0 && i >= policy.KeepLast
+			exceedsMaxAge := policy.MaxAge != nil && now.Sub(removed.removedAt) > policy.MaxAge.Duration
+			if exceedsKeepLast || exceedsMaxAge {
+				prune = append(prune, removed.image)
+			}
+		}
+	}
+
+	return prune
+}
+
+// hasCurrentImage returns true when an image with the same repository, tag and platform is still in the registry.
+func hasCurrentImage(images []storagev1alpha1.Image, removed storagev1alpha1.Image) bool {
+	removedMetadata := removed.GetImageMetadata()
+	for _, image := range images {
+		if _, ok := image.Annotations[storagev1alpha1.AnnotationImageRemovedAtKey]; ok {
+			continue
+		}
+
+		metadata := image.GetImageMetadata()
+		if metadata.Repository == removedMetadata.Repository &&
+			metadata.Tag == removedMetadata.Tag &&
+			metadata.Platform == removedMetadata.Platform {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NeedLeaderElection implements the LeaderElectionRunnable interface.
+func (r *RetentionRunner) NeedLeaderElection() bool {
+	return true
+}
+
+func (r *RetentionRunner) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.Add(r); err != nil {
+		return fmt.Errorf("failed to create RetentionRunner: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+var _ = Describe("RetentionRunner", func() {
+	When("A registry has a retention policy", func() {
+		var (
+			registry v1alpha1.Registry
+			now      time.Time
+		)
+
+		createImage := func(ctx context.Context, tag, digest string, removedAt *time.Time) {
+			image := storagev1alpha1.Image{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      digest,
+					Namespace: "default",
+				},
+				ImageMetadata: storagev1alpha1.ImageMetadata{
+					Registry:   registry.Name,
+					Repository: "sbomscanner",
+					Tag:        tag,
+					Digest:     "sha256:" + digest,
+					Platform:   "linux/amd64",
+				},
+			}
+			if removedAt != nil {
+				image.Annotations = map[string]string{
+					storagev1alpha1.AnnotationImageRemovedAtKey: removedAt.Format(time.RFC3339),
+				}
+			}
+			Expect(k8sClient.Create(ctx, &image)).To(Succeed())
+		}
+
+		BeforeEach(func(ctx context.Context) {
+			now = time.Now()
+
+			By("Creating a Registry keeping the last removed image for 1 day")
+			registry = v1alpha1.Registry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      uuid.New().String(),
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RegistrySpec{
+					URI: "ghcr.io/kubewarden",
+					Retention: &v1alpha1.RetentionPolicy{
+						KeepLast: 1,
+						MaxAge:   &metav1.Duration{Duration: 24 * time.Hour},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, &registry)).To(Succeed())
+
+			By("Creating the current image and two removed images of the latest tag")
+			createImage(ctx, "latest", "current-"+registry.Name, nil)
+			createImage(ctx, "latest", "removed-1h-"+registry.Name, ptr.To(now.Add(-time.Hour)))
+			createImage(ctx, "latest", "removed-2h-"+registry.Name, ptr.To(now.Add(-2*time.Hour)))
+
+			By("Creating an image of a deleted tag, removed 2 days ago")
+			createImage(ctx, "v1", "removed-48h-"+registry.Name, ptr.To(now.Add(-48*time.Hour)))
+		})
+
+		It("Should prune the removed images exceeding the policy and keep the most recent ones", func(ctx context.Context) {
+			runner := &RetentionRunner{
+				Client: k8sClient,
+			}
+			Expect(runner.enforceRetention(ctx, now)).To(Succeed())
+
+			var images storagev1alpha1.ImageList
+			Expect(k8sClient.List(ctx, &images, &client.ListOptions{
+				Namespace:     "default",
+				FieldSelector: fields.SelectorFromSet(fields.Set{storagev1alpha1.IndexImageMetadataRegistry: registry.Name}),
+			})).To(Succeed())
+
+			names := []string{}
+			for _, image := range images.Items {
+				names = append(names, image.Name)
+			}
+			Expect(names).To(ConsistOf(
+				"current-"+registry.Name,
+				"removed-1h-"+registry.Name,
+				"removed-48h-"+registry.Name,
+			))
+		})
+	})
+})
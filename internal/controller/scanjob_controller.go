@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -17,11 +20,15 @@ import (
 	"github.com/kubewarden/sbomscanner/api/v1alpha1"
 	"github.com/kubewarden/sbomscanner/internal/handlers"
 	"github.com/kubewarden/sbomscanner/internal/messaging"
+	"github.com/kubewarden/sbomscanner/internal/scanwindow"
 )
 
 const (
 	maxConcurrentReconciles = 10
 	scanJobsHistoryLimit    = 10
+	// scanWindowRecheckInterval is the maximum delay before a deferred ScanJob is reconciled again,
+	// so that the changes of the scan windows of its registry are applied.
+	scanWindowRecheckInterval = 5 * time.Minute
 )
 
 // ScanJobReconciler reconciles a ScanJob object
@@ -29,11 +36,13 @@ type ScanJobReconciler struct {
 	client.Client
 	Scheme    *runtime.Scheme
 	Publisher messaging.Publisher
+	Recorder  record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=sbomscanner.kubewarden.io,resources=scanjobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=sbomscanner.kubewarden.io,resources=scanjobs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=sbomscanner.kubewarden.io,resources=scanjobs/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile reconciles a ScanJob object.
 func (r *ScanJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -59,7 +68,11 @@ func (r *ScanJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, nil
 	}
 
-	scanJob.InitializeConditions()
+	// The conditions of a deferred ScanJob are kept, so that its status is left unchanged while it stays deferred,
+	// instead of triggering a new reconciliation on each update.
+	if !scanJob.IsDeferred() {
+		scanJob.InitializeConditions()
+	}
 
 	reconcileResult, reconcileErr := r.reconcileScanJob(ctx, scanJob)
 
@@ -86,6 +99,7 @@ func (r *ScanJobReconciler) reconcileScanJob(ctx context.Context, scanJob *v1alp
 		if errors.IsNotFound(err) {
 			log.Error(err, "Registry not found", "registry", scanJob.Spec.Registry)
 			scanJob.MarkFailed(v1alpha1.ReasonRegistryNotFound, fmt.Sprintf("Registry %s not found", scanJob.Spec.Registry))
+			r.Recorder.Eventf(scanJob, corev1.EventTypeWarning, v1alpha1.EventReasonScanFailed, "Registry %s not found", scanJob.Spec.Registry)
 
 			return ctrl.Result{}, nil
 		}
@@ -122,6 +136,53 @@ func (r *ScanJobReconciler) reconcileScanJob(ctx context.Context, scanJob *v1alp
 		return ctrl.Result{}, nil
 	}
 
+	// The rescans do not pull anything from the registry, so they are not restricted to its scan windows.
+	if !scanJob.Spec.Rescan {
+		open, opensAt, err := scanwindow.Check(registry.Spec.ScanWindows, time.Now())
+		if err != nil {
+			log.Error(err, "Invalid scan windows", "registry", registry.Name)
+			scanJob.MarkFailed(v1alpha1.ReasonInternalError, fmt.Sprintf("Invalid scan windows of the registry %s: %v", registry.Name, err))
+			r.Recorder.Eventf(registry, corev1.EventTypeWarning, v1alpha1.EventReasonScanFailed, "ScanJob %s failed: %v", scanJob.Name, err)
+
+			return ctrl.Result{}, nil
+		}
+		if !open {
+			return r.deferScanJob(ctx, scanJob, registry, opensAt), nil
+		}
+	}
+
+	if scanJob.Spec.Rescan {
+		if err := r.publishRescanSBOMs(ctx, scanJob); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if err := r.publishCreateCatalog(ctx, scanJob); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	scanJob.MarkScheduled(v1alpha1.ReasonScheduled, "ScanJob has been scheduled for processing by the controller")
+	r.Recorder.Eventf(registry, corev1.EventTypeNormal, v1alpha1.EventReasonScanStarted, "ScanJob %s started", scanJob.Name)
+
+	return ctrl.Result{}, nil
+}
+
+// deferScanJob keeps the ScanJob pending until the next scan window of the registry opens.
+func (r *ScanJobReconciler) deferScanJob(ctx context.Context, scanJob *v1alpha1.ScanJob, registry *v1alpha1.Registry, opensAt time.Time) ctrl.Result {
+	log := logf.FromContext(ctx)
+
+	message := fmt.Sprintf("ScanJob is deferred until the scan window of the registry opens at %s", opensAt.UTC().Format(time.RFC3339))
+	if !scanJob.IsDeferred() {
+		log.Info("ScanJob deferred, the registry is outside of its scan windows", "scanJob", scanJob.Name, "namespace", scanJob.Namespace, "opensAt", opensAt)
+		r.Recorder.Eventf(registry, corev1.EventTypeNormal, v1alpha1.EventReasonScanDeferred, "ScanJob %s deferred until %s", scanJob.Name, opensAt.UTC().Format(time.RFC3339))
+	}
+	scanJob.MarkDeferred(v1alpha1.ReasonOutsideScanWindow, message)
+
+	return ctrl.Result{RequeueAfter: min(time.Until(opensAt), scanWindowRecheckInterval)}
+}
+
+// publishCreateCatalog requests the discovery of the images of the registry, followed by their scan.
+func (r *ScanJobReconciler) publishCreateCatalog(ctx context.Context, scanJob *v1alpha1.ScanJob) error {
+	log := logf.FromContext(ctx)
+
 	log.V(1).Info("Publishing CreateCatalog message for ScanJob", "scanJob", scanJob.Name, "namespace", scanJob.Namespace, "registry", scanJob.Spec.Registry)
 	messageID := fmt.Sprintf("createCatalog/%s", scanJob.GetUID())
 	message, err := json.Marshal(&handlers.CreateCatalogMessage{
@@ -134,16 +195,40 @@ func (r *ScanJobReconciler) reconcileScanJob(ctx context.Context, scanJob *v1alp
 		},
 	})
 	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("unable to marshal CreateCatalog message: %w", err)
+		return fmt.Errorf("unable to marshal CreateCatalog message: %w", err)
 	}
 
-	if err := r.Publisher.Publish(ctx, handlers.CreateCatalogSubject, messageID, message); err != nil {
-		return ctrl.Result{}, fmt.Errorf("unable to publish CreateSBOM message: %w", err)
+	if err = r.Publisher.Publish(ctx, handlers.CreateCatalogSubject, messageID, message); err != nil {
+		return fmt.Errorf("unable to publish CreateSBOM message: %w", err)
 	}
 
-	scanJob.MarkScheduled(v1alpha1.ReasonScheduled, "ScanJob has been scheduled for processing by the controller")
+	return nil
+}
 
-	return ctrl.Result{}, nil
+// publishRescanSBOMs requests the scan of the SBOMs already stored for the images of the registry.
+func (r *ScanJobReconciler) publishRescanSBOMs(ctx context.Context, scanJob *v1alpha1.ScanJob) error {
+	log := logf.FromContext(ctx)
+
+	log.V(1).Info("Publishing RescanSBOMs message for ScanJob", "scanJob", scanJob.Name, "namespace", scanJob.Namespace, "registry", scanJob.Spec.Registry)
+	messageID := fmt.Sprintf("rescanSBOMs/%s", scanJob.GetUID())
+	message, err := json.Marshal(&handlers.RescanSBOMsMessage{
+		BaseMessage: handlers.BaseMessage{
+			ScanJob: handlers.ObjectRef{
+				Name:      scanJob.Name,
+				Namespace: scanJob.Namespace,
+				UID:       string(scanJob.GetUID()),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to marshal RescanSBOMs message: %w", err)
+	}
+
+	if err = r.Publisher.Publish(ctx, handlers.RescanSBOMsSubject, messageID, message); err != nil {
+		return fmt.Errorf("unable to publish RescanSBOMs message: %w", err)
+	}
+
+	return nil
 }
 
 // cleanupOldScanJobs ensures we don't have more than scanJobsHistoryLimit for any registry
@@ -13,6 +13,7 @@ import (
 	"github.com/google/uuid"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -27,14 +28,17 @@ var _ = Describe("ScanJob Controller", func() {
 		var scanJob v1alpha1.ScanJob
 		var registry v1alpha1.Registry
 		var mockPublisher *messagingMocks.MockPublisher
+		var recorder *record.FakeRecorder
 
 		BeforeEach(func(ctx context.Context) {
 			By("Creating a new ScanJobReconciler")
 			mockPublisher = messagingMocks.NewMockPublisher(GinkgoT())
+			recorder = record.NewFakeRecorder(10)
 			reconciler = ScanJobReconciler{
 				Client:    k8sClient,
 				Publisher: mockPublisher,
 				Scheme:    k8sClient.Scheme(),
+				Recorder:  recorder,
 			}
 
 			By("Creating a Registry")
@@ -125,6 +129,163 @@ var _ = Describe("ScanJob Controller", func() {
 			}, &scanJob)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(scanJob.IsScheduled()).To(BeTrue())
+
+			By("Verifying the ScanStarted event is recorded")
+			Expect(recorder.Events).To(Receive(Equal(fmt.Sprintf("Normal %s ScanJob %s started", v1alpha1.EventReasonScanStarted, scanJob.Name))))
+		})
+	})
+
+	When("A ScanJob requests a rescan", func() {
+		var reconciler ScanJobReconciler
+		var scanJob v1alpha1.ScanJob
+		var mockPublisher *messagingMocks.MockPublisher
+
+		BeforeEach(func(ctx context.Context) {
+			By("Creating a new ScanJobReconciler")
+			mockPublisher = messagingMocks.NewMockPublisher(GinkgoT())
+			reconciler = ScanJobReconciler{
+				Client:    k8sClient,
+				Publisher: mockPublisher,
+				Scheme:    k8sClient.Scheme(),
+				Recorder:  record.NewFakeRecorder(10),
+			}
+
+			By("Creating a Registry")
+			registry := v1alpha1.Registry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-registry-rescan",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RegistrySpec{
+					URI: "https://registry.example.com",
+				},
+			}
+			Expect(k8sClient.Create(ctx, &registry)).To(Succeed())
+
+			By("Creating a ScanJob")
+			scanJob = v1alpha1.ScanJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      uuid.New().String(),
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ScanJobSpec{
+					Registry: registry.Name,
+					Rescan:   true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, &scanJob)).To(Succeed())
+		})
+
+		It("should publish a RescanSBOMs message instead of a CreateCatalog message", func(ctx context.Context) {
+			By("Setting up the expected message publication")
+			message, err := json.Marshal(&handlers.RescanSBOMsMessage{
+				BaseMessage: handlers.BaseMessage{
+					ScanJob: handlers.ObjectRef{
+						Name:      scanJob.Name,
+						Namespace: scanJob.Namespace,
+						UID:       string(scanJob.GetUID()),
+					},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			mockPublisher.On("Publish", mock.Anything, handlers.RescanSBOMsSubject, fmt.Sprintf("rescanSBOMs/%s", scanJob.GetUID()), message).Return(nil)
+
+			By("Reconciling the ScanJob twice, to store the registry data and then publish the message")
+			for range 2 {
+				_, err = reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name:      scanJob.Name,
+						Namespace: scanJob.Namespace,
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			By("Verifying the ScanJob is marked as scheduled")
+			err = k8sClient.Get(ctx, types.NamespacedName{
+				Name:      scanJob.Name,
+				Namespace: scanJob.Namespace,
+			}, &scanJob)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(scanJob.IsScheduled()).To(BeTrue())
+		})
+	})
+
+	When("A ScanJob is created outside of the scan windows of its Registry", func() {
+		var reconciler ScanJobReconciler
+		var scanJob v1alpha1.ScanJob
+		var mockPublisher *messagingMocks.MockPublisher
+		var recorder *record.FakeRecorder
+
+		BeforeEach(func(ctx context.Context) {
+			By("Creating a new ScanJobReconciler")
+			mockPublisher = messagingMocks.NewMockPublisher(GinkgoT())
+			recorder = record.NewFakeRecorder(10)
+			reconciler = ScanJobReconciler{
+				Client:    k8sClient,
+				Publisher: mockPublisher,
+				Scheme:    k8sClient.Scheme(),
+				Recorder:  recorder,
+			}
+
+			By("Creating a Registry whose scan window opens in two hours")
+			now := time.Now().UTC()
+			registry := v1alpha1.Registry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-registry-scan-window",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RegistrySpec{
+					URI: "https://registry.example.com",
+					ScanWindows: []v1alpha1.ScanWindow{
+						{
+							Start: now.Add(2 * time.Hour).Format("15:04"),
+							End:   now.Add(3 * time.Hour).Format("15:04"),
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, &registry)).To(Succeed())
+
+			By("Creating a ScanJob")
+			scanJob = v1alpha1.ScanJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      uuid.New().String(),
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ScanJobSpec{
+					Registry: registry.Name,
+				},
+			}
+			Expect(k8sClient.Create(ctx, &scanJob)).To(Succeed())
+		})
+
+		It("should defer the ScanJob without publishing any message", func(ctx context.Context) {
+			By("Reconciling the ScanJob twice, to store the registry data and then check the scan windows")
+			var result reconcile.Result
+			for range 2 {
+				var err error
+				result, err = reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name:      scanJob.Name,
+						Namespace: scanJob.Namespace,
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(result.RequeueAfter).To(Equal(scanWindowRecheckInterval))
+
+			By("Verifying the ScanJob is deferred and still pending")
+			err := k8sClient.Get(ctx, types.NamespacedName{
+				Name:      scanJob.Name,
+				Namespace: scanJob.Namespace,
+			}, &scanJob)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(scanJob.IsDeferred()).To(BeTrue())
+			Expect(scanJob.IsPending()).To(BeTrue())
+
+			By("Verifying the ScanDeferred event is recorded")
+			Expect(recorder.Events).To(Receive(HavePrefix(fmt.Sprintf("Normal %s ScanJob %s deferred until", v1alpha1.EventReasonScanDeferred, scanJob.Name))))
 		})
 	})
 
@@ -140,6 +301,7 @@ var _ = Describe("ScanJob Controller", func() {
 				Client:    k8sClient,
 				Publisher: mockPublisher,
 				Scheme:    k8sClient.Scheme(),
+				Recorder:  record.NewFakeRecorder(10),
 			}
 
 			By("Creating a ScanJob with non-existent Registry")
@@ -188,6 +350,7 @@ var _ = Describe("ScanJob Controller", func() {
 				Client:    k8sClient,
 				Publisher: mockPublisher,
 				Scheme:    k8sClient.Scheme(),
+				Recorder:  record.NewFakeRecorder(10),
 			}
 
 			By("Creating a completed ScanJob")
@@ -233,6 +396,7 @@ var _ = Describe("ScanJob Controller", func() {
 				Client:    k8sClient,
 				Publisher: mockPublisher,
 				Scheme:    k8sClient.Scheme(),
+				Recorder:  record.NewFakeRecorder(10),
 			}
 			By("Creating a Registry")
 			registry = v1alpha1.Registry{
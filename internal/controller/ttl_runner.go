@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+const ttlInterval = time.Minute
+
+// TTLRunner periodically deletes the images whose TTL annotation has elapsed since their creation,
+// together with their SBOMs and VulnerabilityReports.
+type TTLRunner struct {
+	client.Client
+}
+
+// Start implements the Runnable interface.
+func (r *TTLRunner) Start(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	log.Info("Starting TTL runner")
+
+	ticker := time.NewTicker(ttlInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Stopping TTL runner")
+
+			return nil
+		case <-ticker.C:
+			if err := r.reapExpiredImages(ctx, time.Now()); err != nil {
+				log.Error(err, "Failed to delete the expired images")
+			}
+		}
+	}
+}
+
+// reapExpiredImages deletes the images whose TTL has elapsed.
+func (r *TTLRunner) reapExpiredImages(ctx context.Context, now time.Time) error {
+	log := log.FromContext(ctx)
+
+	var images storagev1alpha1.ImageList
+	if err := r.List(ctx, &images); err != nil {
+		return fmt.Errorf("failed to list images: %w", err)
+	}
+
+	for _, image := range expiredImages(images.Items, now) {
+		// The SBOM and the VulnerabilityReport are garbage collected with the image.
+		if err := r.Delete(ctx, &image); err != nil && !apierrors.IsNotFound(err) {
+			log.Error(err, "Failed to delete expired image", "image", image.Name, "namespace", image.Namespace)
+
+			continue
+		}
+
+		metadata := image.GetImageMetadata()
+		log.Info("Deleted expired image",
+			"namespace", image.Namespace,
+			"image", image.Name,
+			"repository", metadata.Repository,
+			"tag", metadata.Tag,
+			"platform", metadata.Platform,
+			"digest", metadata.Digest,
+			"createdAt", image.CreationTimestamp.UTC().Format(time.RFC3339),
+			"ttl", image.Annotations[storagev1alpha1.AnnotationImageTTLKey])
+	}
+
+	return nil
+}
+
+// expiredImages returns the images whose TTL has elapsed since their creation.
+func expiredImages(images []storagev1alpha1.Image, now time.Time) []storagev1alpha1.Image {
+	var expired []storagev1alpha1.Image
+	for _, image := range images {
+		value, ok := image.Annotations[storagev1alpha1.AnnotationImageTTLKey]
+		if !ok {
+			continue
+		}
+		ttl, err := storagev1alpha1.ParseImageTTL(value)
+		if err != nil {
+			// Do not delete the images with an invalid annotation.
+			continue
+		}
+
+		if now.Sub(image.CreationTimestamp.Time) > ttl {
+			expired = append(expired, image)
+		}
+	}
+
+	return expired
+}
+
+// NeedLeaderElection implements the LeaderElectionRunnable interface.
+func (r *TTLRunner) NeedLeaderElection() bool {
+	return true
+}
+
+func (r *TTLRunner) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.Add(r); err != nil {
+		return fmt.Errorf("failed to create TTLRunner: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+var _ = Describe("TTLRunner", func() {
+	When("Images have a TTL annotation", func() {
+		var suffix string
+
+		createImage := func(ctx context.Context, name, ttl string) {
+			image := storagev1alpha1.Image{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name + "-" + suffix,
+					Namespace: "default",
+				},
+				ImageMetadata: storagev1alpha1.ImageMetadata{
+					Registry:   "ci",
+					Repository: "sbomscanner",
+					Tag:        "pr-" + name,
+					Digest:     "sha256:" + name,
+					Platform:   "linux/amd64",
+				},
+			}
+			if ttl != "" {
+				image.Annotations = map[string]string{
+					storagev1alpha1.AnnotationImageTTLKey: ttl,
+				}
+			}
+			Expect(k8sClient.Create(ctx, &image)).To(Succeed())
+		}
+
+		BeforeEach(func(ctx context.Context) {
+			suffix = uuid.New().String()
+
+			By("Creating images with and without a TTL")
+			createImage(ctx, "expired", "1h")
+			createImage(ctx, "alive", "72h")
+			createImage(ctx, "no-ttl", "")
+		})
+
+		It("Should delete the images whose TTL has elapsed", func(ctx context.Context) {
+			runner := &TTLRunner{
+				Client: k8sClient,
+			}
+			Expect(runner.reapExpiredImages(ctx, time.Now().Add(2*time.Hour))).To(Succeed())
+
+			var image storagev1alpha1.Image
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "expired-" + suffix, Namespace: "default"}, &image)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "alive-" + suffix, Namespace: "default"}, &image)).To(Succeed())
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "no-ttl-" + suffix, Namespace: "default"}, &image)).To(Succeed())
+		})
+	})
+})
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -18,10 +20,12 @@ import (
 // VulnerabilityReportReconciler reconciles a VulnerabilityReport object
 type VulnerabilityReportReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=storage.sbomscanner.kubewarden.io,resources=vulnerabilityreports,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile reconciles a VulnerabilityReport object.
 func (r *VulnerabilityReportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -72,6 +76,7 @@ func (r *VulnerabilityReportReconciler) Reconcile(ctx context.Context, req ctrl.
 		"scannedImagesCount", len(vulnerabilityReports.Items))
 
 	scanJob.Status.ScannedImagesCount = len(vulnerabilityReports.Items)
+	wasComplete := scanJob.IsComplete()
 	// If the ScanJob is failed, we don't want to override its status conditions.
 	// We still update the ScannedImagesCount in case some reports were generated before the failure.
 	if !scanJob.IsFailed() {
@@ -87,9 +92,26 @@ func (r *VulnerabilityReportReconciler) Reconcile(ctx context.Context, req ctrl.
 		return ctrl.Result{}, fmt.Errorf("failed to update ScanJob status: %w", err)
 	}
 
+	// The reports updated after the completion, e.g. by a rescan, do not complete the ScanJob again.
+	if !wasComplete && scanJob.IsComplete() {
+		r.recordScanCompleted(ctx, scanJob)
+	}
+
 	return ctrl.Result{}, nil
 }
 
+// recordScanCompleted records the completion of the ScanJob on its Registry.
+func (r *VulnerabilityReportReconciler) recordScanCompleted(ctx context.Context, scanJob *v1alpha1.ScanJob) {
+	registry, err := scanJob.GetRegistryFromAnnotation()
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "unable to get the Registry of the ScanJob, skipping the completion event", "scanJob", scanJob.Name)
+		return
+	}
+
+	r.Recorder.Eventf(registry, corev1.EventTypeNormal, v1alpha1.EventReasonScanCompleted,
+		"ScanJob %s completed, %d images scanned", scanJob.Name, scanJob.Status.ScannedImagesCount)
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *VulnerabilityReportReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	err := ctrl.NewControllerManagedBy(mgr).
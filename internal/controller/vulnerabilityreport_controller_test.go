@@ -10,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/config"
@@ -50,8 +51,9 @@ var _ = Describe("VulnerabilityReport Controller", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		reconciler := VulnerabilityReportReconciler{
-			Client: mgr.GetClient(),
-			Scheme: mgr.GetScheme(),
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Recorder: record.NewFakeRecorder(10),
 		}
 
 		mgrClient = mgr.GetClient()
@@ -195,8 +197,9 @@ var _ = Describe("VulnerabilityReport Controller", func() {
 			It("should skip reconciliation without error to avoid reconciliation loops", func(ctx context.Context) {
 				By("Creating a VulnerabilityReport reconciler")
 				reconciler := VulnerabilityReportReconciler{
-					Client: mgrClient,
-					Scheme: k8sClient.Scheme(),
+					Client:   mgrClient,
+					Scheme:   k8sClient.Scheme(),
+					Recorder: record.NewFakeRecorder(10),
 				}
 
 				By("Reconciling the VulnerabilityReport")
@@ -232,8 +235,9 @@ var _ = Describe("VulnerabilityReport Controller", func() {
 			It("should skip reconciliation without error to avoid reconciliation loops", func(ctx context.Context) {
 				By("Creating a VulnerabilityReport reconciler")
 				reconciler := VulnerabilityReportReconciler{
-					Client: mgrClient,
-					Scheme: k8sClient.Scheme(),
+					Client:   mgrClient,
+					Scheme:   k8sClient.Scheme(),
+					Recorder: record.NewFakeRecorder(10),
 				}
 
 				By("Reconciling the VulnerabilityReport")
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	"github.com/kubewarden/sbomscanner/internal/writebuffer"
+)
+
+const (
+	// WriteKindSBOM is the kind of the buffered writes of the generated SBOMs, written by GenerateSBOMHandler.WriteSBOM.
+	WriteKindSBOM = "sbom"
+	// WriteKindVulnerabilityReport is the kind of the buffered writes of the VulnerabilityReports,
+	// written by ScanSBOMHandler.WriteVulnerabilityReport.
+	WriteKindVulnerabilityReport = "vulnerabilityReport"
+)
+
+// sbomWrite is the write of a generated SBOM, followed by the request of its scan.
+type sbomWrite struct {
+	SBOM *storagev1alpha1.SBOM `json:"sbom"`
+	// Image is the image of the SBOM, which the Events are recorded on.
+	Image   *storagev1alpha1.Image `json:"image"`
+	ScanJob ObjectRef              `json:"scanjob"`
+}
+
+// vulnerabilityReportWrite is the write of the VulnerabilityReport of a scanned SBOM,
+// followed by the notification of its new vulnerabilities and the update of its image.
+type vulnerabilityReportWrite struct {
+	// SBOM is the scanned SBOM, without its SPDX document.
+	SBOM       *storagev1alpha1.SBOM  `json:"sbom"`
+	ScanJobUID string                 `json:"scanJobUID"`
+	Report     storagev1alpha1.Report `json:"report"`
+}
+
+// permanentWriteError marks the errors of the buffered writes which retrying would not fix,
+// e.g. the storage rejected the object as invalid, or its namespace was deleted, so that the write is dropped.
+func permanentWriteError(err error) error {
+	if err != nil && (apierrors.IsInvalid(err) || apierrors.IsBadRequest(err) || apierrors.IsNotFound(err) || apierrors.IsForbidden(err)) {
+		return &writebuffer.PermanentError{Err: err}
+	}
+
+	return err
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/kubewarden/sbomscanner/internal/writebuffer"
+)
+
+func TestPermanentWriteError(t *testing.T) {
+	var permanentErr *writebuffer.PermanentError
+
+	invalid := apierrors.NewInvalid(schema.GroupKind{Kind: "SBOM"}, "sbom", field.ErrorList{})
+	require.ErrorAs(t, permanentWriteError(invalid), &permanentErr)
+	assert.True(t, apierrors.IsInvalid(permanentErr))
+
+	require.ErrorAs(t, permanentWriteError(apierrors.NewBadRequest("bad request")), &permanentErr)
+	require.ErrorAs(t, permanentWriteError(apierrors.NewNotFound(schema.GroupResource{Resource: "scanjobs"}, "scanjob")), &permanentErr)
+	require.ErrorAs(t, permanentWriteError(apierrors.NewForbidden(schema.GroupResource{Resource: "sboms"}, "sbom", errors.New("namespace is being terminated"))), &permanentErr)
+
+	unavailable := apierrors.NewServiceUnavailable("storage unavailable")
+	assert.NotErrorAs(t, permanentWriteError(unavailable), &permanentErr)
+	assert.NotErrorAs(t, permanentWriteError(errors.New("connection refused")), &permanentErr)
+	assert.NoError(t, permanentWriteError(nil))
+}
+
+func TestWriteVulnerabilityReport_InvalidData(t *testing.T) {
+	handler := &ScanSBOMHandler{}
+
+	var permanentErr *writebuffer.PermanentError
+	require.ErrorAs(t, handler.WriteVulnerabilityReport(t.Context(), []byte(`{"sbom":`)), &permanentErr)
+}
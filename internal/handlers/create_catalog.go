@@ -15,6 +15,7 @@ import (
 	"os"
 	"path"
 	"slices"
+	"time"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	cranev1 "github.com/google/go-containerregistry/pkg/v1"
@@ -35,6 +36,17 @@ import (
 	"github.com/kubewarden/sbomscanner/internal/handlers/dockerauth"
 	registryclient "github.com/kubewarden/sbomscanner/internal/handlers/registry"
 	"github.com/kubewarden/sbomscanner/internal/messaging"
+	platformutil "github.com/kubewarden/sbomscanner/internal/platform"
+	"github.com/kubewarden/sbomscanner/internal/revocation"
+)
+
+const (
+	// dockerReferenceTypeAnnotation is set by BuildKit on the image index entries that reference other manifests.
+	dockerReferenceTypeAnnotation = "vnd.docker.reference.type"
+	// attestationManifestReferenceType is the reference type of the attestation manifests.
+	attestationManifestReferenceType = "attestation-manifest"
+	// discoveryCheckpointInterval is the minimum interval between the updates of the discovery checkpoint.
+	discoveryCheckpointInterval = 30 * time.Second
 )
 
 // CreateCatalogHandler is a handler for creating a catalog of images in a registry.
@@ -42,16 +54,29 @@ type CreateCatalogHandler struct {
 	registryClientFactory registryclient.ClientFactory
 	k8sClient             client.Client
 	scheme                *runtime.Scheme
-	publisher             messaging.Publisher
-	logger                *slog.Logger
+	// defaultPlatform is the platform cataloged for the multi-architecture images of the registries
+	// without platforms nor default platform, all the platforms are cataloged when it is nil.
+	defaultPlatform     *v1alpha1.Platform
+	publisher           messaging.Publisher
+	revocationChecker   *revocation.Checker
+	credentialProviders dockerauth.CredentialProviders
+	// registryAllowlist restricts the registry hosts that are cataloged, nil allows all the hosts.
+	registryAllowlist *registryclient.HostAllowlist
+	logger            *slog.Logger
 }
 
 // NewCreateCatalogHandler creates a new instance of CreateCatalogHandler.
+// defaultPlatform is the platform cataloged for the multi-architecture images
+// when the Registry does not specify any platform, nil to catalog all the platforms.
+// The ScanJobs of the registries whose host is not in registryAllowlist are failed, a nil allowlist allows all the hosts.
 func NewCreateCatalogHandler(
 	registryClientFactory registryclient.ClientFactory,
 	k8sClient client.Client,
 	scheme *runtime.Scheme,
+	defaultPlatform *v1alpha1.Platform,
 	publisher messaging.Publisher,
+	credentialProviders dockerauth.CredentialProviders,
+	registryAllowlist *registryclient.HostAllowlist,
 	logger *slog.Logger,
 ) *CreateCatalogHandler {
 	return &CreateCatalogHandler{
@@ -59,6 +84,10 @@ func NewCreateCatalogHandler(
 		k8sClient:             k8sClient,
 		publisher:             publisher,
 		scheme:                scheme,
+		defaultPlatform:       defaultPlatform,
+		revocationChecker:     revocation.NewChecker(logger),
+		credentialProviders:   credentialProviders,
+		registryAllowlist:     registryAllowlist,
 		logger:                logger.With("handler", "create_catalog_handler"),
 	}
 }
@@ -104,29 +133,30 @@ func (h *CreateCatalogHandler) Handle(ctx context.Context, message messaging.Mes
 	}
 
 	// Retrieve the registry from the scan job annotations.
-	registryData, ok := scanJob.Annotations[v1alpha1.AnnotationScanJobRegistryKey]
-	if !ok {
-		return fmt.Errorf("scan job %s/%s does not have a registry annotation", createCatalogMessage.ScanJob.Namespace, createCatalogMessage.ScanJob.Name)
-	}
-	registry := &v1alpha1.Registry{}
-	if err = json.Unmarshal([]byte(registryData), registry); err != nil {
-		return fmt.Errorf("cannot unmarshal registry data from scan job %s/%s: %w", createCatalogMessage.ScanJob.Namespace, createCatalogMessage.ScanJob.Name, err)
+	registry, err := scanJob.GetRegistryFromAnnotation()
+	if err != nil {
+		return fmt.Errorf("cannot get registry from scan job %s/%s: %w", createCatalogMessage.ScanJob.Namespace, createCatalogMessage.ScanJob.Name, err)
 	}
 	h.logger.DebugContext(ctx, "Registry found", "registry", registry.Name, "namespace", registry.Namespace)
 
+	// The allowlist is enforced before any connection to the registry, including the credential providers.
+	if !h.registryAllowlist.Allows(registry.Spec.URI) {
+		message := fmt.Sprintf("Registry %s is not allowed, the allowed registry hosts are: %s", registry.Spec.URI, h.registryAllowlist)
+		return h.markScanJobFailed(ctx, scanJob, v1alpha1.ReasonRegistryNotAllowed, message)
+	}
+
 	transport, err := h.transportFromRegistry(registry)
 	if err != nil {
 		return fmt.Errorf("cannot create transport for registry %s: %w", registry.Name, err)
 	}
 	registryClient := h.registryClientFactory(transport)
-	// if authSecret value is set, then setup Docker
-	// authentication to get access to the registry
-	if registry.IsPrivate() {
-		var dockerConfig string
-		dockerConfig, err = dockerauth.BuildDockerConfigForRegistry(ctx, h.k8sClient, registry)
-		if err != nil {
-			return fmt.Errorf("cannot setup docker auth: %w", err)
-		}
+	// if authSecret value is set, or a credential provider matches the registry,
+	// then setup Docker authentication to get access to the registry
+	dockerConfig, err := dockerauth.BuildDockerConfig(ctx, h.k8sClient, h.credentialProviders, registry, h.logger)
+	if err != nil {
+		return fmt.Errorf("cannot setup docker auth: %w", err)
+	}
+	if dockerConfig != "" {
 		h.logger.DebugContext(ctx, "Setup registry authentication", "dockerconfig", os.Getenv("DOCKER_CONFIG"))
 		defer func() {
 			if err = os.RemoveAll(dockerConfig); err != nil {
@@ -144,16 +174,9 @@ func (h *CreateCatalogHandler) Handle(ctx context.Context, message messaging.Mes
 	if err != nil {
 		return fmt.Errorf("cannot discover repositories: %w", err)
 	}
-
-	discoveredImageReferences := sets.Set[string]{}
-	for _, repository := range repositories {
-		var repoImages []string
-		repoImages, err = h.discoverImages(ctx, registryClient, repository)
-		if err != nil {
-			return fmt.Errorf("cannot discover images in registry %s: %w", registry.Name, err)
-		}
-		discoveredImageReferences.Insert(repoImages...)
-	}
+	// The repositories are cataloged in lexical order, so that the discovery can resume after the last repository
+	// recorded in the checkpoint.
+	slices.Sort(repositories)
 
 	existingImageList := &storagev1alpha1.ImageList{}
 	listOpts := []client.ListOption{
@@ -164,78 +187,124 @@ func (h *CreateCatalogHandler) Handle(ctx context.Context, message messaging.Mes
 		return fmt.Errorf("cannot list existing images in registry %s: %w", registry.Name, err)
 	}
 	existingImageNames := sets.Set[string]{}
+	// The scan priority is read from the stored images, since the annotation is set by the users.
+	scanPriorities := make(map[string]messaging.Priority, len(existingImageList.Items))
 	for _, existingImage := range existingImageList.Items {
 		existingImageNames.Insert(existingImage.Name)
+		scanPriorities[existingImage.Name] = h.scanPriority(ctx, &existingImage)
+	}
+
+	checkpointRepository := h.loadDiscoveryCheckpoint(ctx, registry, createCatalogMessage.ScanJob.UID)
+	if checkpointRepository != "" {
+		h.logger.InfoContext(ctx, "Resuming discovery from checkpoint", "registry", registry.Name, "namespace", registry.Namespace, "repository", checkpointRepository)
 	}
+	lastCheckpointTime := time.Now()
 
 	if err = message.InProgress(); err != nil {
 		return fmt.Errorf("failed to ack message as in progress: %w", err)
 	}
 
 	var discoveredImages []storagev1alpha1.Image
-	for newImageName := range discoveredImageReferences {
-		var ref name.Reference
-		ref, err = name.ParseReference(newImageName)
-		if err != nil {
-			h.logger.ErrorContext(ctx, "Cannot parse image reference", "reference", newImageName, "error", err)
-			// Avoid blocking other images to be cataloged
+	for _, repository := range repositories {
+		if repository <= checkpointRepository {
+			// The images of the repository were cataloged before the restart.
+			var checkpointedImages []storagev1alpha1.Image
+			checkpointedImages, err = repositoryImages(existingImageList.Items, repository)
+			if err != nil {
+				return fmt.Errorf("cannot resume discovery in registry %s: %w", registry.Name, err)
+			}
+			discoveredImages = append(discoveredImages, checkpointedImages...)
+
 			continue
 		}
 
-		var images []storagev1alpha1.Image
-		images, err = h.refToImages(ctx, registryClient, ref, registry, message)
+		var repoImages []string
+		repoImages, err = h.discoverImages(ctx, registryClient, repository)
 		if err != nil {
-			h.logger.ErrorContext(ctx, "Cannot get images", "reference", ref.String(), "error", err)
-			// Avoid blocking other images to be cataloged
-			continue
+			return fmt.Errorf("cannot discover images in registry %s: %w", registry.Name, err)
 		}
 
-		for _, image := range images {
-			// Re-fetch the scanjob to be sure it was not deleted while we were processing images.
-			// If the scanjob is not found, we circuit-break the image creation.
-			err = h.k8sClient.Get(ctx, types.NamespacedName{
-				Name:      createCatalogMessage.ScanJob.Name,
-				Namespace: createCatalogMessage.ScanJob.Namespace,
-			}, scanJob)
+		for _, newImageName := range repoImages {
+			var ref name.Reference
+			ref, err = name.ParseReference(newImageName)
 			if err != nil {
-				if apierrors.IsNotFound(err) {
-					h.logger.InfoContext(ctx, "ScanJob not found, stopping catalog creation", "scanjob", createCatalogMessage.ScanJob.Name, "namespace", createCatalogMessage.ScanJob.Namespace)
-					return nil
-				}
-				return fmt.Errorf("cannot get scanjob %s/%s: %w", createCatalogMessage.ScanJob.Namespace, createCatalogMessage.ScanJob.Name, err)
-			}
-			if string(scanJob.GetUID()) != createCatalogMessage.ScanJob.UID {
-				h.logger.InfoContext(ctx, "ScanJob not founnd, stopping SBOM generation (UID changed)", "scanjob", createCatalogMessage.ScanJob.Name, "namespace", createCatalogMessage.ScanJob.Namespace,
-					"uid", createCatalogMessage.ScanJob.UID)
-				return nil
+				h.logger.ErrorContext(ctx, "Cannot parse image reference", "reference", newImageName, "error", err)
+				// Avoid blocking other images to be cataloged
+				continue
 			}
 
-			discoveredImages = append(discoveredImages, image)
-
-			if existingImageNames.Has(image.Name) {
+			var images []storagev1alpha1.Image
+			images, err = h.refToImages(ctx, registryClient, ref, registry, message)
+			if err != nil {
+				h.logger.ErrorContext(ctx, "Cannot get images", "reference", ref.String(), "error", err)
+				// Avoid blocking other images to be cataloged
 				continue
 			}
 
-			h.logger.InfoContext(ctx, "Creating image", "image", image.Name, "namespace", image.Namespace)
-			if err = h.k8sClient.Create(ctx, &image); err != nil {
-				if apierrors.IsAlreadyExists(err) {
-					h.logger.InfoContext(ctx, "Image already exists, skipping creation", "image", image.Name, "namespace", image.Namespace)
+			for _, image := range images {
+				// Re-fetch the scanjob to be sure it was not deleted while we were processing images.
+				// If the scanjob is not found, we circuit-break the image creation.
+				err = h.k8sClient.Get(ctx, types.NamespacedName{
+					Name:      createCatalogMessage.ScanJob.Name,
+					Namespace: createCatalogMessage.ScanJob.Namespace,
+				}, scanJob)
+				if err != nil {
+					if apierrors.IsNotFound(err) {
+						h.logger.InfoContext(ctx, "ScanJob not found, stopping catalog creation", "scanjob", createCatalogMessage.ScanJob.Name, "namespace", createCatalogMessage.ScanJob.Namespace)
+						return nil
+					}
+					return fmt.Errorf("cannot get scanjob %s/%s: %w", createCatalogMessage.ScanJob.Namespace, createCatalogMessage.ScanJob.Name, err)
+				}
+				if string(scanJob.GetUID()) != createCatalogMessage.ScanJob.UID {
+					h.logger.InfoContext(ctx, "ScanJob not founnd, stopping SBOM generation (UID changed)", "scanjob", createCatalogMessage.ScanJob.Name, "namespace", createCatalogMessage.ScanJob.Namespace,
+						"uid", createCatalogMessage.ScanJob.UID)
+					return nil
+				}
+
+				discoveredImages = append(discoveredImages, image)
+
+				if existingImageNames.Has(image.Name) {
 					continue
 				}
-				return fmt.Errorf("cannot create image %s: %w", image.Name, err)
+
+				h.logger.InfoContext(ctx, "Creating image", "image", image.Name, "namespace", image.Namespace)
+				if err = h.k8sClient.Create(ctx, &image); err != nil {
+					if apierrors.IsAlreadyExists(err) {
+						h.logger.InfoContext(ctx, "Image already exists, skipping creation", "image", image.Name, "namespace", image.Namespace)
+						continue
+					}
+					return fmt.Errorf("cannot create image %s: %w", image.Name, err)
+				}
+
+				if err = message.InProgress(); err != nil {
+					return fmt.Errorf("failed to ack message as in progress: %w", err)
+				}
 			}
+		}
 
-			if err = message.InProgress(); err != nil {
-				return fmt.Errorf("failed to ack message as in progress: %w", err)
+		if time.Since(lastCheckpointTime) >= discoveryCheckpointInterval {
+			checkpoint := &v1alpha1.DiscoveryCheckpoint{
+				ScanJobUID: createCatalogMessage.ScanJob.UID,
+				Repository: repository,
+				UpdateTime: metav1.Now(),
+			}
+			if err = h.saveDiscoveryCheckpoint(ctx, registry, checkpoint); err != nil {
+				// The checkpoint only speeds up the discovery after a restart, do not fail the catalog creation.
+				h.logger.WarnContext(ctx, "Cannot save discovery checkpoint", "registry", registry.Name, "namespace", registry.Namespace, "error", err)
 			}
+			lastCheckpointTime = time.Now()
 		}
 	}
-
 	discoveredImageNames := sets.Set[string]{}
 	for _, image := range discoveredImages {
 		discoveredImageNames.Insert(image.Name)
 	}
-	if err = h.deleteObsoleteImages(ctx, existingImageNames, discoveredImageNames, registry.Namespace, message); err != nil {
+	if registry.Spec.Retention != nil {
+		// The retention policy is enforced by the controller, which prunes the images marked as removed.
+		if err = h.markRemovedImages(ctx, existingImageList.Items, discoveredImageNames, message); err != nil {
+			return fmt.Errorf("cannot mark removed images in registry %s: %w", registry.Name, err)
+		}
+	} else if err = h.deleteObsoleteImages(ctx, existingImageNames, discoveredImageNames, registry.Namespace, message); err != nil {
 		return fmt.Errorf("cannot delete obsolete images in registry %s: %w", registry.Name, err)
 	}
 
@@ -270,6 +339,10 @@ func (h *CreateCatalogHandler) Handle(ctx context.Context, message messaging.Mes
 		return fmt.Errorf("cannot update scan job status %s/%s: %w", createCatalogMessage.ScanJob.Namespace, createCatalogMessage.ScanJob.Name, err)
 	}
 
+	// The generate SBOM messages are partitioned by registry,
+	// so that the images of a large registry do not delay the scans of the other registries.
+	// The images annotated with the high scan priority are published to the high priority lane.
+	partition := fmt.Sprintf("%s.%s", registry.Namespace, registry.Name)
 	for _, image := range discoveredImages {
 		h.logger.DebugContext(ctx, "Sending generate SBOM message", "image", image.Name, "namespace", image.Namespace)
 
@@ -287,14 +360,130 @@ func (h *CreateCatalogHandler) Handle(ctx context.Context, message messaging.Mes
 			return fmt.Errorf("cannot marshal generate sbom message for image %s/%s: %w", image.Namespace, image.Name, err)
 		}
 
-		if err = h.publisher.Publish(ctx, GenerateSBOMSubject, messageID, message); err != nil {
+		priority, found := scanPriorities[image.Name]
+		if !found {
+			priority = messaging.PriorityNormal
+		}
+		if err = h.publisher.PublishPartitioned(ctx, GenerateSBOMSubject, partition, priority, messageID, message); err != nil {
 			return fmt.Errorf("cannot publish generate sbom message for image %s/%s: %w", image.Namespace, image.Name, err)
 		}
 	}
 
+	if err = h.saveDiscoveryCheckpoint(ctx, registry, nil); err != nil {
+		h.logger.WarnContext(ctx, "Cannot clear discovery checkpoint", "registry", registry.Name, "namespace", registry.Namespace, "error", err)
+	}
+
+	return nil
+}
+
+// scanPriority returns the priority of the scan of the image, set by the scan priority annotation.
+// The normal priority is returned when the annotation is missing or invalid.
+func (h *CreateCatalogHandler) scanPriority(ctx context.Context, image *storagev1alpha1.Image) messaging.Priority {
+	value, found := image.Annotations[storagev1alpha1.AnnotationImageScanPriorityKey]
+	if !found {
+		return messaging.PriorityNormal
+	}
+
+	priority, err := messaging.ParsePriority(value)
+	if err != nil {
+		h.logger.WarnContext(ctx, "Invalid scan priority annotation, using the normal priority",
+			"image", image.Name, "namespace", image.Namespace, "error", err)
+		return messaging.PriorityNormal
+	}
+
+	return priority
+}
+
+// markScanJobFailed marks the ScanJob as failed with the given reason, so that the catalog creation is not retried.
+func (h *CreateCatalogHandler) markScanJobFailed(ctx context.Context, scanJob *v1alpha1.ScanJob, reason, message string) error {
+	h.logger.InfoContext(ctx, "Catalog creation failed, marking ScanJob as failed",
+		"scanjob", scanJob.Name,
+		"namespace", scanJob.Namespace,
+		"reason", reason,
+		"message", message,
+	)
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := h.k8sClient.Get(ctx, client.ObjectKeyFromObject(scanJob), scanJob); err != nil {
+			return fmt.Errorf("cannot get scanjob %s/%s: %w", scanJob.Namespace, scanJob.Name, err)
+		}
+
+		scanJob.MarkFailed(reason, message)
+		return h.k8sClient.Status().Update(ctx, scanJob)
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			h.logger.InfoContext(ctx, "ScanJob not found, skipping updating ScanJob status to failed", "scanjob", scanJob.Name, "namespace", scanJob.Namespace)
+			return nil
+		}
+		return fmt.Errorf("failed to update ScanJob %s/%s status to failed: %w", scanJob.Namespace, scanJob.Name, err)
+	}
+
 	return nil
 }
 
+// loadDiscoveryCheckpoint returns the last repository cataloged by a previous attempt of the scan job,
+// or an empty string when the discovery starts from scratch.
+func (h *CreateCatalogHandler) loadDiscoveryCheckpoint(ctx context.Context, registry *v1alpha1.Registry, scanJobUID string) string {
+	current := &v1alpha1.Registry{}
+	if err := h.k8sClient.Get(ctx, client.ObjectKeyFromObject(registry), current); err != nil {
+		h.logger.WarnContext(ctx, "Cannot get discovery checkpoint, starting discovery from scratch", "registry", registry.Name, "namespace", registry.Namespace, "error", err)
+		return ""
+	}
+
+	checkpoint := current.Status.DiscoveryCheckpoint
+	if checkpoint == nil || checkpoint.ScanJobUID != scanJobUID {
+		return ""
+	}
+
+	return checkpoint.Repository
+}
+
+// saveDiscoveryCheckpoint stores the checkpoint in the registry status.
+// A nil checkpoint clears it.
+func (h *CreateCatalogHandler) saveDiscoveryCheckpoint(ctx context.Context, registry *v1alpha1.Registry, checkpoint *v1alpha1.DiscoveryCheckpoint) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current := &v1alpha1.Registry{}
+		if err := h.k8sClient.Get(ctx, client.ObjectKeyFromObject(registry), current); err != nil {
+			return fmt.Errorf("cannot get registry %s/%s: %w", registry.Namespace, registry.Name, err)
+		}
+		if checkpoint == nil && current.Status.DiscoveryCheckpoint == nil {
+			return nil
+		}
+
+		current.Status.DiscoveryCheckpoint = checkpoint
+		return h.k8sClient.Status().Update(ctx, current)
+	})
+	if err != nil {
+		return fmt.Errorf("cannot update discovery checkpoint of registry %s/%s: %w", registry.Namespace, registry.Name, err)
+	}
+
+	return nil
+}
+
+// repositoryImages returns the images of the given fully qualified repository (e.g. registryclientexample.com/repo)
+// which are still present in the registry.
+// The images marked as removed are skipped, so that resuming the discovery does not restore them.
+// The images removed from the registry since the previous discovery are only detected by the next one.
+func repositoryImages(images []storagev1alpha1.Image, repository string) ([]storagev1alpha1.Image, error) {
+	repo, err := name.NewRepository(repository)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse repository name %q: %w", repository, err)
+	}
+
+	var repoImages []storagev1alpha1.Image
+	for _, image := range images {
+		if _, removed := image.Annotations[storagev1alpha1.AnnotationImageRemovedAtKey]; removed {
+			continue
+		}
+		if image.RegistryURI == repo.RegistryStr() && image.Repository == repo.RepositoryStr() {
+			repoImages = append(repoImages, image)
+		}
+	}
+
+	return repoImages, nil
+}
+
 // discoverRepositories discovers all the repositories in a registry.
 // Returns the list of fully qualified repository names (e.g. registryclientexample.com/repo)
 func (h *CreateCatalogHandler) discoverRepositories(
@@ -312,6 +501,11 @@ func (h *CreateCatalogHandler) discoverRepositories(
 	if len(registry.Spec.Repositories) == 0 {
 		var allRepositories []string
 		allRepositories, err = registryClient.Catalog(ctx, reg)
+		if errors.Is(err, registryclient.ErrInsufficientScope) {
+			// Credentials scoped to a subset of the registry, like Harbor robot accounts,
+			// are usually not allowed to list the catalog.
+			return []string{}, fmt.Errorf("cannot discover repositories, the credentials are not allowed to list the registry catalog, set the repositories to scan in the registry: %w", err)
+		}
 		if err != nil {
 			return []string{}, fmt.Errorf("cannot discover repositories: %w", err)
 		}
@@ -359,6 +553,9 @@ func (h *CreateCatalogHandler) refToImages(
 	if err != nil {
 		return []storagev1alpha1.Image{}, fmt.Errorf("cannot get platforms for %s: %w", ref, err)
 	}
+	if len(registry.Spec.Platforms) == 0 {
+		platforms = h.selectDefaultPlatform(ctx, ref, platforms, registry)
+	}
 
 	images := []storagev1alpha1.Image{}
 
@@ -421,16 +618,73 @@ func (h *CreateCatalogHandler) refToPlatforms(
 	}
 
 	platforms := []*cranev1.Platform{}
+	skippedAttestations, skippedWithoutPlatform := 0, 0
 	for _, manifest := range manifest.Manifests {
+		if isAttestationManifest(manifest) {
+			h.logger.Debug(
+				"skipping attestation manifest",
+				"image", ref.Name(),
+				"digest", manifest.Digest.String())
+			skippedAttestations++
+			continue
+		}
+		if manifest.Platform == nil {
+			h.logger.Debug(
+				"skipping manifest without platform",
+				"image", ref.Name(),
+				"digest", manifest.Digest.String())
+			skippedWithoutPlatform++
+			continue
+		}
 		if !isPlatformAllowed(*manifest.Platform, allowedPlatforms) {
 			continue
 		}
 		platforms = append(platforms, manifest.Platform)
 	}
+	if skippedAttestations > 0 || skippedWithoutPlatform > 0 {
+		h.logger.Info("Skipped the manifests of the image index which are not images",
+			"image", ref.Name(),
+			"attestations", skippedAttestations,
+			"withoutPlatform", skippedWithoutPlatform)
+	}
 
 	return platforms, nil
 }
 
+// selectDefaultPlatform restricts the platforms of a multi-architecture image to the default platform
+// of the registry, or to the default platform of the worker when the registry has none.
+// All the platforms are returned when there is no default platform, or when the image does not provide it.
+func (h *CreateCatalogHandler) selectDefaultPlatform(
+	ctx context.Context,
+	ref name.Reference,
+	platforms []*cranev1.Platform,
+	registry *v1alpha1.Registry,
+) []*cranev1.Platform {
+	defaultPlatform := registry.Spec.DefaultPlatform
+	if defaultPlatform == nil {
+		defaultPlatform = h.defaultPlatform
+	}
+	// The platform of a single-architecture image is only known once its details are fetched.
+	if defaultPlatform == nil || (len(platforms) == 1 && platforms[0] == nil) {
+		return platforms
+	}
+
+	selected := []*cranev1.Platform{}
+	for _, platform := range platforms {
+		if isPlatformAllowed(*platform, []v1alpha1.Platform{*defaultPlatform}) {
+			selected = append(selected, platform)
+		}
+	}
+	if len(selected) == 0 {
+		h.logger.DebugContext(ctx, "Default platform not found, cataloging all the platforms",
+			"image", ref.Name(),
+			"defaultPlatform", defaultPlatform.String())
+		return platforms
+	}
+
+	return selected
+}
+
 // transportFromRegistry creates a new http.RoundTripper from the options specified in the Registry spec.
 func (h *CreateCatalogHandler) transportFromRegistry(registry *v1alpha1.Registry) (http.RoundTripper, error) {
 	transport, ok := remote.DefaultTransport.(*http.Transport)
@@ -440,28 +694,37 @@ func (h *CreateCatalogHandler) transportFromRegistry(registry *v1alpha1.Registry
 	}
 	transport = transport.Clone()
 
-	transport.TLSClientConfig = &tls.Config{
+	transport.TLSClientConfig = tlsConfigFromRegistry(registry, h.logger)
+	if registry.Spec.RevocationCheck != "" {
+		transport.TLSClientConfig.VerifyConnection = h.revocationChecker.VerifyConnection(registry.Spec.RevocationCheck)
+	}
+
+	return transport, nil
+}
+
+// tlsConfigFromRegistry creates a new tls.Config from the options specified in the Registry spec.
+func tlsConfigFromRegistry(registry *v1alpha1.Registry, logger *slog.Logger) *tls.Config {
+	tlsConfig := &tls.Config{
 		InsecureSkipVerify: registry.Spec.Insecure, //nolint:gosec // this a user provided option
 	}
 
 	if len(registry.Spec.CABundle) > 0 {
 		rootCAs, err := x509.SystemCertPool()
 		if err != nil {
-			h.logger.Error("cannot load system cert pool, using empty pool", "error", err)
+			logger.Error("cannot load system cert pool, using empty pool", "error", err)
 			rootCAs = x509.NewCertPool()
 		}
 
-		ok = rootCAs.AppendCertsFromPEM([]byte(registry.Spec.CABundle))
-		if ok {
-			transport.TLSClientConfig.RootCAs = rootCAs
+		if rootCAs.AppendCertsFromPEM([]byte(registry.Spec.CABundle)) {
+			tlsConfig.RootCAs = rootCAs
 		} else {
-			h.logger.Info("cannot load the given CA bundle",
+			logger.Info("cannot load the given CA bundle",
 				"registry", registry.Name,
 				"namespace", registry.Namespace)
 		}
 	}
 
-	return transport, nil
+	return tlsConfig
 }
 
 // deleteObsoleteImages deletes images that are not present in the discovered registry anymore.
@@ -499,6 +762,44 @@ func (h *CreateCatalogHandler) deleteObsoleteImages(
 	return nil
 }
 
+// markRemovedImages annotates the images that are not present in the discovered registry anymore
+// with the time they were removed, instead of deleting them.
+// The annotation is removed from the images found again in the registry.
+func (h *CreateCatalogHandler) markRemovedImages(
+	ctx context.Context,
+	existingImages []storagev1alpha1.Image,
+	discoveredImageNames sets.Set[string],
+	message messaging.Message,
+) error {
+	removedAt := time.Now().UTC().Format(time.RFC3339)
+
+	for _, existingImage := range existingImages {
+		_, marked := existingImage.Annotations[storagev1alpha1.AnnotationImageRemovedAtKey]
+		removed := !discoveredImageNames.Has(existingImage.Name)
+		if marked == removed {
+			continue
+		}
+
+		patch := client.MergeFrom(existingImage.DeepCopy())
+		if removed {
+			h.logger.DebugContext(ctx, "Marking image as removed from the registry", "name", existingImage.Name, "namespace", existingImage.Namespace)
+			metav1.SetMetaDataAnnotation(&existingImage.ObjectMeta, storagev1alpha1.AnnotationImageRemovedAtKey, removedAt)
+		} else {
+			h.logger.DebugContext(ctx, "Image found again in the registry", "name", existingImage.Name, "namespace", existingImage.Namespace)
+			delete(existingImage.Annotations, storagev1alpha1.AnnotationImageRemovedAtKey)
+		}
+
+		if err := h.k8sClient.Patch(ctx, &existingImage, patch); err != nil {
+			return fmt.Errorf("cannot update image %s/%s: %w", existingImage.Namespace, existingImage.Name, err)
+		}
+		if err := message.InProgress(); err != nil {
+			return fmt.Errorf("cannot mark message as in progress: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // imageDetailsToImage converts ImageDetails from the registry client to an Image resource.
 func imageDetailsToImage(
 	ref name.Reference,
@@ -556,6 +857,8 @@ func imageDetailsToImage(
 			Repository:  ref.Context().RepositoryStr(),
 			Tag:         ref.Identifier(),
 			Platform:    details.Platform.String(),
+			OSVersion:   details.Platform.OSVersion,
+			OSFeatures:  platformutil.NormalizeOSFeatures(details.Platform.OSFeatures),
 			Digest:      details.Digest.String(),
 		},
 		Layers: imageLayers,
@@ -571,12 +874,28 @@ func computeImageUID(ref name.Reference, digest string) string {
 	return hex.EncodeToString(sha.Sum(nil))
 }
 
+// isAttestationManifest returns true if the image index entry is an attestation manifest
+// rather than an image that can be scanned.
+// See https://docs.docker.com/build/metadata/attestations/attestation-storage/
+func isAttestationManifest(descriptor cranev1.Descriptor) bool {
+	if descriptor.Annotations[dockerReferenceTypeAnnotation] == attestationManifestReferenceType {
+		return true
+	}
+
+	return descriptor.Platform != nil && isUnknownPlatform(*descriptor.Platform)
+}
+
+// isUnknownPlatform returns true if the platform is "unknown/unknown",
+// which is used by the image indexes to store attestations.
+func isUnknownPlatform(platform cranev1.Platform) bool {
+	return platform.OS == "unknown" && platform.Architecture == "unknown"
+}
+
 // isPlatformAllowed verify if the platform of the image is allowed by the registry filter.
 func isPlatformAllowed(platform cranev1.Platform, allowedPlatforms []v1alpha1.Platform) bool {
 	// Images can contain "unknown/unknown" layers, which usually contain attestations.
-	// See https://docs.docker.com/build/metadata/attestations/attestation-storage/
 	// We need to skip these images, as they cannot be scanned.
-	if platform.OS == "unknown" && platform.Architecture == "unknown" {
+	if isUnknownPlatform(platform) {
 		return false
 	}
 
@@ -587,6 +906,14 @@ func isPlatformAllowed(platform cranev1.Platform, allowedPlatforms []v1alpha1.Pl
 	}
 
 	return slices.ContainsFunc(allowedPlatforms, func(allowedPlatform v1alpha1.Platform) bool {
+		// The OS version of the allowed platform matches its builds, eg. 10.0.17763 matches 10.0.17763.1234.
+		if !platformutil.MatchOSVersion(platform.OSVersion, allowedPlatform.OSVersion) {
+			return false
+		}
+		// The OS features of the allowed platform are required, eg. win32k selects the images requiring it.
+		if !platformutil.MatchOSFeatures(platform.OSFeatures, allowedPlatform.OSFeatures) {
+			return false
+		}
 		if allowedPlatform.Variant == "" {
 			return platform.OS == allowedPlatform.OS && platform.Architecture == allowedPlatform.Architecture
 		}
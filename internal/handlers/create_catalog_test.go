@@ -35,6 +35,7 @@ import (
 	"github.com/kubewarden/sbomscanner/api/v1alpha1"
 	registryClient "github.com/kubewarden/sbomscanner/internal/handlers/registry"
 	registryMocks "github.com/kubewarden/sbomscanner/internal/handlers/registry/mocks"
+	"github.com/kubewarden/sbomscanner/internal/messaging"
 	messagingMocks "github.com/kubewarden/sbomscanner/internal/messaging/mocks"
 	"github.com/kubewarden/sbomscanner/pkg/generated/clientset/versioned/scheme"
 	corev1 "k8s.io/api/core/v1"
@@ -205,7 +206,10 @@ func TestCreateCatalogHandler_Handle(t *testing.T) {
 		mockRegistryClientFactory,
 		k8sClient,
 		scheme,
+		nil,
 		mockPublisher,
+		nil,
+		nil,
 		slog.Default().With("handler", "create_catalog_handler"),
 	)
 
@@ -252,16 +256,20 @@ func TestCreateCatalogHandler_Handle(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	mockPublisher.On("Publish",
+	mockPublisher.On("PublishPartitioned",
 		mock.Anything,
 		GenerateSBOMSubject,
+		"default.test-registry",
+		messaging.PriorityNormal,
 		fmt.Sprintf("generateSBOM/%s/%s", scanJob.UID, amd64ImageName),
 		expectedMessageAmd64,
 	).Return(nil).Once()
 
-	mockPublisher.On("Publish",
+	mockPublisher.On("PublishPartitioned",
 		mock.Anything,
 		GenerateSBOMSubject,
+		"default.test-registry",
+		messaging.PriorityNormal,
 		fmt.Sprintf("generateSBOM/%s/%s", scanJob.UID, arm64ImageName),
 		expectedMessageArm64,
 	).Return(nil).Once()
@@ -384,6 +392,9 @@ func TestCreateCatalogHandler_Handle_ObsoleteImages(t *testing.T) {
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      existingImageUID,
 			Namespace: "default",
+			Annotations: map[string]string{
+				storagev1alpha1.AnnotationImageScanPriorityKey: string(messaging.PriorityHigh),
+			},
 			OwnerReferences: []metav1.OwnerReference{{
 				APIVersion: "sbomscanner.io/v1alpha1",
 				Kind:       "Registry",
@@ -430,9 +441,11 @@ func TestCreateCatalogHandler_Handle_ObsoleteImages(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	mockPublisher.On("Publish",
+	mockPublisher.On("PublishPartitioned",
 		mock.Anything,
 		GenerateSBOMSubject,
+		"default.test-registry",
+		messaging.PriorityHigh,
 		fmt.Sprintf("generateSBOM/%s/%s", scanJob.UID, existingImage.Name),
 		expectedMessage,
 	).Return(nil).Once()
@@ -460,7 +473,10 @@ func TestCreateCatalogHandler_Handle_ObsoleteImages(t *testing.T) {
 		mockRegistryClientFactory,
 		k8sClient,
 		scheme,
+		nil,
 		mockPublisher,
+		nil,
+		nil,
 		slog.Default().With("handler", "create_catalog_handler"),
 	)
 
@@ -491,6 +507,196 @@ func TestCreateCatalogHandler_Handle_ObsoleteImages(t *testing.T) {
 	assert.Equal(t, existingImageUID, imageList.Items[0].Name)
 }
 
+func TestCreateCatalogHandler_Handle_ResumeFromCheckpoint(t *testing.T) {
+	registryURI := "registry.test"
+	imageTag := "v1.0"
+
+	// repo1 was cataloged before the restart, only repo2 is discovered again.
+	repository2, err := name.NewRepository(path.Join(registryURI, "repo2"))
+	require.NoError(t, err)
+	image1, err := name.ParseReference(fmt.Sprintf("%s/repo1:%s", registryURI, imageTag))
+	require.NoError(t, err)
+	image2, err := name.ParseReference(fmt.Sprintf("%s/repo2:%s", registryURI, imageTag))
+	require.NoError(t, err)
+
+	mockRegistryClient := registryMocks.NewClient(t)
+	mockRegistryClient.On("ListRepositoryContents", mock.Anything, repository2).
+		Return([]string{image2.String()}, nil)
+
+	platform := cranev1.Platform{
+		Architecture: "amd64",
+		OS:           "linux",
+	}
+	digest, err := cranev1.NewHash("sha256:8ec69d882e7f29f0652d537557160e638168550f738d0d49f90a7ef96bf31787")
+	require.NoError(t, err)
+
+	mockRegistryClient.On("GetImageIndex", image2).
+		Return(nil, errors.New("not an image index"))
+
+	imageDetails, err := buildImageDetails(digest, platform)
+	require.NoError(t, err)
+	mockRegistryClient.On("GetImageDetails", image2, (*cranev1.Platform)(nil)).
+		Return(imageDetails, nil)
+
+	mockRegistryClientFactory := func(_ http.RoundTripper) registryClient.Client { return mockRegistryClient }
+	mockPublisher := messagingMocks.NewMockPublisher(t)
+
+	registry := &v1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-registry",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.RegistrySpec{
+			URI:          registryURI,
+			Repositories: []string{"repo2", "repo1"},
+			Retention:    &v1alpha1.RetentionPolicy{},
+		},
+	}
+	registryData, err := json.Marshal(registry)
+	require.NoError(t, err)
+
+	scanJob := &v1alpha1.ScanJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scanjob",
+			Namespace: "default",
+			UID:       "test-scanjob-uid",
+			Annotations: map[string]string{
+				v1alpha1.AnnotationScanJobRegistryKey: string(registryData),
+			},
+		},
+		Spec: v1alpha1.ScanJobSpec{
+			Registry: registry.Name,
+		},
+	}
+
+	registry.Status.DiscoveryCheckpoint = &v1alpha1.DiscoveryCheckpoint{
+		ScanJobUID: string(scanJob.UID),
+		Repository: path.Join(registryURI, "repo1"),
+		UpdateTime: metav1.Now(),
+	}
+
+	checkpointedImage := &storagev1alpha1.Image{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      computeImageUID(image1, digest.String()),
+			Namespace: "default",
+		},
+		ImageMetadata: storagev1alpha1.ImageMetadata{
+			Registry:    registry.Name,
+			RegistryURI: registryURI,
+			Repository:  "repo1",
+			Tag:         imageTag,
+			Digest:      digest.String(),
+			Platform:    platform.String(),
+		},
+	}
+	// The image was removed from repo1 before the previous discovery, it must not be restored.
+	removedDigest, err := cranev1.NewHash("sha256:1782cafde43390b032f960c0fad3def745fac18994ced169003cb56e9a93c028")
+	require.NoError(t, err)
+	removedImage := &storagev1alpha1.Image{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      computeImageUID(image1, removedDigest.String()),
+			Namespace: "default",
+			Annotations: map[string]string{
+				storagev1alpha1.AnnotationImageRemovedAtKey: "2025-01-01T00:00:00Z",
+			},
+		},
+		ImageMetadata: storagev1alpha1.ImageMetadata{
+			Registry:    registry.Name,
+			RegistryURI: registryURI,
+			Repository:  "repo1",
+			Tag:         imageTag,
+			Digest:      removedDigest.String(),
+			Platform:    platform.String(),
+		},
+	}
+	discoveredImageName := computeImageUID(image2, digest.String())
+
+	for _, imageName := range []string{checkpointedImage.Name, discoveredImageName} {
+		expectedMessage, err := json.Marshal(&GenerateSBOMMessage{
+			BaseMessage: BaseMessage{
+				ScanJob: ObjectRef{
+					Name:      scanJob.Name,
+					Namespace: scanJob.Namespace,
+					UID:       string(scanJob.UID),
+				},
+			},
+			Image: ObjectRef{
+				Name:      imageName,
+				Namespace: registry.Namespace,
+			},
+		})
+		require.NoError(t, err)
+
+		mockPublisher.On("PublishPartitioned",
+			mock.Anything,
+			GenerateSBOMSubject,
+			"default.test-registry",
+			messaging.PriorityNormal,
+			fmt.Sprintf("generateSBOM/%s/%s", scanJob.UID, imageName),
+			expectedMessage,
+		).Return(nil).Once()
+	}
+
+	scheme := scheme.Scheme
+	err = v1alpha1.AddToScheme(scheme)
+	require.NoError(t, err)
+	err = storagev1alpha1.AddToScheme(scheme)
+	require.NoError(t, err)
+
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(registry, checkpointedImage, removedImage, scanJob).
+		WithStatusSubresource(&v1alpha1.ScanJob{}, &v1alpha1.Registry{}).
+		WithIndex(&storagev1alpha1.Image{}, storagev1alpha1.IndexImageMetadataRegistry, func(obj client.Object) []string {
+			image, ok := obj.(*storagev1alpha1.Image)
+			if !ok {
+				return nil
+			}
+			return []string{image.GetImageMetadata().Registry}
+		}).
+		Build()
+
+	handler := NewCreateCatalogHandler(
+		mockRegistryClientFactory,
+		k8sClient,
+		scheme,
+		nil,
+		mockPublisher,
+		nil,
+		nil,
+		slog.Default().With("handler", "create_catalog_handler"),
+	)
+
+	message, err := json.Marshal(&CreateCatalogMessage{
+		BaseMessage: BaseMessage{
+			ScanJob: ObjectRef{
+				Name:      scanJob.Name,
+				Namespace: scanJob.Namespace,
+				UID:       string(scanJob.UID),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	err = handler.Handle(t.Context(), &testMessage{data: message})
+	require.NoError(t, err)
+
+	updatedScanJob := &v1alpha1.ScanJob{}
+	err = k8sClient.Get(t.Context(), client.ObjectKeyFromObject(scanJob), updatedScanJob)
+	require.NoError(t, err)
+	assert.Equal(t, 2, updatedScanJob.Status.ImagesCount)
+
+	updatedRemovedImage := &storagev1alpha1.Image{}
+	err = k8sClient.Get(t.Context(), client.ObjectKeyFromObject(removedImage), updatedRemovedImage)
+	require.NoError(t, err)
+	assert.Contains(t, updatedRemovedImage.Annotations, storagev1alpha1.AnnotationImageRemovedAtKey, "the removed image should stay marked")
+
+	updatedRegistry := &v1alpha1.Registry{}
+	err = k8sClient.Get(t.Context(), client.ObjectKeyFromObject(registry), updatedRegistry)
+	require.NoError(t, err)
+	assert.Nil(t, updatedRegistry.Status.DiscoveryCheckpoint, "checkpoint should be cleared after the discovery completes")
+}
+
 func TestCreateCatalogHandler_DiscoverRepositories(t *testing.T) {
 	tests := []struct {
 		name                 string
@@ -585,6 +791,64 @@ func TestCatalogHandler_DeleteObsoleteImages(t *testing.T) {
 	assert.Equal(t, "image-1", remainingImages.Items[0].Name)
 }
 
+func TestCatalogHandler_MarkRemovedImages(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, storagev1alpha1.AddToScheme(scheme))
+	existingImages := []storagev1alpha1.Image{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "image-1",
+				Namespace: "default",
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "image-2",
+				Namespace: "default",
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "image-3",
+				Namespace: "default",
+				Annotations: map[string]string{
+					storagev1alpha1.AnnotationImageRemovedAtKey: "2025-01-01T00:00:00Z",
+				},
+			},
+		},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	for _, image := range existingImages {
+		require.NoError(t, k8sClient.Create(t.Context(), image.DeepCopy()))
+	}
+
+	handler := &CreateCatalogHandler{
+		k8sClient: k8sClient,
+		logger:    slog.Default(),
+	}
+
+	ctx := t.Context()
+
+	var storedImages storagev1alpha1.ImageList
+	require.NoError(t, k8sClient.List(ctx, &storedImages, client.InNamespace("default")))
+
+	discoveredImageNames := sets.New(
+		"image-1", // Image 2 is removed, image 3 is found again
+		"image-3",
+	)
+	err := handler.markRemovedImages(ctx, storedImages.Items, discoveredImageNames, &testMessage{})
+	require.NoError(t, err)
+
+	var remainingImages storagev1alpha1.ImageList
+	require.NoError(t, k8sClient.List(ctx, &remainingImages, client.InNamespace("default")))
+	require.Len(t, remainingImages.Items, 3, "the removed images are kept")
+
+	for _, image := range remainingImages.Items {
+		_, marked := image.Annotations[storagev1alpha1.AnnotationImageRemovedAtKey]
+		assert.Equal(t, image.Name == "image-2", marked, "image %s", image.Name)
+	}
+}
+
 func TestCreateCatalogHandler_Handle_StopProcessing(t *testing.T) {
 	registry := &v1alpha1.Registry{
 		ObjectMeta: metav1.ObjectMeta{
@@ -766,7 +1030,7 @@ func TestCreateCatalogHandler_Handle_StopProcessing(t *testing.T) {
 			}
 			mockPublisher := messagingMocks.NewMockPublisher(t)
 
-			handler := NewCreateCatalogHandler(mockRegistryClientFactory, k8sClient, scheme, mockPublisher, slog.Default())
+			handler := NewCreateCatalogHandler(mockRegistryClientFactory, k8sClient, scheme, nil, mockPublisher, nil, nil, slog.Default())
 
 			message, err := json.Marshal(&CreateCatalogMessage{
 				BaseMessage: BaseMessage{
@@ -792,6 +1056,86 @@ func TestCreateCatalogHandler_Handle_StopProcessing(t *testing.T) {
 	}
 }
 
+func TestCreateCatalogHandler_Handle_RegistryNotAllowed(t *testing.T) {
+	registry := &v1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-registry",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.RegistrySpec{
+			URI: "test.io",
+		},
+	}
+	registryData, err := json.Marshal(registry)
+	require.NoError(t, err)
+
+	scanJob := &v1alpha1.ScanJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scanjob",
+			Namespace: "default",
+			UID:       "test-scanjob-uid",
+			Annotations: map[string]string{
+				v1alpha1.AnnotationScanJobRegistryKey: string(registryData),
+			},
+		},
+		Spec: v1alpha1.ScanJobSpec{
+			Registry: "test-registry",
+		},
+	}
+
+	scheme := scheme.Scheme
+	err = storagev1alpha1.AddToScheme(scheme)
+	require.NoError(t, err)
+	err = v1alpha1.AddToScheme(scheme)
+	require.NoError(t, err)
+
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(registry, scanJob).
+		WithStatusSubresource(&v1alpha1.ScanJob{}).
+		Build()
+
+	// The registry client is never created: no call is expected on the mocks.
+	mockRegistryClient := registryMocks.NewClient(t)
+	mockRegistryClientFactory := func(_ http.RoundTripper) registryClient.Client {
+		return mockRegistryClient
+	}
+	mockPublisher := messagingMocks.NewMockPublisher(t)
+
+	allowlist, err := registryClient.NewHostAllowlist([]string{"*.example.com"})
+	require.NoError(t, err)
+
+	handler := NewCreateCatalogHandler(mockRegistryClientFactory, k8sClient, scheme, nil, mockPublisher, nil, allowlist, slog.Default())
+
+	message, err := json.Marshal(&CreateCatalogMessage{
+		BaseMessage: BaseMessage{
+			ScanJob: ObjectRef{
+				Name:      scanJob.Name,
+				Namespace: scanJob.Namespace,
+				UID:       string(scanJob.UID),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	err = handler.Handle(context.Background(), &testMessage{data: message})
+	require.NoError(t, err)
+
+	updatedScanJob := &v1alpha1.ScanJob{}
+	err = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(scanJob), updatedScanJob)
+	require.NoError(t, err)
+	assert.True(t, updatedScanJob.IsFailed())
+	failedCondition := meta.FindStatusCondition(updatedScanJob.Status.Conditions, v1alpha1.ConditionTypeFailed)
+	require.NotNil(t, failedCondition)
+	assert.Equal(t, v1alpha1.ReasonRegistryNotAllowed, failedCondition.Reason)
+	assert.Contains(t, failedCondition.Message, "test.io")
+
+	imageList := &storagev1alpha1.ImageList{}
+	err = k8sClient.List(context.Background(), imageList)
+	require.NoError(t, err)
+	assert.Empty(t, imageList.Items)
+}
+
 func TestImageDetailsToImage(t *testing.T) {
 	digest, err := cranev1.NewHash("sha256:f41b7d70c5779beba4a570ca861f788d480156321de2876ce479e072fb0246f1")
 	require.NoError(t, err)
@@ -1034,7 +1378,10 @@ func TestCreateCatalogHandler_Handle_PrivateRegistry(t *testing.T) {
 		mockRegistryClientFactory,
 		k8sClient,
 		scheme,
+		nil,
 		mockPublisher,
+		nil,
+		nil,
 		slog.Default().With("handler", "create_catalog_handler"),
 	)
 
@@ -1065,9 +1412,11 @@ func TestCreateCatalogHandler_Handle_PrivateRegistry(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	mockPublisher.On("Publish",
+	mockPublisher.On("PublishPartitioned",
 		mock.Anything,
 		GenerateSBOMSubject,
+		"default.test-registry",
+		messaging.PriorityNormal,
 		fmt.Sprintf("generateSBOM/%s/%s", scanJob.UID, amd64ImageName),
 		expectedMessageAmd64,
 	).Return(nil).Once()
@@ -1199,6 +1548,69 @@ func Test_isPlatformAllowed(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "OS version matches",
+			platform: cranev1.Platform{
+				Architecture: "amd64",
+				OS:           "windows",
+				OSVersion:    "10.0.17763.1234",
+			},
+			allowedPlatforms: []v1alpha1.Platform{
+				{
+					Architecture: "amd64",
+					OS:           "windows",
+					OSVersion:    "10.0.17763",
+				},
+			},
+			want: true,
+		},
+		{
+			name: "OS version doesn't match",
+			platform: cranev1.Platform{
+				Architecture: "amd64",
+				OS:           "windows",
+				OSVersion:    "10.0.20348.1234",
+			},
+			allowedPlatforms: []v1alpha1.Platform{
+				{
+					Architecture: "amd64",
+					OS:           "windows",
+					OSVersion:    "10.0.17763",
+				},
+			},
+			want: false,
+		},
+		{
+			name: "OS features match",
+			platform: cranev1.Platform{
+				Architecture: "amd64",
+				OS:           "windows",
+				OSFeatures:   []string{"win32k"},
+			},
+			allowedPlatforms: []v1alpha1.Platform{
+				{
+					Architecture: "amd64",
+					OS:           "windows",
+					OSFeatures:   []string{"win32k"},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "OS features missing",
+			platform: cranev1.Platform{
+				Architecture: "amd64",
+				OS:           "windows",
+			},
+			allowedPlatforms: []v1alpha1.Platform{
+				{
+					Architecture: "amd64",
+					OS:           "windows",
+					OSFeatures:   []string{"win32k"},
+				},
+			},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1209,3 +1621,154 @@ func Test_isPlatformAllowed(t *testing.T) {
 		})
 	}
 }
+
+func Test_isAttestationManifest(t *testing.T) {
+	tests := []struct {
+		name       string
+		descriptor cranev1.Descriptor
+		want       bool
+	}{
+		{
+			name: "image manifest",
+			descriptor: cranev1.Descriptor{
+				Platform: &cranev1.Platform{
+					Architecture: "amd64",
+					OS:           "linux",
+				},
+			},
+			want: false,
+		},
+		{
+			name: "unknown platform",
+			descriptor: cranev1.Descriptor{
+				Platform: &cranev1.Platform{
+					Architecture: "unknown",
+					OS:           "unknown",
+				},
+			},
+			want: true,
+		},
+		{
+			name: "attestation manifest annotation",
+			descriptor: cranev1.Descriptor{
+				Annotations: map[string]string{
+					"vnd.docker.reference.type":   "attestation-manifest",
+					"vnd.docker.reference.digest": "sha256:6b5b3b1b9d0f7b2a3c2e1d4f6a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b",
+				},
+			},
+			want: true,
+		},
+		{
+			name:       "no platform",
+			descriptor: cranev1.Descriptor{},
+			want:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isAttestationManifest(tt.descriptor))
+		})
+	}
+}
+
+func TestCreateCatalogHandler_ScanPriority(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    messaging.Priority
+	}{
+		{
+			name:     "no annotation",
+			expected: messaging.PriorityNormal,
+		},
+		{
+			name:        "high priority",
+			annotations: map[string]string{storagev1alpha1.AnnotationImageScanPriorityKey: "high"},
+			expected:    messaging.PriorityHigh,
+		},
+		{
+			name:        "normal priority",
+			annotations: map[string]string{storagev1alpha1.AnnotationImageScanPriorityKey: "normal"},
+			expected:    messaging.PriorityNormal,
+		},
+		{
+			name:        "invalid priority",
+			annotations: map[string]string{storagev1alpha1.AnnotationImageScanPriorityKey: "urgent"},
+			expected:    messaging.PriorityNormal,
+		},
+	}
+
+	handler := &CreateCatalogHandler{logger: slog.Default()}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			image := &storagev1alpha1.Image{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-image",
+					Namespace:   "default",
+					Annotations: test.annotations,
+				},
+			}
+
+			assert.Equal(t, test.expected, handler.scanPriority(t.Context(), image))
+		})
+	}
+}
+
+func TestCreateCatalogHandler_SelectDefaultPlatform(t *testing.T) {
+	amd64 := &cranev1.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := &cranev1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}
+	armv7 := &cranev1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}
+
+	tests := []struct {
+		name                    string
+		handlerDefaultPlatform  *v1alpha1.Platform
+		registryDefaultPlatform *v1alpha1.Platform
+		platforms               []*cranev1.Platform
+		expected                []*cranev1.Platform
+	}{
+		{
+			name:      "no default platform",
+			platforms: []*cranev1.Platform{amd64, arm64},
+			expected:  []*cranev1.Platform{amd64, arm64},
+		},
+		{
+			name:                   "worker default platform",
+			handlerDefaultPlatform: &v1alpha1.Platform{OS: "linux", Architecture: "amd64"},
+			platforms:              []*cranev1.Platform{amd64, arm64, armv7},
+			expected:               []*cranev1.Platform{amd64},
+		},
+		{
+			name:                    "registry default platform overrides the worker one",
+			handlerDefaultPlatform:  &v1alpha1.Platform{OS: "linux", Architecture: "amd64"},
+			registryDefaultPlatform: &v1alpha1.Platform{OS: "linux", Architecture: "arm64"},
+			platforms:               []*cranev1.Platform{amd64, arm64, armv7},
+			expected:                []*cranev1.Platform{arm64},
+		},
+		{
+			name:                   "default platform not provided by the image",
+			handlerDefaultPlatform: &v1alpha1.Platform{OS: "linux", Architecture: "s390x"},
+			platforms:              []*cranev1.Platform{amd64, arm64},
+			expected:               []*cranev1.Platform{amd64, arm64},
+		},
+		{
+			name:                   "single-architecture image",
+			handlerDefaultPlatform: &v1alpha1.Platform{OS: "linux", Architecture: "amd64"},
+			platforms:              []*cranev1.Platform{nil},
+			expected:               []*cranev1.Platform{nil},
+		},
+	}
+
+	ref, err := name.ParseReference("registry.test/repo:latest")
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := &CreateCatalogHandler{defaultPlatform: test.handlerDefaultPlatform, logger: slog.Default()}
+			registry := &v1alpha1.Registry{
+				Spec: v1alpha1.RegistrySpec{DefaultPlatform: test.registryDefaultPlatform},
+			}
+
+			assert.Equal(t, test.expected, handler.selectDefaultPlatform(t.Context(), ref, test.platforms, registry))
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package dockerauth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+const (
+	// ACRCredentialProviderName is the name of the Azure Container Registry credential provider.
+	ACRCredentialProviderName = "acr"
+
+	// acrRefreshTokenUsername is the username used to authenticate to ACR with a refresh token.
+	acrRefreshTokenUsername = "00000000-0000-0000-0000-000000000000"
+	// acrAADScope is the scope of the AAD access tokens exchanged for ACR refresh tokens.
+	acrAADScope = "https://management.azure.com/.default"
+	// acrDefaultRefreshTokenLifetime is used when the expiry of the refresh token cannot be read.
+	acrDefaultRefreshTokenLifetime = 3 * time.Hour
+	// acrTokenRefreshMargin is how long before their expiry the ACR refresh tokens are refreshed.
+	acrTokenRefreshMargin = 5 * time.Minute
+)
+
+// ErrACRAccessDenied is returned when ACR refuses to exchange the AAD token of the worker,
+// usually because its identity is not granted the AcrPull role on the registry.
+var ErrACRAccessDenied = errors.New("the worker identity is not allowed to access the registry, check it has the AcrPull role")
+
+// acrHostRegexp matches the hosts of the Azure Container Registries, e.g. myregistry.azurecr.io.
+var acrHostRegexp = regexp.MustCompile(`^[a-z0-9]+\.azurecr\.io$`)
+
+// acrGetAADTokenFunc returns an AAD access token of the worker identity.
+type acrGetAADTokenFunc func(ctx context.Context) (string, error)
+
+// ACRCredentialProvider obtains the credentials of the Azure Container Registries
+// by exchanging an AAD access token of the worker identity, e.g. the AKS workload identity
+// or a managed identity, for an ACR refresh token.
+// The refresh tokens are cached per registry and refreshed before they expire.
+type ACRCredentialProvider struct {
+	getAADToken acrGetAADTokenFunc
+	httpClient  *http.Client
+	now         func() time.Time
+	cache       *credentialsCache
+	logger      *slog.Logger
+}
+
+// NewACRCredentialProvider creates a new ACRCredentialProvider.
+func NewACRCredentialProvider(logger *slog.Logger) *ACRCredentialProvider {
+	return &ACRCredentialProvider{
+		getAADToken: newAzureAADTokenGetter(),
+		httpClient:  http.DefaultClient,
+		now:         time.Now,
+		cache:       newCredentialsCache(acrTokenRefreshMargin),
+		logger:      logger.With("component", "acr_credential_provider"),
+	}
+}
+
+// Name returns the name of the provider.
+func (p *ACRCredentialProvider) Name() string {
+	return ACRCredentialProviderName
+}
+
+// Matches returns true when the host is an Azure Container Registry.
+func (p *ACRCredentialProvider) Matches(host string) bool {
+	return acrHostRegexp.MatchString(host)
+}
+
+// Credentials returns an ACR refresh token of the worker identity for the registry host.
+// ErrNoCredentials is returned when the worker has no Azure identity,
+// so that the public images can still be pulled anonymously.
+func (p *ACRCredentialProvider) Credentials(ctx context.Context, host string) (authn.AuthConfig, error) {
+	if authConfig, ok := p.cache.get(host, p.now()); ok {
+		return authConfig, nil
+	}
+
+	aadToken, err := p.getAADToken(ctx)
+	if err != nil {
+		if errors.Is(err, ErrNoCredentials) {
+			p.logger.DebugContext(ctx, "No Azure identity found", "host", host, "error", err)
+			return authn.AuthConfig{}, err
+		}
+		return authn.AuthConfig{}, fmt.Errorf("cannot get AAD access token: %w", err)
+	}
+
+	p.logger.DebugContext(ctx, "Exchanging AAD access token for ACR refresh token", "host", host)
+	refreshToken, err := p.exchangeRefreshToken(ctx, host, aadToken)
+	if err != nil {
+		return authn.AuthConfig{}, err
+	}
+
+	authConfig := authn.AuthConfig{Username: acrRefreshTokenUsername, Password: refreshToken}
+	expiresAt := p.now().Add(acrDefaultRefreshTokenLifetime)
+	if jwtExpiresAt, err := jwtExpiry(refreshToken); err == nil {
+		expiresAt = jwtExpiresAt
+	}
+	p.cache.put(host, authConfig, expiresAt)
+	p.logger.DebugContext(ctx, "ACR refresh token obtained", "host", host, "expiresAt", expiresAt)
+
+	return authConfig, nil
+}
+
+// exchangeRefreshToken calls the ACR token exchange endpoint to get a refresh token for the AAD access token.
+func (p *ACRCredentialProvider) exchangeRefreshToken(ctx context.Context, host, aadToken string) (string, error) {
+	form := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {host},
+		"access_token": {aadToken},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/oauth2/exchange", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("cannot create ACR token exchange request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot call ACR token exchange endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("cannot read ACR token exchange response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return "", fmt.Errorf("%w: %s: %s", ErrACRAccessDenied, resp.Status, body)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("ACR token exchange failed: %s: %s", resp.Status, body)
+	}
+
+	var exchangeResponse struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(body, &exchangeResponse); err != nil {
+		return "", fmt.Errorf("cannot decode ACR token exchange response: %w", err)
+	}
+	if exchangeResponse.RefreshToken == "" {
+		return "", errors.New("ACR returned an empty refresh token")
+	}
+
+	return exchangeResponse.RefreshToken, nil
+}
+
+// jwtExpiry returns the expiry of a JWT, without verifying its signature.
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("invalid JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot decode JWT payload: %w", err)
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("cannot decode JWT claims: %w", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, errors.New("JWT has no expiry")
+	}
+
+	return time.Unix(claims.Exp, 0), nil
+}
+
+// newAzureAADTokenGetter returns a function getting the AAD access tokens with the default Azure credentials chain,
+// which includes the AKS workload identity and the managed identities.
+// The credential is created on first use, so that workers outside of Azure do not need it.
+// ErrNoCredentials is returned when the credential cannot be created, or when none of the sources of the chain
+// is available, rather than when an available identity fails to authenticate.
+func newAzureAADTokenGetter() acrGetAADTokenFunc {
+	var (
+		once          sync.Once
+		credential    *azidentity.DefaultAzureCredential
+		credentialErr error
+	)
+
+	return func(ctx context.Context) (string, error) {
+		once.Do(func() {
+			credential, credentialErr = azidentity.NewDefaultAzureCredential(nil)
+		})
+		if credentialErr != nil {
+			return "", fmt.Errorf("%w: cannot create Azure credential: %w", ErrNoCredentials, credentialErr)
+		}
+
+		token, err := credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{acrAADScope}})
+		if err != nil {
+			var authenticationFailedErr *azidentity.AuthenticationFailedError
+			if !errors.As(err, &authenticationFailedErr) {
+				return "", fmt.Errorf("%w: %w", ErrNoCredentials, err)
+			}
+			return "", fmt.Errorf("cannot get Azure token: %w", err)
+		}
+
+		return token.Token, nil
+	}
+}
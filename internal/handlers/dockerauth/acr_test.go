@@ -0,0 +1,124 @@
+package dockerauth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+func newTestJWT(t *testing.T, expiresAt time.Time) string {
+	t.Helper()
+
+	claims, err := json.Marshal(map[string]int64{"exp": expiresAt.Unix()})
+	require.NoError(t, err)
+
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(claims) + ".signature"
+}
+
+func TestACRCredentialProvider_Matches(t *testing.T) {
+	provider := NewACRCredentialProvider(slog.Default())
+
+	assert.True(t, provider.Matches("myregistry.azurecr.io"))
+	assert.False(t, provider.Matches("azurecr.io"))
+	assert.False(t, provider.Matches("myregistry.azurecr.io.example.com"))
+	assert.False(t, provider.Matches("ghcr.io"))
+}
+
+func TestACRCredentialProvider_Credentials(t *testing.T) {
+	now := time.Now()
+	refreshToken := newTestJWT(t, now.Add(3*time.Hour))
+	exchanges := 0
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/oauth2/exchange", r.URL.Path)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "access_token", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "aad-token", r.PostForm.Get("access_token"))
+		assert.Equal(t, r.Host, r.PostForm.Get("service"))
+
+		exchanges++
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]string{"refresh_token": refreshToken}))
+	}))
+	defer server.Close()
+
+	provider := NewACRCredentialProvider(slog.Default())
+	provider.httpClient = server.Client()
+	provider.now = func() time.Time { return now }
+	provider.getAADToken = func(_ context.Context) (string, error) {
+		return "aad-token", nil
+	}
+
+	host := strings.TrimPrefix(server.URL, "https://")
+	authConfig, err := provider.Credentials(t.Context(), host)
+	require.NoError(t, err)
+	assert.Equal(t, authn.AuthConfig{Username: acrRefreshTokenUsername, Password: refreshToken}, authConfig)
+
+	// The cached refresh token is used until the refresh margin.
+	now = now.Add(2 * time.Hour)
+	_, err = provider.Credentials(t.Context(), host)
+	require.NoError(t, err)
+	assert.Equal(t, 1, exchanges)
+
+	// The refresh token is refreshed before it expires.
+	now = now.Add(time.Hour)
+	_, err = provider.Credentials(t.Context(), host)
+	require.NoError(t, err)
+	assert.Equal(t, 2, exchanges)
+}
+
+func TestACRCredentialProvider_AccessDenied(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = fmt.Fprint(w, `{"errors":[{"code":"UNAUTHORIZED","message":"authentication required"}]}`)
+	}))
+	defer server.Close()
+
+	provider := NewACRCredentialProvider(slog.Default())
+	provider.httpClient = server.Client()
+	provider.getAADToken = func(_ context.Context) (string, error) {
+		return "aad-token", nil
+	}
+
+	_, err := provider.Credentials(t.Context(), strings.TrimPrefix(server.URL, "https://"))
+	require.ErrorIs(t, err, ErrACRAccessDenied)
+}
+
+func TestJWTExpiry(t *testing.T) {
+	expiresAt := time.Unix(1735689600, 0)
+
+	actual, err := jwtExpiry(newTestJWT(t, expiresAt))
+	require.NoError(t, err)
+	assert.Equal(t, expiresAt, actual)
+
+	_, err = jwtExpiry("not-a-jwt")
+	require.Error(t, err)
+}
+
+func TestACRCredentialProvider_NoCredentials(t *testing.T) {
+	provider := NewACRCredentialProvider(slog.Default())
+	provider.getAADToken = func(_ context.Context) (string, error) {
+		return "", fmt.Errorf("%w: no managed identity endpoint", ErrNoCredentials)
+	}
+
+	_, err := provider.Credentials(t.Context(), "myregistry.azurecr.io")
+	require.ErrorIs(t, err, ErrNoCredentials)
+
+	// Without credentials, the registry is accessed anonymously.
+	registry := &v1alpha1.Registry{Spec: v1alpha1.RegistrySpec{URI: "myregistry.azurecr.io"}}
+	dockerConfig, err := BuildDockerConfig(t.Context(), nil, CredentialProviders{provider}, registry, slog.Default())
+	require.NoError(t, err)
+	assert.Empty(t, dockerConfig)
+}
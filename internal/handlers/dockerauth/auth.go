@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/docker/cli/cli/config"
 	"github.com/docker/cli/cli/config/types"
@@ -14,12 +15,20 @@ import (
 	"github.com/kubewarden/sbomscanner/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // BuildDockerConfigForRegistry retrieve the Secret listed in the Registry resource
 // and creates the dockerconfig file.
+// The Secret is always read from the namespace of the Registry.
 func BuildDockerConfigForRegistry(ctx context.Context, k8sClient client.Client, registry *v1alpha1.Registry) (string, error) {
+	// The webhook rejects the invalid names, but the Registry could have been stored before it was enforced.
+	if errs := validation.IsDNS1123Subdomain(registry.Spec.AuthSecret); len(errs) > 0 {
+		return "", fmt.Errorf("authSecret %q is not the name of a Secret in the namespace %s of the Registry: %s",
+			registry.Spec.AuthSecret, registry.Namespace, strings.Join(errs, ", "))
+	}
+
 	authSecret := &corev1.Secret{}
 	err := k8sClient.Get(ctx, k8stypes.NamespacedName{
 		Name:      registry.Spec.AuthSecret,
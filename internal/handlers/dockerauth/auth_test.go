@@ -0,0 +1,86 @@
+package dockerauth
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+func TestBuildDockerConfigForRegistry_SecretNamespace(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "registry-credentials",
+			Namespace: "tenant-a",
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry.example.com":{"username":"user","password":"password"}}}`),
+		},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret).Build()
+
+	tests := []struct {
+		name       string
+		namespace  string
+		authSecret string
+		assertErr  func(t *testing.T, err error)
+	}{
+		{
+			name:       "secret in the namespace of the registry",
+			namespace:  "tenant-a",
+			authSecret: "registry-credentials",
+			assertErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:       "secret in another namespace",
+			namespace:  "tenant-b",
+			authSecret: "registry-credentials",
+			assertErr: func(t *testing.T, err error) {
+				require.Error(t, err)
+				assert.True(t, apierrors.IsNotFound(err))
+			},
+		},
+		{
+			name:       "reference to another namespace",
+			namespace:  "tenant-b",
+			authSecret: "tenant-a/registry-credentials",
+			assertErr: func(t *testing.T, err error) {
+				require.ErrorContains(t, err, "is not the name of a Secret in the namespace tenant-b of the Registry")
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			registry := &v1alpha1.Registry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "registry",
+					Namespace: test.namespace,
+				},
+				Spec: v1alpha1.RegistrySpec{
+					URI:        "registry.example.com",
+					AuthSecret: test.authSecret,
+				},
+			}
+
+			t.Setenv("DOCKER_CONFIG", "")
+			dockerConfig, err := BuildDockerConfigForRegistry(t.Context(), k8sClient, registry)
+			if dockerConfig != "" {
+				defer os.RemoveAll(dockerConfig)
+			}
+
+			test.assertErr(t, err)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package dockerauth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// cachedCredentials are credentials cached until shortly before they expire.
+type cachedCredentials struct {
+	authConfig authn.AuthConfig
+	expiresAt  time.Time
+}
+
+// credentialsCache caches the credentials obtained by a CredentialProvider, e.g. per registry host,
+// and drops them refreshMargin before they expire.
+// The lock is only held to read and write the cache, never while the credentials are requested,
+// so that a slow identity endpoint does not block the scans of the other registries.
+// The concurrent requests of the same missing credentials might all request them.
+type credentialsCache struct {
+	refreshMargin time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedCredentials
+}
+
+// newCredentialsCache creates a credentialsCache refreshing the credentials refreshMargin before they expire.
+func newCredentialsCache(refreshMargin time.Duration) *credentialsCache {
+	return &credentialsCache{
+		refreshMargin: refreshMargin,
+		entries:       map[string]cachedCredentials{},
+	}
+}
+
+// get returns the credentials cached for the key, unless they are within the refresh margin of their expiry at now.
+func (c *credentialsCache) get(key string, now time.Time) (authn.AuthConfig, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expiresAt.Add(-c.refreshMargin)) {
+		return authn.AuthConfig{}, false
+	}
+
+	return entry.authConfig, true
+}
+
+// put caches the credentials of the key until expiresAt.
+func (c *credentialsCache) put(key string, authConfig authn.AuthConfig, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = cachedCredentials{authConfig: authConfig, expiresAt: expiresAt}
+}
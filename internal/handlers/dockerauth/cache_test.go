@@ -0,0 +1,29 @@
+package dockerauth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialsCache(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	authConfig := authn.AuthConfig{Username: "user", Password: "secret"}
+	cache := newCredentialsCache(5 * time.Minute)
+
+	_, ok := cache.get("registry.example.com", now)
+	assert.False(t, ok)
+
+	cache.put("registry.example.com", authConfig, now.Add(time.Hour))
+	cached, ok := cache.get("registry.example.com", now.Add(54*time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, authConfig, cached)
+
+	_, ok = cache.get("registry.example.com", now.Add(55*time.Minute))
+	assert.False(t, ok, "the credentials are dropped within the refresh margin")
+
+	_, ok = cache.get("other.example.com", now)
+	assert.False(t, ok)
+}
@@ -1,4 +1,5 @@
 // Package dockerauth provides functions to setup Docker authentication
 // for private registries using the `config.json` file defined
-// inside of a Kubernetes Secret.
+// inside of a Kubernetes Secret, or the credentials obtained by
+// a CredentialProvider from the cloud identity of the worker.
 package dockerauth
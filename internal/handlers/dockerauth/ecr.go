@@ -0,0 +1,162 @@
+package dockerauth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+const (
+	// ECRCredentialProviderName is the name of the AWS ECR credential provider.
+	ECRCredentialProviderName = "ecr"
+
+	// ecrTokenRefreshMargin is how long before their expiry the ECR tokens are refreshed.
+	// ECR tokens are valid for 12 hours.
+	ecrTokenRefreshMargin = 30 * time.Minute
+)
+
+// ecrHostRegexp matches the hosts of the ECR private registries,
+// e.g. 123456789012.dkr.ecr.eu-west-1.amazonaws.com.
+var ecrHostRegexp = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+
+// ecrGetAuthorizationTokenFunc calls the ECR GetAuthorizationToken API of the region for the account.
+type ecrGetAuthorizationTokenFunc func(ctx context.Context, region, accountID string) (*ecr.GetAuthorizationTokenOutput, error)
+
+// ECRCredentialProvider obtains the credentials of the AWS ECR private registries
+// with the GetAuthorizationToken API, using the AWS credentials of the worker pod,
+// e.g. IAM Roles for Service Accounts (IRSA).
+// The tokens are cached per registry and refreshed before they expire.
+type ECRCredentialProvider struct {
+	getAuthorizationToken ecrGetAuthorizationTokenFunc
+	now                   func() time.Time
+	cache                 *credentialsCache
+	logger                *slog.Logger
+}
+
+// NewECRCredentialProvider creates a new ECRCredentialProvider.
+func NewECRCredentialProvider(logger *slog.Logger) *ECRCredentialProvider {
+	return &ECRCredentialProvider{
+		getAuthorizationToken: getECRAuthorizationToken,
+		now:                   time.Now,
+		cache:                 newCredentialsCache(ecrTokenRefreshMargin),
+		logger:                logger.With("component", "ecr_credential_provider"),
+	}
+}
+
+// Name returns the name of the provider.
+func (p *ECRCredentialProvider) Name() string {
+	return ECRCredentialProviderName
+}
+
+// Matches returns true when the host is an ECR private registry.
+func (p *ECRCredentialProvider) Matches(host string) bool {
+	return ecrHostRegexp.MatchString(host)
+}
+
+// Credentials returns the credentials of the ECR registry host.
+// The region and the account are taken from the host, so that registries of multiple regions
+// and accounts can be scanned by the same worker.
+// ErrNoCredentials is returned when the worker has no AWS credentials,
+// so that the public images can still be pulled anonymously.
+func (p *ECRCredentialProvider) Credentials(ctx context.Context, host string) (authn.AuthConfig, error) {
+	matches := ecrHostRegexp.FindStringSubmatch(host)
+	if matches == nil {
+		return authn.AuthConfig{}, fmt.Errorf("%s is not an ECR registry", host)
+	}
+	accountID, region := matches[1], matches[2]
+
+	if authConfig, ok := p.cache.get(host, p.now()); ok {
+		return authConfig, nil
+	}
+
+	p.logger.DebugContext(ctx, "Requesting ECR authorization token", "host", host, "region", region, "account", accountID)
+	output, err := p.getAuthorizationToken(ctx, region, accountID)
+	if err != nil {
+		return authn.AuthConfig{}, fmt.Errorf("cannot get ECR authorization token: %w", err)
+	}
+	if len(output.AuthorizationData) == 0 {
+		return authn.AuthConfig{}, errors.New("ECR returned no authorization data")
+	}
+
+	authorizationData := output.AuthorizationData[0]
+	if authorizationData.AuthorizationToken == nil {
+		return authn.AuthConfig{}, errors.New("ECR returned an empty authorization token")
+	}
+	authConfig, err := parseECRAuthorizationToken(*authorizationData.AuthorizationToken)
+	if err != nil {
+		return authn.AuthConfig{}, err
+	}
+
+	expiresAt := p.now().Add(12 * time.Hour)
+	if authorizationData.ExpiresAt != nil {
+		expiresAt = *authorizationData.ExpiresAt
+	}
+	p.cache.put(host, authConfig, expiresAt)
+	p.logger.DebugContext(ctx, "ECR authorization token obtained", "host", host, "expiresAt", expiresAt)
+
+	return authConfig, nil
+}
+
+// parseECRAuthorizationToken decodes the base64 encoded "user:password" ECR authorization token.
+func parseECRAuthorizationToken(authorizationToken string) (authn.AuthConfig, error) {
+	decoded, err := base64.StdEncoding.DecodeString(authorizationToken)
+	if err != nil {
+		return authn.AuthConfig{}, fmt.Errorf("cannot decode ECR authorization token: %w", err)
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return authn.AuthConfig{}, errors.New("invalid ECR authorization token")
+	}
+
+	return authn.AuthConfig{Username: username, Password: password}, nil
+}
+
+// getECRAuthorizationToken calls the ECR GetAuthorizationToken API with the default AWS credentials chain,
+// which includes the web identity token mounted by IRSA.
+// ErrNoCredentials is returned when no source of the chain is configured and the instance metadata service,
+// used as the last resort, provides no credentials. The failures of the configured sources are returned as they are.
+func getECRAuthorizationToken(ctx context.Context, region, accountID string) (*ecr.GetAuthorizationTokenOutput, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("cannot load AWS configuration: %w", err)
+	}
+	if cfg.Credentials == nil {
+		return nil, fmt.Errorf("%w: no AWS credentials provider", ErrNoCredentials)
+	}
+	if _, err = cfg.Credentials.Retrieve(ctx); err != nil {
+		if isIMDSFallback(cfg.Credentials) {
+			return nil, fmt.Errorf("%w: no AWS credentials source configured: %w", ErrNoCredentials, err)
+		}
+		return nil, fmt.Errorf("cannot retrieve AWS credentials: %w", err)
+	}
+
+	output, err := ecr.NewFromConfig(cfg).GetAuthorizationToken(ctx, &ecr.GetAuthorizationTokenInput{
+		RegistryIds: []string{accountID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot call ECR GetAuthorizationToken: %w", err)
+	}
+
+	return output, nil
+}
+
+// isIMDSFallback reports whether the credentials provider only uses the instance metadata service,
+// which the default credentials chain falls back to when none of its other sources is configured.
+func isIMDSFallback(provider aws.CredentialsProvider) bool {
+	source, ok := provider.(aws.CredentialProviderSource)
+	if !ok {
+		return false
+	}
+	return slices.Equal(source.ProviderSources(), []aws.CredentialSource{aws.CredentialSourceIMDS})
+}
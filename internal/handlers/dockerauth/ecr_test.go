@@ -0,0 +1,164 @@
+package dockerauth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestECRCredentialProvider_Matches(t *testing.T) {
+	provider := NewECRCredentialProvider(slog.Default())
+
+	assert.True(t, provider.Matches("123456789012.dkr.ecr.eu-west-1.amazonaws.com"))
+	assert.True(t, provider.Matches("123456789012.dkr.ecr-fips.us-east-1.amazonaws.com"))
+	assert.True(t, provider.Matches("123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn"))
+	assert.False(t, provider.Matches("public.ecr.aws"))
+	assert.False(t, provider.Matches("ghcr.io"))
+	assert.False(t, provider.Matches("123456789012.dkr.ecr.eu-west-1.amazonaws.com.example.com"))
+}
+
+func TestECRCredentialProvider_Credentials(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	token := base64.StdEncoding.EncodeToString([]byte("AWS:secret"))
+
+	type call struct {
+		region    string
+		accountID string
+	}
+	var calls []call
+
+	provider := NewECRCredentialProvider(slog.Default())
+	provider.now = func() time.Time { return now }
+	provider.getAuthorizationToken = func(_ context.Context, region, accountID string) (*ecr.GetAuthorizationTokenOutput, error) {
+		calls = append(calls, call{region: region, accountID: accountID})
+		expiresAt := now.Add(12 * time.Hour)
+		return &ecr.GetAuthorizationTokenOutput{
+			AuthorizationData: []ecrtypes.AuthorizationData{
+				{AuthorizationToken: &token, ExpiresAt: &expiresAt},
+			},
+		}, nil
+	}
+
+	host := "123456789012.dkr.ecr.eu-west-1.amazonaws.com"
+	authConfig, err := provider.Credentials(t.Context(), host)
+	require.NoError(t, err)
+	assert.Equal(t, authn.AuthConfig{Username: "AWS", Password: "secret"}, authConfig)
+
+	// The cached token is used until the refresh margin.
+	now = now.Add(11 * time.Hour)
+	_, err = provider.Credentials(t.Context(), host)
+	require.NoError(t, err)
+	assert.Len(t, calls, 1)
+
+	// The token is refreshed before it expires.
+	now = now.Add(45 * time.Minute)
+	_, err = provider.Credentials(t.Context(), host)
+	require.NoError(t, err)
+	assert.Len(t, calls, 2)
+
+	// Registries of other regions and accounts get their own token.
+	_, err = provider.Credentials(t.Context(), "210987654321.dkr.ecr.us-east-1.amazonaws.com")
+	require.NoError(t, err)
+	assert.Equal(t, []call{
+		{region: "eu-west-1", accountID: "123456789012"},
+		{region: "eu-west-1", accountID: "123456789012"},
+		{region: "us-east-1", accountID: "210987654321"},
+	}, calls)
+}
+
+func TestECRCredentialProvider_CredentialsErrors(t *testing.T) {
+	invalidToken := base64.StdEncoding.EncodeToString([]byte("invalid"))
+
+	tests := []struct {
+		name   string
+		host   string
+		output *ecr.GetAuthorizationTokenOutput
+		err    error
+	}{
+		{
+			name: "not an ECR registry",
+			host: "ghcr.io",
+		},
+		{
+			name: "API error",
+			host: "123456789012.dkr.ecr.eu-west-1.amazonaws.com",
+			err:  errors.New("no EC2 IMDS role found"),
+		},
+		{
+			name:   "no authorization data",
+			host:   "123456789012.dkr.ecr.eu-west-1.amazonaws.com",
+			output: &ecr.GetAuthorizationTokenOutput{},
+		},
+		{
+			name: "invalid token",
+			host: "123456789012.dkr.ecr.eu-west-1.amazonaws.com",
+			output: &ecr.GetAuthorizationTokenOutput{
+				AuthorizationData: []ecrtypes.AuthorizationData{{AuthorizationToken: &invalidToken}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider := NewECRCredentialProvider(slog.Default())
+			provider.getAuthorizationToken = func(_ context.Context, _, _ string) (*ecr.GetAuthorizationTokenOutput, error) {
+				return test.output, test.err
+			}
+
+			_, err := provider.Credentials(t.Context(), test.host)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestGetECRAuthorizationToken_NoCredentials(t *testing.T) {
+	// No source of the default credentials chain provides credentials.
+	dir := t.TempDir()
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", "")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	_, err := getECRAuthorizationToken(t.Context(), "eu-west-1", "123456789012")
+	require.ErrorIs(t, err, ErrNoCredentials)
+
+	// The registry is then accessed anonymously.
+	provider := NewECRCredentialProvider(slog.Default())
+	_, err = provider.Credentials(t.Context(), "123456789012.dkr.ecr.eu-west-1.amazonaws.com")
+	require.ErrorIs(t, err, ErrNoCredentials)
+}
+
+func TestGetECRAuthorizationToken_ConfiguredSourceFailure(t *testing.T) {
+	// The web identity token of IRSA is configured, but cannot be read.
+	dir := t.TempDir()
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/sbomscanner")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", filepath.Join(dir, "token"))
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", "")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	// The failure is reported instead of accessing the registry anonymously.
+	_, err := getECRAuthorizationToken(t.Context(), "eu-west-1", "123456789012")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrNoCredentials)
+}
@@ -0,0 +1,111 @@
+package dockerauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	// GCPCredentialProviderName is the name of the GCP Artifact Registry and Container Registry credential provider.
+	GCPCredentialProviderName = "gcp"
+
+	// gcpAccessTokenUsername is the username used to authenticate to the GCP registries with an OAuth2 access token.
+	gcpAccessTokenUsername = "oauth2accesstoken"
+	// gcpCloudPlatformScope is the OAuth2 scope granting access to the GCP registries.
+	gcpCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+	// gcpCacheKey is the key of the cached access token, which is the same for all the GCP registries.
+	gcpCacheKey = GCPCredentialProviderName
+	// gcpDefaultTokenLifetime is used when the expiry of the access token is unknown.
+	gcpDefaultTokenLifetime = time.Hour
+	// gcpTokenRefreshMargin is how long before their expiry the access tokens are refreshed.
+	gcpTokenRefreshMargin = 5 * time.Minute
+)
+
+// gcpHostRegexp matches the hosts of Container Registry (e.g. gcr.io, eu.gcr.io)
+// and Artifact Registry (e.g. europe-west1-docker.pkg.dev).
+var gcpHostRegexp = regexp.MustCompile(`^(?:[a-z]+\.)?gcr\.io$|^[a-z0-9-]+-docker\.pkg\.dev$`)
+
+// gcpNewTokenSourceFunc returns the source of the OAuth2 access tokens of the worker.
+type gcpNewTokenSourceFunc func(ctx context.Context) (oauth2.TokenSource, error)
+
+// GCPCredentialProvider obtains the credentials of the GCP Artifact Registry and Container Registry
+// from the Application Default Credentials (ADC) of the worker, e.g. the GKE workload identity
+// served by the metadata server.
+// The access tokens are cached and refreshed before they expire.
+type GCPCredentialProvider struct {
+	newTokenSource gcpNewTokenSourceFunc
+	now            func() time.Time
+	cache          *credentialsCache
+	logger         *slog.Logger
+}
+
+// NewGCPCredentialProvider creates a new GCPCredentialProvider.
+func NewGCPCredentialProvider(logger *slog.Logger) *GCPCredentialProvider {
+	return &GCPCredentialProvider{
+		newTokenSource: newGCPTokenSource,
+		now:            time.Now,
+		cache:          newCredentialsCache(gcpTokenRefreshMargin),
+		logger:         logger.With("component", "gcp_credential_provider"),
+	}
+}
+
+// Name returns the name of the provider.
+func (p *GCPCredentialProvider) Name() string {
+	return GCPCredentialProviderName
+}
+
+// Matches returns true when the host is an Artifact Registry or Container Registry host.
+func (p *GCPCredentialProvider) Matches(host string) bool {
+	return gcpHostRegexp.MatchString(host)
+}
+
+// Credentials returns an access token of the worker identity.
+// ErrNoCredentials is returned when the worker has no Application Default Credentials,
+// so that the public images can still be pulled anonymously.
+func (p *GCPCredentialProvider) Credentials(ctx context.Context, host string) (authn.AuthConfig, error) {
+	if authConfig, ok := p.cache.get(gcpCacheKey, p.now()); ok {
+		return authConfig, nil
+	}
+
+	tokenSource, err := p.newTokenSource(ctx)
+	if err != nil {
+		p.logger.DebugContext(ctx, "No GCP application default credentials found", "host", host, "error", err)
+		return authn.AuthConfig{}, fmt.Errorf("%w: %w", ErrNoCredentials, err)
+	}
+
+	token, err := tokenSource.Token()
+	if err != nil {
+		return authn.AuthConfig{}, fmt.Errorf("cannot get GCP access token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return authn.AuthConfig{}, errors.New("GCP returned an empty access token")
+	}
+
+	authConfig := authn.AuthConfig{Username: gcpAccessTokenUsername, Password: token.AccessToken}
+	expiresAt := token.Expiry
+	if expiresAt.IsZero() {
+		expiresAt = p.now().Add(gcpDefaultTokenLifetime)
+	}
+	p.cache.put(gcpCacheKey, authConfig, expiresAt)
+	p.logger.DebugContext(ctx, "GCP access token obtained", "host", host, "expiresAt", expiresAt)
+
+	return authConfig, nil
+}
+
+// newGCPTokenSource returns the token source of the Application Default Credentials.
+func newGCPTokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	credentials, err := google.FindDefaultCredentials(ctx, gcpCloudPlatformScope)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find GCP application default credentials: %w", err)
+	}
+
+	return credentials.TokenSource, nil
+}
@@ -0,0 +1,76 @@
+package dockerauth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+type fakeTokenSource struct {
+	calls int
+}
+
+func (s *fakeTokenSource) Token() (*oauth2.Token, error) {
+	s.calls++
+	return &oauth2.Token{AccessToken: "access-token", Expiry: time.Now().Add(time.Hour)}, nil
+}
+
+func TestGCPCredentialProvider_Matches(t *testing.T) {
+	provider := NewGCPCredentialProvider(slog.Default())
+
+	assert.True(t, provider.Matches("gcr.io"))
+	assert.True(t, provider.Matches("eu.gcr.io"))
+	assert.True(t, provider.Matches("europe-west1-docker.pkg.dev"))
+	assert.True(t, provider.Matches("us-docker.pkg.dev"))
+	assert.False(t, provider.Matches("ghcr.io"))
+	assert.False(t, provider.Matches("gcr.io.example.com"))
+	assert.False(t, provider.Matches("europe-west1-maven.pkg.dev"))
+}
+
+func TestGCPCredentialProvider_Credentials(t *testing.T) {
+	tokenSource := &fakeTokenSource{}
+	provider := NewGCPCredentialProvider(slog.Default())
+	provider.newTokenSource = func(_ context.Context) (oauth2.TokenSource, error) {
+		return tokenSource, nil
+	}
+
+	authConfig, err := provider.Credentials(t.Context(), "europe-west1-docker.pkg.dev")
+	require.NoError(t, err)
+	assert.Equal(t, authn.AuthConfig{Username: "oauth2accesstoken", Password: "access-token"}, authConfig)
+
+	// The token is reused for all the registries until the refresh margin.
+	_, err = provider.Credentials(t.Context(), "gcr.io")
+	require.NoError(t, err)
+	assert.Equal(t, 1, tokenSource.calls)
+
+	// The token is refreshed before it expires.
+	provider.now = func() time.Time { return time.Now().Add(58 * time.Minute) }
+	_, err = provider.Credentials(t.Context(), "gcr.io")
+	require.NoError(t, err)
+	assert.Equal(t, 2, tokenSource.calls)
+}
+
+func TestGCPCredentialProvider_NoCredentials(t *testing.T) {
+	provider := NewGCPCredentialProvider(slog.Default())
+	provider.newTokenSource = func(_ context.Context) (oauth2.TokenSource, error) {
+		return nil, errors.New("could not find default credentials")
+	}
+
+	_, err := provider.Credentials(t.Context(), "gcr.io")
+	require.ErrorIs(t, err, ErrNoCredentials)
+
+	// Without credentials, the registry is accessed anonymously.
+	registry := &v1alpha1.Registry{Spec: v1alpha1.RegistrySpec{URI: "gcr.io"}}
+	dockerConfig, err := BuildDockerConfig(t.Context(), nil, CredentialProviders{provider}, registry, slog.Default())
+	require.NoError(t, err)
+	assert.Empty(t, dockerConfig)
+}
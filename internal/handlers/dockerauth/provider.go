@@ -0,0 +1,151 @@
+package dockerauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+// ErrNoCredentials is returned by a CredentialProvider when the worker has no identity to authenticate with.
+// The registry is then accessed anonymously.
+var ErrNoCredentials = errors.New("no credentials available")
+
+// CredentialProvider obtains short-lived registry credentials from the identity of the worker,
+// e.g. a cloud workload identity, instead of reading them from a Secret.
+type CredentialProvider interface {
+	// Name returns the name of the provider, used in logs and errors.
+	Name() string
+	// Matches returns true when the provider can authenticate to the registry host.
+	Matches(host string) bool
+	// Credentials returns the credentials to authenticate to the registry host.
+	// Implementations cache the credentials and refresh them before they expire.
+	Credentials(ctx context.Context, host string) (authn.AuthConfig, error)
+}
+
+// CredentialProviders is the list of the credential providers available to the worker.
+type CredentialProviders []CredentialProvider
+
+// CredentialProviderNames are the names of the credential providers available to the worker.
+var CredentialProviderNames = []string{ECRCredentialProviderName, GCPCredentialProviderName, ACRCredentialProviderName}
+
+// NewCredentialProviders creates the credential providers, except the disabled ones.
+// An error is returned when a disabled provider does not exist.
+func NewCredentialProviders(disabled []string, logger *slog.Logger) (CredentialProviders, error) {
+	for _, name := range disabled {
+		if !slices.Contains(CredentialProviderNames, name) {
+			return nil, fmt.Errorf("unknown credential provider %q, must be one of %s", name, strings.Join(CredentialProviderNames, ", "))
+		}
+	}
+
+	var providers CredentialProviders
+	for _, name := range CredentialProviderNames {
+		if slices.Contains(disabled, name) {
+			continue
+		}
+		switch name {
+		case ECRCredentialProviderName:
+			providers = append(providers, NewECRCredentialProvider(logger))
+		case GCPCredentialProviderName:
+			providers = append(providers, NewGCPCredentialProvider(logger))
+		case ACRCredentialProviderName:
+			providers = append(providers, NewACRCredentialProvider(logger))
+		}
+	}
+
+	return providers, nil
+}
+
+// For returns the provider authenticating to the registry.
+// It returns nil when the registry has an authSecret, which takes precedence,
+// or when no provider matches the registry host.
+func (p CredentialProviders) For(registry *v1alpha1.Registry) CredentialProvider {
+	if registry.IsPrivate() {
+		return nil
+	}
+
+	host := registryHost(registry)
+	for _, provider := range p {
+		if provider.Matches(host) {
+			return provider
+		}
+	}
+
+	return nil
+}
+
+// BuildDockerConfig creates the dockerconfig file used to authenticate to the registry,
+// either from the authSecret of the registry or from the matching credential provider.
+// It returns an empty string when the registry does not need authentication,
+// or when the worker has no identity for the matching credential provider, so that the registry is accessed anonymously.
+func BuildDockerConfig(ctx context.Context, k8sClient client.Client, providers CredentialProviders, registry *v1alpha1.Registry, logger *slog.Logger) (string, error) {
+	if registry.IsPrivate() {
+		return BuildDockerConfigForRegistry(ctx, k8sClient, registry)
+	}
+
+	provider := providers.For(registry)
+	if provider == nil {
+		return "", nil
+	}
+
+	dockerConfig, err := BuildDockerConfigFromProvider(ctx, provider, registry)
+	if errors.Is(err, ErrNoCredentials) {
+		logger.WarnContext(ctx, "No credentials available for the registry, accessing it anonymously",
+			"registry", registry.Name,
+			"namespace", registry.Namespace,
+			"provider", provider.Name(),
+			"error", err,
+		)
+		return "", nil
+	}
+	return dockerConfig, err
+}
+
+// BuildDockerConfigFromProvider obtains the credentials of the registry from the provider
+// and creates the dockerconfig file.
+func BuildDockerConfigFromProvider(ctx context.Context, provider CredentialProvider, registry *v1alpha1.Registry) (string, error) {
+	host := registryHost(registry)
+	authConfig, err := provider.Credentials(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("cannot get %s credentials for %s: %w", provider.Name(), host, err)
+	}
+
+	data, err := json.Marshal(map[string]map[string]authn.AuthConfig{
+		"auths": {
+			registry.Spec.URI: authConfig,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal docker config: %w", err)
+	}
+	dockerConfig, err := createDockerConfigJSON(registry.Spec.URI, data)
+	if err != nil {
+		return "", fmt.Errorf("cannot create dockerconfig file: %w", err)
+	}
+
+	err = os.Setenv("DOCKER_CONFIG", dockerConfig)
+	if err != nil {
+		return "", fmt.Errorf("cannot set DOCKER_CONFIG env: %w", err)
+	}
+	return dockerConfig, nil
+}
+
+// registryHost returns the host of the registry, without the scheme and the path.
+func registryHost(registry *v1alpha1.Registry) string {
+	reg, err := name.NewRegistry(registry.Spec.URI)
+	if err != nil {
+		return registry.Spec.URI
+	}
+
+	return reg.RegistryStr()
+}
@@ -0,0 +1,113 @@
+package dockerauth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+type fakeCredentialProvider struct {
+	host       string
+	authConfig authn.AuthConfig
+	err        error
+}
+
+func (p *fakeCredentialProvider) Name() string {
+	return "fake"
+}
+
+func (p *fakeCredentialProvider) Matches(host string) bool {
+	return host == p.host
+}
+
+func (p *fakeCredentialProvider) Credentials(_ context.Context, _ string) (authn.AuthConfig, error) {
+	return p.authConfig, p.err
+}
+
+func TestCredentialProviders_For(t *testing.T) {
+	provider := &fakeCredentialProvider{host: "registry.example.com"}
+	providers := CredentialProviders{provider}
+
+	registry := &v1alpha1.Registry{Spec: v1alpha1.RegistrySpec{URI: "registry.example.com"}}
+	assert.Equal(t, provider, providers.For(registry))
+
+	registry.Spec.AuthSecret = "my-auth-secret"
+	assert.Nil(t, providers.For(registry), "the authSecret takes precedence")
+
+	registry = &v1alpha1.Registry{Spec: v1alpha1.RegistrySpec{URI: "ghcr.io"}}
+	assert.Nil(t, providers.For(registry))
+}
+
+func TestBuildDockerConfigFromProvider(t *testing.T) {
+	provider := &fakeCredentialProvider{
+		host:       "registry.example.com",
+		authConfig: authn.AuthConfig{Username: "AWS", Password: "secret"},
+	}
+	registry := &v1alpha1.Registry{Spec: v1alpha1.RegistrySpec{URI: "registry.example.com"}}
+
+	t.Setenv("DOCKER_CONFIG", "")
+	dockerConfig, err := BuildDockerConfigFromProvider(t.Context(), provider, registry)
+	require.NoError(t, err)
+	defer os.RemoveAll(dockerConfig)
+
+	assert.Equal(t, dockerConfig, os.Getenv("DOCKER_CONFIG"))
+
+	data, err := os.Open(path.Join(dockerConfig, "config.json"))
+	require.NoError(t, err)
+	defer data.Close()
+	cf, err := config.LoadFromReader(data)
+	require.NoError(t, err)
+
+	authConfig, err := cf.GetAuthConfig("registry.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "AWS", authConfig.Username)
+	assert.Equal(t, "secret", authConfig.Password)
+}
+
+func TestNewCredentialProviders(t *testing.T) {
+	providers, err := NewCredentialProviders(nil, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, providers, 3)
+	assert.Equal(t, ECRCredentialProviderName, providers[0].Name())
+	assert.Equal(t, GCPCredentialProviderName, providers[1].Name())
+	assert.Equal(t, ACRCredentialProviderName, providers[2].Name())
+
+	providers, err = NewCredentialProviders([]string{ECRCredentialProviderName, ACRCredentialProviderName}, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, providers, 1)
+	assert.Equal(t, GCPCredentialProviderName, providers[0].Name())
+
+	_, err = NewCredentialProviders([]string{"unknown"}, slog.Default())
+	require.Error(t, err)
+}
+
+func TestBuildDockerConfig_NoCredentials(t *testing.T) {
+	provider := &fakeCredentialProvider{
+		host: "123456789012.dkr.ecr.eu-west-1.amazonaws.com",
+		err:  fmt.Errorf("%w: no AWS identity", ErrNoCredentials),
+	}
+	registry := &v1alpha1.Registry{Spec: v1alpha1.RegistrySpec{URI: "123456789012.dkr.ecr.eu-west-1.amazonaws.com"}}
+
+	// The registry is accessed anonymously when the worker has no identity.
+	var logs bytes.Buffer
+	dockerConfig, err := BuildDockerConfig(t.Context(), nil, CredentialProviders{provider}, registry, slog.New(slog.NewTextHandler(&logs, nil)))
+	require.NoError(t, err)
+	assert.Empty(t, dockerConfig)
+	assert.Contains(t, logs.String(), "level=WARN")
+	assert.Contains(t, logs.String(), "accessing it anonymously")
+
+	provider.err = assert.AnError
+	_, err = BuildDockerConfig(t.Context(), nil, CredentialProviders{provider}, registry, slog.Default())
+	require.ErrorIs(t, err, assert.AnError)
+}
@@ -3,17 +3,26 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	_ "modernc.org/sqlite" // sqlite driver for RPM DB and Java DB
 
 	trivyCommands "github.com/aquasecurity/trivy/pkg/commands"
+	"github.com/aquasecurity/trivy/pkg/version/app"
+	xhttp "github.com/aquasecurity/trivy/pkg/x/http"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
@@ -21,17 +30,73 @@ import (
 	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
 	"github.com/kubewarden/sbomscanner/api/v1alpha1"
 	"github.com/kubewarden/sbomscanner/internal/handlers/dockerauth"
+	registryclient "github.com/kubewarden/sbomscanner/internal/handlers/registry"
+	"github.com/kubewarden/sbomscanner/internal/handlers/resumable"
 	"github.com/kubewarden/sbomscanner/internal/messaging"
+	"github.com/kubewarden/sbomscanner/internal/revocation"
+	"github.com/kubewarden/sbomscanner/internal/writebuffer"
 )
 
+// DefaultLayerDownloadConcurrency is the default maximum number of layers of an image
+// downloaded and analyzed concurrently, the same as the Trivy default.
+const DefaultLayerDownloadConcurrency = 5
+
 // GenerateSBOMHandler is responsible for handling SBOM generation requests.
 type GenerateSBOMHandler struct {
 	k8sClient             client.Client
 	scheme                *runtime.Scheme
 	workDir               string
 	trivyJavaDBRepository string
-	publisher             messaging.Publisher
-	logger                *slog.Logger
+	scanTimeout           time.Duration
+	// layerDownloadConcurrency bounds the number of layers of a single image downloaded concurrently.
+	layerDownloadConcurrency int
+	// imageLimits bounds the images whose layers are downloaded.
+	imageLimits ImageLimits
+	// sbomSchemaVersion is the schema version the stored SBOMs are converted to, e.g. "SPDX-2.3".
+	sbomSchemaVersion   string
+	publisher           messaging.Publisher
+	revocationChecker   *revocation.Checker
+	credentialProviders dockerauth.CredentialProviders
+	// registryAllowlist restricts the registry hosts the images are pulled from, nil allows all the hosts.
+	registryAllowlist *registryclient.HostAllowlist
+	// writeBuffer buffers the writes of the SBOMs, nil writes them right away.
+	writeBuffer *writebuffer.Buffer
+	// transport is the HTTP transport used by Trivy to pull the images, resuming the interrupted layer downloads.
+	transport http.RoundTripper
+	// recorder records the outcome of the SBOM generation on the images.
+	recorder record.EventRecorder
+	// workerID identifies the worker in the annotations of the SBOMs it produces.
+	workerID string
+	// allowSBOMReferrers allows the discovery of the SBOMs attached to the images.
+	allowSBOMReferrers bool
+	logger             *slog.Logger
+}
+
+// GenerateSBOMHandlerOptions configures the optional behaviors and collaborators of GenerateSBOMHandler.
+// The zero value is valid.
+type GenerateSBOMHandlerOptions struct {
+	// ScanTimeout bounds the SBOM generation of an image, 0 disables the timeout.
+	ScanTimeout time.Duration
+	// LayerDownloadConcurrency bounds the number of layers of a single image downloaded concurrently,
+	// DefaultLayerDownloadConcurrency when 0.
+	LayerDownloadConcurrency int
+	// ImageLimits bounds the images whose layers are downloaded.
+	ImageLimits ImageLimits
+	// SBOMSchemaVersion is the schema version the stored SBOMs are converted to, DefaultSBOMSchemaVersion when empty.
+	SBOMSchemaVersion string
+	// CredentialProviders authenticate the registries without an authSecret with the workload identity of the worker.
+	CredentialProviders dockerauth.CredentialProviders
+	// RegistryAllowlist restricts the registry hosts the images are pulled from, nil allows all the hosts.
+	RegistryAllowlist *registryclient.HostAllowlist
+	// WriteBuffer buffers the writes of the SBOMs, nil writes them right away.
+	WriteBuffer *writebuffer.Buffer
+	// Recorder records the outcome of the SBOM generation on the images, nil discards the events.
+	Recorder record.EventRecorder
+	// WorkerID identifies the worker in the annotations of the SBOMs it produces.
+	WorkerID string
+	// AllowSBOMReferrers allows the Registries enabling discoverSBOMReferrers to store the SBOMs attached to their images.
+	// The attached SBOMs are not verified, so the Registries cannot enable the discovery on their own.
+	AllowSBOMReferrers bool
 }
 
 // NewGenerateSBOMHandler creates a new instance of GenerateSBOMHandler.
@@ -41,15 +106,39 @@ func NewGenerateSBOMHandler(
 	workDir string,
 	trivyJavaDBRepository string,
 	publisher messaging.Publisher,
+	opts GenerateSBOMHandlerOptions,
 	logger *slog.Logger,
 ) *GenerateSBOMHandler {
+	if opts.LayerDownloadConcurrency <= 0 {
+		opts.LayerDownloadConcurrency = DefaultLayerDownloadConcurrency
+	}
+	if opts.SBOMSchemaVersion == "" {
+		opts.SBOMSchemaVersion = DefaultSBOMSchemaVersion
+	}
+	if opts.Recorder == nil {
+		// The fake recorder discards the events when it has no channel.
+		opts.Recorder = &record.FakeRecorder{}
+	}
+
 	return &GenerateSBOMHandler{
-		k8sClient:             k8sClient,
-		scheme:                scheme,
-		workDir:               workDir,
-		trivyJavaDBRepository: trivyJavaDBRepository,
-		publisher:             publisher,
-		logger:                logger.With("handler", "generate_sbom_handler"),
+		k8sClient:                k8sClient,
+		scheme:                   scheme,
+		workDir:                  workDir,
+		trivyJavaDBRepository:    trivyJavaDBRepository,
+		scanTimeout:              opts.ScanTimeout,
+		layerDownloadConcurrency: opts.LayerDownloadConcurrency,
+		imageLimits:              opts.ImageLimits,
+		sbomSchemaVersion:        opts.SBOMSchemaVersion,
+		publisher:                publisher,
+		revocationChecker:        revocation.NewChecker(logger),
+		credentialProviders:      opts.CredentialProviders,
+		registryAllowlist:        opts.RegistryAllowlist,
+		writeBuffer:              opts.WriteBuffer,
+		transport:                resumable.NewTransport(xhttp.NewTransport(xhttp.Options{}), resumable.DefaultMaxResumes, logger),
+		recorder:                 opts.Recorder,
+		workerID:                 opts.WorkerID,
+		allowSBOMReferrers:       opts.AllowSBOMReferrers,
+		logger:                   logger.With("handler", "generate_sbom_handler"),
 	}
 }
 
@@ -109,17 +198,50 @@ func (h *GenerateSBOMHandler) Handle(ctx context.Context, message messaging.Mess
 	h.logger.DebugContext(ctx, "Image found", "image", image)
 
 	// Retrieve the registry from the scan job annotations.
-	registryData, ok := scanJob.Annotations[v1alpha1.AnnotationScanJobRegistryKey]
-	if !ok {
-		return fmt.Errorf("scan job %s/%s does not have a registry annotation", scanJob.Namespace, scanJob.Name)
+	registry, err := scanJob.GetRegistryFromAnnotation()
+	if err != nil {
+		return fmt.Errorf("cannot get registry from scan job %s/%s: %w", scanJob.Namespace, scanJob.Name, err)
 	}
-	registry := &v1alpha1.Registry{}
-	if err = json.Unmarshal([]byte(registryData), registry); err != nil {
-		return fmt.Errorf("cannot unmarshal registry data from scan job %s/%s: %w", scanJob.Namespace, scanJob.Name, err)
+
+	// The allowlist is enforced before any connection to the registry.
+	if registryURI := image.GetImageMetadata().RegistryURI; !h.registryAllowlist.Allows(registryURI) {
+		message := fmt.Sprintf("Registry %s of image %s is not allowed, the allowed registry hosts are: %s", registryURI, image.Name, h.registryAllowlist)
+		return h.markScanJobFailed(ctx, scanJob, image, v1alpha1.ReasonRegistryNotAllowed, message)
 	}
 
-	sbom, err := h.getOrGenerateSBOM(ctx, image, registry, generateSBOMMessage)
+	sbom, err := h.getOrGenerateSBOM(ctx, image, registry, scanJob.EffectiveSBOMScope(registry), generateSBOMMessage)
 	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			// The scan timeout was exceeded: retrying would most likely time out again,
+			// so the ScanJob is marked as failed right away.
+			message := fmt.Sprintf("Scan of image %s exceeded the timeout of %s", image.Name, h.scanTimeoutFor(registry))
+			h.recordScanError(ctx, image, storagev1alpha1.ImageScanErrorReasonTimeout, message)
+			return h.markScanJobFailed(ctx, scanJob, image, v1alpha1.ReasonScanTimeout, message)
+		}
+		var mismatchErr *resumable.DigestMismatchError
+		if errors.As(err, &mismatchErr) {
+			// The registry sent a corrupted or altered blob: the SBOM would not describe the image,
+			// so the ScanJob is marked as failed instead of storing it.
+			message := fmt.Sprintf("A blob of image %s does not match its digest %s, got %s", image.Name, mismatchErr.Expected, mismatchErr.Actual)
+			h.recordScanError(ctx, image, storagev1alpha1.ImageScanErrorReasonGeneratorError, message)
+			return h.markScanJobFailed(ctx, scanJob, image, v1alpha1.ReasonBlobDigestMismatch, message)
+		}
+		var limitErr *ImageLimitExceededError
+		if errors.As(err, &limitErr) {
+			// The image would be rejected again by every retry.
+			message := fmt.Sprintf("Image %s exceeds the limits of the worker: %s", image.Name, limitErr.Message)
+			h.recordScanError(ctx, image, storagev1alpha1.ImageScanErrorReasonGeneratorError, message)
+			return h.markScanJobFailed(ctx, scanJob, image, v1alpha1.ReasonImageLimitExceeded, message)
+		}
+		reason := classifyScanError(err)
+		h.recordScanError(ctx, image, reason, err.Error())
+		if scanJobReason, terminal := terminalScanErrorReason(err, reason); terminal && ctx.Err() == nil {
+			// Retrying would fail again, e.g. the image was deleted from the registry.
+			message := fmt.Sprintf("Scan of image %s failed with an error that retrying would not fix: %s", image.Name, truncateScanErrorMessage(err.Error()))
+			return h.markScanJobFailed(ctx, scanJob, image, scanJobReason, message)
+		}
+		// The transient errors are retried: the message is delivered again with a backoff,
+		// the error is recorded so that it can be triaged in the meantime.
 		return fmt.Errorf("failed to get or generate SBOM: %w", err)
 	}
 
@@ -127,23 +249,61 @@ func (h *GenerateSBOMHandler) Handle(ctx context.Context, message messaging.Mess
 		return fmt.Errorf("failed to ack message as in progress: %w", err)
 	}
 
-	if err = h.k8sClient.Create(ctx, sbom); err != nil {
-		if apierrors.IsAlreadyExists(err) {
-			h.logger.InfoContext(ctx, "SBOM already exists, skipping creation", "sbom", generateSBOMMessage.Image.Name, "namespace", generateSBOMMessage.Image.Namespace)
-		} else {
+	write := &sbomWrite{
+		SBOM:    sbom,
+		Image:   image,
+		ScanJob: generateSBOMMessage.ScanJob,
+	}
+	if h.writeBuffer != nil {
+		// The SBOM is written, and its scan requested, in the background by the write buffer.
+		if err = h.writeBuffer.Enqueue(ctx, WriteKindSBOM, write); err != nil {
+			return fmt.Errorf("failed to buffer SBOM: %w", err)
+		}
+		return nil
+	}
+
+	return h.writeSBOM(ctx, write)
+}
+
+// WriteSBOM writes the SBOM buffered by Handle, and requests its scan. It is the writebuffer.WriteFunc of the SBOMs.
+func (h *GenerateSBOMHandler) WriteSBOM(ctx context.Context, data []byte) error {
+	write := &sbomWrite{}
+	if err := json.Unmarshal(data, write); err != nil {
+		return &writebuffer.PermanentError{Err: fmt.Errorf("failed to unmarshal SBOM write: %w", err)}
+	}
+
+	return permanentWriteError(h.writeSBOM(ctx, write))
+}
+
+// writeSBOM creates the SBOM, or reuses the one stored by a previous scan job of the image, and publishes its scan.
+func (h *GenerateSBOMHandler) writeSBOM(ctx context.Context, write *sbomWrite) error {
+	sbom := write.SBOM
+
+	// The SBOM stored by a previous scan job of the image is evaluated again, without pulling the image.
+	reevaluation := false
+	err := h.k8sClient.Create(ctx, sbom)
+	if err != nil {
+		if !apierrors.IsAlreadyExists(err) {
 			return fmt.Errorf("failed to create SBOM: %w", err)
 		}
+		if reevaluation, err = h.reuseOrReplaceSBOM(ctx, sbom); err != nil {
+			return err
+		}
+	}
+	if !reevaluation {
+		h.recorder.Eventf(write.Image, corev1.EventTypeNormal, v1alpha1.EventReasonSBOMGenerated, "SBOM generated by ScanJob %s", write.ScanJob.Name)
 	}
 
-	scanSBOMMessageID := fmt.Sprintf("scanSBOM/%s/%s", scanJob.UID, generateSBOMMessage.Image.Name)
+	scanSBOMMessageID := fmt.Sprintf("scanSBOM/%s/%s", write.ScanJob.UID, sbom.Name)
 	scanSBOMMessage, err := json.Marshal(&ScanSBOMMessage{
 		BaseMessage: BaseMessage{
-			ScanJob: generateSBOMMessage.ScanJob,
+			ScanJob: write.ScanJob,
 		},
 		SBOM: ObjectRef{
-			Name:      generateSBOMMessage.Image.Name,
-			Namespace: generateSBOMMessage.Image.Namespace,
+			Name:      sbom.Name,
+			Namespace: sbom.Namespace,
 		},
+		Reevaluation: reevaluation,
 	})
 	if err != nil {
 		return fmt.Errorf("cannot marshal scan SBOM message: %w", err)
@@ -156,29 +316,63 @@ func (h *GenerateSBOMHandler) Handle(ctx context.Context, message messaging.Mess
 	return nil
 }
 
-// getOrGenerateSBOM checks if an SBOM with the same digest exists and reuses it, or generates a new one.
-func (h *GenerateSBOMHandler) getOrGenerateSBOM(ctx context.Context, image *storagev1alpha1.Image, registry *v1alpha1.Registry, message *GenerateSBOMMessage) (*storagev1alpha1.SBOM, error) {
-	// Check if an SBOM with the same digest already exists
-	existingSBOM, err := h.findSBOMByDigest(ctx, image.GetImageMetadata().Digest, image.Namespace)
+// getOrGenerateSBOM checks if an SBOM with the same digest and scope exists and reuses it, or generates a new one.
+func (h *GenerateSBOMHandler) getOrGenerateSBOM(
+	ctx context.Context,
+	image *storagev1alpha1.Image,
+	registry *v1alpha1.Registry,
+	scope *v1alpha1.SBOMScope,
+	message *GenerateSBOMMessage,
+) (*storagev1alpha1.SBOM, error) {
+	// Check if an SBOM with the same digest and scope already exists
+	existingSBOM, err := h.findSBOMByDigest(ctx, image.GetImageMetadata().Digest, image.Namespace, scope.String())
 	if err != nil && !apierrors.IsNotFound(err) {
 		return nil, fmt.Errorf("failed to check for existing SBOM: %w", err)
 	}
 
 	var spdxBytes []byte
+	annotations := map[string]string{}
 	if existingSBOM != nil {
 		h.logger.InfoContext(ctx, "Found existing SBOM with matching digest, reusing content",
 			"sbom", existingSBOM.Name,
 			"digest", image.GetImageMetadata().Digest,
 		)
 		spdxBytes = existingSBOM.SPDX.Raw
+		for _, key := range sbomContentAnnotations {
+			if value, ok := existingSBOM.Annotations[key]; ok {
+				annotations[key] = value
+			}
+		}
 	} else {
-		h.logger.InfoContext(ctx, "No existing SBOM found, generating new one", "digest", image.GetImageMetadata().Digest)
-		spdxBytes, err = h.generateSPDX(ctx, image, registry)
-		if err != nil {
-			return nil, err
+		var referrer *referrerSBOM
+		if h.referrerDiscoveryEnabled(ctx, registry) {
+			referrer = h.discoverReferrerSBOM(ctx, image, registry)
+		}
+
+		if referrer != nil {
+			spdxBytes = referrer.SPDX
+			annotations[storagev1alpha1.AnnotationSBOMSourceKey] = storagev1alpha1.SBOMSourceReferrer
+			annotations[storagev1alpha1.AnnotationSBOMReferrerDigestKey] = referrer.Digest
+		} else {
+			h.logger.InfoContext(ctx, "No existing SBOM found, generating new one",
+				"digest", image.GetImageMetadata().Digest,
+				"scope", scope.String(),
+				"layerDownloadConcurrency", h.layerDownloadConcurrency,
+			)
+			spdxBytes, err = h.generateSPDXWithTimeout(ctx, image, registry, scope)
+			if err != nil {
+				return nil, err
+			}
+			annotations[storagev1alpha1.AnnotationSBOMScopeKey] = scope.String()
 		}
 	}
 
+	// The SBOMs are stored with the pinned schema version, whatever the version of their source.
+	if spdxBytes, err = convertSPDXVersion(spdxBytes, h.sbomSchemaVersion); err != nil {
+		return nil, fmt.Errorf("failed to convert SBOM to %s: %w", h.sbomSchemaVersion, err)
+	}
+	annotations[storagev1alpha1.AnnotationSBOMSchemaVersionKey] = h.sbomSchemaVersion
+
 	sbom := &storagev1alpha1.SBOM{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      message.Image.Name,
@@ -187,10 +381,12 @@ func (h *GenerateSBOMHandler) getOrGenerateSBOM(ctx context.Context, image *stor
 				api.LabelManagedByKey: api.LabelManagedByValue,
 				api.LabelPartOfKey:    api.LabelPartOfValue,
 			},
+			Annotations: annotations,
 		},
 		ImageMetadata: image.GetImageMetadata(),
 		SPDX:          runtime.RawExtension{Raw: spdxBytes},
 	}
+	setGeneratedBy(&sbom.ObjectMeta, h.workerID, time.Now())
 
 	if err := controllerutil.SetControllerReference(image, sbom, h.scheme); err != nil {
 		return nil, fmt.Errorf("failed to set owner reference: %w", err)
@@ -199,27 +395,243 @@ func (h *GenerateSBOMHandler) getOrGenerateSBOM(ctx context.Context, image *stor
 	return sbom, nil
 }
 
-// findSBOMByDigest searches for an existing SBOM with the given digest.
-func (h *GenerateSBOMHandler) findSBOMByDigest(ctx context.Context, digest string, namespace string) (*storagev1alpha1.SBOM, error) {
+// referrerDiscoveryEnabled reports whether the SBOMs attached to the images of the registry are discovered.
+// The discovery must be enabled both by the Registry and by the worker, since the attached SBOMs are trusted as they are.
+func (h *GenerateSBOMHandler) referrerDiscoveryEnabled(ctx context.Context, registry *v1alpha1.Registry) bool {
+	if !registry.Spec.DiscoverSBOMReferrers {
+		return false
+	}
+	if !h.allowSBOMReferrers {
+		h.logger.DebugContext(ctx, "SBOM referrers discovery is not allowed by the worker, ignoring discoverSBOMReferrers", "registry", registry.Name)
+		return false
+	}
+	return true
+}
+
+// discoverReferrerSBOM returns the SBOM attached to the image in the registry, if any.
+// Errors are logged and nil is returned, so that the SBOM is generated instead.
+func (h *GenerateSBOMHandler) discoverReferrerSBOM(ctx context.Context, image *storagev1alpha1.Image, registry *v1alpha1.Registry) *referrerSBOM {
+	imageRef := fmt.Sprintf(
+		"%s/%s@%s",
+		image.GetImageMetadata().RegistryURI,
+		image.GetImageMetadata().Repository,
+		image.GetImageMetadata().Digest,
+	)
+
+	cleanupRegistryAuth, err := h.setupRegistryAuth(ctx, registry)
+	if err != nil {
+		h.logger.WarnContext(ctx, "Cannot discover SBOM referrers, falling back to generation", "image", imageRef, "error", err)
+		return nil
+	}
+	defer cleanupRegistryAuth()
+
+	referrer, err := h.findReferrerSBOM(ctx, registry, imageRef)
+	if err != nil {
+		h.logger.WarnContext(ctx, "Cannot discover SBOM referrers, falling back to generation", "image", imageRef, "error", err)
+		return nil
+	}
+	if referrer == nil {
+		h.logger.DebugContext(ctx, "No SBOM referrer found", "image", imageRef)
+		return nil
+	}
+
+	h.logger.InfoContext(ctx, "Found SBOM attached to the image, skipping generation", "image", imageRef, "referrer", referrer.Digest)
+	return referrer
+}
+
+// findSBOMByDigest searches for an existing SBOM with the given digest and scope.
+func (h *GenerateSBOMHandler) findSBOMByDigest(ctx context.Context, digest string, namespace string, scope string) (*storagev1alpha1.SBOM, error) {
 	sbomList := &storagev1alpha1.SBOMList{}
 	err := h.k8sClient.List(ctx, sbomList,
 		client.InNamespace(namespace),
 		client.MatchingFields{storagev1alpha1.IndexImageMetadataDigest: digest},
-		client.Limit(1),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find SBOM by digest: %w", err)
 	}
 
-	if len(sbomList.Items) == 0 {
-		return nil, apierrors.NewNotFound(storagev1alpha1.Resource("sbom"), digest)
+	for i := range sbomList.Items {
+		if sbomScopeOf(&sbomList.Items[i]) == scope {
+			return &sbomList.Items[i], nil
+		}
 	}
 
-	return &sbomList.Items[0], nil
+	return nil, apierrors.NewNotFound(storagev1alpha1.Resource("sbom"), digest)
 }
 
-// generateSPDX generates SPDX JSON content for an image using Trivy.
-func (h *GenerateSBOMHandler) generateSPDX(ctx context.Context, image *storagev1alpha1.Image, registry *v1alpha1.Registry) ([]byte, error) {
+// reuseOrReplaceSBOM handles an SBOM which already exists for the image.
+// The existing SBOM is reused when it has the scope and the schema version of the new one, and true is returned,
+// otherwise its content is replaced by the one of the new SBOM, e.g. when the scope of the registry changed.
+func (h *GenerateSBOMHandler) reuseOrReplaceSBOM(ctx context.Context, sbom *storagev1alpha1.SBOM) (bool, error) {
+	existingSBOM := &storagev1alpha1.SBOM{}
+	if err := h.k8sClient.Get(ctx, client.ObjectKeyFromObject(sbom), existingSBOM); err != nil {
+		return false, fmt.Errorf("failed to get existing SBOM: %w", err)
+	}
+
+	if sbomScopeOf(existingSBOM) == sbomScopeOf(sbom) && sbomSchemaVersionOf(existingSBOM) == sbomSchemaVersionOf(sbom) {
+		h.logger.InfoContext(ctx, "SBOM already exists, skipping creation", "sbom", sbom.Name, "namespace", sbom.Namespace)
+		return true, nil
+	}
+
+	h.logger.InfoContext(ctx, "SBOM already exists with another scope or schema version, replacing it", "sbom", sbom.Name, "namespace", sbom.Namespace,
+		"scope", sbomScopeOf(sbom), "previousScope", sbomScopeOf(existingSBOM),
+		"schemaVersion", sbomSchemaVersionOf(sbom), "previousSchemaVersion", sbomSchemaVersionOf(existingSBOM))
+	for _, key := range sbomContentAnnotations {
+		delete(existingSBOM.Annotations, key)
+		if value, ok := sbom.Annotations[key]; ok {
+			metav1.SetMetaDataAnnotation(&existingSBOM.ObjectMeta, key, value)
+		}
+	}
+	setGeneratedBy(&existingSBOM.ObjectMeta, h.workerID, time.Now())
+	existingSBOM.SPDX = sbom.SPDX
+	if err := h.k8sClient.Update(ctx, existingSBOM); err != nil {
+		return false, fmt.Errorf("failed to replace SBOM: %w", err)
+	}
+
+	return false, nil
+}
+
+// scanTimeoutFor returns the scan timeout of the registry, falling back to the one of the handler.
+// A zero duration means no timeout.
+func (h *GenerateSBOMHandler) scanTimeoutFor(registry *v1alpha1.Registry) time.Duration {
+	if registry.Spec.ScanTimeout != nil {
+		return registry.Spec.ScanTimeout.Duration
+	}
+
+	return h.scanTimeout
+}
+
+// generateSPDXWithTimeout generates the SPDX content of the image, bounding the time spent pulling and analyzing it
+// with the scan timeout.
+// When the timeout is exceeded, the returned error wraps context.DeadlineExceeded.
+func (h *GenerateSBOMHandler) generateSPDXWithTimeout(
+	ctx context.Context,
+	image *storagev1alpha1.Image,
+	registry *v1alpha1.Registry,
+	scope *v1alpha1.SBOMScope,
+) ([]byte, error) {
+	scanTimeout := h.scanTimeoutFor(registry)
+	if scanTimeout <= 0 {
+		return h.generateSPDX(ctx, image, registry, scope)
+	}
+
+	scanCtx, cancel := context.WithTimeout(ctx, scanTimeout)
+	defer cancel()
+
+	spdxBytes, err := h.generateSPDX(scanCtx, image, registry, scope)
+	if err != nil && errors.Is(scanCtx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("scan of image %s/%s exceeded timeout of %s: %w", image.Namespace, image.Name, scanTimeout, context.DeadlineExceeded)
+	}
+
+	return spdxBytes, err
+}
+
+// markScanJobFailed marks the ScanJob as failed with the given reason, because the scan of the image
+// failed with an error that retrying would not fix.
+func (h *GenerateSBOMHandler) markScanJobFailed(ctx context.Context, scanJob *v1alpha1.ScanJob, image *storagev1alpha1.Image, reason, message string) error {
+	h.logger.InfoContext(ctx, "Image scan failed, marking ScanJob as failed",
+		"scanjob", scanJob.Name,
+		"namespace", scanJob.Namespace,
+		"image", image.Name,
+		"reason", reason,
+		"message", message,
+	)
+	h.recorder.Eventf(image, corev1.EventTypeWarning, v1alpha1.EventReasonScanFailed, "%s: %s", reason, message)
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := h.k8sClient.Get(ctx, client.ObjectKeyFromObject(scanJob), scanJob); err != nil {
+			return fmt.Errorf("cannot get scanjob %s/%s: %w", scanJob.Namespace, scanJob.Name, err)
+		}
+
+		scanJob.MarkFailed(reason, message)
+		return h.k8sClient.Status().Update(ctx, scanJob)
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			h.logger.InfoContext(ctx, "ScanJob not found, skipping updating ScanJob status to failed", "scanjob", scanJob.Name, "namespace", scanJob.Namespace)
+			return nil
+		}
+		return fmt.Errorf("failed to update ScanJob %s/%s status to failed: %w", scanJob.Namespace, scanJob.Name, err)
+	}
+
+	return nil
+}
+
+// recordScanError records the failure of the scan in the lastError status of the image,
+// so that it can be triaged without the logs of the workers. It is cleared by the next successful scan.
+// A failure to record the error is only logged, the scan failure being reported anyway.
+func (h *GenerateSBOMHandler) recordScanError(ctx context.Context, image *storagev1alpha1.Image, reason storagev1alpha1.ImageScanErrorReason, message string) {
+	if ctx.Err() != nil {
+		// The worker is shutting down, the scan is not failed.
+		return
+	}
+
+	patch := client.MergeFrom(image.DeepCopy())
+	image.Status.LastError = &storagev1alpha1.ImageScanError{
+		Reason:     reason,
+		Message:    truncateScanErrorMessage(message),
+		OccurredAt: metav1.Now(),
+	}
+	if err := h.k8sClient.Patch(ctx, image, patch); err != nil {
+		h.logger.ErrorContext(ctx, "Failed to record the scan error of the image",
+			"image", image.Name,
+			"namespace", image.Namespace,
+			"reason", reason,
+			"error", err,
+		)
+	}
+}
+
+// setupRegistryAuth sets up the Docker authentication to get access to the registry,
+// when its authSecret is set or a credential provider matches it.
+// The returned function removes the credentials and must always be called.
+func (h *GenerateSBOMHandler) setupRegistryAuth(ctx context.Context, registry *v1alpha1.Registry) (func(), error) {
+	dockerConfig, err := dockerauth.BuildDockerConfig(ctx, h.k8sClient, h.credentialProviders, registry, h.logger)
+	if err != nil {
+		return nil, fmt.Errorf("cannot setup docker auth for registry %s: %w", registry.Name, err)
+	}
+	if dockerConfig == "" {
+		return func() {}, nil
+	}
+	h.logger.DebugContext(ctx, "Setup registry authentication", "dockerconfig", os.Getenv("DOCKER_CONFIG"))
+
+	return func() {
+		if err := os.RemoveAll(dockerConfig); err != nil {
+			h.logger.Error("failed to remove dockerconfig directory", "error", err)
+		}
+		// unset the DOCKER_CONFIG variable so at every run
+		// we start from a clean environment.
+		if err := os.Unsetenv("DOCKER_CONFIG"); err != nil {
+			h.logger.Error("failed to unset DOCKER_CONFIG variable", "error", err)
+		}
+	}, nil
+}
+
+// trivyTransport returns the HTTP transport used by Trivy to pull the images of the registry.
+// When the revocation check of the registry is enabled, the certificates are checked on every connection
+// opened by Trivy, so that a certificate revoked during the scan is not missed.
+// The returned function closes the idle connections of the transport and must always be called.
+func (h *GenerateSBOMHandler) trivyTransport(registry *v1alpha1.Registry) (http.RoundTripper, func(), error) {
+	if registry.Spec.RevocationCheck == "" {
+		return h.transport, func() {}, nil
+	}
+
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		// should not happen
+		return nil, nil, errors.New("http.DefaultTransport is not an *http.Transport")
+	}
+	transport = transport.Clone()
+	transport.TLSClientConfig = tlsConfigFromRegistry(registry, h.logger)
+	transport.TLSClientConfig.VerifyConnection = h.revocationChecker.VerifyConnection(registry.Spec.RevocationCheck)
+	userAgentTransport := xhttp.NewUserAgent(transport, "trivy/"+app.Version())
+
+	return resumable.NewTransport(userAgentTransport, resumable.DefaultMaxResumes, h.logger), transport.CloseIdleConnections, nil
+}
+
+// generateSPDX generates SPDX JSON content for an image using Trivy, analyzing the packages of the given scope.
+// A nil scope analyzes all the packages.
+func (h *GenerateSBOMHandler) generateSPDX(ctx context.Context, image *storagev1alpha1.Image, registry *v1alpha1.Registry, scope *v1alpha1.SBOMScope) ([]byte, error) {
 	sbomFile, err := os.CreateTemp(h.workDir, "trivy.sbom.*.json")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temporary SBOM file: %w", err)
@@ -233,29 +645,26 @@ func (h *GenerateSBOMHandler) generateSPDX(ctx context.Context, image *storagev1
 		}
 	}()
 
-	// if authSecret value is set, then setup Docker
-	// authentication to get access to the registry
-	if registry.IsPrivate() {
-		var dockerConfig string
-		dockerConfig, err = dockerauth.BuildDockerConfigForRegistry(ctx, h.k8sClient, registry)
-		if err != nil {
-			return nil, fmt.Errorf("cannot setup docker auth for registry %s: %w", registry.Name, err)
+	cleanupRegistryAuth, err := h.setupRegistryAuth(ctx, registry)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanupRegistryAuth()
+
+	imageRef := fmt.Sprintf(
+		"%s/%s@%s",
+		image.GetImageMetadata().RegistryURI,
+		image.GetImageMetadata().Repository,
+		image.GetImageMetadata().Digest,
+	)
+
+	if h.imageLimits.Enabled() {
+		if err = h.checkImageLimits(ctx, registry, imageRef); err != nil {
+			return nil, err
 		}
-		h.logger.DebugContext(ctx, "Setup registry authentication", "dockerconfig", os.Getenv("DOCKER_CONFIG"))
-		defer func() {
-			if err = os.RemoveAll(dockerConfig); err != nil {
-				h.logger.Error("failed to remove dockerconfig directory", "error", err)
-			}
-			// unset the DOCKER_CONFIG variable so at every run
-			// we start from a clean environment.
-			if err = os.Unsetenv("DOCKER_CONFIG"); err != nil {
-				h.logger.Error("failed to unset DOCKER_CONFIG variable", "error", err)
-			}
-		}()
 	}
 
-	app := trivyCommands.NewApp()
-	app.SetArgs([]string{
+	args := []string{
 		"image",
 		"--skip-version-check",
 		"--disable-telemetry",
@@ -266,15 +675,40 @@ func (h *GenerateSBOMHandler) generateSPDX(ctx context.Context, image *storagev1
 		// See: https://github.com/aquasecurity/trivy/discussions/9666
 		"--java-db-repository", h.trivyJavaDBRepository,
 		"--output", sbomFile.Name(),
-		fmt.Sprintf(
-			"%s/%s@%s",
-			image.GetImageMetadata().RegistryURI,
-			image.GetImageMetadata().Repository,
-			image.GetImageMetadata().Digest,
-		),
-	})
+		// Trivy downloads the layers of the image while analyzing them,
+		// so the number of layers analyzed in parallel also bounds the concurrent blob downloads.
+		"--parallel", strconv.Itoa(h.layerDownloadConcurrency),
+	}
+	if scope.OSPackagesOnly() {
+		// The language analyzers are disabled, so that the application dependencies are not searched.
+		args = append(args, "--pkg-types", "os")
+	}
+	args = append(args, imageRef)
+
+	transport, closeTransport, err := h.trivyTransport(registry)
+	if err != nil {
+		return nil, err
+	}
+	defer closeTransport()
+	var sizeTransport *uncompressedSizeTransport
+	if h.imageLimits.MaxSize > 0 {
+		sizeTransport = newUncompressedSizeTransport(transport, imageRef, h.imageLimits.MaxSize)
+		transport = sizeTransport
+	}
+	// The requests are bound to the context, so that the scan timeout also cancels the stalled downloads.
+	transport = newScanContextTransport(ctx, transport)
 
-	if err = app.ExecuteContext(ctx); err != nil {
+	app := trivyCommands.NewApp()
+	app.SetArgs(args)
+
+	// The transport of the context takes precedence over the default one set by Trivy.
+	err = app.ExecuteContext(xhttp.WithTransport(ctx, transport))
+	if sizeTransport != nil {
+		if limitErr := sizeTransport.Err(); limitErr != nil {
+			return nil, limitErr
+		}
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to execute trivy: %w", err)
 	}
 
@@ -285,5 +719,11 @@ func (h *GenerateSBOMHandler) generateSPDX(ctx context.Context, image *storagev1
 		return nil, fmt.Errorf("failed to read SBOM output: %w", err)
 	}
 
+	if scope != nil && len(scope.Ecosystems) > 0 {
+		if spdxBytes, err = filterSPDXEcosystems(spdxBytes, scope.Ecosystems); err != nil {
+			return nil, fmt.Errorf("failed to restrict the SBOM to the ecosystems %v: %w", scope.Ecosystems, err)
+		}
+	}
+
 	return spdxBytes, nil
 }
@@ -3,11 +3,18 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -15,8 +22,11 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
 	"github.com/spdx/tools-golang/spdx"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -29,6 +39,7 @@ import (
 	"github.com/kubewarden/sbomscanner/pkg/generated/clientset/versioned/scheme"
 	corev1 "k8s.io/api/core/v1"
 	k8sscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestGenerateSBOMHandler_Handle(t *testing.T) {
@@ -169,7 +180,7 @@ func testGenerateSBOM(t *testing.T, platform, sha256, expectedSPDXJSON string) {
 		expectedScanMessage,
 	).Return(nil).Once()
 
-	handler := NewGenerateSBOMHandler(k8sClient, scheme, "/tmp", testTrivyJavaDBRepository, publisher, slog.Default())
+	handler := NewGenerateSBOMHandler(k8sClient, scheme, "/tmp", testTrivyJavaDBRepository, publisher, GenerateSBOMHandlerOptions{Recorder: record.NewFakeRecorder(10), WorkerID: testWorkerID}, slog.Default())
 
 	message, err := json.Marshal(&GenerateSBOMMessage{
 		BaseMessage: BaseMessage{
@@ -198,6 +209,8 @@ func testGenerateSBOM(t *testing.T, platform, sha256, expectedSPDXJSON string) {
 
 	assert.Equal(t, image.ImageMetadata, sbom.ImageMetadata)
 	assert.Equal(t, image.UID, sbom.GetOwnerReferences()[0].UID)
+	assert.Equal(t, testWorkerID, sbom.Annotations[storagev1alpha1.AnnotationGeneratedByKey])
+	assert.Equal(t, "SPDX-2.3", sbom.Annotations[storagev1alpha1.AnnotationSBOMSchemaVersionKey])
 
 	generatedSPDX := &spdx.Document{}
 	err = json.Unmarshal(sbom.SPDX.Raw, generatedSPDX)
@@ -320,7 +333,7 @@ func TestGenerateSBOMHandler_Handle_ReuseSBOMWithSameDigest(t *testing.T) {
 		expectedScanMessage,
 	).Return(nil).Once()
 
-	handler := NewGenerateSBOMHandler(k8sClient, scheme, "/tmp", testTrivyJavaDBRepository, publisher, slog.Default())
+	handler := NewGenerateSBOMHandler(k8sClient, scheme, "/tmp", testTrivyJavaDBRepository, publisher, GenerateSBOMHandlerOptions{Recorder: record.NewFakeRecorder(10), WorkerID: testWorkerID}, slog.Default())
 
 	message, err := json.Marshal(&GenerateSBOMMessage{
 		BaseMessage: BaseMessage{
@@ -349,6 +362,157 @@ func TestGenerateSBOMHandler_Handle_ReuseSBOMWithSameDigest(t *testing.T) {
 	assert.Equal(t, expectedSPDXContent, newSBOM.SPDX.Raw, "SPDX content should be reused from existing SBOM")
 	assert.Equal(t, newImage.ImageMetadata, newSBOM.ImageMetadata)
 	assert.Equal(t, newImage.UID, newSBOM.GetOwnerReferences()[0].UID)
+	assert.Equal(t, testWorkerID, newSBOM.Annotations[storagev1alpha1.AnnotationGeneratedByKey], "the SBOM should record the worker which wrote it")
+	assert.NotEmpty(t, newSBOM.Annotations[storagev1alpha1.AnnotationGeneratedAtKey])
+}
+
+func TestGenerateSBOMHandler_Handle_ReplaceSBOMWithAnotherScope(t *testing.T) {
+	digest := "sha256:1782cafde43390b032f960c0fad3def745fac18994ced169003cb56e9a93c028"
+
+	imageMetadata := storagev1alpha1.ImageMetadata{
+		Registry:    "ghcr",
+		RegistryURI: "ghcr.io/kubewarden/sbomscanner/test-assets",
+		Repository:  "golang",
+		Tag:         "latest",
+		Platform:    "linux/amd64",
+		Digest:      digest,
+	}
+
+	// The OS packages only SBOM of another image with the same digest, expected to be reused
+	expectedSPDXContent := []byte(`{"spdxVersion":"SPDX-2.3","dataLicense":"CC0-1.0","name":"os"}`)
+	osSBOM := &storagev1alpha1.SBOM{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-image",
+			Namespace: "default",
+			Annotations: map[string]string{
+				storagev1alpha1.AnnotationSBOMScopeKey: v1alpha1.SBOMScopePackagesOS,
+			},
+		},
+		ImageMetadata: imageMetadata,
+		SPDX:          runtime.RawExtension{Raw: expectedSPDXContent},
+	}
+
+	// The SBOM of the image, stored before the scope was set, including all the packages
+	existingSBOM := &storagev1alpha1.SBOM{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "image",
+			Namespace: "default",
+			UID:       "existing-sbom-uid",
+		},
+		ImageMetadata: imageMetadata,
+		SPDX:          runtime.RawExtension{Raw: []byte(`{"spdxVersion":"SPDX-2.3","dataLicense":"CC0-1.0","name":"all"}`)},
+	}
+
+	image := &storagev1alpha1.Image{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "image",
+			Namespace: "default",
+			UID:       "image-uid",
+		},
+		ImageMetadata: imageMetadata,
+	}
+
+	registry := &v1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-registry",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.RegistrySpec{
+			URI: "test.io",
+		},
+	}
+	registryData, err := json.Marshal(registry)
+	require.NoError(t, err)
+
+	scanJob := &v1alpha1.ScanJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scanjob",
+			Namespace: "default",
+			UID:       "test-scanjob-uid",
+			Annotations: map[string]string{
+				v1alpha1.AnnotationScanJobRegistryKey: string(registryData),
+			},
+		},
+		Spec: v1alpha1.ScanJobSpec{
+			Registry: "test-registry",
+			SBOMScope: &v1alpha1.SBOMScope{
+				Packages: v1alpha1.SBOMScopePackagesOS,
+			},
+		},
+	}
+
+	scheme := scheme.Scheme
+	err = storagev1alpha1.AddToScheme(scheme)
+	require.NoError(t, err)
+	err = v1alpha1.AddToScheme(scheme)
+	require.NoError(t, err)
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(osSBOM, existingSBOM, image, registry, scanJob).
+		WithIndex(&storagev1alpha1.SBOM{}, storagev1alpha1.IndexImageMetadataDigest, func(obj client.Object) []string {
+			sbom, ok := obj.(*storagev1alpha1.SBOM)
+			if !ok {
+				return nil
+			}
+			return []string{sbom.GetImageMetadata().Digest}
+		}).
+		Build()
+
+	publisher := messagingMocks.NewMockPublisher(t)
+
+	// The replaced SBOM is not a reevaluation, since its content changed.
+	expectedScanMessage, err := json.Marshal(&ScanSBOMMessage{
+		BaseMessage: BaseMessage{
+			ScanJob: ObjectRef{
+				Name:      scanJob.Name,
+				Namespace: scanJob.Namespace,
+				UID:       string(scanJob.UID),
+			},
+		},
+		SBOM: ObjectRef{
+			Name:      image.Name,
+			Namespace: image.Namespace,
+		},
+	})
+	require.NoError(t, err)
+
+	publisher.On("Publish",
+		mock.Anything,
+		ScanSBOMSubject,
+		fmt.Sprintf("scanSBOM/%s/%s", scanJob.UID, image.Name),
+		expectedScanMessage,
+	).Return(nil).Once()
+
+	handler := NewGenerateSBOMHandler(k8sClient, scheme, "/tmp", testTrivyJavaDBRepository, publisher, GenerateSBOMHandlerOptions{Recorder: record.NewFakeRecorder(10), WorkerID: testWorkerID}, slog.Default())
+
+	message, err := json.Marshal(&GenerateSBOMMessage{
+		BaseMessage: BaseMessage{
+			ScanJob: ObjectRef{
+				Name:      scanJob.Name,
+				Namespace: scanJob.Namespace,
+				UID:       string(scanJob.UID),
+			},
+		},
+		Image: ObjectRef{
+			Name:      image.Name,
+			Namespace: image.Namespace,
+		},
+	})
+	require.NoError(t, err)
+
+	err = handler.Handle(t.Context(), &testMessage{data: message})
+	require.NoError(t, err)
+
+	replacedSBOM := &storagev1alpha1.SBOM{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{
+		Name:      image.Name,
+		Namespace: image.Namespace,
+	}, replacedSBOM)
+	require.NoError(t, err)
+	assert.Equal(t, existingSBOM.UID, replacedSBOM.UID, "SBOM should be updated in place")
+	assert.Equal(t, expectedSPDXContent, replacedSBOM.SPDX.Raw, "SPDX content should be reused from the SBOM with the same scope")
+	assert.Equal(t, v1alpha1.SBOMScopePackagesOS, replacedSBOM.Annotations[storagev1alpha1.AnnotationSBOMScopeKey])
+	assert.Equal(t, testWorkerID, replacedSBOM.Annotations[storagev1alpha1.AnnotationGeneratedByKey])
 }
 
 func TestGenerateSBOMHandler_Handle_StopProcessing(t *testing.T) {
@@ -448,7 +612,7 @@ func TestGenerateSBOMHandler_Handle_StopProcessing(t *testing.T) {
 			publisher := messagingMocks.NewMockPublisher(t)
 			// Publisher should not be called since we exit early
 
-			handler := NewGenerateSBOMHandler(k8sClient, scheme, "/tmp", testTrivyJavaDBRepository, publisher, slog.Default())
+			handler := NewGenerateSBOMHandler(k8sClient, scheme, "/tmp", testTrivyJavaDBRepository, publisher, GenerateSBOMHandlerOptions{Recorder: record.NewFakeRecorder(10), WorkerID: testWorkerID}, slog.Default())
 
 			message, err := json.Marshal(&GenerateSBOMMessage{
 				BaseMessage: BaseMessage{
@@ -530,6 +694,127 @@ func TestGenerateSBOMHandler_Handle_ExistingSBOM(t *testing.T) {
 			UID:       "sbom-uid",
 		},
 		ImageMetadata: image.ImageMetadata,
+		SPDX:          runtime.RawExtension{Raw: []byte(`{"spdxVersion":"SPDX-2.3","dataLicense":"CC0-1.0","name":"existing"}`)},
+	}
+
+	scheme := scheme.Scheme
+	err = storagev1alpha1.AddToScheme(scheme)
+	require.NoError(t, err)
+	err = v1alpha1.AddToScheme(scheme)
+	require.NoError(t, err)
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(image, registry, scanJob, existingSBOM).
+		WithIndex(&storagev1alpha1.SBOM{}, storagev1alpha1.IndexImageMetadataDigest, func(obj client.Object) []string {
+			sbom, ok := obj.(*storagev1alpha1.SBOM)
+			if !ok {
+				return nil
+			}
+			return []string{sbom.GetImageMetadata().Digest}
+		}).
+		Build()
+
+	publisher := messagingMocks.NewMockPublisher(t)
+
+	expectedScanMessage, err := json.Marshal(&ScanSBOMMessage{
+		BaseMessage: BaseMessage{
+			ScanJob: ObjectRef{
+				Name:      scanJob.Name,
+				Namespace: scanJob.Namespace,
+				UID:       string(scanJob.UID),
+			},
+		},
+		SBOM: ObjectRef{
+			Name:      existingSBOM.Name,
+			Namespace: existingSBOM.Namespace,
+		},
+		Reevaluation: true,
+	})
+	require.NoError(t, err)
+
+	publisher.On("Publish",
+		mock.Anything,
+		ScanSBOMSubject,
+		fmt.Sprintf("scanSBOM/%s/%s", scanJob.UID, existingSBOM.Name),
+		expectedScanMessage,
+	).Return(nil).Once()
+
+	handler := NewGenerateSBOMHandler(k8sClient, scheme, "/tmp", testTrivyJavaDBRepository, publisher, GenerateSBOMHandlerOptions{Recorder: record.NewFakeRecorder(10), WorkerID: testWorkerID}, slog.Default())
+
+	message, err := json.Marshal(&GenerateSBOMMessage{
+		BaseMessage: BaseMessage{
+			ScanJob: ObjectRef{
+				Name:      scanJob.Name,
+				Namespace: scanJob.Namespace,
+				UID:       string(scanJob.UID),
+			},
+		},
+		Image: ObjectRef{
+			Name:      image.Name,
+			Namespace: image.Namespace,
+		},
+	})
+	require.NoError(t, err)
+
+	err = handler.Handle(t.Context(), &testMessage{data: message})
+	require.NoError(t, err)
+}
+
+func TestGenerateSBOMHandler_Handle_ReplaceSBOMWithAnotherSchemaVersion(t *testing.T) {
+	spdxData, err := os.ReadFile(filepath.Join("..", "..", "test", "fixtures", "golang-1.12-alpine-amd64.spdx.json"))
+	require.NoError(t, err)
+
+	image := &storagev1alpha1.Image{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-image",
+			Namespace: "default",
+			UID:       "image-uid",
+		},
+		ImageMetadata: storagev1alpha1.ImageMetadata{
+			Registry:    "ghcr",
+			RegistryURI: "ghcr.io/kubewarden/sbomscanner/test-assets",
+			Repository:  "golang",
+			Tag:         "1.12-alpine",
+			Platform:    "linux/amd64",
+			Digest:      "sha256:1782cafde43390b032f960c0fad3def745fac18994ced169003cb56e9a93c028",
+		},
+	}
+
+	registry := &v1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-registry",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.RegistrySpec{
+			URI: "test.io",
+		},
+	}
+	registryData, err := json.Marshal(registry)
+	require.NoError(t, err)
+
+	scanJob := &v1alpha1.ScanJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scanjob",
+			Namespace: "default",
+			Annotations: map[string]string{
+				v1alpha1.AnnotationScanJobRegistryKey: string(registryData),
+			},
+			UID: "scanjob-uid",
+		},
+		Spec: v1alpha1.ScanJobSpec{
+			Registry: "test-registry",
+		},
+	}
+
+	// The SBOM of the image, stored with the SPDX version generated by Trivy.
+	existingSBOM := &storagev1alpha1.SBOM{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-image",
+			Namespace: "default",
+			UID:       "sbom-uid",
+		},
+		ImageMetadata: image.ImageMetadata,
+		SPDX:          runtime.RawExtension{Raw: spdxData},
 	}
 
 	scheme := scheme.Scheme
@@ -551,6 +836,7 @@ func TestGenerateSBOMHandler_Handle_ExistingSBOM(t *testing.T) {
 
 	publisher := messagingMocks.NewMockPublisher(t)
 
+	// The converted SBOM is not a reevaluation, since its content changed.
 	expectedScanMessage, err := json.Marshal(&ScanSBOMMessage{
 		BaseMessage: BaseMessage{
 			ScanJob: ObjectRef{
@@ -573,7 +859,7 @@ func TestGenerateSBOMHandler_Handle_ExistingSBOM(t *testing.T) {
 		expectedScanMessage,
 	).Return(nil).Once()
 
-	handler := NewGenerateSBOMHandler(k8sClient, scheme, "/tmp", testTrivyJavaDBRepository, publisher, slog.Default())
+	handler := NewGenerateSBOMHandler(k8sClient, scheme, "/tmp", testTrivyJavaDBRepository, publisher, GenerateSBOMHandlerOptions{SBOMSchemaVersion: "SPDX-2.2", Recorder: record.NewFakeRecorder(10), WorkerID: testWorkerID}, slog.Default())
 
 	message, err := json.Marshal(&GenerateSBOMMessage{
 		BaseMessage: BaseMessage{
@@ -592,6 +878,13 @@ func TestGenerateSBOMHandler_Handle_ExistingSBOM(t *testing.T) {
 
 	err = handler.Handle(t.Context(), &testMessage{data: message})
 	require.NoError(t, err)
+
+	replacedSBOM := &storagev1alpha1.SBOM{}
+	err = k8sClient.Get(t.Context(), client.ObjectKeyFromObject(existingSBOM), replacedSBOM)
+	require.NoError(t, err)
+	assert.Equal(t, existingSBOM.UID, replacedSBOM.UID, "SBOM should be updated in place")
+	assert.Equal(t, "SPDX-2.2", replacedSBOM.Annotations[storagev1alpha1.AnnotationSBOMSchemaVersionKey])
+	assert.Equal(t, "SPDX-2.2", spdxVersionOf(replacedSBOM.SPDX.Raw), "the SPDX document should be converted to the pinned version")
 }
 
 func TestGenerateSBOMHandler_Handle_PrivateRegistry(t *testing.T) {
@@ -704,7 +997,7 @@ func TestGenerateSBOMHandler_Handle_PrivateRegistry(t *testing.T) {
 		expectedScanMessage,
 	).Return(nil).Once()
 
-	handler := NewGenerateSBOMHandler(k8sClient, scheme, "/tmp", testTrivyJavaDBRepository, publisher, slog.Default())
+	handler := NewGenerateSBOMHandler(k8sClient, scheme, "/tmp", testTrivyJavaDBRepository, publisher, GenerateSBOMHandlerOptions{Recorder: record.NewFakeRecorder(10), WorkerID: testWorkerID}, slog.Default())
 
 	message, err := json.Marshal(&GenerateSBOMMessage{
 		BaseMessage: BaseMessage{
@@ -724,3 +1017,571 @@ func TestGenerateSBOMHandler_Handle_PrivateRegistry(t *testing.T) {
 	err = handler.Handle(t.Context(), &testMessage{data: message})
 	require.NoError(t, err)
 }
+
+func TestGenerateSBOMHandler_Handle_BlobDigestMismatch(t *testing.T) {
+	// The registry corrupts the last byte of the blobs it serves.
+	registryHandler := ggcrregistry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !strings.Contains(r.URL.Path, "/blobs/") {
+			registryHandler.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := httptest.NewRecorder()
+		registryHandler.ServeHTTP(recorder, r)
+		body := recorder.Body.Bytes()
+		if recorder.Code == http.StatusOK && len(body) > 0 {
+			body[len(body)-1] ^= 0xff
+		}
+		maps.Copy(w.Header(), recorder.Header())
+		w.WriteHeader(recorder.Code)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	registryURI := strings.TrimPrefix(server.URL, "http://")
+	repository, err := name.NewRepository(registryURI + "/test")
+	require.NoError(t, err)
+	ref := pushTestImage(t, repository)
+
+	image := &storagev1alpha1.Image{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-image",
+			Namespace: "default",
+			UID:       "test-image-uid",
+		},
+		ImageMetadata: storagev1alpha1.ImageMetadata{
+			Registry:    "test-registry",
+			RegistryURI: registryURI,
+			Repository:  "test",
+			Tag:         "latest",
+			Platform:    "linux/amd64",
+			Digest:      ref.DigestStr(),
+		},
+	}
+
+	registry := &v1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-registry",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.RegistrySpec{
+			URI: registryURI,
+		},
+	}
+	registryData, err := json.Marshal(registry)
+	require.NoError(t, err)
+
+	scanJob := &v1alpha1.ScanJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scanjob",
+			Namespace: "default",
+			UID:       "test-scanjob-uid",
+			Annotations: map[string]string{
+				v1alpha1.AnnotationScanJobRegistryKey: string(registryData),
+			},
+		},
+		Spec: v1alpha1.ScanJobSpec{
+			Registry: "test-registry",
+		},
+	}
+	scanJob.InitializeConditions()
+
+	scheme := scheme.Scheme
+	require.NoError(t, storagev1alpha1.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(image, registry, scanJob).
+		WithStatusSubresource(&v1alpha1.ScanJob{}).
+		WithIndex(&storagev1alpha1.SBOM{}, storagev1alpha1.IndexImageMetadataDigest, func(obj client.Object) []string {
+			sbom, ok := obj.(*storagev1alpha1.SBOM)
+			if !ok {
+				return nil
+			}
+			return []string{sbom.GetImageMetadata().Digest}
+		}).
+		Build()
+
+	publisher := messagingMocks.NewMockPublisher(t)
+
+	handler := NewGenerateSBOMHandler(k8sClient, scheme, t.TempDir(), testTrivyJavaDBRepository, publisher, GenerateSBOMHandlerOptions{Recorder: record.NewFakeRecorder(10), WorkerID: testWorkerID}, slog.Default())
+
+	message, err := json.Marshal(&GenerateSBOMMessage{
+		BaseMessage: BaseMessage{
+			ScanJob: ObjectRef{
+				Name:      scanJob.Name,
+				Namespace: scanJob.Namespace,
+				UID:       string(scanJob.UID),
+			},
+		},
+		Image: ObjectRef{
+			Name:      image.Name,
+			Namespace: image.Namespace,
+		},
+	})
+	require.NoError(t, err)
+
+	err = handler.Handle(t.Context(), &testMessage{data: message})
+	require.NoError(t, err)
+
+	updatedScanJob := &v1alpha1.ScanJob{}
+	require.NoError(t, k8sClient.Get(t.Context(), client.ObjectKeyFromObject(scanJob), updatedScanJob))
+	assert.True(t, updatedScanJob.IsFailed())
+	failedCondition := meta.FindStatusCondition(updatedScanJob.Status.Conditions, v1alpha1.ConditionTypeFailed)
+	require.NotNil(t, failedCondition)
+	assert.Equal(t, v1alpha1.ReasonBlobDigestMismatch, failedCondition.Reason)
+	assert.Contains(t, failedCondition.Message, "does not match its digest")
+
+	updatedImage := &storagev1alpha1.Image{}
+	require.NoError(t, k8sClient.Get(t.Context(), client.ObjectKeyFromObject(image), updatedImage))
+	require.NotNil(t, updatedImage.Status.LastError)
+	assert.Equal(t, storagev1alpha1.ImageScanErrorReasonGeneratorError, updatedImage.Status.LastError.Reason)
+	assert.Contains(t, updatedImage.Status.LastError.Message, "does not match its digest")
+
+	err = k8sClient.Get(t.Context(), client.ObjectKeyFromObject(image), &storagev1alpha1.SBOM{})
+	assert.True(t, apierrors.IsNotFound(err), "no SBOM should be stored for the corrupted image")
+}
+
+func TestGenerateSBOMHandler_Handle_ScanTimeout(t *testing.T) {
+	// The registry hangs on the blob downloads, once the image is pushed.
+	registryHandler := ggcrregistry.New()
+	var hang atomic.Bool
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hang.Load() || r.Method != http.MethodGet || !strings.Contains(r.URL.Path, "/blobs/") {
+			registryHandler.ServeHTTP(w, r)
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	registryURI := strings.TrimPrefix(server.URL, "http://")
+	repository, err := name.NewRepository(registryURI + "/test")
+	require.NoError(t, err)
+	ref := pushTestImage(t, repository)
+	hang.Store(true)
+
+	image := &storagev1alpha1.Image{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-image",
+			Namespace: "default",
+			UID:       "test-image-uid",
+		},
+		ImageMetadata: storagev1alpha1.ImageMetadata{
+			Registry:    "test-registry",
+			RegistryURI: registryURI,
+			Repository:  "test",
+			Tag:         "latest",
+			Platform:    "linux/amd64",
+			Digest:      ref.DigestStr(),
+		},
+	}
+
+	registry := &v1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-registry",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.RegistrySpec{
+			URI:         registryURI,
+			ScanTimeout: &metav1.Duration{Duration: 2 * time.Second},
+		},
+	}
+	registryData, err := json.Marshal(registry)
+	require.NoError(t, err)
+
+	scanJob := &v1alpha1.ScanJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scanjob",
+			Namespace: "default",
+			UID:       "test-scanjob-uid",
+			Annotations: map[string]string{
+				v1alpha1.AnnotationScanJobRegistryKey: string(registryData),
+			},
+		},
+		Spec: v1alpha1.ScanJobSpec{
+			Registry: "test-registry",
+		},
+	}
+	scanJob.InitializeConditions()
+
+	scheme := scheme.Scheme
+	require.NoError(t, storagev1alpha1.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(image, registry, scanJob).
+		WithStatusSubresource(&v1alpha1.ScanJob{}).
+		WithIndex(&storagev1alpha1.SBOM{}, storagev1alpha1.IndexImageMetadataDigest, func(obj client.Object) []string {
+			sbom, ok := obj.(*storagev1alpha1.SBOM)
+			if !ok {
+				return nil
+			}
+			return []string{sbom.GetImageMetadata().Digest}
+		}).
+		Build()
+
+	publisher := messagingMocks.NewMockPublisher(t)
+
+	recorder := record.NewFakeRecorder(10)
+
+	handler := NewGenerateSBOMHandler(k8sClient, scheme, t.TempDir(), testTrivyJavaDBRepository, publisher, GenerateSBOMHandlerOptions{ScanTimeout: time.Hour, Recorder: recorder, WorkerID: testWorkerID}, slog.Default())
+
+	message, err := json.Marshal(&GenerateSBOMMessage{
+		BaseMessage: BaseMessage{
+			ScanJob: ObjectRef{
+				Name:      scanJob.Name,
+				Namespace: scanJob.Namespace,
+				UID:       string(scanJob.UID),
+			},
+		},
+		Image: ObjectRef{
+			Name:      image.Name,
+			Namespace: image.Namespace,
+		},
+	})
+	require.NoError(t, err)
+
+	// The timeout fails the scan instead of being retried.
+	err = handler.Handle(t.Context(), &testMessage{data: message})
+	require.NoError(t, err)
+
+	updatedScanJob := &v1alpha1.ScanJob{}
+	require.NoError(t, k8sClient.Get(t.Context(), client.ObjectKeyFromObject(scanJob), updatedScanJob))
+	assert.True(t, updatedScanJob.IsFailed())
+	failedCondition := meta.FindStatusCondition(updatedScanJob.Status.Conditions, v1alpha1.ConditionTypeFailed)
+	require.NotNil(t, failedCondition)
+	assert.Equal(t, v1alpha1.ReasonScanTimeout, failedCondition.Reason)
+	assert.Contains(t, failedCondition.Message, "exceeded the timeout of 2s")
+
+	updatedImage := &storagev1alpha1.Image{}
+	require.NoError(t, k8sClient.Get(t.Context(), client.ObjectKeyFromObject(image), updatedImage))
+	require.NotNil(t, updatedImage.Status.LastError)
+	assert.Equal(t, storagev1alpha1.ImageScanErrorReasonTimeout, updatedImage.Status.LastError.Reason)
+	assert.Equal(t, storagev1alpha1.ImageScanPhaseFailed, updatedImage.Status.ScanPhase())
+	assert.Contains(t, <-recorder.Events, "Warning ScanFailed "+v1alpha1.ReasonScanTimeout)
+
+	err = k8sClient.Get(t.Context(), client.ObjectKeyFromObject(image), &storagev1alpha1.SBOM{})
+	assert.True(t, apierrors.IsNotFound(err), "no SBOM should be stored for the image which timed out")
+}
+
+func TestGenerateSBOMHandler_Handle_RegistryInAnotherNamespace(t *testing.T) {
+	image := &storagev1alpha1.Image{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-image",
+			Namespace: "default",
+		},
+		ImageMetadata: storagev1alpha1.ImageMetadata{
+			Registry:    "test-registry",
+			RegistryURI: "registry.example.com",
+			Repository:  "test",
+			Tag:         "latest",
+			Platform:    "linux/amd64",
+			Digest:      "sha256:1782cafde43390b032f960c0fad3def745fac18994ced169003cb56e9a93c028",
+		},
+	}
+
+	// The registry of another tenant, whose credentials must not be used.
+	registry := &v1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-registry",
+			Namespace: "other-tenant",
+		},
+		Spec: v1alpha1.RegistrySpec{
+			URI:        "registry.example.com",
+			AuthSecret: "registry-credentials",
+		},
+	}
+	registryData, err := json.Marshal(registry)
+	require.NoError(t, err)
+
+	scanJob := &v1alpha1.ScanJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scanjob",
+			Namespace: "default",
+			UID:       "test-scanjob-uid",
+			Annotations: map[string]string{
+				v1alpha1.AnnotationScanJobRegistryKey: string(registryData),
+			},
+		},
+		Spec: v1alpha1.ScanJobSpec{
+			Registry: "test-registry",
+		},
+	}
+
+	scheme := scheme.Scheme
+	require.NoError(t, storagev1alpha1.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(image, scanJob).
+		Build()
+
+	publisher := messagingMocks.NewMockPublisher(t)
+
+	handler := NewGenerateSBOMHandler(k8sClient, scheme, "/tmp", testTrivyJavaDBRepository, publisher, GenerateSBOMHandlerOptions{Recorder: record.NewFakeRecorder(10), WorkerID: testWorkerID}, slog.Default())
+
+	message, err := json.Marshal(&GenerateSBOMMessage{
+		BaseMessage: BaseMessage{
+			ScanJob: ObjectRef{
+				Name:      scanJob.Name,
+				Namespace: scanJob.Namespace,
+				UID:       string(scanJob.UID),
+			},
+		},
+		Image: ObjectRef{
+			Name:      image.Name,
+			Namespace: image.Namespace,
+		},
+	})
+	require.NoError(t, err)
+
+	err = handler.Handle(t.Context(), &testMessage{data: message})
+	require.ErrorContains(t, err, "is not in the namespace default of the ScanJob")
+}
+
+func TestGenerateSBOMHandler_scanTimeoutFor(t *testing.T) {
+	handler := NewGenerateSBOMHandler(nil, nil, "/tmp", testTrivyJavaDBRepository, nil, GenerateSBOMHandlerOptions{ScanTimeout: 30 * time.Minute}, slog.Default())
+
+	registry := &v1alpha1.Registry{}
+	assert.Equal(t, 30*time.Minute, handler.scanTimeoutFor(registry))
+
+	registry.Spec.ScanTimeout = &metav1.Duration{Duration: 5 * time.Minute}
+	assert.Equal(t, 5*time.Minute, handler.scanTimeoutFor(registry))
+}
+
+func TestGenerateSBOMHandler_referrerDiscoveryEnabled(t *testing.T) {
+	tests := []struct {
+		name                  string
+		allowSBOMReferrers    bool
+		discoverSBOMReferrers bool
+		expected              bool
+	}{
+		{name: "disabled", expected: false},
+		{name: "enabled by the registry only", discoverSBOMReferrers: true, expected: false},
+		{name: "allowed by the worker only", allowSBOMReferrers: true, expected: false},
+		{name: "enabled by the registry and allowed by the worker", allowSBOMReferrers: true, discoverSBOMReferrers: true, expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := NewGenerateSBOMHandler(nil, nil, "/tmp", testTrivyJavaDBRepository, nil, GenerateSBOMHandlerOptions{AllowSBOMReferrers: test.allowSBOMReferrers}, slog.Default())
+
+			registry := &v1alpha1.Registry{Spec: v1alpha1.RegistrySpec{DiscoverSBOMReferrers: test.discoverSBOMReferrers}}
+			assert.Equal(t, test.expected, handler.referrerDiscoveryEnabled(t.Context(), registry))
+		})
+	}
+}
+
+func TestGenerateSBOMHandler_trivyTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	handler := NewGenerateSBOMHandler(nil, nil, "/tmp", testTrivyJavaDBRepository, nil, GenerateSBOMHandlerOptions{}, slog.Default())
+
+	get := func(t *testing.T, registry *v1alpha1.Registry) error {
+		t.Helper()
+		transport, closeTransport, err := handler.trivyTransport(registry)
+		require.NoError(t, err)
+		defer closeTransport()
+
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL+"/v2/", nil)
+		require.NoError(t, err)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			return err
+		}
+		require.NoError(t, resp.Body.Close())
+
+		return nil
+	}
+
+	t.Run("without revocation check", func(t *testing.T) {
+		transport, closeTransport, err := handler.trivyTransport(&v1alpha1.Registry{})
+		require.NoError(t, err)
+		defer closeTransport()
+		assert.Same(t, handler.transport, transport)
+	})
+
+	t.Run("revocation check on the connections of Trivy", func(t *testing.T) {
+		registry := &v1alpha1.Registry{Spec: v1alpha1.RegistrySpec{
+			CABundle:        string(caBundle),
+			RevocationCheck: v1alpha1.RevocationCheckEnforce,
+		}}
+		require.NoError(t, get(t, registry))
+	})
+
+	t.Run("revocation check of an unverified certificate", func(t *testing.T) {
+		registry := &v1alpha1.Registry{Spec: v1alpha1.RegistrySpec{
+			Insecure:        true,
+			RevocationCheck: v1alpha1.RevocationCheckEnforce,
+		}}
+		require.ErrorContains(t, get(t, registry), "cannot check revocation of an unverified certificate chain")
+	})
+}
+
+func TestGenerateSBOMHandler_Handle_ScanErrorClassification(t *testing.T) {
+	tests := []struct {
+		name                 string
+		handler              http.Handler
+		expectedImageReason  storagev1alpha1.ImageScanErrorReason
+		expectedScanJobError string
+		expectRetry          bool
+	}{
+		{
+			name:                 "image deleted from the registry",
+			handler:              ggcrregistry.New(),
+			expectedImageReason:  storagev1alpha1.ImageScanErrorReasonNotFound,
+			expectedScanJobError: v1alpha1.ReasonImageNotFound,
+		},
+		{
+			name: "credentials rejected",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors":[{"code":"DENIED","message":"requested access to the resource is denied"}]}`))
+			}),
+			expectedImageReason:  storagev1alpha1.ImageScanErrorReasonAuthFailed,
+			expectedScanJobError: v1alpha1.ReasonRegistryAuthFailed,
+		},
+		{
+			name: "invalid manifest",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.Contains(r.URL.Path, "/manifests/") {
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":[{"code":"MANIFEST_INVALID","message":"manifest invalid"}]}`))
+			}),
+			expectedImageReason:  storagev1alpha1.ImageScanErrorReasonGeneratorError,
+			expectedScanJobError: v1alpha1.ReasonInvalidManifest,
+		},
+		{
+			name: "registry server error",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.Contains(r.URL.Path, "/manifests/") {
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				w.WriteHeader(http.StatusNotImplemented)
+			}),
+			expectedImageReason: storagev1alpha1.ImageScanErrorReasonGeneratorError,
+			expectRetry:         true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+			registryURI := strings.TrimPrefix(server.URL, "http://")
+
+			image := &storagev1alpha1.Image{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-image",
+					Namespace: "default",
+					UID:       "test-image-uid",
+				},
+				ImageMetadata: storagev1alpha1.ImageMetadata{
+					Registry:    "test-registry",
+					RegistryURI: registryURI,
+					Repository:  "test",
+					Tag:         "latest",
+					Platform:    "linux/amd64",
+					Digest:      "sha256:1782cafde43390b032f960c0fad3def745fac18994ced169003cb56e9a93c028",
+				},
+			}
+			registry := &v1alpha1.Registry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-registry",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RegistrySpec{
+					URI: registryURI,
+				},
+			}
+			registryData, err := json.Marshal(registry)
+			require.NoError(t, err)
+			scanJob := &v1alpha1.ScanJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-scanjob",
+					Namespace: "default",
+					UID:       "test-scanjob-uid",
+					Annotations: map[string]string{
+						v1alpha1.AnnotationScanJobRegistryKey: string(registryData),
+					},
+				},
+				Spec: v1alpha1.ScanJobSpec{
+					Registry: "test-registry",
+				},
+			}
+			scanJob.InitializeConditions()
+
+			scheme := scheme.Scheme
+			require.NoError(t, storagev1alpha1.AddToScheme(scheme))
+			require.NoError(t, v1alpha1.AddToScheme(scheme))
+			k8sClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithRuntimeObjects(image, registry, scanJob).
+				WithStatusSubresource(&v1alpha1.ScanJob{}).
+				WithIndex(&storagev1alpha1.SBOM{}, storagev1alpha1.IndexImageMetadataDigest, func(obj client.Object) []string {
+					sbom, ok := obj.(*storagev1alpha1.SBOM)
+					if !ok {
+						return nil
+					}
+					return []string{sbom.GetImageMetadata().Digest}
+				}).
+				Build()
+
+			// The image limits make the handler fetch the manifest before running Trivy.
+			handler := NewGenerateSBOMHandler(k8sClient, scheme, t.TempDir(), testTrivyJavaDBRepository, messagingMocks.NewMockPublisher(t), GenerateSBOMHandlerOptions{ImageLimits: ImageLimits{MaxLayers: 100}, Recorder: record.NewFakeRecorder(10), WorkerID: testWorkerID}, slog.Default())
+
+			message, err := json.Marshal(&GenerateSBOMMessage{
+				BaseMessage: BaseMessage{
+					ScanJob: ObjectRef{
+						Name:      scanJob.Name,
+						Namespace: scanJob.Namespace,
+						UID:       string(scanJob.UID),
+					},
+				},
+				Image: ObjectRef{
+					Name:      image.Name,
+					Namespace: image.Namespace,
+				},
+			})
+			require.NoError(t, err)
+
+			err = handler.Handle(t.Context(), &testMessage{data: message})
+
+			updatedScanJob := &v1alpha1.ScanJob{}
+			require.NoError(t, k8sClient.Get(t.Context(), client.ObjectKeyFromObject(scanJob), updatedScanJob))
+			if test.expectRetry {
+				require.Error(t, err, "the transient errors are retried")
+				assert.False(t, updatedScanJob.IsFailed())
+			} else {
+				require.NoError(t, err, "the terminal errors are not retried")
+				assert.True(t, updatedScanJob.IsFailed())
+				failedCondition := meta.FindStatusCondition(updatedScanJob.Status.Conditions, v1alpha1.ConditionTypeFailed)
+				require.NotNil(t, failedCondition)
+				assert.Equal(t, test.expectedScanJobError, failedCondition.Reason)
+			}
+
+			updatedImage := &storagev1alpha1.Image{}
+			require.NoError(t, k8sClient.Get(t.Context(), client.ObjectKeyFromObject(image), updatedImage))
+			require.NotNil(t, updatedImage.Status.LastError)
+			assert.Equal(t, test.expectedImageReason, updatedImage.Status.LastError.Reason)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+// ImageLimits bounds the images scanned by the workers, to protect them from the images
+// whose layers would exhaust their disk. A zero value disables the corresponding limit.
+type ImageLimits struct {
+	// MaxLayers is the maximum number of layers of an image.
+	MaxLayers int
+	// MaxSize is the maximum total uncompressed size in bytes of the layers of an image.
+	// The manifests only declare the compressed size of the layers: the images whose compressed size
+	// already exceeds it are rejected before downloading the layers, the others while downloading them.
+	MaxSize int64
+}
+
+// Enabled reports whether any limit is set.
+func (l ImageLimits) Enabled() bool {
+	return l.MaxLayers > 0 || l.MaxSize > 0
+}
+
+// ImageLimitExceededError is returned when an image exceeds the ImageLimits.
+type ImageLimitExceededError struct {
+	// Image is the reference of the image.
+	Image string
+	// Message describes the exceeded limit.
+	Message string
+}
+
+func (e *ImageLimitExceededError) Error() string {
+	return fmt.Sprintf("image %s exceeds the limits of the worker: %s", e.Image, e.Message)
+}
+
+// checkImageLimits fetches the manifest of the image and checks it against the limits,
+// so that the layers of the images exceeding them are never downloaded.
+func checkImageLimits(ctx context.Context, ref name.Digest, options []remote.Option, limits ImageLimits) error {
+	if !limits.Enabled() {
+		return nil
+	}
+
+	options = append(slices.Clone(options), remote.WithContext(ctx))
+	image, err := remote.Image(ref, options...)
+	if err != nil {
+		return fmt.Errorf("cannot fetch manifest of %s: %w", ref, err)
+	}
+	manifest, err := image.Manifest()
+	if err != nil {
+		return fmt.Errorf("cannot read manifest of %s: %w", ref, err)
+	}
+
+	if limits.MaxLayers > 0 && len(manifest.Layers) > limits.MaxLayers {
+		return &ImageLimitExceededError{
+			Image:   ref.String(),
+			Message: fmt.Sprintf("%d layers, the maximum is %d", len(manifest.Layers), limits.MaxLayers),
+		}
+	}
+
+	if limits.MaxSize > 0 {
+		var size int64
+		for _, layer := range manifest.Layers {
+			size += layer.Size
+		}
+		if size > limits.MaxSize {
+			return &ImageLimitExceededError{
+				Image:   ref.String(),
+				Message: fmt.Sprintf("%d bytes of compressed layers, the maximum is %d", size, limits.MaxSize),
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkImageLimits checks the manifest of the image against the limits of the handler.
+// The registry credentials must already be set up.
+func (h *GenerateSBOMHandler) checkImageLimits(ctx context.Context, registry *v1alpha1.Registry, imageRef string) error {
+	ref, err := name.NewDigest(imageRef)
+	if err != nil {
+		return fmt.Errorf("cannot parse image reference %s: %w", imageRef, err)
+	}
+
+	options, err := h.remoteOptions(registry)
+	if err != nil {
+		return err
+	}
+
+	return checkImageLimits(ctx, ref, options, h.imageLimits)
+}
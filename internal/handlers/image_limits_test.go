@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestCheckImageLimits(t *testing.T) {
+	server := httptest.NewServer(ggcrregistry.New())
+	defer server.Close()
+
+	repository, err := name.NewRepository(strings.TrimPrefix(server.URL, "http://") + "/test")
+	require.NoError(t, err)
+
+	// 3 layers of 1024 bytes each.
+	image, err := random.Image(1024, 3)
+	require.NoError(t, err)
+	digest, err := image.Digest()
+	require.NoError(t, err)
+	ref := repository.Digest(digest.String())
+	require.NoError(t, remote.Write(ref, image))
+
+	manifest, err := image.Manifest()
+	require.NoError(t, err)
+	var size int64
+	for _, layer := range manifest.Layers {
+		size += layer.Size
+	}
+
+	tests := []struct {
+		name          string
+		limits        ImageLimits
+		expectedError string
+	}{
+		{
+			name:   "no limits",
+			limits: ImageLimits{},
+		},
+		{
+			name:   "within the limits",
+			limits: ImageLimits{MaxLayers: 3, MaxSize: size},
+		},
+		{
+			name:          "too many layers",
+			limits:        ImageLimits{MaxLayers: 2},
+			expectedError: "3 layers, the maximum is 2",
+		},
+		{
+			name:          "too large",
+			limits:        ImageLimits{MaxSize: size - 1},
+			expectedError: "bytes of compressed layers, the maximum is",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkImageLimits(t.Context(), ref, nil, test.limits)
+			if test.expectedError == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			var limitErr *ImageLimitExceededError
+			require.ErrorAs(t, err, &limitErr)
+			assert.Equal(t, ref.String(), limitErr.Image)
+			assert.Contains(t, limitErr.Message, test.expectedError)
+		})
+	}
+}
+
+func TestCheckImageLimits_ManifestNotFound(t *testing.T) {
+	server := httptest.NewServer(ggcrregistry.New())
+	defer server.Close()
+
+	ref, err := name.NewDigest(strings.TrimPrefix(server.URL, "http://") + "/test@sha256:" + strings.Repeat("0", 64))
+	require.NoError(t, err)
+
+	err = checkImageLimits(t.Context(), ref, nil, ImageLimits{MaxLayers: 1})
+	require.Error(t, err)
+	var limitErr *ImageLimitExceededError
+	assert.NotErrorAs(t, err, &limitErr)
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+const (
+	// imageStatusBatchMaxItems is the maximum number of image statuses written by an ImageStatusBatch.
+	imageStatusBatchMaxItems = 100
+	// imageStatusBatchInterval is the maximum time an image status waits before being written.
+	imageStatusBatchInterval = time.Second
+)
+
+// ImageStatusBatcher writes the statuses of the scanned images with ImageStatusBatch requests,
+// so that a registry sweep does not update thousands of images one at a time.
+// The statuses are written every imageStatusBatchInterval, or as soon as imageStatusBatchMaxItems are queued.
+// Only the latest status queued for an image is written.
+type ImageStatusBatcher struct {
+	k8sClient client.Client
+	logger    *slog.Logger
+
+	mu sync.Mutex
+	// pending holds the statuses to write, by namespace and image name.
+	pending map[string]map[string]storagev1alpha1.ImageStatus
+	count   int
+	full    chan struct{}
+}
+
+// NewImageStatusBatcher creates a new ImageStatusBatcher.
+func NewImageStatusBatcher(k8sClient client.Client, logger *slog.Logger) *ImageStatusBatcher {
+	return &ImageStatusBatcher{
+		k8sClient: k8sClient,
+		logger:    logger.With("component", "image_status_batcher"),
+		pending:   map[string]map[string]storagev1alpha1.ImageStatus{},
+		full:      make(chan struct{}, 1),
+	}
+}
+
+// Queue queues the write of the status of the image.
+func (b *ImageStatusBatcher) Queue(image *storagev1alpha1.Image) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	statuses, ok := b.pending[image.Namespace]
+	if !ok {
+		statuses = map[string]storagev1alpha1.ImageStatus{}
+		b.pending[image.Namespace] = statuses
+	}
+	if _, ok = statuses[image.Name]; !ok {
+		b.count++
+	}
+	statuses[image.Name] = *image.Status.DeepCopy()
+
+	if b.count >= imageStatusBatchMaxItems {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run writes the queued statuses until the context is canceled.
+// The statuses queued when the context is canceled are written before returning.
+func (b *ImageStatusBatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(imageStatusBatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.flush(context.WithoutCancel(ctx))
+			b.mu.Lock()
+			count := b.count
+			b.mu.Unlock()
+			if count > 0 {
+				b.logger.WarnContext(ctx, "Image statuses not updated before stopping", "images", count)
+			}
+			return
+		case <-ticker.C:
+		case <-b.full:
+		}
+		b.flush(ctx)
+	}
+}
+
+// flush writes the queued statuses, with one ImageStatusBatch per namespace and imageStatusBatchMaxItems images.
+// The statuses which cannot be written are queued again, unless a newer status of their image was queued meanwhile,
+// since the next scans of the images rely on their last scan time.
+// The statuses of the images which do not exist anymore, or which are invalid, are dropped.
+func (b *ImageStatusBatcher) flush(ctx context.Context) {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = map[string]map[string]storagev1alpha1.ImageStatus{}
+	b.count = 0
+	b.mu.Unlock()
+
+	for _, namespace := range slices.Sorted(maps.Keys(pending)) {
+		statuses := pending[namespace]
+		names := slices.Sorted(maps.Keys(statuses))
+		for chunk := range slices.Chunk(names, imageStatusBatchMaxItems) {
+			batch := &storagev1alpha1.ImageStatusBatch{}
+			batch.Namespace = namespace
+			for _, name := range chunk {
+				batch.Spec.Items = append(batch.Spec.Items, storagev1alpha1.ImageStatusUpdate{
+					Name:   name,
+					Status: statuses[name],
+				})
+			}
+			b.requeue(namespace, b.write(ctx, batch))
+		}
+	}
+}
+
+// write creates the ImageStatusBatch, and returns the status updates to write again.
+func (b *ImageStatusBatcher) write(ctx context.Context, batch *storagev1alpha1.ImageStatusBatch) []storagev1alpha1.ImageStatusUpdate {
+	if err := b.k8sClient.Create(ctx, batch); err != nil {
+		if apierrors.IsInvalid(err) || apierrors.IsBadRequest(err) {
+			b.logger.ErrorContext(ctx, "Failed to update the image statuses, dropping them", "namespace", batch.Namespace, "images", len(batch.Spec.Items), "error", err)
+			return nil
+		}
+		b.logger.ErrorContext(ctx, "Failed to update the image statuses, retrying", "namespace", batch.Namespace, "images", len(batch.Spec.Items), "error", err)
+		return batch.Spec.Items
+	}
+
+	// The results are in the order of the items.
+	var retries []storagev1alpha1.ImageStatusUpdate
+	for i, result := range batch.Status.Results {
+		if result.Succeeded {
+			continue
+		}
+		switch result.Reason {
+		case metav1.StatusReasonNotFound, metav1.StatusReasonInvalid:
+			// The image might have been deleted since it was scanned.
+			b.logger.InfoContext(ctx, "Image status not updated", "image", result.Name, "namespace", batch.Namespace, "reason", result.Reason, "message", result.Message)
+		default:
+			b.logger.WarnContext(ctx, "Image status not updated, retrying", "image", result.Name, "namespace", batch.Namespace, "reason", result.Reason, "message", result.Message)
+			retries = append(retries, batch.Spec.Items[i])
+		}
+	}
+	b.logger.DebugContext(ctx, "Image statuses updated", "namespace", batch.Namespace, "images", len(batch.Spec.Items)-len(retries))
+
+	return retries
+}
+
+// requeue queues the status updates again, unless a newer status of their image was queued meanwhile.
+func (b *ImageStatusBatcher) requeue(namespace string, items []storagev1alpha1.ImageStatusUpdate) {
+	if len(items) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	statuses, ok := b.pending[namespace]
+	if !ok {
+		statuses = map[string]storagev1alpha1.ImageStatus{}
+		b.pending[namespace] = statuses
+	}
+	for _, item := range items {
+		if _, ok = statuses[item.Name]; ok {
+			continue
+		}
+		statuses[item.Name] = item.Status
+		b.count++
+	}
+}
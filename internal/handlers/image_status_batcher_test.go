@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// imageStatusBatchInterceptor applies the ImageStatusBatches created with the fake client,
+// as the storage does, since they are not persisted.
+func imageStatusBatchInterceptor() interceptor.Funcs {
+	return interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			batch, ok := obj.(*storagev1alpha1.ImageStatusBatch)
+			if !ok {
+				return c.Create(ctx, obj, opts...)
+			}
+
+			for _, item := range batch.Spec.Items {
+				image := &storagev1alpha1.Image{}
+				err := c.Get(ctx, client.ObjectKey{Name: item.Name, Namespace: batch.Namespace}, image)
+				if apierrors.IsNotFound(err) {
+					batch.Status.Results = append(batch.Status.Results, storagev1alpha1.ImageStatusUpdateResult{
+						Name:   item.Name,
+						Reason: metav1.StatusReasonNotFound,
+					})
+					continue
+				}
+				if err != nil {
+					return err
+				}
+
+				image.Status = item.Status
+				if err = c.Update(ctx, image); err != nil {
+					return err
+				}
+				batch.Status.Results = append(batch.Status.Results, storagev1alpha1.ImageStatusUpdateResult{Name: item.Name, Succeeded: true})
+			}
+
+			return nil
+		},
+	}
+}
+
+func TestImageStatusBatcher(t *testing.T) {
+	scheme := scheme.Scheme
+	require.NoError(t, storagev1alpha1.AddToScheme(scheme))
+
+	var images []client.Object
+	for i := range imageStatusBatchMaxItems + 1 {
+		images = append(images, &storagev1alpha1.Image{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("image-%03d", i), Namespace: "default"},
+		})
+	}
+	images = append(images, &storagev1alpha1.Image{
+		ObjectMeta: metav1.ObjectMeta{Name: "image-000", Namespace: "other"},
+	})
+
+	var batches []*storagev1alpha1.ImageStatusBatch
+	funcs := imageStatusBatchInterceptor()
+	applyBatch := funcs.Create
+	funcs.Create = func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+		if batch, ok := obj.(*storagev1alpha1.ImageStatusBatch); ok {
+			batches = append(batches, batch)
+		}
+		return applyBatch(ctx, c, obj, opts...)
+	}
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(images...).
+		WithInterceptorFuncs(funcs).
+		Build()
+
+	batcher := NewImageStatusBatcher(k8sClient, slog.Default())
+
+	scannedAt := metav1.Unix(1750000000, 0)
+	for _, object := range images {
+		image := object.(*storagev1alpha1.Image).DeepCopy()
+		image.Status.LastError = &storagev1alpha1.ImageScanError{Reason: storagev1alpha1.ImageScanErrorReasonTimeout}
+		batcher.Queue(image)
+		// Only the latest status of an image is written.
+		image.Status.LastError = nil
+		image.Status.LastScannedAt = &scannedAt
+		batcher.Queue(image)
+	}
+	// The image deleted since it was queued does not fail the other updates.
+	batcher.Queue(&storagev1alpha1.Image{ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: "default"}})
+
+	select {
+	case <-batcher.full:
+	default:
+		t.Fatal("the batcher must be flushed once the maximum number of items is queued")
+	}
+	batcher.flush(t.Context())
+
+	// The images of a namespace are split in batches of imageStatusBatchMaxItems.
+	require.Len(t, batches, 3)
+	assert.Equal(t, "default", batches[0].Namespace)
+	assert.Len(t, batches[0].Spec.Items, imageStatusBatchMaxItems)
+	assert.Equal(t, "default", batches[1].Namespace)
+	assert.Len(t, batches[1].Spec.Items, 2)
+	assert.Equal(t, "other", batches[2].Namespace)
+	assert.Len(t, batches[2].Spec.Items, 1)
+
+	for _, object := range images {
+		image := &storagev1alpha1.Image{}
+		require.NoError(t, k8sClient.Get(t.Context(), client.ObjectKeyFromObject(object), image))
+		assert.Nil(t, image.Status.LastError)
+		require.NotNil(t, image.Status.LastScannedAt)
+		assert.True(t, scannedAt.Equal(image.Status.LastScannedAt))
+	}
+
+	// Nothing is written when nothing is queued.
+	batcher.flush(t.Context())
+	assert.Len(t, batches, 3)
+}
+
+func TestImageStatusBatcher_Retry(t *testing.T) {
+	scheme := scheme.Scheme
+	require.NoError(t, storagev1alpha1.AddToScheme(scheme))
+
+	images := []client.Object{
+		&storagev1alpha1.Image{ObjectMeta: metav1.ObjectMeta{Name: "image-1", Namespace: "default"}},
+		&storagev1alpha1.Image{ObjectMeta: metav1.ObjectMeta{Name: "image-2", Namespace: "default"}},
+	}
+
+	var unavailable bool
+	funcs := imageStatusBatchInterceptor()
+	applyBatch := funcs.Create
+	funcs.Create = func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+		batch, ok := obj.(*storagev1alpha1.ImageStatusBatch)
+		if !ok {
+			return applyBatch(ctx, c, obj, opts...)
+		}
+		if unavailable {
+			return apierrors.NewServiceUnavailable("storage unavailable")
+		}
+		if err := applyBatch(ctx, c, obj, opts...); err != nil {
+			return err
+		}
+		// The update of image-2 fails with a transient error.
+		for i, result := range batch.Status.Results {
+			if result.Name == "image-2" {
+				batch.Status.Results[i] = storagev1alpha1.ImageStatusUpdateResult{Name: result.Name, Reason: metav1.StatusReasonInternalError}
+			}
+		}
+		return nil
+	}
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(images...).
+		WithInterceptorFuncs(funcs).
+		Build()
+
+	batcher := NewImageStatusBatcher(k8sClient, slog.Default())
+
+	scannedAt := metav1.Unix(1750000000, 0)
+	for _, object := range images {
+		image := object.(*storagev1alpha1.Image).DeepCopy()
+		image.Status.LastScannedAt = &scannedAt
+		batcher.Queue(image)
+	}
+	// The image deleted since it was queued is not retried.
+	batcher.Queue(&storagev1alpha1.Image{ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: "default"}})
+
+	// The statuses of the failed batch are queued again.
+	unavailable = true
+	batcher.flush(t.Context())
+	assert.Equal(t, 3, batcher.count)
+
+	// Only the status of the failed image is queued again.
+	unavailable = false
+	batcher.flush(t.Context())
+	assert.Equal(t, 1, batcher.count)
+	assert.Contains(t, batcher.pending["default"], "image-2")
+
+	image := &storagev1alpha1.Image{}
+	require.NoError(t, k8sClient.Get(t.Context(), client.ObjectKeyFromObject(images[0]), image))
+	require.NotNil(t, image.Status.LastScannedAt)
+	assert.True(t, scannedAt.Equal(image.Status.LastScannedAt))
+
+	// A newer status queued meanwhile is not overwritten by the retried one.
+	newerScannedAt := metav1.Unix(1760000000, 0)
+	image = images[1].(*storagev1alpha1.Image).DeepCopy()
+	image.Status.LastScannedAt = &newerScannedAt
+	batcher.Queue(image)
+	batcher.requeue("default", []storagev1alpha1.ImageStatusUpdate{{Name: "image-2", Status: storagev1alpha1.ImageStatus{LastScannedAt: &scannedAt}}})
+	assert.Equal(t, 1, batcher.count)
+	assert.True(t, newerScannedAt.Equal(batcher.pending["default"]["image-2"].LastScannedAt))
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/kubewarden/sbomscanner/internal/handlers/resumable"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// uncompressedSizeTransport bounds the total uncompressed size of the blobs downloaded while scanning an image.
+// The manifests only declare the compressed size of the layers, so a layer compressing well,
+// e.g. a decompression bomb, is only detected while Trivy downloads and extracts it.
+// A copy of each blob is decompressed as it is read, and the reads fail once the limit is exceeded.
+// The layers already analyzed are served from the Trivy cache without being downloaded, so they are not counted.
+type uncompressedSizeTransport struct {
+	inner   http.RoundTripper
+	image   string
+	maxSize int64
+
+	size     atomic.Int64
+	mu       sync.Mutex
+	exceeded *ImageLimitExceededError
+}
+
+func newUncompressedSizeTransport(inner http.RoundTripper, image string, maxSize int64) *uncompressedSizeTransport {
+	return &uncompressedSizeTransport{
+		inner:   inner,
+		image:   image,
+		maxSize: maxSize,
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *uncompressedSizeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Method != http.MethodGet || resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	if _, ok := resumable.BlobDigest(req); !ok {
+		return resp, nil
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	body := &uncompressedSizeBody{
+		transport: t,
+		body:      resp.Body,
+		pipe:      pipeWriter,
+		done:      make(chan struct{}),
+	}
+	go body.count(pipeReader)
+	resp.Body = body
+
+	return resp, nil
+}
+
+// Err returns the error of the exceeded limit, if any.
+// Trivy may wrap or swallow the errors of the reads, so it is checked once the scan is done.
+func (t *uncompressedSizeTransport) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.exceeded == nil {
+		return nil
+	}
+	return t.exceeded
+}
+
+// add adds n uncompressed bytes to the size of the image and reports the exceeded limit.
+func (t *uncompressedSizeTransport) add(n int64) error {
+	size := t.size.Add(n)
+	if size <= t.maxSize {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.exceeded == nil {
+		t.exceeded = &ImageLimitExceededError{
+			Image:   t.image,
+			Message: fmt.Sprintf("more than %d bytes of uncompressed layers", t.maxSize),
+		}
+	}
+	return t.exceeded
+}
+
+// uncompressedSizeBody is the body of a blob response, whose content is decompressed and counted as it is read.
+type uncompressedSizeBody struct {
+	transport *uncompressedSizeTransport
+	body      io.ReadCloser
+	// pipe is nil once the blob is counted, or could not be decompressed.
+	pipe *io.PipeWriter
+	done chan struct{}
+}
+
+func (b *uncompressedSizeBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 && b.pipe != nil {
+		if _, writeErr := b.pipe.Write(p[:n]); writeErr != nil {
+			b.pipe = nil
+			var limitErr *ImageLimitExceededError
+			if errors.As(writeErr, &limitErr) {
+				return 0, limitErr
+			}
+		}
+	}
+	if errors.Is(err, io.EOF) && b.pipe != nil {
+		// The whole blob is counted before reporting its end.
+		_ = b.pipe.Close()
+		b.pipe = nil
+		<-b.done
+		if limitErr := b.transport.Err(); limitErr != nil {
+			return n, limitErr
+		}
+	}
+
+	return n, err
+}
+
+func (b *uncompressedSizeBody) Close() error {
+	if b.pipe != nil {
+		_ = b.pipe.Close()
+		b.pipe = nil
+	}
+	return b.body.Close()
+}
+
+// count decompresses the content written to the pipe and adds its size to the transport.
+// The content which is neither gzip nor zstd compressed is counted as is.
+func (b *uncompressedSizeBody) count(pipe *io.PipeReader) {
+	defer close(b.done)
+
+	err := b.decompress(pipe)
+	// Closing the pipe with the error unblocks the writes, and makes the next ones fail with it.
+	pipe.CloseWithError(err)
+}
+
+func (b *uncompressedSizeBody) decompress(pipe io.Reader) error {
+	reader := bufio.NewReader(pipe)
+	magic, _ := reader.Peek(len(zstdMagic))
+
+	var content io.Reader = reader
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		content = gzipReader
+	case bytes.HasPrefix(magic, zstdMagic):
+		zstdReader, err := zstd.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		defer zstdReader.Close()
+		content = zstdReader
+	}
+
+	_, err := io.Copy(sizeCounter{transport: b.transport}, content)
+	return err
+}
+
+// sizeCounter is a writer adding the size of the content written to the transport.
+type sizeCounter struct {
+	transport *uncompressedSizeTransport
+}
+
+func (c sizeCounter) Write(p []byte) (int, error) {
+	if err := c.transport.add(int64(len(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
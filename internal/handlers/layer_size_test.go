@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBlobPath = "/v2/test/blobs/sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func gzipContent(t *testing.T, content []byte) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	_, err := writer.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	return buffer.Bytes()
+}
+
+func zstdContent(t *testing.T, content []byte) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	writer, err := zstd.NewWriter(&buffer)
+	require.NoError(t, err)
+	_, err = writer.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	return buffer.Bytes()
+}
+
+func TestUncompressedSizeTransport(t *testing.T) {
+	// 1 MiB of zeros compresses to about 1 KiB.
+	uncompressed := make([]byte, 1<<20)
+
+	tests := []struct {
+		name        string
+		path        string
+		body        []byte
+		maxSize     int64
+		expectError bool
+	}{
+		{
+			name:        "gzip blob exceeding the limit",
+			path:        testBlobPath,
+			body:        gzipContent(t, uncompressed),
+			maxSize:     1 << 19,
+			expectError: true,
+		},
+		{
+			name:        "zstd blob exceeding the limit",
+			path:        testBlobPath,
+			body:        zstdContent(t, uncompressed),
+			maxSize:     1 << 19,
+			expectError: true,
+		},
+		{
+			name:        "uncompressed blob exceeding the limit",
+			path:        testBlobPath,
+			body:        uncompressed,
+			maxSize:     1 << 19,
+			expectError: true,
+		},
+		{
+			name:    "gzip blob within the limit",
+			path:    testBlobPath,
+			body:    gzipContent(t, uncompressed),
+			maxSize: 1 << 20,
+		},
+		{
+			name:    "manifest is not counted",
+			path:    "/v2/test/manifests/latest",
+			body:    uncompressed,
+			maxSize: 1 << 19,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write(test.body)
+			}))
+			defer server.Close()
+
+			transport := newUncompressedSizeTransport(http.DefaultTransport, "registry.test/test@sha256:0123", test.maxSize)
+			client := &http.Client{Transport: transport}
+
+			resp, err := client.Get(server.URL + test.path)
+			require.NoError(t, err)
+			content, readErr := io.ReadAll(resp.Body)
+			require.NoError(t, resp.Body.Close())
+
+			if !test.expectError {
+				require.NoError(t, readErr)
+				assert.Equal(t, test.body, content)
+				require.NoError(t, transport.Err())
+				return
+			}
+
+			var limitErr *ImageLimitExceededError
+			require.ErrorAs(t, readErr, &limitErr)
+			assert.Equal(t, "registry.test/test@sha256:0123", limitErr.Image)
+			require.ErrorAs(t, transport.Err(), &limitErr)
+		})
+	}
+}
+
+func TestUncompressedSizeTransport_SumsBlobs(t *testing.T) {
+	body := gzipContent(t, make([]byte, 1<<20))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	// Each blob is within the limit, but not both of them.
+	transport := newUncompressedSizeTransport(http.DefaultTransport, "registry.test/test@sha256:0123", 3<<19)
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(server.URL + testBlobPath)
+	require.NoError(t, err)
+	_, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	resp, err = client.Get(server.URL + strings.Replace(testBlobPath, "0123", "3210", 1))
+	require.NoError(t, err)
+	_, err = io.ReadAll(resp.Body)
+	require.NoError(t, resp.Body.Close())
+
+	var limitErr *ImageLimitExceededError
+	require.ErrorAs(t, err, &limitErr)
+}
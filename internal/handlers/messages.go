@@ -1,11 +1,17 @@
 package handlers
 
+import "time"
+
 const (
 	GenerateSBOMSubject  = "sbomscanner.sbom.generate"
 	ScanSBOMSubject      = "sbomscanner.sbom.scan"
 	CreateCatalogSubject = "sbomscanner.catalog.create"
+	RescanSBOMsSubject   = "sbomscanner.sbom.rescan"
 )
 
+// WorkerSubjects are the subjects of the messages handled by the workers.
+var WorkerSubjects = []string{GenerateSBOMSubject, ScanSBOMSubject, CreateCatalogSubject, RescanSBOMsSubject}
+
 // ObjectRef is a reference to a Kubernetes object, used in messages to identify resources.
 // UID should be populated when you need to verify the exact resource instance
 // (e.g., to detect if the resource was deleted and recreated).
@@ -25,6 +31,11 @@ type CreateCatalogMessage struct {
 	BaseMessage
 }
 
+// RescanSBOMsMessage represents a request to scan again the SBOMs already stored for the images of a registry.
+type RescanSBOMsMessage struct {
+	BaseMessage
+}
+
 // GenerateSBOMMessage represents the request message for generating a SBOM.
 type GenerateSBOMMessage struct {
 	BaseMessage
@@ -35,4 +46,10 @@ type GenerateSBOMMessage struct {
 type ScanSBOMMessage struct {
 	BaseMessage
 	SBOM ObjectRef `json:"sbom"`
+	// Reevaluation is true when the SBOM was stored before the scan job, and is evaluated again
+	// without pulling the image.
+	Reevaluation bool `json:"reevaluation,omitempty"`
+	// VulnerabilityDBNotBefore makes the worker download the vulnerability database again
+	// when its cached database was downloaded before this time.
+	VulnerabilityDBNotBefore time.Time `json:"vulnerabilityDBNotBefore,omitzero"`
 }
@@ -0,0 +1,3 @@
+// Package notification sends the new vulnerabilities found by the scans to an external webhook,
+// e.g. a Slack incoming webhook, signing the payloads so that the receivers can authenticate them.
+package notification
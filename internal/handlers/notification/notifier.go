@@ -0,0 +1,276 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+const (
+	// SignatureHeader is the header holding the HMAC-SHA256 signature of the payload, as sha256=<hex>.
+	// The signed content is the timestamp header value, a dot, and the body.
+	SignatureHeader = "X-SBOMscanner-Signature"
+	// TimestampHeader is the header holding the Unix time the payload was signed at,
+	// so that the receivers can reject the replayed payloads.
+	TimestampHeader = "X-SBOMscanner-Timestamp"
+
+	// DefaultMinSeverity is the default minimum severity of the notified vulnerabilities.
+	DefaultMinSeverity = "CRITICAL"
+	// DefaultAttempts is the default maximum number of attempts to send a notification.
+	DefaultAttempts = 5
+
+	retryDelay    = time.Second
+	retryMaxDelay = 30 * time.Second
+	// requestTimeout bounds every attempt, so that a stalled webhook does not block the scans.
+	requestTimeout = 10 * time.Second
+)
+
+// severities are the severities of the vulnerabilities, from the lowest to the highest.
+var severities = []string{"UNKNOWN", "LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+// Options configures the notifications.
+type Options struct {
+	// URL of the webhook the notifications are POSTed to. The notifications are disabled when empty.
+	URL string
+	// MinSeverity is the minimum severity of the notified vulnerabilities, e.g. CRITICAL.
+	MinSeverity string
+	// SigningSecret is the key of the HMAC-SHA256 signature of the payloads. The payloads are not signed when empty.
+	SigningSecret []byte
+	// Attempts is the maximum number of attempts to send a notification, with an exponential backoff between them.
+	Attempts uint
+}
+
+// Enabled returns true if the notifications must be sent.
+func (o Options) Enabled() bool {
+	return o.URL != ""
+}
+
+// Validate returns an error if the options are not valid.
+func (o Options) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+
+	webhookURL, err := url.Parse(o.URL)
+	if err != nil {
+		return fmt.Errorf("invalid notification URL: %w", err)
+	}
+	if webhookURL.Scheme != "http" && webhookURL.Scheme != "https" {
+		return fmt.Errorf("invalid notification URL %s: the scheme must be http or https", webhookURL.Redacted())
+	}
+	if !slices.Contains(severities, o.MinSeverity) {
+		return fmt.Errorf("invalid notification minimum severity %q, must be one of %s", o.MinSeverity, strings.Join(severities, ", "))
+	}
+	if o.Attempts == 0 {
+		return errors.New("the notification attempts must be greater than 0")
+	}
+
+	return nil
+}
+
+// Payload is the JSON body of a notification.
+type Payload struct {
+	// Text is a human readable summary of the notification, displayed by the Slack incoming webhooks.
+	Text string `json:"text"`
+	// Namespace of the VulnerabilityReport.
+	Namespace string `json:"namespace"`
+	// Name of the VulnerabilityReport.
+	Name string `json:"name"`
+	// ImageMetadata identifies the scanned image.
+	ImageMetadata storagev1alpha1.ImageMetadata `json:"imageMetadata"`
+	// MinSeverity is the minimum severity of the notified vulnerabilities.
+	MinSeverity string `json:"minSeverity"`
+	// Summary counts all the vulnerabilities of the report.
+	Summary storagev1alpha1.Summary `json:"summary"`
+	// Vulnerabilities are the new vulnerabilities with at least the minimum severity.
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+// Vulnerability is a vulnerability of a notification.
+type Vulnerability struct {
+	CVE              string   `json:"cve"`
+	Severity         string   `json:"severity"`
+	Title            string   `json:"title,omitempty"`
+	Target           string   `json:"target"`
+	PackageName      string   `json:"packageName,omitempty"`
+	PURL             string   `json:"purl"`
+	InstalledVersion string   `json:"installedVersion"`
+	FixedVersions    []string `json:"fixedVersions,omitempty"`
+}
+
+// Notifier POSTs the new vulnerabilities of the VulnerabilityReports to a webhook.
+type Notifier struct {
+	options    Options
+	httpClient *http.Client
+	// retryDelay is the delay before the first retry, doubled after each attempt.
+	retryDelay time.Duration
+	logger     *slog.Logger
+}
+
+// NewNotifier creates a new Notifier. The options must be valid.
+func NewNotifier(options Options, logger *slog.Logger) *Notifier {
+	return &Notifier{
+		options:    options,
+		httpClient: &http.Client{Timeout: requestTimeout},
+		retryDelay: retryDelay,
+		logger:     logger.With("component", "notifier"),
+	}
+}
+
+// NotifyNewVulnerabilities sends the vulnerabilities of the report with at least the minimum severity
+// which were not in the previous results of the report, nil when the report is created.
+// Nothing is sent when there is no new vulnerability.
+func (n *Notifier) NotifyNewVulnerabilities(
+	ctx context.Context,
+	report *storagev1alpha1.VulnerabilityReport,
+	previousResults []storagev1alpha1.Result,
+) error {
+	vulnerabilities := NewVulnerabilities(report.Report.Results, previousResults, n.options.MinSeverity)
+	if len(vulnerabilities) == 0 {
+		return nil
+	}
+
+	imageMetadata := report.GetImageMetadata()
+	payload := Payload{
+		Text: fmt.Sprintf("%d new vulnerabilities with severity %s or higher found in %s/%s:%s (%s)",
+			len(vulnerabilities), n.options.MinSeverity,
+			imageMetadata.RegistryURI, imageMetadata.Repository, imageMetadata.Tag, imageMetadata.Platform),
+		Namespace:       report.Namespace,
+		Name:            report.Name,
+		ImageMetadata:   imageMetadata,
+		MinSeverity:     n.options.MinSeverity,
+		Summary:         report.Report.Summary,
+		Vulnerabilities: vulnerabilities,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	err = retry.Do(
+		func() error {
+			return n.send(ctx, body)
+		},
+		retry.Context(ctx),
+		retry.Attempts(n.options.Attempts),
+		retry.Delay(n.retryDelay),
+		retry.DelayType(retry.BackOffDelay),
+		retry.MaxDelay(retryMaxDelay),
+		retry.LastErrorOnly(true),
+		retry.OnRetry(func(attempt uint, err error) {
+			n.logger.InfoContext(ctx, "Failed to send notification, retrying", "attempt", attempt+1, "error", err)
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+
+	n.logger.InfoContext(ctx, "Notification sent",
+		"vulnerabilityReport", report.Name,
+		"namespace", report.Namespace,
+		"vulnerabilities", len(vulnerabilities))
+
+	return nil
+}
+
+// send POSTs the body to the webhook.
+// The client errors are not retried, except 429 Too Many Requests.
+func (n *Notifier) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.options.URL, bytes.NewReader(body))
+	if err != nil {
+		return retry.Unrecoverable(fmt.Errorf("failed to create request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.options.SigningSecret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign(n.options.SigningSecret, timestamp, body))
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+	defer resp.Body.Close()
+	// Drain the body so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return retry.Unrecoverable(err)
+	}
+
+	return err
+}
+
+// NewVulnerabilities returns the vulnerabilities of the results with at least the minimum severity
+// which are not in the previous results. The suppressed vulnerabilities are ignored.
+func NewVulnerabilities(results, previousResults []storagev1alpha1.Result, minSeverity string) []Vulnerability {
+	minSeverityIndex := slices.Index(severities, minSeverity)
+
+	previous := map[string]bool{}
+	for _, result := range previousResults {
+		for _, vulnerability := range result.Vulnerabilities {
+			previous[vulnerabilityKey(result.Target, vulnerability)] = true
+		}
+	}
+
+	var vulnerabilities []Vulnerability
+	for _, result := range results {
+		for _, vulnerability := range result.Vulnerabilities {
+			if vulnerability.Suppressed || slices.Index(severities, vulnerability.Severity) < minSeverityIndex {
+				continue
+			}
+			if previous[vulnerabilityKey(result.Target, vulnerability)] {
+				continue
+			}
+			vulnerabilities = append(vulnerabilities, Vulnerability{
+				CVE:              vulnerability.CVE,
+				Severity:         vulnerability.Severity,
+				Title:            vulnerability.Title,
+				Target:           result.Target,
+				PackageName:      vulnerability.PackageName,
+				PURL:             vulnerability.PURL,
+				InstalledVersion: vulnerability.InstalledVersion,
+				FixedVersions:    vulnerability.FixedVersions,
+			})
+		}
+	}
+
+	return vulnerabilities
+}
+
+// vulnerabilityKey identifies a vulnerability of a package across the scans of an image.
+func vulnerabilityKey(target string, vulnerability storagev1alpha1.Vulnerability) string {
+	return strings.Join([]string{target, vulnerability.CVE, vulnerability.PURL, vulnerability.PackagePath}, "|")
+}
+
+// Sign returns the value of the signature header of the body sent at timestamp.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
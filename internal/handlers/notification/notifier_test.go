@@ -0,0 +1,195 @@
+package notification
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+func newTestReport(vulnerabilities ...storagev1alpha1.Vulnerability) *storagev1alpha1.VulnerabilityReport {
+	return &storagev1alpha1.VulnerabilityReport{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-report", Namespace: "default"},
+		ImageMetadata: storagev1alpha1.ImageMetadata{
+			RegistryURI: "ghcr.io",
+			Repository:  "kubewarden/sbomscanner",
+			Tag:         "latest",
+			Platform:    "linux/amd64",
+		},
+		Report: storagev1alpha1.Report{
+			Summary: storagev1alpha1.Summary{Critical: 1},
+			Results: []storagev1alpha1.Result{
+				{Target: "alpine:3.20", Vulnerabilities: vulnerabilities},
+			},
+		},
+	}
+}
+
+func TestNotifyNewVulnerabilities(t *testing.T) {
+	var payload Payload
+	var signature, timestamp string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.NoError(t, json.Unmarshal(body, &payload))
+		timestamp = r.Header.Get(TimestampHeader)
+		signature = r.Header.Get(SignatureHeader)
+		assert.Equal(t, Sign([]byte("secret"), timestamp, body), signature)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(Options{
+		URL:           server.URL,
+		MinSeverity:   "HIGH",
+		SigningSecret: []byte("secret"),
+		Attempts:      1,
+	}, slog.Default())
+
+	report := newTestReport(
+		storagev1alpha1.Vulnerability{CVE: "CVE-2024-0001", Severity: "CRITICAL", PURL: "pkg:apk/alpine/openssl@3.3.0"},
+		storagev1alpha1.Vulnerability{CVE: "CVE-2024-0002", Severity: "HIGH", PURL: "pkg:apk/alpine/curl@8.0.0"},
+		storagev1alpha1.Vulnerability{CVE: "CVE-2024-0003", Severity: "MEDIUM", PURL: "pkg:apk/alpine/curl@8.0.0"},
+		storagev1alpha1.Vulnerability{CVE: "CVE-2024-0004", Severity: "CRITICAL", PURL: "pkg:apk/alpine/zlib@1.3", Suppressed: true},
+	)
+	previousResults := []storagev1alpha1.Result{
+		{
+			Target: "alpine:3.20",
+			Vulnerabilities: []storagev1alpha1.Vulnerability{
+				{CVE: "CVE-2024-0002", Severity: "HIGH", PURL: "pkg:apk/alpine/curl@8.0.0"},
+			},
+		},
+	}
+
+	require.NoError(t, notifier.NotifyNewVulnerabilities(t.Context(), report, previousResults))
+
+	assert.NotEmpty(t, timestamp)
+	assert.Equal(t, "default", payload.Namespace)
+	assert.Equal(t, "test-report", payload.Name)
+	assert.Equal(t, "HIGH", payload.MinSeverity)
+	assert.Equal(t, report.ImageMetadata, payload.ImageMetadata)
+	assert.Equal(t, []Vulnerability{
+		{CVE: "CVE-2024-0001", Severity: "CRITICAL", Target: "alpine:3.20", PURL: "pkg:apk/alpine/openssl@3.3.0"},
+	}, payload.Vulnerabilities)
+	assert.Equal(t, "1 new vulnerabilities with severity HIGH or higher found in ghcr.io/kubewarden/sbomscanner:latest (linux/amd64)", payload.Text)
+}
+
+func TestNotifyNewVulnerabilities_NothingNew(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(Options{URL: server.URL, MinSeverity: "CRITICAL", Attempts: 1}, slog.Default())
+	report := newTestReport(
+		storagev1alpha1.Vulnerability{CVE: "CVE-2024-0002", Severity: "HIGH", PURL: "pkg:apk/alpine/curl@8.0.0"},
+	)
+
+	require.NoError(t, notifier.NotifyNewVulnerabilities(t.Context(), report, nil))
+	assert.Zero(t, requests.Load())
+}
+
+func TestNotifyNewVulnerabilities_Retry(t *testing.T) {
+	tests := []struct {
+		name             string
+		statusCodes      []int
+		expectedRequests int32
+		expectedError    string
+	}{
+		{
+			name:             "server error retried",
+			statusCodes:      []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK},
+			expectedRequests: 3,
+		},
+		{
+			name:             "client error not retried",
+			statusCodes:      []int{http.StatusNotFound},
+			expectedRequests: 1,
+			expectedError:    "unexpected status code 404",
+		},
+		{
+			name:             "attempts exhausted",
+			statusCodes:      []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			expectedRequests: 3,
+			expectedError:    "unexpected status code 500",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				request := requests.Add(1)
+				w.WriteHeader(test.statusCodes[request-1])
+			}))
+			defer server.Close()
+
+			notifier := NewNotifier(Options{URL: server.URL, MinSeverity: "CRITICAL", Attempts: 3}, slog.Default())
+			notifier.retryDelay = time.Millisecond
+			report := newTestReport(
+				storagev1alpha1.Vulnerability{CVE: "CVE-2024-0001", Severity: "CRITICAL", PURL: "pkg:apk/alpine/openssl@3.3.0"},
+			)
+
+			err := notifier.NotifyNewVulnerabilities(t.Context(), report, nil)
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedRequests, requests.Load())
+		})
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       Options
+		expectedError string
+	}{
+		{
+			name:    "disabled",
+			options: Options{},
+		},
+		{
+			name:    "valid",
+			options: Options{URL: "https://hooks.slack.com/services/T000/B000/XXXX", MinSeverity: "HIGH", Attempts: 5},
+		},
+		{
+			name:          "invalid scheme",
+			options:       Options{URL: "ftp://example.com", MinSeverity: "HIGH", Attempts: 5},
+			expectedError: "the scheme must be http or https",
+		},
+		{
+			name:          "invalid severity",
+			options:       Options{URL: "https://example.com", MinSeverity: "SEVERE", Attempts: 5},
+			expectedError: `invalid notification minimum severity "SEVERE"`,
+		},
+		{
+			name:          "no attempts",
+			options:       Options{URL: "https://example.com", MinSeverity: "HIGH"},
+			expectedError: "the notification attempts must be greater than 0",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.options.Validate()
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
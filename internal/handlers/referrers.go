@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+	"github.com/kubewarden/sbomscanner/internal/sbomimport"
+)
+
+const (
+	// spdxArtifactType is the artifact type of the SPDX JSON SBOMs attached to the images.
+	spdxArtifactType = "application/spdx+json"
+	// cycloneDXArtifactType is the artifact type of the CycloneDX JSON SBOMs attached to the images.
+	cycloneDXArtifactType = "application/vnd.cyclonedx+json"
+	// maxReferrerSBOMSize is the maximum size of a SBOM attached to an image.
+	maxReferrerSBOMSize = 100 << 20
+)
+
+// sbomArtifactTypes are the artifact types of the referrers containing a SBOM.
+var sbomArtifactTypes = []string{spdxArtifactType, cycloneDXArtifactType}
+
+// referrerSBOM is a SBOM attached to an image, found with the OCI referrers API.
+type referrerSBOM struct {
+	// Digest is the digest of the referrer manifest.
+	Digest string
+	// SPDX is the SBOM, converted to SPDX JSON when needed.
+	SPDX []byte
+}
+
+// findReferrerSBOM returns the first valid SBOM attached to the image, using the OCI referrers API.
+// It returns nil when no valid SBOM is attached to the image.
+func findReferrerSBOM(ctx context.Context, ref name.Digest, options []remote.Option, logger *slog.Logger) (*referrerSBOM, error) {
+	options = append(slices.Clone(options), remote.WithContext(ctx))
+
+	index, err := remote.Referrers(ref, options...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list referrers of %s: %w", ref, err)
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read referrers of %s: %w", ref, err)
+	}
+
+	for _, descriptor := range manifest.Manifests {
+		if !slices.Contains(sbomArtifactTypes, descriptor.ArtifactType) {
+			continue
+		}
+
+		referrer := ref.Context().Digest(descriptor.Digest.String())
+		data, err := fetchReferrerSBOM(referrer, descriptor.ArtifactType, options)
+		if err != nil {
+			logger.WarnContext(ctx, "Cannot fetch SBOM referrer, skipping", "referrer", referrer.String(), "error", err)
+			continue
+		}
+
+		document, err := sbomimport.Parse(data)
+		if err != nil {
+			logger.WarnContext(ctx, "Invalid SBOM referrer, skipping", "referrer", referrer.String(), "error", err)
+			continue
+		}
+
+		return &referrerSBOM{
+			Digest: descriptor.Digest.String(),
+			SPDX:   document.SPDX,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// fetchReferrerSBOM returns the content of the layer of the referrer with the SBOM media type.
+func fetchReferrerSBOM(referrer name.Digest, artifactType string, options []remote.Option) ([]byte, error) {
+	image, err := remote.Image(referrer, options...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch referrer manifest: %w", err)
+	}
+	layers, err := image.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read referrer layers: %w", err)
+	}
+
+	for _, layer := range layers {
+		mediaType, err := layer.MediaType()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read referrer layer media type: %w", err)
+		}
+		if string(mediaType) != artifactType {
+			continue
+		}
+
+		reader, err := layer.Compressed()
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch referrer layer: %w", err)
+		}
+		defer reader.Close()
+
+		data, err := io.ReadAll(io.LimitReader(reader, maxReferrerSBOMSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read referrer layer: %w", err)
+		}
+		if len(data) > maxReferrerSBOMSize {
+			return nil, fmt.Errorf("SBOM exceeds the maximum size of %d bytes", maxReferrerSBOMSize)
+		}
+
+		return data, nil
+	}
+
+	return nil, errors.New("no layer with the SBOM media type found")
+}
+
+// findReferrerSBOM looks for a SBOM attached to the image in the registry.
+// The registry credentials must already be set up.
+func (h *GenerateSBOMHandler) findReferrerSBOM(ctx context.Context, registry *v1alpha1.Registry, imageRef string) (*referrerSBOM, error) {
+	ref, err := name.NewDigest(imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse image reference %s: %w", imageRef, err)
+	}
+
+	options, err := h.remoteOptions(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	return findReferrerSBOM(ctx, ref, options, h.logger)
+}
+
+// remoteOptions returns the options to fetch the manifests of the registry,
+// with its TLS configuration and the credentials set up in the Docker config.
+func (h *GenerateSBOMHandler) remoteOptions(registry *v1alpha1.Registry) ([]remote.Option, error) {
+	transport, ok := remote.DefaultTransport.(*http.Transport)
+	if !ok {
+		// should not happen
+		return nil, errors.New("remote.DefaultTransport is not an *http.Transport")
+	}
+	transport = transport.Clone()
+	transport.TLSClientConfig = tlsConfigFromRegistry(registry, h.logger)
+	if registry.Spec.RevocationCheck != "" {
+		transport.TLSClientConfig.VerifyConnection = h.revocationChecker.VerifyConnection(registry.Spec.RevocationCheck)
+	}
+
+	return []remote.Option{
+		remote.WithTransport(transport),
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+	}, nil
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	cranev1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const testReferrerSPDX = `{
+  "spdxVersion": "SPDX-2.3",
+  "dataLicense": "CC0-1.0",
+  "SPDXID": "SPDXRef-DOCUMENT",
+  "name": "test-image",
+  "documentNamespace": "https://example.com/test-image",
+  "creationInfo": {
+    "creators": ["Tool: test"],
+    "created": "2025-01-01T00:00:00Z"
+  },
+  "packages": [
+    {
+      "name": "musl",
+      "SPDXID": "SPDXRef-Package-1",
+      "versionInfo": "1.1.20-r4",
+      "downloadLocation": "NONE"
+    }
+  ]
+}`
+
+// pushTestImage pushes a random image to the repository and returns its digest reference.
+func pushTestImage(t *testing.T, repository name.Repository) name.Digest {
+	t.Helper()
+
+	image, err := random.Image(64, 1)
+	require.NoError(t, err)
+	digest, err := image.Digest()
+	require.NoError(t, err)
+
+	ref := repository.Digest(digest.String())
+	require.NoError(t, remote.Write(ref, image))
+
+	return ref
+}
+
+// attachTestArtifact pushes an artifact with the given artifact type and content, referring to the subject.
+func attachTestArtifact(t *testing.T, subject name.Digest, artifactType string, content string) cranev1.Hash {
+	t.Helper()
+
+	subjectImage, err := remote.Image(subject)
+	require.NoError(t, err)
+	subjectDescriptor, err := partial.Descriptor(subjectImage)
+	require.NoError(t, err)
+
+	artifact, err := mutate.AppendLayers(empty.Image, static.NewLayer([]byte(content), types.MediaType(artifactType)))
+	require.NoError(t, err)
+	artifact = mutate.MediaType(artifact, types.OCIManifestSchema1)
+	artifact = mutate.ConfigMediaType(artifact, types.MediaType(artifactType))
+	artifact, ok := mutate.Subject(artifact, *subjectDescriptor).(cranev1.Image)
+	require.True(t, ok)
+
+	digest, err := artifact.Digest()
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(subject.Context().Digest(digest.String()), artifact))
+
+	return digest
+}
+
+func TestFindReferrerSBOM(t *testing.T) {
+	server := httptest.NewServer(ggcrregistry.New(ggcrregistry.WithReferrersSupport(true)))
+	defer server.Close()
+
+	repository, err := name.NewRepository(strings.TrimPrefix(server.URL, "http://") + "/test")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		artifacts      map[string]string
+		expectReferrer bool
+	}{
+		{
+			name:           "no referrers",
+			expectReferrer: false,
+		},
+		{
+			name: "SPDX referrer",
+			artifacts: map[string]string{
+				spdxArtifactType: testReferrerSPDX,
+			},
+			expectReferrer: true,
+		},
+		{
+			name: "unrelated referrer",
+			artifacts: map[string]string{
+				"application/vnd.dev.cosign.artifact.sig.v1+json": "{}",
+			},
+			expectReferrer: false,
+		},
+		{
+			name: "invalid SBOM referrer",
+			artifacts: map[string]string{
+				spdxArtifactType: `{"spdxVersion": "SPDX-2.3", "name" "broken"}`,
+			},
+			expectReferrer: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			image := pushTestImage(t, repository)
+
+			var expectedDigest cranev1.Hash
+			for artifactType, content := range test.artifacts {
+				expectedDigest = attachTestArtifact(t, image, artifactType, content)
+			}
+
+			referrer, err := findReferrerSBOM(context.Background(), image, nil, slog.Default())
+			require.NoError(t, err)
+
+			if !test.expectReferrer {
+				assert.Nil(t, referrer)
+				return
+			}
+			require.NotNil(t, referrer)
+			assert.Equal(t, expectedDigest.String(), referrer.Digest)
+			assert.JSONEq(t, testReferrerSPDX, string(referrer.SPDX))
+		})
+	}
+}
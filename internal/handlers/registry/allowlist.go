@@ -0,0 +1,108 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// HostAllowlist restricts the registry hosts the worker may contact, in the locked-down environments.
+// A nil HostAllowlist allows all the hosts.
+type HostAllowlist struct {
+	patterns []string
+}
+
+// NewHostAllowlist returns the allowlist of the given host patterns, or nil to allow all the hosts when empty.
+// A pattern is a host, e.g. registry.example.com, matching the host on any port,
+// a host and a port, e.g. registry.example.com:5000, matching only this port,
+// or a wildcard, e.g. *.example.com, matching the subdomains of the domain on any port.
+func NewHostAllowlist(patterns []string) (*HostAllowlist, error) {
+	if len(patterns) == 0 {
+		return nil, nil //nolint:nilnil // A nil allowlist allows all the hosts.
+	}
+
+	allowlist := &HostAllowlist{}
+	for _, pattern := range patterns {
+		normalized, err := normalizeHostPattern(strings.ToLower(strings.TrimSpace(pattern)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed registry host %q: %w", pattern, err)
+		}
+		allowlist.patterns = append(allowlist.patterns, normalized)
+	}
+
+	return allowlist, nil
+}
+
+// normalizeHostPattern returns an error if the pattern is not a host, a host and a port, or a wildcard.
+// The hosts are normalized as the registry URIs, e.g. docker.io is index.docker.io.
+func normalizeHostPattern(pattern string) (string, error) {
+	if pattern == "" {
+		return "", errors.New("the host must not be empty")
+	}
+	if strings.Contains(pattern, "/") {
+		return "", errors.New("the host must not contain a scheme or a path")
+	}
+	host, isWildcard := strings.CutPrefix(pattern, "*.")
+	if strings.Contains(host, "*") {
+		return "", errors.New("the wildcard must be the first label, e.g. *.example.com")
+	}
+	if isWildcard && strings.Contains(host, ":") {
+		return "", errors.New("the wildcards match any port, the port must not be set")
+	}
+	registry, err := name.NewRegistry(host, name.StrictValidation)
+	if err != nil {
+		return "", err
+	}
+	if isWildcard {
+		return pattern, nil
+	}
+
+	return registry.RegistryStr(), nil
+}
+
+// Allows returns true if the registry of the URI, e.g. registry.example.com:5000, matches a pattern of the allowlist.
+// The URIs that cannot be parsed are not allowed.
+func (a *HostAllowlist) Allows(uri string) bool {
+	if a == nil {
+		return true
+	}
+
+	registry, err := name.NewRegistry(uri)
+	if err != nil {
+		return false
+	}
+	hostPort := strings.ToLower(registry.RegistryStr())
+	host := hostPort
+	if splitHost, _, err := net.SplitHostPort(hostPort); err == nil {
+		host = splitHost
+	}
+
+	for _, pattern := range a.patterns {
+		switch {
+		case strings.HasPrefix(pattern, "*."):
+			if strings.HasSuffix(host, pattern[1:]) {
+				return true
+			}
+		case strings.Contains(pattern, ":"):
+			if hostPort == pattern {
+				return true
+			}
+		case host == pattern:
+			return true
+		}
+	}
+
+	return false
+}
+
+// String returns the patterns of the allowlist, for the logs and the status messages.
+func (a *HostAllowlist) String() string {
+	if a == nil {
+		return "*"
+	}
+
+	return strings.Join(a.patterns, ", ")
+}
@@ -0,0 +1,56 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHostAllowlist(t *testing.T) {
+	allowlist, err := NewHostAllowlist(nil)
+	require.NoError(t, err)
+	assert.Nil(t, allowlist)
+
+	_, err = NewHostAllowlist([]string{"registry.example.com", "registry.example.com:5000", " *.Example.org "})
+	require.NoError(t, err)
+
+	for _, pattern := range []string{"", "https://registry.example.com", "registry.example.com/library", "registry.*.com", "*.example.com:5000"} {
+		_, err = NewHostAllowlist([]string{pattern})
+		require.Error(t, err, pattern)
+	}
+}
+
+func TestHostAllowlistAllows(t *testing.T) {
+	allowlist, err := NewHostAllowlist([]string{"ghcr.io", "registry.example.com:5000", "*.internal.example.org", "docker.io"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		uri      string
+		expected bool
+	}{
+		{uri: "ghcr.io", expected: true},
+		{uri: "GHCR.io", expected: true},
+		{uri: "ghcr.io:443", expected: true},
+		{uri: "registry.example.com:5000", expected: true},
+		{uri: "registry.example.com", expected: false},
+		{uri: "registry.example.com:5001", expected: false},
+		{uri: "harbor.internal.example.org", expected: true},
+		{uri: "harbor.internal.example.org:8443", expected: true},
+		{uri: "internal.example.org", expected: false},
+		{uri: "evilinternal.example.org", expected: false},
+		{uri: "docker.io", expected: true},
+		{uri: "index.docker.io", expected: true},
+		{uri: "quay.io", expected: false},
+		{uri: "ghcr.io.attacker.com", expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.uri, func(t *testing.T) {
+			assert.Equal(t, test.expected, allowlist.Allows(test.uri))
+		})
+	}
+
+	var allowAll *HostAllowlist
+	assert.True(t, allowAll.Allows("docker.io"))
+}
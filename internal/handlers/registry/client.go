@@ -75,7 +75,7 @@ func (c *client) Catalog(ctx context.Context, registry name.Registry) ([]string,
 
 	catalogger, err := puller.Catalogger(ctx, registry)
 	if err != nil {
-		return []string{}, fmt.Errorf("cannot create catalogger for %s: %w", registry.Name(), err)
+		return []string{}, fmt.Errorf("cannot create catalogger for %s: %w", registry.Name(), classifyError(err))
 	}
 
 	repositories := []string{}
@@ -84,7 +84,7 @@ func (c *client) Catalog(ctx context.Context, registry name.Registry) ([]string,
 		var repos *remote.Catalogs
 		repos, err = catalogger.Next(ctx)
 		if err != nil {
-			return []string{}, fmt.Errorf("cannot iterate over repository %s contents: %w", registry.Name(), err)
+			return []string{}, fmt.Errorf("cannot iterate over repository %s contents: %w", registry.Name(), classifyError(err))
 		}
 		for _, repo := range repos.Repos {
 			repositories = append(repositories, path.Join(registry.Name(), repo))
@@ -112,7 +112,7 @@ func (c *client) ListRepositoryContents(ctx context.Context, repo name.Repositor
 
 	lister, err := puller.Lister(ctx, repo)
 	if err != nil {
-		return []string{}, fmt.Errorf("cannot create lister for repository %s: %w", repo, err)
+		return []string{}, fmt.Errorf("cannot create lister for repository %s: %w", repo, classifyError(err))
 	}
 
 	images := []string{}
@@ -120,7 +120,7 @@ func (c *client) ListRepositoryContents(ctx context.Context, repo name.Repositor
 		var tags *remote.Tags
 		tags, err = lister.Next(ctx)
 		if err != nil {
-			return []string{}, fmt.Errorf("cannot iterate over repository contents: %w", err)
+			return []string{}, fmt.Errorf("cannot iterate over repository contents: %w", classifyError(err))
 		}
 		for _, tag := range tags.Tags {
 			images = append(images, repo.Tag(tag).String())
@@ -143,7 +143,7 @@ func (c *client) GetImageIndex(ref name.Reference) (cranev1.ImageIndex, error) {
 		remote.WithTransport(c.transport),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("cannot fetch image index %q: %w", ref, err)
+		return nil, fmt.Errorf("cannot fetch image index %q: %w", ref, classifyError(err))
 	}
 	return index, nil
 }
@@ -161,7 +161,7 @@ func (c *client) GetImageDetails(ref name.Reference, platform *cranev1.Platform)
 
 	img, err := remote.Image(ref, options...)
 	if err != nil {
-		return ImageDetails{}, fmt.Errorf("cannot fetch image %q: %w", ref, err)
+		return ImageDetails{}, fmt.Errorf("cannot fetch image %q: %w", ref, classifyError(err))
 	}
 
 	imageDigest, err := img.Digest()
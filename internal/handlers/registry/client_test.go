@@ -0,0 +1,127 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_CatalogPagination(t *testing.T) {
+	pages := map[string][]string{
+		"":                {"library/alpine", "library/busybox"},
+		"library/busybox": {"project/app"},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case "/v2/_catalog":
+			last := r.URL.Query().Get("last")
+			repos, ok := pages[last]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			// Harbor paginates the catalog with the Link header, as described by the distribution spec.
+			if last == "" {
+				w.Header().Set("Link", fmt.Sprintf(`</v2/_catalog?last=%s&n=2>; rel="next"`, url.QueryEscape(repos[len(repos)-1])))
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(map[string][]string{"repositories": repos}))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	registry, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+	require.NoError(t, err)
+
+	client := NewClient(http.DefaultTransport, slog.Default())
+	repositories, err := client.Catalog(t.Context(), registry)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		registry.Name() + "/library/alpine",
+		registry.Name() + "/library/busybox",
+		registry.Name() + "/project/app",
+	}, repositories)
+}
+
+func TestClient_CatalogInsufficientScope(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":[{"code":"DENIED","message":"requested access to the resource is denied"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	registry, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+	require.NoError(t, err)
+
+	client := NewClient(http.DefaultTransport, slog.Default())
+	_, err = client.Catalog(t.Context(), registry)
+	require.ErrorIs(t, err, ErrInsufficientScope)
+	require.NotErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{
+			name:     "credentials rejected",
+			err:      &transport.Error{StatusCode: http.StatusUnauthorized, Errors: []transport.Diagnostic{{Code: transport.UnauthorizedErrorCode}}},
+			expected: ErrUnauthenticated,
+		},
+		{
+			name:     "forbidden",
+			err:      &transport.Error{StatusCode: http.StatusForbidden},
+			expected: ErrInsufficientScope,
+		},
+		{
+			name:     "access denied",
+			err:      &transport.Error{StatusCode: http.StatusUnauthorized, Errors: []transport.Diagnostic{{Code: transport.DeniedErrorCode}}},
+			expected: ErrInsufficientScope,
+		},
+		{
+			name: "not found",
+			err:  &transport.Error{StatusCode: http.StatusNotFound},
+		},
+		{
+			name: "not a registry error",
+			err:  errors.New("connection refused"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := classifyError(fmt.Errorf("wrapped: %w", test.err))
+			require.ErrorIs(t, err, test.err)
+			if test.expected == nil {
+				assert.NotErrorIs(t, err, ErrUnauthenticated)
+				assert.NotErrorIs(t, err, ErrInsufficientScope)
+				return
+			}
+			assert.ErrorIs(t, err, test.expected)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+var (
+	// ErrUnauthenticated is returned when the registry rejects the credentials,
+	// for example because they are invalid or expired.
+	ErrUnauthenticated = errors.New("registry rejected the credentials")
+
+	// ErrInsufficientScope is returned when the credentials are valid, but are not allowed
+	// to access the requested resource.
+	// This is typically the case of Harbor robot accounts, which are scoped to a set of projects
+	// and cannot list the registry catalog or pull from the other projects.
+	ErrInsufficientScope = errors.New("registry credentials are not allowed to access the resource")
+)
+
+// classifyError wraps the authentication and authorization errors returned by the registry
+// with ErrUnauthenticated or ErrInsufficientScope, so that they can be told apart from generic failures.
+//
+// A 403 status, or a DENIED error code, means the registry recognized the credentials,
+// but the token does not grant the requested scope.
+// Any other 401 status, including the token service rejecting the credentials, means they are invalid.
+func classifyError(err error) error {
+	var transportErr *transport.Error
+	if !errors.As(err, &transportErr) {
+		return err
+	}
+
+	if transportErr.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("%w: %w", ErrInsufficientScope, err)
+	}
+	for _, diagnostic := range transportErr.Errors {
+		if diagnostic.Code == transport.DeniedErrorCode {
+			return fmt.Errorf("%w: %w", ErrInsufficientScope, err)
+		}
+	}
+	if transportErr.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+
+	return err
+}
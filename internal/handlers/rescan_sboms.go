@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+	"github.com/kubewarden/sbomscanner/internal/messaging"
+)
+
+// RescanSBOMsHandler is a handler for scanning again the SBOMs already stored for the images of a registry.
+// The images are neither discovered nor pulled, so that the reports are updated as soon as the vulnerability
+// database is refreshed.
+type RescanSBOMsHandler struct {
+	k8sClient client.Client
+	publisher messaging.Publisher
+	logger    *slog.Logger
+}
+
+// NewRescanSBOMsHandler creates a new instance of RescanSBOMsHandler.
+func NewRescanSBOMsHandler(
+	k8sClient client.Client,
+	publisher messaging.Publisher,
+	logger *slog.Logger,
+) *RescanSBOMsHandler {
+	return &RescanSBOMsHandler{
+		k8sClient: k8sClient,
+		publisher: publisher,
+		logger:    logger.With("handler", "rescan_sboms_handler"),
+	}
+}
+
+// Handle processes the rescan SBOMs message and publishes a scan SBOM message for each SBOM of the registry.
+// The scan SBOM messages make every worker refresh its vulnerability database once for the scan job,
+// since the database is cached by each worker.
+func (h *RescanSBOMsHandler) Handle(ctx context.Context, message messaging.Message) error {
+	rescanSBOMsMessage := &RescanSBOMsMessage{}
+	if err := json.Unmarshal(message.Data(), rescanSBOMsMessage); err != nil {
+		return fmt.Errorf("cannot unmarshal message: %w", err)
+	}
+
+	h.logger.InfoContext(ctx, "SBOMs rescan requested",
+		"scanjob", rescanSBOMsMessage.ScanJob.Name,
+		"namespace", rescanSBOMsMessage.ScanJob.Namespace,
+	)
+
+	scanJob := &v1alpha1.ScanJob{}
+	err := h.k8sClient.Get(ctx, client.ObjectKey{
+		Name:      rescanSBOMsMessage.ScanJob.Name,
+		Namespace: rescanSBOMsMessage.ScanJob.Namespace,
+	}, scanJob)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// Stop processing if the scanjob is not found, since it might have been deleted.
+			h.logger.InfoContext(ctx, "ScanJob not found, stopping SBOMs rescan", "scanjob", rescanSBOMsMessage.ScanJob.Name, "namespace", rescanSBOMsMessage.ScanJob.Namespace)
+			return nil
+		}
+		return fmt.Errorf("cannot get scanjob %s/%s: %w", rescanSBOMsMessage.ScanJob.Namespace, rescanSBOMsMessage.ScanJob.Name, err)
+	}
+	if string(scanJob.GetUID()) != rescanSBOMsMessage.ScanJob.UID {
+		h.logger.InfoContext(ctx, "ScanJob not found, stopping SBOMs rescan (UID changed)", "scanjob", rescanSBOMsMessage.ScanJob.Name, "namespace", rescanSBOMsMessage.ScanJob.Namespace,
+			"uid", rescanSBOMsMessage.ScanJob.UID)
+		return nil
+	}
+
+	sbomList := &storagev1alpha1.SBOMList{}
+	if err = h.k8sClient.List(ctx, sbomList,
+		client.InNamespace(scanJob.Namespace),
+		client.MatchingFields{storagev1alpha1.IndexImageMetadataRegistry: scanJob.Spec.Registry},
+	); err != nil {
+		return fmt.Errorf("cannot list SBOMs of registry %s: %w", scanJob.Spec.Registry, err)
+	}
+
+	// It is possible that the controller is slow to set the status condition "Scheduled" to true,
+	// so we might encounter conflicts when setting the status conditions.
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err = h.k8sClient.Get(ctx, client.ObjectKeyFromObject(scanJob), scanJob); err != nil {
+			return fmt.Errorf("cannot get scan job %s/%s while updating status: %w", scanJob.Namespace, scanJob.Name, err)
+		}
+
+		if len(sbomList.Items) == 0 {
+			h.logger.InfoContext(ctx, "No SBOMs to rescan", "scanjob", scanJob.Name, "namespace", scanJob.Namespace)
+			scanJob.MarkComplete(v1alpha1.ReasonNoSBOMsToRescan, "No SBOMs to rescan")
+		} else {
+			h.logger.InfoContext(ctx, "SBOMs to rescan", "count", len(sbomList.Items))
+			scanJob.MarkInProgress(v1alpha1.ReasonRescanInProgress, "SBOMs rescan in progress")
+			scanJob.Status.ImagesCount = len(sbomList.Items)
+			scanJob.Status.ScannedImagesCount = 0
+		}
+
+		return h.k8sClient.Status().Update(ctx, scanJob)
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			h.logger.InfoContext(ctx, "ScanJob not found, stopping SBOMs rescan", "scanjob", rescanSBOMsMessage.ScanJob.Name, "namespace", rescanSBOMsMessage.ScanJob.Namespace)
+			return nil
+		}
+		return fmt.Errorf("cannot update scan job status %s/%s: %w", rescanSBOMsMessage.ScanJob.Namespace, rescanSBOMsMessage.ScanJob.Name, err)
+	}
+
+	// The workers refresh their vulnerability database when it was downloaded before the scan job was created.
+	notBefore := scanJob.GetCreationTimestampFromAnnotation()
+	for _, sbom := range sbomList.Items {
+		h.logger.DebugContext(ctx, "Sending scan SBOM message", "sbom", sbom.Name, "namespace", sbom.Namespace)
+
+		messageID := fmt.Sprintf("scanSBOM/%s/%s", scanJob.UID, sbom.Name)
+		message, err := json.Marshal(&ScanSBOMMessage{
+			BaseMessage: BaseMessage{
+				ScanJob: rescanSBOMsMessage.ScanJob,
+			},
+			SBOM: ObjectRef{
+				Name:      sbom.Name,
+				Namespace: sbom.Namespace,
+			},
+			Reevaluation:             true,
+			VulnerabilityDBNotBefore: notBefore,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot marshal scan SBOM message for SBOM %s/%s: %w", sbom.Namespace, sbom.Name, err)
+		}
+
+		if err = h.publisher.Publish(ctx, ScanSBOMSubject, messageID, message); err != nil {
+			return fmt.Errorf("cannot publish scan SBOM message for SBOM %s/%s: %w", sbom.Namespace, sbom.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+	messagingMocks "github.com/kubewarden/sbomscanner/internal/messaging/mocks"
+	"github.com/kubewarden/sbomscanner/pkg/generated/clientset/versioned/scheme"
+)
+
+func TestRescanSBOMsHandler_Handle(t *testing.T) {
+	creationTimestamp := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	scanJob := &v1alpha1.ScanJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scanjob",
+			Namespace: "default",
+			UID:       "test-scanjob-uid",
+			Annotations: map[string]string{
+				v1alpha1.AnnotationScanJobCreationTimestampKey: creationTimestamp.Format(time.RFC3339Nano),
+			},
+		},
+		Spec: v1alpha1.ScanJobSpec{
+			Registry: "test-registry",
+			Rescan:   true,
+		},
+	}
+
+	newSBOM := func(name, registry string) *storagev1alpha1.SBOM {
+		return &storagev1alpha1.SBOM{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			ImageMetadata: storagev1alpha1.ImageMetadata{
+				Registry:    registry,
+				RegistryURI: "registry.test",
+				Repository:  "repo1",
+				Tag:         "v1.0",
+			},
+		}
+	}
+	sbom := newSBOM("test-sbom", scanJob.Spec.Registry)
+	otherRegistrySBOM := newSBOM("other-sbom", "other-registry")
+
+	expectedMessage, err := json.Marshal(&ScanSBOMMessage{
+		BaseMessage: BaseMessage{
+			ScanJob: ObjectRef{
+				Name:      scanJob.Name,
+				Namespace: scanJob.Namespace,
+				UID:       string(scanJob.UID),
+			},
+		},
+		SBOM: ObjectRef{
+			Name:      sbom.Name,
+			Namespace: sbom.Namespace,
+		},
+		Reevaluation:             true,
+		VulnerabilityDBNotBefore: creationTimestamp,
+	})
+	require.NoError(t, err)
+
+	mockPublisher := messagingMocks.NewMockPublisher(t)
+	mockPublisher.On("Publish",
+		mock.Anything,
+		ScanSBOMSubject,
+		fmt.Sprintf("scanSBOM/%s/%s", scanJob.UID, sbom.Name),
+		expectedMessage,
+	).Return(nil).Once()
+
+	scheme := scheme.Scheme
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, storagev1alpha1.AddToScheme(scheme))
+
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(scanJob, sbom, otherRegistrySBOM).
+		WithStatusSubresource(&v1alpha1.ScanJob{}).
+		WithIndex(&storagev1alpha1.SBOM{}, storagev1alpha1.IndexImageMetadataRegistry, func(obj client.Object) []string {
+			sbom, ok := obj.(*storagev1alpha1.SBOM)
+			if !ok {
+				return nil
+			}
+			return []string{sbom.GetImageMetadata().Registry}
+		}).
+		Build()
+
+	handler := NewRescanSBOMsHandler(k8sClient, mockPublisher, slog.Default())
+
+	message, err := json.Marshal(&RescanSBOMsMessage{
+		BaseMessage: BaseMessage{
+			ScanJob: ObjectRef{
+				Name:      scanJob.Name,
+				Namespace: scanJob.Namespace,
+				UID:       string(scanJob.UID),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	err = handler.Handle(t.Context(), &testMessage{data: message})
+	require.NoError(t, err)
+
+	updatedScanJob := &v1alpha1.ScanJob{}
+	err = k8sClient.Get(t.Context(), client.ObjectKeyFromObject(scanJob), updatedScanJob)
+	require.NoError(t, err)
+	assert.True(t, updatedScanJob.IsInProgress())
+	assert.Equal(t, 1, updatedScanJob.Status.ImagesCount)
+	assert.Equal(t, 0, updatedScanJob.Status.ScannedImagesCount)
+}
+
+func TestRescanSBOMsHandler_Handle_NoSBOMs(t *testing.T) {
+	scanJob := &v1alpha1.ScanJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scanjob",
+			Namespace: "default",
+			UID:       "test-scanjob-uid",
+		},
+		Spec: v1alpha1.ScanJobSpec{
+			Registry: "test-registry",
+			Rescan:   true,
+		},
+	}
+
+	scheme := scheme.Scheme
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, storagev1alpha1.AddToScheme(scheme))
+
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(scanJob).
+		WithStatusSubresource(&v1alpha1.ScanJob{}).
+		WithIndex(&storagev1alpha1.SBOM{}, storagev1alpha1.IndexImageMetadataRegistry, func(obj client.Object) []string {
+			sbom, ok := obj.(*storagev1alpha1.SBOM)
+			if !ok {
+				return nil
+			}
+			return []string{sbom.GetImageMetadata().Registry}
+		}).
+		Build()
+
+	// No scan SBOM message is expected.
+	handler := NewRescanSBOMsHandler(k8sClient, messagingMocks.NewMockPublisher(t), slog.Default())
+
+	message, err := json.Marshal(&RescanSBOMsMessage{
+		BaseMessage: BaseMessage{
+			ScanJob: ObjectRef{
+				Name:      scanJob.Name,
+				Namespace: scanJob.Namespace,
+				UID:       string(scanJob.UID),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	err = handler.Handle(t.Context(), &testMessage{data: message})
+	require.NoError(t, err)
+
+	updatedScanJob := &v1alpha1.ScanJob{}
+	err = k8sClient.Get(t.Context(), client.ObjectKeyFromObject(scanJob), updatedScanJob)
+	require.NoError(t, err)
+	assert.True(t, updatedScanJob.IsComplete())
+}
@@ -0,0 +1,3 @@
+// Package resumable provides an HTTP transport verifying the digests of the downloaded OCI blobs,
+// and resuming their interrupted downloads with range requests instead of downloading them again from the start.
+package resumable
@@ -0,0 +1,197 @@
+package resumable
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// DefaultMaxResumes is the default maximum number of times the download of a single blob is resumed.
+const DefaultMaxResumes = 5
+
+// blobPathPattern matches the path of the OCI distribution API blob endpoint, capturing the digest of the blob.
+var blobPathPattern = regexp.MustCompile(`^/v2/.+/blobs/((sha256:[a-f0-9]{64})|(sha512:[a-f0-9]{128}))$`)
+
+// DigestMismatchError is returned when the content of a downloaded blob does not match its digest,
+// e.g. because it was corrupted by the registry or altered in transit.
+type DigestMismatchError struct {
+	Expected string
+	Actual   string
+}
+
+func (e *DigestMismatchError) Error() string {
+	return fmt.Sprintf("blob digest mismatch: expected %s, got %s", e.Expected, e.Actual)
+}
+
+// Transport is an http.RoundTripper verifying and resuming the downloads of the OCI blobs.
+// The digest of the content of a blob is checked once it has been read, and a DigestMismatchError
+// is returned instead of io.EOF when it does not match the digest requested.
+// When the body of a blob fails to be read, the download is resumed from the last byte received
+// with a range request.
+// The other requests are sent unchanged to the inner transport.
+type Transport struct {
+	inner      http.RoundTripper
+	maxResumes int
+	logger     *slog.Logger
+}
+
+// NewTransport creates a new Transport sending the requests with the inner transport,
+// resuming the download of a blob at most maxResumes times.
+func NewTransport(inner http.RoundTripper, maxResumes int, logger *slog.Logger) *Transport {
+	return &Transport{
+		inner:      inner,
+		maxResumes: maxResumes,
+		logger:     logger.With("component", "resumable_transport"),
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The partial requests are sent by the client itself, and the decompressed bodies
+	// cannot be resumed, since the offsets would not match the content sent by the registry.
+	if req.Method != http.MethodGet || resp.StatusCode != http.StatusOK || req.Header.Get("Range") != "" || resp.Uncompressed {
+		return resp, nil
+	}
+	digest, ok := BlobDigest(req)
+	if !ok {
+		return resp, nil
+	}
+
+	algorithm, _, _ := strings.Cut(digest, ":")
+	var digester hash.Hash
+	switch algorithm {
+	case "sha512":
+		digester = sha512.New()
+	default:
+		digester = sha256.New()
+	}
+
+	resp.Body = &resumableBody{
+		transport: t,
+		req:       req,
+		body:      resp.Body,
+		digest:    digest,
+		algorithm: algorithm,
+		hash:      digester,
+	}
+
+	return resp, nil
+}
+
+// BlobDigest returns the digest of the blob requested, if req requests a blob.
+// The registries usually redirect the blob requests to a storage service,
+// so the requests which caused the redirects are searched as well.
+func BlobDigest(req *http.Request) (string, bool) {
+	for req != nil {
+		if match := blobPathPattern.FindStringSubmatch(req.URL.Path); match != nil {
+			return match[1], true
+		}
+		if req.Response == nil {
+			break
+		}
+		req = req.Response.Request
+	}
+
+	return "", false
+}
+
+// resumableBody is the body of a blob response, resumed with a range request when it fails to be read.
+type resumableBody struct {
+	transport *Transport
+	req       *http.Request
+	body      io.ReadCloser
+	digest    string
+	algorithm string
+	hash      hash.Hash
+	// offset is the number of bytes of the blob read so far.
+	offset  int64
+	resumes int
+}
+
+// Read implements the io.Reader interface.
+func (b *resumableBody) Read(p []byte) (int, error) {
+	for {
+		n, err := b.body.Read(p)
+		b.hash.Write(p[:n])
+		b.offset += int64(n)
+
+		switch {
+		case err == nil:
+			return n, nil
+		case errors.Is(err, io.EOF):
+			if digest := b.algorithm + ":" + hex.EncodeToString(b.hash.Sum(nil)); digest != b.digest {
+				b.transport.logger.Warn("Downloaded blob does not match its digest", "expected", b.digest, "actual", digest, "url", b.req.URL.Redacted())
+				return n, &DigestMismatchError{Expected: b.digest, Actual: digest}
+			}
+			return n, io.EOF
+		case b.req.Context().Err() != nil:
+			return n, err
+		}
+
+		if resumeErr := b.resume(err); resumeErr != nil {
+			return n, resumeErr
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// Close implements the io.Closer interface.
+func (b *resumableBody) Close() error {
+	return b.body.Close()
+}
+
+// resume requests the rest of the blob, starting from the last byte read.
+// The cause of the interruption is returned when the download cannot be resumed.
+func (b *resumableBody) resume(cause error) error {
+	if b.resumes >= b.transport.maxResumes {
+		return cause
+	}
+	b.resumes++
+
+	if err := b.body.Close(); err != nil {
+		b.transport.logger.Debug("Failed to close the interrupted blob body", "digest", b.digest, "error", err)
+	}
+
+	b.transport.logger.Info("Resuming interrupted blob download",
+		"digest", b.digest,
+		"offset", b.offset,
+		"attempt", b.resumes,
+		"error", cause,
+	)
+
+	req := b.req.Clone(b.req.Context())
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", b.offset))
+	resp, err := b.transport.inner.RoundTrip(req)
+	if err != nil {
+		b.body = http.NoBody
+		return fmt.Errorf("failed to resume the download of blob %s at byte %d: %w", b.digest, b.offset, err)
+	}
+
+	if resp.StatusCode != http.StatusPartialContent || !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", b.offset)) {
+		if err := resp.Body.Close(); err != nil {
+			b.transport.logger.Debug("Failed to close the blob body", "digest", b.digest, "error", err)
+		}
+		b.body = http.NoBody
+		return fmt.Errorf("failed to resume the download of blob %s at byte %d, the range request is not supported (status %d): %w",
+			b.digest, b.offset, resp.StatusCode, cause)
+	}
+
+	b.body = resp.Body
+
+	return nil
+}
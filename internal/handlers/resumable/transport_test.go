@@ -0,0 +1,165 @@
+package resumable
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBlobServer returns a server serving the blob, interrupting the first response after cutAt bytes.
+// The range requests are honored when supportsRange is true.
+func newBlobServer(t *testing.T, blob []byte, cutAt int, supportsRange bool) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempt := requests.Add(1)
+
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && supportsRange {
+			start, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rangeHeader, "bytes="), "-"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(blob)-1, len(blob)))
+			w.Header().Set("Content-Length", strconv.Itoa(len(blob)-start))
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(blob[start:])
+			return
+		}
+
+		w.Header().Set("Content-Length", strconv.Itoa(len(blob)))
+		if attempt > 1 {
+			_, _ = w.Write(blob)
+			return
+		}
+
+		// Send a part of the blob, then drop the connection.
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(blob[:cutAt])
+		w.(http.Flusher).Flush()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("failed to hijack the connection: %v", err)
+			return
+		}
+		_ = conn.Close()
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func digestOf(blob []byte) string {
+	sum := sha256.Sum256(blob)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func get(t *testing.T, transport http.RoundTripper, url string) ([]byte, error) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+func TestTransport_ResumeBlob(t *testing.T) {
+	blob := bytes.Repeat([]byte("layer"), 10000)
+	server, requests := newBlobServer(t, blob, 1000, true)
+
+	transport := NewTransport(http.DefaultTransport, DefaultMaxResumes, slog.Default())
+	content, err := get(t, transport, server.URL+"/v2/kubewarden/sbomscanner/blobs/"+digestOf(blob))
+
+	require.NoError(t, err)
+	assert.Equal(t, blob, content)
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestTransport_DigestMismatch(t *testing.T) {
+	blob := bytes.Repeat([]byte("layer"), 10000)
+	server, _ := newBlobServer(t, blob, 1000, true)
+
+	transport := NewTransport(http.DefaultTransport, DefaultMaxResumes, slog.Default())
+	expected := digestOf([]byte("another layer"))
+	_, err := get(t, transport, server.URL+"/v2/kubewarden/sbomscanner/blobs/"+expected)
+
+	var mismatchErr *DigestMismatchError
+	require.ErrorAs(t, err, &mismatchErr)
+	assert.Equal(t, expected, mismatchErr.Expected)
+	assert.Equal(t, digestOf(blob), mismatchErr.Actual)
+}
+
+func TestTransport_SHA512(t *testing.T) {
+	blob := bytes.Repeat([]byte("layer"), 10000)
+	server, _ := newBlobServer(t, blob, 1000, true)
+	sum := sha512.Sum512(blob)
+
+	transport := NewTransport(http.DefaultTransport, DefaultMaxResumes, slog.Default())
+	content, err := get(t, transport, server.URL+"/v2/kubewarden/sbomscanner/blobs/sha512:"+hex.EncodeToString(sum[:]))
+
+	require.NoError(t, err)
+	assert.Equal(t, blob, content)
+}
+
+func TestTransport_RangeNotSupported(t *testing.T) {
+	blob := bytes.Repeat([]byte("layer"), 10000)
+	server, _ := newBlobServer(t, blob, 1000, false)
+
+	transport := NewTransport(http.DefaultTransport, DefaultMaxResumes, slog.Default())
+	_, err := get(t, transport, server.URL+"/v2/kubewarden/sbomscanner/blobs/"+digestOf(blob))
+
+	require.ErrorContains(t, err, "range request is not supported")
+}
+
+func TestTransport_MaxResumes(t *testing.T) {
+	blob := bytes.Repeat([]byte("layer"), 10000)
+	server, requests := newBlobServer(t, blob, 1000, true)
+
+	transport := NewTransport(http.DefaultTransport, 0, slog.Default())
+	_, err := get(t, transport, server.URL+"/v2/kubewarden/sbomscanner/blobs/"+digestOf(blob))
+
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestTransport_NotABlob(t *testing.T) {
+	blob := bytes.Repeat([]byte("layer"), 10000)
+	server, requests := newBlobServer(t, blob, 1000, true)
+
+	transport := NewTransport(http.DefaultTransport, DefaultMaxResumes, slog.Default())
+	_, err := get(t, transport, server.URL+"/v2/kubewarden/sbomscanner/manifests/latest")
+
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestBlobDigest_Redirect(t *testing.T) {
+	digest := digestOf([]byte("layer"))
+	original, err := http.NewRequest(http.MethodGet, "https://registry.example.com/v2/kubewarden/sbomscanner/blobs/"+digest, nil)
+	require.NoError(t, err)
+	redirected, err := http.NewRequest(http.MethodGet, "https://storage.example.com/layers/abc?signature=xyz", nil)
+	require.NoError(t, err)
+	redirected.Response = &http.Response{Request: original}
+
+	got, ok := BlobDigest(redirected)
+
+	require.True(t, ok)
+	assert.Equal(t, digest, got)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	spdxjson "github.com/spdx/tools-golang/json"
+	"github.com/spdx/tools-golang/spdx"
+	"github.com/spdx/tools-golang/spdx/common"
+	"github.com/spdx/tools-golang/spdx/v2/v2_2"
+	"github.com/spdx/tools-golang/spdx/v2/v2_3"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// DefaultSBOMSchemaVersion is the schema version of the SBOMs generated by Trivy.
+const DefaultSBOMSchemaVersion = spdx.Version
+
+// SupportedSBOMSchemaVersions are the schema versions the stored SBOMs can be pinned to.
+// The SBOMs are stored as SPDX JSON documents, so only the SPDX 2 versions are supported.
+var SupportedSBOMSchemaVersions = []string{v2_2.Version, v2_3.Version}
+
+// ValidateSBOMSchemaVersion returns an error if the SBOMs cannot be pinned to the schema version.
+func ValidateSBOMSchemaVersion(version string) error {
+	if slices.Contains(SupportedSBOMSchemaVersions, version) {
+		return nil
+	}
+	if strings.HasPrefix(strings.ToLower(version), "cyclonedx") {
+		return fmt.Errorf("unsupported SBOM schema version %q: the SBOMs are stored as SPDX documents, the supported versions are %s",
+			version, strings.Join(SupportedSBOMSchemaVersions, ", "))
+	}
+
+	return fmt.Errorf("unsupported SBOM schema version %q, the supported versions are %s",
+		version, strings.Join(SupportedSBOMSchemaVersions, ", "))
+}
+
+// sbomSchemaVersionOf returns the schema version recorded in the SBOM,
+// or the version of its SPDX document for the SBOMs stored before the version was recorded.
+func sbomSchemaVersionOf(sbom *storagev1alpha1.SBOM) string {
+	if version, ok := sbom.Annotations[storagev1alpha1.AnnotationSBOMSchemaVersionKey]; ok {
+		return version
+	}
+
+	return spdxVersionOf(sbom.SPDX.Raw)
+}
+
+// spdxVersionOf returns the spdxVersion of the SPDX JSON document, empty when it cannot be read.
+func spdxVersionOf(spdxBytes []byte) string {
+	header := struct {
+		SPDXVersion string `json:"spdxVersion"`
+	}{}
+	if err := json.Unmarshal(spdxBytes, &header); err != nil {
+		return ""
+	}
+
+	return header.SPDXVersion
+}
+
+// convertSPDXVersion converts the SPDX JSON document to the given SPDX version.
+// The document is returned unchanged when it already has this version.
+// Converting to an older version drops the fields the older version does not define,
+// e.g. the primary package purpose added by SPDX 2.3.
+func convertSPDXVersion(spdxBytes []byte, version string) ([]byte, error) {
+	if spdxVersionOf(spdxBytes) == version {
+		return spdxBytes, nil
+	}
+
+	var document common.AnyDocument
+	switch version {
+	case v2_2.Version:
+		document = &v2_2.Document{}
+	case v2_3.Version:
+		document = &v2_3.Document{}
+	default:
+		return nil, fmt.Errorf("unsupported SPDX version %q", version)
+	}
+
+	if err := spdxjson.ReadInto(bytes.NewReader(spdxBytes), document); err != nil {
+		return nil, fmt.Errorf("failed to read SPDX document: %w", err)
+	}
+	var converted bytes.Buffer
+	if err := spdxjson.Write(document, &converted); err != nil {
+		return nil, fmt.Errorf("failed to write SPDX document: %w", err)
+	}
+
+	return bytes.TrimSpace(converted.Bytes()), nil
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	spdxjson "github.com/spdx/tools-golang/json"
+	"github.com/spdx/tools-golang/spdx/v2/v2_2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+func TestValidateSBOMSchemaVersion(t *testing.T) {
+	require.NoError(t, ValidateSBOMSchemaVersion("SPDX-2.2"))
+	require.NoError(t, ValidateSBOMSchemaVersion("SPDX-2.3"))
+
+	require.EqualError(t, ValidateSBOMSchemaVersion("SPDX-3.0"),
+		`unsupported SBOM schema version "SPDX-3.0", the supported versions are SPDX-2.2, SPDX-2.3`)
+	require.EqualError(t, ValidateSBOMSchemaVersion("CycloneDX-1.6"),
+		`unsupported SBOM schema version "CycloneDX-1.6": the SBOMs are stored as SPDX documents, the supported versions are SPDX-2.2, SPDX-2.3`)
+	require.Error(t, ValidateSBOMSchemaVersion(""))
+}
+
+func TestConvertSPDXVersion(t *testing.T) {
+	spdxBytes, err := os.ReadFile("../../test/fixtures/golang-1.12-alpine-amd64.spdx.json")
+	require.NoError(t, err)
+	require.Equal(t, "SPDX-2.3", spdxVersionOf(spdxBytes))
+
+	unchanged, err := convertSPDXVersion(spdxBytes, "SPDX-2.3")
+	require.NoError(t, err)
+	assert.Equal(t, spdxBytes, unchanged)
+
+	converted, err := convertSPDXVersion(spdxBytes, "SPDX-2.2")
+	require.NoError(t, err)
+	assert.Equal(t, "SPDX-2.2", spdxVersionOf(converted))
+
+	original, err := spdxjson.Read(bytes.NewReader(spdxBytes))
+	require.NoError(t, err)
+	document := &v2_2.Document{}
+	require.NoError(t, spdxjson.ReadInto(bytes.NewReader(converted), document))
+	assert.Equal(t, original.DocumentName, document.DocumentName)
+	assert.Len(t, document.Packages, len(original.Packages))
+	assert.Len(t, document.Relationships, len(original.Relationships))
+
+	// The document converted back has the version of the stored SBOMs again.
+	reverted, err := convertSPDXVersion(converted, "SPDX-2.3")
+	require.NoError(t, err)
+	assert.Equal(t, "SPDX-2.3", spdxVersionOf(reverted))
+
+	_, err = convertSPDXVersion(spdxBytes, "SPDX-3.0")
+	require.Error(t, err)
+}
+
+func TestSBOMSchemaVersionOf(t *testing.T) {
+	sbom := &storagev1alpha1.SBOM{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{storagev1alpha1.AnnotationSBOMSchemaVersionKey: "SPDX-2.2"},
+		},
+		SPDX: runtime.RawExtension{Raw: []byte(`{"spdxVersion":"SPDX-2.3"}`)},
+	}
+	assert.Equal(t, "SPDX-2.2", sbomSchemaVersionOf(sbom))
+
+	// The SBOMs stored before the version was recorded have the version of their document.
+	sbom.Annotations = nil
+	assert.Equal(t, "SPDX-2.3", sbomSchemaVersionOf(sbom))
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	ftypes "github.com/aquasecurity/trivy/pkg/fanal/types"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+// Trivy records the package type of the SPDX packages in their annotations:
+// "PkgType: <type>" on the packages and "Type: <type>" on the OS and application components.
+// The type is the OS family for the OS packages, and the language package type, e.g. "npm" or "jar", for the others.
+var spdxTypeAnnotationPrefixes = []string{"PkgType: ", "Type: "}
+
+// sbomContentAnnotations are the annotations describing the content of the SBOM,
+// copied when the content is reused or replaced.
+var sbomContentAnnotations = []string{
+	storagev1alpha1.AnnotationSBOMSourceKey,
+	storagev1alpha1.AnnotationSBOMReferrerDigestKey,
+	storagev1alpha1.AnnotationSBOMScopeKey,
+	storagev1alpha1.AnnotationSBOMSchemaVersionKey,
+}
+
+// sbomScopeOf returns the scope recorded in the SBOM.
+// The SBOMs without the scope annotation include all the packages.
+func sbomScopeOf(sbom *storagev1alpha1.SBOM) string {
+	if scope, ok := sbom.Annotations[storagev1alpha1.AnnotationSBOMScopeKey]; ok {
+		return scope
+	}
+
+	return v1alpha1.SBOMScopePackagesAll
+}
+
+// filterSPDXEcosystems removes the language packages not belonging to the given ecosystems
+// from the SPDX JSON document generated by Trivy, together with their relationships.
+// The OS packages and the packages without type, e.g. the container image, are kept.
+// The document is handled as generic JSON, so that the fields unknown to SBOMscanner are preserved.
+func filterSPDXEcosystems(spdxBytes []byte, ecosystems []string) ([]byte, error) {
+	document := map[string]any{}
+	if err := json.Unmarshal(spdxBytes, &document); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal SPDX document: %w", err)
+	}
+
+	packages, _ := document["packages"].([]any)
+	removed := map[string]bool{}
+	keptPackages := make([]any, 0, len(packages))
+	for _, pkg := range packages {
+		pkgObject, _ := pkg.(map[string]any)
+		pkgType := spdxPackageType(pkgObject)
+		if pkgType == "" || isOSType(pkgType) || slices.Contains(ecosystems, pkgType) {
+			keptPackages = append(keptPackages, pkg)
+			continue
+		}
+		if id, ok := pkgObject["SPDXID"].(string); ok {
+			removed[id] = true
+		}
+	}
+	document["packages"] = keptPackages
+
+	if relationships, ok := document["relationships"].([]any); ok {
+		keptRelationships := make([]any, 0, len(relationships))
+		for _, relationship := range relationships {
+			relationshipObject, _ := relationship.(map[string]any)
+			element, _ := relationshipObject["spdxElementId"].(string)
+			related, _ := relationshipObject["relatedSpdxElement"].(string)
+			if removed[element] || removed[related] {
+				continue
+			}
+			keptRelationships = append(keptRelationships, relationship)
+		}
+		document["relationships"] = keptRelationships
+	}
+
+	filtered, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SPDX document: %w", err)
+	}
+
+	return filtered, nil
+}
+
+// spdxPackageType returns the package type recorded by Trivy in the annotations of the SPDX package,
+// or an empty string when it is not recorded.
+func spdxPackageType(pkg map[string]any) string {
+	annotations, _ := pkg["annotations"].([]any)
+	for _, annotation := range annotations {
+		annotationObject, _ := annotation.(map[string]any)
+		comment, _ := annotationObject["comment"].(string)
+		for _, prefix := range spdxTypeAnnotationPrefixes {
+			if pkgType, found := strings.CutPrefix(comment, prefix); found {
+				return pkgType
+			}
+		}
+	}
+
+	return ""
+}
+
+func isOSType(pkgType string) bool {
+	return slices.Contains(ftypes.OSTypes, ftypes.OSType(pkgType))
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+const testScopeSPDX = `{
+	"spdxVersion": "SPDX-2.3",
+	"SPDXID": "SPDXRef-DOCUMENT",
+	"packages": [
+		{"SPDXID": "SPDXRef-ContainerImage", "name": "image"},
+		{"SPDXID": "SPDXRef-OperatingSystem", "name": "alpine", "annotations": [{"comment": "Type: alpine"}]},
+		{"SPDXID": "SPDXRef-Package-musl", "name": "musl", "annotations": [{"comment": "PkgType: alpine"}]},
+		{"SPDXID": "SPDXRef-Application-npm", "name": "package-lock.json", "annotations": [{"comment": "Type: npm"}]},
+		{"SPDXID": "SPDXRef-Package-lodash", "name": "lodash", "annotations": [{"comment": "PkgType: npm"}]},
+		{"SPDXID": "SPDXRef-Application-jar", "name": "app.jar", "annotations": [{"comment": "Type: jar"}]},
+		{"SPDXID": "SPDXRef-Package-log4j", "name": "log4j-core", "annotations": [{"comment": "PkgType: jar"}]}
+	],
+	"relationships": [
+		{"spdxElementId": "SPDXRef-DOCUMENT", "relatedSpdxElement": "SPDXRef-ContainerImage", "relationshipType": "DESCRIBES"},
+		{"spdxElementId": "SPDXRef-ContainerImage", "relatedSpdxElement": "SPDXRef-OperatingSystem", "relationshipType": "CONTAINS"},
+		{"spdxElementId": "SPDXRef-OperatingSystem", "relatedSpdxElement": "SPDXRef-Package-musl", "relationshipType": "CONTAINS"},
+		{"spdxElementId": "SPDXRef-ContainerImage", "relatedSpdxElement": "SPDXRef-Application-npm", "relationshipType": "CONTAINS"},
+		{"spdxElementId": "SPDXRef-Application-npm", "relatedSpdxElement": "SPDXRef-Package-lodash", "relationshipType": "CONTAINS"},
+		{"spdxElementId": "SPDXRef-ContainerImage", "relatedSpdxElement": "SPDXRef-Application-jar", "relationshipType": "CONTAINS"},
+		{"spdxElementId": "SPDXRef-Application-jar", "relatedSpdxElement": "SPDXRef-Package-log4j", "relationshipType": "CONTAINS"}
+	]
+}`
+
+func TestFilterSPDXEcosystems(t *testing.T) {
+	filtered, err := filterSPDXEcosystems([]byte(testScopeSPDX), []string{"npm"})
+	require.NoError(t, err)
+
+	document := struct {
+		SPDXVersion string `json:"spdxVersion"`
+		Packages    []struct {
+			SPDXID string `json:"SPDXID"`
+		} `json:"packages"`
+		Relationships []struct {
+			Element string `json:"spdxElementId"`
+			Related string `json:"relatedSpdxElement"`
+		} `json:"relationships"`
+	}{}
+	require.NoError(t, json.Unmarshal(filtered, &document))
+
+	assert.Equal(t, "SPDX-2.3", document.SPDXVersion)
+
+	var packages []string
+	for _, pkg := range document.Packages {
+		packages = append(packages, pkg.SPDXID)
+	}
+	assert.Equal(t, []string{
+		"SPDXRef-ContainerImage",
+		"SPDXRef-OperatingSystem",
+		"SPDXRef-Package-musl",
+		"SPDXRef-Application-npm",
+		"SPDXRef-Package-lodash",
+	}, packages)
+
+	var related []string
+	for _, relationship := range document.Relationships {
+		related = append(related, relationship.Related)
+	}
+	assert.Equal(t, []string{
+		"SPDXRef-ContainerImage",
+		"SPDXRef-OperatingSystem",
+		"SPDXRef-Package-musl",
+		"SPDXRef-Application-npm",
+		"SPDXRef-Package-lodash",
+	}, related)
+}
+
+func TestFilterSPDXEcosystems_InvalidDocument(t *testing.T) {
+	_, err := filterSPDXEcosystems([]byte("not json"), []string{"npm"})
+	require.Error(t, err)
+}
+
+func TestSBOMScopeString(t *testing.T) {
+	tests := []struct {
+		name     string
+		scope    *v1alpha1.SBOMScope
+		expected string
+	}{
+		{
+			name:     "nil scope",
+			scope:    nil,
+			expected: "all",
+		},
+		{
+			name:     "all packages",
+			scope:    &v1alpha1.SBOMScope{Packages: v1alpha1.SBOMScopePackagesAll},
+			expected: "all",
+		},
+		{
+			name:     "OS packages only",
+			scope:    &v1alpha1.SBOMScope{Packages: v1alpha1.SBOMScopePackagesOS},
+			expected: "os",
+		},
+		{
+			name:     "ecosystems are sorted and deduplicated",
+			scope:    &v1alpha1.SBOMScope{Ecosystems: []string{"npm", "jar", "npm"}},
+			expected: "all:jar,npm",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.scope.String())
+		})
+	}
+}
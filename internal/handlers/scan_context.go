@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+)
+
+// scanContextTransport sends the requests to the registry with the context of the scan.
+// Trivy pulls the images without passing its context to the requests,
+// so without it the scan timeout would not cancel a stalled download.
+type scanContextTransport struct {
+	inner http.RoundTripper
+	ctx   context.Context
+}
+
+func newScanContextTransport(ctx context.Context, inner http.RoundTripper) *scanContextTransport {
+	return &scanContextTransport{
+		inner: inner,
+		ctx:   ctx,
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *scanContextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.inner.RoundTrip(req.WithContext(t.ctx))
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+	registryclient "github.com/kubewarden/sbomscanner/internal/handlers/registry"
+)
+
+// maxScanErrorMessageLength bounds the message recorded in the status of the images,
+// since the errors of Trivy can embed the output of every image source it tried.
+const maxScanErrorMessageLength = 1024
+
+// scanErrorPatterns classify the errors of Trivy which do not wrap the underlying error,
+// by the messages of the registry and of the Go standard library they contain.
+var scanErrorPatterns = []struct {
+	reason   storagev1alpha1.ImageScanErrorReason
+	patterns []string
+}{
+	{storagev1alpha1.ImageScanErrorReasonTLSError, []string{"x509: ", "tls: "}},
+	{storagev1alpha1.ImageScanErrorReasonRateLimited, []string{"TOOMANYREQUESTS", "429 Too Many Requests"}},
+	{storagev1alpha1.ImageScanErrorReasonAuthFailed, []string{"UNAUTHORIZED", "DENIED", "401 Unauthorized", "403 Forbidden"}},
+	{storagev1alpha1.ImageScanErrorReasonNotFound, []string{"MANIFEST_UNKNOWN", "NAME_UNKNOWN", "BLOB_UNKNOWN", "404 Not Found"}},
+	{storagev1alpha1.ImageScanErrorReasonTimeout, []string{"context deadline exceeded", "i/o timeout"}},
+}
+
+// classifyScanError returns the reason of the failed scan of an image.
+// The errors which cannot be attributed to the registry nor to the network are reported as generator errors.
+func classifyScanError(err error) storagev1alpha1.ImageScanErrorReason {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return storagev1alpha1.ImageScanErrorReasonTimeout
+	}
+	if errors.Is(err, registryclient.ErrUnauthenticated) || errors.Is(err, registryclient.ErrInsufficientScope) {
+		return storagev1alpha1.ImageScanErrorReasonAuthFailed
+	}
+
+	var transportErr *transport.Error
+	if errors.As(err, &transportErr) {
+		switch transportErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return storagev1alpha1.ImageScanErrorReasonAuthFailed
+		case http.StatusNotFound:
+			return storagev1alpha1.ImageScanErrorReasonNotFound
+		case http.StatusTooManyRequests:
+			return storagev1alpha1.ImageScanErrorReasonRateLimited
+		}
+	}
+
+	var (
+		unknownAuthorityErr   x509.UnknownAuthorityError
+		hostnameErr           x509.HostnameError
+		certificateInvalidErr x509.CertificateInvalidError
+		verificationErr       *tls.CertificateVerificationError
+		recordHeaderErr       tls.RecordHeaderError
+	)
+	if errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &certificateInvalidErr) ||
+		errors.As(err, &verificationErr) || errors.As(err, &recordHeaderErr) {
+		return storagev1alpha1.ImageScanErrorReasonTLSError
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return storagev1alpha1.ImageScanErrorReasonTimeout
+	}
+
+	message := err.Error()
+	for _, pattern := range scanErrorPatterns {
+		for _, substring := range pattern.patterns {
+			if strings.Contains(message, substring) {
+				return pattern.reason
+			}
+		}
+	}
+
+	return storagev1alpha1.ImageScanErrorReasonGeneratorError
+}
+
+// invalidManifestPatterns match the errors of Trivy reporting a manifest which cannot be parsed.
+var invalidManifestPatterns = []string{"MANIFEST_INVALID", "unsupported MediaType"}
+
+// terminalScanErrorReason returns the reason of the ScanJob failed by a scan error that retrying would not fix:
+// the image is not in the registry anymore, the registry rejects the credentials, or the manifest is invalid.
+// The transient errors, e.g. the network errors, the rate limiting and the server errors of the registry,
+// are not terminal and the scan is retried.
+func terminalScanErrorReason(err error, reason storagev1alpha1.ImageScanErrorReason) (string, bool) {
+	switch reason {
+	case storagev1alpha1.ImageScanErrorReasonNotFound:
+		return v1alpha1.ReasonImageNotFound, true
+	case storagev1alpha1.ImageScanErrorReasonAuthFailed:
+		return v1alpha1.ReasonRegistryAuthFailed, true
+	case storagev1alpha1.ImageScanErrorReasonGeneratorError:
+		if isInvalidManifestError(err) {
+			return v1alpha1.ReasonInvalidManifest, true
+		}
+	case storagev1alpha1.ImageScanErrorReasonRateLimited,
+		storagev1alpha1.ImageScanErrorReasonTLSError,
+		storagev1alpha1.ImageScanErrorReasonTimeout:
+	}
+
+	return "", false
+}
+
+// isInvalidManifestError returns true when the registry rejected the manifest of the image, or when it cannot be parsed.
+func isInvalidManifestError(err error) bool {
+	var transportErr *transport.Error
+	if errors.As(err, &transportErr) {
+		for _, diagnostic := range transportErr.Errors {
+			if diagnostic.Code == transport.ManifestInvalidErrorCode {
+				return true
+			}
+		}
+	}
+
+	message := err.Error()
+	for _, pattern := range invalidManifestPatterns {
+		if strings.Contains(message, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// truncateScanErrorMessage truncates the message to maxScanErrorMessageLength bytes.
+func truncateScanErrorMessage(message string) string {
+	if len(message) <= maxScanErrorMessageLength {
+		return message
+	}
+
+	return strings.ToValidUTF8(message[:maxScanErrorMessageLength-3], "") + "..."
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/stretchr/testify/assert"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+	registryclient "github.com/kubewarden/sbomscanner/internal/handlers/registry"
+)
+
+func TestClassifyScanError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedReason storagev1alpha1.ImageScanErrorReason
+	}{
+		{
+			name:           "scan timeout",
+			err:            fmt.Errorf("failed to generate SBOM: %w", context.DeadlineExceeded),
+			expectedReason: storagev1alpha1.ImageScanErrorReasonTimeout,
+		},
+		{
+			name:           "rejected credentials",
+			err:            fmt.Errorf("%w: denied", registryclient.ErrUnauthenticated),
+			expectedReason: storagev1alpha1.ImageScanErrorReasonAuthFailed,
+		},
+		{
+			name:           "unauthorized",
+			err:            fmt.Errorf("cannot pull image: %w", &transport.Error{StatusCode: http.StatusUnauthorized}),
+			expectedReason: storagev1alpha1.ImageScanErrorReasonAuthFailed,
+		},
+		{
+			name:           "image not found",
+			err:            &transport.Error{StatusCode: http.StatusNotFound},
+			expectedReason: storagev1alpha1.ImageScanErrorReasonNotFound,
+		},
+		{
+			name:           "rate limited",
+			err:            &transport.Error{StatusCode: http.StatusTooManyRequests},
+			expectedReason: storagev1alpha1.ImageScanErrorReasonRateLimited,
+		},
+		{
+			name:           "untrusted certificate",
+			err:            fmt.Errorf("cannot pull image: %w", x509.UnknownAuthorityError{}),
+			expectedReason: storagev1alpha1.ImageScanErrorReasonTLSError,
+		},
+		{
+			name:           "unwrapped Trivy error",
+			err:            errors.New("unable to inspect the image: remote error: GET https://registry.example.com/v2/: TOOMANYREQUESTS: rate limit exceeded"),
+			expectedReason: storagev1alpha1.ImageScanErrorReasonRateLimited,
+		},
+		{
+			name:           "unwrapped TLS error",
+			err:            errors.New(`Get "https://registry.example.com/v2/": tls: failed to verify certificate: x509: certificate signed by unknown authority`),
+			expectedReason: storagev1alpha1.ImageScanErrorReasonTLSError,
+		},
+		{
+			name:           "other error",
+			err:            errors.New("analyze error: unable to analyze the layer"),
+			expectedReason: storagev1alpha1.ImageScanErrorReasonGeneratorError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedReason, classifyScanError(test.err))
+		})
+	}
+}
+
+func TestTruncateScanErrorMessage(t *testing.T) {
+	assert.Equal(t, "short", truncateScanErrorMessage("short"))
+
+	truncated := truncateScanErrorMessage(strings.Repeat("é", maxScanErrorMessageLength))
+	assert.LessOrEqual(t, len(truncated), maxScanErrorMessageLength)
+	assert.True(t, strings.HasSuffix(truncated, "..."))
+}
+
+func TestTerminalScanErrorReason(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedReason string
+		expectTerminal bool
+	}{
+		{
+			name:           "image not found",
+			err:            &transport.Error{StatusCode: http.StatusNotFound},
+			expectedReason: v1alpha1.ReasonImageNotFound,
+			expectTerminal: true,
+		},
+		{
+			name:           "credentials rejected",
+			err:            &transport.Error{StatusCode: http.StatusUnauthorized},
+			expectedReason: v1alpha1.ReasonRegistryAuthFailed,
+			expectTerminal: true,
+		},
+		{
+			name: "invalid manifest",
+			err: &transport.Error{
+				StatusCode: http.StatusBadRequest,
+				Errors:     []transport.Diagnostic{{Code: transport.ManifestInvalidErrorCode}},
+			},
+			expectedReason: v1alpha1.ReasonInvalidManifest,
+			expectTerminal: true,
+		},
+		{
+			name:           "unsupported media type",
+			err:            errors.New(`unable to inspect the image: unsupported MediaType: "application/vnd.unknown"`),
+			expectedReason: v1alpha1.ReasonInvalidManifest,
+			expectTerminal: true,
+		},
+		{
+			name: "rate limited",
+			err:  &transport.Error{StatusCode: http.StatusTooManyRequests},
+		},
+		{
+			name: "registry server error",
+			err:  &transport.Error{StatusCode: http.StatusBadGateway},
+		},
+		{
+			name: "network error",
+			err:  errors.New("dial tcp 10.0.0.1:443: connect: connection refused"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reason, terminal := terminalScanErrorReason(test.err, classifyScanError(test.err))
+			assert.Equal(t, test.expectTerminal, terminal)
+			assert.Equal(t, test.expectedReason, reason)
+		})
+	}
+}
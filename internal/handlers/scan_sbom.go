@@ -8,13 +8,17 @@ import (
 	"log/slog"
 	"os"
 	"path"
+	"strings"
+	"time"
 
 	"go.yaml.in/yaml/v3"
 	_ "modernc.org/sqlite" // sqlite driver for RPM DB and Java DB
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 
 	vexrepo "github.com/aquasecurity/trivy/pkg/vex/repo"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -25,8 +29,11 @@ import (
 	"github.com/kubewarden/sbomscanner/api"
 	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
 	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+	"github.com/kubewarden/sbomscanner/internal/handlers/notification"
 	vulnReport "github.com/kubewarden/sbomscanner/internal/handlers/vulnerabilityreport"
 	"github.com/kubewarden/sbomscanner/internal/messaging"
+	"github.com/kubewarden/sbomscanner/internal/vulndb"
+	"github.com/kubewarden/sbomscanner/internal/writebuffer"
 )
 
 const (
@@ -34,6 +41,8 @@ const (
 	trivyVEXSubPath = ".trivy/vex"
 	// trivyVEXRepoFile is the file used by trivy to hold VEX repositories.
 	trivyVEXRepoFile = "repository.yaml"
+	// maxEventCVEs is the maximum number of CVEs listed in the message of an Event.
+	maxEventCVEs = 5
 )
 
 // ScanSBOMHandler is responsible for handling SBOM scan requests.
@@ -41,9 +50,41 @@ type ScanSBOMHandler struct {
 	k8sClient             client.Client
 	scheme                *runtime.Scheme
 	workDir               string
-	trivyDBRepository     string
+	vulnDB                *vulndb.DB
 	trivyJavaDBRepository string
-	logger                *slog.Logger
+	// cveAllowlistFile is the file listing the CVEs suppressed from all the reports, read by every scan.
+	// No CVE is suppressed when empty.
+	cveAllowlistFile string
+	// notifier sends the new vulnerabilities of the reports, nil when the notifications are disabled.
+	notifier *notification.Notifier
+	// writeBuffer buffers the writes of the VulnerabilityReports, nil writes them right away.
+	writeBuffer *writebuffer.Buffer
+	// minScanInterval is the minimum time between two scans of the same image digest, 0 scans every trigger.
+	minScanInterval time.Duration
+	// statusBatcher writes the last scan time of the images.
+	statusBatcher *ImageStatusBatcher
+	// recorder records the outcome of the scans on the images.
+	recorder record.EventRecorder
+	// workerID identifies the worker in the annotations of the VulnerabilityReports it produces.
+	workerID string
+	logger   *slog.Logger
+}
+
+// ScanSBOMHandlerOptions configures the optional behaviors and collaborators of ScanSBOMHandler.
+// The zero value is valid.
+type ScanSBOMHandlerOptions struct {
+	// CVEAllowlistFile is the file listing the CVEs suppressed from all the reports, empty suppresses none.
+	CVEAllowlistFile string
+	// Notifier sends the new vulnerabilities of the reports, nil disables the notifications.
+	Notifier *notification.Notifier
+	// WriteBuffer buffers the writes of the VulnerabilityReports, nil writes them right away.
+	WriteBuffer *writebuffer.Buffer
+	// MinScanInterval is the minimum time between two scans of the same image digest, 0 scans every trigger.
+	MinScanInterval time.Duration
+	// Recorder records the outcome of the scans on the images, nil discards the events.
+	Recorder record.EventRecorder
+	// WorkerID identifies the worker in the annotations of the VulnerabilityReports it produces.
+	WorkerID string
 }
 
 // NewScanSBOMHandler creates a new instance of ScanSBOMHandler.
@@ -51,16 +92,30 @@ func NewScanSBOMHandler(
 	k8sClient client.Client,
 	scheme *runtime.Scheme,
 	workDir string,
-	trivyDBRepository string,
+	vulnDB *vulndb.DB,
 	trivyJavaDBRepository string,
+	statusBatcher *ImageStatusBatcher,
+	opts ScanSBOMHandlerOptions,
 	logger *slog.Logger,
 ) *ScanSBOMHandler {
+	if opts.Recorder == nil {
+		// The fake recorder discards the events when it has no channel.
+		opts.Recorder = &record.FakeRecorder{}
+	}
+
 	return &ScanSBOMHandler{
 		k8sClient:             k8sClient,
 		scheme:                scheme,
 		workDir:               workDir,
-		trivyDBRepository:     trivyDBRepository,
+		vulnDB:                vulnDB,
 		trivyJavaDBRepository: trivyJavaDBRepository,
+		cveAllowlistFile:      opts.CVEAllowlistFile,
+		notifier:              opts.Notifier,
+		writeBuffer:           opts.WriteBuffer,
+		minScanInterval:       opts.MinScanInterval,
+		statusBatcher:         statusBatcher,
+		recorder:              opts.Recorder,
+		workerID:              opts.WorkerID,
 		logger:                logger.With("handler", "scan_sbom_handler"),
 	}
 }
@@ -75,6 +130,7 @@ func (h *ScanSBOMHandler) Handle(ctx context.Context, message messaging.Message)
 	h.logger.InfoContext(ctx, "SBOM scan requested",
 		"sbom", scanSBOMMessage.SBOM.Name,
 		"namespace", scanSBOMMessage.SBOM.Namespace,
+		"reevaluation", scanSBOMMessage.Reevaluation,
 	)
 
 	scanJob := &v1alpha1.ScanJob{}
@@ -117,6 +173,14 @@ func (h *ScanSBOMHandler) Handle(ctx context.Context, message messaging.Message)
 		return fmt.Errorf("failed to get SBOM: %w", err)
 	}
 
+	coalesced, err := h.coalesceRecentScan(ctx, scanSBOMMessage, scanJob, sbom)
+	if err != nil {
+		return err
+	}
+	if coalesced {
+		return nil
+	}
+
 	vexHubList := &v1alpha1.VEXHubList{}
 	err = h.k8sClient.List(ctx, vexHubList, &client.ListOptions{})
 	if err != nil {
@@ -159,12 +223,11 @@ func (h *ScanSBOMHandler) Handle(ctx context.Context, message messaging.Message)
 		"sbom",
 		"--skip-version-check",
 		"--disable-telemetry",
-		"--cache-dir", h.workDir,
 		"--format", "json",
-		"--db-repository", h.trivyDBRepository,
 		"--java-db-repository", h.trivyJavaDBRepository,
 		"--output", reportFile.Name(),
 	}
+	trivyArgs = append(trivyArgs, h.vulnDB.TrivyArgs()...)
 	// Set XDG_DATA_HOME environment variable to /tmp because trivy expects
 	// the repository file in that location and there is no way to change it
 	// through input flags:
@@ -201,13 +264,18 @@ func (h *ScanSBOMHandler) Handle(ctx context.Context, message messaging.Message)
 		trivyArgs = append(trivyArgs, "--vex", "repo", "--show-suppressed")
 	}
 
-	app := trivyCommands.NewApp()
 	// add SBOM file name at the end.
 	trivyArgs = append(trivyArgs, sbomFile.Name())
-	app.SetArgs(trivyArgs)
 
-	if err = app.ExecuteContext(ctx); err != nil {
-		return fmt.Errorf("failed to execute trivy: %w", err)
+	if !scanSBOMMessage.VulnerabilityDBNotBefore.IsZero() {
+		if err = h.vulnDB.RefreshIfDownloadedBefore(ctx, scanSBOMMessage.VulnerabilityDBNotBefore); err != nil {
+			return fmt.Errorf("failed to refresh the vulnerability database: %w", err)
+		}
+	}
+
+	vulnerabilityDB, err := h.scan(ctx, trivyArgs)
+	if err != nil {
+		return err
 	}
 
 	h.logger.InfoContext(ctx, "SBOM scanned",
@@ -234,39 +302,260 @@ func (h *ScanSBOMHandler) Handle(ctx context.Context, message messaging.Message)
 	if err != nil {
 		return fmt.Errorf("failed to convert from trivy results: %w", err)
 	}
+	if h.cveAllowlistFile != "" {
+		// The allowlist is read by every scan, so that the updates of its ConfigMap apply without a restart.
+		var allowlist vulnReport.Allowlist
+		allowlist, err = vulnReport.LoadAllowlist(h.cveAllowlistFile)
+		if err != nil {
+			return err
+		}
+		allowlist.Apply(results, time.Now())
+	}
 	summary := vulnReport.ComputeSummary(results)
 
+	// The SPDX document is not needed to write the report, and would make the buffered write as large as the SBOM.
+	scannedSBOM := sbom.DeepCopy()
+	scannedSBOM.SPDX = runtime.RawExtension{}
+	scannedSBOM.ManagedFields = nil
+	write := &vulnerabilityReportWrite{
+		SBOM:       scannedSBOM,
+		ScanJobUID: string(scanJob.UID),
+		Report: storagev1alpha1.Report{
+			Summary:         summary,
+			Results:         results,
+			VulnerabilityDB: vulnerabilityDB,
+			Evaluation: &storagev1alpha1.Evaluation{
+				Reevaluation:          scanSBOMMessage.Reevaluation,
+				SBOMUID:               string(sbom.UID),
+				SBOMCreationTimestamp: sbom.CreationTimestamp,
+			},
+		},
+	}
+	if h.writeBuffer != nil {
+		// The report is written in the background by the write buffer.
+		if err = h.writeBuffer.Enqueue(ctx, WriteKindVulnerabilityReport, write); err != nil {
+			return fmt.Errorf("failed to buffer vulnerability report: %w", err)
+		}
+		return nil
+	}
+
+	return h.writeVulnerabilityReport(ctx, write)
+}
+
+// WriteVulnerabilityReport writes the VulnerabilityReport buffered by Handle, notifies its new vulnerabilities
+// and records the scan on the image. It is the writebuffer.WriteFunc of the VulnerabilityReports.
+func (h *ScanSBOMHandler) WriteVulnerabilityReport(ctx context.Context, data []byte) error {
+	write := &vulnerabilityReportWrite{}
+	if err := json.Unmarshal(data, write); err != nil {
+		return &writebuffer.PermanentError{Err: fmt.Errorf("failed to unmarshal vulnerability report write: %w", err)}
+	}
+
+	return permanentWriteError(h.writeVulnerabilityReport(ctx, write))
+}
+
+// coalesceRecentScan skips the scan of the SBOM when its image was scanned less than the minimum scan interval ago.
+// The image has the digest of the SBOM, and its last scan time is recorded once the report of the scan is written,
+// so that it is shared by the workers and survives their restarts. The existing VulnerabilityReport of the SBOM
+// is then counted by the ScanJob, instead of being produced again. It reports whether the scan was skipped.
+func (h *ScanSBOMHandler) coalesceRecentScan(
+	ctx context.Context,
+	scanSBOMMessage *ScanSBOMMessage,
+	scanJob *v1alpha1.ScanJob,
+	sbom *storagev1alpha1.SBOM,
+) (bool, error) {
+	if h.minScanInterval <= 0 {
+		return false, nil
+	}
+
+	image := &storagev1alpha1.Image{}
+	if err := h.k8sClient.Get(ctx, client.ObjectKeyFromObject(sbom), image); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get image: %w", err)
+	}
+	lastScan := image.Status.LastScannedAt
+	if lastScan == nil || time.Since(lastScan.Time) >= h.minScanInterval {
+		return false, nil
+	}
+	// The scans requiring a vulnerability database newer than the last scan are never skipped.
+	if lastScan.Time.Before(scanSBOMMessage.VulnerabilityDBNotBefore) {
+		return false, nil
+	}
+
+	vulnerabilityReport := &storagev1alpha1.VulnerabilityReport{}
+	if err := h.k8sClient.Get(ctx, client.ObjectKeyFromObject(sbom), vulnerabilityReport); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get vulnerability report: %w", err)
+	}
+	// The report of a previous SBOM of the image does not describe the current one.
+	if vulnerabilityReport.Report.Evaluation == nil || vulnerabilityReport.Report.Evaluation.SBOMUID != string(sbom.UID) {
+		return false, nil
+	}
+
+	if vulnerabilityReport.Labels[v1alpha1.LabelScanJobUIDKey] != string(scanJob.UID) {
+		patch := client.MergeFrom(vulnerabilityReport.DeepCopy())
+		metav1.SetMetaDataLabel(&vulnerabilityReport.ObjectMeta, v1alpha1.LabelScanJobUIDKey, string(scanJob.UID))
+		if err := h.k8sClient.Patch(ctx, vulnerabilityReport, patch); err != nil {
+			return false, fmt.Errorf("failed to update the ScanJob of vulnerability report %s/%s: %w",
+				vulnerabilityReport.Namespace, vulnerabilityReport.Name, err)
+		}
+	}
+
+	h.logger.InfoContext(ctx, "SBOM scan skipped, image digest scanned less than the minimum scan interval ago",
+		"sbom", sbom.Name,
+		"namespace", sbom.Namespace,
+		"digest", sbom.GetImageMetadata().Digest,
+		"lastScannedAt", lastScan.Time,
+		"minScanInterval", h.minScanInterval,
+		"scanjob", scanJob.Name,
+	)
+
+	return true, nil
+}
+
+// writeVulnerabilityReport creates or updates the VulnerabilityReport of the SBOM, notifies its new vulnerabilities,
+// and records the scan on the image.
+func (h *ScanSBOMHandler) writeVulnerabilityReport(ctx context.Context, write *vulnerabilityReportWrite) error {
+	sbom := write.SBOM
+	results := write.Report.Results
+
 	vulnerabilityReport := &storagev1alpha1.VulnerabilityReport{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      sbom.Name,
 			Namespace: sbom.Namespace,
 		},
 	}
-	if err = controllerutil.SetControllerReference(sbom, vulnerabilityReport, h.scheme); err != nil {
+	if err := controllerutil.SetControllerReference(sbom, vulnerabilityReport, h.scheme); err != nil {
 		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	_, err = controllerutil.CreateOrUpdate(ctx, h.k8sClient, vulnerabilityReport, func() error {
+	// The results of the existing report are kept, so that only the new vulnerabilities are notified.
+	var (
+		previousResults     []storagev1alpha1.Result
+		previousNotRetained bool
+	)
+	_, err := controllerutil.CreateOrUpdate(ctx, h.k8sClient, vulnerabilityReport, func() error {
+		previousResults = vulnerabilityReport.Report.Results
+		previousNotRetained = vulnerabilityReport.Annotations[storagev1alpha1.AnnotationRawDocumentKey] == storagev1alpha1.RawDocumentNotRetained
+		// The results are replaced, so the findings of the report are written again.
+		delete(vulnerabilityReport.Annotations, storagev1alpha1.AnnotationRawDocumentKey)
 		vulnerabilityReport.Labels = map[string]string{
-			v1alpha1.LabelScanJobUIDKey: string(scanJob.UID),
+			v1alpha1.LabelScanJobUIDKey: write.ScanJobUID,
 			api.LabelManagedByKey:       api.LabelManagedByValue,
 			api.LabelPartOfKey:          api.LabelPartOfValue,
 		}
+		setGeneratedBy(&vulnerabilityReport.ObjectMeta, h.workerID, time.Now())
 
 		vulnerabilityReport.ImageMetadata = sbom.GetImageMetadata()
-		vulnerabilityReport.Report = storagev1alpha1.Report{
-			Summary: summary,
-			Results: results,
-		}
+		vulnerabilityReport.Report = write.Report
 		return nil
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create or update vulnerability report: %w", err)
 	}
+	if previousNotRetained {
+		// The storage did not retain the results of the existing report, so the new vulnerabilities
+		// cannot be told apart: none is notified, rather than notifying all of them again.
+		h.logger.WarnContext(ctx, "Results of the previous VulnerabilityReport not retained by the storage, not notifying the new vulnerabilities",
+			"vulnerabilityReport", vulnerabilityReport.Name,
+			"namespace", vulnerabilityReport.Namespace,
+		)
+		previousResults = results
+	}
+
+	if h.notifier != nil {
+		// The report is already written, so a failed notification does not fail the scan.
+		if err = h.notifier.NotifyNewVulnerabilities(ctx, vulnerabilityReport, previousResults); err != nil {
+			h.logger.ErrorContext(ctx, "Failed to notify the new vulnerabilities",
+				"vulnerabilityReport", vulnerabilityReport.Name,
+				"namespace", vulnerabilityReport.Namespace,
+				"error", err)
+		}
+	}
+
+	image, err := h.updateImageLastScannedAt(ctx, sbom)
+	if err != nil {
+		return err
+	}
+	if image != nil {
+		h.recordScanEvents(image, vulnerabilityReport, previousResults)
+	}
 
 	return nil
 }
 
+// recordScanEvents records the summary of the report on the image,
+// and the critical vulnerabilities which were not in the previous results of the report.
+func (h *ScanSBOMHandler) recordScanEvents(
+	image *storagev1alpha1.Image,
+	report *storagev1alpha1.VulnerabilityReport,
+	previousResults []storagev1alpha1.Result,
+) {
+	summary := report.Report.Summary
+	h.recorder.Eventf(image, corev1.EventTypeNormal, v1alpha1.EventReasonImageScanned,
+		"VulnerabilityReport %s updated: %d critical, %d high, %d medium, %d low, %d unknown vulnerabilities",
+		report.Name, summary.Critical, summary.High, summary.Medium, summary.Low, summary.Unknown)
+
+	newCriticals := notification.NewVulnerabilities(report.Report.Results, previousResults, "CRITICAL")
+	if len(newCriticals) == 0 {
+		return
+	}
+	cves := make([]string, 0, maxEventCVEs)
+	for _, vulnerability := range newCriticals {
+		if len(cves) == maxEventCVEs {
+			cves = append(cves, "...")
+			break
+		}
+		cves = append(cves, vulnerability.CVE)
+	}
+	h.recorder.Eventf(image, corev1.EventTypeWarning, v1alpha1.EventReasonNewCriticalVulnerabilities,
+		"%d new critical vulnerabilities found: %s", len(newCriticals), strings.Join(cves, ", "))
+}
+
+// scan runs the trivy scan and returns the vulnerability database it used.
+// The database is not refreshed by the worker during the scan.
+func (h *ScanSBOMHandler) scan(ctx context.Context, trivyArgs []string) (*storagev1alpha1.VulnerabilityDB, error) {
+	h.vulnDB.RLock()
+	defer h.vulnDB.RUnlock()
+
+	app := trivyCommands.NewApp()
+	app.SetArgs(trivyArgs)
+	if err := app.ExecuteContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to execute trivy: %w", err)
+	}
+
+	vulnerabilityDB, err := h.vulnDB.Info()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the vulnerability database info: %w", err)
+	}
+
+	return vulnerabilityDB, nil
+}
+
+// updateImageLastScannedAt records the time of the scan on the image of the SBOM, which has the same name,
+// clears the error of its previous failed scan, and returns the image. The image might have been deleted during the scan, in which case nil is returned.
+// The status is written by the statusBatcher, together with the statuses of the images scanned meanwhile.
+func (h *ScanSBOMHandler) updateImageLastScannedAt(ctx context.Context, sbom *storagev1alpha1.SBOM) (*storagev1alpha1.Image, error) {
+	image := &storagev1alpha1.Image{}
+	if err := h.k8sClient.Get(ctx, client.ObjectKeyFromObject(sbom), image); err != nil {
+		if apierrors.IsNotFound(err) {
+			h.logger.InfoContext(ctx, "Image not found, skipping the update of the last scan time", "image", sbom.Name, "namespace", sbom.Namespace)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get image: %w", err)
+	}
+
+	now := metav1.Now()
+	image.Status.LastScannedAt = &now
+	image.Status.LastError = nil
+	h.statusBatcher.Queue(image)
+
+	return image, nil
+}
+
 // setupVEXHubRepositories creates all the necessary files and directories
 // to use VEX Hub repositories.
 func (h *ScanSBOMHandler) setupVEXHubRepositories(vexHubList *v1alpha1.VEXHubList, trivyVEXPath, vexRepoPath string) error {
@@ -10,9 +10,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
 	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+	"github.com/kubewarden/sbomscanner/internal/vulndb"
 	"github.com/kubewarden/sbomscanner/pkg/generated/clientset/versioned/scheme"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -20,6 +22,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	_ "modernc.org/sqlite"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -141,6 +144,12 @@ func testScanSBOM(t *testing.T, cacheDir, platform, sourceSBOMJSON, expectedRepo
 		},
 		SPDX: runtime.RawExtension{Raw: spdxData},
 	}
+	image := &storagev1alpha1.Image{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sbom.Name,
+			Namespace: sbom.Namespace,
+		},
+	}
 	vexHubs := &v1alpha1.VEXHubList{
 		Items: vexHubList,
 	}
@@ -154,7 +163,9 @@ func testScanSBOM(t *testing.T, cacheDir, platform, sourceSBOMJSON, expectedRepo
 		WithScheme(scheme).
 		WithRuntimeObjects(scanJob).
 		WithRuntimeObjects(sbom).
+		WithRuntimeObjects(image).
 		WithRuntimeObjects(vexHubs).
+		WithInterceptorFuncs(imageStatusBatchInterceptor()).
 		Build()
 
 	reportData, err := os.ReadFile(expectedReportJSON)
@@ -164,7 +175,10 @@ func testScanSBOM(t *testing.T, cacheDir, platform, sourceSBOMJSON, expectedRepo
 	err = json.Unmarshal(reportData, expectedReport)
 	require.NoError(t, err, "failed to unmarshal expected report file %s", expectedReportJSON)
 
-	handler := NewScanSBOMHandler(k8sClient, scheme, cacheDir, testTrivyDBRepository, testTrivyJavaDBRepository, slog.Default())
+	vulnDB, err := vulndb.New(vulndb.Options{Repository: testTrivyDBRepository, CacheDir: cacheDir}, slog.Default())
+	require.NoError(t, err)
+	statusBatcher := NewImageStatusBatcher(k8sClient, slog.Default())
+	handler := NewScanSBOMHandler(k8sClient, scheme, cacheDir, vulnDB, testTrivyJavaDBRepository, statusBatcher, ScanSBOMHandlerOptions{Recorder: record.NewFakeRecorder(10), WorkerID: testWorkerID}, slog.Default())
 
 	message, err := json.Marshal(&ScanSBOMMessage{
 		BaseMessage: BaseMessage{
@@ -194,6 +208,8 @@ func testScanSBOM(t *testing.T, cacheDir, platform, sourceSBOMJSON, expectedRepo
 	assert.Equal(t, sbom.GetImageMetadata(), vulnerabilityReport.GetImageMetadata())
 	assert.Equal(t, sbom.UID, vulnerabilityReport.GetOwnerReferences()[0].UID)
 	assert.Equal(t, string(scanJob.UID), vulnerabilityReport.Labels[v1alpha1.LabelScanJobUIDKey])
+	assert.Equal(t, testWorkerID, vulnerabilityReport.Annotations[storagev1alpha1.AnnotationGeneratedByKey])
+	assert.NotEmpty(t, vulnerabilityReport.Annotations[storagev1alpha1.AnnotationGeneratedAtKey])
 
 	report := &vulnerabilityReport.Report
 	require.NotEmpty(t, report)
@@ -201,7 +217,21 @@ func testScanSBOM(t *testing.T, cacheDir, platform, sourceSBOMJSON, expectedRepo
 	// override report field since trivy uses the sbom name as Target,
 	// which changes at every test run.
 	report.Results[0].Target = expectedReport.Results[0].Target
+	// the vulnerability database is downloaded by the test, its version changes over time.
+	require.NotNil(t, report.VulnerabilityDB)
+	assert.Equal(t, testTrivyDBRepository, report.VulnerabilityDB.Source)
+	assert.False(t, report.VulnerabilityDB.UpdatedAt.IsZero())
+	report.VulnerabilityDB = nil
+	require.NotNil(t, report.Evaluation)
+	assert.False(t, report.Evaluation.Reevaluation)
+	assert.Equal(t, string(sbom.UID), report.Evaluation.SBOMUID)
+	report.Evaluation = nil
 	assert.Equal(t, expectedReport, report)
+
+	statusBatcher.flush(t.Context())
+	err = k8sClient.Get(t.Context(), client.ObjectKeyFromObject(image), image)
+	require.NoError(t, err)
+	assert.NotNil(t, image.Status.LastScannedAt, "the last scan time of the image must be recorded")
 }
 
 func fakeVEXHubRepository(t *testing.T) *httptest.Server {
@@ -286,7 +316,9 @@ func TestScanSBOMHandler_Handle_StopProcessing(t *testing.T) {
 				Build()
 
 			cacheDir := t.TempDir()
-			handler := NewScanSBOMHandler(k8sClient, scheme, cacheDir, testTrivyDBRepository, testTrivyJavaDBRepository, slog.Default())
+			vulnDB, err := vulndb.New(vulndb.Options{Repository: testTrivyDBRepository, CacheDir: cacheDir}, slog.Default())
+			require.NoError(t, err)
+			handler := NewScanSBOMHandler(k8sClient, scheme, cacheDir, vulnDB, testTrivyJavaDBRepository, NewImageStatusBatcher(k8sClient, slog.Default()), ScanSBOMHandlerOptions{Recorder: record.NewFakeRecorder(10), WorkerID: testWorkerID}, slog.Default())
 
 			message, err := json.Marshal(&ScanSBOMMessage{
 				BaseMessage: BaseMessage{
@@ -317,3 +349,174 @@ func TestScanSBOMHandler_Handle_StopProcessing(t *testing.T) {
 		})
 	}
 }
+
+func TestScanSBOMHandler_RecordScanEvents(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	handler := NewScanSBOMHandler(nil, nil, "", nil, testTrivyJavaDBRepository, nil, ScanSBOMHandlerOptions{Recorder: recorder}, slog.Default())
+
+	image := &storagev1alpha1.Image{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-image", Namespace: "default"},
+	}
+	report := &storagev1alpha1.VulnerabilityReport{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-image", Namespace: "default"},
+		Report: storagev1alpha1.Report{
+			Summary: storagev1alpha1.Summary{Critical: 2, High: 1},
+			Results: []storagev1alpha1.Result{
+				{
+					Target: "alpine:3.20",
+					Vulnerabilities: []storagev1alpha1.Vulnerability{
+						{CVE: "CVE-2024-0001", Severity: "CRITICAL", PURL: "pkg:apk/alpine/openssl@3.3.0"},
+						{CVE: "CVE-2024-0002", Severity: "CRITICAL", PURL: "pkg:apk/alpine/curl@8.0.0"},
+						{CVE: "CVE-2024-0003", Severity: "HIGH", PURL: "pkg:apk/alpine/curl@8.0.0"},
+					},
+				},
+			},
+		},
+	}
+	previousResults := []storagev1alpha1.Result{
+		{
+			Target: "alpine:3.20",
+			Vulnerabilities: []storagev1alpha1.Vulnerability{
+				{CVE: "CVE-2024-0002", Severity: "CRITICAL", PURL: "pkg:apk/alpine/curl@8.0.0"},
+			},
+		},
+	}
+
+	handler.recordScanEvents(image, report, previousResults)
+
+	require.Len(t, recorder.Events, 2)
+	assert.Equal(t, "Normal ImageScanned VulnerabilityReport test-image updated: 2 critical, 1 high, 0 medium, 0 low, 0 unknown vulnerabilities", <-recorder.Events)
+	assert.Equal(t, "Warning NewCriticalVulnerabilities 1 new critical vulnerabilities found: CVE-2024-0001", <-recorder.Events)
+
+	// Rescanning the same report records no new critical vulnerabilities.
+	handler.recordScanEvents(image, report, report.Report.Results)
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Normal ImageScanned VulnerabilityReport test-image updated: 2 critical, 1 high, 0 medium, 0 low, 0 unknown vulnerabilities", <-recorder.Events)
+}
+
+func TestScanSBOMHandler_HandleCoalescesRecentScan(t *testing.T) {
+	scanJob := &v1alpha1.ScanJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scanjob",
+			Namespace: "default",
+			UID:       "test-scanjob-uid",
+		},
+	}
+	sbom := &storagev1alpha1.SBOM{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-sbom",
+			Namespace: "default",
+			UID:       "test-sbom-uid",
+		},
+		ImageMetadata: storagev1alpha1.ImageMetadata{
+			Digest: "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		},
+	}
+
+	for _, test := range []struct {
+		name                     string
+		lastScanAgo              time.Duration
+		vulnerabilityDBNotBefore time.Duration
+		reportSBOMUID            string
+		expectedCoalesced        bool
+	}{
+		{
+			name:              "image scanned within the interval",
+			lastScanAgo:       time.Minute,
+			reportSBOMUID:     string(sbom.UID),
+			expectedCoalesced: true,
+		},
+		{
+			name:              "image never scanned",
+			reportSBOMUID:     string(sbom.UID),
+			expectedCoalesced: false,
+		},
+		{
+			name:              "image scanned before the interval",
+			lastScanAgo:       2 * time.Hour,
+			reportSBOMUID:     string(sbom.UID),
+			expectedCoalesced: false,
+		},
+		{
+			name:                     "newer vulnerability database required",
+			lastScanAgo:              time.Minute,
+			vulnerabilityDBNotBefore: 30 * time.Second,
+			reportSBOMUID:            string(sbom.UID),
+			expectedCoalesced:        false,
+		},
+		{
+			name:              "report of another SBOM",
+			lastScanAgo:       time.Minute,
+			reportSBOMUID:     "previous-sbom-uid",
+			expectedCoalesced: false,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			now := time.Now()
+			image := &storagev1alpha1.Image{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      sbom.Name,
+					Namespace: sbom.Namespace,
+				},
+			}
+			if test.lastScanAgo != 0 {
+				image.Status.LastScannedAt = &metav1.Time{Time: now.Add(-test.lastScanAgo)}
+			}
+			vulnerabilityReport := &storagev1alpha1.VulnerabilityReport{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      sbom.Name,
+					Namespace: sbom.Namespace,
+					Labels:    map[string]string{v1alpha1.LabelScanJobUIDKey: "previous-scanjob-uid"},
+				},
+				Report: storagev1alpha1.Report{
+					Evaluation: &storagev1alpha1.Evaluation{SBOMUID: test.reportSBOMUID},
+				},
+			}
+
+			scheme := scheme.Scheme
+			require.NoError(t, storagev1alpha1.AddToScheme(scheme))
+			require.NoError(t, v1alpha1.AddToScheme(scheme))
+			k8sClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithRuntimeObjects(scanJob, sbom, image, vulnerabilityReport).
+				Build()
+
+			handler := NewScanSBOMHandler(k8sClient, scheme, "", nil, testTrivyJavaDBRepository, NewImageStatusBatcher(k8sClient, slog.Default()), ScanSBOMHandlerOptions{MinScanInterval: time.Hour, Recorder: record.NewFakeRecorder(10), WorkerID: testWorkerID}, slog.Default())
+
+			scanSBOMMessage := &ScanSBOMMessage{
+				BaseMessage: BaseMessage{
+					ScanJob: ObjectRef{
+						Name:      scanJob.Name,
+						Namespace: scanJob.Namespace,
+						UID:       string(scanJob.UID),
+					},
+				},
+				SBOM: ObjectRef{
+					Name:      sbom.Name,
+					Namespace: sbom.Namespace,
+				},
+			}
+			if test.vulnerabilityDBNotBefore != 0 {
+				scanSBOMMessage.VulnerabilityDBNotBefore = now.Add(-test.vulnerabilityDBNotBefore)
+			}
+			if test.expectedCoalesced {
+				// The coalesced scans return before the vulnerability database is used.
+				message, err := json.Marshal(scanSBOMMessage)
+				require.NoError(t, err)
+				require.NoError(t, handler.Handle(context.Background(), &testMessage{data: message}))
+			} else {
+				coalesced, err := handler.coalesceRecentScan(context.Background(), scanSBOMMessage, scanJob, sbom)
+				require.NoError(t, err)
+				assert.False(t, coalesced)
+			}
+
+			updatedReport := &storagev1alpha1.VulnerabilityReport{}
+			require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(vulnerabilityReport), updatedReport))
+			expectedScanJobUID := "previous-scanjob-uid"
+			if test.expectedCoalesced {
+				expectedScanJobUID = string(scanJob.UID)
+			}
+			assert.Equal(t, expectedScanJobUID, updatedReport.Labels[v1alpha1.LabelScanJobUIDKey])
+		})
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"log/slog"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -17,16 +20,20 @@ import (
 // ScanJobFailureHandler handles failures for messages related to scan jobs.
 type ScanJobFailureHandler struct {
 	k8sClient client.Client
-	logger    *slog.Logger
+	// recorder records the failures on the registries of the scan jobs.
+	recorder record.EventRecorder
+	logger   *slog.Logger
 }
 
 // NewScanJobFailureHandler creates a new instance of ScanJobFailureHandler.
 func NewScanJobFailureHandler(
 	k8sClient client.Client,
+	recorder record.EventRecorder,
 	logger *slog.Logger,
 ) *ScanJobFailureHandler {
 	return &ScanJobFailureHandler{
 		k8sClient: k8sClient,
+		recorder:  recorder,
 		logger:    logger.With("handler", "scanjob_failure_handler"),
 	}
 }
@@ -65,6 +72,7 @@ func (h *ScanJobFailureHandler) HandleFailure(ctx context.Context, message messa
 		}
 		return fmt.Errorf("failed to update ScanJob %s/%s status to failed: %w", scanJob.Namespace, scanJob.Name, err)
 	}
+	h.recordFailure(scanJob, errorMessage)
 
 	h.logger.DebugContext(ctx, "ScanJob marked as failed",
 		"scanjob", scanJob.Name,
@@ -73,3 +81,15 @@ func (h *ScanJobFailureHandler) HandleFailure(ctx context.Context, message messa
 	)
 	return nil
 }
+
+// recordFailure records the failure on the Registry of the ScanJob,
+// or on the ScanJob when it failed before the Registry was stored in its annotation.
+func (h *ScanJobFailureHandler) recordFailure(scanJob *sbombasticv1alpha1.ScanJob, errorMessage string) {
+	var object runtime.Object = scanJob
+	if registry, err := scanJob.GetRegistryFromAnnotation(); err == nil {
+		object = registry
+	}
+
+	h.recorder.Eventf(object, corev1.EventTypeWarning, sbombasticv1alpha1.EventReasonScanFailed,
+		"ScanJob %s failed: %s", scanJob.Name, errorMessage)
+}
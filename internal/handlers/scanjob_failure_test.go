@@ -11,6 +11,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	sbombasticv1alpha1 "github.com/kubewarden/sbomscanner/api/v1alpha1"
@@ -38,7 +39,8 @@ func TestScanJobFailureHandler_HandleFailure(t *testing.T) {
 		WithStatusSubresource(scanJob).
 		Build()
 
-	handler := NewScanJobFailureHandler(k8sClient, slog.Default())
+	recorder := record.NewFakeRecorder(10)
+	handler := NewScanJobFailureHandler(k8sClient, recorder, slog.Default())
 
 	message, err := json.Marshal(&GenerateSBOMMessage{
 		BaseMessage: BaseMessage{
@@ -70,4 +72,7 @@ func TestScanJobFailureHandler_HandleFailure(t *testing.T) {
 	require.NotNil(t, failedCondition)
 	assert.Equal(t, sbombasticv1alpha1.ReasonInternalError, failedCondition.Reason)
 	assert.Equal(t, errorMessage, failedCondition.Message)
+
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Warning ScanFailed ScanJob test-scanjob failed: SBOM generation failed", <-recorder.Events)
 }
@@ -15,6 +15,7 @@ import (
 const (
 	testTrivyDBRepository     = "ghcr.io/kubewarden/sbomscanner/test-assets/trivy-db:2"
 	testTrivyJavaDBRepository = "ghcr.io/kubewarden/sbomscanner/test-assets/trivy-java-db:2"
+	testWorkerID              = "sbomscanner-worker-0"
 )
 
 const (
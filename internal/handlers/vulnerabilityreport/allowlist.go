@@ -0,0 +1,146 @@
+package vulnerabilityreport
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+const (
+	// AllowlistRepository is the repository of the VEX status of the vulnerabilities suppressed by the CVE allowlist.
+	AllowlistRepository = "cve-allowlist"
+	// AllowlistStatus is the status of the VEX status of the vulnerabilities suppressed by the CVE allowlist.
+	AllowlistStatus = "allowlisted"
+)
+
+// severities are the severities of the vulnerabilities, from the lowest to the highest.
+var severities = []string{"UNKNOWN", "LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+// AllowedCVE is an entry of the CVE allowlist.
+type AllowedCVE struct {
+	// CVE identifier, e.g. CVE-2024-0001.
+	CVE string `yaml:"cve"`
+	// Reason of the suppression, recorded in the statement of the suppressed vulnerabilities.
+	Reason string `yaml:"reason"`
+	// Expires is the date, e.g. 2025-06-30, or the RFC 3339 time when the suppression lapses.
+	// The CVE is suppressed without time limit when empty.
+	Expires string `yaml:"expires,omitempty"`
+	// MaxSeverity is the highest severity of the suppressed vulnerabilities, e.g. MEDIUM,
+	// so that the CVE reappears when its severity is raised. All the severities are suppressed when empty.
+	MaxSeverity string `yaml:"maxSeverity,omitempty"`
+}
+
+// AllowlistRule is the suppression of an allowed CVE.
+type AllowlistRule struct {
+	Reason string
+	// ExpiresAt is the time when the suppression lapses, nil when it does not expire.
+	ExpiresAt *time.Time
+	// MaxSeverity is the highest severity of the suppressed vulnerabilities, empty for all the severities.
+	MaxSeverity string
+}
+
+// Allowlist maps the allowed CVE identifiers to their suppression.
+type Allowlist map[string]AllowlistRule
+
+// LoadAllowlist reads the CVE allowlist from a YAML file holding a list of AllowedCVE.
+func LoadAllowlist(path string) (Allowlist, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CVE allowlist: %w", err)
+	}
+
+	var entries []AllowedCVE
+	if err = yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse the CVE allowlist %s: %w", path, err)
+	}
+
+	allowlist := make(Allowlist, len(entries))
+	for i, entry := range entries {
+		cve := strings.ToUpper(strings.TrimSpace(entry.CVE))
+		if cve == "" {
+			return nil, fmt.Errorf("invalid CVE allowlist %s: entry %d has no cve", path, i)
+		}
+		if strings.TrimSpace(entry.Reason) == "" {
+			return nil, fmt.Errorf("invalid CVE allowlist %s: %s has no reason", path, cve)
+		}
+
+		rule := AllowlistRule{Reason: entry.Reason}
+		if entry.Expires != "" {
+			var expiresAt time.Time
+			expiresAt, err = parseExpiry(entry.Expires)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CVE allowlist %s: %s: %w", path, cve, err)
+			}
+			rule.ExpiresAt = &expiresAt
+		}
+		if entry.MaxSeverity != "" {
+			rule.MaxSeverity = strings.ToUpper(entry.MaxSeverity)
+			if !slices.Contains(severities, rule.MaxSeverity) {
+				return nil, fmt.Errorf("invalid CVE allowlist %s: %s: invalid maxSeverity %q, must be one of %s",
+					path, cve, entry.MaxSeverity, strings.Join(severities, ", "))
+			}
+		}
+		allowlist[cve] = rule
+	}
+
+	return allowlist, nil
+}
+
+// parseExpiry parses a date, which expires at its start in UTC, or an RFC 3339 time.
+func parseExpiry(value string) (time.Time, error) {
+	if expiresAt, err := time.Parse(time.DateOnly, value); err == nil {
+		return expiresAt, nil
+	}
+	expiresAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expires %q, must be a date such as 2025-06-30 or an RFC 3339 time", value)
+	}
+
+	return expiresAt, nil
+}
+
+// Apply marks the vulnerabilities of the results whose CVE is allowed as suppressed, with the reason of the allowlist.
+// The suppressions expired at now, and the vulnerabilities more severe than the MaxSeverity of their rule, are left unchanged.
+// The vulnerabilities already suppressed by a VEX document keep their VEX status.
+func (a Allowlist) Apply(results []storagev1alpha1.Result, now time.Time) {
+	for i := range results {
+		for j := range results[i].Vulnerabilities {
+			vuln := &results[i].Vulnerabilities[j]
+			if vuln.Suppressed {
+				continue
+			}
+			rule, ok := a[strings.ToUpper(vuln.CVE)]
+			if !ok || !rule.suppresses(vuln.Severity, now) {
+				continue
+			}
+			vuln.Suppressed = true
+			vuln.VEXStatus = &storagev1alpha1.VEXStatus{
+				Repository: AllowlistRepository,
+				Status:     AllowlistStatus,
+				Statement:  rule.Reason,
+			}
+			if rule.ExpiresAt != nil {
+				vuln.VEXStatus.ExpiresAt = &metav1.Time{Time: *rule.ExpiresAt}
+			}
+		}
+	}
+}
+
+// suppresses returns true if the rule suppresses a vulnerability of the severity at now.
+func (r AllowlistRule) suppresses(severity string, now time.Time) bool {
+	if r.ExpiresAt != nil && !now.Before(*r.ExpiresAt) {
+		return false
+	}
+	if r.MaxSeverity != "" && slices.Index(severities, severity) > slices.Index(severities, r.MaxSeverity) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,154 @@
+package vulnerabilityreport
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+func TestLoadAllowlist(t *testing.T) {
+	tests := []struct {
+		name              string
+		content           string
+		expectedAllowlist Allowlist
+		expectedError     string
+	}{
+		{
+			name: "valid",
+			content: `
+- cve: CVE-2024-0001
+  reason: Not reachable
+- cve: " cve-2024-0002 "
+  reason: Accepted risk
+  expires: 2025-06-30
+  maxSeverity: medium
+- cve: CVE-2024-0003
+  reason: Accepted risk
+  expires: "2025-06-30T12:00:00Z"
+`,
+			expectedAllowlist: Allowlist{
+				"CVE-2024-0001": {Reason: "Not reachable"},
+				"CVE-2024-0002": {Reason: "Accepted risk", ExpiresAt: ptr(time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)), MaxSeverity: "MEDIUM"},
+				"CVE-2024-0003": {Reason: "Accepted risk", ExpiresAt: ptr(time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC))},
+			},
+		},
+		{
+			name:              "empty",
+			content:           "",
+			expectedAllowlist: Allowlist{},
+		},
+		{
+			name:          "missing cve",
+			content:       "- reason: Not reachable\n",
+			expectedError: "entry 0 has no cve",
+		},
+		{
+			name:          "missing reason",
+			content:       "- cve: CVE-2024-0001\n",
+			expectedError: "CVE-2024-0001 has no reason",
+		},
+		{
+			name:          "invalid expires",
+			content:       "- cve: CVE-2024-0001\n  reason: Accepted risk\n  expires: next month\n",
+			expectedError: `invalid expires "next month"`,
+		},
+		{
+			name:          "invalid max severity",
+			content:       "- cve: CVE-2024-0001\n  reason: Accepted risk\n  maxSeverity: SEVERE\n",
+			expectedError: `invalid maxSeverity "SEVERE"`,
+		},
+		{
+			name:          "malformed",
+			content:       "cve: CVE-2024-0001\n",
+			expectedError: "failed to parse the CVE allowlist",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "allowlist.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(test.content), 0o600))
+
+			allowlist, err := LoadAllowlist(path)
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedAllowlist, allowlist)
+		})
+	}
+}
+
+func TestAllowlistApply(t *testing.T) {
+	vexStatus := &storagev1alpha1.VEXStatus{Repository: "vexhub", Status: "not_affected", Statement: "vulnerable_code_not_present"}
+	results := []storagev1alpha1.Result{
+		{
+			Vulnerabilities: []storagev1alpha1.Vulnerability{
+				{CVE: "CVE-2024-0001", Severity: "CRITICAL"},
+				{CVE: "CVE-2024-0002", Severity: "HIGH"},
+				{CVE: "CVE-2024-0003", Severity: "HIGH", Suppressed: true, VEXStatus: vexStatus},
+			},
+		},
+	}
+
+	Allowlist{
+		"CVE-2024-0001": {Reason: "Not reachable"},
+		"CVE-2024-0003": {Reason: "Accepted risk"},
+	}.Apply(results, time.Now())
+
+	assert.Equal(t, []storagev1alpha1.Vulnerability{
+		{
+			CVE:        "CVE-2024-0001",
+			Severity:   "CRITICAL",
+			Suppressed: true,
+			VEXStatus:  &storagev1alpha1.VEXStatus{Repository: AllowlistRepository, Status: AllowlistStatus, Statement: "Not reachable"},
+		},
+		{CVE: "CVE-2024-0002", Severity: "HIGH"},
+		{CVE: "CVE-2024-0003", Severity: "HIGH", Suppressed: true, VEXStatus: vexStatus},
+	}, results[0].Vulnerabilities)
+	assert.Equal(t, storagev1alpha1.Summary{High: 1, Suppressed: 2}, ComputeSummary(results))
+}
+
+func TestAllowlistApply_TimeBoundedAndMaxSeverity(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(24 * time.Hour)
+	expiredAt := now.Add(-24 * time.Hour)
+	allowlist := Allowlist{
+		"CVE-2024-0001": {Reason: "Accepted until the fix", ExpiresAt: &expiresAt},
+		"CVE-2024-0002": {Reason: "Expired", ExpiresAt: &expiredAt},
+		"CVE-2024-0003": {Reason: "Low impact", MaxSeverity: "MEDIUM"},
+	}
+	results := []storagev1alpha1.Result{
+		{
+			Vulnerabilities: []storagev1alpha1.Vulnerability{
+				{CVE: "CVE-2024-0001", Severity: "MEDIUM"},
+				{CVE: "CVE-2024-0002", Severity: "LOW"},
+				{CVE: "CVE-2024-0003", Severity: "MEDIUM"},
+				{CVE: "CVE-2024-0003", Severity: "HIGH"},
+			},
+		},
+	}
+
+	allowlist.Apply(results, now)
+
+	vulnerabilities := results[0].Vulnerabilities
+	assert.True(t, vulnerabilities[0].Suppressed)
+	assert.Equal(t, &metav1.Time{Time: expiresAt}, vulnerabilities[0].VEXStatus.ExpiresAt)
+	assert.False(t, vulnerabilities[1].Suppressed, "the expired suppression must not apply")
+	assert.Nil(t, vulnerabilities[1].VEXStatus)
+	assert.True(t, vulnerabilities[2].Suppressed)
+	assert.Nil(t, vulnerabilities[2].VEXStatus.ExpiresAt)
+	assert.False(t, vulnerabilities[3].Suppressed, "the severity above maxSeverity must not be suppressed")
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
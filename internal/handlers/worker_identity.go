@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// setGeneratedBy records in the annotations of the artifact the worker which produced it, and when.
+// The worker is not recorded when its identity is empty, so that the annotation of a previous write is not kept.
+func setGeneratedBy(objectMeta *metav1.ObjectMeta, workerID string, now time.Time) {
+	if workerID != "" {
+		metav1.SetMetaDataAnnotation(objectMeta, storagev1alpha1.AnnotationGeneratedByKey, workerID)
+	} else {
+		delete(objectMeta.Annotations, storagev1alpha1.AnnotationGeneratedByKey)
+	}
+	metav1.SetMetaDataAnnotation(objectMeta, storagev1alpha1.AnnotationGeneratedAtKey, now.UTC().Format(time.RFC3339))
+}
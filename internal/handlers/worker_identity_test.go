@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+func TestSetGeneratedBy(t *testing.T) {
+	now := time.Date(2025, 6, 15, 14, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	objectMeta := metav1.ObjectMeta{
+		Annotations: map[string]string{storagev1alpha1.AnnotationSBOMScopeKey: "os"},
+	}
+
+	setGeneratedBy(&objectMeta, testWorkerID, now)
+	assert.Equal(t, map[string]string{
+		storagev1alpha1.AnnotationSBOMScopeKey:   "os",
+		storagev1alpha1.AnnotationGeneratedByKey: testWorkerID,
+		storagev1alpha1.AnnotationGeneratedAtKey: "2025-06-15T12:30:00Z",
+	}, objectMeta.Annotations)
+
+	// A worker without identity overwrites the annotations of the previous write.
+	setGeneratedBy(&objectMeta, "", now.Add(time.Hour))
+	assert.Equal(t, map[string]string{
+		storagev1alpha1.AnnotationSBOMScopeKey:   "os",
+		storagev1alpha1.AnnotationGeneratedAtKey: "2025-06-15T13:30:00Z",
+	}, objectMeta.Annotations)
+}
@@ -0,0 +1,213 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const dispatchInterval = time.Second
+
+// FairDispatcher moves the messages published with PublishPartitioned from the backlog to the work queue.
+// Messages are taken one at a time from each partition in a round-robin fashion,
+// and only while the work queue holds less than maxPending messages,
+// so that the workers interleave the partitions instead of draining them one after the other.
+// The high priority lane of the backlog is drained before the normal one.
+type FairDispatcher struct {
+	js         jetstream.JetStream
+	maxPending uint64
+	// lastPartitions are the subjects of the last partition a message was dispatched from, by lane,
+	// used to resume the round-robin on the next dispatch.
+	lastPartitions map[Priority]string
+	logger         *slog.Logger
+}
+
+// NewFairDispatcher creates a new FairDispatcher instance with the provided NATS connection.
+// The streams are expected to be created by the NatsPublisher.
+// The number of messages of the backlog and of the work queue is exported as the sbomscanner_scan_queue_messages metric,
+// e.g. to autoscale the workers.
+func NewFairDispatcher(nc *nats.Conn, maxPending uint64, logger *slog.Logger) (*FairDispatcher, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	registerDispatcherMetricsOnce.Do(func() {
+		metrics.Registry.MustRegister(scanQueueMessagesGauge)
+	})
+
+	return &FairDispatcher{
+		js:             js,
+		maxPending:     maxPending,
+		lastPartitions: make(map[Priority]string),
+		logger:         logger.With("component", "fair_dispatcher"),
+	}, nil
+}
+
+// Start implements the Runnable interface.
+func (d *FairDispatcher) Start(ctx context.Context) error {
+	d.logger.InfoContext(ctx, "Starting fair dispatcher", "maxPending", d.maxPending)
+
+	ticker := time.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.logger.InfoContext(ctx, "Stopping fair dispatcher")
+
+			return nil
+		case <-ticker.C:
+			if err := d.dispatch(ctx); err != nil {
+				d.logger.ErrorContext(ctx, "Failed to dispatch messages", "error", err)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements the LeaderElectionRunnable interface.
+// Only one dispatcher must move the messages, otherwise the work queue depth would not be honored.
+func (d *FairDispatcher) NeedLeaderElection() bool {
+	return true
+}
+
+// dispatch moves messages from the backlog partitions to the work queue, until the work queue is full
+// or the backlog is empty. The lanes are dispatched by priority.
+// The depths of the queues are recorded before the messages are moved.
+func (d *FairDispatcher) dispatch(ctx context.Context) error {
+	workQueue, err := d.js.Stream(ctx, StreamName)
+	if err != nil {
+		return fmt.Errorf("failed to get stream %s: %w", StreamName, err)
+	}
+	workQueueInfo, err := workQueue.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get stream %s info: %w", StreamName, err)
+	}
+	backlog, err := d.js.Stream(ctx, BacklogStreamName)
+	if err != nil {
+		return fmt.Errorf("failed to get stream %s: %w", BacklogStreamName, err)
+	}
+	backlogInfo, err := backlog.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get stream %s info: %w", BacklogStreamName, err)
+	}
+	scanQueueMessagesGauge.WithLabelValues(scanQueueWork).Set(float64(workQueueInfo.State.Msgs))
+	scanQueueMessagesGauge.WithLabelValues(scanQueueBacklog).Set(float64(backlogInfo.State.Msgs))
+
+	if workQueueInfo.State.Msgs >= d.maxPending || backlogInfo.State.Msgs == 0 {
+		return nil
+	}
+	budget := d.maxPending - workQueueInfo.State.Msgs
+
+	for _, priority := range priorities {
+		if budget == 0 {
+			break
+		}
+
+		budget, err = d.dispatchLane(ctx, backlog, priority, budget)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dispatchLane moves at most budget messages from the partitions of the lane to the work queue.
+// It returns the remaining budget.
+func (d *FairDispatcher) dispatchLane(ctx context.Context, backlog jetstream.Stream, priority Priority, budget uint64) (uint64, error) {
+	backlogInfo, err := backlog.Info(ctx, jetstream.WithSubjectFilter(priority.backlogSubject()))
+	if err != nil {
+		return budget, fmt.Errorf("failed to get stream %s info: %w", BacklogStreamName, err)
+	}
+	if len(backlogInfo.State.Subjects) == 0 {
+		return budget, nil
+	}
+
+	partitions := roundRobinOrder(backlogInfo.State.Subjects, d.lastPartitions[priority])
+	for budget > 0 && len(partitions) > 0 {
+		var remaining []string
+		for _, partition := range partitions {
+			if budget == 0 {
+				break
+			}
+
+			dispatched, err := d.dispatchNext(ctx, backlog, partition)
+			if err != nil {
+				return budget, err
+			}
+			if !dispatched {
+				continue
+			}
+
+			d.lastPartitions[priority] = partition
+			budget--
+			remaining = append(remaining, partition)
+		}
+		partitions = remaining
+	}
+
+	return budget, nil
+}
+
+// roundRobinOrder returns the partitions sorted by subject, starting after the last partition served.
+func roundRobinOrder(subjects map[string]uint64, lastPartition string) []string {
+	partitions := make([]string, 0, len(subjects))
+	for subject := range subjects {
+		partitions = append(partitions, subject)
+	}
+	slices.Sort(partitions)
+
+	next, _ := slices.BinarySearch(partitions, lastPartition)
+	if next < len(partitions) && partitions[next] == lastPartition {
+		next++
+	}
+	next %= len(partitions)
+
+	return slices.Concat(partitions[next:], partitions[:next])
+}
+
+// dispatchNext moves the oldest message of the partition to the work queue.
+// It returns false if the partition is empty.
+func (d *FairDispatcher) dispatchNext(ctx context.Context, backlog jetstream.Stream, partition string) (bool, error) {
+	rawMsg, err := backlog.GetMsg(ctx, 1, jetstream.WithGetMsgSubject(partition))
+	if err != nil {
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get message from partition %s: %w", partition, err)
+	}
+
+	subject := rawMsg.Header.Get(subjectHeader)
+	if subject == "" {
+		d.logger.ErrorContext(ctx, "Dropping partitioned message without subject", "partition", partition, "sequence", rawMsg.Sequence)
+	} else {
+		// The message ID is preserved, so that if the dispatcher stops before the message is deleted from the backlog,
+		// the duplicate published on the next dispatch is discarded by JetStream.
+		msg := &nats.Msg{
+			Subject: subject,
+			Data:    rawMsg.Data,
+			Header: nats.Header{
+				jetstream.MsgIDHeader: []string{rawMsg.Header.Get(jetstream.MsgIDHeader)},
+			},
+		}
+		if _, err := d.js.PublishMsg(ctx, msg); err != nil {
+			return false, fmt.Errorf("failed to dispatch message from partition %s: %w", partition, err)
+		}
+
+		d.logger.DebugContext(ctx, "Message dispatched", "subject", subject, "partition", partition, "sequence", rawMsg.Sequence)
+	}
+
+	if err := backlog.DeleteMsg(ctx, rawMsg.Sequence); err != nil {
+		return false, fmt.Errorf("failed to delete message %d from partition %s: %w", rawMsg.Sequence, partition, err)
+	}
+
+	return true, nil
+}
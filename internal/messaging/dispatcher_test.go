@@ -0,0 +1,181 @@
+package messaging
+
+import (
+	"log/slog"
+	"testing"
+
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDispatcherSubject = "sbomscanner.dispatcher.test"
+
+func TestFairDispatcher_Dispatch(t *testing.T) {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1 // Use a random port
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	ns := natstest.RunServer(&opts)
+	defer ns.Shutdown()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+
+	publisher, err := NewNatsPublisher(t.Context(), nc, 1, slog.Default())
+	require.NoError(t, err)
+
+	for _, id := range []string{"a1", "a2", "a3"} {
+		err = publisher.PublishPartitioned(t.Context(), testDispatcherSubject, "default.registry-a", PriorityNormal, id, []byte(id))
+		require.NoError(t, err)
+	}
+	err = publisher.PublishPartitioned(t.Context(), testDispatcherSubject, "default.registry-b", PriorityNormal, "b1", []byte("b1"))
+	require.NoError(t, err)
+	// Send a duplicate message with the same ID to test idempotency
+	err = publisher.PublishPartitioned(t.Context(), testDispatcherSubject, "default.registry-b", PriorityNormal, "b1", []byte("b1 duplicate"))
+	require.NoError(t, err)
+
+	dispatcher, err := NewFairDispatcher(nc, 2, slog.Default())
+	require.NoError(t, err)
+
+	cons, err := publisher.js.CreateOrUpdateConsumer(t.Context(), StreamName, jetstream.ConsumerConfig{
+		Durable:   "test",
+		AckPolicy: jetstream.AckExplicitPolicy,
+	})
+	require.NoError(t, err)
+
+	fetch := func() []string {
+		batch, err := cons.FetchNoWait(10)
+		require.NoError(t, err)
+		require.NoError(t, batch.Error())
+
+		var messages []string
+		for msg := range batch.Messages() {
+			assert.Equal(t, testDispatcherSubject, msg.Subject())
+			messages = append(messages, string(msg.Data()))
+			require.NoError(t, msg.DoubleAck(t.Context()))
+		}
+
+		return messages
+	}
+
+	// The first message of each registry is dispatched, up to the work queue depth.
+	require.NoError(t, dispatcher.dispatch(t.Context()))
+	assert.Equal(t, []string{"a1", "b1"}, fetch())
+
+	// Registry b is empty, so the remaining messages of registry a are dispatched.
+	require.NoError(t, dispatcher.dispatch(t.Context()))
+	assert.Equal(t, []string{"a2", "a3"}, fetch())
+
+	require.NoError(t, dispatcher.dispatch(t.Context()))
+	assert.Empty(t, fetch())
+
+	backlog, err := publisher.js.Stream(t.Context(), BacklogStreamName)
+	require.NoError(t, err)
+	backlogInfo, err := backlog.Info(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), backlogInfo.State.Msgs)
+}
+
+func TestFairDispatcher_DispatchFullWorkQueue(t *testing.T) {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1 // Use a random port
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	ns := natstest.RunServer(&opts)
+	defer ns.Shutdown()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+
+	publisher, err := NewNatsPublisher(t.Context(), nc, 1, slog.Default())
+	require.NoError(t, err)
+
+	err = publisher.Publish(t.Context(), testDispatcherSubject, "queued", []byte("queued"))
+	require.NoError(t, err)
+	err = publisher.PublishPartitioned(t.Context(), testDispatcherSubject, "default.registry-a", PriorityNormal, "a1", []byte("a1"))
+	require.NoError(t, err)
+
+	dispatcher, err := NewFairDispatcher(nc, 1, slog.Default())
+	require.NoError(t, err)
+
+	require.NoError(t, dispatcher.dispatch(t.Context()))
+	assert.InDelta(t, 1, testutil.ToFloat64(scanQueueMessagesGauge.WithLabelValues(scanQueueWork)), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(scanQueueMessagesGauge.WithLabelValues(scanQueueBacklog)), 0)
+
+	backlog, err := publisher.js.Stream(t.Context(), BacklogStreamName)
+	require.NoError(t, err)
+	backlogInfo, err := backlog.Info(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), backlogInfo.State.Msgs)
+}
+
+func TestFairDispatcher_DispatchPriority(t *testing.T) {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1 // Use a random port
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	ns := natstest.RunServer(&opts)
+	defer ns.Shutdown()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+
+	publisher, err := NewNatsPublisher(t.Context(), nc, 1, slog.Default())
+	require.NoError(t, err)
+
+	for _, id := range []string{"a1", "a2"} {
+		err = publisher.PublishPartitioned(t.Context(), testDispatcherSubject, "default.registry-a", PriorityNormal, id, []byte(id))
+		require.NoError(t, err)
+	}
+	err = publisher.PublishPartitioned(t.Context(), testDispatcherSubject, "default.registry-a", PriorityHigh, "a3", []byte("a3"))
+	require.NoError(t, err)
+	err = publisher.PublishPartitioned(t.Context(), testDispatcherSubject, "default.registry-b", PriorityHigh, "b1", []byte("b1"))
+	require.NoError(t, err)
+
+	dispatcher, err := NewFairDispatcher(nc, 3, slog.Default())
+	require.NoError(t, err)
+
+	cons, err := publisher.js.CreateOrUpdateConsumer(t.Context(), StreamName, jetstream.ConsumerConfig{
+		Durable:   "test",
+		AckPolicy: jetstream.AckExplicitPolicy,
+	})
+	require.NoError(t, err)
+
+	fetch := func() []string {
+		batch, err := cons.FetchNoWait(10)
+		require.NoError(t, err)
+		require.NoError(t, batch.Error())
+
+		var messages []string
+		for msg := range batch.Messages() {
+			messages = append(messages, string(msg.Data()))
+			require.NoError(t, msg.DoubleAck(t.Context()))
+		}
+
+		return messages
+	}
+
+	// The high priority lane is drained first, the remaining budget is used by the normal lane.
+	require.NoError(t, dispatcher.dispatch(t.Context()))
+	assert.Equal(t, []string{"a3", "b1", "a1"}, fetch())
+
+	require.NoError(t, dispatcher.dispatch(t.Context()))
+	assert.Equal(t, []string{"a2"}, fetch())
+}
+
+func TestParsePriority(t *testing.T) {
+	priority, err := ParsePriority("high")
+	require.NoError(t, err)
+	assert.Equal(t, PriorityHigh, priority)
+
+	priority, err = ParsePriority("normal")
+	require.NoError(t, err)
+	assert.Equal(t, PriorityNormal, priority)
+
+	_, err = ParsePriority("urgent")
+	require.ErrorContains(t, err, `unknown priority "urgent"`)
+}
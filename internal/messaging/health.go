@@ -0,0 +1,53 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// JetStreamChecker verifies the connectivity of a NatsSubscriber to JetStream.
+// It is meant to be used as a readiness check, see healthz.Checker.
+type JetStreamChecker struct {
+	nc     *nats.Conn
+	cons   jetstream.Consumer
+	logger *slog.Logger
+}
+
+// NewJetStreamChecker creates a new JetStreamChecker for the subscriber using the provided NATS connection.
+func NewJetStreamChecker(nc *nats.Conn, subscriber *NatsSubscriber, logger *slog.Logger) *JetStreamChecker {
+	return &JetStreamChecker{
+		nc:     nc,
+		cons:   subscriber.cons,
+		logger: logger,
+	}
+}
+
+// Name returns the name of the check.
+func (c *JetStreamChecker) Name() string {
+	return "jetstream"
+}
+
+// Check verifies that the NATS connection is established and that the consumer of the subscriber exists.
+// The connection status is read locally, so the JetStream API is only queried while connected.
+func (c *JetStreamChecker) Check(req *http.Request) error {
+	if status := c.nc.Status(); status != nats.CONNECTED {
+		c.logger.Debug("NATS not connected", "status", status.String())
+		return fmt.Errorf("NATS connection is %s", status)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := c.cons.Info(ctx); err != nil {
+		c.logger.Debug("JetStream consumer info failed", "error", err)
+		return fmt.Errorf("JetStream consumer not available: %w", err)
+	}
+
+	return nil
+}
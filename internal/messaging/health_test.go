@@ -0,0 +1,47 @@
+package messaging
+
+import (
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJetStreamChecker_Check(t *testing.T) {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1 // Use a random port
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	ns := natstest.RunServer(&opts)
+	defer ns.Shutdown()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	publisher, err := NewNatsPublisher(t.Context(), nc, 1, slog.Default())
+	require.NoError(t, err)
+
+	handlers := HandlerRegistry{
+		testSubscriberSubject: &testHandler{},
+	}
+	subscriber, err := NewNatsSubscriber(t.Context(), nc, "test-durable", DefaultAckWait, handlers, nil, nil, slog.Default())
+	require.NoError(t, err)
+
+	checker := NewJetStreamChecker(nc, subscriber, slog.Default())
+	req := httptest.NewRequestWithContext(t.Context(), "GET", "/readyz", nil)
+
+	require.NoError(t, checker.Check(req))
+
+	// The check fails when the consumer is deleted.
+	err = publisher.js.DeleteConsumer(t.Context(), StreamName, "test-durable")
+	require.NoError(t, err)
+	require.ErrorContains(t, checker.Check(req), "JetStream consumer not available")
+
+	// The check fails when the connection is closed.
+	nc.Close()
+	require.ErrorContains(t, checker.Check(req), "NATS connection is")
+}
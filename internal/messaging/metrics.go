@@ -0,0 +1,27 @@
+package messaging
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The queues of the scan messages, as labelled in the scanQueueMessagesGauge.
+const (
+	scanQueueBacklog = "backlog"
+	scanQueueWork    = "work"
+)
+
+var (
+	scanQueueMessagesGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "sbomscanner",
+			Name:      "scan_queue_messages",
+			Help: "Number of messages waiting in the scan queues: the backlog, waiting to be dispatched, " +
+				"and the work queue, consumed by the workers.",
+		},
+		[]string{"queue"},
+	)
+
+	registerDispatcherMetricsOnce sync.Once
+)
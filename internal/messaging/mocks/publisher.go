@@ -7,6 +7,7 @@ package messaging
 import (
 	"context"
 
+	messaging0 "github.com/kubewarden/sbomscanner/internal/messaging"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -105,3 +106,84 @@ func (_c *MockPublisher_Publish_Call) RunAndReturn(run func(ctx context.Context,
 	_c.Call.Return(run)
 	return _c
 }
+
+// PublishPartitioned provides a mock function for the type MockPublisher
+func (_mock *MockPublisher) PublishPartitioned(ctx context.Context, subject string, partition string, priority messaging0.Priority, messageID string, message []byte) error {
+	ret := _mock.Called(ctx, subject, partition, priority, messageID, message)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PublishPartitioned")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, messaging0.Priority, string, []byte) error); ok {
+		r0 = returnFunc(ctx, subject, partition, priority, messageID, message)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockPublisher_PublishPartitioned_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PublishPartitioned'
+type MockPublisher_PublishPartitioned_Call struct {
+	*mock.Call
+}
+
+// PublishPartitioned is a helper method to define mock.On call
+//   - ctx context.Context
+//   - subject string
+//   - partition string
+//   - priority messaging0.Priority
+//   - messageID string
+//   - message []byte
+func (_e *MockPublisher_Expecter) PublishPartitioned(ctx interface{}, subject interface{}, partition interface{}, priority interface{}, messageID interface{}, message interface{}) *MockPublisher_PublishPartitioned_Call {
+	return &MockPublisher_PublishPartitioned_Call{Call: _e.mock.On("PublishPartitioned", ctx, subject, partition, priority, messageID, message)}
+}
+
+func (_c *MockPublisher_PublishPartitioned_Call) Run(run func(ctx context.Context, subject string, partition string, priority messaging0.Priority, messageID string, message []byte)) *MockPublisher_PublishPartitioned_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 messaging0.Priority
+		if args[3] != nil {
+			arg3 = args[3].(messaging0.Priority)
+		}
+		var arg4 string
+		if args[4] != nil {
+			arg4 = args[4].(string)
+		}
+		var arg5 []byte
+		if args[5] != nil {
+			arg5 = args[5].([]byte)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+			arg4,
+			arg5,
+		)
+	})
+	return _c
+}
+
+func (_c *MockPublisher_PublishPartitioned_Call) Return(err error) *MockPublisher_PublishPartitioned_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockPublisher_PublishPartitioned_Call) RunAndReturn(run func(ctx context.Context, subject string, partition string, priority messaging0.Priority, messageID string, message []byte) error) *MockPublisher_PublishPartitioned_Call {
+	_c.Call.Return(run)
+	return _c
+}
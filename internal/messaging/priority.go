@@ -0,0 +1,50 @@
+package messaging
+
+import "fmt"
+
+// Priority is the lane of the backlog a partitioned message is published to.
+// The FairDispatcher drains the high priority lane before the normal one,
+// while the partitions of a lane are still served in a round-robin fashion.
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+
+	backlogHighSubjectPrefix = "sbomscanner_backlog_high."
+	backlogHighSubject       = backlogHighSubjectPrefix + ">"
+)
+
+// priorities are the lanes of the backlog, in the order they are dispatched.
+var priorities = []Priority{PriorityHigh, PriorityNormal}
+
+// ParsePriority returns the Priority with the given name.
+// An error is returned when the name is not a known priority.
+func ParsePriority(name string) (Priority, error) {
+	switch priority := Priority(name); priority {
+	case PriorityHigh, PriorityNormal:
+		return priority, nil
+	default:
+		return "", fmt.Errorf("unknown priority %q, must be one of %q or %q", name, PriorityHigh, PriorityNormal)
+	}
+}
+
+// backlogSubjectPrefix returns the prefix of the backlog subjects of the lane.
+// The normal lane keeps the subjects used before the lanes were introduced,
+// so that the messages already in the backlog are still dispatched after an upgrade.
+func (p Priority) backlogSubjectPrefix() string {
+	if p == PriorityHigh {
+		return backlogHighSubjectPrefix
+	}
+
+	return backlogSubjectPrefix
+}
+
+// backlogSubject returns the subject filter matching all the partitions of the lane.
+func (p Priority) backlogSubject() string {
+	if p == PriorityHigh {
+		return backlogHighSubject
+	}
+
+	return backlogSubject
+}
@@ -10,8 +10,18 @@ import (
 )
 
 const (
-	streamName        = "SBOMBASTIC"
+	// StreamName is the work queue stream consumed by the workers.
+	StreamName        = "SBOMBASTIC"
 	sbombasticSubject = "sbomscanner.>"
+
+	// BacklogStreamName is the stream holding the partitioned messages
+	// waiting to be moved to the work queue by the FairDispatcher.
+	BacklogStreamName    = "SBOMBASTIC_BACKLOG"
+	backlogSubjectPrefix = "sbomscanner_backlog."
+	backlogSubject       = backlogSubjectPrefix + ">"
+
+	// subjectHeader carries the subject a partitioned message is dispatched to.
+	subjectHeader = "Sbomscanner-Subject"
 )
 
 type Publisher interface {
@@ -20,6 +30,29 @@ type Publisher interface {
 	// If a message with the same ID has already been published in, it will be ignored.
 	// The default deduplication window is 2 minutes.
 	Publish(ctx context.Context, subject string, messageID string, message []byte) error
+	// PublishPartitioned publishes a message to the backlog of the given partition, in the lane of the given priority.
+	// The FairDispatcher moves the message to the subject in a round-robin fashion across the partitions,
+	// so that a large partition does not delay the messages of the other ones.
+	// The messages of the high priority lane are moved before the normal ones.
+	// The messageID is used for deduplication, as in Publish.
+	PublishPartitioned(ctx context.Context, subject string, partition string, priority Priority, messageID string, message []byte) error
+}
+
+// StreamConfigs returns the configuration of the work queue and the backlog streams with the given number of replicas.
+func StreamConfigs(replicas int) []jetstream.StreamConfig {
+	return []jetstream.StreamConfig{
+		{
+			Name:      StreamName,
+			Retention: jetstream.WorkQueuePolicy,
+			Subjects:  []string{sbombasticSubject},
+			Replicas:  replicas,
+		},
+		{
+			Name:     BacklogStreamName,
+			Subjects: []string{backlogSubject, backlogHighSubject},
+			Replicas: replicas,
+		},
+	}
 }
 
 // NatsPublisher is an implementation of the Publisher interface that uses NATS JetStream to publish messages.
@@ -29,7 +62,9 @@ type NatsPublisher struct {
 }
 
 // NewNatsPublisher creates a new NatsPublisher instance with the provided NATS connection.
-func NewNatsPublisher(ctx context.Context, nc *nats.Conn, logger *slog.Logger) (*NatsPublisher, error) {
+// The streams are created with the given number of replicas, or updated if they already exist,
+// so that the replication factor can be changed on a running cluster.
+func NewNatsPublisher(ctx context.Context, nc *nats.Conn, replicas int, logger *slog.Logger) (*NatsPublisher, error) {
 	js, err := jetstream.New(nc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
@@ -37,17 +72,13 @@ func NewNatsPublisher(ctx context.Context, nc *nats.Conn, logger *slog.Logger) (
 
 	logger = logger.With("component", "nats_publisher")
 
-	// CreateStream is an idempotent operation, if the stream already exists, it will succeed without error.
-	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
-		Name:      streamName,
-		Retention: jetstream.WorkQueuePolicy,
-		Subjects:  []string{sbombasticSubject},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create JetStream stream: %w", err)
-	}
+	for _, config := range StreamConfigs(replicas) {
+		if _, err = js.CreateOrUpdateStream(ctx, config); err != nil {
+			return nil, fmt.Errorf("failed to create JetStream stream %s: %w", config.Name, err)
+		}
 
-	logger.DebugContext(ctx, "Stream created", "stream", streamName, "subjects", sbombasticSubject)
+		logger.DebugContext(ctx, "Stream created", "stream", config.Name, "subjects", config.Subjects, "replicas", replicas)
+	}
 
 	publisher := &NatsPublisher{
 		js:     js,
@@ -77,3 +108,33 @@ func (p *NatsPublisher) Publish(ctx context.Context, subject string, messageID s
 
 	return nil
 }
+
+// PublishPartitioned publishes a message to the backlog of the given partition, in the lane of the given priority.
+// The FairDispatcher moves the message to the subject in a round-robin fashion across the partitions,
+// so that a large partition does not delay the messages of the other ones.
+// The messages of the high priority lane are moved before the normal ones.
+// The messageID is used for deduplication, as in Publish.
+func (p *NatsPublisher) PublishPartitioned(
+	ctx context.Context,
+	subject string,
+	partition string,
+	priority Priority,
+	messageID string,
+	message []byte,
+) error {
+	msg := &nats.Msg{
+		Subject: priority.backlogSubjectPrefix() + partition,
+		Data:    message,
+		Header: nats.Header{
+			jetstream.MsgIDHeader: []string{messageID},
+			subjectHeader:         []string{subject},
+		},
+	}
+	if _, err := p.js.PublishMsg(ctx, msg); err != nil {
+		return fmt.Errorf("failed to publish partitioned message: %w", err)
+	}
+
+	p.logger.DebugContext(ctx, "Partitioned message published", "subject", subject, "partition", partition, "priority", priority, "header", msg.Header, "message", string(msg.Data))
+
+	return nil
+}
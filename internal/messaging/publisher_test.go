@@ -24,7 +24,7 @@ func TestPublisher_Publish(t *testing.T) {
 	nc, err := nats.Connect(ns.ClientURL())
 	require.NoError(t, err)
 
-	publisher, err := NewNatsPublisher(t.Context(), nc, slog.Default())
+	publisher, err := NewNatsPublisher(t.Context(), nc, 1, slog.Default())
 	require.NoError(t, err)
 
 	message := []byte(`{"data":"test data"}`)
@@ -36,7 +36,7 @@ func TestPublisher_Publish(t *testing.T) {
 	err = publisher.Publish(t.Context(), testPublisherSubject, "id", messageDup)
 	require.NoError(t, err)
 
-	cons, err := publisher.js.CreateOrUpdateConsumer(t.Context(), streamName, jetstream.ConsumerConfig{})
+	cons, err := publisher.js.CreateOrUpdateConsumer(t.Context(), StreamName, jetstream.ConsumerConfig{})
 	require.NoError(t, err)
 
 	batch, err := cons.FetchNoWait(10) // Fetch max 10 messages, but we expect only 1
@@ -2,17 +2,36 @@ package messaging
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math"
 	"math/rand/v2"
+	"net/http"
+	"slices"
 	"time"
 
+	"github.com/avast/retry-go/v4"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 )
 
-const maxDeliver = 5
+const (
+	maxDeliver = 5
+
+	// consumerSetupAttemptTimeout bounds each attempt to create the consumer,
+	// since the JetStream API does not answer while the stream has no leader.
+	consumerSetupAttemptTimeout = 5 * time.Second
+
+	// DefaultAckWait is the default time the server waits for the acknowledgement of a message
+	// before delivering it again, e.g. to another worker when the worker processing it crashed.
+	DefaultAckWait = 2 * time.Minute
+	// MinAckWait is the minimum AckWait, so that the progress signals are not sent too often.
+	MinAckWait = 10 * time.Second
+	// inProgressSignalsPerAckWait is the number of progress signals sent per AckWait while a message is processed,
+	// so that a late signal does not let the server deliver a running message again.
+	inProgressSignalsPerAckWait = 4
+)
 
 // RetryConfig defines retry behavior for message handling.
 type RetryConfig struct {
@@ -29,19 +48,41 @@ type RetryConfig struct {
 // HandlerRegistry is a map that associates subjects with their respective handlers.
 type HandlerRegistry map[string]Handler
 
+// ConsumerConfig returns the configuration of the durable consumer of the work queue stream
+// receiving the messages of the given subjects, delivered again when they are not acknowledged within ackWait.
+// The subjects are sorted, so that the configuration does not depend on their order.
+func ConsumerConfig(durable string, subjects []string, ackWait time.Duration) jetstream.ConsumerConfig {
+	subjects = slices.Sorted(slices.Values(subjects))
+
+	return jetstream.ConsumerConfig{
+		FilterSubjects: subjects,
+		Durable:        durable,
+		// AckWait defines how long the server will wait for an acknowledgement
+		// before resending a message. The subscriber extends it while the message is processed,
+		// so it only bounds the time a message waits to be delivered again when its worker crashed.
+		AckWait: ackWait,
+		// We do not set MaxDeliver here because we want to handle retries manually
+		// to implement custom backoff and failure handling logic.
+	}
+}
+
 // NatsSubscriber is an implementation of a message subscriber that uses NATS JetStream to receive messages.
 type NatsSubscriber struct {
-	cons           jetstream.Consumer
-	handlers       HandlerRegistry
-	failureHandler FailureHandler
-	retryConfig    *RetryConfig
-	logger         *slog.Logger
+	cons jetstream.Consumer
+	// inProgressInterval is the interval between the progress signals sent while a message is processed.
+	inProgressInterval time.Duration
+	handlers           HandlerRegistry
+	failureHandler     FailureHandler
+	retryConfig        *RetryConfig
+	logger             *slog.Logger
 }
 
 // NewNatsSubscriber creates a new NatsSubscriber instance with the provided NATS connection and durable subscription name.
+// The messages are delivered again when they are not acknowledged within ackWait, which is extended while they are processed.
 func NewNatsSubscriber(ctx context.Context,
 	nc *nats.Conn,
 	durable string,
+	ackWait time.Duration,
 	handlers HandlerRegistry,
 	failureHandler FailureHandler,
 	retryConfig *RetryConfig,
@@ -57,35 +98,71 @@ func NewNatsSubscriber(ctx context.Context,
 		subjects = append(subjects, subject)
 	}
 
-	cons, err := js.CreateOrUpdateConsumer(ctx,
-		streamName,
-		jetstream.ConsumerConfig{
-			FilterSubjects: subjects,
-			Durable:        durable,
-			// AckWait defines how long the server will wait for an acknowledgement
-			// before resending a message.
-			// We set it to a higher value than the default to allow for longer processing times.
-			// Handlers that are expected to take longer should use `InProgress` to extend the AckWait.
-			AckWait: 10 * time.Minute,
-			// We do not set MaxDeliver here because we want to handle retries manually
-			// to implement custom backoff and failure handling logic.
-		})
+	cons, err := createOrUpdateConsumer(ctx, js, ConsumerConfig(durable, subjects, ackWait), logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create or update consumer: %w", err)
 	}
 
 	subscriber := &NatsSubscriber{
-		cons:           cons,
-		handlers:       handlers,
-		failureHandler: failureHandler,
-		retryConfig:    retryConfig,
-		logger:         logger.With("component", "subscriber"),
+		cons:               cons,
+		inProgressInterval: ackWait / inProgressSignalsPerAckWait,
+		handlers:           handlers,
+		failureHandler:     failureHandler,
+		retryConfig:        retryConfig,
+		logger:             logger.With("component", "subscriber"),
 	}
 
 	return subscriber, nil
 }
 
+// createOrUpdateConsumer creates or updates the consumer of the work queue stream.
+// When the stream is replicated, the request fails while the stream has no leader,
+// e.g. during the leader election following the loss of a NATS server, so it is retried until a leader is elected.
+func createOrUpdateConsumer(ctx context.Context, js jetstream.JetStream, config jetstream.ConsumerConfig, logger *slog.Logger) (jetstream.Consumer, error) {
+	var cons jetstream.Consumer
+	err := retry.Do(
+		func() error {
+			attemptCtx, cancel := context.WithTimeout(ctx, consumerSetupAttemptTimeout)
+			defer cancel()
+
+			var err error
+			cons, err = js.CreateOrUpdateConsumer(attemptCtx, StreamName, config)
+			return err
+		},
+		retry.Context(ctx),
+		retry.Attempts(10),
+		retry.Delay(time.Second),
+		retry.DelayType(retry.BackOffDelay),
+		retry.MaxDelay(10*time.Second),
+		retry.LastErrorOnly(true),
+		retry.RetryIf(isStreamLeaderUnavailable),
+		retry.OnRetry(func(n uint, err error) {
+			logger.InfoContext(ctx, "Stream leader not available, retrying consumer setup", "attempt", n+1, "error", err)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return cons, nil
+}
+
+// isStreamLeaderUnavailable returns true if the error is caused by a stream without leader.
+// The JetStream API answers with 503 Service Unavailable, or does not answer at all, until a leader is elected.
+func isStreamLeaderUnavailable(err error) bool {
+	var apiErr *jetstream.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusServiceUnavailable
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, nats.ErrTimeout) ||
+		errors.Is(err, nats.ErrNoResponders)
+}
+
 // Run starts the subscriber and processes messages in a loop until the context is done.
+// The subscribers sharing the durable consumer, e.g. the replicas of the worker, pull one message at a time,
+// so that the messages are spread across them and each message is processed by a single subscriber.
 func (s *NatsSubscriber) Run(ctx context.Context) error {
 	consContext, err := s.cons.Consume(
 		func(msg jetstream.Msg) {
@@ -107,7 +184,22 @@ func (s *NatsSubscriber) Run(ctx context.Context) error {
 				return
 			}
 
-			if err := s.handleMessage(ctx, msg.Subject(), msg); err != nil {
+			stopInProgress := s.keepInProgress(ctx, msg)
+			err = s.handleMessage(ctx, msg.Subject(), msg)
+			stopInProgress()
+			if err != nil {
+				if ctx.Err() != nil {
+					// The subscriber is shutting down: the message is delivered again right away,
+					// to another subscriber if any, without waiting for the backoff of the failures.
+					s.logger.InfoContext(ctx, "Message interrupted by the shutdown, releasing it", "subject", msg.Subject())
+					if err := msg.Nak(); err != nil {
+						s.logger.ErrorContext(ctx, "Failed to nak message",
+							"subject", msg.Subject(),
+							"error", err,
+						)
+					}
+					return
+				}
 				s.handleFailure(ctx, msg, metadata, err)
 				return
 			}
@@ -119,6 +211,7 @@ func (s *NatsSubscriber) Run(ctx context.Context) error {
 				)
 			}
 		},
+		jetstream.PullMaxMessages(1),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to start consuming: %w", err)
@@ -134,6 +227,38 @@ func (s *NatsSubscriber) Run(ctx context.Context) error {
 	return nil
 }
 
+// keepInProgress signals the server that the message is still processed every inProgressInterval,
+// so that the long scans are not delivered again while they are running, until the returned function is called.
+func (s *NatsSubscriber) keepInProgress(ctx context.Context, msg jetstream.Msg) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(s.inProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := msg.InProgress(); err != nil {
+					s.logger.WarnContext(ctx, "Failed to signal message in progress",
+						"subject", msg.Subject(),
+						"error", err,
+					)
+				}
+			}
+		}
+	}()
+
+	// The signals are stopped before the message is acknowledged.
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
 // handleMessage handles individual message processing.
 func (s *NatsSubscriber) handleMessage(ctx context.Context, subject string, message Message) error {
 	handler, found := s.handlers[subject]
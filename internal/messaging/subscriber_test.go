@@ -5,12 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	natstest "github.com/nats-io/nats-server/v2/test"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -56,7 +59,7 @@ func TestSubscriber_Run(t *testing.T) {
 	require.NoError(t, err)
 	defer nc.Close()
 
-	publisher, err := NewNatsPublisher(t.Context(), nc, slog.Default())
+	publisher, err := NewNatsPublisher(t.Context(), nc, 1, slog.Default())
 	require.NoError(t, err)
 
 	processed := make(chan Message, 1)
@@ -71,7 +74,7 @@ func TestSubscriber_Run(t *testing.T) {
 	handlers := HandlerRegistry{
 		testSubscriberSubject: testHandler,
 	}
-	subscriber, err := NewNatsSubscriber(t.Context(), nc, "test-durable", handlers, nil, nil, slog.Default())
+	subscriber, err := NewNatsSubscriber(t.Context(), nc, "test-durable", DefaultAckWait, handlers, nil, nil, slog.Default())
 	require.NoError(t, err, "failed to create subscriber")
 
 	ctx, cancel := context.WithCancel(t.Context())
@@ -99,6 +102,153 @@ func TestSubscriber_Run(t *testing.T) {
 	require.NoError(t, err, "unexpected subscriber error")
 }
 
+func TestSubscriber_Run_SharedConsumer(t *testing.T) {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1 // Use a random port
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	ns := natstest.RunServer(&opts)
+	defer ns.Shutdown()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	publisher, err := NewNatsPublisher(t.Context(), nc, 1, slog.Default())
+	require.NoError(t, err)
+
+	const (
+		workers  = 3
+		messages = 30
+	)
+
+	var (
+		mu          sync.Mutex
+		deliveries  = map[string]int{}
+		perWorker   = make([]int, workers)
+		allReceived = make(chan struct{})
+	)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	subscribers := make([]*NatsSubscriber, workers)
+	for worker := range workers {
+		// Each worker has its own connection, as the replicas of the worker.
+		workerConn, err := nats.Connect(ns.ClientURL())
+		require.NoError(t, err)
+		defer workerConn.Close()
+
+		handlers := HandlerRegistry{
+			testSubscriberSubject: &testHandler{handleFunc: func(m Message) error {
+				time.Sleep(10 * time.Millisecond)
+
+				mu.Lock()
+				defer mu.Unlock()
+				deliveries[string(m.Data())]++
+				perWorker[worker]++
+				if len(deliveries) == messages {
+					select {
+					case <-allReceived:
+					default:
+						close(allReceived)
+					}
+				}
+
+				return nil
+			}},
+		}
+		subscribers[worker], err = NewNatsSubscriber(t.Context(), workerConn, "test-durable", DefaultAckWait, handlers, nil, nil, slog.Default())
+		require.NoError(t, err, "failed to create subscriber")
+	}
+
+	// The batch is published before the workers start, so that the first worker could pull all of it.
+	for i := range messages {
+		id := fmt.Sprintf("message-%d", i)
+		require.NoError(t, publisher.Publish(t.Context(), testSubscriberSubject, id, []byte(id)))
+	}
+
+	var wg sync.WaitGroup
+	for _, subscriber := range subscribers {
+		wg.Go(func() {
+			assert.NoError(t, subscriber.Run(ctx))
+		})
+	}
+
+	select {
+	case <-allReceived:
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "timed out waiting for the messages to be processed")
+	}
+	// Leave the time to a duplicate delivery to be processed.
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for id, count := range deliveries {
+		assert.Equal(t, 1, count, "message %s processed more than once", id)
+	}
+	for worker, count := range perWorker {
+		assert.Positive(t, count, "worker %d processed no message", worker)
+	}
+}
+
+func TestSubscriber_Run_LongProcessing(t *testing.T) {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1 // Use a random port
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	ns := natstest.RunServer(&opts)
+	defer ns.Shutdown()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	publisher, err := NewNatsPublisher(t.Context(), nc, 1, slog.Default())
+	require.NoError(t, err)
+
+	var deliveries atomic.Int32
+	processed := make(chan struct{})
+	handlers := HandlerRegistry{
+		testSubscriberSubject: &testHandler{handleFunc: func(Message) error {
+			if deliveries.Add(1) == 1 {
+				// The processing takes longer than the AckWait.
+				time.Sleep(2500 * time.Millisecond)
+				close(processed)
+			}
+
+			return nil
+		}},
+	}
+	// Two subscribers, so that a message delivered again would be processed while the first one is running.
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	var wg sync.WaitGroup
+	for range 2 {
+		subscriber, err := NewNatsSubscriber(t.Context(), nc, "test-durable-long", time.Second, handlers, nil, nil, slog.Default())
+		require.NoError(t, err, "failed to create subscriber")
+		wg.Go(func() {
+			assert.NoError(t, subscriber.Run(ctx))
+		})
+	}
+
+	require.NoError(t, publisher.Publish(t.Context(), testSubscriberSubject, "id", []byte("long")))
+
+	select {
+	case <-processed:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for message to be processed")
+	}
+	time.Sleep(1500 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	assert.Equal(t, int32(1), deliveries.Load(), "the message is not delivered again while it is processed")
+}
+
 func TestSubscriber_Run_WithRetry(t *testing.T) {
 	opts := natstest.DefaultTestOptions
 	opts.Port = -1 // Use a random port
@@ -111,7 +261,7 @@ func TestSubscriber_Run_WithRetry(t *testing.T) {
 	require.NoError(t, err)
 	defer nc.Close()
 
-	publisher, err := NewNatsPublisher(t.Context(), nc, slog.Default())
+	publisher, err := NewNatsPublisher(t.Context(), nc, 1, slog.Default())
 	require.NoError(t, err)
 
 	var attemptCount atomic.Int32
@@ -137,7 +287,7 @@ func TestSubscriber_Run_WithRetry(t *testing.T) {
 		Jitter:      0,
 		MaxAttempts: 5,
 	}
-	subscriber, err := NewNatsSubscriber(t.Context(), nc, "test-durable-retry", handlers, nil, retryConfig, slog.Default())
+	subscriber, err := NewNatsSubscriber(t.Context(), nc, "test-durable-retry", DefaultAckWait, handlers, nil, retryConfig, slog.Default())
 	require.NoError(t, err, "failed to create subscriber")
 
 	ctx, cancel := context.WithCancel(t.Context())
@@ -177,7 +327,7 @@ func TestSubscriber_Run_WithMaxRetriesExceeded(t *testing.T) {
 	require.NoError(t, err)
 	defer nc.Close()
 
-	publisher, err := NewNatsPublisher(t.Context(), nc, slog.Default())
+	publisher, err := NewNatsPublisher(t.Context(), nc, 1, slog.Default())
 	require.NoError(t, err)
 
 	var attemptCount atomic.Int32
@@ -210,7 +360,7 @@ func TestSubscriber_Run_WithMaxRetriesExceeded(t *testing.T) {
 		Jitter:      0,
 		MaxAttempts: 5,
 	}
-	subscriber, err := NewNatsSubscriber(t.Context(), nc, "test-durable-max-retry", handlers, testFailureHandler, retryConfig, slog.Default())
+	subscriber, err := NewNatsSubscriber(t.Context(), nc, "test-durable-max-retry", DefaultAckWait, handlers, testFailureHandler, retryConfig, slog.Default())
 	require.NoError(t, err, "failed to create subscriber")
 
 	ctx, cancel := context.WithCancel(t.Context())
@@ -300,3 +450,52 @@ func TestSubscriber_handleMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestIsStreamLeaderUnavailable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "no leader",
+			err:      &jetstream.APIError{Code: 503, ErrorCode: 10008, Description: "JetStream system temporarily unavailable"},
+			expected: true,
+		},
+		{
+			name:     "request timeout",
+			err:      fmt.Errorf("failed to create consumer: %w", context.DeadlineExceeded),
+			expected: true,
+		},
+		{
+			name:     "no responders",
+			err:      nats.ErrNoResponders,
+			expected: true,
+		},
+		{
+			name:     "stream not found",
+			err:      jetstream.ErrStreamNotFound,
+			expected: false,
+		},
+		{
+			name:     "invalid consumer configuration",
+			err:      jetstream.ErrOverlappingFilterSubjects,
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, isStreamLeaderUnavailable(test.err))
+		})
+	}
+}
+
+func TestConsumerConfig(t *testing.T) {
+	config := ConsumerConfig("worker", []string{"sbomscanner.sbom.scan", "sbomscanner.catalog.create"}, time.Minute)
+
+	assert.Equal(t, "worker", config.Durable)
+	// The subjects are sorted, so that the consumer is not updated when the handlers are listed in another order.
+	assert.Equal(t, []string{"sbomscanner.catalog.create", "sbomscanner.sbom.scan"}, config.FilterSubjects)
+	assert.Equal(t, time.Minute, config.AckWait)
+}
@@ -0,0 +1,2 @@
+// Package platform contains the OCI platforms supported by SBOMscanner.
+package platform
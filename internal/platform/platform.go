@@ -0,0 +1,131 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// The supported platforms were defined based on the OCI image spec:
+// https://specs.opencontainers.org/image-spec/image-index/
+
+// ValidPlatforms maps OS to supported architectures
+var ValidPlatforms = map[string][]string{
+	"aix":       {"ppc64"},
+	"android":   {"386", "amd64", "arm", "arm64"},
+	"darwin":    {"amd64", "arm64"},
+	"dragonfly": {"amd64"},
+	"freebsd":   {"386", "amd64", "arm"},
+	"illumos":   {"amd64"},
+	"ios":       {"arm64"},
+	"js":        {"wasm"},
+	"linux":     {"386", "amd64", "arm", "arm64", "loong64", "mips", "mipsle", "mips64", "mips64le", "ppc64", "ppc64le", "riscv64", "s390x"},
+	"netbsd":    {"386", "amd64", "arm"},
+	"openbsd":   {"386", "amd64", "arm", "arm64"},
+	"plan9":     {"386", "amd64", "arm"},
+	"solaris":   {"amd64"},
+	"wasip1":    {"wasm"},
+	"windows":   {"386", "amd64", "arm", "arm64"},
+}
+
+// AllowedVariants maps architecture to valid variants
+var AllowedVariants = map[string][]string{
+	"arm":   {"v6", "v7", "v8"},
+	"arm64": {"v8"},
+}
+
+// KnownOSFeatures maps OS to the os.features values of the OCI image spec.
+// The images can declare other values, which are stored but cannot be selected.
+var KnownOSFeatures = map[string][]string{
+	"windows": {"win32k"},
+}
+
+// defaultVariants maps architecture to the variant assumed when none is provided.
+// The default variant is omitted from the normalized platform.
+var defaultVariants = map[string]string{
+	"arm64": "v8",
+}
+
+// Normalize returns the canonical lowercase os/arch[/variant] form of the given platform.
+// The default variant of the architecture is dropped, eg. "linux/ARM64/v8" becomes "linux/arm64".
+// The OS version suffix (eg. "windows/amd64:10.0.17763") is preserved.
+func Normalize(platform string) string {
+	platform, osVersion, hasOSVersion := strings.Cut(strings.TrimSpace(platform), ":")
+
+	parts := strings.Split(strings.ToLower(platform), "/")
+	if len(parts) == 3 && defaultVariants[parts[1]] == parts[2] {
+		parts = parts[:2]
+	}
+
+	normalized := strings.Join(parts, "/")
+	if hasOSVersion {
+		normalized += ":" + osVersion
+	}
+
+	return normalized
+}
+
+// osVersionPattern matches the OS versions of the OCI image spec, eg. the "10.0.17763.1234" build of Windows Server 2019.
+var osVersionPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*$`)
+
+// ValidateOSVersion checks that the OS version is made of dot-separated numbers.
+func ValidateOSVersion(osVersion string) error {
+	if !osVersionPattern.MatchString(osVersion) {
+		return errors.New("the OS version must be made of dot-separated numbers, eg. 10.0.17763")
+	}
+
+	return nil
+}
+
+// SplitOSVersion returns the platform without its OS version suffix, and the OS version,
+// eg. "windows/amd64" and "10.0.17763" for "windows/amd64:10.0.17763".
+func SplitOSVersion(platform string) (string, string) {
+	platform, osVersion, _ := strings.Cut(platform, ":")
+	return platform, osVersion
+}
+
+// MatchOSVersion checks if the OS version matches the expected one, empty matching all the versions.
+// The expected version matches its own builds, eg. "10.0.17763" matches "10.0.17763.1234".
+func MatchOSVersion(osVersion, expected string) bool {
+	return expected == "" || osVersion == expected || strings.HasPrefix(osVersion, expected+".")
+}
+
+// ValidateOSFeatures checks that the OS features are known for the OS and are not repeated.
+func ValidateOSFeatures(os string, osFeatures []string) error {
+	for i, feature := range osFeatures {
+		if !slices.Contains(KnownOSFeatures[os], feature) {
+			return fmt.Errorf("unsupported OS feature %s for OS %s (allowed: %v)", feature, os, KnownOSFeatures[os])
+		}
+		if slices.Contains(osFeatures[:i], feature) {
+			return fmt.Errorf("duplicate OS feature %s", feature)
+		}
+	}
+
+	return nil
+}
+
+// NormalizeOSFeatures returns the OS features sorted and without duplicates,
+// so that the images declaring the same features in another order have the same platform identity.
+func NormalizeOSFeatures(osFeatures []string) []string {
+	if len(osFeatures) == 0 {
+		return nil
+	}
+
+	normalized := slices.Clone(osFeatures)
+	slices.Sort(normalized)
+
+	return slices.Compact(normalized)
+}
+
+// MatchOSFeatures checks if the OS features include all the expected ones, none matching all the features.
+func MatchOSFeatures(osFeatures, expected []string) bool {
+	for _, feature := range expected {
+		if !slices.Contains(osFeatures, feature) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,103 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		platform string
+		want     string
+	}{
+		{platform: "linux/amd64", want: "linux/amd64"},
+		{platform: "Linux/AMD64", want: "linux/amd64"},
+		{platform: " linux/amd64 ", want: "linux/amd64"},
+		{platform: "linux/ARM64", want: "linux/arm64"},
+		{platform: "linux/arm64/v8", want: "linux/arm64"},
+		{platform: "linux/arm/V7", want: "linux/arm/v7"},
+		{platform: "linux/arm", want: "linux/arm"},
+		{platform: "windows/amd64:10.0.17763.1234", want: "windows/amd64:10.0.17763.1234"},
+		{platform: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			assert.Equal(t, tt.want, Normalize(tt.platform))
+		})
+	}
+}
+
+func TestValidateOSVersion(t *testing.T) {
+	for _, osVersion := range []string{"10.0.17763", "10.0.17763.1234", "10"} {
+		assert.NoError(t, ValidateOSVersion(osVersion), osVersion)
+	}
+	for _, osVersion := range []string{"", "10.0.", ".10", "ltsc2019", "10.0.17763 "} {
+		assert.Error(t, ValidateOSVersion(osVersion), osVersion)
+	}
+}
+
+func TestSplitOSVersion(t *testing.T) {
+	platform, osVersion := SplitOSVersion("windows/amd64:10.0.17763")
+	assert.Equal(t, "windows/amd64", platform)
+	assert.Equal(t, "10.0.17763", osVersion)
+
+	platform, osVersion = SplitOSVersion("linux/arm/v7")
+	assert.Equal(t, "linux/arm/v7", platform)
+	assert.Empty(t, osVersion)
+}
+
+func TestMatchOSVersion(t *testing.T) {
+	tests := []struct {
+		osVersion string
+		expected  string
+		want      bool
+	}{
+		{osVersion: "10.0.17763.1234", expected: "", want: true},
+		{osVersion: "", expected: "", want: true},
+		{osVersion: "10.0.17763.1234", expected: "10.0.17763", want: true},
+		{osVersion: "10.0.17763", expected: "10.0.17763", want: true},
+		{osVersion: "10.0.20348.1234", expected: "10.0.17763", want: false},
+		{osVersion: "10.0.177630", expected: "10.0.17763", want: false},
+		{osVersion: "", expected: "10.0.17763", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.osVersion+"~"+tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.want, MatchOSVersion(tt.osVersion, tt.expected))
+		})
+	}
+}
+
+func TestValidateOSFeatures(t *testing.T) {
+	assert.NoError(t, ValidateOSFeatures("windows", nil))
+	assert.NoError(t, ValidateOSFeatures("windows", []string{"win32k"}))
+	assert.EqualError(t, ValidateOSFeatures("linux", []string{"win32k"}), "unsupported OS feature win32k for OS linux (allowed: [])")
+	assert.EqualError(t, ValidateOSFeatures("windows", []string{"sse4"}), "unsupported OS feature sse4 for OS windows (allowed: [win32k])")
+	assert.EqualError(t, ValidateOSFeatures("windows", []string{"win32k", "win32k"}), "duplicate OS feature win32k")
+}
+
+func TestNormalizeOSFeatures(t *testing.T) {
+	assert.Nil(t, NormalizeOSFeatures(nil))
+	assert.Nil(t, NormalizeOSFeatures([]string{}))
+	assert.Equal(t, []string{"a", "win32k"}, NormalizeOSFeatures([]string{"win32k", "a", "win32k"}))
+}
+
+func TestMatchOSFeatures(t *testing.T) {
+	tests := []struct {
+		name       string
+		osFeatures []string
+		expected   []string
+		want       bool
+	}{
+		{name: "no expected features", osFeatures: []string{"win32k"}, want: true},
+		{name: "no features", want: true},
+		{name: "expected feature", osFeatures: []string{"win32k"}, expected: []string{"win32k"}, want: true},
+		{name: "extra feature", osFeatures: []string{"a", "win32k"}, expected: []string{"win32k"}, want: true},
+		{name: "missing feature", expected: []string{"win32k"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MatchOSFeatures(tt.osFeatures, tt.expected))
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package revocation
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+const (
+	// defaultCacheTTL is how long the OCSP responses and the CRLs without a next update are cached.
+	defaultCacheTTL = time.Hour
+	// requestTimeout is the timeout of the requests to the OCSP responders and the CRL distribution points.
+	requestTimeout = 10 * time.Second
+	// maxResponseSize is the maximum size of the OCSP responses and the CRLs.
+	maxResponseSize = 10 << 20
+)
+
+// ErrCertificateRevoked is returned when a certificate of the chain is revoked.
+var ErrCertificateRevoked = errors.New("certificate revoked")
+
+// status is the revocation status of a certificate.
+type status int
+
+const (
+	statusUnknown status = iota
+	statusGood
+	statusRevoked
+)
+
+// cachedStatus is an OCSP status cached until it expires.
+type cachedStatus struct {
+	status    status
+	expiresAt time.Time
+}
+
+// cachedCRL is the set of the serial numbers revoked by a CRL, cached until it expires.
+type cachedCRL struct {
+	issuer    *x509.Certificate
+	revoked   map[string]struct{}
+	expiresAt time.Time
+}
+
+// Checker checks the revocation status of the certificates presented by the TLS servers.
+// The OCSP stapled responses are used when available, otherwise the OCSP responders
+// and then the CRL distribution points of the certificates are queried.
+// The OCSP responses and the CRLs are cached until their next update,
+// so that the responders are not queried on every connection.
+// Certificates whose status cannot be determined are accepted.
+type Checker struct {
+	httpClient *http.Client
+	logger     *slog.Logger
+
+	mu        sync.Mutex
+	ocspCache map[string]cachedStatus
+	crlCache  map[string]cachedCRL
+}
+
+// NewChecker creates a new Checker.
+func NewChecker(logger *slog.Logger) *Checker {
+	return &Checker{
+		httpClient: &http.Client{Timeout: requestTimeout},
+		logger:     logger.With("component", "revocation_checker"),
+		ocspCache:  make(map[string]cachedStatus),
+		crlCache:   make(map[string]cachedCRL),
+	}
+}
+
+// VerifyConnection returns a function to be used as tls.Config.VerifyConnection,
+// checking the revocation status of the verified certificate chain according to the mode,
+// one of the RevocationCheck values of the Registry.
+func (c *Checker) VerifyConnection(mode string) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		err := c.checkConnectionState(ctx, state)
+		if errors.Is(err, ErrCertificateRevoked) && mode != v1alpha1.RevocationCheckEnforce {
+			c.logger.WarnContext(ctx, "Revoked certificate accepted", "server", state.ServerName, "error", err)
+			return nil
+		}
+
+		return err
+	}
+}
+
+// checkConnectionState checks every certificate of the verified chain against its issuer.
+func (c *Checker) checkConnectionState(ctx context.Context, state tls.ConnectionState) error {
+	if len(state.VerifiedChains) == 0 {
+		return errors.New("cannot check revocation of an unverified certificate chain")
+	}
+
+	chain := state.VerifiedChains[0]
+	for i := 0; i < len(chain)-1; i++ {
+		certificate, issuer := chain[i], chain[i+1]
+
+		var stapled []byte
+		if i == 0 {
+			stapled = state.OCSPResponse
+		}
+
+		if c.status(ctx, certificate, issuer, stapled) == statusRevoked {
+			return fmt.Errorf("%w: %s (serial %s)", ErrCertificateRevoked, certificate.Subject, certificate.SerialNumber)
+		}
+	}
+
+	return nil
+}
+
+// status returns the revocation status of the certificate,
+// trying the stapled OCSP response, the OCSP responders and the CRLs in order.
+func (c *Checker) status(ctx context.Context, certificate, issuer *x509.Certificate, stapled []byte) status {
+	logger := c.logger.With("subject", certificate.Subject.String(), "serial", certificate.SerialNumber.String())
+	key := ocspCacheKey(certificate, issuer)
+
+	if len(stapled) > 0 {
+		response, err := ocsp.ParseResponseForCert(stapled, certificate, issuer)
+		if err == nil {
+			return c.cacheOCSPResponse(key, response)
+		}
+		logger.DebugContext(ctx, "Ignoring invalid stapled OCSP response", "error", err)
+	}
+
+	if status, ok := c.cachedOCSPStatus(key); ok {
+		return status
+	}
+
+	for _, server := range certificate.OCSPServer {
+		response, err := c.queryOCSP(ctx, server, certificate, issuer)
+		if err != nil {
+			logger.DebugContext(ctx, "OCSP query failed", "server", server, "error", err)
+			continue
+		}
+		if status := c.cacheOCSPResponse(key, response); status != statusUnknown {
+			return status
+		}
+	}
+
+	for _, url := range certificate.CRLDistributionPoints {
+		crl, err := c.crl(ctx, url, issuer)
+		if err != nil {
+			logger.DebugContext(ctx, "CRL check failed", "url", url, "error", err)
+			continue
+		}
+		if _, revoked := crl.revoked[certificate.SerialNumber.String()]; revoked {
+			return statusRevoked
+		}
+		return statusGood
+	}
+
+	logger.DebugContext(ctx, "Cannot determine the revocation status of the certificate")
+	return statusUnknown
+}
+
+func (c *Checker) queryOCSP(ctx context.Context, server string, certificate, issuer *x509.Certificate) (*ocsp.Response, error) {
+	request, err := ocsp.CreateRequest(certificate, issuer, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create OCSP request: %w", err)
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(request))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create HTTP request: %w", err)
+	}
+	httpRequest.Header.Set("Content-Type", "application/ocsp-request")
+
+	body, err := c.do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := ocsp.ParseResponseForCert(body, certificate, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse OCSP response: %w", err)
+	}
+
+	return response, nil
+}
+
+// crl returns the CRL downloaded from the URL, verified against the issuer.
+func (c *Checker) crl(ctx context.Context, url string, issuer *x509.Certificate) (cachedCRL, error) {
+	c.mu.Lock()
+	cached, ok := c.crlCache[url]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) && cached.issuer.Equal(issuer) {
+		return cached, nil
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return cachedCRL{}, fmt.Errorf("cannot create HTTP request: %w", err)
+	}
+
+	body, err := c.do(httpRequest)
+	if err != nil {
+		return cachedCRL{}, err
+	}
+
+	list, err := x509.ParseRevocationList(body)
+	if err != nil {
+		return cachedCRL{}, fmt.Errorf("cannot parse CRL: %w", err)
+	}
+	if err := list.CheckSignatureFrom(issuer); err != nil {
+		return cachedCRL{}, fmt.Errorf("invalid CRL signature: %w", err)
+	}
+
+	cached = cachedCRL{
+		issuer:    issuer,
+		revoked:   make(map[string]struct{}, len(list.RevokedCertificateEntries)),
+		expiresAt: expiresAt(list.NextUpdate),
+	}
+	for _, entry := range list.RevokedCertificateEntries {
+		cached.revoked[entry.SerialNumber.String()] = struct{}{}
+	}
+
+	c.mu.Lock()
+	c.crlCache[url] = cached
+	c.mu.Unlock()
+
+	return cached, nil
+}
+
+func (c *Checker) do(request *http.Request) ([]byte, error) {
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", request.URL, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request to %s failed with status %d", request.URL, response.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response from %s: %w", request.URL, err)
+	}
+
+	return body, nil
+}
+
+func (c *Checker) cachedOCSPStatus(key string) (status, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.ocspCache[key]
+	if !ok || time.Now().After(cached.expiresAt) {
+		return statusUnknown, false
+	}
+
+	return cached.status, true
+}
+
+// cacheOCSPResponse caches the status of the OCSP response until its next update and returns it.
+// Unknown statuses are not cached, so that the other sources are tried.
+func (c *Checker) cacheOCSPResponse(key string, response *ocsp.Response) status {
+	var s status
+	switch response.Status {
+	case ocsp.Good:
+		s = statusGood
+	case ocsp.Revoked:
+		s = statusRevoked
+	default:
+		return statusUnknown
+	}
+
+	c.mu.Lock()
+	c.ocspCache[key] = cachedStatus{status: s, expiresAt: expiresAt(response.NextUpdate)}
+	c.mu.Unlock()
+
+	return s
+}
+
+// ocspCacheKey identifies a certificate by its issuer and its serial number.
+func ocspCacheKey(certificate, issuer *x509.Certificate) string {
+	return fmt.Sprintf("%x/%s", sha256.Sum256(issuer.RawSubjectPublicKeyInfo), certificate.SerialNumber)
+}
+
+// expiresAt returns the time a response with the given next update expires from the cache.
+func expiresAt(nextUpdate time.Time) time.Time {
+	if nextUpdate.IsZero() {
+		return time.Now().Add(defaultCacheTTL)
+	}
+
+	return nextUpdate
+}
@@ -0,0 +1,267 @@
+package revocation
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+type testCA struct {
+	certificate *x509.Certificate
+	key         crypto.Signer
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{certificate: certificate, key: key}
+}
+
+func (ca *testCA) issue(t *testing.T, serial int64, ocspServer, crlURL string) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "registry.test.local"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if ocspServer != "" {
+		template.OCSPServer = []string{ocspServer}
+	}
+	if crlURL != "" {
+		template.CRLDistributionPoints = []string{crlURL}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return certificate
+}
+
+func (ca *testCA) ocspResponse(t *testing.T, certificate *x509.Certificate, status int) []byte {
+	t.Helper()
+
+	response, err := ocsp.CreateResponse(ca.certificate, ca.certificate, ocsp.Response{
+		Status:       status,
+		SerialNumber: certificate.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Minute),
+		NextUpdate:   time.Now().Add(time.Hour),
+		RevokedAt:    time.Now().Add(-time.Minute),
+	}, ca.key)
+	require.NoError(t, err)
+
+	return response
+}
+
+// newOCSPResponder returns an OCSP responder answering with the given status and counting the requests.
+func (ca *testCA) newOCSPResponder(t *testing.T, status int, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+		request, err := ocsp.ParseRequest(body)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		response, err := ocsp.CreateResponse(ca.certificate, ca.certificate, ocsp.Response{
+			Status:       status,
+			SerialNumber: request.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, ca.key)
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, _ = w.Write(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// newCRLServer returns a CRL distribution point revoking the given serial numbers.
+func (ca *testCA) newCRLServer(t *testing.T, revokedSerials ...int64) *httptest.Server {
+	t.Helper()
+
+	template := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Minute),
+		NextUpdate: time.Now().Add(time.Hour),
+	}
+	for _, serial := range revokedSerials {
+		template.RevokedCertificateEntries = append(template.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: time.Now().Add(-time.Minute),
+		})
+	}
+	crl, err := x509.CreateRevocationList(rand.Reader, template, ca.certificate, ca.key)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(crl)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func connectionState(certificate *x509.Certificate, ca *testCA, stapled []byte) tls.ConnectionState {
+	return tls.ConnectionState{
+		ServerName:     "registry.test.local",
+		VerifiedChains: [][]*x509.Certificate{{certificate, ca.certificate}},
+		OCSPResponse:   stapled,
+	}
+}
+
+func TestChecker_OCSP(t *testing.T) {
+	ca := newTestCA(t)
+
+	tests := []struct {
+		name          string
+		ocspStatus    int
+		mode          string
+		expectedError error
+	}{
+		{
+			name:       "good certificate",
+			ocspStatus: ocsp.Good,
+			mode:       v1alpha1.RevocationCheckEnforce,
+		},
+		{
+			name:          "revoked certificate is rejected in Enforce mode",
+			ocspStatus:    ocsp.Revoked,
+			mode:          v1alpha1.RevocationCheckEnforce,
+			expectedError: ErrCertificateRevoked,
+		},
+		{
+			name:       "revoked certificate is accepted in Warn mode",
+			ocspStatus: ocsp.Revoked,
+			mode:       v1alpha1.RevocationCheckWarn,
+		},
+		{
+			name:       "unknown certificate is accepted",
+			ocspStatus: ocsp.Unknown,
+			mode:       v1alpha1.RevocationCheckEnforce,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests atomic.Int32
+			responder := ca.newOCSPResponder(t, test.ocspStatus, &requests)
+			certificate := ca.issue(t, 2, responder.URL, "")
+
+			checker := NewChecker(slog.Default())
+			err := checker.VerifyConnection(test.mode)(connectionState(certificate, ca, nil))
+			if test.expectedError != nil {
+				require.ErrorIs(t, err, test.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, int32(1), requests.Load())
+		})
+	}
+}
+
+func TestChecker_OCSPCache(t *testing.T) {
+	ca := newTestCA(t)
+
+	var requests atomic.Int32
+	responder := ca.newOCSPResponder(t, ocsp.Revoked, &requests)
+	certificate := ca.issue(t, 2, responder.URL, "")
+
+	checker := NewChecker(slog.Default())
+	verify := checker.VerifyConnection(v1alpha1.RevocationCheckEnforce)
+	for range 3 {
+		require.ErrorIs(t, verify(connectionState(certificate, ca, nil)), ErrCertificateRevoked)
+	}
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestChecker_StapledOCSP(t *testing.T) {
+	ca := newTestCA(t)
+
+	var requests atomic.Int32
+	responder := ca.newOCSPResponder(t, ocsp.Good, &requests)
+	certificate := ca.issue(t, 2, responder.URL, "")
+	stapled := ca.ocspResponse(t, certificate, ocsp.Revoked)
+
+	checker := NewChecker(slog.Default())
+	err := checker.VerifyConnection(v1alpha1.RevocationCheckEnforce)(connectionState(certificate, ca, stapled))
+	require.ErrorIs(t, err, ErrCertificateRevoked)
+	assert.Equal(t, int32(0), requests.Load(), "the OCSP responder must not be queried when a response is stapled")
+}
+
+func TestChecker_CRL(t *testing.T) {
+	ca := newTestCA(t)
+	crlServer := ca.newCRLServer(t, 3)
+
+	checker := NewChecker(slog.Default())
+	verify := checker.VerifyConnection(v1alpha1.RevocationCheckEnforce)
+
+	require.NoError(t, verify(connectionState(ca.issue(t, 2, "", crlServer.URL), ca, nil)))
+	require.ErrorIs(t, verify(connectionState(ca.issue(t, 3, "", crlServer.URL), ca, nil)), ErrCertificateRevoked)
+}
+
+func TestChecker_CRLWithInvalidSignature(t *testing.T) {
+	ca := newTestCA(t)
+	otherCA := newTestCA(t)
+	crlServer := otherCA.newCRLServer(t, 2)
+
+	checker := NewChecker(slog.Default())
+	err := checker.VerifyConnection(v1alpha1.RevocationCheckEnforce)(connectionState(ca.issue(t, 2, "", crlServer.URL), ca, nil))
+	require.NoError(t, err, "CRLs not signed by the issuer must be ignored")
+}
+
+func TestChecker_UnverifiedChain(t *testing.T) {
+	checker := NewChecker(slog.Default())
+	err := checker.VerifyConnection(v1alpha1.RevocationCheckEnforce)(tls.ConnectionState{})
+	require.ErrorContains(t, err, "unverified certificate chain")
+}
@@ -0,0 +1,2 @@
+// Package revocation checks the revocation status of TLS certificates using OCSP and CRLs.
+package revocation
@@ -0,0 +1,3 @@
+// Package sbomimport parses and validates SBOM documents generated outside of SBOMscanner,
+// so that they can be imported as SBOM resources without scanning the images again.
+package sbomimport
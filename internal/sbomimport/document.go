@@ -0,0 +1,255 @@
+package sbomimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/google/go-containerregistry/pkg/name"
+	spdxjson "github.com/spdx/tools-golang/json"
+	"github.com/spdx/tools-golang/spdx"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// Format is the format of an SBOM document.
+type Format string
+
+const (
+	FormatSPDXJSON      Format = "spdx-json"
+	FormatCycloneDXJSON Format = "cyclonedx-json"
+)
+
+// Document is a validated SBOM document.
+type Document struct {
+	// Format is the format of the original document.
+	Format Format
+	// Name is the name of the document, or of the component it describes.
+	Name string
+	// SPDX is the document in SPDX JSON format.
+	// CycloneDX documents are converted to SPDX, since it is the format stored in the SBOM resources.
+	SPDX []byte
+}
+
+// documentHeader contains the fields used to detect the format of a document.
+type documentHeader struct {
+	SPDXVersion string `json:"spdxVersion"`
+	BOMFormat   string `json:"bomFormat"`
+}
+
+// Parse validates the given SBOM document and returns it in SPDX JSON format.
+// Malformed documents are rejected with the line and the context of the error.
+func Parse(data []byte) (*Document, error) {
+	var header documentHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, withErrorContext(data, err)
+	}
+
+	switch {
+	case strings.HasPrefix(header.SPDXVersion, "SPDX-"):
+		return parseSPDX(data)
+	case header.BOMFormat == "CycloneDX":
+		return parseCycloneDX(data)
+	default:
+		return nil, errors.New("unknown SBOM format: expected an SPDX or CycloneDX JSON document")
+	}
+}
+
+func parseSPDX(data []byte) (*Document, error) {
+	doc, err := spdxjson.Read(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SPDX document: %w", withErrorContext(data, err))
+	}
+	if doc.SPDXIdentifier != "DOCUMENT" {
+		return nil, fmt.Errorf("invalid SPDX document: unexpected SPDXID %q, expected \"SPDXRef-DOCUMENT\"", doc.SPDXIdentifier)
+	}
+	if doc.DocumentName == "" {
+		return nil, errors.New("invalid SPDX document: missing name")
+	}
+	if len(doc.Packages) == 0 {
+		return nil, errors.New("invalid SPDX document: no packages found")
+	}
+
+	return &Document{
+		Format: FormatSPDXJSON,
+		Name:   doc.DocumentName,
+		SPDX:   data,
+	}, nil
+}
+
+func parseCycloneDX(data []byte) (*Document, error) {
+	bom := cdx.NewBOM()
+	if err := cdx.NewBOMDecoder(bytes.NewReader(data), cdx.BOMFileFormatJSON).Decode(bom); err != nil {
+		return nil, fmt.Errorf("invalid CycloneDX document: %w", withErrorContext(data, err))
+	}
+	if bom.Metadata == nil || bom.Metadata.Component == nil || bom.Metadata.Component.Name == "" {
+		return nil, errors.New("invalid CycloneDX document: missing metadata component")
+	}
+	if bom.Components == nil || len(*bom.Components) == 0 {
+		return nil, errors.New("invalid CycloneDX document: no components found")
+	}
+
+	spdxBytes, err := cycloneDXToSPDX(bom)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert CycloneDX document to SPDX: %w", err)
+	}
+
+	return &Document{
+		Format: FormatCycloneDXJSON,
+		Name:   bom.Metadata.Component.Name,
+		SPDX:   spdxBytes,
+	}, nil
+}
+
+// cycloneDXToSPDX converts the components of a CycloneDX document to SPDX packages.
+// The package URLs are preserved, since they are used to detect the vulnerabilities.
+func cycloneDXToSPDX(bom *cdx.BOM) ([]byte, error) {
+	root := bom.Metadata.Component
+	namespace := strings.TrimPrefix(bom.SerialNumber, "urn:uuid:")
+	if namespace == "" {
+		namespace = root.Name + "-" + root.Version
+	}
+
+	doc := &spdx.Document{
+		SPDXVersion:       spdx.Version,
+		DataLicense:       spdx.DataLicense,
+		SPDXIdentifier:    "DOCUMENT",
+		DocumentName:      root.Name,
+		DocumentNamespace: "https://sbomscanner.kubewarden.io/imported/" + namespace,
+		CreationInfo: &spdx.CreationInfo{
+			Creators: []common.Creator{{CreatorType: "Tool", Creator: "sbomscanner"}},
+			Created:  time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+
+	rootPackage := cycloneDXComponentToSPDXPackage(root, "Root")
+	doc.Packages = append(doc.Packages, rootPackage)
+	doc.Relationships = append(doc.Relationships, &spdx.Relationship{
+		RefA:         common.MakeDocElementID("", "DOCUMENT"),
+		RefB:         common.MakeDocElementID("", string(rootPackage.PackageSPDXIdentifier)),
+		Relationship: common.TypeRelationshipDescribe,
+	})
+
+	for i, component := range flattenCycloneDXComponents(*bom.Components) {
+		pkg := cycloneDXComponentToSPDXPackage(&component, fmt.Sprintf("Package-%d", i))
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, &spdx.Relationship{
+			RefA:         common.MakeDocElementID("", string(rootPackage.PackageSPDXIdentifier)),
+			RefB:         common.MakeDocElementID("", string(pkg.PackageSPDXIdentifier)),
+			Relationship: common.TypeRelationshipContains,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := spdxjson.Write(doc, &buf); err != nil {
+		return nil, fmt.Errorf("cannot write SPDX document: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func cycloneDXComponentToSPDXPackage(component *cdx.Component, id string) *spdx.Package {
+	pkg := &spdx.Package{
+		PackageName:             component.Name,
+		PackageSPDXIdentifier:   common.ElementID(id),
+		PackageVersion:          component.Version,
+		PackageDownloadLocation: "NOASSERTION",
+	}
+	if component.PackageURL != "" {
+		pkg.PackageExternalReferences = []*spdx.PackageExternalReference{
+			{
+				Category: common.CategoryPackageManager,
+				RefType:  common.TypePackageManagerPURL,
+				Locator:  component.PackageURL,
+			},
+		}
+	}
+
+	return pkg
+}
+
+// flattenCycloneDXComponents returns the given components and all their nested components.
+func flattenCycloneDXComponents(components []cdx.Component) []cdx.Component {
+	var flattened []cdx.Component
+	for _, component := range components {
+		flattened = append(flattened, component)
+		if component.Components != nil {
+			flattened = append(flattened, flattenCycloneDXComponents(*component.Components)...)
+		}
+	}
+
+	return flattened
+}
+
+// ImageMetadataFromName derives the image metadata from a document name referencing an image by digest,
+// e.g. "ghcr.io/kubewarden/sbomscanner/test-assets/golang:1.12-alpine@sha256:...".
+func ImageMetadataFromName(documentName string) (storagev1alpha1.ImageMetadata, error) {
+	ref, err := name.ParseReference(documentName)
+	if err != nil {
+		return storagev1alpha1.ImageMetadata{}, fmt.Errorf("cannot parse image reference %q: %w", documentName, err)
+	}
+
+	metadata := storagev1alpha1.ImageMetadata{
+		RegistryURI: ref.Context().RegistryStr(),
+		Repository:  ref.Context().RepositoryStr(),
+	}
+
+	// name.ParseReference drops the tag of "repo:tag@sha256:..." references
+	// and defaults it to "latest" when missing, so the tag is extracted from the name.
+	repository, digest, _ := strings.Cut(documentName, "@")
+	metadata.Digest = digest
+	if separator := strings.LastIndex(repository, ":"); separator > strings.LastIndex(repository, "/") {
+		metadata.Tag = repository[separator+1:]
+	}
+
+	return metadata, nil
+}
+
+// withErrorContext adds the line, the column and the content of the line
+// to the JSON errors reporting the offset where they occurred.
+func withErrorContext(data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+
+	line, column, content := locate(data, offset)
+	return fmt.Errorf("line %d, column %d: %w\n  %s", line, column, err, content)
+}
+
+// locate returns the line and the column of the given offset, and the content of its line.
+func locate(data []byte, offset int64) (int, int, string) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	column := int(offset) - lineStart + 1
+
+	lineEnd := bytes.IndexByte(data[lineStart:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(data) - lineStart
+	}
+
+	const maxContentLength = 120
+	content := strings.TrimSpace(string(data[lineStart : lineStart+lineEnd]))
+	if len(content) > maxContentLength {
+		content = content[:maxContentLength] + "..."
+	}
+
+	return line, column, content
+}
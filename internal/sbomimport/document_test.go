@@ -0,0 +1,183 @@
+package sbomimport
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+const spdxDocument = `{
+  "spdxVersion": "SPDX-2.3",
+  "dataLicense": "CC0-1.0",
+  "SPDXID": "SPDXRef-DOCUMENT",
+  "name": "ghcr.io/kubewarden/sbomscanner/test-assets/golang:1.12-alpine@sha256:1782cafde43390b032f960c0fad3def745fac18994ced169003cb56e9a93c028",
+  "documentNamespace": "http://aquasecurity.github.io/trivy/container_image/golang",
+  "creationInfo": {
+    "creators": ["Tool: trivy-0.67.2"],
+    "created": "2025-01-01T00:00:00Z"
+  },
+  "packages": [
+    {
+      "name": "musl",
+      "SPDXID": "SPDXRef-Package-1",
+      "versionInfo": "1.1.20-r4",
+      "downloadLocation": "NONE",
+      "externalRefs": [
+        {
+          "referenceCategory": "PACKAGE-MANAGER",
+          "referenceType": "purl",
+          "referenceLocator": "pkg:apk/alpine/musl@1.1.20-r4"
+        }
+      ]
+    }
+  ]
+}`
+
+const cycloneDXDocument = `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.6",
+  "serialNumber": "urn:uuid:3e671687-395b-41f5-a30f-a58921a69b79",
+  "version": 1,
+  "metadata": {
+    "component": {
+      "type": "container",
+      "name": "ghcr.io/kubewarden/sbomscanner/test-assets/golang@sha256:1782cafde43390b032f960c0fad3def745fac18994ced169003cb56e9a93c028"
+    }
+  },
+  "components": [
+    {
+      "type": "library",
+      "name": "musl",
+      "version": "1.1.20-r4",
+      "purl": "pkg:apk/alpine/musl@1.1.20-r4",
+      "components": [
+        {
+          "type": "library",
+          "name": "musl-utils",
+          "version": "1.1.20-r4",
+          "purl": "pkg:apk/alpine/musl-utils@1.1.20-r4"
+        }
+      ]
+    }
+  ]
+}`
+
+func TestParse_SPDX(t *testing.T) {
+	document, err := Parse([]byte(spdxDocument))
+	require.NoError(t, err)
+
+	assert.Equal(t, FormatSPDXJSON, document.Format)
+	assert.Equal(t, "ghcr.io/kubewarden/sbomscanner/test-assets/golang:1.12-alpine@sha256:1782cafde43390b032f960c0fad3def745fac18994ced169003cb56e9a93c028", document.Name)
+	assert.JSONEq(t, spdxDocument, string(document.SPDX))
+}
+
+func TestParse_CycloneDX(t *testing.T) {
+	document, err := Parse([]byte(cycloneDXDocument))
+	require.NoError(t, err)
+
+	assert.Equal(t, FormatCycloneDXJSON, document.Format)
+
+	// The converted document must be a valid SPDX document
+	converted, err := Parse(document.SPDX)
+	require.NoError(t, err)
+	assert.Equal(t, FormatSPDXJSON, converted.Format)
+
+	var spdx struct {
+		Packages []struct {
+			Name         string `json:"name"`
+			ExternalRefs []struct {
+				ReferenceLocator string `json:"referenceLocator"`
+			} `json:"externalRefs"`
+		} `json:"packages"`
+	}
+	require.NoError(t, json.Unmarshal(document.SPDX, &spdx))
+	require.Len(t, spdx.Packages, 3)
+
+	var purls []string
+	for _, pkg := range spdx.Packages {
+		for _, ref := range pkg.ExternalRefs {
+			purls = append(purls, ref.ReferenceLocator)
+		}
+	}
+	assert.ElementsMatch(t, []string{"pkg:apk/alpine/musl@1.1.20-r4", "pkg:apk/alpine/musl-utils@1.1.20-r4"}, purls)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		document      string
+		expectedError string
+	}{
+		{
+			name:          "malformed JSON",
+			document:      "{\n  \"spdxVersion\": \"SPDX-2.3\",\n  \"name\" \"missing colon\"\n}",
+			expectedError: "line 3, column",
+		},
+		{
+			name:          "unknown format",
+			document:      `{"foo": "bar"}`,
+			expectedError: "unknown SBOM format",
+		},
+		{
+			name:          "SPDX without packages",
+			document:      `{"spdxVersion": "SPDX-2.3", "SPDXID": "SPDXRef-DOCUMENT", "name": "test", "creationInfo": {"creators": [], "created": ""}}`,
+			expectedError: "no packages found",
+		},
+		{
+			name:          "CycloneDX without metadata component",
+			document:      `{"bomFormat": "CycloneDX", "specVersion": "1.6", "components": []}`,
+			expectedError: "missing metadata component",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(test.document))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.expectedError)
+		})
+	}
+}
+
+func TestImageMetadataFromName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected storagev1alpha1.ImageMetadata
+	}{
+		{
+			name: "ghcr.io/kubewarden/sbomscanner/test-assets/golang:1.12-alpine@sha256:1782cafde43390b032f960c0fad3def745fac18994ced169003cb56e9a93c028",
+			expected: storagev1alpha1.ImageMetadata{
+				RegistryURI: "ghcr.io",
+				Repository:  "kubewarden/sbomscanner/test-assets/golang",
+				Tag:         "1.12-alpine",
+				Digest:      "sha256:1782cafde43390b032f960c0fad3def745fac18994ced169003cb56e9a93c028",
+			},
+		},
+		{
+			name: "ghcr.io/kubewarden/sbomscanner/test-assets/golang@sha256:1782cafde43390b032f960c0fad3def745fac18994ced169003cb56e9a93c028",
+			expected: storagev1alpha1.ImageMetadata{
+				RegistryURI: "ghcr.io",
+				Repository:  "kubewarden/sbomscanner/test-assets/golang",
+				Digest:      "sha256:1782cafde43390b032f960c0fad3def745fac18994ced169003cb56e9a93c028",
+			},
+		},
+		{
+			name: "registry.local:5000/golang:1.12-alpine",
+			expected: storagev1alpha1.ImageMetadata{
+				RegistryURI: "registry.local:5000",
+				Repository:  "golang",
+				Tag:         "1.12-alpine",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			metadata, err := ImageMetadataFromName(test.name)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, metadata)
+		})
+	}
+}
@@ -0,0 +1,2 @@
+// Package scanwindow evaluates the scan windows of the registries, restricting their scans to some times of the day.
+package scanwindow
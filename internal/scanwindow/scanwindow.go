@@ -0,0 +1,121 @@
+package scanwindow
+
+import (
+	"fmt"
+	"slices"
+	"time"
+	// The time zone database is embedded, since the container images do not provide it.
+	_ "time/tzdata"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+// timeOfDayLayout is the layout of the start and the end of the windows.
+const timeOfDayLayout = "15:04"
+
+// weekdays maps the names of the days of the windows to their weekday.
+var weekdays = map[string]time.Weekday{
+	time.Sunday.String():    time.Sunday,
+	time.Monday.String():    time.Monday,
+	time.Tuesday.String():   time.Tuesday,
+	time.Wednesday.String(): time.Wednesday,
+	time.Thursday.String():  time.Thursday,
+	time.Friday.String():    time.Friday,
+	time.Saturday.String():  time.Saturday,
+}
+
+// window is a parsed ScanWindow.
+type window struct {
+	start    time.Time
+	end      time.Time
+	days     []time.Weekday
+	location *time.Location
+}
+
+// Validate returns an error if the window is not valid.
+func Validate(scanWindow v1alpha1.ScanWindow) error {
+	_, err := parse(scanWindow)
+	return err
+}
+
+// Check reports whether the registry with the given windows can be scanned at the given time.
+// When it cannot, the time the next window opens is returned.
+// The registries without windows can be scanned at any time.
+func Check(scanWindows []v1alpha1.ScanWindow, now time.Time) (bool, time.Time, error) {
+	var next time.Time
+	for i, scanWindow := range scanWindows {
+		w, err := parse(scanWindow)
+		if err != nil {
+			return false, time.Time{}, fmt.Errorf("invalid scan window %d: %w", i, err)
+		}
+
+		// The windows may overlap, the registry can be scanned while any of them is open.
+		open, opens := w.check(now)
+		if open {
+			return true, time.Time{}, nil
+		}
+		if next.IsZero() || opens.Before(next) {
+			next = opens
+		}
+	}
+
+	return len(scanWindows) == 0, next, nil
+}
+
+// parse parses the start, the end, the days and the time zone of the window.
+func parse(scanWindow v1alpha1.ScanWindow) (*window, error) {
+	start, err := time.Parse(timeOfDayLayout, scanWindow.Start)
+	if err != nil {
+		return nil, fmt.Errorf("start %q must be a time of day in the HH:MM format", scanWindow.Start)
+	}
+	end, err := time.Parse(timeOfDayLayout, scanWindow.End)
+	if err != nil {
+		return nil, fmt.Errorf("end %q must be a time of day in the HH:MM format", scanWindow.End)
+	}
+
+	w := &window{start: start, end: end, location: time.UTC}
+	if scanWindow.TimeZone != "" {
+		if w.location, err = time.LoadLocation(scanWindow.TimeZone); err != nil {
+			return nil, fmt.Errorf("timeZone %q is not a known IANA time zone", scanWindow.TimeZone)
+		}
+	}
+	for _, day := range scanWindow.Days {
+		weekday, ok := weekdays[day]
+		if !ok {
+			return nil, fmt.Errorf("day %q must be a day of the week, e.g. Monday", day)
+		}
+		w.days = append(w.days, weekday)
+	}
+
+	return w, nil
+}
+
+// check reports whether the window is open at the given time, or returns when it opens next.
+func (w *window) check(now time.Time) (bool, time.Time) {
+	local := now.In(w.location)
+	// The window opened the day before may still be open when it spans midnight,
+	// and a window opening on some days only opens again within a week.
+	for offset := -1; offset <= 7; offset++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, w.location)
+		if len(w.days) > 0 && !slices.Contains(w.days, day.Weekday()) {
+			continue
+		}
+
+		// The times are computed from the date, so that the windows follow the daylight saving time changes.
+		opens := time.Date(day.Year(), day.Month(), day.Day(), w.start.Hour(), w.start.Minute(), 0, 0, w.location)
+		closes := time.Date(day.Year(), day.Month(), day.Day(), w.end.Hour(), w.end.Minute(), 0, 0, w.location)
+		if !closes.After(opens) {
+			closes = time.Date(day.Year(), day.Month(), day.Day()+1, w.end.Hour(), w.end.Minute(), 0, 0, w.location)
+		}
+
+		if !now.Before(opens) && now.Before(closes) {
+			return true, time.Time{}
+		}
+		if opens.After(now) {
+			return false, opens
+		}
+	}
+
+	// Not reached: the window opens at least once a week.
+	return false, time.Time{}
+}
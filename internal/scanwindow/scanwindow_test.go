@@ -0,0 +1,169 @@
+package scanwindow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		window    v1alpha1.ScanWindow
+		wantError string
+	}{
+		{
+			name:   "valid",
+			window: v1alpha1.ScanWindow{Start: "22:00", End: "04:00", Days: []string{"Saturday", "Sunday"}, TimeZone: "Europe/Berlin"},
+		},
+		{
+			name:      "invalid start",
+			window:    v1alpha1.ScanWindow{Start: "25:00", End: "04:00"},
+			wantError: `start "25:00" must be a time of day in the HH:MM format`,
+		},
+		{
+			name:      "missing end",
+			window:    v1alpha1.ScanWindow{Start: "00:00"},
+			wantError: `end "" must be a time of day in the HH:MM format`,
+		},
+		{
+			name:      "unknown time zone",
+			window:    v1alpha1.ScanWindow{Start: "00:00", End: "06:00", TimeZone: "Mars/Olympus_Mons"},
+			wantError: `timeZone "Mars/Olympus_Mons" is not a known IANA time zone`,
+		},
+		{
+			name:      "unknown day",
+			window:    v1alpha1.ScanWindow{Start: "00:00", End: "06:00", Days: []string{"sat"}},
+			wantError: `day "sat" must be a day of the week, e.g. Monday`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Validate(test.window)
+			if test.wantError == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, test.wantError)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		windows   []v1alpha1.ScanWindow
+		now       time.Time
+		wantOpen  bool
+		wantOpens time.Time
+	}{
+		{
+			name:     "no window",
+			now:      time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC),
+			wantOpen: true,
+		},
+		{
+			name:     "inside the window",
+			windows:  []v1alpha1.ScanWindow{{Start: "00:00", End: "06:00"}},
+			now:      time.Date(2025, 6, 2, 5, 59, 0, 0, time.UTC),
+			wantOpen: true,
+		},
+		{
+			name:      "after the window",
+			windows:   []v1alpha1.ScanWindow{{Start: "00:00", End: "06:00"}},
+			now:       time.Date(2025, 6, 2, 6, 0, 0, 0, time.UTC),
+			wantOpens: time.Date(2025, 6, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "window spanning midnight, opened the day before",
+			windows:  []v1alpha1.ScanWindow{{Start: "22:00", End: "04:00"}},
+			now:      time.Date(2025, 6, 2, 3, 0, 0, 0, time.UTC),
+			wantOpen: true,
+		},
+		{
+			name:      "before the window spanning midnight",
+			windows:   []v1alpha1.ScanWindow{{Start: "22:00", End: "04:00"}},
+			now:       time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC),
+			wantOpens: time.Date(2025, 6, 2, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "whole day window",
+			windows:  []v1alpha1.ScanWindow{{Start: "00:00", End: "00:00", Days: []string{"Monday"}}},
+			now:      time.Date(2025, 6, 2, 23, 59, 0, 0, time.UTC),
+			wantOpen: true,
+		},
+		{
+			// 2025-06-04 is a Wednesday.
+			name:      "window on the weekend",
+			windows:   []v1alpha1.ScanWindow{{Start: "08:00", End: "20:00", Days: []string{"Saturday", "Sunday"}}},
+			now:       time.Date(2025, 6, 4, 12, 0, 0, 0, time.UTC),
+			wantOpens: time.Date(2025, 6, 7, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			// 2025-06-09 is a Monday: the window opened on Sunday evening is still open.
+			name:     "window spanning midnight on the weekend",
+			windows:  []v1alpha1.ScanWindow{{Start: "22:00", End: "02:00", Days: []string{"Sunday"}}},
+			now:      time.Date(2025, 6, 9, 1, 0, 0, 0, time.UTC),
+			wantOpen: true,
+		},
+		{
+			name:     "time zone",
+			windows:  []v1alpha1.ScanWindow{{Start: "00:00", End: "06:00", TimeZone: "Europe/Berlin"}},
+			now:      time.Date(2025, 6, 2, 23, 0, 0, 0, time.UTC),
+			wantOpen: true,
+		},
+		{
+			name:      "time zone after the window",
+			windows:   []v1alpha1.ScanWindow{{Start: "00:00", End: "06:00", TimeZone: "Europe/Berlin"}},
+			now:       time.Date(2025, 6, 2, 5, 0, 0, 0, time.UTC),
+			wantOpens: time.Date(2025, 6, 3, 0, 0, 0, 0, berlin),
+		},
+		{
+			// The clocks of Berlin moved from 02:00 to 03:00 on 2025-03-30.
+			name:      "daylight saving time change",
+			windows:   []v1alpha1.ScanWindow{{Start: "01:00", End: "05:00", TimeZone: "Europe/Berlin"}},
+			now:       time.Date(2025, 3, 30, 3, 0, 0, 0, time.UTC),
+			wantOpens: time.Date(2025, 3, 31, 1, 0, 0, 0, berlin),
+		},
+		{
+			name: "overlapping windows",
+			windows: []v1alpha1.ScanWindow{
+				{Start: "00:00", End: "06:00"},
+				{Start: "04:00", End: "08:00"},
+			},
+			now:      time.Date(2025, 6, 2, 7, 0, 0, 0, time.UTC),
+			wantOpen: true,
+		},
+		{
+			name: "earliest of the next windows",
+			windows: []v1alpha1.ScanWindow{
+				{Start: "22:00", End: "23:00"},
+				{Start: "20:00", End: "21:00"},
+			},
+			now:       time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC),
+			wantOpens: time.Date(2025, 6, 2, 20, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			open, opens, err := Check(test.windows, test.now)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantOpen, open)
+			assert.True(t, test.wantOpens.Equal(opens), "expected the window to open at %s, got %s", test.wantOpens, opens)
+		})
+	}
+}
+
+func TestCheckInvalidWindow(t *testing.T) {
+	_, _, err := Check([]v1alpha1.ScanWindow{{Start: "00:00", End: "06:00"}, {Start: "noon", End: "13:00"}}, time.Now())
+	require.EqualError(t, err, `invalid scan window 1: start "noon" must be a time of day in the HH:MM format`)
+}
@@ -0,0 +1,255 @@
+// Package smoketest runs an end-to-end scan of a single image, without the Registry resources
+// nor the messaging, to validate that a worker can pull images, generate their SBOM
+// and match it against the vulnerability database.
+package smoketest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	trivyCommands "github.com/aquasecurity/trivy/pkg/commands"
+	trivyTypes "github.com/aquasecurity/trivy/pkg/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	cranev1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	vulnReport "github.com/kubewarden/sbomscanner/internal/handlers/vulnerabilityreport"
+	"github.com/kubewarden/sbomscanner/internal/vulndb"
+)
+
+// DefaultImage is the image scanned by default: a small public image, mirrored on a registry without pull rate limits.
+const DefaultImage = "public.ecr.aws/docker/library/alpine:3.20"
+
+// Options configures the smoke test.
+type Options struct {
+	// Image is the reference of the scanned image.
+	Image string
+	// Platform is the platform of the scanned image when it is a multi-architecture image.
+	Platform *cranev1.Platform
+	// WorkDir is the directory of the temporary files and of the image layers cache.
+	WorkDir string
+	// TrivyJavaDBRepository is the OCI repository of the Java database.
+	TrivyJavaDBRepository string
+	// VulnDB configures the vulnerability database.
+	VulnDB vulndb.Options
+}
+
+// Validate checks that the options are consistent.
+func (o Options) Validate() error {
+	if o.Image == "" {
+		return errors.New("the image is required")
+	}
+	if _, err := name.ParseReference(o.Image); err != nil {
+		return fmt.Errorf("invalid image %q: %w", o.Image, err)
+	}
+	if o.WorkDir == "" {
+		return errors.New("the work directory is required")
+	}
+
+	return o.VulnDB.Validate()
+}
+
+// Summary is the outcome of a successful smoke test.
+type Summary struct {
+	// Image is the reference of the scanned image, by digest.
+	Image string `json:"image"`
+	// Layers is the number of layers of the image.
+	Layers int `json:"layers"`
+	// Packages is the number of packages of the SBOM.
+	Packages int `json:"packages"`
+	// Vulnerabilities counts the vulnerabilities found by severity.
+	Vulnerabilities storagev1alpha1.Summary `json:"vulnerabilities"`
+	// VulnerabilityDB is the vulnerability database used by the scan.
+	VulnerabilityDB *storagev1alpha1.VulnerabilityDB `json:"vulnerabilityDB,omitempty"`
+	// Duration is the duration of the smoke test.
+	Duration string `json:"duration"`
+}
+
+// Run pulls the image, generates its SBOM and scans it for vulnerabilities.
+// Any failure of the pipeline is returned as an error.
+func Run(ctx context.Context, options Options, logger *slog.Logger) (*Summary, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	ref, layers, err := resolveImage(ctx, options.Image, options.Platform, []remote.Option{remote.WithAuthFromKeychain(authn.DefaultKeychain)})
+	if err != nil {
+		return nil, err
+	}
+	logger.InfoContext(ctx, "Image resolved", "image", ref.String(), "layers", layers)
+
+	spdx, err := generateSPDX(ctx, ref, options)
+	if err != nil {
+		return nil, err
+	}
+	packages, err := countPackages(spdx)
+	if err != nil {
+		return nil, err
+	}
+	logger.InfoContext(ctx, "SBOM generated", "image", ref.String(), "packages", packages)
+
+	vulnDB, err := vulndb.New(options.VulnDB, logger)
+	if err != nil {
+		return nil, fmt.Errorf("cannot set up the vulnerability database: %w", err)
+	}
+	report, err := scanSPDX(ctx, spdx, vulnDB, options)
+	if err != nil {
+		return nil, err
+	}
+	results, err := vulnReport.NewFromTrivyResults(*report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert from trivy results: %w", err)
+	}
+	vulnerabilityDB, err := vulnDB.Info()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the vulnerability database info: %w", err)
+	}
+	logger.InfoContext(ctx, "SBOM scanned", "image", ref.String())
+
+	return &Summary{
+		Image:           ref.String(),
+		Layers:          layers,
+		Packages:        packages,
+		Vulnerabilities: vulnReport.ComputeSummary(results),
+		VulnerabilityDB: vulnerabilityDB,
+		Duration:        time.Since(start).Round(time.Second).String(),
+	}, nil
+}
+
+// resolveImage returns the digest reference of the image, of the given platform for the multi-architecture images,
+// and its number of layers.
+func resolveImage(ctx context.Context, image string, platform *cranev1.Platform, options []remote.Option) (name.Digest, int, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return name.Digest{}, 0, fmt.Errorf("invalid image %q: %w", image, err)
+	}
+
+	options = append(options, remote.WithContext(ctx))
+	if platform != nil {
+		options = append(options, remote.WithPlatform(*platform))
+	}
+	img, err := remote.Image(ref, options...)
+	if err != nil {
+		return name.Digest{}, 0, fmt.Errorf("cannot pull image %s: %w", ref, err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return name.Digest{}, 0, fmt.Errorf("cannot get digest of image %s: %w", ref, err)
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return name.Digest{}, 0, fmt.Errorf("cannot read manifest of image %s: %w", ref, err)
+	}
+
+	return ref.Context().Digest(digest.String()), len(manifest.Layers), nil
+}
+
+// generateSPDX generates the SPDX JSON SBOM of the image with Trivy, the same way the workers do.
+func generateSPDX(ctx context.Context, ref name.Digest, options Options) ([]byte, error) {
+	sbomFile, err := os.CreateTemp(options.WorkDir, "trivy.sbom.*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary SBOM file: %w", err)
+	}
+	defer os.Remove(sbomFile.Name())
+	defer sbomFile.Close()
+
+	app := trivyCommands.NewApp()
+	app.SetArgs([]string{
+		"image",
+		"--skip-version-check",
+		"--disable-telemetry",
+		"--quiet",
+		"--cache-dir", options.WorkDir,
+		"--format", "spdx-json",
+		"--skip-db-update",
+		"--java-db-repository", options.TrivyJavaDBRepository,
+		"--output", sbomFile.Name(),
+		ref.String(),
+	})
+	if err = app.ExecuteContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to generate the SBOM of image %s: %w", ref, err)
+	}
+
+	spdx, err := io.ReadAll(sbomFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SBOM output: %w", err)
+	}
+
+	return spdx, nil
+}
+
+// scanSPDX scans the SBOM for vulnerabilities with Trivy, the same way the workers do.
+func scanSPDX(ctx context.Context, spdx []byte, vulnDB *vulndb.DB, options Options) (*trivyTypes.Report, error) {
+	sbomFile, err := os.CreateTemp(options.WorkDir, "trivy.sbom.*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary SBOM file: %w", err)
+	}
+	defer os.Remove(sbomFile.Name())
+	defer sbomFile.Close()
+	if _, err = sbomFile.Write(spdx); err != nil {
+		return nil, fmt.Errorf("failed to write SBOM file: %w", err)
+	}
+
+	reportFile, err := os.CreateTemp(options.WorkDir, "trivy.report.*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary report file: %w", err)
+	}
+	defer os.Remove(reportFile.Name())
+	defer reportFile.Close()
+
+	args := []string{
+		"sbom",
+		"--skip-version-check",
+		"--disable-telemetry",
+		"--quiet",
+		"--format", "json",
+		"--java-db-repository", options.TrivyJavaDBRepository,
+		"--output", reportFile.Name(),
+	}
+	args = append(args, vulnDB.TrivyArgs()...)
+	args = append(args, sbomFile.Name())
+
+	vulnDB.RLock()
+	defer vulnDB.RUnlock()
+	app := trivyCommands.NewApp()
+	app.SetArgs(args)
+	if err = app.ExecuteContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to scan the SBOM: %w", err)
+	}
+
+	reportBytes, err := io.ReadAll(reportFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the scan report: %w", err)
+	}
+	report := &trivyTypes.Report{}
+	if err = json.Unmarshal(reportBytes, report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the scan report: %w", err)
+	}
+
+	return report, nil
+}
+
+// countPackages returns the number of packages of the SPDX JSON document.
+// An SBOM without any package means the image was not analyzed.
+func countPackages(spdx []byte) (int, error) {
+	var document struct {
+		Packages []json.RawMessage `json:"packages"`
+	}
+	if err := json.Unmarshal(spdx, &document); err != nil {
+		return 0, fmt.Errorf("invalid SBOM: %w", err)
+	}
+	if len(document.Packages) == 0 {
+		return 0, errors.New("the SBOM does not contain any package")
+	}
+
+	return len(document.Packages), nil
+}
@@ -0,0 +1,145 @@
+package smoketest
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	cranev1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/kubewarden/sbomscanner/internal/vulndb"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	validOptions := Options{
+		Image:   DefaultImage,
+		WorkDir: t.TempDir(),
+		VulnDB: vulndb.Options{
+			Repository: "public.ecr.aws/aquasecurity/trivy-db",
+			CacheDir:   t.TempDir(),
+		},
+	}
+	require.NoError(t, validOptions.Validate())
+
+	tests := []struct {
+		name          string
+		mutate        func(*Options)
+		expectedError string
+	}{
+		{
+			name:          "missing image",
+			mutate:        func(o *Options) { o.Image = "" },
+			expectedError: "the image is required",
+		},
+		{
+			name:          "invalid image",
+			mutate:        func(o *Options) { o.Image = "INVALID:image:ref" },
+			expectedError: "invalid image",
+		},
+		{
+			name:          "missing work directory",
+			mutate:        func(o *Options) { o.WorkDir = "" },
+			expectedError: "the work directory is required",
+		},
+		{
+			name:          "invalid vulnerability database",
+			mutate:        func(o *Options) { o.VulnDB.Repository = "" },
+			expectedError: "the vulnerability database repository is required",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := validOptions
+			test.mutate(&options)
+			require.ErrorContains(t, options.Validate(), test.expectedError)
+		})
+	}
+}
+
+func TestResolveImage(t *testing.T) {
+	server := httptest.NewServer(ggcrregistry.New())
+	defer server.Close()
+
+	repository, err := name.NewRepository(strings.TrimPrefix(server.URL, "http://") + "/test")
+	require.NoError(t, err)
+
+	amd64Image, err := random.Image(64, 2)
+	require.NoError(t, err)
+	arm64Image, err := random.Image(64, 3)
+	require.NoError(t, err)
+	index := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{
+			Add:        amd64Image,
+			Descriptor: cranev1.Descriptor{Platform: &cranev1.Platform{OS: "linux", Architecture: "amd64"}},
+		},
+		mutate.IndexAddendum{
+			Add:        arm64Image,
+			Descriptor: cranev1.Descriptor{Platform: &cranev1.Platform{OS: "linux", Architecture: "arm64"}},
+		},
+	)
+	require.NoError(t, remote.WriteIndex(repository.Tag("multiarch"), index))
+	require.NoError(t, remote.Write(repository.Tag("single"), amd64Image))
+
+	amd64Digest, err := amd64Image.Digest()
+	require.NoError(t, err)
+	arm64Digest, err := arm64Image.Digest()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		image          string
+		platform       *cranev1.Platform
+		expectedDigest cranev1.Hash
+		expectedLayers int
+	}{
+		{
+			name:           "single platform image",
+			image:          repository.Tag("single").String(),
+			expectedDigest: amd64Digest,
+			expectedLayers: 2,
+		},
+		{
+			name:           "multi-architecture image",
+			image:          repository.Tag("multiarch").String(),
+			platform:       &cranev1.Platform{OS: "linux", Architecture: "arm64"},
+			expectedDigest: arm64Digest,
+			expectedLayers: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ref, layers, err := resolveImage(t.Context(), test.image, test.platform, nil)
+			require.NoError(t, err)
+			assert.Equal(t, repository.Digest(test.expectedDigest.String()).String(), ref.String())
+			assert.Equal(t, test.expectedLayers, layers)
+		})
+	}
+
+	t.Run("image not found", func(t *testing.T) {
+		_, _, err := resolveImage(t.Context(), repository.Tag("missing").String(), nil, nil)
+		require.ErrorContains(t, err, "cannot pull image")
+	})
+}
+
+func TestCountPackages(t *testing.T) {
+	packages, err := countPackages([]byte(`{"spdxVersion": "SPDX-2.3", "packages": [{"name": "musl"}, {"name": "busybox"}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, 2, packages)
+
+	_, err = countPackages([]byte(`{"spdxVersion": "SPDX-2.3", "packages": []}`))
+	require.ErrorContains(t, err, "does not contain any package")
+
+	_, err = countPackages([]byte(`not json`))
+	require.ErrorContains(t, err, "invalid SBOM")
+}
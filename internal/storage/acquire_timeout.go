@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/storage"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// defaultRetryAfterSeconds is the delay after which the clients are asked to retry
+// the requests failed because no database connection could be acquired in time,
+// when the request context has no retry-after delay.
+const defaultRetryAfterSeconds = 1
+
+// errAcquireTimeout is the cause of the acquire contexts canceled by the AcquireTimeoutTracer.
+var errAcquireTimeout = errors.New("timed out acquiring a database connection")
+
+var (
+	acquireTimeoutsCounter = metrics.NewCounter(
+		&metrics.CounterOpts{
+			Namespace:      "sbomscanner",
+			Name:           "database_acquire_timeouts_total",
+			Help:           "Number of times a database connection could not be acquired from the pool within the acquire timeout.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+
+	registerAcquireTimeoutMetricsOnce sync.Once
+)
+
+// acquireCancelKey is the context key of the cancel function of the acquire context.
+type acquireCancelKey struct{}
+
+// retryAfterKey is the context key of the retry-after delay of the request.
+type retryAfterKey struct{}
+
+// WithRetryAfter returns a copy of the request context with the delay after which the clients are asked
+// to retry the requests failed because no database connection could be acquired in time.
+func WithRetryAfter(ctx context.Context, retryAfter time.Duration) context.Context {
+	return context.WithValue(ctx, retryAfterKey{}, retryAfter)
+}
+
+// retryAfterSeconds returns the retry-after delay of the request context, rounded up to whole seconds.
+func retryAfterSeconds(ctx context.Context) int32 {
+	retryAfter, ok := ctx.Value(retryAfterKey{}).(time.Duration)
+	if !ok || retryAfter <= 0 {
+		return defaultRetryAfterSeconds
+	}
+
+	return int32((retryAfter + time.Second - 1) / time.Second)
+}
+
+// AcquireTimeoutTracer is a pgxpool.AcquireTracer bounding the time waited for a connection of the pool,
+// so that the requests fail when the pool is saturated instead of waiting for the request timeout.
+// It does not trace the queries.
+type AcquireTimeoutTracer struct {
+	timeout time.Duration
+}
+
+var (
+	_ pgx.QueryTracer       = &AcquireTimeoutTracer{}
+	_ pgxpool.AcquireTracer = &AcquireTimeoutTracer{}
+)
+
+// NewAcquireTimeoutTracer creates a new AcquireTimeoutTracer with the given acquire timeout.
+func NewAcquireTimeoutTracer(timeout time.Duration) *AcquireTimeoutTracer {
+	registerAcquireTimeoutMetricsOnce.Do(func() {
+		legacyregistry.MustRegister(acquireTimeoutsCounter)
+	})
+
+	return &AcquireTimeoutTracer{
+		timeout: timeout,
+	}
+}
+
+// TraceAcquireStart implements the pgxpool.AcquireTracer interface.
+// The returned context is the one used by the pool to acquire the connection.
+func (t *AcquireTimeoutTracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	ctx, cancel := context.WithTimeoutCause(ctx, t.timeout, errAcquireTimeout)
+
+	return context.WithValue(ctx, acquireCancelKey{}, cancel)
+}
+
+// TraceAcquireEnd implements the pgxpool.AcquireTracer interface.
+func (t *AcquireTimeoutTracer) TraceAcquireEnd(ctx context.Context, _ *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	if data.Err != nil && errors.Is(context.Cause(ctx), errAcquireTimeout) {
+		acquireTimeoutsCounter.Inc()
+	}
+
+	if cancel, ok := ctx.Value(acquireCancelKey{}).(context.CancelFunc); ok {
+		cancel()
+	}
+}
+
+// TraceQueryStart implements the pgx.QueryTracer interface, required to be combined with the other tracers.
+func (t *AcquireTimeoutTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return ctx
+}
+
+// TraceQueryEnd implements the pgx.QueryTracer interface.
+func (t *AcquireTimeoutTracer) TraceQueryEnd(_ context.Context, _ *pgx.Conn, _ pgx.TraceQueryEndData) {
+}
+
+// isAcquireTimeout returns true if err is caused by the acquire timeout of the pool.
+// The pool returns the error of the acquire context, which only exceeds its deadline
+// before the one of the request context when the acquire timeout expires.
+func isAcquireTimeout(ctx context.Context, err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil
+}
+
+// newAcquireTimeoutError returns the 503 Service Unavailable error asking the client to retry
+// after the retry-after delay of the request, returned when no database connection could be acquired in time.
+func newAcquireTimeoutError(ctx context.Context) error {
+	return &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusServiceUnavailable,
+		Reason:  metav1.StatusReasonServiceUnavailable,
+		Message: errAcquireTimeout.Error() + ", please retry later",
+		Details: &metav1.StatusDetails{
+			RetryAfterSeconds: retryAfterSeconds(ctx),
+		},
+	}}
+}
+
+// newInternalError returns the storage error of a failed database operation.
+func newInternalError(ctx context.Context, err error) error {
+	if isAcquireTimeout(ctx, err) {
+		return newAcquireTimeoutError(ctx)
+	}
+
+	return storage.NewInternalError(err)
+}
+
+// newAPIInternalError returns the API error of a failed database operation,
+// for the stores not backed by the generic registry.
+func newAPIInternalError(ctx context.Context, err error) error {
+	if isAcquireTimeout(ctx, err) {
+		return newAcquireTimeoutError(ctx)
+	}
+
+	return apierrors.NewInternalError(err)
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apiserver/pkg/storage"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestAcquireTimeoutTracer(t *testing.T) {
+	tracer := NewAcquireTimeoutTracer(10 * time.Millisecond)
+	acquireTimeoutsCounter.Reset()
+
+	// The pool waits for a connection until the acquire context is done.
+	acquireCtx := tracer.TraceAcquireStart(t.Context(), nil, pgxpool.TraceAcquireStartData{})
+	<-acquireCtx.Done()
+	tracer.TraceAcquireEnd(acquireCtx, nil, pgxpool.TraceAcquireEndData{Err: acquireCtx.Err()})
+
+	count, err := testutil.GetCounterMetricValue(acquireTimeoutsCounter)
+	require.NoError(t, err)
+	assert.InDelta(t, 1, count, 0)
+
+	// The connections acquired in time are not counted, and their acquire context is released.
+	acquireCtx = tracer.TraceAcquireStart(t.Context(), nil, pgxpool.TraceAcquireStartData{})
+	tracer.TraceAcquireEnd(acquireCtx, nil, pgxpool.TraceAcquireEndData{})
+	require.ErrorIs(t, acquireCtx.Err(), context.Canceled)
+
+	count, err = testutil.GetCounterMetricValue(acquireTimeoutsCounter)
+	require.NoError(t, err)
+	assert.InDelta(t, 1, count, 0)
+}
+
+func TestNewInternalError(t *testing.T) {
+	err := newInternalError(t.Context(), context.DeadlineExceeded)
+	var statusErr *apierrors.StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, int32(http.StatusServiceUnavailable), statusErr.ErrStatus.Code)
+	assert.Equal(t, int32(defaultRetryAfterSeconds), statusErr.ErrStatus.Details.RetryAfterSeconds)
+
+	// The clients are asked to retry after the delay of the request, rounded up to whole seconds.
+	err = newInternalError(WithRetryAfter(t.Context(), 2500*time.Millisecond), context.DeadlineExceeded)
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, int32(3), statusErr.ErrStatus.Details.RetryAfterSeconds)
+
+	// The deadline of the request is not an acquire timeout.
+	ctx, cancel := context.WithDeadline(t.Context(), time.Now())
+	defer cancel()
+	assert.True(t, storage.IsInternalError(newInternalError(ctx, context.DeadlineExceeded)))
+
+	assert.True(t, storage.IsInternalError(newInternalError(t.Context(), errors.New("connection refused"))))
+	assert.True(t, apierrors.IsInternalError(newAPIInternalError(t.Context(), errors.New("connection refused"))))
+}
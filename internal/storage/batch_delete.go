@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// DefaultDeleteBatchSize is the default maximum number of rows deleted by a single statement.
+	DefaultDeleteBatchSize = 1000
+	// DefaultDeleteBatchPause is the default pause between two delete batches.
+	DefaultDeleteBatchPause = 100 * time.Millisecond
+	// DefaultDeleteBatchWorkers is the default number of batches deleted concurrently.
+	DefaultDeleteBatchWorkers = 1
+)
+
+// DeleteBatchOptions configures the bulk deletes, which are performed in batches
+// so that the locks are held for a bounded time.
+type DeleteBatchOptions struct {
+	// Size is the maximum number of rows deleted by a single statement.
+	Size int
+	// Pause is the time waited between two batches, letting the other queries acquire the locks.
+	Pause time.Duration
+	// Workers is the number of batches deleted concurrently, each in its own transaction.
+	Workers int
+}
+
+// NewDeleteBatchOptions returns the default DeleteBatchOptions.
+func NewDeleteBatchOptions() DeleteBatchOptions {
+	return DeleteBatchOptions{
+		Size:    DefaultDeleteBatchSize,
+		Pause:   DefaultDeleteBatchPause,
+		Workers: DefaultDeleteBatchWorkers,
+	}
+}
+
+// Validate returns an error if the options are not valid.
+func (o DeleteBatchOptions) Validate() error {
+	if o.Size <= 0 {
+		return errors.New("delete batch size must be greater than zero")
+	}
+	if o.Pause < 0 {
+		return errors.New("delete batch pause must not be negative")
+	}
+	if o.Workers <= 0 {
+		return errors.New("delete batch workers must be greater than zero")
+	}
+
+	return nil
+}
+
+// deleteInBatches runs the delete query until it deletes less rows than the batch size,
+// pausing between the batches, and returns the total number of deleted rows.
+// The query must delete at most the number of rows given as its last parameter.
+func deleteInBatches(
+	ctx context.Context,
+	db *pgxpool.Pool,
+	options DeleteBatchOptions,
+	logger *slog.Logger,
+	query string,
+	args ...any,
+) (int64, error) {
+	return deleteReturningInBatches(ctx, db, options, logger, nil, query, args...)
+}
+
+// deleteReturningInBatches runs the delete query in batches like deleteInBatches, on the given number of workers,
+// and calls scan with each row returned by the query. The calls of scan are serialized.
+// Each batch is a transaction of its own. The workers run the query concurrently, so it must select the rows
+// to delete with FOR UPDATE SKIP LOCKED: the workers then delete disjoint rows and never wait for each other.
+// A worker stops once its batch is not full, the remaining rows being locked by the batches of the other workers.
+func deleteReturningInBatches(
+	ctx context.Context,
+	db *pgxpool.Pool,
+	options DeleteBatchOptions,
+	logger *slog.Logger,
+	scan func(pgx.Rows) error,
+	query string,
+	args ...any,
+) (int64, error) {
+	var (
+		total   atomic.Int64
+		batches atomic.Int64
+		scanMu  sync.Mutex
+	)
+	args = append(args, options.Size)
+
+	group, ctx := errgroup.WithContext(ctx)
+	for worker := 1; worker <= max(options.Workers, 1); worker++ {
+		group.Go(func() error {
+			for {
+				batch := batches.Add(1)
+				deleted, err := deleteBatch(ctx, db, &scanMu, scan, query, args...)
+				if err != nil {
+					return fmt.Errorf("failed to delete batch %d: %w", batch, err)
+				}
+
+				deletedTotal := total.Add(deleted)
+				if deleted > 0 {
+					logger.InfoContext(ctx, "Deleted batch", "worker", worker, "batch", batch, "deleted", deleted, "total", deletedTotal)
+				}
+				if deleted < int64(options.Size) {
+					return nil
+				}
+
+				select {
+				case <-ctx.Done():
+					return fmt.Errorf("batch delete interrupted: %w", ctx.Err())
+				case <-time.After(options.Pause):
+				}
+			}
+		})
+	}
+	err := group.Wait()
+
+	return total.Load(), err
+}
+
+// deleteBatch runs the delete query once, calling scan with the returned rows, and returns the number of deleted rows.
+func deleteBatch(ctx context.Context, db *pgxpool.Pool, scanMu *sync.Mutex, scan func(pgx.Rows) error, query string, args ...any) (int64, error) {
+	if scan == nil {
+		result, err := db.Exec(ctx, query, args...)
+		if err != nil {
+			return 0, err
+		}
+
+		return result.RowsAffected(), nil
+	}
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	scanMu.Lock()
+	defer scanMu.Unlock()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return 0, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	return rows.CommandTag().RowsAffected(), nil
+}
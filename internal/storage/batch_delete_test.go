@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteBatchOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       DeleteBatchOptions
+		expectedError string
+	}{
+		{
+			name:    "defaults",
+			options: NewDeleteBatchOptions(),
+		},
+		{
+			name:    "concurrent workers",
+			options: DeleteBatchOptions{Size: 500, Pause: time.Second, Workers: 4},
+		},
+		{
+			name:          "no size",
+			options:       DeleteBatchOptions{Workers: 1},
+			expectedError: "delete batch size must be greater than zero",
+		},
+		{
+			name:          "negative pause",
+			options:       DeleteBatchOptions{Size: 1, Pause: -time.Second, Workers: 1},
+			expectedError: "delete batch pause must not be negative",
+		},
+		{
+			name:          "no workers",
+			options:       DeleteBatchOptions{Size: 1},
+			expectedError: "delete batch workers must be greater than zero",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.options.Validate()
+			if test.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, test.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/registry/rest"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+const (
+	// clusterVulnerabilitySummaryTTL is how long a computed summary is served before being recomputed.
+	clusterVulnerabilitySummaryTTL = 30 * time.Second
+	// clusterVulnerabilitySummaryTopCVEs is the maximum number of CVEs reported in the summary.
+	clusterVulnerabilitySummaryTopCVEs = 10
+)
+
+// clusterVulnerabilitySummarySQL sums the precomputed summaries of all the VulnerabilityReports,
+// so that the potentially large results do not need to be read.
+const clusterVulnerabilitySummarySQL = `
+SELECT
+    COUNT(*),
+    COALESCE(SUM((object->'report'->'summary'->>'critical')::int), 0),
+    COALESCE(SUM((object->'report'->'summary'->>'high')::int), 0),
+    COALESCE(SUM((object->'report'->'summary'->>'medium')::int), 0),
+    COALESCE(SUM((object->'report'->'summary'->>'low')::int), 0),
+    COALESCE(SUM((object->'report'->'summary'->>'unknown')::int), 0),
+    COALESCE(SUM((object->'report'->'summary'->>'suppressed')::int), 0)
+FROM vulnerabilityreports
+`
+
+// clusterTopCVEsSQL returns the non suppressed CVEs affecting the highest number of images.
+// A CVE can have different severities in different images, the highest one is reported:
+// the severities are ranked, since their names do not sort by severity.
+const clusterTopCVEsSQL = `
+SELECT
+    cve,
+    CASE MIN(CASE severity
+        WHEN 'CRITICAL' THEN 0
+        WHEN 'HIGH' THEN 1
+        WHEN 'MEDIUM' THEN 2
+        WHEN 'LOW' THEN 3
+        ELSE 4
+    END)
+        WHEN 0 THEN 'CRITICAL'
+        WHEN 1 THEN 'HIGH'
+        WHEN 2 THEN 'MEDIUM'
+        WHEN 3 THEN 'LOW'
+        ELSE 'UNKNOWN'
+    END AS severity,
+    COUNT(DISTINCT namespace || '/' || report_name) AS images
+FROM vulnerability_findings
+WHERE NOT suppressed
+GROUP BY cve
+ORDER BY images DESC, cve
+LIMIT $1
+`
+
+var (
+	_ rest.Storage              = &clusterVulnerabilitySummaryStore{}
+	_ rest.Getter               = &clusterVulnerabilitySummaryStore{}
+	_ rest.Lister               = &clusterVulnerabilitySummaryStore{}
+	_ rest.Scoper               = &clusterVulnerabilitySummaryStore{}
+	_ rest.SingularNameProvider = &clusterVulnerabilitySummaryStore{}
+	_ rest.ShortNamesProvider   = &clusterVulnerabilitySummaryStore{}
+	_ rest.CategoriesProvider   = &clusterVulnerabilitySummaryStore{}
+)
+
+// clusterVulnerabilitySummaryStore serves the read-only ClusterVulnerabilitySummary resource.
+// The summary is computed from the database and cached for clusterVulnerabilitySummaryTTL,
+// so that frequent requests do not aggregate all the VulnerabilityReports every time.
+// When the query cache is enabled, the summary is cached there instead, and recomputed after the writes.
+type clusterVulnerabilitySummaryStore struct {
+	rest.TableConvertor
+
+	db         *pgxpool.Pool
+	queryCache *QueryCache
+	logger     *slog.Logger
+
+	mu         sync.Mutex
+	summary    *v1alpha1.ClusterVulnerabilitySummary
+	computedAt time.Time
+}
+
+// NewClusterVulnerabilitySummaryStore returns a read-only store for the ClusterVulnerabilitySummary resource.
+func NewClusterVulnerabilitySummaryStore(db *pgxpool.Pool, queryCache *QueryCache, logger *slog.Logger) rest.Storage {
+	return &clusterVulnerabilitySummaryStore{
+		TableConvertor: &clusterVulnerabilitySummaryTableConvertor{},
+		db:             db,
+		queryCache:     queryCache,
+		logger:         logger.With("store", "clustervulnerabilitysummary"),
+	}
+}
+
+func (s *clusterVulnerabilitySummaryStore) New() runtime.Object {
+	return &v1alpha1.ClusterVulnerabilitySummary{}
+}
+
+func (s *clusterVulnerabilitySummaryStore) Destroy() {
+}
+
+func (s *clusterVulnerabilitySummaryStore) NewList() runtime.Object {
+	return &v1alpha1.ClusterVulnerabilitySummaryList{}
+}
+
+func (s *clusterVulnerabilitySummaryStore) NamespaceScoped() bool {
+	return false
+}
+
+func (s *clusterVulnerabilitySummaryStore) GetSingularName() string {
+	return "clustervulnerabilitysummary"
+}
+
+func (s *clusterVulnerabilitySummaryStore) ShortNames() []string {
+	return []string{"vulnsummary"}
+}
+
+func (s *clusterVulnerabilitySummaryStore) Categories() []string {
+	return []string{CategorySBOMScanner}
+}
+
+// Get returns the ClusterVulnerabilitySummary, which only exists with the ClusterVulnerabilitySummaryName name.
+func (s *clusterVulnerabilitySummaryStore) Get(ctx context.Context, name string, _ *metav1.GetOptions) (runtime.Object, error) {
+	if name != v1alpha1.ClusterVulnerabilitySummaryName {
+		return nil, apierrors.NewNotFound(v1alpha1.Resource("clustervulnerabilitysummaries"), name)
+	}
+
+	return s.getSummary(ctx)
+}
+
+// List returns a list containing the single ClusterVulnerabilitySummary.
+func (s *clusterVulnerabilitySummaryStore) List(ctx context.Context, _ *metainternalversion.ListOptions) (runtime.Object, error) {
+	summary, err := s.getSummary(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1alpha1.ClusterVulnerabilitySummaryList{
+		Items: []v1alpha1.ClusterVulnerabilitySummary{*summary},
+	}, nil
+}
+
+// getSummary returns the cached summary, computing it again when it is older than clusterVulnerabilitySummaryTTL.
+func (s *clusterVulnerabilitySummaryStore) getSummary(ctx context.Context) (*v1alpha1.ClusterVulnerabilitySummary, error) {
+	if s.queryCache.Enabled() {
+		return s.getCachedSummary(ctx)
+	}
+
+	s.mu.Lock()
+	if s.summary != nil && time.Since(s.computedAt) < clusterVulnerabilitySummaryTTL {
+		summary := s.summary.DeepCopy()
+		s.mu.Unlock()
+		return summary, nil
+	}
+	s.mu.Unlock()
+
+	// The lock is not held during the queries, so that a slow database does not block all the requests.
+	// The requests arriving while the summary is expired might compute it concurrently.
+	s.logger.DebugContext(ctx, "Computing cluster vulnerability summary")
+
+	summary, err := s.computeSummary(ctx)
+	if err != nil {
+		return nil, newAPIInternalError(ctx, err)
+	}
+
+	s.mu.Lock()
+	s.summary = summary
+	s.computedAt = time.Now()
+	s.mu.Unlock()
+
+	return summary.DeepCopy(), nil
+}
+
+// getCachedSummary returns the summary cached by the query cache.
+func (s *clusterVulnerabilitySummaryStore) getCachedSummary(ctx context.Context) (*v1alpha1.ClusterVulnerabilitySummary, error) {
+	obj, err := s.queryCache.GetOrCompute(ctx, queryClusterVulnerabilitySummary, "", "", func(ctx context.Context) (runtime.Object, error) {
+		s.logger.DebugContext(ctx, "Computing cluster vulnerability summary")
+
+		return s.computeSummary(ctx)
+	})
+	if err != nil {
+		return nil, newAPIInternalError(ctx, err)
+	}
+
+	summary, ok := obj.(*v1alpha1.ClusterVulnerabilitySummary)
+	if !ok {
+		return nil, apierrors.NewInternalError(fmt.Errorf("unexpected type %T", obj))
+	}
+
+	return summary, nil
+}
+
+// computeSummary aggregates the VulnerabilityReports stored in the database.
+func (s *clusterVulnerabilitySummaryStore) computeSummary(ctx context.Context) (*v1alpha1.ClusterVulnerabilitySummary, error) {
+	summary := &v1alpha1.ClusterVulnerabilitySummary{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              v1alpha1.ClusterVulnerabilitySummaryName,
+			CreationTimestamp: metav1.Now(),
+		},
+		TopCVEs: []v1alpha1.CVEOccurrence{},
+	}
+
+	err := s.db.QueryRow(ctx, clusterVulnerabilitySummarySQL).Scan(
+		&summary.ReportsCount,
+		&summary.Summary.Critical,
+		&summary.Summary.High,
+		&summary.Summary.Medium,
+		&summary.Summary.Low,
+		&summary.Summary.Unknown,
+		&summary.Summary.Suppressed,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate vulnerability summaries: %w", err)
+	}
+
+	rows, err := s.db.Query(ctx, clusterTopCVEsSQL, clusterVulnerabilitySummaryTopCVEs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top CVEs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var occurrence v1alpha1.CVEOccurrence
+		if err = rows.Scan(&occurrence.CVE, &occurrence.Severity, &occurrence.Images); err != nil {
+			return nil, fmt.Errorf("failed to scan top CVEs: %w", err)
+		}
+		summary.TopCVEs = append(summary.TopCVEs, occurrence)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read top CVEs: %w", err)
+	}
+
+	return summary, nil
+}
+
+type clusterVulnerabilitySummaryTableConvertor struct{}
+
+func (c *clusterVulnerabilitySummaryTableConvertor) ConvertToTable(_ context.Context, obj runtime.Object, _ runtime.Object) (*metav1.Table, error) {
+	table := &metav1.Table{
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "Name", Type: "string", Description: "Name"},
+			{Name: "Reports", Type: "integer", Description: "Number of vulnerability reports"},
+			{Name: "Vulnerabilities", Type: "string", Description: "Vulnerabilities"},
+		},
+		Rows: []metav1.TableRow{},
+	}
+
+	// Handle both single object and list
+	var summaries []v1alpha1.ClusterVulnerabilitySummary
+	switch t := obj.(type) {
+	case *v1alpha1.ClusterVulnerabilitySummaryList:
+		summaries = t.Items
+	case *v1alpha1.ClusterVulnerabilitySummary:
+		summaries = []v1alpha1.ClusterVulnerabilitySummary{*t}
+	default:
+		return nil, fmt.Errorf("unexpected type %T", obj)
+	}
+
+	for _, summary := range summaries {
+		row := metav1.TableRow{
+			Object: runtime.RawExtension{Object: &summary},
+			Cells: []interface{}{
+				summary.Name,
+				summary.ReportsCount,
+				computeVulnerabilities(summary.Summary),
+			},
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, nil
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+func TestClusterVulnerabilitySummaryStore(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	insertReport := func(name string, summary v1alpha1.Summary) {
+		data, err := json.Marshal(map[string]any{
+			"metadata": metav1.ObjectMeta{Name: name, Namespace: "default"},
+			"report":   map[string]any{"summary": summary},
+		})
+		require.NoError(t, err)
+		_, err = db.Exec(ctx, "INSERT INTO vulnerabilityreports (name, namespace, object) VALUES ($1, 'default', $2)", name, data)
+		require.NoError(t, err)
+	}
+	insertFinding := func(report, cve, severity string, suppressed bool) {
+		_, err := db.Exec(ctx, `
+INSERT INTO vulnerability_findings (namespace, report_name, cve, severity, fixable, suppressed, package_name, purl, installed_version)
+VALUES ('default', $1, $2, $3, false, $4, '', '', '')`, report, cve, severity, suppressed)
+		require.NoError(t, err)
+	}
+
+	insertReport("alpine", v1alpha1.Summary{Critical: 1, Medium: 1, Unknown: 1, Suppressed: 1})
+	insertReport("debian", v1alpha1.Summary{Critical: 1, High: 1, Low: 1})
+	// The severities of the same CVE differ between the images, and do not sort by name.
+	insertFinding("alpine", "CVE-2024-0001", "MEDIUM", false)
+	insertFinding("debian", "CVE-2024-0001", "CRITICAL", false)
+	insertFinding("alpine", "CVE-2024-0002", "UNKNOWN", false)
+	insertFinding("debian", "CVE-2024-0002", "HIGH", false)
+	insertFinding("alpine", "CVE-2024-0003", "CRITICAL", false)
+	insertFinding("debian", "CVE-2024-0004", "LOW", false)
+	// The suppressed findings are not reported.
+	insertFinding("alpine", "CVE-2024-0005", "CRITICAL", true)
+
+	s := NewClusterVulnerabilitySummaryStore(db, nil, slog.Default()).(*clusterVulnerabilitySummaryStore)
+
+	obj, err := s.Get(ctx, v1alpha1.ClusterVulnerabilitySummaryName, &metav1.GetOptions{})
+	require.NoError(t, err)
+	summary, ok := obj.(*v1alpha1.ClusterVulnerabilitySummary)
+	require.True(t, ok)
+
+	assert.Equal(t, 2, summary.ReportsCount)
+	assert.Equal(t, v1alpha1.Summary{Critical: 2, High: 1, Medium: 1, Low: 1, Unknown: 1, Suppressed: 1}, summary.Summary)
+	assert.Equal(t, []v1alpha1.CVEOccurrence{
+		{CVE: "CVE-2024-0001", Severity: "CRITICAL", Images: 2},
+		{CVE: "CVE-2024-0002", Severity: "HIGH", Images: 2},
+		{CVE: "CVE-2024-0003", Severity: "CRITICAL", Images: 1},
+		{CVE: "CVE-2024-0004", Severity: "LOW", Images: 1},
+	}, summary.TopCVEs)
+
+	// The summary is cached, the reports written since are not counted until it expires.
+	insertReport("ubuntu", v1alpha1.Summary{})
+	obj, err = s.Get(ctx, v1alpha1.ClusterVulnerabilitySummaryName, &metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, obj.(*v1alpha1.ClusterVulnerabilitySummary).ReportsCount)
+}
+
+func TestClusterVulnerabilitySummaryStoreGetNotFound(t *testing.T) {
+	s := NewClusterVulnerabilitySummaryStore(nil, nil, slog.Default())
+
+	_, err := s.(*clusterVulnerabilitySummaryStore).Get(t.Context(), "other", &metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestClusterVulnerabilitySummaryTableConvertor(t *testing.T) {
+	summary := &v1alpha1.ClusterVulnerabilitySummary{
+		ObjectMeta:   metav1.ObjectMeta{Name: v1alpha1.ClusterVulnerabilitySummaryName},
+		ReportsCount: 12,
+		Summary:      v1alpha1.Summary{Critical: 1, High: 2, Medium: 3, Suppressed: 4},
+	}
+
+	table, err := (&clusterVulnerabilitySummaryTableConvertor{}).ConvertToTable(t.Context(), summary, nil)
+	require.NoError(t, err)
+	require.Len(t, table.Rows, 1)
+	assert.Equal(t, []any{v1alpha1.ClusterVulnerabilitySummaryName, 12, "6 (4 suppressed)"}, table.Rows[0].Cells)
+
+	_, err = (&clusterVulnerabilitySummaryTableConvertor{}).ConvertToTable(t.Context(), &v1alpha1.Image{}, nil)
+	require.Error(t, err)
+}
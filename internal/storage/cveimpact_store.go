@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/registry/rest"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// cveImpactSQL returns the images affected by a CVE, one row per VulnerabilityReport.
+// A CVE can affect several packages of the same image, the highest severity is reported.
+const cveImpactSQL = `
+SELECT
+    f.namespace,
+    f.report_name,
+    r.object->'imageMetadata',
+    (array_agg(f.severity ORDER BY CASE f.severity
+        WHEN 'CRITICAL' THEN 0
+        WHEN 'HIGH' THEN 1
+        WHEN 'MEDIUM' THEN 2
+        WHEN 'LOW' THEN 3
+        ELSE 4
+    END))[1],
+    bool_or(f.fixable),
+    bool_and(f.suppressed)
+FROM vulnerability_findings f
+JOIN vulnerabilityreports r ON r.name = f.report_name AND r.namespace = f.namespace
+WHERE f.cve = $1
+GROUP BY f.namespace, f.report_name, r.object->'imageMetadata'
+ORDER BY f.namespace, f.report_name
+`
+
+var (
+	_ rest.Storage              = &cveImpactStore{}
+	_ rest.Getter               = &cveImpactStore{}
+	_ rest.Scoper               = &cveImpactStore{}
+	_ rest.SingularNameProvider = &cveImpactStore{}
+)
+
+// cveImpactStore serves the read-only CVEImpact resource.
+// The affected images are looked up in the vulnerability_findings table, indexed by CVE.
+type cveImpactStore struct {
+	rest.TableConvertor
+
+	db         *pgxpool.Pool
+	queryCache *QueryCache
+	logger     *slog.Logger
+}
+
+// NewCVEImpactStore returns a read-only store for the CVEImpact resource.
+// The impacts are cached in queryCache when it is enabled.
+func NewCVEImpactStore(db *pgxpool.Pool, queryCache *QueryCache, logger *slog.Logger) rest.Storage {
+	return &cveImpactStore{
+		TableConvertor: &cveImpactTableConvertor{},
+		db:             db,
+		queryCache:     queryCache,
+		logger:         logger.With("store", "cveimpact"),
+	}
+}
+
+func (s *cveImpactStore) New() runtime.Object {
+	return &v1alpha1.CVEImpact{}
+}
+
+func (s *cveImpactStore) Destroy() {
+}
+
+func (s *cveImpactStore) NamespaceScoped() bool {
+	return false
+}
+
+func (s *cveImpactStore) GetSingularName() string {
+	return "cveimpact"
+}
+
+// Get returns the images affected by the CVE with the given identifier.
+// NotFound is returned when no stored VulnerabilityReport contains the CVE.
+func (s *cveImpactStore) Get(ctx context.Context, name string, _ *metav1.GetOptions) (runtime.Object, error) {
+	obj, err := s.queryCache.GetOrCompute(ctx, queryCVEImpact, "", name, func(ctx context.Context) (runtime.Object, error) {
+		s.logger.DebugContext(ctx, "Looking up images affected by CVE", "cve", name)
+
+		affectedImages, err := s.queryAffectedImages(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		return &v1alpha1.CVEImpact{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.Now(),
+			},
+			AffectedImages: affectedImages,
+		}, nil
+	})
+	if err != nil {
+		return nil, newAPIInternalError(ctx, err)
+	}
+
+	impact, ok := obj.(*v1alpha1.CVEImpact)
+	if !ok {
+		return nil, apierrors.NewInternalError(fmt.Errorf("unexpected type %T", obj))
+	}
+	if len(impact.AffectedImages) == 0 {
+		return nil, apierrors.NewNotFound(v1alpha1.Resource("cveimpacts"), name)
+	}
+
+	return impact, nil
+}
+
+// queryAffectedImages returns the images whose VulnerabilityReport contains the CVE.
+func (s *cveImpactStore) queryAffectedImages(ctx context.Context, cve string) ([]v1alpha1.AffectedImage, error) {
+	rows, err := s.db.Query(ctx, cveImpactSQL, cve)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query affected images: %w", err)
+	}
+	defer rows.Close()
+
+	affectedImages := []v1alpha1.AffectedImage{}
+	for rows.Next() {
+		var affectedImage v1alpha1.AffectedImage
+		var imageMetadata []byte
+		err = rows.Scan(
+			&affectedImage.Namespace,
+			&affectedImage.Name,
+			&imageMetadata,
+			&affectedImage.Severity,
+			&affectedImage.Fixable,
+			&affectedImage.Suppressed,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan affected images: %w", err)
+		}
+		if err = json.Unmarshal(imageMetadata, &affectedImage.ImageMetadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal image metadata: %w", err)
+		}
+		affectedImages = append(affectedImages, affectedImage)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read affected images: %w", err)
+	}
+
+	return affectedImages, nil
+}
+
+type cveImpactTableConvertor struct{}
+
+func (c *cveImpactTableConvertor) ConvertToTable(_ context.Context, obj runtime.Object, _ runtime.Object) (*metav1.Table, error) {
+	table := &metav1.Table{
+		ColumnDefinitions: append(
+			append([]metav1.TableColumnDefinition{
+				{Name: "Namespace", Type: "string", Description: "Namespace of the vulnerability report"},
+			}, imageMetadataTableColumns()...),
+			metav1.TableColumnDefinition{Name: "Severity", Type: "string", Description: "Highest severity of the CVE in the image"},
+			metav1.TableColumnDefinition{Name: "Fixable", Type: "boolean", Description: "A fixed version is available"},
+			metav1.TableColumnDefinition{Name: "Suppressed", Type: "boolean", Description: "The CVE is suppressed by a VEX statement"},
+		),
+		Rows: []metav1.TableRow{},
+	}
+
+	impact, ok := obj.(*v1alpha1.CVEImpact)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type %T", obj)
+	}
+
+	for _, affectedImage := range impact.AffectedImages {
+		row := metav1.TableRow{
+			Object: runtime.RawExtension{Object: impact},
+			Cells: append(
+				append([]interface{}{affectedImage.Namespace}, imageMetadataTableRowCells(affectedImage.Name, &affectedImage)...),
+				affectedImage.Severity,
+				affectedImage.Fixable,
+				affectedImage.Suppressed,
+			),
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, nil
+}
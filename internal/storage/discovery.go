@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"k8s.io/apiserver/pkg/registry/generic/registry"
+	"k8s.io/apiserver/pkg/registry/rest"
+)
+
+// CategorySBOMScanner is the category of the SBOMscanner resources that can be listed without a selector,
+// so that `kubectl get sbomscanner` shows them all.
+const CategorySBOMScanner = "sbomscanner"
+
+// CategoryAll is the category listed by `kubectl get all`.
+// Only the lightweight namespaced resources belong to it: the SBOMs and the VulnerabilityReports
+// are too large to be listed together with the other resources.
+const CategoryAll = "all"
+
+var (
+	_ rest.ShortNamesProvider = &discoveryStore{}
+	_ rest.CategoriesProvider = &discoveryStore{}
+)
+
+// discoveryStore adds the short names and the categories to the discovery information of a registry.Store,
+// which does not provide them.
+type discoveryStore struct {
+	*registry.Store
+
+	shortNames []string
+	categories []string
+}
+
+// WithDiscovery returns the store advertising the given short names and categories in the API discovery.
+func WithDiscovery(store *registry.Store, shortNames, categories []string) rest.Storage {
+	return &discoveryStore{
+		Store:      store,
+		shortNames: shortNames,
+		categories: categories,
+	}
+}
+
+func (s *discoveryStore) ShortNames() []string {
+	return s.shortNames
+}
+
+func (s *discoveryStore) Categories() []string {
+	return s.categories
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/registry/generic/registry"
+	"k8s.io/apiserver/pkg/registry/rest"
+)
+
+func TestWithDiscovery(t *testing.T) {
+	storage := WithDiscovery(&registry.Store{}, []string{"img"}, []string{CategoryAll, CategorySBOMScanner})
+
+	shortNamesProvider, ok := storage.(rest.ShortNamesProvider)
+	require.True(t, ok)
+	assert.Equal(t, []string{"img"}, shortNamesProvider.ShortNames())
+
+	categoriesProvider, ok := storage.(rest.CategoriesProvider)
+	require.True(t, ok)
+	assert.Equal(t, []string{CategoryAll, CategorySBOMScanner}, categoriesProvider.Categories())
+
+	// The verbs of the wrapped store are still discovered.
+	assert.Implements(t, (*rest.StandardStorage)(nil), storage)
+	assert.Implements(t, (*rest.Scoper)(nil), storage)
+	assert.Implements(t, (*rest.SingularNameProvider)(nil), storage)
+	assert.Implements(t, (*rest.TableConvertor)(nil), storage)
+}
+
+func TestReadOnlyStoresDiscovery(t *testing.T) {
+	summaryStore := NewClusterVulnerabilitySummaryStore(nil, nil, slog.Default())
+	assert.Equal(t, []string{"vulnsummary"}, summaryStore.(rest.ShortNamesProvider).ShortNames())
+	assert.Equal(t, []string{CategorySBOMScanner}, summaryStore.(rest.CategoriesProvider).Categories())
+
+	// The ImagePackages cannot be listed without a field selector, so they do not belong to any category.
+	imagePackageStore := NewImagePackageStore(nil, nil, slog.Default())
+	assert.Equal(t, []string{"imgpkg"}, imagePackageStore.(rest.ShortNamesProvider).ShortNames())
+	assert.NotImplements(t, (*rest.CategoriesProvider)(nil), imagePackageStore)
+}
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// documentReferenceField is the field of the stored objects referencing their offloaded document.
+// It is not part of the API types, so it is ignored when the objects are unmarshalled.
+const documentReferenceField = "documentReference"
+
+// documentLoadConcurrency is the maximum number of offloaded documents read concurrently by a list.
+const documentLoadConcurrency = 16
+
+// documentReference points to the offloaded document of an object.
+type documentReference struct {
+	// Key of the document in the document storage.
+	Key string `json:"key,omitempty"`
+	// SHA256 is the checksum of the document, verified when it is read.
+	SHA256 string `json:"sha256,omitempty"`
+	// NotRetained is true when the document was dropped instead of offloaded.
+	NotRetained bool `json:"notRetained,omitempty"`
+	// Size is the size of the document in bytes, counted in the size of the lists.
+	// It is 0 for the documents offloaded before it was recorded.
+	Size int64 `json:"size,omitempty"`
+}
+
+// documentOffload moves the large document of the objects of a store, e.g. the SPDX document of the SBOMs,
+// to a DocumentStorage. The rows keep the rest of the object, so that the queries on the metadata are unchanged.
+type documentOffload struct {
+	// storage is nil when the documents are not retained: they are dropped once the write hook of the store
+	// has normalized them, and the objects are read with the AnnotationRawDocumentKey annotation instead.
+	storage DocumentStorage
+	// detach removes the document from the object and returns it, nil when the object has no document.
+	detach func(obj runtime.Object) ([]byte, error)
+	// attach sets the document read from the document storage on the object.
+	attach func(obj runtime.Object, document []byte) error
+}
+
+// encodeObject marshals the object stored in the row of name and namespace.
+// When the documents are offloaded, the document is written to the document storage first,
+// and the row keeps the reference returned, with the checksum of the document.
+// The documents are addressed by their checksum, so that an update does not overwrite the document
+// of the committed row. A rollback does not delete the document, which may be the one of the committed row
+// or of a concurrent write of the same content: the documents left behind are reported, and deleted, by VerifyIntegrity.
+func (s *store) encodeObject(ctx context.Context, name, namespace string, obj runtime.Object) ([]byte, *documentReference, error) {
+	if s.documents == nil {
+		data, err := json.Marshal(obj)
+		return data, nil, err
+	}
+
+	detached := obj.DeepCopyObject()
+	// The annotation is only set when the objects are read, the rows record it in their reference.
+	// It is sent back by the clients updating the objects read without their document, e.g. to change their labels,
+	// in which case the document is still not retained.
+	notRetained, err := removeRawDocumentAnnotation(detached)
+	if err != nil {
+		return nil, nil, err
+	}
+	document, err := s.documents.detach(detached)
+	if err != nil {
+		return nil, nil, fmt.Errorf("detaching document: %w", err)
+	}
+
+	var ref *documentReference
+	switch {
+	case len(document) == 0 && !notRetained:
+		data, err := json.Marshal(detached)
+		return data, nil, err
+	case len(document) == 0 || s.documents.storage == nil:
+		ref = &documentReference{NotRetained: true}
+	default:
+		sum := sha256.Sum256(document)
+		checksum := hex.EncodeToString(sum[:])
+		ref = &documentReference{
+			Key:    path.Join(s.table, namespace, name, checksum),
+			SHA256: checksum,
+			Size:   int64(len(document)),
+		}
+		if err = s.documents.storage.Put(ctx, ref.Key, document); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	data, err := json.Marshal(detached)
+	if err != nil {
+		return nil, nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, nil, err
+	}
+	if fields[documentReferenceField], err = json.Marshal(ref); err != nil {
+		return nil, nil, err
+	}
+	if data, err = json.Marshal(fields); err != nil {
+		return nil, nil, err
+	}
+
+	return data, ref, nil
+}
+
+// decodeObject unmarshals the object of a row, without its offloaded document.
+// The reference of the document is returned when it is offloaded, so that it can be read with loadDocument
+// once the rows are released.
+func (s *store) decodeObject(data []byte, obj runtime.Object) (*documentReference, error) {
+	if err := json.Unmarshal(data, obj); err != nil {
+		return nil, err
+	}
+	if s.documents == nil {
+		return nil, nil
+	}
+
+	var row struct {
+		DocumentReference *documentReference `json:"documentReference"`
+	}
+	if err := json.Unmarshal(data, &row); err != nil {
+		return nil, err
+	}
+
+	return row.DocumentReference, nil
+}
+
+// loadDocument reads the offloaded document of the object, verifies its checksum and attaches it to the object.
+// The objects whose document was not retained are annotated instead.
+func (s *store) loadDocument(ctx context.Context, obj runtime.Object, ref *documentReference) error {
+	if ref == nil {
+		return nil
+	}
+	if ref.NotRetained {
+		return setRawDocumentAnnotation(obj)
+	}
+
+	document, err := s.documents.storage.Get(ctx, ref.Key)
+	if err != nil {
+		return err
+	}
+	if sum := sha256.Sum256(document); hex.EncodeToString(sum[:]) != ref.SHA256 {
+		return fmt.Errorf("document %s does not match its checksum %s", ref.Key, ref.SHA256)
+	}
+
+	return s.documents.attach(obj, document)
+}
+
+// loadDocuments reads the offloaded documents of the listed objects concurrently.
+// refs are the references returned by decodeObject, in the order of the objects.
+func (s *store) loadDocuments(ctx context.Context, objs []runtime.Object, refs []*documentReference) error {
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(documentLoadConcurrency)
+	for i, ref := range refs {
+		if ref == nil {
+			continue
+		}
+		group.Go(func() error {
+			return s.loadDocument(ctx, objs[i], ref)
+		})
+	}
+
+	return group.Wait()
+}
+
+// deleteDocument removes an offloaded document which is no longer referenced by its row.
+// The failures are only logged, since the row has already been committed.
+func (s *store) deleteDocument(ctx context.Context, ref *documentReference) {
+	if ref == nil || ref.NotRetained {
+		return
+	}
+
+	if err := s.documents.storage.Delete(ctx, ref.Key); err != nil {
+		s.logger.WarnContext(ctx, "Failed to delete offloaded document", "key", ref.Key, "error", err)
+	}
+}
+
+// setRawDocumentAnnotation annotates the object whose document was not retained.
+func setRawDocumentAnnotation(obj runtime.Object) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	annotations := accessor.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[v1alpha1.AnnotationRawDocumentKey] = v1alpha1.RawDocumentNotRetained
+	accessor.SetAnnotations(annotations)
+
+	return nil
+}
+
+// removeRawDocumentAnnotation removes the annotation of the documents not retained from the object,
+// and returns true if it was set.
+func removeRawDocumentAnnotation(obj runtime.Object) (bool, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
+	annotations := accessor.GetAnnotations()
+	if annotations[v1alpha1.AnnotationRawDocumentKey] != v1alpha1.RawDocumentNotRetained {
+		return false, nil
+	}
+	delete(annotations, v1alpha1.AnnotationRawDocumentKey)
+	accessor.SetAnnotations(annotations)
+
+	return true, nil
+}
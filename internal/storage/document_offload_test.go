@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// memoryDocumentStorage is an in-memory DocumentStorage.
+type memoryDocumentStorage struct {
+	mu        sync.Mutex
+	documents map[string][]byte
+	modified  map[string]time.Time
+}
+
+func newMemoryDocumentStorage() *memoryDocumentStorage {
+	return &memoryDocumentStorage{documents: map[string][]byte{}, modified: map[string]time.Time{}}
+}
+
+func (m *memoryDocumentStorage) Put(_ context.Context, key string, document []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.documents[key] = document
+	m.modified[key] = time.Now()
+
+	return nil
+}
+
+func (m *memoryDocumentStorage) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	document, ok := m.documents[key]
+	if !ok {
+		return nil, fmt.Errorf("document %s not found", key)
+	}
+
+	return document, nil
+}
+
+func (m *memoryDocumentStorage) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.documents, key)
+	delete(m.modified, key)
+
+	return nil
+}
+
+func (m *memoryDocumentStorage) List(_ context.Context, prefix string) ([]StoredDocument, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var documents []StoredDocument
+	for key := range m.documents {
+		if strings.HasPrefix(key, prefix) {
+			documents = append(documents, StoredDocument{Key: key, LastModified: m.modified[key]})
+		}
+	}
+
+	return documents, nil
+}
+
+func TestStoreDocumentOffload_SBOM(t *testing.T) {
+	documents := newMemoryDocumentStorage()
+	s := &store{table: "sboms", documents: newSBOMDocumentOffload(documents), logger: slog.Default()}
+
+	sbom := &v1alpha1.SBOM{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-sbom", Namespace: "default"},
+		SPDX:       runtime.RawExtension{Raw: []byte(`{"spdxVersion":"SPDX-2.3"}`)},
+	}
+
+	data, ref, err := s.encodeObject(t.Context(), "test-sbom", "default", sbom)
+	require.NoError(t, err)
+	require.NotNil(t, ref)
+	assert.Equal(t, "sboms/default/test-sbom/"+ref.SHA256, ref.Key)
+	assert.Equal(t, int64(len(sbom.SPDX.Raw)), ref.Size)
+	assert.Equal(t, sbom.SPDX.Raw, documents.documents[ref.Key])
+	assert.NotContains(t, string(data), "SPDX-2.3")
+	assert.Contains(t, string(data), ref.SHA256)
+	// The object given is stored unchanged, since it is returned to the client.
+	assert.JSONEq(t, `{"spdxVersion":"SPDX-2.3"}`, string(sbom.SPDX.Raw))
+
+	decoded := &v1alpha1.SBOM{}
+	decodedRef, err := s.decodeObject(data, decoded)
+	require.NoError(t, err)
+	assert.Equal(t, ref, decodedRef)
+	assert.Nil(t, decoded.SPDX.Raw)
+
+	require.NoError(t, s.loadDocument(t.Context(), decoded, decodedRef))
+	assert.Equal(t, sbom, decoded)
+
+	s.deleteDocument(t.Context(), decodedRef)
+	assert.Empty(t, documents.documents)
+}
+
+func TestStoreDocumentOffload_ChecksumMismatch(t *testing.T) {
+	documents := newMemoryDocumentStorage()
+	s := &store{table: "sboms", documents: newSBOMDocumentOffload(documents), logger: slog.Default()}
+
+	sbom := &v1alpha1.SBOM{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-sbom", Namespace: "default"},
+		SPDX:       runtime.RawExtension{Raw: []byte(`{"spdxVersion":"SPDX-2.3"}`)},
+	}
+	data, ref, err := s.encodeObject(t.Context(), "test-sbom", "default", sbom)
+	require.NoError(t, err)
+	documents.documents[ref.Key] = []byte(`{"spdxVersion":"SPDX-2.2"}`)
+
+	decoded := &v1alpha1.SBOM{}
+	decodedRef, err := s.decodeObject(data, decoded)
+	require.NoError(t, err)
+	require.ErrorContains(t, s.loadDocument(t.Context(), decoded, decodedRef), "does not match its checksum")
+}
+
+func TestStoreDocumentOffload_VulnerabilityReport(t *testing.T) {
+	documents := newMemoryDocumentStorage()
+	s := &store{table: "vulnerabilityreports", documents: newVulnerabilityReportDocumentOffload(documents, true), logger: slog.Default()}
+
+	report := &v1alpha1.VulnerabilityReport{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-report", Namespace: "default"},
+		Report: v1alpha1.Report{
+			Summary: v1alpha1.Summary{Critical: 1},
+			Results: []v1alpha1.Result{{Target: "alpine:3.20"}},
+		},
+	}
+
+	data, ref, err := s.encodeObject(t.Context(), "test-report", "default", report)
+	require.NoError(t, err)
+	require.NotNil(t, ref)
+
+	// The summary is kept in the row for the aggregations.
+	var row struct {
+		Report struct {
+			Summary v1alpha1.Summary  `json:"summary"`
+			Results []v1alpha1.Result `json:"results"`
+		} `json:"report"`
+	}
+	require.NoError(t, json.Unmarshal(data, &row))
+	assert.Equal(t, 1, row.Report.Summary.Critical)
+	assert.Empty(t, row.Report.Results)
+
+	decoded := &v1alpha1.VulnerabilityReport{}
+	decodedRef, err := s.decodeObject(data, decoded)
+	require.NoError(t, err)
+	require.NoError(t, s.loadDocument(t.Context(), decoded, decodedRef))
+	assert.Equal(t, report, decoded)
+}
+
+func TestStoreDocumentOffload_NotRetained(t *testing.T) {
+	s := &store{table: "vulnerabilityreports", documents: newVulnerabilityReportDocumentOffload(nil, false), logger: slog.Default()}
+
+	report := &v1alpha1.VulnerabilityReport{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-report", Namespace: "default"},
+		Report: v1alpha1.Report{
+			Summary: v1alpha1.Summary{Critical: 1},
+			Results: []v1alpha1.Result{{Target: "alpine:3.20"}},
+		},
+	}
+
+	data, ref, err := s.encodeObject(t.Context(), "test-report", "default", report)
+	require.NoError(t, err)
+	assert.Equal(t, &documentReference{NotRetained: true}, ref)
+	assert.NotContains(t, string(data), "alpine:3.20")
+
+	decoded := &v1alpha1.VulnerabilityReport{}
+	decodedRef, err := s.decodeObject(data, decoded)
+	require.NoError(t, err)
+	require.NoError(t, s.loadDocument(t.Context(), decoded, decodedRef))
+	assert.Equal(t, 1, decoded.Report.Summary.Critical)
+	assert.Empty(t, decoded.Report.Results)
+	assert.Equal(t, v1alpha1.RawDocumentNotRetained, decoded.Annotations[v1alpha1.AnnotationRawDocumentKey])
+
+	// An object read without its results and updated by a client, e.g. to change its labels,
+	// is still recorded as not retained, without storing the annotation.
+	decoded.Labels = map[string]string{"team": "security"}
+	data, ref, err = s.encodeObject(t.Context(), "test-report", "default", decoded)
+	require.NoError(t, err)
+	assert.Equal(t, &documentReference{NotRetained: true}, ref)
+	assert.NotContains(t, string(data), v1alpha1.AnnotationRawDocumentKey)
+	assert.Equal(t, v1alpha1.RawDocumentNotRetained, decoded.Annotations[v1alpha1.AnnotationRawDocumentKey],
+		"the object given is not changed")
+
+	s.deleteDocument(t.Context(), ref)
+}
+
+func TestStoreDocumentOffload_Disabled(t *testing.T) {
+	s := &store{table: "sboms", logger: slog.Default()}
+
+	sbom := &v1alpha1.SBOM{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-sbom", Namespace: "default"},
+		SPDX:       runtime.RawExtension{Raw: []byte(`{"spdxVersion":"SPDX-2.3"}`)},
+	}
+
+	data, ref, err := s.encodeObject(t.Context(), "test-sbom", "default", sbom)
+	require.NoError(t, err)
+	assert.Nil(t, ref)
+	assert.Contains(t, string(data), "SPDX-2.3")
+
+	decoded := &v1alpha1.SBOM{}
+	decodedRef, err := s.decodeObject(data, decoded)
+	require.NoError(t, err)
+	assert.Nil(t, decodedRef)
+	assert.Equal(t, sbom, decoded)
+}
+
+func TestLoadDocuments(t *testing.T) {
+	documents := newMemoryDocumentStorage()
+	s := &store{table: "sboms", documents: newSBOMDocumentOffload(documents), logger: slog.Default()}
+
+	var objs []runtime.Object
+	var refs []*documentReference
+	for i := range 20 {
+		name := fmt.Sprintf("sbom-%d", i)
+		sbom := &v1alpha1.SBOM{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			SPDX:       runtime.RawExtension{Raw: fmt.Appendf(nil, `{"name":%q}`, name)},
+		}
+		data, _, err := s.encodeObject(t.Context(), name, "default", sbom)
+		require.NoError(t, err)
+
+		decoded := &v1alpha1.SBOM{}
+		ref, err := s.decodeObject(data, decoded)
+		require.NoError(t, err)
+		objs = append(objs, decoded)
+		refs = append(refs, ref)
+	}
+	// An object stored before the offload was enabled has no reference.
+	objs = append(objs, &v1alpha1.SBOM{SPDX: runtime.RawExtension{Raw: []byte(`{}`)}})
+	refs = append(refs, nil)
+
+	require.NoError(t, s.loadDocuments(t.Context(), objs, refs))
+	for i := range 20 {
+		sbom, ok := objs[i].(*v1alpha1.SBOM)
+		require.True(t, ok)
+		assert.JSONEq(t, fmt.Sprintf(`{"name":%q}`, sbom.Name), string(sbom.SPDX.Raw))
+	}
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// DocumentStorage stores the documents offloaded from the rows of the database, e.g. the SPDX documents of the SBOMs.
+// The documents are addressed by keys chosen by the stores.
+type DocumentStorage interface {
+	// Put writes the document at key, replacing the existing one.
+	Put(ctx context.Context, key string, document []byte) error
+	// Get reads the document at key.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the document at key. Deleting a missing document is not an error.
+	Delete(ctx context.Context, key string) error
+	// List returns the documents whose key starts with prefix.
+	List(ctx context.Context, prefix string) ([]StoredDocument, error)
+}
+
+// StoredDocument is a document listed by a DocumentStorage.
+type StoredDocument struct {
+	Key          string
+	LastModified time.Time
+}
+
+// ObjectStorageOptions configures the S3-compatible object storage where the documents are offloaded.
+// The credentials are read from the default AWS credential chain, e.g. the AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY environment variables, or the web identity of the pod.
+type ObjectStorageOptions struct {
+	// Endpoint is the URL of the S3-compatible service, e.g. https://minio.example.com.
+	// The AWS S3 endpoint of the region is used when empty.
+	Endpoint string
+	// Bucket is the bucket where the documents are stored. The offload is disabled when empty.
+	Bucket string
+	// Region is the region of the bucket.
+	Region string
+	// Prefix is prepended to the keys of the documents, e.g. to share a bucket between installations.
+	Prefix string
+	// UsePathStyle addresses the bucket in the path of the URLs instead of the host name,
+	// as required by most of the S3-compatible services.
+	UsePathStyle bool
+}
+
+// Enabled returns true if the documents must be offloaded to the object storage.
+func (o ObjectStorageOptions) Enabled() bool {
+	return o.Bucket != ""
+}
+
+// Validate returns an error if the options are not valid.
+func (o ObjectStorageOptions) Validate() error {
+	if !o.Enabled() {
+		if o.Endpoint != "" || o.Prefix != "" {
+			return errors.New("object storage bucket is required when its endpoint or prefix is set")
+		}
+		return nil
+	}
+	if o.Region == "" {
+		return errors.New("object storage region is required")
+	}
+
+	return nil
+}
+
+// s3DocumentStorage stores the documents in an S3-compatible object storage.
+type s3DocumentStorage struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// NewS3DocumentStorage returns a DocumentStorage writing the documents to the bucket of the options.
+func NewS3DocumentStorage(ctx context.Context, options ObjectStorageOptions) (DocumentStorage, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(options.Region))
+	if err != nil {
+		return nil, fmt.Errorf("loading object storage configuration: %w", err)
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if options.Endpoint != "" {
+			o.BaseEndpoint = aws.String(options.Endpoint)
+		}
+		o.UsePathStyle = options.UsePathStyle
+	})
+
+	return &s3DocumentStorage{
+		client: client,
+		bucket: options.Bucket,
+		prefix: options.Prefix,
+	}, nil
+}
+
+func (s *s3DocumentStorage) Put(ctx context.Context, key string, document []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(path.Join(s.prefix, key)),
+		Body:          bytes.NewReader(document),
+		ContentLength: aws.Int64(int64(len(document))),
+		ContentType:   aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("writing document %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (s *s3DocumentStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, key)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading document %s: %w", key, err)
+	}
+	defer output.Body.Close()
+
+	document, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading document %s: %w", key, err)
+	}
+
+	return document, nil
+}
+
+func (s *s3DocumentStorage) Delete(ctx context.Context, key string) error {
+	// Deleting a missing object succeeds, so there is no not found error to ignore.
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, key)),
+	})
+	if err != nil {
+		return fmt.Errorf("deleting document %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (s *s3DocumentStorage) List(ctx context.Context, prefix string) ([]StoredDocument, error) {
+	// The prefix of the options is not part of the keys of the stores.
+	objectPrefix := path.Join(s.prefix, prefix)
+	if strings.HasSuffix(prefix, "/") {
+		objectPrefix += "/"
+	}
+
+	var documents []StoredDocument
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(objectPrefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing documents %s: %w", prefix, err)
+		}
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			if s.prefix != "" {
+				key = strings.TrimPrefix(key, path.Clean(s.prefix)+"/")
+			}
+			documents = append(documents, StoredDocument{Key: key, LastModified: aws.ToTime(object.LastModified)})
+		}
+	}
+
+	return documents, nil
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"github.com/stephenafamo/bob/dialect/psql"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	"github.com/kubewarden/sbomscanner/internal/platform"
+)
+
+// normalizeImageMetadata normalizes the platform and the OS features of the image metadata, and fills the OS version
+// from the suffix of the platform, or the suffix from the OS version, so that both agree.
+func normalizeImageMetadata(metadata *v1alpha1.ImageMetadata) {
+	metadata.Platform = platform.Normalize(metadata.Platform)
+	metadata.OSFeatures = platform.NormalizeOSFeatures(metadata.OSFeatures)
+
+	_, osVersion := platform.SplitOSVersion(metadata.Platform)
+	switch {
+	case metadata.OSVersion == "":
+		metadata.OSVersion = osVersion
+	case osVersion == "":
+		metadata.Platform += ":" + metadata.OSVersion
+	}
+}
+
+// validateImageMetadata checks that the OS version of the image metadata, if set, is well formed
+// and agrees with the suffix of the platform.
+func validateImageMetadata(metadata *v1alpha1.ImageMetadata, fldPath *field.Path) field.ErrorList {
+	if metadata.OSVersion == "" {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	if err := platform.ValidateOSVersion(metadata.OSVersion); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("osVersion"), metadata.OSVersion, err.Error()))
+	}
+	if _, osVersion := platform.SplitOSVersion(metadata.Platform); osVersion != metadata.OSVersion {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("platform"), metadata.Platform,
+			"the OS version suffix of the platform must match the osVersion field"))
+	}
+
+	return allErrs
+}
+
+// osVersionExpression returns the SQL expression of the imageMetadata.osVersion field,
+// empty for the images without an OS version, as the field is omitted from their objects.
+func osVersionExpression() psql.Expression {
+	return psql.Group(psql.F("COALESCE", psql.Quote("object").OP("#>>", psql.S("{imageMetadata,osVersion}")), psql.S(""))())
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stephenafamo/bob/dialect/psql"
+	"github.com/stephenafamo/bob/dialect/psql/sm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+func TestImageMetadataNormalize(t *testing.T) {
+	tests := []struct {
+		name               string
+		metadata           v1alpha1.ImageMetadata
+		expectedPlatform   string
+		expectedOSVersion  string
+		expectedOSFeatures []string
+	}{
+		{
+			name:             "linux platform",
+			metadata:         v1alpha1.ImageMetadata{Platform: "linux/arm64/v8"},
+			expectedPlatform: "linux/arm64",
+		},
+		{
+			name:              "OS version from the platform suffix",
+			metadata:          v1alpha1.ImageMetadata{Platform: "windows/amd64:10.0.17763.1234"},
+			expectedPlatform:  "windows/amd64:10.0.17763.1234",
+			expectedOSVersion: "10.0.17763.1234",
+		},
+		{
+			name:              "platform suffix from the OS version",
+			metadata:          v1alpha1.ImageMetadata{Platform: "windows/amd64", OSVersion: "10.0.17763.1234"},
+			expectedPlatform:  "windows/amd64:10.0.17763.1234",
+			expectedOSVersion: "10.0.17763.1234",
+		},
+		{
+			name:               "OS features",
+			metadata:           v1alpha1.ImageMetadata{Platform: "windows/amd64", OSFeatures: []string{"win32k", "custom", "win32k"}},
+			expectedPlatform:   "windows/amd64",
+			expectedOSFeatures: []string{"custom", "win32k"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			metadata := test.metadata
+			normalizeImageMetadata(&metadata)
+
+			assert.Equal(t, test.expectedPlatform, metadata.Platform)
+			assert.Equal(t, test.expectedOSVersion, metadata.OSVersion)
+			assert.Equal(t, test.expectedOSFeatures, metadata.OSFeatures)
+		})
+	}
+}
+
+func TestImageMetadataValidate(t *testing.T) {
+	tests := []struct {
+		name           string
+		metadata       v1alpha1.ImageMetadata
+		expectedErrors []string
+	}{
+		{
+			name:     "no OS version",
+			metadata: v1alpha1.ImageMetadata{Platform: "linux/amd64"},
+		},
+		{
+			name:     "OS version",
+			metadata: v1alpha1.ImageMetadata{Platform: "windows/amd64:10.0.17763.1234", OSVersion: "10.0.17763.1234"},
+		},
+		{
+			name:     "malformed OS version",
+			metadata: v1alpha1.ImageMetadata{Platform: "windows/amd64:ltsc2019", OSVersion: "ltsc2019"},
+			expectedErrors: []string{
+				`imageMetadata.osVersion: Invalid value: "ltsc2019": the OS version must be made of dot-separated numbers`,
+			},
+		},
+		{
+			name:     "OS version not matching the platform",
+			metadata: v1alpha1.ImageMetadata{Platform: "windows/amd64:10.0.20348", OSVersion: "10.0.17763.1234"},
+			expectedErrors: []string{
+				`imageMetadata.platform: Invalid value: "windows/amd64:10.0.20348": the OS version suffix of the platform must match the osVersion field`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errs := validateImageMetadata(&test.metadata, field.NewPath("imageMetadata"))
+
+			require.Len(t, errs, len(test.expectedErrors), errs.ToAggregate())
+			for i, expectedError := range test.expectedErrors {
+				assert.Contains(t, errs[i].Error(), expectedError)
+			}
+		})
+	}
+}
+
+func TestImageMetadataOSVersionExpression(t *testing.T) {
+	expressions, err := buildFieldSelectorExpressions(
+		mustParseFieldSelector("imageMetadata.osVersion="),
+		map[string]func() psql.Expression{"imageMetadata.osVersion": osVersionExpression},
+	)
+	require.NoError(t, err)
+	require.Len(t, expressions, 1)
+
+	query, args, err := psql.Select(
+		sm.From("images"),
+		sm.Columns("name"),
+		sm.Where(expressions[0]),
+	).Build(t.Context())
+	require.NoError(t, err)
+
+	assert.Contains(t, query, `(COALESCE(("object" #>> '{imageMetadata,osVersion}'), '')) = $1`)
+	assert.Equal(t, []any{""}, args)
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/stephenafamo/bob/dialect/psql"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apiserver/pkg/storage"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// DefaultImageStaleAfter is the default age of the last scan after which an image is stale.
+const DefaultImageStaleAfter = 7 * 24 * time.Hour
+
+// imageStaleness evaluates the scan freshness of the images.
+// An image is stale when it has never been scanned, or when its last scan is older than staleAfter.
+type imageStaleness struct {
+	staleAfter time.Duration
+	now        func() time.Time
+}
+
+func newImageStaleness(staleAfter time.Duration) imageStaleness {
+	return imageStaleness{
+		staleAfter: staleAfter,
+		now:        time.Now,
+	}
+}
+
+// cutoff returns the time before which the scans are stale.
+// It is truncated to the second, the precision of the serialized scan times,
+// so that the images are evaluated the same way in Go and in SQL.
+func (s imageStaleness) cutoff() time.Time {
+	return s.now().Add(-s.staleAfter).UTC().Truncate(time.Second)
+}
+
+// isStale returns whether the image must be rescanned.
+func (s imageStaleness) isStale(image *v1alpha1.Image) bool {
+	lastScannedAt := image.Status.LastScannedAt
+
+	return lastScannedAt == nil || lastScannedAt.Time.Before(s.cutoff())
+}
+
+// getAttrs returns the labels and fields of the images, including the computed status.stale and status.phase fields.
+func (s imageStaleness) getAttrs(obj runtime.Object) (labels.Set, fields.Set, error) {
+	labelSet, fieldSet, err := getAttrs(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	image, ok := obj.(*v1alpha1.Image)
+	if !ok {
+		return nil, nil, errors.New("object is not an Image")
+	}
+	fieldSet[v1alpha1.FieldImageStale] = strconv.FormatBool(s.isStale(image))
+	fieldSet[v1alpha1.FieldImageScanPhase] = string(image.Status.ScanPhase())
+
+	return labelSet, fieldSet, nil
+}
+
+// matcher returns a storage.SelectionPredicate that matches the given label and field selectors,
+// including the status.stale and status.phase field selectors.
+func (s imageStaleness) matcher(label labels.Selector, field fields.Selector) storage.SelectionPredicate {
+	return storage.SelectionPredicate{
+		Label:    label,
+		Field:    field,
+		GetAttrs: s.getAttrs,
+	}
+}
+
+// staleExpression returns the SQL expression of the status.stale field, evaluating to 'true' or 'false'.
+// The scan times are compared as text, since metav1.Time is always serialized as RFC 3339 in UTC.
+func (s imageStaleness) staleExpression() psql.Expression {
+	lastScannedAt := psql.Quote("object").OP("#>>", psql.S("{status,lastScannedAt}"))
+
+	return psql.Cast(
+		psql.Group(psql.Or(
+			lastScannedAt.IsNull(),
+			lastScannedAt.LT(psql.Arg(s.cutoff().Format(time.RFC3339))),
+		)),
+		"text",
+	)
+}
+
+// lastScannedCell returns the table cell of the time elapsed since the last scan of the image.
+func (s imageStaleness) lastScannedCell(image *v1alpha1.Image) string {
+	if image.Status.LastScannedAt == nil {
+		return "<none>"
+	}
+
+	return duration.HumanDuration(s.now().Sub(image.Status.LastScannedAt.Time))
+}
+
+// scanPhaseExpression returns the SQL expression of the status.phase field, as computed by ImageStatus.ScanPhase.
+func scanPhaseExpression() psql.Expression {
+	return psql.Case().
+		When(psql.Quote("object").OP("#>>", psql.S("{status,lastError}")).IsNotNull(), psql.S(string(v1alpha1.ImageScanPhaseFailed))).
+		When(psql.Quote("object").OP("#>>", psql.S("{status,lastScannedAt}")).IsNotNull(), psql.S(string(v1alpha1.ImageScanPhaseScanned))).
+		Else(psql.S(string(v1alpha1.ImageScanPhasePending)))
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stephenafamo/bob/dialect/psql"
+	"github.com/stephenafamo/bob/dialect/psql/sm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+func TestImageStaleness(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	staleness := imageStaleness{
+		staleAfter: 24 * time.Hour,
+		now:        func() time.Time { return now },
+	}
+
+	newImage := func(lastScannedAt *metav1.Time) *v1alpha1.Image {
+		return &v1alpha1.Image{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Status:     v1alpha1.ImageStatus{LastScannedAt: lastScannedAt},
+		}
+	}
+	recent := metav1.NewTime(now.Add(-time.Hour))
+	old := metav1.NewTime(now.Add(-48 * time.Hour))
+
+	tests := []struct {
+		name          string
+		image         *v1alpha1.Image
+		expectedStale bool
+		expectedCell  string
+	}{
+		{
+			name:          "never scanned",
+			image:         newImage(nil),
+			expectedStale: true,
+			expectedCell:  "<none>",
+		},
+		{
+			name:          "scanned recently",
+			image:         newImage(&recent),
+			expectedStale: false,
+			expectedCell:  "60m",
+		},
+		{
+			name:          "scanned before the threshold",
+			image:         newImage(&old),
+			expectedStale: true,
+			expectedCell:  "2d",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedStale, staleness.isStale(test.image))
+			assert.Equal(t, test.expectedCell, staleness.lastScannedCell(test.image))
+
+			predicate := staleness.matcher(labels.Everything(), mustParseFieldSelector("status.stale=true"))
+			matches, err := predicate.Matches(test.image)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedStale, matches)
+		})
+	}
+}
+
+func TestImageStalenessExpression(t *testing.T) {
+	staleness := imageStaleness{
+		staleAfter: 24 * time.Hour,
+		now:        func() time.Time { return time.Date(2025, 6, 15, 12, 0, 0, 500, time.UTC) },
+	}
+
+	expressions, err := buildFieldSelectorExpressions(
+		mustParseFieldSelector("status.stale=true"),
+		map[string]func() psql.Expression{v1alpha1.FieldImageStale: staleness.staleExpression},
+	)
+	require.NoError(t, err)
+	require.Len(t, expressions, 1)
+
+	query, args, err := psql.Select(
+		sm.From("images"),
+		sm.Columns("name"),
+		sm.Where(expressions[0]),
+	).Build(t.Context())
+	require.NoError(t, err)
+
+	assert.Contains(t, query, `(CAST((((("object" #>> '{status,lastScannedAt}') IS NULL) OR (("object" #>> '{status,lastScannedAt}') < $1))) AS text)) = $2`)
+	assert.Equal(t, []any{"2025-06-14T12:00:00Z", "true"}, args)
+}
+
+func TestImageScanPhase(t *testing.T) {
+	staleness := newImageStaleness(DefaultImageStaleAfter)
+	scannedAt := metav1.NewTime(time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC))
+	scanError := &v1alpha1.ImageScanError{
+		Reason:     v1alpha1.ImageScanErrorReasonNotFound,
+		Message:    "MANIFEST_UNKNOWN",
+		OccurredAt: scannedAt,
+	}
+
+	tests := []struct {
+		name          string
+		status        v1alpha1.ImageStatus
+		expectedPhase v1alpha1.ImageScanPhase
+	}{
+		{
+			name:          "never scanned",
+			expectedPhase: v1alpha1.ImageScanPhasePending,
+		},
+		{
+			name:          "scanned",
+			status:        v1alpha1.ImageStatus{LastScannedAt: &scannedAt},
+			expectedPhase: v1alpha1.ImageScanPhaseScanned,
+		},
+		{
+			name:          "first scan failed",
+			status:        v1alpha1.ImageStatus{LastError: scanError},
+			expectedPhase: v1alpha1.ImageScanPhaseFailed,
+		},
+		{
+			name:          "rescan failed",
+			status:        v1alpha1.ImageStatus{LastScannedAt: &scannedAt, LastError: scanError},
+			expectedPhase: v1alpha1.ImageScanPhaseFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			image := &v1alpha1.Image{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Status:     test.status,
+			}
+			assert.Equal(t, test.expectedPhase, image.Status.ScanPhase())
+
+			for _, phase := range v1alpha1.ImageScanPhases {
+				predicate := staleness.matcher(labels.Everything(), mustParseFieldSelector("status.phase="+string(phase)))
+				matches, err := predicate.Matches(image)
+				require.NoError(t, err)
+				assert.Equal(t, phase == test.expectedPhase, matches, "status.phase=%s", phase)
+			}
+		})
+	}
+}
+
+func TestImageScanPhaseExpression(t *testing.T) {
+	expressions, err := buildFieldSelectorExpressions(
+		mustParseFieldSelector("status.phase=Failed"),
+		map[string]func() psql.Expression{v1alpha1.FieldImageScanPhase: scanPhaseExpression},
+	)
+	require.NoError(t, err)
+	require.Len(t, expressions, 1)
+
+	query, args, err := psql.Select(
+		sm.From("images"),
+		sm.Columns("name"),
+		sm.Where(expressions[0]),
+	).Build(t.Context())
+	require.NoError(t, err)
+
+	assert.Contains(t, query, `(CASE WHEN (("object" #>> '{status,lastError}') IS NOT NULL) THEN 'Failed' WHEN (("object" #>> '{status,lastScannedAt}') IS NOT NULL) THEN 'Scanned' ELSE 'Pending' END) = $1`)
+	assert.Equal(t, []any{"Failed"}, args)
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	"github.com/stephenafamo/bob/dialect/psql"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
@@ -24,13 +26,19 @@ CREATE TABLE IF NOT EXISTS images (
 `
 
 // NewImageStore returns a store registry that will work against API services.
+// The writes invalidate the results of queryCache.
 func NewImageStore(
 	scheme *runtime.Scheme,
 	optsGetter generic.RESTOptionsGetter,
 	db *pgxpool.Pool,
+	readOnly *ReadOnlyMode,
+	staleAfter time.Duration,
+	queryCache *QueryCache,
+	maxListBytes int64,
 	logger *slog.Logger,
 ) (*registry.Store, error) {
 	strategy := newImageStrategy(scheme)
+	staleness := newImageStaleness(staleAfter)
 
 	newFunc := func() runtime.Object { return &v1alpha1.Image{} }
 	newListFunc := func() runtime.Object { return &v1alpha1.ImageList{} }
@@ -38,7 +46,7 @@ func NewImageStore(
 	store := &registry.Store{
 		NewFunc:                   newFunc,
 		NewListFunc:               newListFunc,
-		PredicateFunc:             matcher,
+		PredicateFunc:             staleness.matcher,
 		DefaultQualifiedResource:  v1alpha1.Resource("images"),
 		SingularQualifiedResource: v1alpha1.Resource("image"),
 		Storage: registry.DryRunnableStorage{
@@ -48,16 +56,24 @@ func NewImageStore(
 				table:       "images",
 				newFunc:     newFunc,
 				newListFunc: newListFunc,
-				logger:      logger.With("store", "image"),
+				readOnly:    readOnly,
+				queryCache:  queryCache,
+				computedFields: map[string]func() psql.Expression{
+					v1alpha1.FieldImageStale:     staleness.staleExpression,
+					v1alpha1.FieldImageScanPhase: scanPhaseExpression,
+					"imageMetadata.osVersion":    osVersionExpression,
+				},
+				maxListBytes: maxListBytes,
+				logger:       logger.With("store", "image"),
 			},
 		},
 		CreateStrategy: strategy,
 		UpdateStrategy: strategy,
 		DeleteStrategy: strategy,
-		TableConvertor: &imageTableConvertor{},
+		TableConvertor: &imageTableConvertor{staleness: staleness},
 	}
 
-	options := &generic.StoreOptions{RESTOptions: optsGetter, AttrFunc: getAttrs}
+	options := &generic.StoreOptions{RESTOptions: optsGetter, AttrFunc: staleness.getAttrs}
 	if err := store.CompleteWithOptions(options); err != nil {
 		return nil, fmt.Errorf("unable to complete store with options: %w", err)
 	}
@@ -65,12 +81,18 @@ func NewImageStore(
 	return store, nil
 }
 
-type imageTableConvertor struct{}
+type imageTableConvertor struct {
+	staleness imageStaleness
+}
 
 func (c *imageTableConvertor) ConvertToTable(_ context.Context, obj runtime.Object, _ runtime.Object) (*metav1.Table, error) {
 	table := &metav1.Table{
-		ColumnDefinitions: imageMetadataTableColumns(),
-		Rows:              []metav1.TableRow{},
+		ColumnDefinitions: append(imageMetadataTableColumns(),
+			metav1.TableColumnDefinition{Name: "Last Scanned", Type: "string", Description: "Time elapsed since the last vulnerability scan of the image"},
+			metav1.TableColumnDefinition{Name: "Stale", Type: "boolean", Description: "The image has never been scanned or its last scan is older than the staleness threshold"},
+			metav1.TableColumnDefinition{Name: "Last Error", Type: "string", Description: "Reason of the last failed scan of the image, cleared by the next successful scan"},
+		),
+		Rows: []metav1.TableRow{},
 	}
 
 	// Handle both single object and list
@@ -87,10 +109,23 @@ func (c *imageTableConvertor) ConvertToTable(_ context.Context, obj runtime.Obje
 	for _, image := range images {
 		row := metav1.TableRow{
 			Object: runtime.RawExtension{Object: &image},
-			Cells:  imageMetadataTableRowCells(image.Name, &image),
+			Cells: append(imageMetadataTableRowCells(image.Name, &image),
+				c.staleness.lastScannedCell(&image),
+				c.staleness.isStale(&image),
+				lastErrorCell(&image),
+			),
 		}
 		table.Rows = append(table.Rows, row)
 	}
 
 	return table, nil
 }
+
+// lastErrorCell returns the reason of the last failed scan of the image, or an empty cell.
+func lastErrorCell(image *v1alpha1.Image) string {
+	if image.Status.LastError == nil {
+		return ""
+	}
+
+	return string(image.Status.LastError.Reason)
+}
@@ -2,10 +2,13 @@ package storage
 
 import (
 	"context"
+	"slices"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/apiserver/pkg/storage/names"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
 )
 
 func newImageStrategy(typer runtime.ObjectTyper) imageStrategy {
@@ -21,14 +24,21 @@ func (imageStrategy) NamespaceScoped() bool {
 	return true
 }
 
-func (imageStrategy) PrepareForCreate(_ context.Context, _ runtime.Object) {
+// PrepareForCreate normalizes the platform of the image before it is stored.
+func (imageStrategy) PrepareForCreate(_ context.Context, obj runtime.Object) {
+	image := obj.(*v1alpha1.Image)
+	normalizeImageMetadata(&image.ImageMetadata)
 }
 
-func (imageStrategy) PrepareForUpdate(_ context.Context, _, _ runtime.Object) {
+// PrepareForUpdate normalizes the platform of the image before it is stored.
+func (imageStrategy) PrepareForUpdate(_ context.Context, obj, _ runtime.Object) {
+	image := obj.(*v1alpha1.Image)
+	normalizeImageMetadata(&image.ImageMetadata)
 }
 
-func (imageStrategy) Validate(_ context.Context, _ runtime.Object) field.ErrorList {
-	return field.ErrorList{}
+// Validate checks the TTL annotation and the OS version of the image.
+func (imageStrategy) Validate(_ context.Context, obj runtime.Object) field.ErrorList {
+	return validateImage(obj.(*v1alpha1.Image))
 }
 
 // WarningsOnCreate returns warnings for the creation of the given object.
@@ -47,11 +57,41 @@ func (imageStrategy) AllowUnconditionalUpdate() bool {
 func (imageStrategy) Canonicalize(_ runtime.Object) {
 }
 
-func (imageStrategy) ValidateUpdate(_ context.Context, _, _ runtime.Object) field.ErrorList {
-	return field.ErrorList{}
+// ValidateUpdate checks the TTL annotation and the OS version of the image.
+func (imageStrategy) ValidateUpdate(_ context.Context, obj, _ runtime.Object) field.ErrorList {
+	return validateImage(obj.(*v1alpha1.Image))
 }
 
 // WarningsOnUpdate returns warnings for the given update.
 func (imageStrategy) WarningsOnUpdate(_ context.Context, _, _ runtime.Object) []string {
 	return nil
 }
+
+// validateImage checks that the TTL annotation of the image, if set, is a positive duration,
+// that the reason of the last scan error, if set, is supported, and that the OS version is well formed.
+func validateImage(image *v1alpha1.Image) field.ErrorList {
+	allErrs := validateImageMetadata(&image.ImageMetadata, field.NewPath("imageMetadata"))
+
+	if value, ok := image.Annotations[v1alpha1.AnnotationImageTTLKey]; ok {
+		if _, err := v1alpha1.ParseImageTTL(value); err != nil {
+			fieldPath := field.NewPath("metadata").Child("annotations").Key(v1alpha1.AnnotationImageTTLKey)
+			allErrs = append(allErrs, field.Invalid(fieldPath, value, err.Error()))
+		}
+	}
+
+	allErrs = append(allErrs, validateImageStatus(&image.Status, field.NewPath("status"))...)
+
+	return allErrs
+}
+
+// validateImageStatus checks that the reason of the last scan error, if set, is supported.
+func validateImageStatus(status *v1alpha1.ImageStatus, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if lastError := status.LastError; lastError != nil && !slices.Contains(v1alpha1.ImageScanErrorReasons, lastError.Reason) {
+		fieldPath := fldPath.Child("lastError").Child("reason")
+		allErrs = append(allErrs, field.NotSupported(fieldPath, lastError.Reason, v1alpha1.ImageScanErrorReasons))
+	}
+
+	return allErrs
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+func TestImageStrategyValidate(t *testing.T) {
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		lastError      *v1alpha1.ImageScanError
+		expectedErrors []string
+	}{
+		{
+			name: "no TTL",
+		},
+		{
+			name:        "valid TTL",
+			annotations: map[string]string{v1alpha1.AnnotationImageTTLKey: "72h"},
+		},
+		{
+			name:        "invalid TTL",
+			annotations: map[string]string{v1alpha1.AnnotationImageTTLKey: "3 days"},
+			expectedErrors: []string{
+				`metadata.annotations[sbomscanner.kubewarden.io/ttl]: Invalid value: "3 days": invalid TTL "3 days"`,
+			},
+		},
+		{
+			name:        "negative TTL",
+			annotations: map[string]string{v1alpha1.AnnotationImageTTLKey: "-1h"},
+			expectedErrors: []string{
+				`metadata.annotations[sbomscanner.kubewarden.io/ttl]: Invalid value: "-1h": the TTL must be greater than 0`,
+			},
+		},
+		{
+			name:      "valid last error",
+			lastError: &v1alpha1.ImageScanError{Reason: v1alpha1.ImageScanErrorReasonRateLimited, Message: "429 Too Many Requests"},
+		},
+		{
+			name:      "unsupported last error reason",
+			lastError: &v1alpha1.ImageScanError{Reason: "Unknown", Message: "failure"},
+			expectedErrors: []string{
+				`status.lastError.reason: Unsupported value: "Unknown"`,
+			},
+		},
+	}
+
+	strategy := newImageStrategy(runtime.NewScheme())
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			image := &v1alpha1.Image{
+				ObjectMeta: metav1.ObjectMeta{Annotations: test.annotations},
+				Status:     v1alpha1.ImageStatus{LastError: test.lastError},
+			}
+
+			for _, errs := range []field.ErrorList{
+				strategy.Validate(t.Context(), image),
+				strategy.ValidateUpdate(t.Context(), image, &v1alpha1.Image{}),
+			} {
+				require.Len(t, errs, len(test.expectedErrors), errs.ToAggregate())
+				for i, expectedError := range test.expectedErrors {
+					assert.Contains(t, errs[i].Error(), expectedError)
+				}
+			}
+		})
+	}
+}
+
+func TestImageStrategyPrepareNormalizesPlatform(t *testing.T) {
+	tests := []struct {
+		name              string
+		metadata          v1alpha1.ImageMetadata
+		expectedPlatform  string
+		expectedOSVersion string
+	}{
+		{
+			name:             "canonical platform",
+			metadata:         v1alpha1.ImageMetadata{Platform: "linux/amd64"},
+			expectedPlatform: "linux/amd64",
+		},
+		{
+			name:             "uppercase platform",
+			metadata:         v1alpha1.ImageMetadata{Platform: "Linux/ARM64"},
+			expectedPlatform: "linux/arm64",
+		},
+		{
+			name:             "default variant",
+			metadata:         v1alpha1.ImageMetadata{Platform: "linux/arm64/v8"},
+			expectedPlatform: "linux/arm64",
+		},
+		{
+			name:             "non-default variant",
+			metadata:         v1alpha1.ImageMetadata{Platform: "linux/ARM/v7"},
+			expectedPlatform: "linux/arm/v7",
+		},
+		{
+			name:              "OS version suffix",
+			metadata:          v1alpha1.ImageMetadata{Platform: "Windows/AMD64:10.0.17763.1234"},
+			expectedPlatform:  "windows/amd64:10.0.17763.1234",
+			expectedOSVersion: "10.0.17763.1234",
+		},
+		{
+			name:              "OS version field",
+			metadata:          v1alpha1.ImageMetadata{Platform: "windows/amd64", OSVersion: "10.0.17763.1234"},
+			expectedPlatform:  "windows/amd64:10.0.17763.1234",
+			expectedOSVersion: "10.0.17763.1234",
+		},
+	}
+
+	strategy := newImageStrategy(runtime.NewScheme())
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			image := &v1alpha1.Image{ImageMetadata: test.metadata}
+			strategy.PrepareForCreate(t.Context(), image)
+			assert.Equal(t, test.expectedPlatform, image.Platform)
+			assert.Equal(t, test.expectedOSVersion, image.OSVersion)
+
+			image = &v1alpha1.Image{ImageMetadata: test.metadata}
+			strategy.PrepareForUpdate(t.Context(), image, &v1alpha1.Image{})
+			assert.Equal(t, test.expectedPlatform, image.Platform)
+			assert.Equal(t, test.expectedOSVersion, image.OSVersion)
+		})
+	}
+}
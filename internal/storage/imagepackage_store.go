@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stephenafamo/bob/dialect/psql"
+	"github.com/stephenafamo/bob/dialect/psql/sm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/registry/rest"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// imagePackageColumns maps the field selectors of the ImagePackage resource to the columns of the sbom_packages table.
+var imagePackageColumns = map[string]string{
+	"metadata.namespace":         "namespace",
+	v1alpha1.FieldPackageName:    "name",
+	v1alpha1.FieldPackageVersion: "version",
+	v1alpha1.FieldPackagePURL:    "purl",
+}
+
+var (
+	_ rest.Storage              = &imagePackageStore{}
+	_ rest.Lister               = &imagePackageStore{}
+	_ rest.Scoper               = &imagePackageStore{}
+	_ rest.SingularNameProvider = &imagePackageStore{}
+	_ rest.ShortNamesProvider   = &imagePackageStore{}
+)
+
+// imagePackageStore serves the read-only ImagePackage resource.
+// The packages are searched in the sbom_packages table, indexed by name, version and package URL.
+type imagePackageStore struct {
+	rest.TableConvertor
+
+	db         *pgxpool.Pool
+	queryCache *QueryCache
+	logger     *slog.Logger
+}
+
+// NewImagePackageStore returns a read-only store for the ImagePackage resource.
+// The search results are cached in queryCache when it is enabled.
+func NewImagePackageStore(db *pgxpool.Pool, queryCache *QueryCache, logger *slog.Logger) rest.Storage {
+	return &imagePackageStore{
+		TableConvertor: &imagePackageTableConvertor{},
+		db:             db,
+		queryCache:     queryCache,
+		logger:         logger.With("store", "imagepackage"),
+	}
+}
+
+func (s *imagePackageStore) New() runtime.Object {
+	return &v1alpha1.ImagePackage{}
+}
+
+func (s *imagePackageStore) Destroy() {
+}
+
+func (s *imagePackageStore) NewList() runtime.Object {
+	return &v1alpha1.ImagePackageList{}
+}
+
+func (s *imagePackageStore) NamespaceScoped() bool {
+	return true
+}
+
+func (s *imagePackageStore) GetSingularName() string {
+	return "imagepackage"
+}
+
+// ShortNames returns the short names of the ImagePackage resource.
+// No category is returned, since the packages cannot be listed without a field selector.
+func (s *imagePackageStore) ShortNames() []string {
+	return []string{"imgpkg"}
+}
+
+// List returns the packages matching the field selector, together with the image they were found in.
+// A package name or package URL is required, so that the whole table is never returned.
+func (s *imagePackageStore) List(ctx context.Context, options *metainternalversion.ListOptions) (runtime.Object, error) {
+	var fieldSelector fields.Selector
+	if options != nil {
+		fieldSelector = options.FieldSelector
+	}
+	if fieldSelector == nil ||
+		(!hasFieldRequirement(fieldSelector, v1alpha1.FieldPackageName) &&
+			!hasFieldRequirement(fieldSelector, v1alpha1.FieldPackagePURL)) {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf(
+			"a %q or %q field selector is required", v1alpha1.FieldPackageName, v1alpha1.FieldPackagePURL,
+		))
+	}
+
+	queryBuilder := psql.Select(
+		sm.Columns("p.namespace", "p.sbom_name", "s.object->'imageMetadata'", "p.name", "p.version", "p.purl"),
+		sm.From("sbom_packages").As("p"),
+		sm.InnerJoin("sboms").As("s").On(psql.Raw("s.name = p.sbom_name AND s.namespace = p.namespace")),
+		sm.OrderBy("p.namespace"),
+		sm.OrderBy("p.sbom_name"),
+		sm.OrderBy("p.name"),
+	)
+
+	namespace, _ := genericapirequest.NamespaceFrom(ctx)
+	if namespace != "" {
+		queryBuilder.Apply(sm.Where(psql.Quote("p", "namespace").EQ(psql.Arg(namespace))))
+	}
+
+	for _, requirement := range fieldSelector.Requirements() {
+		column, ok := imagePackageColumns[requirement.Field]
+		if !ok {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("unsupported field selector %q", requirement.Field))
+		}
+
+		switch requirement.Operator {
+		case selection.Equals, selection.DoubleEquals:
+			queryBuilder.Apply(sm.Where(psql.Quote("p", column).EQ(psql.Arg(requirement.Value))))
+		case selection.NotEquals:
+			queryBuilder.Apply(sm.Where(psql.Quote("p", column).NE(psql.Arg(requirement.Value))))
+		case selection.In, selection.NotIn, selection.Exists, selection.DoesNotExist, selection.GreaterThan, selection.LessThan:
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("unsupported field selector operator: %v", requirement.Operator))
+		}
+	}
+
+	query, args, err := queryBuilder.Build(ctx)
+	if err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+
+	list, err := s.queryCache.GetOrCompute(ctx, queryImagePackages, namespace, fieldSelector.String(), func(ctx context.Context) (runtime.Object, error) {
+		s.logger.DebugContext(ctx, "Searching packages", "fieldSelector", fieldSelector.String())
+
+		imagePackages, err := s.queryImagePackages(ctx, query, args)
+		if err != nil {
+			return nil, err
+		}
+
+		return &v1alpha1.ImagePackageList{Items: imagePackages}, nil
+	})
+	if err != nil {
+		return nil, newAPIInternalError(ctx, err)
+	}
+
+	return list, nil
+}
+
+// queryImagePackages runs the search query built by List.
+func (s *imagePackageStore) queryImagePackages(ctx context.Context, query string, args []any) ([]v1alpha1.ImagePackage, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search packages: %w", err)
+	}
+	defer rows.Close()
+
+	imagePackages := []v1alpha1.ImagePackage{}
+	for rows.Next() {
+		var imagePackage v1alpha1.ImagePackage
+		var imageMetadata []byte
+		err = rows.Scan(
+			&imagePackage.Namespace,
+			&imagePackage.Name,
+			&imageMetadata,
+			&imagePackage.Package.Name,
+			&imagePackage.Package.Version,
+			&imagePackage.Package.PURL,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan packages: %w", err)
+		}
+		if err = json.Unmarshal(imageMetadata, &imagePackage.ImageMetadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal image metadata: %w", err)
+		}
+		imagePackages = append(imagePackages, imagePackage)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read packages: %w", err)
+	}
+
+	return imagePackages, nil
+}
+
+// hasFieldRequirement returns true when the field selector matches the field with an equality operator.
+func hasFieldRequirement(fieldSelector fields.Selector, field string) bool {
+	_, found := fieldSelector.RequiresExactMatch(field)
+
+	return found
+}
+
+type imagePackageTableConvertor struct{}
+
+func (c *imagePackageTableConvertor) ConvertToTable(_ context.Context, obj runtime.Object, _ runtime.Object) (*metav1.Table, error) {
+	table := &metav1.Table{
+		ColumnDefinitions: append(imageMetadataTableColumns(),
+			metav1.TableColumnDefinition{Name: "Package", Type: "string", Description: "Name of the package"},
+			metav1.TableColumnDefinition{Name: "Version", Type: "string", Description: "Version of the package"},
+			metav1.TableColumnDefinition{Name: "PURL", Type: "string", Description: "Package URL"},
+		),
+		Rows: []metav1.TableRow{},
+	}
+
+	// Handle both single object and list
+	var imagePackages []v1alpha1.ImagePackage
+	switch t := obj.(type) {
+	case *v1alpha1.ImagePackageList:
+		imagePackages = t.Items
+	case *v1alpha1.ImagePackage:
+		imagePackages = []v1alpha1.ImagePackage{*t}
+	default:
+		return nil, fmt.Errorf("unexpected type %T", obj)
+	}
+
+	for _, imagePackage := range imagePackages {
+		row := metav1.TableRow{
+			Object: runtime.RawExtension{Object: &imagePackage},
+			Cells: append(imageMetadataTableRowCells(imagePackage.Name, &imagePackage),
+				imagePackage.Package.Name,
+				imagePackage.Package.Version,
+				imagePackage.Package.PURL,
+			),
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, nil
+}
@@ -0,0 +1,313 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stephenafamo/bob/dialect/psql"
+	"github.com/stephenafamo/bob/dialect/psql/sm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/watch"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/registry/generic/registry"
+	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/apiserver/pkg/storage"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+var (
+	_ rest.Storage              = &imageStatusBatchStore{}
+	_ rest.Creater              = &imageStatusBatchStore{}
+	_ rest.Scoper               = &imageStatusBatchStore{}
+	_ rest.SingularNameProvider = &imageStatusBatchStore{}
+)
+
+// imageStatusBatchStore serves the create-only ImageStatusBatch resource.
+// The statuses of the images are updated in the images table in a single transaction,
+// and the watchers of the images are notified once it is committed.
+type imageStatusBatchStore struct {
+	images *store
+	logger *slog.Logger
+}
+
+// NewImageStatusBatchStore returns a create-only store for the ImageStatusBatch resource,
+// updating the images of the given Image store.
+func NewImageStatusBatchStore(imageStore *registry.Store, logger *slog.Logger) (rest.Storage, error) {
+	images, ok := imageStore.Storage.Storage.(*store)
+	if !ok {
+		return nil, fmt.Errorf("unexpected Image storage %T", imageStore.Storage.Storage)
+	}
+
+	return &imageStatusBatchStore{
+		images: images,
+		logger: logger.With("store", "imagestatusbatch"),
+	}, nil
+}
+
+func (s *imageStatusBatchStore) New() runtime.Object {
+	return &v1alpha1.ImageStatusBatch{}
+}
+
+func (s *imageStatusBatchStore) Destroy() {
+}
+
+func (s *imageStatusBatchStore) NamespaceScoped() bool {
+	return true
+}
+
+func (s *imageStatusBatchStore) GetSingularName() string {
+	return "imagestatusbatch"
+}
+
+// Create updates the statuses of the images listed by the batch in a single transaction.
+// The images which are not found, or whose new status is invalid, are reported as failed in the results,
+// without failing the other updates.
+// Nothing is written when the request is a dry run.
+func (s *imageStatusBatchStore) Create(
+	ctx context.Context,
+	obj runtime.Object,
+	createValidation rest.ValidateObjectFunc,
+	options *metav1.CreateOptions,
+) (runtime.Object, error) {
+	batch, ok := obj.(*v1alpha1.ImageStatusBatch)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected an ImageStatusBatch object but got %T", obj))
+	}
+	namespace, ok := genericapirequest.NamespaceFrom(ctx)
+	if !ok || namespace == "" {
+		return nil, apierrors.NewBadRequest("namespace is required")
+	}
+
+	if errs := validateImageStatusBatch(batch); len(errs) > 0 {
+		return nil, apierrors.NewInvalid(v1alpha1.Kind("ImageStatusBatch"), batch.Name, errs)
+	}
+	if createValidation != nil {
+		if err := createValidation(ctx, obj); err != nil {
+			return nil, err
+		}
+	}
+	if s.images.readOnly.Enabled() {
+		return nil, newReadOnlyError(s.images.table, batch.Name)
+	}
+
+	dryRun := options != nil && len(options.DryRun) > 0
+	s.logger.DebugContext(ctx, "Updating image statuses", "namespace", namespace, "images", len(batch.Spec.Items), "dryRun", dryRun)
+
+	results, updatedImages, err := s.updateStatuses(ctx, namespace, batch.Spec.Items, dryRun)
+	if err != nil {
+		return nil, newAPIInternalError(ctx, err)
+	}
+
+	if !dryRun {
+		s.images.queryCache.Invalidate(namespace)
+		for _, image := range updatedImages {
+			if err = s.images.broadcaster.Action(watch.Modified, image); err != nil {
+				return nil, newAPIInternalError(ctx, err)
+			}
+		}
+	}
+
+	result := batch.DeepCopy()
+	result.Namespace = namespace
+	result.CreationTimestamp = metav1.Now()
+	result.Status.Results = results
+
+	return result, nil
+}
+
+// updateStatuses locks the images of the batch, and writes their new statuses in a single transaction,
+// which is rolled back when dryRun is true.
+func (s *imageStatusBatchStore) updateStatuses(
+	ctx context.Context,
+	namespace string,
+	items []v1alpha1.ImageStatusUpdate,
+	dryRun bool,
+) ([]v1alpha1.ImageStatusUpdateResult, []*v1alpha1.Image, error) {
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+
+	query, args, err := psql.Select(
+		sm.Columns("name", "object"),
+		sm.From(psql.Quote(s.images.table)),
+		sm.Where(psql.Quote("namespace").EQ(psql.Arg(namespace))),
+		sm.Where(psql.Raw("name = ANY(?)", names)),
+		sm.ForUpdate(),
+	).Build(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build the images query: %w", err)
+	}
+
+	tx, err := s.images.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err = tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			s.logger.ErrorContext(ctx, "failed to rollback transaction", "error", err)
+		}
+	}()
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query images: %w", err)
+	}
+	objects := make(map[string][]byte, len(items))
+	for rows.Next() {
+		var name string
+		var object []byte
+		if err = rows.Scan(&name, &object); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan images: %w", err)
+		}
+		objects[name] = object
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read images: %w", err)
+	}
+
+	results, updatedImages, err := applyImageStatusUpdates(items, objects)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updates := &pgx.Batch{}
+	for _, image := range updatedImages {
+		var object []byte
+		object, err = json.Marshal(image)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal image %s: %w", image.Name, err)
+		}
+		updates.Queue(
+			fmt.Sprintf("UPDATE %s SET object = $1 WHERE name = $2 AND namespace = $3", pgx.Identifier{s.images.table}.Sanitize()),
+			object, image.Name, namespace,
+		)
+	}
+	if err = tx.SendBatch(ctx, updates).Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to update images: %w", err)
+	}
+
+	if s.images.writeHook != nil {
+		for _, image := range updatedImages {
+			if err = s.images.writeHook(ctx, tx, image.Name, namespace, image); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if dryRun {
+		return results, updatedImages, nil
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return results, updatedImages, nil
+}
+
+// applyImageStatusUpdates merges the status updates into the stored images, and increments their resource versions.
+// The images missing from the stored objects are reported as not found,
+// and the images whose merged status is invalid are reported as invalid.
+func applyImageStatusUpdates(
+	items []v1alpha1.ImageStatusUpdate,
+	objects map[string][]byte,
+) ([]v1alpha1.ImageStatusUpdateResult, []*v1alpha1.Image, error) {
+	versioner := storage.APIObjectVersioner{}
+
+	results := make([]v1alpha1.ImageStatusUpdateResult, 0, len(items))
+	var updatedImages []*v1alpha1.Image
+	for _, item := range items {
+		object, ok := objects[item.Name]
+		if !ok {
+			notFound := apierrors.NewNotFound(v1alpha1.Resource("images"), item.Name)
+			results = append(results, v1alpha1.ImageStatusUpdateResult{
+				Name:    item.Name,
+				Reason:  metav1.StatusReasonNotFound,
+				Message: notFound.Error(),
+			})
+			continue
+		}
+
+		image := &v1alpha1.Image{}
+		if err := json.Unmarshal(object, image); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal image %s: %w", item.Name, err)
+		}
+		version, err := versioner.ObjectResourceVersion(image)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read the resource version of image %s: %w", item.Name, err)
+		}
+		mergeImageStatus(&image.Status, item.Status)
+		// The status is validated as an update of the image would be.
+		if errs := validateImageStatus(&image.Status, field.NewPath("status")); len(errs) > 0 {
+			invalid := apierrors.NewInvalid(v1alpha1.Kind("Image"), item.Name, errs)
+			results = append(results, v1alpha1.ImageStatusUpdateResult{
+				Name:    item.Name,
+				Reason:  metav1.StatusReasonInvalid,
+				Message: invalid.Error(),
+			})
+			continue
+		}
+		if err = versioner.UpdateObject(image, version+1); err != nil {
+			return nil, nil, fmt.Errorf("failed to update the resource version of image %s: %w", item.Name, err)
+		}
+
+		updatedImages = append(updatedImages, image)
+		results = append(results, v1alpha1.ImageStatusUpdateResult{Name: item.Name, Succeeded: true})
+	}
+
+	return results, updatedImages, nil
+}
+
+// mergeImageStatus merges the status update into the stored status of an image.
+// The last scan time never moves backwards, and a scan error which occurred after the update was made is kept,
+// so that the status written concurrently, e.g. by a failed scan, is not overwritten by an older update.
+func mergeImageStatus(stored *v1alpha1.ImageStatus, update v1alpha1.ImageStatus) {
+	// The update was made at its last scan time, or at the time of its scan error.
+	updatedAt := update.LastScannedAt
+	if update.LastError != nil && (updatedAt == nil || updatedAt.Before(&update.LastError.OccurredAt)) {
+		updatedAt = &update.LastError.OccurredAt
+	}
+
+	if update.LastScannedAt != nil && (stored.LastScannedAt == nil || stored.LastScannedAt.Before(update.LastScannedAt)) {
+		stored.LastScannedAt = update.LastScannedAt
+	}
+	if stored.LastError != nil && updatedAt != nil && updatedAt.Before(&stored.LastError.OccurredAt) {
+		return
+	}
+	stored.LastError = update.LastError
+}
+
+// validateImageStatusBatch checks that the batch lists at least one image, and at most one update per image.
+func validateImageStatusBatch(batch *v1alpha1.ImageStatusBatch) field.ErrorList {
+	var allErrs field.ErrorList
+
+	itemsPath := field.NewPath("spec").Child("items")
+	if len(batch.Spec.Items) == 0 {
+		allErrs = append(allErrs, field.Required(itemsPath, "at least one image status update is required"))
+	}
+
+	seen := sets.New[string]()
+	for i, item := range batch.Spec.Items {
+		namePath := itemsPath.Index(i).Child("name")
+		switch {
+		case item.Name == "":
+			allErrs = append(allErrs, field.Required(namePath, "the name of the image is required"))
+		case seen.Has(item.Name):
+			allErrs = append(allErrs, field.Duplicate(namePath, item.Name))
+		}
+		seen.Insert(item.Name)
+	}
+
+	return allErrs
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+func TestApplyImageStatusUpdates(t *testing.T) {
+	image, err := json.Marshal(&v1alpha1.Image{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "image",
+			Namespace:       "default",
+			ResourceVersion: "3",
+		},
+		ImageMetadata: v1alpha1.ImageMetadata{Repository: "kubewarden/sbomscanner"},
+	})
+	require.NoError(t, err)
+
+	lastScannedAt := metav1.NewTime(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	results, updatedImages, err := applyImageStatusUpdates(
+		[]v1alpha1.ImageStatusUpdate{
+			{Name: "image", Status: v1alpha1.ImageStatus{LastScannedAt: &lastScannedAt}},
+			{Name: "missing", Status: v1alpha1.ImageStatus{LastScannedAt: &lastScannedAt}},
+			{Name: "invalid", Status: v1alpha1.ImageStatus{LastError: &v1alpha1.ImageScanError{Reason: "Unknown", OccurredAt: lastScannedAt}}},
+		},
+		map[string][]byte{"image": image, "invalid": image},
+	)
+	require.NoError(t, err)
+
+	require.Len(t, results, 3)
+	assert.Equal(t, v1alpha1.ImageStatusUpdateResult{Name: "image", Succeeded: true}, results[0])
+	assert.Equal(t, v1alpha1.ImageStatusUpdateResult{
+		Name:    "missing",
+		Reason:  metav1.StatusReasonNotFound,
+		Message: `images.storage.sbomscanner.kubewarden.io "missing" not found`,
+	}, results[1])
+	assert.Equal(t, "invalid", results[2].Name)
+	assert.False(t, results[2].Succeeded)
+	assert.Equal(t, metav1.StatusReasonInvalid, results[2].Reason)
+	assert.Contains(t, results[2].Message, "status.lastError.reason")
+
+	require.Len(t, updatedImages, 1)
+	assert.Equal(t, "4", updatedImages[0].ResourceVersion)
+	assert.Equal(t, &lastScannedAt, updatedImages[0].Status.LastScannedAt)
+	assert.Equal(t, "kubewarden/sbomscanner", updatedImages[0].Repository)
+}
+
+func TestMergeImageStatus(t *testing.T) {
+	before := metav1.NewTime(time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC))
+	scannedAt := metav1.NewTime(time.Date(2025, 1, 2, 4, 0, 0, 0, time.UTC))
+	after := metav1.NewTime(time.Date(2025, 1, 2, 5, 0, 0, 0, time.UTC))
+	scanError := func(occurredAt metav1.Time) *v1alpha1.ImageScanError {
+		return &v1alpha1.ImageScanError{Reason: v1alpha1.ImageScanErrorReasons[0], Message: "failed", OccurredAt: occurredAt}
+	}
+
+	tests := []struct {
+		name     string
+		stored   v1alpha1.ImageStatus
+		update   v1alpha1.ImageStatus
+		expected v1alpha1.ImageStatus
+	}{
+		{
+			name:     "first scan",
+			update:   v1alpha1.ImageStatus{LastScannedAt: &scannedAt},
+			expected: v1alpha1.ImageStatus{LastScannedAt: &scannedAt},
+		},
+		{
+			name:     "error of a previous scan cleared",
+			stored:   v1alpha1.ImageStatus{LastScannedAt: &before, LastError: scanError(before)},
+			update:   v1alpha1.ImageStatus{LastScannedAt: &scannedAt},
+			expected: v1alpha1.ImageStatus{LastScannedAt: &scannedAt},
+		},
+		{
+			name:     "error of a later scan kept",
+			stored:   v1alpha1.ImageStatus{LastScannedAt: &before, LastError: scanError(after)},
+			update:   v1alpha1.ImageStatus{LastScannedAt: &scannedAt},
+			expected: v1alpha1.ImageStatus{LastScannedAt: &scannedAt, LastError: scanError(after)},
+		},
+		{
+			name:     "last scan time not moved backwards",
+			stored:   v1alpha1.ImageStatus{LastScannedAt: &after},
+			update:   v1alpha1.ImageStatus{LastScannedAt: &scannedAt},
+			expected: v1alpha1.ImageStatus{LastScannedAt: &after},
+		},
+		{
+			name:     "error recorded",
+			stored:   v1alpha1.ImageStatus{LastScannedAt: &before},
+			update:   v1alpha1.ImageStatus{LastScannedAt: &before, LastError: scanError(scannedAt)},
+			expected: v1alpha1.ImageStatus{LastScannedAt: &before, LastError: scanError(scannedAt)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := test.stored
+			mergeImageStatus(&status, test.update)
+			assert.Equal(t, test.expected, status)
+		})
+	}
+}
+
+func TestApplyImageStatusUpdatesInvalidObject(t *testing.T) {
+	_, _, err := applyImageStatusUpdates(
+		[]v1alpha1.ImageStatusUpdate{{Name: "image"}},
+		map[string][]byte{"image": []byte("not json")},
+	)
+	require.Error(t, err)
+}
+
+func TestValidateImageStatusBatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		items    []v1alpha1.ImageStatusUpdate
+		expected field.ErrorList
+	}{
+		{
+			name:  "valid",
+			items: []v1alpha1.ImageStatusUpdate{{Name: "image-1"}, {Name: "image-2"}},
+		},
+		{
+			name:     "no items",
+			expected: field.ErrorList{field.Required(field.NewPath("spec", "items"), "at least one image status update is required")},
+		},
+		{
+			name:     "missing name",
+			items:    []v1alpha1.ImageStatusUpdate{{Name: "image-1"}, {}},
+			expected: field.ErrorList{field.Required(field.NewPath("spec", "items").Index(1).Child("name"), "the name of the image is required")},
+		},
+		{
+			name:     "duplicate name",
+			items:    []v1alpha1.ImageStatusUpdate{{Name: "image-1"}, {Name: "image-1"}},
+			expected: field.ErrorList{field.Duplicate(field.NewPath("spec", "items").Index(1).Child("name"), "image-1")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			batch := &v1alpha1.ImageStatusBatch{Spec: v1alpha1.ImageStatusBatchSpec{Items: test.items}}
+			assert.Equal(t, test.expected, validateImageStatusBatch(batch))
+		})
+	}
+}
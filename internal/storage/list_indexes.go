@@ -0,0 +1,23 @@
+package storage
+
+// createListIndexesSQL creates the indexes used by the hot list queries,
+// which would otherwise scan the whole tables:
+//   - the namespaced and the paginated lists, and the metadata.name field selector, since the primary key starts with the name,
+//   - the imageMetadata.registry and imageMetadata.digest field selectors,
+//   - the top CVEs of the ClusterVulnerabilitySummary, covered by a partial index of the non suppressed findings.
+//
+// The expressions of the field selector indexes must match the ones built by buildFieldSelectorExpressions.
+const createListIndexesSQL = `
+CREATE INDEX IF NOT EXISTS images_namespace_name_idx ON images (namespace, name);
+CREATE INDEX IF NOT EXISTS images_registry_idx ON images ((object #>> '{imageMetadata,registry}'));
+CREATE INDEX IF NOT EXISTS images_digest_idx ON images ((object #>> '{imageMetadata,digest}'));
+CREATE INDEX IF NOT EXISTS sboms_namespace_name_idx ON sboms (namespace, name);
+CREATE INDEX IF NOT EXISTS sboms_registry_idx ON sboms ((object #>> '{imageMetadata,registry}'));
+CREATE INDEX IF NOT EXISTS sboms_digest_idx ON sboms ((object #>> '{imageMetadata,digest}'));
+CREATE INDEX IF NOT EXISTS vulnerabilityreports_namespace_name_idx ON vulnerabilityreports (namespace, name);
+CREATE INDEX IF NOT EXISTS vulnerabilityreports_registry_idx ON vulnerabilityreports ((object #>> '{imageMetadata,registry}'));
+CREATE INDEX IF NOT EXISTS vulnerabilityreports_digest_idx ON vulnerabilityreports ((object #>> '{imageMetadata,digest}'));
+CREATE INDEX IF NOT EXISTS vulnerability_findings_unsuppressed_cve_idx ON vulnerability_findings (cve)
+    INCLUDE (severity, namespace, report_name)
+    WHERE NOT suppressed;
+`
@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// TestListIndexes checks that the hot queries use the indexes, rather than scanning the tables,
+// so that a change of the queries does not silently drop the index usage.
+func TestListIndexes(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := postgres.Run(ctx,
+		"postgres:16-alpine",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpassword"),
+		postgres.BasicWaitStrategies(),
+	)
+	require.NoError(t, err, "failed to start postgres container")
+	t.Cleanup(func() {
+		require.NoError(t, pgContainer.Terminate(context.Background()), "failed to terminate postgres container")
+	})
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err, "failed to get connection string")
+
+	db, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err, "failed to create connection pool")
+	t.Cleanup(db.Close)
+
+	require.NoError(t, RunMigrations(ctx, db, ""))
+
+	listQuery := func(table, key, fieldSelector string) (string, []any) {
+		s := &store{table: table}
+		query, args, err := s.listQuery(ctx, key, matcher(labels.Everything(), mustParseFieldSelector(fieldSelector)))
+		require.NoError(t, err)
+		return query, args
+	}
+	paginatedListQuery := func(table, key string) (string, []any) {
+		s := &store{table: table}
+		predicate := matcher(labels.Everything(), fields.Everything())
+		predicate.Limit = 100
+		var err error
+		predicate.Continue, err = encodeListContinue(key, "default", "test")
+		require.NoError(t, err)
+		query, args, err := s.listQuery(ctx, key, predicate)
+		require.NoError(t, err)
+		return query, args
+	}
+
+	tests := []struct {
+		name  string
+		query func() (string, []any)
+		// index is the index expected to be used, if the planner has a single candidate.
+		index string
+	}{
+		{
+			name: "namespaced list",
+			query: func() (string, []any) {
+				return listQuery("images", "/storage.sbomscanner.kubewarden.io/images/default", "")
+			},
+			index: "images_namespace_name_idx",
+		},
+		{
+			name: "paginated cluster-wide list",
+			query: func() (string, []any) {
+				return paginatedListQuery("vulnerabilityreports", "/storage.sbomscanner.kubewarden.io/vulnerabilityreports")
+			},
+			index: "vulnerabilityreports_namespace_name_idx",
+		},
+		{
+			name: "name field selector",
+			query: func() (string, []any) {
+				return listQuery("sboms", "/storage.sbomscanner.kubewarden.io/sboms/default", "metadata.name=test")
+			},
+		},
+		{
+			name: "registry field selector",
+			query: func() (string, []any) {
+				return listQuery("vulnerabilityreports", "/storage.sbomscanner.kubewarden.io/vulnerabilityreports", "imageMetadata.registry=test")
+			},
+			index: "vulnerabilityreports_registry_idx",
+		},
+		{
+			name: "digest field selector",
+			query: func() (string, []any) {
+				return listQuery("sboms", "/storage.sbomscanner.kubewarden.io/sboms", "imageMetadata.digest=sha256:1234")
+			},
+			index: "sboms_digest_idx",
+		},
+		{
+			name: "CVE impact",
+			query: func() (string, []any) {
+				return cveImpactSQL, []any{"CVE-2021-44228"}
+			},
+			index: "vulnerability_findings_cve_idx",
+		},
+		{
+			name: "registry image counts",
+			query: func() (string, []any) {
+				query, args, err := registryImageCountsQuery(ctx, "default", "")
+				require.NoError(t, err)
+				return query, args
+			},
+		},
+		{
+			name: "cluster-wide registry image counts",
+			query: func() (string, []any) {
+				query, args, err := registryImageCountsQuery(ctx, "", "")
+				require.NoError(t, err)
+				return query, args
+			},
+			index: "images_namespace_registry_idx",
+		},
+		{
+			name: "cluster top CVEs",
+			query: func() (string, []any) {
+				return clusterTopCVEsSQL, []any{clusterVulnerabilitySummaryTopCVEs}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan := explain(t, db, test.query)
+
+			assert.NotContains(t, plan, "Seq Scan", plan)
+			if test.index != "" {
+				assert.Contains(t, plan, test.index, plan)
+			}
+		})
+	}
+}
+
+// explain returns the plan of the query, with the sequential scans disabled,
+// so that the planner does not prefer them on the empty tables.
+func explain(t *testing.T, db *pgxpool.Pool, query func() (string, []any)) string {
+	t.Helper()
+	ctx := context.Background()
+
+	conn, err := db.Acquire(ctx)
+	require.NoError(t, err)
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, "SET enable_seqscan = off")
+	require.NoError(t, err)
+	defer func() {
+		_, err := conn.Exec(ctx, "RESET enable_seqscan")
+		require.NoError(t, err)
+	}()
+
+	sql, args := query()
+	rows, err := conn.Query(ctx, "EXPLAIN "+sql, args...)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var line string
+		require.NoError(t, rows.Scan(&line))
+		plan = append(plan, line)
+	}
+	require.NoError(t, rows.Err())
+
+	return strings.Join(plan, "\n")
+}
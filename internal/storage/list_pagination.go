@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apiserver/pkg/storage"
+)
+
+// listResourceVersion is the resourceVersion of the lists and of their continue tokens,
+// since the storage has no global revision.
+const listResourceVersion = 1
+
+// errInvalidContinue is returned for the continue tokens not issued by a list of the same key.
+var errInvalidContinue = errors.New("invalid continue token")
+
+// encodeListContinue returns the continue token of the page ending with the object of namespace and name.
+// The token holds the key of the object relative to the list key, so that it cannot be used on another list.
+func encodeListContinue(key, namespace, name string) (string, error) {
+	startKey := name
+	// The key was validated by the list.
+	if listNamespace, _ := extractNamespace(key); listNamespace == "" {
+		startKey = namespace + "/" + name
+	}
+
+	return storage.EncodeContinue(key+"/"+startKey, key+"/", listResourceVersion)
+}
+
+// decodeListContinue returns the namespace and the name of the last object of the page of the continue token.
+// The namespace is empty for the tokens of the namespaced lists.
+func decodeListContinue(key, continueValue string) (string, string, error) {
+	keyPrefix := key + "/"
+	startKey, _, err := storage.DecodeContinue(continueValue, keyPrefix)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", errInvalidContinue, err)
+	}
+
+	listNamespace, err := extractNamespace(key)
+	if err != nil {
+		return "", "", err
+	}
+	parts := strings.Split(strings.TrimPrefix(startKey, keyPrefix), "/")
+	switch {
+	case listNamespace != "" && len(parts) == 1:
+		return "", parts[0], nil
+	case listNamespace == "" && len(parts) == 2:
+		return parts[0], parts[1], nil
+	}
+
+	return "", "", fmt.Errorf("%w: it was issued for another list", errInvalidContinue)
+}
+
+// newListTooLargeError returns the error of the unpaginated lists exceeding the maximum response size.
+func newListTooLargeError(table string, maxBytes int64) error {
+	return apierrors.NewBadRequest(fmt.Sprintf(
+		"the list of %s exceeds the maximum response size of %s: paginate it with the limit and continue parameters, "+
+			"e.g. with kubectl get --chunk-size, or narrow it with a namespace, a label selector or a field selector",
+		table, resource.NewQuantity(maxBytes, resource.BinarySI).String(),
+	))
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestListContinue(t *testing.T) {
+	tests := []struct {
+		name              string
+		key               string
+		namespace         string
+		expectedNamespace string
+	}{
+		{
+			name:              "cluster-wide list",
+			key:               keyPrefix,
+			namespace:         "default",
+			expectedNamespace: "default",
+		},
+		{
+			name:      "namespaced list",
+			key:       keyPrefix + "/default",
+			namespace: "default",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			continueValue, err := encodeListContinue(test.key, test.namespace, "test")
+			require.NoError(t, err)
+
+			namespace, name, err := decodeListContinue(test.key, continueValue)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedNamespace, namespace)
+			assert.Equal(t, "test", name)
+		})
+	}
+}
+
+func TestListContinue_Invalid(t *testing.T) {
+	clusterWide, err := encodeListContinue(keyPrefix, "default", "test")
+	require.NoError(t, err)
+
+	_, _, err = decodeListContinue(keyPrefix+"/default", clusterWide)
+	require.ErrorIs(t, err, errInvalidContinue, "a token of a cluster-wide list is rejected by a namespaced list")
+
+	_, _, err = decodeListContinue(keyPrefix, "not-a-token")
+	require.ErrorIs(t, err, errInvalidContinue)
+}
+
+func TestListQuery_Pagination(t *testing.T) {
+	s := &store{table: "sboms"}
+
+	predicate := matcher(labels.Everything(), fields.Everything())
+	predicate.Limit = 10
+	query, args, err := s.listQuery(t.Context(), keyPrefix, predicate)
+	require.NoError(t, err)
+	assert.Contains(t, query, `ORDER BY "namespace", "name"`)
+	assert.Contains(t, query, "LIMIT 11", "one more row than the limit is read to tell whether there is a next page")
+	assert.Empty(t, args)
+
+	predicate.Continue, err = encodeListContinue(keyPrefix, "default", "test")
+	require.NoError(t, err)
+	query, args, err = s.listQuery(t.Context(), keyPrefix, predicate)
+	require.NoError(t, err)
+	assert.Contains(t, query, `("namespace", "name") > ($1, $2)`)
+	assert.Equal(t, []any{"default", "test"}, args)
+
+	predicate.Continue, err = encodeListContinue(keyPrefix+"/default", "default", "test")
+	require.NoError(t, err)
+	query, args, err = s.listQuery(t.Context(), keyPrefix+"/default", predicate)
+	require.NoError(t, err)
+	assert.Contains(t, query, `"name" > $2`)
+	assert.Equal(t, []any{"default", "test"}, args)
+
+	query, _, err = s.listQuery(t.Context(), keyPrefix, matcher(labels.Everything(), fields.Everything()))
+	require.NoError(t, err)
+	assert.NotContains(t, query, "ORDER BY", "the unpaginated lists are not sorted")
+}
@@ -46,6 +46,7 @@ func getAttrs(obj runtime.Object) (labels.Set, fields.Set, error) {
 		"imageMetadata.tag":         imageMetadataAccessor.GetImageMetadata().Tag,
 		"imageMetadata.platform":    imageMetadataAccessor.GetImageMetadata().Platform,
 		"imageMetadata.digest":      imageMetadataAccessor.GetImageMetadata().Digest,
+		"imageMetadata.osVersion":   imageMetadataAccessor.GetImageMetadata().OSVersion,
 	}
 
 	return labels.Set(objMeta.GetLabels()), generic.MergeFieldsSets(selectableMetadata, selectableFields), nil
@@ -2,20 +2,95 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func RunMigrations(ctx context.Context, db *pgxpool.Pool) error {
-	if _, err := db.Exec(ctx, CreateImageTableSQL); err != nil {
-		return fmt.Errorf("creating image table: %w", err)
+// createSchemaMigrationsTableSQL creates the table recording the applied migrations.
+const createSchemaMigrationsTableSQL = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    name VARCHAR(253) PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+`
+
+// recordMigrationSQL records a migration as applied, keeping the time it was first applied.
+const recordMigrationSQL = `
+INSERT INTO schema_migrations (name) VALUES ($1)
+ON CONFLICT (name) DO NOTHING
+`
+
+// migrationsLockID is the key of the advisory lock held while the migrations are applied,
+// so that the storage replicas running them at startup do not apply them concurrently.
+const migrationsLockID = 0x73626f6d7363616e // "sbomscan"
+
+// migration is a named, idempotent schema change.
+type migration struct {
+	name string
+	sql  string
+}
+
+// migrations are applied in order by RunMigrations.
+// New migrations must be appended and must be idempotent.
+var migrations = []migration{
+	{name: "create_images_table", sql: CreateImageTableSQL},
+	{name: "create_sboms_table", sql: CreateSBOMTableSQL},
+	{name: "create_vulnerabilityreports_table", sql: CreateVulnerabilityReportTableSQL},
+	{name: "create_vulnerability_snapshots_table", sql: CreateVulnerabilitySnapshotTableSQL},
+	{name: "create_vulnerability_findings_table", sql: CreateVulnerabilityFindingsTableSQL},
+	{name: "backfill_vulnerability_findings", sql: backfillVulnerabilityFindingsSQL},
+	{name: "create_sbom_packages_table", sql: CreateSBOMPackagesTableSQL},
+	{name: "backfill_sbom_packages", sql: backfillSBOMPackagesSQL},
+	{name: "create_list_indexes", sql: createListIndexesSQL},
+	{name: "create_images_namespace_registry_index", sql: createImagesNamespaceRegistryIndexSQL},
+	{name: "add_vulnerability_findings_details", sql: addVulnerabilityFindingsDetailsSQL},
+	{name: "backfill_vulnerability_findings_details", sql: backfillVulnerabilityFindingsDetailsSQL},
+	{name: "create_vulnerability_snapshot_times_table", sql: createVulnerabilitySnapshotTimesTableSQL},
+}
+
+// RunMigrations applies the migrations and records them in the schema_migrations table.
+// The migrations are applied while holding an advisory lock, so concurrent calls wait for each other.
+// If schema is not empty, it is created if missing, and the connections are expected to have it as search_path,
+// so that the tables are created in it.
+func RunMigrations(ctx context.Context, db *pgxpool.Pool, schema string) (err error) {
+	// Advisory locks are held by the session, so all the statements run on the same connection.
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
 	}
-	if _, err := db.Exec(ctx, CreateSBOMTableSQL); err != nil {
-		return fmt.Errorf("creating sbom table: %w", err)
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationsLockID); err != nil {
+		return fmt.Errorf("acquiring migrations lock: %w", err)
+	}
+	defer func() {
+		if _, unlockErr := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationsLockID); unlockErr != nil {
+			// Close the connection, so that the lock is not kept by a connection returned to the pool.
+			_ = conn.Conn().Close(context.WithoutCancel(ctx))
+			err = errors.Join(err, fmt.Errorf("releasing migrations lock: %w", unlockErr))
+		}
+	}()
+
+	if schema != "" {
+		if _, err := conn.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+pgx.Identifier{schema}.Sanitize()); err != nil {
+			return fmt.Errorf("creating schema %s: %w", schema, err)
+		}
 	}
-	if _, err := db.Exec(ctx, CreateVulnerabilityReportTableSQL); err != nil {
-		return fmt.Errorf("creating vulnerability report table: %w", err)
+
+	if _, err := conn.Exec(ctx, createSchemaMigrationsTableSQL); err != nil {
+		return fmt.Errorf("creating schema migrations table: %w", err)
+	}
+
+	for _, migration := range migrations {
+		if _, err := conn.Exec(ctx, migration.sql); err != nil {
+			return fmt.Errorf("applying migration %s: %w", migration.name, err)
+		}
+		if _, err := conn.Exec(ctx, recordMigrationSQL, migration.name); err != nil {
+			return fmt.Errorf("recording migration %s: %w", migration.name, err)
+		}
 	}
 
 	return nil
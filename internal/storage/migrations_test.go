@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMigrationsAppendOnly checks that the released migrations are neither renamed, removed nor reordered,
+// since the databases which applied them would diverge from the new ones.
+func TestMigrationsAppendOnly(t *testing.T) {
+	released := []string{
+		"create_images_table",
+		"create_sboms_table",
+		"create_vulnerabilityreports_table",
+		"create_vulnerability_snapshots_table",
+		"create_vulnerability_findings_table",
+		"backfill_vulnerability_findings",
+		"create_sbom_packages_table",
+		"backfill_sbom_packages",
+		"create_list_indexes",
+		"create_images_namespace_registry_index",
+		"add_vulnerability_findings_details",
+		"backfill_vulnerability_findings_details",
+	}
+
+	names := make([]string, 0, len(migrations))
+	for _, migration := range migrations {
+		names = append(names, migration.name)
+	}
+	assert.GreaterOrEqual(t, len(names), len(released))
+	assert.Equal(t, released, names[:min(len(released), len(names))])
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		assert.False(t, seen[name], "duplicate migration %s", name)
+		seen[name] = true
+	}
+}
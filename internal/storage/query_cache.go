@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// The aggregate queries served from the QueryCache.
+const (
+	queryClusterVulnerabilitySummary = "clustervulnerabilitysummary"
+	queryCVEImpact                   = "cveimpact"
+	queryImagePackages               = "imagepackages"
+	queryRegistryImageCounts         = "registryimagecounts"
+)
+
+var (
+	queryCacheRequestsCounter = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "sbomscanner",
+			Name:           "query_cache_requests_total",
+			Help:           "Number of aggregate queries looked up in the query cache, by query and result (hit or miss).",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"query", "result"},
+	)
+
+	registerQueryCacheMetricsOnce sync.Once
+)
+
+// queryCacheKey identifies a cached result.
+// The namespace is part of the key, so that the results of a namespace are never served to another one;
+// it is empty for the cluster-wide queries.
+type queryCacheKey struct {
+	query     string
+	namespace string
+	params    string
+}
+
+type queryCacheEntry struct {
+	obj       runtime.Object
+	expiresAt time.Time
+}
+
+// QueryCache caches the results of the expensive read-only aggregate queries for a short TTL,
+// so that the dashboards polling the same aggregates do not run the queries every time.
+// The results are invalidated by the writes of the stores the queries read from.
+// It is safe for concurrent use. A nil QueryCache is disabled: every lookup runs the query.
+type QueryCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[queryCacheKey]queryCacheEntry
+	// generation is incremented by every invalidation, so that the results computed
+	// concurrently with a write are not cached.
+	generation uint64
+}
+
+// NewQueryCache creates a new QueryCache caching the results for the given TTL.
+// It returns nil, a disabled cache, when the TTL is not greater than zero.
+func NewQueryCache(ttl time.Duration) *QueryCache {
+	if ttl <= 0 {
+		return nil
+	}
+
+	registerQueryCacheMetricsOnce.Do(func() {
+		legacyregistry.MustRegister(queryCacheRequestsCounter)
+	})
+
+	return &QueryCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[queryCacheKey]queryCacheEntry{},
+	}
+}
+
+// Enabled returns true if the results are cached.
+func (c *QueryCache) Enabled() bool {
+	return c != nil
+}
+
+// GetOrCompute returns a copy of the cached result of the query with the given namespace and parameters.
+// When there is none, or when it expired, the result is computed and cached. The errors are not cached.
+func (c *QueryCache) GetOrCompute(
+	ctx context.Context,
+	query, namespace, params string,
+	compute func(ctx context.Context) (runtime.Object, error),
+) (runtime.Object, error) {
+	if c == nil {
+		return compute(ctx)
+	}
+
+	key := queryCacheKey{query: query, namespace: namespace, params: params}
+
+	c.mu.Lock()
+	entry, found := c.entries[key]
+	generation := c.generation
+	c.mu.Unlock()
+
+	if found && c.now().Before(entry.expiresAt) {
+		queryCacheRequestsCounter.WithLabelValues(query, "hit").Inc()
+		return entry.obj.DeepCopyObject(), nil
+	}
+	queryCacheRequestsCounter.WithLabelValues(query, "miss").Inc()
+
+	obj, err := compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation == generation {
+		c.removeExpired()
+		c.entries[key] = queryCacheEntry{obj: obj.DeepCopyObject(), expiresAt: c.now().Add(c.ttl)}
+	}
+
+	return obj, nil
+}
+
+// Invalidate drops the cached results of the namespace, and the cluster-wide ones which aggregate all the namespaces.
+// It is called after the writes of the objects of the namespace.
+func (c *QueryCache) Invalidate(namespace string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for key := range c.entries {
+		if key.namespace == namespace || key.namespace == "" {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// removeExpired drops the expired results, so that the parameters queried once do not stay in memory.
+// It must be called with the lock held.
+func (c *QueryCache) removeExpired() {
+	now := c.now()
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// countingQuery returns a query computing an ImagePackageList with the given namespace,
+// and the number of times it ran.
+func countingQuery(namespace string) (func(context.Context) (runtime.Object, error), *int) {
+	calls := 0
+	compute := func(context.Context) (runtime.Object, error) {
+		calls++
+		list := &v1alpha1.ImagePackageList{Items: []v1alpha1.ImagePackage{{}}}
+		list.Items[0].Namespace = namespace
+
+		return list, nil
+	}
+
+	return compute, &calls
+}
+
+func newTestQueryCache(t *testing.T, now *time.Time) *QueryCache {
+	t.Helper()
+
+	cache := NewQueryCache(time.Minute)
+	require.NotNil(t, cache)
+	cache.now = func() time.Time { return *now }
+
+	return cache
+}
+
+func TestQueryCacheTTL(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTestQueryCache(t, &now)
+	compute, calls := countingQuery("default")
+
+	for range 3 {
+		_, err := cache.GetOrCompute(t.Context(), queryImagePackages, "default", "name=musl", compute)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, *calls)
+
+	now = now.Add(time.Minute)
+	_, err := cache.GetOrCompute(t.Context(), queryImagePackages, "default", "name=musl", compute)
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls, "the expired result is computed again")
+}
+
+func TestQueryCacheKeys(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTestQueryCache(t, &now)
+
+	computeDefault, defaultCalls := countingQuery("default")
+	computeOther, otherCalls := countingQuery("other")
+
+	obj, err := cache.GetOrCompute(t.Context(), queryImagePackages, "default", "name=musl", computeDefault)
+	require.NoError(t, err)
+	assert.Equal(t, "default", obj.(*v1alpha1.ImagePackageList).Items[0].Namespace)
+
+	obj, err = cache.GetOrCompute(t.Context(), queryImagePackages, "other", "name=musl", computeOther)
+	require.NoError(t, err)
+	assert.Equal(t, "other", obj.(*v1alpha1.ImagePackageList).Items[0].Namespace, "the namespaces do not share the results")
+
+	_, err = cache.GetOrCompute(t.Context(), queryImagePackages, "default", "name=busybox", computeDefault)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, *defaultCalls, "the parameters are part of the key")
+	assert.Equal(t, 1, *otherCalls)
+}
+
+func TestQueryCacheReturnsCopies(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTestQueryCache(t, &now)
+	compute, _ := countingQuery("default")
+
+	obj, err := cache.GetOrCompute(t.Context(), queryImagePackages, "default", "name=musl", compute)
+	require.NoError(t, err)
+	obj.(*v1alpha1.ImagePackageList).Items[0].Namespace = "modified"
+
+	obj, err = cache.GetOrCompute(t.Context(), queryImagePackages, "default", "name=musl", compute)
+	require.NoError(t, err)
+	assert.Equal(t, "default", obj.(*v1alpha1.ImagePackageList).Items[0].Namespace)
+}
+
+func TestQueryCacheInvalidate(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTestQueryCache(t, &now)
+
+	computeDefault, defaultCalls := countingQuery("default")
+	computeOther, otherCalls := countingQuery("other")
+	computeCluster, clusterCalls := countingQuery("")
+
+	lookup := func() {
+		_, err := cache.GetOrCompute(t.Context(), queryImagePackages, "default", "name=musl", computeDefault)
+		require.NoError(t, err)
+		_, err = cache.GetOrCompute(t.Context(), queryImagePackages, "other", "name=musl", computeOther)
+		require.NoError(t, err)
+		_, err = cache.GetOrCompute(t.Context(), queryCVEImpact, "", "CVE-2024-0001", computeCluster)
+		require.NoError(t, err)
+	}
+
+	lookup()
+	cache.Invalidate("default")
+	lookup()
+
+	assert.Equal(t, 2, *defaultCalls, "the results of the written namespace are invalidated")
+	assert.Equal(t, 1, *otherCalls, "the results of the other namespaces are kept")
+	assert.Equal(t, 2, *clusterCalls, "the cluster-wide results are invalidated")
+}
+
+func TestQueryCacheConcurrentInvalidation(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTestQueryCache(t, &now)
+	compute, calls := countingQuery("default")
+
+	// A write committed while the result is computed.
+	_, err := cache.GetOrCompute(t.Context(), queryImagePackages, "default", "name=musl", func(ctx context.Context) (runtime.Object, error) {
+		cache.Invalidate("default")
+		return compute(ctx)
+	})
+	require.NoError(t, err)
+
+	_, err = cache.GetOrCompute(t.Context(), queryImagePackages, "default", "name=musl", compute)
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls, "the result computed before the write is not cached")
+}
+
+func TestQueryCacheErrors(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTestQueryCache(t, &now)
+
+	calls := 0
+	failing := func(context.Context) (runtime.Object, error) {
+		calls++
+		return nil, errors.New("query failed")
+	}
+
+	for range 2 {
+		_, err := cache.GetOrCompute(t.Context(), queryCVEImpact, "", "CVE-2024-0001", failing)
+		require.ErrorContains(t, err, "query failed")
+	}
+	assert.Equal(t, 2, calls, "the errors are not cached")
+}
+
+func TestQueryCacheDisabled(t *testing.T) {
+	cache := NewQueryCache(0)
+	require.Nil(t, cache)
+	assert.False(t, cache.Enabled())
+
+	compute, calls := countingQuery("default")
+	for range 2 {
+		_, err := cache.GetOrCompute(t.Context(), queryImagePackages, "default", "name=musl", compute)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, *calls)
+
+	cache.Invalidate("default")
+}
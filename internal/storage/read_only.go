@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"errors"
+	"log/slog"
+	"sync/atomic"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// errReadOnly is the reason returned when a write operation is rejected in read-only mode.
+var errReadOnly = errors.New("storage in read-only mode")
+
+// ReadOnlyMode controls whether the stores reject the write operations.
+// It is safe for concurrent use, so that the mode can be toggled while the server is running.
+// A nil ReadOnlyMode is never read-only.
+type ReadOnlyMode struct {
+	enabled atomic.Bool
+	logger  *slog.Logger
+}
+
+// NewReadOnlyMode creates a new ReadOnlyMode with the given initial state.
+func NewReadOnlyMode(enabled bool, logger *slog.Logger) *ReadOnlyMode {
+	mode := &ReadOnlyMode{
+		logger: logger.With("component", "read_only_mode"),
+	}
+	mode.enabled.Store(enabled)
+
+	return mode
+}
+
+// Enabled returns true if the write operations must be rejected.
+func (m *ReadOnlyMode) Enabled() bool {
+	return m != nil && m.enabled.Load()
+}
+
+// Set enables or disables the read-only mode.
+func (m *ReadOnlyMode) Set(enabled bool) {
+	if m.enabled.Swap(enabled) != enabled {
+		m.logger.Info("Storage read-only mode changed", "readOnly", enabled)
+	}
+}
+
+// newReadOnlyError returns the error returned by the write operations on the given table in read-only mode.
+func newReadOnlyError(table, name string) error {
+	return apierrors.NewForbidden(v1alpha1.Resource(table), name, errReadOnly)
+}
@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stephenafamo/bob/dialect/psql"
+	"github.com/stephenafamo/bob/dialect/psql/sm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/registry/rest"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// createImagesNamespaceRegistryIndexSQL creates the index the image counts are grouped by,
+// with the same expression as the imageMetadata.registry field selector.
+const createImagesNamespaceRegistryIndexSQL = `
+CREATE INDEX IF NOT EXISTS images_namespace_registry_idx ON images (namespace, (object #>> '{imageMetadata,registry}'));
+`
+
+var (
+	_ rest.Storage              = &registryImageCountStore{}
+	_ rest.Getter               = &registryImageCountStore{}
+	_ rest.Lister               = &registryImageCountStore{}
+	_ rest.Scoper               = &registryImageCountStore{}
+	_ rest.SingularNameProvider = &registryImageCountStore{}
+)
+
+// registryImageCountStore serves the read-only RegistryImageCount resource.
+// The images are counted by the database, grouped by their imageMetadata.registry field,
+// so that the images are never read by the storage.
+type registryImageCountStore struct {
+	rest.TableConvertor
+
+	db         *pgxpool.Pool
+	queryCache *QueryCache
+	logger     *slog.Logger
+}
+
+// NewRegistryImageCountStore returns a read-only store for the RegistryImageCount resource.
+// The counts are cached in queryCache when it is enabled.
+func NewRegistryImageCountStore(db *pgxpool.Pool, queryCache *QueryCache, logger *slog.Logger) rest.Storage {
+	return &registryImageCountStore{
+		TableConvertor: &registryImageCountTableConvertor{},
+		db:             db,
+		queryCache:     queryCache,
+		logger:         logger.With("store", "registryimagecount"),
+	}
+}
+
+func (s *registryImageCountStore) New() runtime.Object {
+	return &v1alpha1.RegistryImageCount{}
+}
+
+func (s *registryImageCountStore) Destroy() {
+}
+
+func (s *registryImageCountStore) NewList() runtime.Object {
+	return &v1alpha1.RegistryImageCountList{}
+}
+
+func (s *registryImageCountStore) NamespaceScoped() bool {
+	return true
+}
+
+func (s *registryImageCountStore) GetSingularName() string {
+	return "registryimagecount"
+}
+
+// Get returns the number of images of the registry with the given name.
+// NotFound is returned when no image of the registry is stored.
+func (s *registryImageCountStore) Get(ctx context.Context, name string, _ *metav1.GetOptions) (runtime.Object, error) {
+	namespace, _ := genericapirequest.NamespaceFrom(ctx)
+
+	list, err := s.countImages(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Items) == 0 {
+		return nil, apierrors.NewNotFound(v1alpha1.Resource("registryimagecounts"), name)
+	}
+
+	return &list.Items[0], nil
+}
+
+// List returns the number of images of every registry of the namespace, or of all the namespaces.
+func (s *registryImageCountStore) List(ctx context.Context, _ *metainternalversion.ListOptions) (runtime.Object, error) {
+	namespace, _ := genericapirequest.NamespaceFrom(ctx)
+
+	return s.countImages(ctx, namespace, "")
+}
+
+// countImages counts the images of the namespace, of all the namespaces when it is empty,
+// grouped by registry. Only the given registry is counted when its name is not empty.
+func (s *registryImageCountStore) countImages(ctx context.Context, namespace, registry string) (*v1alpha1.RegistryImageCountList, error) {
+	query, args, err := registryImageCountsQuery(ctx, namespace, registry)
+	if err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+
+	obj, err := s.queryCache.GetOrCompute(ctx, queryRegistryImageCounts, namespace, registry, func(ctx context.Context) (runtime.Object, error) {
+		s.logger.DebugContext(ctx, "Counting images by registry", "namespace", namespace, "registry", registry)
+
+		return s.queryRegistryImageCounts(ctx, query, args)
+	})
+	if err != nil {
+		return nil, newAPIInternalError(ctx, err)
+	}
+
+	list, ok := obj.(*v1alpha1.RegistryImageCountList)
+	if !ok {
+		return nil, apierrors.NewInternalError(fmt.Errorf("unexpected type %T", obj))
+	}
+
+	return list, nil
+}
+
+// registryImageCountsQuery builds the query counting the images of the namespace grouped by registry,
+// scoped to the registry when its name is not empty.
+// The grouping follows the images_namespace_registry_idx index, so that the rows are not sorted.
+func registryImageCountsQuery(ctx context.Context, namespace, registry string) (string, []any, error) {
+	// The path is a literal, rather than an argument, so that the indexes on the expression can be used.
+	registryExpression := psql.Quote("object").OP("#>>", psql.S("{imageMetadata,registry}"))
+
+	queryBuilder := psql.Select(
+		sm.Columns(
+			psql.Quote("namespace"),
+			registryExpression,
+			psql.F("MAX", psql.Quote("object").OP("#>>", psql.S("{imageMetadata,registryURI}")))(),
+			psql.Raw("COUNT(*)"),
+		),
+		sm.From("images"),
+		sm.GroupBy(psql.Quote("namespace")),
+		sm.GroupBy(registryExpression),
+		sm.OrderBy(psql.Quote("namespace")),
+		sm.OrderBy(registryExpression),
+	)
+	if namespace != "" {
+		queryBuilder.Apply(sm.Where(psql.Quote("namespace").EQ(psql.Arg(namespace))))
+	}
+	if registry != "" {
+		queryBuilder.Apply(sm.Where(registryExpression.EQ(psql.Arg(registry))))
+	}
+
+	return queryBuilder.Build(ctx)
+}
+
+// queryRegistryImageCounts runs the query counting the images, one row per registry.
+func (s *registryImageCountStore) queryRegistryImageCounts(ctx context.Context, query string, args []any) (*v1alpha1.RegistryImageCountList, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count images by registry: %w", err)
+	}
+	defer rows.Close()
+
+	list := &v1alpha1.RegistryImageCountList{Items: []v1alpha1.RegistryImageCount{}}
+	for rows.Next() {
+		registryImageCount := v1alpha1.RegistryImageCount{
+			ObjectMeta: metav1.ObjectMeta{
+				CreationTimestamp: metav1.Now(),
+			},
+		}
+		err = rows.Scan(
+			&registryImageCount.Namespace,
+			&registryImageCount.Name,
+			&registryImageCount.RegistryURI,
+			&registryImageCount.Images,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan image counts: %w", err)
+		}
+		list.Items = append(list.Items, registryImageCount)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read image counts: %w", err)
+	}
+
+	return list, nil
+}
+
+type registryImageCountTableConvertor struct{}
+
+func (c *registryImageCountTableConvertor) ConvertToTable(_ context.Context, obj runtime.Object, _ runtime.Object) (*metav1.Table, error) {
+	table := &metav1.Table{
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "Name", Type: "string", Format: "name", Description: "Name of the registry"},
+			{Name: "Registry URI", Type: "string", Description: "URI of the registry"},
+			{Name: "Images", Type: "integer", Description: "Number of images of the registry"},
+		},
+		Rows: []metav1.TableRow{},
+	}
+
+	// Handle both single object and list
+	var registryImageCounts []v1alpha1.RegistryImageCount
+	switch t := obj.(type) {
+	case *v1alpha1.RegistryImageCountList:
+		registryImageCounts = t.Items
+	case *v1alpha1.RegistryImageCount:
+		registryImageCounts = []v1alpha1.RegistryImageCount{*t}
+	default:
+		return nil, fmt.Errorf("unexpected type %T", obj)
+	}
+
+	for _, registryImageCount := range registryImageCounts {
+		row := metav1.TableRow{
+			Object: runtime.RawExtension{Object: &registryImageCount},
+			Cells: []interface{}{
+				registryImageCount.Name,
+				registryImageCount.RegistryURI,
+				registryImageCount.Images,
+			},
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, nil
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+func TestRegistryImageCountsQuery(t *testing.T) {
+	tests := []struct {
+		name         string
+		namespace    string
+		registry     string
+		expectedArgs []any
+		expectWhere  bool
+	}{
+		{
+			name: "all namespaces",
+		},
+		{
+			name:         "namespace",
+			namespace:    "default",
+			expectedArgs: []any{"default"},
+			expectWhere:  true,
+		},
+		{
+			name:         "registry",
+			namespace:    "default",
+			registry:     "my-registry",
+			expectedArgs: []any{"default", "my-registry"},
+			expectWhere:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, args, err := registryImageCountsQuery(t.Context(), test.namespace, test.registry)
+			require.NoError(t, err)
+
+			assert.Contains(t, query, `GROUP BY "namespace", ("object" #>> '{imageMetadata,registry}')`)
+			if test.expectWhere {
+				assert.Contains(t, query, "WHERE")
+			} else {
+				assert.NotContains(t, query, "WHERE")
+			}
+			assert.Equal(t, test.expectedArgs, args)
+		})
+	}
+}
+
+func TestRegistryImageCountTableConvertor(t *testing.T) {
+	list := &v1alpha1.RegistryImageCountList{
+		Items: []v1alpha1.RegistryImageCount{
+			{
+				ObjectMeta:  metav1.ObjectMeta{Name: "docker-hub", Namespace: "default"},
+				RegistryURI: "docker.io",
+				Images:      1250,
+			},
+			{
+				ObjectMeta:  metav1.ObjectMeta{Name: "my-registry", Namespace: "default"},
+				RegistryURI: "ghcr.io",
+				Images:      87,
+			},
+		},
+	}
+
+	table, err := (&registryImageCountTableConvertor{}).ConvertToTable(t.Context(), list, nil)
+	require.NoError(t, err)
+	require.Len(t, table.Rows, 2)
+	assert.Equal(t, []any{"docker-hub", "docker.io", 1250}, table.Rows[0].Cells)
+	assert.Equal(t, []any{"my-registry", "ghcr.io", 87}, table.Rows[1].Cells)
+
+	table, err = (&registryImageCountTableConvertor{}).ConvertToTable(t.Context(), &list.Items[1], nil)
+	require.NoError(t, err)
+	require.Len(t, table.Rows, 1)
+	assert.Equal(t, "my-registry", table.Rows[0].Cells[0])
+}
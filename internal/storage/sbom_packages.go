@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// CreateSBOMPackagesTableSQL creates the table holding the packages listed in the SBOMs,
+// so that the images containing a package can be searched without parsing the SPDX documents.
+// The packages are deleted together with their SBOM.
+const CreateSBOMPackagesTableSQL = `
+CREATE TABLE IF NOT EXISTS sbom_packages (
+    namespace VARCHAR(253) NOT NULL,
+    sbom_name VARCHAR(253) NOT NULL,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL,
+    purl TEXT NOT NULL,
+    FOREIGN KEY (sbom_name, namespace) REFERENCES sboms (name, namespace) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS sbom_packages_name_version_idx ON sbom_packages (name, version);
+CREATE INDEX IF NOT EXISTS sbom_packages_purl_idx ON sbom_packages (purl);
+CREATE INDEX IF NOT EXISTS sbom_packages_sbom_idx ON sbom_packages (namespace, sbom_name);
+`
+
+// backfillSBOMPackagesSQL populates the packages of the SBOMs stored before the packages table existed.
+// The qualifiers and the subpath of the package URLs are removed, as done by spdxPackagesRows.
+const backfillSBOMPackagesSQL = `
+INSERT INTO sbom_packages (namespace, sbom_name, name, version, purl)
+SELECT
+    s.namespace,
+    s.name,
+    COALESCE(package->>'name', ''),
+    COALESCE(package->>'versionInfo', ''),
+    COALESCE(split_part(split_part((
+        SELECT ref->>'referenceLocator'
+        FROM jsonb_array_elements(COALESCE(package->'externalRefs', '[]'::jsonb)) AS ref
+        WHERE ref->>'referenceType' = 'purl'
+        LIMIT 1
+    ), '#', 1), '?', 1), '')
+FROM sboms s,
+    jsonb_path_query(s.object, 'lax $.spdx.packages[*]') AS package
+WHERE NOT EXISTS (
+    SELECT 1 FROM sbom_packages p
+    WHERE p.namespace = s.namespace AND p.sbom_name = s.name
+)
+`
+
+// deleteSBOMPackagesSQL deletes the packages of an SBOM, before they are written again.
+const deleteSBOMPackagesSQL = `
+DELETE FROM sbom_packages WHERE namespace = $1 AND sbom_name = $2
+`
+
+// spdxDocument contains the fields of an SPDX JSON document used to index the packages.
+type spdxDocument struct {
+	Packages []struct {
+		Name         string `json:"name"`
+		VersionInfo  string `json:"versionInfo"`
+		ExternalRefs []struct {
+			ReferenceType    string `json:"referenceType"`
+			ReferenceLocator string `json:"referenceLocator"`
+		} `json:"externalRefs"`
+	} `json:"packages"`
+}
+
+// writeSBOMPackages replaces the packages of the SBOM in the write transaction.
+// Deleted SBOMs do not need to be handled, since their packages are deleted in cascade.
+func writeSBOMPackages(ctx context.Context, tx pgx.Tx, name, namespace string, obj runtime.Object) error {
+	sbom, ok := obj.(*v1alpha1.SBOM)
+	if !ok {
+		return fmt.Errorf("unexpected type %T", obj)
+	}
+
+	if _, err := tx.Exec(ctx, deleteSBOMPackagesSQL, namespace, name); err != nil {
+		return fmt.Errorf("failed to delete SBOM packages: %w", err)
+	}
+
+	rows, err := spdxPackagesRows(name, namespace, sbom.SPDX.Raw)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"sbom_packages"},
+		[]string{"namespace", "sbom_name", "name", "version", "purl"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert SBOM packages: %w", err)
+	}
+
+	return nil
+}
+
+// spdxPackagesRows returns one row per package of the SPDX document.
+func spdxPackagesRows(name, namespace string, spdx []byte) ([][]any, error) {
+	rows := [][]any{}
+	if len(spdx) == 0 {
+		return rows, nil
+	}
+
+	var document spdxDocument
+	if err := json.Unmarshal(spdx, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse SPDX document: %w", err)
+	}
+
+	for _, spdxPackage := range document.Packages {
+		purl := ""
+		for _, ref := range spdxPackage.ExternalRefs {
+			if ref.ReferenceType == "purl" {
+				purl = normalizePURL(ref.ReferenceLocator)
+				break
+			}
+		}
+		rows = append(rows, []any{namespace, name, spdxPackage.Name, spdxPackage.VersionInfo, purl})
+	}
+
+	return rows, nil
+}
+
+// normalizePURL removes the qualifiers and the subpath of a package URL,
+// e.g. "pkg:rpm/redhat/openssl@3.0.7?arch=x86_64" becomes "pkg:rpm/redhat/openssl@3.0.7".
+func normalizePURL(purl string) string {
+	purl, _, _ = strings.Cut(purl, "#")
+	purl, _, _ = strings.Cut(purl, "?")
+
+	return purl
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSPDXPackagesRows(t *testing.T) {
+	spdx := []byte(`{
+		"spdxVersion": "SPDX-2.3",
+		"packages": [
+			{
+				"name": "log4j-core",
+				"versionInfo": "2.14.1",
+				"externalRefs": [
+					{"referenceType": "cpe23Type", "referenceLocator": "cpe:2.3:a:apache:log4j:2.14.1:*:*:*:*:*:*:*"},
+					{"referenceType": "purl", "referenceLocator": "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1"}
+				]
+			},
+			{
+				"name": "openssl",
+				"versionInfo": "3.0.7",
+				"externalRefs": [
+					{"referenceType": "purl", "referenceLocator": "pkg:rpm/redhat/openssl@3.0.7?arch=x86_64#docs"}
+				]
+			},
+			{
+				"name": "sha256:1234"
+			}
+		]
+	}`)
+
+	rows, err := spdxPackagesRows("sbom", "default", spdx)
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{
+		{"default", "sbom", "log4j-core", "2.14.1", "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1"},
+		{"default", "sbom", "openssl", "3.0.7", "pkg:rpm/redhat/openssl@3.0.7"},
+		{"default", "sbom", "sha256:1234", "", ""},
+	}, rows)
+}
+
+func TestSPDXPackagesRowsEmpty(t *testing.T) {
+	rows, err := spdxPackagesRows("sbom", "default", nil)
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+
+	_, err = spdxPackagesRows("sbom", "default", []byte(`{"packages": "invalid"}`))
+	require.Error(t, err)
+}
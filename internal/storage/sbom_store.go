@@ -7,6 +7,7 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	"github.com/stephenafamo/bob/dialect/psql"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
@@ -24,13 +25,21 @@ CREATE TABLE IF NOT EXISTS sboms (
 `
 
 // NewSBOMStore returns a store registry that will work against API services.
+// The SPDX documents are offloaded to documents when it is not nil.
+// The SBOMs with more than packageCountThreshold packages are reported with a warning, 0 disables the warnings.
+// The writes invalidate the results of queryCache.
 func NewSBOMStore(
 	scheme *runtime.Scheme,
 	optsGetter generic.RESTOptionsGetter,
 	db *pgxpool.Pool,
+	readOnly *ReadOnlyMode,
+	documents DocumentStorage,
+	queryCache *QueryCache,
+	packageCountThreshold int,
+	maxListBytes int64,
 	logger *slog.Logger,
 ) (*registry.Store, error) {
-	strategy := newSBOMStrategy(scheme)
+	strategy := newSBOMStrategy(scheme, packageCountThreshold)
 
 	newFunc := func() runtime.Object { return &v1alpha1.SBOM{} }
 	newListFunc := func() runtime.Object { return &v1alpha1.SBOMList{} }
@@ -43,12 +52,20 @@ func NewSBOMStore(
 		SingularQualifiedResource: v1alpha1.Resource("sbom"),
 		Storage: registry.DryRunnableStorage{
 			Storage: &store{
-				db:          db,
-				broadcaster: watch.NewBroadcaster(1000, watch.WaitIfChannelFull),
-				table:       "sboms",
-				newFunc:     newFunc,
-				newListFunc: newListFunc,
-				logger:      logger.With("store", "sbom"),
+				db:           db,
+				broadcaster:  watch.NewBroadcaster(1000, watch.WaitIfChannelFull),
+				table:        "sboms",
+				newFunc:      newFunc,
+				newListFunc:  newListFunc,
+				readOnly:     readOnly,
+				writeHook:    writeSBOMPackages,
+				documents:    newSBOMDocumentOffload(documents),
+				queryCache:   queryCache,
+				maxListBytes: maxListBytes,
+				logger:       logger.With("store", "sbom"),
+				computedFields: map[string]func() psql.Expression{
+					"imageMetadata.osVersion": osVersionExpression,
+				},
 			},
 		},
 		CreateStrategy: strategy,
@@ -65,12 +82,45 @@ func NewSBOMStore(
 	return store, nil
 }
 
+// newSBOMDocumentOffload returns the offload of the SPDX documents of the SBOMs,
+// nil when documents is nil.
+func newSBOMDocumentOffload(documents DocumentStorage) *documentOffload {
+	if documents == nil {
+		return nil
+	}
+
+	return &documentOffload{
+		storage: documents,
+		detach: func(obj runtime.Object) ([]byte, error) {
+			sbom, ok := obj.(*v1alpha1.SBOM)
+			if !ok {
+				return nil, fmt.Errorf("expected SBOM, got %T", obj)
+			}
+			document := sbom.SPDX.Raw
+			sbom.SPDX = runtime.RawExtension{}
+
+			return document, nil
+		},
+		attach: func(obj runtime.Object, document []byte) error {
+			sbom, ok := obj.(*v1alpha1.SBOM)
+			if !ok {
+				return fmt.Errorf("expected SBOM, got %T", obj)
+			}
+			sbom.SPDX = runtime.RawExtension{Raw: document}
+
+			return nil
+		},
+	}
+}
+
 type sbomTableConvertor struct{}
 
 func (c *sbomTableConvertor) ConvertToTable(_ context.Context, obj runtime.Object, _ runtime.Object) (*metav1.Table, error) {
 	table := &metav1.Table{
-		ColumnDefinitions: imageMetadataTableColumns(),
-		Rows:              []metav1.TableRow{},
+		ColumnDefinitions: append(imageMetadataTableColumns(),
+			metav1.TableColumnDefinition{Name: "Packages", Type: "integer", Description: "Number of packages of the SBOM"},
+		),
+		Rows: []metav1.TableRow{},
 	}
 
 	// Handle both single object and list
@@ -87,7 +137,7 @@ func (c *sbomTableConvertor) ConvertToTable(_ context.Context, obj runtime.Objec
 	for _, sbom := range sboms {
 		row := metav1.TableRow{
 			Object: runtime.RawExtension{Object: &sbom},
-			Cells:  imageMetadataTableRowCells(sbom.Name, &sbom),
+			Cells:  append(imageMetadataTableRowCells(sbom.Name, &sbom), sbom.PackageCount),
 		}
 		table.Rows = append(table.Rows, row)
 	}
@@ -1,40 +1,108 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
 
+	spdxjson "github.com/spdx/tools-golang/json"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/apiserver/pkg/storage/names"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+var (
+	largeSBOMsCounter = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "sbomscanner",
+			Name:           "large_sboms_total",
+			Help:           "Number of SBOMs stored with more packages than the package count warning threshold.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"namespace"},
+	)
+
+	registerSBOMMetricsOnce sync.Once
 )
 
-// newSBOMStrategy creates and returns a sbomStrategy instance
-func newSBOMStrategy(typer runtime.ObjectTyper) sbomStrategy {
-	return sbomStrategy{typer, names.SimpleNameGenerator}
+// newSBOMStrategy creates and returns a sbomStrategy instance.
+// The SBOMs with more than packageCountThreshold packages are reported, 0 disables the reports.
+func newSBOMStrategy(typer runtime.ObjectTyper, packageCountThreshold int) sbomStrategy {
+	if packageCountThreshold > 0 {
+		registerSBOMMetricsOnce.Do(func() {
+			legacyregistry.MustRegister(largeSBOMsCounter)
+		})
+	}
+
+	return sbomStrategy{typer, names.SimpleNameGenerator, packageCountThreshold}
 }
 
 type sbomStrategy struct {
 	runtime.ObjectTyper
 	names.NameGenerator
+	packageCountThreshold int
 }
 
 func (sbomStrategy) NamespaceScoped() bool {
 	return true
 }
 
-func (sbomStrategy) PrepareForCreate(_ context.Context, _ runtime.Object) {
+// PrepareForCreate normalizes the platform of the image and counts the packages of the SBOM before it is stored.
+func (sbomStrategy) PrepareForCreate(_ context.Context, obj runtime.Object) {
+	sbom := obj.(*v1alpha1.SBOM)
+	normalizeImageMetadata(&sbom.ImageMetadata)
+	sbom.PackageCount = countSPDXPackages(sbom.SPDX.Raw)
+}
+
+// PrepareForUpdate normalizes the platform of the image and counts the packages of the SBOM before it is stored.
+func (sbomStrategy) PrepareForUpdate(_ context.Context, obj, _ runtime.Object) {
+	sbom := obj.(*v1alpha1.SBOM)
+	normalizeImageMetadata(&sbom.ImageMetadata)
+	sbom.PackageCount = countSPDXPackages(sbom.SPDX.Raw)
+}
+
+// countSPDXPackages returns the number of packages of the SPDX document.
+// The invalid documents count no package, since they are rejected by the validation.
+func countSPDXPackages(spdx []byte) int {
+	var document struct {
+		Packages []json.RawMessage `json:"packages"`
+	}
+	if err := json.Unmarshal(spdx, &document); err != nil {
+		return 0
+	}
+
+	return len(document.Packages)
 }
 
-func (sbomStrategy) PrepareForUpdate(_ context.Context, _, _ runtime.Object) {
+// packageCountWarnings warns about the SBOMs with more packages than the threshold, e.g. the bloated images
+// whose scans are slow, and counts them in the large SBOMs metric.
+func (s sbomStrategy) packageCountWarnings(sbom *v1alpha1.SBOM) []string {
+	if s.packageCountThreshold <= 0 || sbom.PackageCount <= s.packageCountThreshold {
+		return nil
+	}
+	largeSBOMsCounter.WithLabelValues(sbom.Namespace).Inc()
+
+	return []string{fmt.Sprintf("the SBOM has %d packages, more than the package count warning threshold of %d",
+		sbom.PackageCount, s.packageCountThreshold)}
 }
 
-func (sbomStrategy) Validate(_ context.Context, _ runtime.Object) field.ErrorList {
-	return field.ErrorList{}
+// Validate rejects the SBOMs whose document is not a valid SPDX JSON document, or whose OS version is malformed.
+func (sbomStrategy) Validate(_ context.Context, obj runtime.Object) field.ErrorList {
+	sbom := obj.(*v1alpha1.SBOM)
+	allErrs := validateImageMetadata(&sbom.ImageMetadata, field.NewPath("imageMetadata"))
+	return append(allErrs, validateSPDX(sbom.SPDX.Raw, field.NewPath("spdx"))...)
 }
 
-// WarningsOnCreate returns warnings for the creation of the given object.
-func (sbomStrategy) WarningsOnCreate(_ context.Context, _ runtime.Object) []string {
-	return nil
+// WarningsOnCreate warns when the SBOM has more packages than the package count warning threshold.
+func (s sbomStrategy) WarningsOnCreate(_ context.Context, obj runtime.Object) []string {
+	return s.packageCountWarnings(obj.(*v1alpha1.SBOM))
 }
 
 func (sbomStrategy) AllowCreateOnUpdate() bool {
@@ -48,11 +116,40 @@ func (sbomStrategy) AllowUnconditionalUpdate() bool {
 func (sbomStrategy) Canonicalize(_ runtime.Object) {
 }
 
-func (sbomStrategy) ValidateUpdate(_ context.Context, _, _ runtime.Object) field.ErrorList {
-	return field.ErrorList{}
+// ValidateUpdate rejects the SBOMs whose document is not a valid SPDX JSON document, or whose OS version is malformed.
+func (sbomStrategy) ValidateUpdate(_ context.Context, obj, _ runtime.Object) field.ErrorList {
+	sbom := obj.(*v1alpha1.SBOM)
+	allErrs := validateImageMetadata(&sbom.ImageMetadata, field.NewPath("imageMetadata"))
+	return append(allErrs, validateSPDX(sbom.SPDX.Raw, field.NewPath("spdx"))...)
+}
+
+// validateSPDX checks that the document parses as SPDX JSON and has the fields identifying the document,
+// so that the packages and the vulnerabilities of the SBOM can be read from it later.
+func validateSPDX(data []byte, fldPath *field.Path) field.ErrorList {
+	if len(data) == 0 {
+		return field.ErrorList{field.Required(fldPath, "the SPDX document is required")}
+	}
+
+	doc, err := spdxjson.Read(bytes.NewReader(data))
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, field.OmitValueType{}, fmt.Sprintf("invalid SPDX JSON document: %v", err))}
+	}
+
+	allErrs := field.ErrorList{}
+	if !strings.HasPrefix(doc.SPDXVersion, "SPDX-") {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("spdxVersion"), doc.SPDXVersion, "must be an SPDX version, e.g. SPDX-2.3"))
+	}
+	if doc.SPDXIdentifier != "DOCUMENT" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("SPDXID"), "SPDXRef-"+string(doc.SPDXIdentifier), "must be SPDXRef-DOCUMENT"))
+	}
+	if doc.DocumentName == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), "the document name is required"))
+	}
+
+	return allErrs
 }
 
-// WarningsOnUpdate returns warnings for the given update.
-func (sbomStrategy) WarningsOnUpdate(_ context.Context, _, _ runtime.Object) []string {
-	return nil
+// WarningsOnUpdate warns when the SBOM has more packages than the package count warning threshold.
+func (s sbomStrategy) WarningsOnUpdate(_ context.Context, obj, _ runtime.Object) []string {
+	return s.packageCountWarnings(obj.(*v1alpha1.SBOM))
 }
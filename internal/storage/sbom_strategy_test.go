@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+func TestSBOMStrategyValidate(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("..", "..", "test", "fixtures", "golang-1.12-alpine-amd64.spdx.json"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		spdx           []byte
+		expectedErrors []string
+	}{
+		{
+			name: "valid document",
+			spdx: fixture,
+		},
+		{
+			name:           "missing document",
+			expectedErrors: []string{"spdx: Required value: the SPDX document is required"},
+		},
+		{
+			name:           "not JSON",
+			spdx:           []byte("not a document"),
+			expectedErrors: []string{"spdx: Invalid value: invalid SPDX JSON document"},
+		},
+		{
+			name:           "CycloneDX document",
+			spdx:           []byte(`{"bomFormat": "CycloneDX", "specVersion": "1.5"}`),
+			expectedErrors: []string{"spdx: Invalid value: invalid SPDX JSON document"},
+		},
+		{
+			name: "missing name",
+			spdx: []byte(`{"spdxVersion": "SPDX-2.3", "SPDXID": "SPDXRef-DOCUMENT", "dataLicense": "CC0-1.0"}`),
+			expectedErrors: []string{
+				"spdx.name: Required value: the document name is required",
+			},
+		},
+		{
+			name: "unexpected SPDXID",
+			spdx: []byte(`{"spdxVersion": "SPDX-2.3", "SPDXID": "SPDXRef-Package", "name": "test"}`),
+			expectedErrors: []string{
+				`spdx.SPDXID: Invalid value: "SPDXRef-Package": must be SPDXRef-DOCUMENT`,
+			},
+		},
+	}
+
+	strategy := newSBOMStrategy(runtime.NewScheme(), 0)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sbom := &v1alpha1.SBOM{
+				SPDX: runtime.RawExtension{Raw: test.spdx},
+			}
+
+			for _, errs := range []field.ErrorList{
+				strategy.Validate(t.Context(), sbom),
+				strategy.ValidateUpdate(t.Context(), sbom, &v1alpha1.SBOM{}),
+			} {
+				require.Len(t, errs, len(test.expectedErrors), errs.ToAggregate())
+				for i, expectedError := range test.expectedErrors {
+					assert.Contains(t, errs[i].Error(), expectedError)
+				}
+			}
+		})
+	}
+}
+
+func TestSBOMStrategyPackageCount(t *testing.T) {
+	spdx := []byte(`{"spdxVersion": "SPDX-2.3", "packages": [{"name": "musl"}, {"name": "busybox"}, {"name": "zlib"}]}`)
+
+	tests := []struct {
+		name             string
+		threshold        int
+		expectedWarnings []string
+	}{
+		{
+			name:      "disabled",
+			threshold: 0,
+		},
+		{
+			name:      "below the threshold",
+			threshold: 3,
+		},
+		{
+			name:      "above the threshold",
+			threshold: 2,
+			expectedWarnings: []string{
+				"the SBOM has 3 packages, more than the package count warning threshold of 2",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			strategy := newSBOMStrategy(runtime.NewScheme(), test.threshold)
+			sbom := &v1alpha1.SBOM{SPDX: runtime.RawExtension{Raw: spdx}}
+
+			strategy.PrepareForCreate(t.Context(), sbom)
+			assert.Equal(t, 3, sbom.PackageCount)
+			assert.Equal(t, test.expectedWarnings, strategy.WarningsOnCreate(t.Context(), sbom))
+
+			sbom.PackageCount = 0
+			strategy.PrepareForUpdate(t.Context(), sbom, &v1alpha1.SBOM{})
+			assert.Equal(t, 3, sbom.PackageCount)
+			assert.Equal(t, test.expectedWarnings, strategy.WarningsOnUpdate(t.Context(), sbom, &v1alpha1.SBOM{}))
+		})
+	}
+}
+
+func TestCountSPDXPackages(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("..", "..", "test", "fixtures", "golang-1.12-alpine-amd64.spdx.json"))
+	require.NoError(t, err)
+
+	assert.Positive(t, countSPDXPackages(fixture))
+	assert.Zero(t, countSPDXPackages([]byte(`{"spdxVersion": "SPDX-2.3"}`)))
+	assert.Zero(t, countSPDXPackages([]byte(`not json`)))
+}
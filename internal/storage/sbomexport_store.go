@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/registry/rest"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+var (
+	_ rest.Storage              = &sbomExportStore{}
+	_ rest.Getter               = &sbomExportStore{}
+	_ rest.Scoper               = &sbomExportStore{}
+	_ rest.SingularNameProvider = &sbomExportStore{}
+
+	_ rest.ResourceStreamer = &sbomExportStreamer{}
+)
+
+// sbomExportPageSize is the number of SBOMs read at once while an export is streamed,
+// so that the SBOMs of the namespace are never all held in memory.
+const sbomExportPageSize = 100
+
+// sbomExportManifestFile is the name of the manifest of the export archives, written after the SBOMs.
+const sbomExportManifestFile = "manifest.json"
+
+// sbomExportManifest lists the SBOMs of an export archive.
+type sbomExportManifest struct {
+	Namespace  string            `json:"namespace"`
+	ExportedAt time.Time         `json:"exportedAt"`
+	SBOMs      []sbomExportEntry `json:"sboms"`
+}
+
+// sbomExportEntry is the manifest entry of an SBOM of an export archive.
+type sbomExportEntry struct {
+	Name          string                 `json:"name"`
+	File          string                 `json:"file"`
+	Size          int64                  `json:"size"`
+	SHA256        string                 `json:"sha256"`
+	ImageMetadata v1alpha1.ImageMetadata `json:"imageMetadata"`
+}
+
+// sbomExportStore serves the get-only SBOMExport resource, streaming the archive of the SBOMs of a namespace.
+type sbomExportStore struct {
+	sboms      rest.Lister
+	authorizer authorizer.Authorizer
+	logger     *slog.Logger
+}
+
+// NewSBOMExportStore returns a get-only store for the SBOMExport resource, exporting the SBOMs of the given SBOM store.
+// The requests are authorized to list the SBOMs with the given authorizer.
+func NewSBOMExportStore(sbomStore rest.Lister, authz authorizer.Authorizer, logger *slog.Logger) rest.Storage {
+	return &sbomExportStore{
+		sboms:      sbomStore,
+		authorizer: authz,
+		logger:     logger.With("store", "sbomexport"),
+	}
+}
+
+func (s *sbomExportStore) New() runtime.Object {
+	return &v1alpha1.SBOMExport{}
+}
+
+func (s *sbomExportStore) Destroy() {
+}
+
+func (s *sbomExportStore) NamespaceScoped() bool {
+	return true
+}
+
+func (s *sbomExportStore) GetSingularName() string {
+	return "sbomexport"
+}
+
+// Get returns the streamer of the archive of the SBOMs of the namespace, named after the export.
+// The requester must also be allowed to list the SBOMs of the namespace, so that the export does not bypass their RBAC.
+func (s *sbomExportStore) Get(ctx context.Context, name string, _ *metav1.GetOptions) (runtime.Object, error) {
+	namespace, ok := genericapirequest.NamespaceFrom(ctx)
+	if !ok || namespace == "" {
+		return nil, apierrors.NewBadRequest("the namespace of the SBOM export is required")
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return nil, apierrors.NewInvalid(
+			v1alpha1.SchemeGroupVersion.WithKind("SBOMExport").GroupKind(),
+			name,
+			field.ErrorList{field.Invalid(field.NewPath("metadata", "name"), name, errs[0])},
+		)
+	}
+
+	if err := s.authorizeListSBOMs(ctx, namespace); err != nil {
+		return nil, err
+	}
+
+	return &sbomExportStreamer{
+		sboms:     s.sboms,
+		namespace: namespace,
+		name:      name,
+		logger:    s.logger,
+	}, nil
+}
+
+// authorizeListSBOMs checks that the requester is allowed to list the SBOMs of the namespace.
+func (s *sbomExportStore) authorizeListSBOMs(ctx context.Context, namespace string) error {
+	user, ok := genericapirequest.UserFrom(ctx)
+	if !ok {
+		return apierrors.NewForbidden(v1alpha1.Resource("sboms"), "", errors.New("the requester is unknown"))
+	}
+
+	decision, reason, err := s.authorizer.Authorize(ctx, authorizer.AttributesRecord{
+		User:            user,
+		Verb:            "list",
+		Namespace:       namespace,
+		APIGroup:        v1alpha1.GroupName,
+		APIVersion:      v1alpha1.SchemeGroupVersion.Version,
+		Resource:        "sboms",
+		ResourceRequest: true,
+	})
+	if err != nil {
+		return apierrors.NewInternalError(fmt.Errorf("authorizing the list of the SBOMs: %w", err))
+	}
+	if decision != authorizer.DecisionAllow {
+		return apierrors.NewForbidden(v1alpha1.Resource("sboms"), "",
+			fmt.Errorf("the requester cannot list the SBOMs of the namespace %s: %s", namespace, reason))
+	}
+
+	return nil
+}
+
+// sbomExportStreamer streams the archive of the SBOMs of a namespace, reading them one page at a time.
+type sbomExportStreamer struct {
+	sboms     rest.Lister
+	namespace string
+	name      string
+	logger    *slog.Logger
+}
+
+// GetObjectKind implements the runtime.Object interface, the streamer has no kind.
+func (s *sbomExportStreamer) GetObjectKind() schema.ObjectKind {
+	return schema.EmptyObjectKind
+}
+
+// DeepCopyObject implements the runtime.Object interface.
+func (s *sbomExportStreamer) DeepCopyObject() runtime.Object {
+	streamer := *s
+	return &streamer
+}
+
+// InputStream returns the archive, written while it is read.
+// The SBOMs are read from the store as the archive is consumed, and the writing stops when the reader is closed.
+func (s *sbomExportStreamer) InputStream(ctx context.Context, _, _ string) (io.ReadCloser, bool, string, error) {
+	reader, writer := io.Pipe()
+	go func() {
+		err := s.write(ctx, writer)
+		if err != nil && !errors.Is(err, io.ErrClosedPipe) && ctx.Err() == nil {
+			s.logger.ErrorContext(ctx, "Failed to export the SBOMs", "namespace", s.namespace, "name", s.name, "error", err)
+		}
+		writer.CloseWithError(err)
+	}()
+
+	return reader, false, v1alpha1.SBOMExportContentType, nil
+}
+
+// write writes the gzip-compressed tar of the SBOMs of the namespace, followed by its manifest.
+func (s *sbomExportStreamer) write(ctx context.Context, w io.Writer) error {
+	// The namespace of the request is not in the context of the stream.
+	ctx = genericapirequest.WithNamespace(ctx, s.namespace)
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	manifest := sbomExportManifest{
+		Namespace:  s.namespace,
+		ExportedAt: time.Now().UTC().Truncate(time.Second),
+		SBOMs:      []sbomExportEntry{},
+	}
+
+	options := &metainternalversion.ListOptions{Limit: sbomExportPageSize}
+	for {
+		obj, err := s.sboms.List(ctx, options)
+		if err != nil {
+			return fmt.Errorf("listing the SBOMs: %w", err)
+		}
+		list, ok := obj.(*v1alpha1.SBOMList)
+		if !ok {
+			return fmt.Errorf("unexpected SBOM list %T", obj)
+		}
+
+		for i := range list.Items {
+			sbom := &list.Items[i]
+			checksum := sha256.Sum256(sbom.SPDX.Raw)
+			entry := sbomExportEntry{
+				Name:          sbom.Name,
+				File:          path.Join("sboms", sbom.Name+".spdx.json"),
+				Size:          int64(len(sbom.SPDX.Raw)),
+				SHA256:        hex.EncodeToString(checksum[:]),
+				ImageMetadata: sbom.ImageMetadata,
+			}
+			if err := s.writeFile(tarWriter, entry.File, sbom.SPDX.Raw, manifest.ExportedAt); err != nil {
+				return err
+			}
+			manifest.SBOMs = append(manifest.SBOMs, entry)
+		}
+
+		if list.Continue == "" {
+			break
+		}
+		options.Continue = list.Continue
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling the manifest: %w", err)
+	}
+	if err := s.writeFile(tarWriter, sbomExportManifestFile, data, manifest.ExportedAt); err != nil {
+		return err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("closing the archive: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("closing the compression of the archive: %w", err)
+	}
+
+	return nil
+}
+
+// writeFile writes the file to the archive, in the directory named after the export.
+func (s *sbomExportStreamer) writeFile(tarWriter *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     path.Join(s.name, name),
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return fmt.Errorf("writing the header of %s: %w", header.Name, err)
+	}
+	if _, err := tarWriter.Write(data); err != nil {
+		return fmt.Errorf("writing %s: %w", header.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/registry/rest"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// fakeSBOMLister lists the SBOMs of a namespace one page at a time, the continue token being the offset of the page.
+type fakeSBOMLister struct {
+	rest.Lister
+	sboms []v1alpha1.SBOM
+	pages int
+}
+
+func (l *fakeSBOMLister) List(ctx context.Context, options *metainternalversion.ListOptions) (runtime.Object, error) {
+	if namespace, _ := genericapirequest.NamespaceFrom(ctx); namespace != "default" {
+		return nil, errors.New("unexpected namespace " + namespace)
+	}
+	l.pages++
+
+	offset := 0
+	if options.Continue != "" {
+		offset, _ = strconv.Atoi(options.Continue)
+	}
+	end := min(offset+int(options.Limit), len(l.sboms))
+
+	list := &v1alpha1.SBOMList{Items: l.sboms[offset:end]}
+	if end < len(l.sboms) {
+		list.Continue = strconv.Itoa(end)
+	}
+
+	return list, nil
+}
+
+func TestSBOMExportStoreGet(t *testing.T) {
+	sboms := make([]v1alpha1.SBOM, 150)
+	for i := range sboms {
+		sboms[i] = v1alpha1.SBOM{
+			ObjectMeta:    metav1.ObjectMeta{Name: "sbom-" + strconv.Itoa(i), Namespace: "default"},
+			ImageMetadata: v1alpha1.ImageMetadata{Repository: "kubewarden/sbomscanner", Tag: strconv.Itoa(i)},
+			SPDX:          runtime.RawExtension{Raw: []byte(`{"spdxVersion":"SPDX-2.3","name":"` + strconv.Itoa(i) + `"}`)},
+		}
+	}
+	lister := &fakeSBOMLister{sboms: sboms}
+	store := NewSBOMExportStore(lister, authorizer.AuthorizerFunc(func(context.Context, authorizer.Attributes) (authorizer.Decision, string, error) {
+		return authorizer.DecisionAllow, "", nil
+	}), slog.Default()).(*sbomExportStore)
+
+	ctx := genericapirequest.WithNamespace(t.Context(), "default")
+	ctx = genericapirequest.WithUser(ctx, &user.DefaultInfo{Name: "alice"})
+	obj, err := store.Get(ctx, "export", &metav1.GetOptions{})
+	require.NoError(t, err)
+
+	streamer, ok := obj.(rest.ResourceStreamer)
+	require.True(t, ok)
+	stream, flush, contentType, err := streamer.InputStream(t.Context(), "v1alpha1", "*/*")
+	require.NoError(t, err)
+	defer stream.Close()
+	assert.False(t, flush)
+	assert.Equal(t, v1alpha1.SBOMExportContentType, contentType)
+
+	gzipReader, err := gzip.NewReader(stream)
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+
+	files := map[string][]byte{}
+	var names []string
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tarReader)
+		require.NoError(t, err)
+		files[header.Name] = data
+		names = append(names, header.Name)
+	}
+
+	require.Len(t, names, len(sboms)+1)
+	assert.Equal(t, "export/sboms/sbom-0.spdx.json", names[0])
+	assert.Equal(t, "export/manifest.json", names[len(names)-1])
+	assert.Equal(t, sboms[42].SPDX.Raw, files["export/sboms/sbom-42.spdx.json"])
+	assert.Equal(t, 2, lister.pages)
+
+	var manifest sbomExportManifest
+	require.NoError(t, json.Unmarshal(files["export/manifest.json"], &manifest))
+	assert.Equal(t, "default", manifest.Namespace)
+	require.Len(t, manifest.SBOMs, len(sboms))
+	assert.Equal(t, "sbom-42", manifest.SBOMs[42].Name)
+	assert.Equal(t, "sboms/sbom-42.spdx.json", manifest.SBOMs[42].File)
+	assert.Equal(t, int64(len(sboms[42].SPDX.Raw)), manifest.SBOMs[42].Size)
+	assert.Len(t, manifest.SBOMs[42].SHA256, 64)
+	assert.Equal(t, "42", manifest.SBOMs[42].ImageMetadata.Tag)
+}
+
+func TestSBOMExportStoreGetForbidden(t *testing.T) {
+	var attributes authorizer.Attributes
+	store := NewSBOMExportStore(&fakeSBOMLister{}, authorizer.AuthorizerFunc(func(_ context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+		attributes = a
+		return authorizer.DecisionNoOpinion, "no RBAC policy matched", nil
+	}), slog.Default()).(*sbomExportStore)
+
+	ctx := genericapirequest.WithNamespace(t.Context(), "default")
+	ctx = genericapirequest.WithUser(ctx, &user.DefaultInfo{Name: "alice"})
+	_, err := store.Get(ctx, "export", &metav1.GetOptions{})
+	require.Error(t, err)
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.Contains(t, err.Error(), "no RBAC policy matched")
+
+	require.NotNil(t, attributes)
+	assert.Equal(t, "alice", attributes.GetUser().GetName())
+	assert.Equal(t, "list", attributes.GetVerb())
+	assert.Equal(t, "default", attributes.GetNamespace())
+	assert.Equal(t, v1alpha1.GroupName, attributes.GetAPIGroup())
+	assert.Equal(t, "sboms", attributes.GetResource())
+}
+
+func TestSBOMExportStoreGetInvalidName(t *testing.T) {
+	store := NewSBOMExportStore(&fakeSBOMLister{}, nil, slog.Default()).(*sbomExportStore)
+
+	ctx := genericapirequest.WithNamespace(t.Context(), "default")
+	_, err := store.Get(ctx, "Export_1", &metav1.GetOptions{})
+	require.Error(t, err)
+	assert.True(t, apierrors.IsInvalid(err))
+}
@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SchemaDump describes the database schema and the migration state of the storage.
+type SchemaDump struct {
+	// Migrations lists the migrations known by this version of the storage.
+	Migrations []MigrationState `json:"migrations"`
+	// Tables lists the tables of the current schema.
+	Tables []TableSchema `json:"tables"`
+}
+
+// MigrationState reports whether a migration has been applied.
+type MigrationState struct {
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// TableSchema describes a table, as reported by information_schema.
+type TableSchema struct {
+	Name     string         `json:"name"`
+	Columns  []ColumnSchema `json:"columns"`
+	RowCount int64          `json:"rowCount"`
+}
+
+// ColumnSchema describes a column, as reported by information_schema.
+type ColumnSchema struct {
+	Name                   string  `json:"name"`
+	DataType               string  `json:"dataType"`
+	CharacterMaximumLength *int64  `json:"characterMaximumLength,omitempty"`
+	Nullable               bool    `json:"nullable"`
+	Default                *string `json:"default,omitempty"`
+}
+
+const appliedMigrationsSQL = `
+SELECT name, applied_at
+FROM schema_migrations
+`
+
+const tablesSQL = `
+SELECT table_name
+FROM information_schema.tables
+WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+ORDER BY table_name
+`
+
+const columnsSQL = `
+SELECT table_name, column_name, data_type, character_maximum_length, is_nullable = 'YES', column_default
+FROM information_schema.columns
+WHERE table_schema = current_schema()
+ORDER BY table_name, ordinal_position
+`
+
+// DumpSchema returns the migration state, the table definitions and the row counts of the database.
+// All the queries run in a read-only transaction, so that the dump is safe to run against production.
+func DumpSchema(ctx context.Context, db *pgxpool.Pool) (*SchemaDump, error) {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly, IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return nil, fmt.Errorf("starting read-only transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	tables, err := dumpTables(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	migrationStates, err := dumpMigrations(ctx, tx, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SchemaDump{
+		Migrations: migrationStates,
+		Tables:     tables,
+	}, nil
+}
+
+// dumpTables returns the definitions and the row counts of the tables in the current schema.
+func dumpTables(ctx context.Context, tx pgx.Tx) ([]TableSchema, error) {
+	tableNames, err := queryStrings(ctx, tx, tablesSQL)
+	if err != nil {
+		return nil, fmt.Errorf("listing tables: %w", err)
+	}
+
+	tables := make([]TableSchema, 0, len(tableNames))
+	indexes := make(map[string]int, len(tableNames))
+	for _, name := range tableNames {
+		indexes[name] = len(tables)
+		tables = append(tables, TableSchema{Name: name, Columns: []ColumnSchema{}})
+	}
+
+	rows, err := tx.Query(ctx, columnsSQL)
+	if err != nil {
+		return nil, fmt.Errorf("listing columns: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			tableName string
+			column    ColumnSchema
+		)
+		if err := rows.Scan(&tableName, &column.Name, &column.DataType, &column.CharacterMaximumLength, &column.Nullable, &column.Default); err != nil {
+			return nil, fmt.Errorf("scanning column: %w", err)
+		}
+		// Skip the columns of the views.
+		if i, ok := indexes[tableName]; ok {
+			tables[i].Columns = append(tables[i].Columns, column)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading columns: %w", err)
+	}
+
+	for i := range tables {
+		query := "SELECT COUNT(*) FROM " + pgx.Identifier{tables[i].Name}.Sanitize()
+		if err := tx.QueryRow(ctx, query).Scan(&tables[i].RowCount); err != nil {
+			return nil, fmt.Errorf("counting rows of table %s: %w", tables[i].Name, err)
+		}
+	}
+
+	return tables, nil
+}
+
+// dumpMigrations returns the state of the migrations known by the storage.
+// Databases initialized before the migrations were recorded have no schema_migrations table,
+// in which case all the migrations are reported as not applied.
+func dumpMigrations(ctx context.Context, tx pgx.Tx, tables []TableSchema) ([]MigrationState, error) {
+	appliedAt := make(map[string]time.Time)
+
+	for _, table := range tables {
+		if table.Name != "schema_migrations" {
+			continue
+		}
+
+		rows, err := tx.Query(ctx, appliedMigrationsSQL)
+		if err != nil {
+			return nil, fmt.Errorf("listing applied migrations: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				name string
+				at   time.Time
+			)
+			if err := rows.Scan(&name, &at); err != nil {
+				return nil, fmt.Errorf("scanning applied migration: %w", err)
+			}
+			appliedAt[name] = at
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("reading applied migrations: %w", err)
+		}
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, migration := range migrations {
+		state := MigrationState{Name: migration.name}
+		if at, ok := appliedAt[migration.name]; ok {
+			state.Applied = true
+			state.AppliedAt = &at
+		}
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+// queryStrings returns the single string column of the rows returned by the query.
+func queryStrings(ctx context.Context, tx pgx.Tx, query string) ([]string, error) {
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Wrapped by the callers.
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string]) //nolint:wrapcheck // Wrapped by the callers.
+}
+
+// PendingMigrations returns the names of the migrations known by the storage which are not applied to the database.
+func PendingMigrations(ctx context.Context, db *pgxpool.Pool) ([]string, error) {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("starting read-only transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	tableNames, err := queryStrings(ctx, tx, tablesSQL)
+	if err != nil {
+		return nil, fmt.Errorf("listing tables: %w", err)
+	}
+	tables := make([]TableSchema, 0, len(tableNames))
+	for _, name := range tableNames {
+		tables = append(tables, TableSchema{Name: name})
+	}
+
+	states, err := dumpMigrations(ctx, tx, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for _, state := range states {
+		if !state.Applied {
+			pending = append(pending, state.Name)
+		}
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// slowQueryKey is the context key of the query traced by the SlowQueryTracer.
+type slowQueryKey struct{}
+
+// slowQuery is the query traced by the SlowQueryTracer.
+type slowQuery struct {
+	sql   string
+	start time.Time
+}
+
+// SlowQueryTracer is a pgx.QueryTracer logging the queries taking longer than the threshold,
+// to help finding the missing indexes without a tracing stack.
+// The queries run on behalf of an API request are logged with the operation and the namespace of the request.
+type SlowQueryTracer struct {
+	threshold time.Duration
+	logger    *slog.Logger
+}
+
+// NewSlowQueryTracer creates a new SlowQueryTracer logging the queries taking longer than the threshold.
+func NewSlowQueryTracer(threshold time.Duration, logger *slog.Logger) *SlowQueryTracer {
+	return &SlowQueryTracer{
+		threshold: threshold,
+		logger:    logger.With("component", "slow_query_tracer"),
+	}
+}
+
+// TraceQueryStart implements the pgx.QueryTracer interface.
+func (t *SlowQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, slowQueryKey{}, slowQuery{
+		sql:   data.SQL,
+		start: time.Now(),
+	})
+}
+
+// TraceQueryEnd implements the pgx.QueryTracer interface.
+// The rows of a query are read before the query ends, so the duration includes their transfer.
+func (t *SlowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	query, ok := ctx.Value(slowQueryKey{}).(slowQuery)
+	if !ok {
+		return
+	}
+
+	duration := time.Since(query.start)
+	if duration < t.threshold {
+		return
+	}
+
+	// The arguments are not logged, since they can contain the content of the documents.
+	attrs := []any{
+		"sql", strings.Join(strings.Fields(query.sql), " "),
+		"duration", duration,
+	}
+	if info, ok := request.RequestInfoFrom(ctx); ok {
+		attrs = append(attrs,
+			"operation", info.Verb,
+			"resource", info.Resource,
+			"namespace", info.Namespace,
+		)
+	}
+	if data.Err != nil {
+		attrs = append(attrs, "error", data.Err)
+	}
+
+	t.logger.WarnContext(ctx, "Slow query", attrs...)
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func TestSlowQueryTracer(t *testing.T) {
+	var logs bytes.Buffer
+	tracer := NewSlowQueryTracer(10*time.Millisecond, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	ctx := request.WithRequestInfo(t.Context(), &request.RequestInfo{
+		Verb:      "list",
+		Resource:  "vulnerabilityreports",
+		Namespace: "default",
+	})
+
+	// Fast queries are not logged.
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{})
+	assert.Empty(t, logs.String())
+
+	queryCtx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT object\n\tFROM vulnerabilityreports\n\tWHERE namespace = $1"})
+	time.Sleep(20 * time.Millisecond)
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{})
+
+	assert.Contains(t, logs.String(), `msg="Slow query"`)
+	assert.Contains(t, logs.String(), `sql="SELECT object FROM vulnerabilityreports WHERE namespace = $1"`)
+	assert.Contains(t, logs.String(), "operation=list resource=vulnerabilityreports namespace=default")
+}
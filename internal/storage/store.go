@@ -3,7 +3,6 @@ package storage
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -28,6 +27,7 @@ import (
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/apiserver/pkg/registry/generic/registry"
 	"k8s.io/apiserver/pkg/storage"
+	"k8s.io/apiserver/pkg/warning"
 )
 
 // objectSchema is the schema of an object in the database.
@@ -46,7 +46,22 @@ type store struct {
 	table       string
 	newFunc     func() runtime.Object
 	newListFunc func() runtime.Object
-	logger      *slog.Logger
+	readOnly    *ReadOnlyMode
+	// writeHook is called in the write transaction of the created and updated objects,
+	// to keep the tables derived from the objects in sync.
+	writeHook func(ctx context.Context, tx pgx.Tx, name, namespace string, obj runtime.Object) error
+	// computedFields are the field selectors not stored in the objects,
+	// mapped to the function building the SQL expression of their value at query time.
+	computedFields map[string]func() psql.Expression
+	// documents offloads the large documents of the objects to a document storage,
+	// the objects are stored whole in the rows when it is nil.
+	documents *documentOffload
+	// queryCache caches the aggregate queries reading the objects of the store,
+	// its results are invalidated by the writes.
+	queryCache *QueryCache
+	// maxListBytes is the maximum size of the objects of an unpaginated list or of a page, 0 for no limit.
+	maxListBytes int64
+	logger       *slog.Logger
 }
 
 // Versioner returns API object versioner associated with this interface.
@@ -65,13 +80,17 @@ func (s *store) Create(ctx context.Context, key string, obj, out runtime.Object,
 		return storage.NewInternalError(fmt.Errorf("invalid key: %s", key))
 	}
 
+	if s.readOnly.Enabled() {
+		return newReadOnlyError(s.table, name)
+	}
+
 	if err := s.Versioner().UpdateObject(obj, 1); err != nil {
-		return storage.NewInternalError(err)
+		return newInternalError(ctx, err)
 	}
 
-	bytes, err := json.Marshal(obj)
+	bytes, _, err := s.encodeObject(ctx, name, namespace, obj)
 	if err != nil {
-		return storage.NewInternalError(err)
+		return newInternalError(ctx, err)
 	}
 
 	query, args, err := psql.Insert(
@@ -80,20 +99,41 @@ func (s *store) Create(ctx context.Context, key string, obj, out runtime.Object,
 		im.OnConflict().DoNothing(),
 	).Build(ctx)
 	if err != nil {
-		return storage.NewInternalError(err)
+		return newInternalError(ctx, err)
 	}
 
-	result, err := s.db.Exec(ctx, query, args...)
+	tx, err := s.db.Begin(ctx)
 	if err != nil {
-		return storage.NewInternalError(err)
+		return newInternalError(ctx, err)
+	}
+	defer func() {
+		if err = tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			s.logger.ErrorContext(ctx, "failed to rollback transaction", "error", err)
+		}
+	}()
+
+	result, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		return newInternalError(ctx, err)
 	}
 
 	if result.RowsAffected() == 0 {
 		return storage.NewKeyExistsError(key, 0)
 	}
 
+	if s.writeHook != nil {
+		if err = s.writeHook(ctx, tx, name, namespace, obj); err != nil {
+			return newInternalError(ctx, err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return newInternalError(ctx, err)
+	}
+	s.queryCache.Invalidate(namespace)
+
 	if err = s.broadcaster.Action(watch.Added, obj); err != nil {
-		return storage.NewInternalError(err)
+		return newInternalError(ctx, err)
 	}
 
 	if out != nil {
@@ -121,9 +161,13 @@ func (s *store) Delete(
 		return storage.NewInternalError(fmt.Errorf("invalid key: %s", key))
 	}
 
+	if s.readOnly.Enabled() {
+		return newReadOnlyError(s.table, name)
+	}
+
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
-		return storage.NewInternalError(err)
+		return newInternalError(ctx, err)
 	}
 	defer func() {
 		if err = tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
@@ -148,11 +192,15 @@ func (s *store) Delete(
 		if errors.Is(err, pgx.ErrNoRows) {
 			return storage.NewKeyNotFoundError(key, 0)
 		}
-		return storage.NewInternalError(err)
+		return newInternalError(ctx, err)
 	}
 
-	if err = json.Unmarshal(objectRecord.Object, out); err != nil {
-		return storage.NewInternalError(err)
+	ref, err := s.decodeObject(objectRecord.Object, out)
+	if err != nil {
+		return newInternalError(ctx, err)
+	}
+	if err = s.loadDocument(ctx, out, ref); err != nil {
+		return newInternalError(ctx, err)
 	}
 
 	if err = preconditions.Check(key, out); err != nil {
@@ -164,11 +212,13 @@ func (s *store) Delete(
 	}
 
 	if err = tx.Commit(ctx); err != nil {
-		return storage.NewInternalError(err)
+		return newInternalError(ctx, err)
 	}
+	s.queryCache.Invalidate(namespace)
+	s.deleteDocument(ctx, ref)
 
 	if err = s.broadcaster.Action(watch.Deleted, out); err != nil {
-		return storage.NewInternalError(err)
+		return newInternalError(ctx, err)
 	}
 
 	return nil
@@ -193,8 +243,23 @@ func (s *store) Watch(ctx context.Context, key string, opts storage.ListOptions)
 		opts.ProgressNotify,
 	)
 
+	// The broadcaster sends the events of all the namespaces,
+	// they are filtered so that a namespaced watch only receives the events of its namespace.
+	_, namespace := extractNameAndNamespace(key)
+	if namespace == "" {
+		var err error
+		if namespace, err = extractNamespace(key); err != nil {
+			return nil, storage.NewInternalError(err)
+		}
+	}
+
 	if opts.ResourceVersion == "" {
-		return s.broadcaster.Watch()
+		watcher, err := s.broadcaster.Watch()
+		if err != nil {
+			return nil, err
+		}
+
+		return filterNamespace(watcher, namespace), nil
 	}
 
 	if opts.ResourceVersion == "0" {
@@ -203,7 +268,12 @@ func (s *store) Watch(ctx context.Context, key string, opts storage.ListOptions)
 			return nil, err
 		}
 
-		return s.broadcaster.WatchWithPrefix([]watch.Event{{Type: watch.Added, Object: obj}})
+		watcher, err := s.broadcaster.WatchWithPrefix([]watch.Event{{Type: watch.Added, Object: obj}})
+		if err != nil {
+			return nil, err
+		}
+
+		return filterNamespace(watcher, namespace), nil
 	}
 
 	listObj := s.newListFunc()
@@ -232,7 +302,29 @@ func (s *store) Watch(ctx context.Context, key string, opts storage.ListOptions)
 		})
 	}
 
-	return s.broadcaster.WatchWithPrefix(events)
+	watcher, err := s.broadcaster.WatchWithPrefix(events)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterNamespace(watcher, namespace), nil
+}
+
+// filterNamespace filters the events of the watcher to the objects of the namespace,
+// all the events are kept when the namespace is empty, for the cluster-wide watches.
+func filterNamespace(watcher watch.Interface, namespace string) watch.Interface {
+	if namespace == "" {
+		return watcher
+	}
+
+	return watch.Filter(watcher, func(event watch.Event) (watch.Event, bool) {
+		accessor, err := meta.Accessor(event.Object)
+		if err != nil {
+			return event, false
+		}
+
+		return event, accessor.GetNamespace() == namespace
+	})
 }
 
 // Get unmarshals object found at key into objPtr. On a not found error, will either
@@ -268,7 +360,7 @@ func (s *store) Get(ctx context.Context, key string, opts storage.GetOptions, ob
 		sm.Where(psql.Quote("namespace").EQ(psql.Arg(namespace))),
 	).Build(ctx)
 	if err != nil {
-		return storage.NewInternalError(err)
+		return newInternalError(ctx, err)
 	}
 
 	var objectRecord objectSchema
@@ -284,12 +376,18 @@ func (s *store) Get(ctx context.Context, key string, opts storage.GetOptions, ob
 			}
 			return storage.NewKeyNotFoundError(key, 0)
 		}
-		return storage.NewInternalError(err)
+		return newInternalError(ctx, err)
 	}
 
-	err = json.Unmarshal(objectRecord.Object, objPtr)
+	ref, err := s.decodeObject(objectRecord.Object, objPtr)
 	if err != nil {
-		return storage.NewInternalError(err)
+		return newInternalError(ctx, err)
+	}
+	if err = s.loadDocument(ctx, objPtr, ref); err != nil {
+		return newInternalError(ctx, err)
+	}
+	if ref != nil && ref.NotRetained {
+		warning.AddWarning(ctx, "", fmt.Sprintf("raw document not retained: %s %s/%s only keeps its normalized data", s.table, namespace, name))
 	}
 
 	return nil
@@ -299,8 +397,6 @@ func (s *store) Get(ctx context.Context, key string, opts storage.GetOptions, ob
 // that satisfies runtime.IsList definition).
 // The returned contents may be delayed, but it is guaranteed that they will
 // match 'opts.ResourceVersion' according 'opts.ResourceVersionMatch'.
-//
-//nolint:gocognit // This function can't be easily split into smaller parts.
 func (s *store) GetList(ctx context.Context, key string, opts storage.ListOptions, listObj runtime.Object) error {
 	s.logger.DebugContext(ctx, "Getting list",
 		"key", key,
@@ -311,46 +407,17 @@ func (s *store) GetList(ctx context.Context, key string, opts storage.ListOption
 		"continue", opts.Predicate.Continue,
 	)
 
-	queryBuilder := psql.Select(
-		sm.From(psql.Quote(s.table)),
-		sm.Columns("name", "namespace", "object"),
-	)
-
-	namespace := extractNamespace(key)
-	if namespace != "" {
-		queryBuilder.Apply(
-			sm.Where(psql.Quote("namespace").EQ(psql.Arg(namespace))),
-		)
-	}
-
-	if opts.Predicate.Label != nil {
-		labelSelectorExpressions, err := buildLabelSelectorExpressions(opts.Predicate.Label)
-		if err != nil {
-			return storage.NewInternalError(err)
-		}
-		for _, expression := range labelSelectorExpressions {
-			queryBuilder.Apply(sm.Where(expression))
-		}
-	}
-
-	if opts.Predicate.Field != nil {
-		fieldSelectorExpressions, err := buildFieldSelectorExpressions(opts.Predicate.Field)
-		if err != nil {
-			return storage.NewInternalError(err)
-		}
-		for _, expression := range fieldSelectorExpressions {
-			queryBuilder.Apply(sm.Where(expression))
-		}
-	}
-
-	query, args, err := queryBuilder.Build(ctx)
+	query, args, err := s.listQuery(ctx, key, opts.Predicate)
 	if err != nil {
-		return storage.NewInternalError(err)
+		if errors.Is(err, errInvalidContinue) {
+			return apierrors.NewBadRequest(err.Error())
+		}
+		return newInternalError(ctx, err)
 	}
 
 	rows, err := s.db.Query(ctx, query, args...)
 	if err != nil {
-		return storage.NewInternalError(err)
+		return newInternalError(ctx, err)
 	}
 	defer rows.Close()
 
@@ -359,6 +426,11 @@ func (s *store) GetList(ctx context.Context, key string, opts storage.ListOption
 		return err
 	}
 
+	var objs []runtime.Object
+	var refs []*documentReference
+	var last objectSchema
+	var size int64
+	hasMore := false
 	for rows.Next() {
 		var objectRecord objectSchema
 		err = rows.Scan(
@@ -367,30 +439,145 @@ func (s *store) GetList(ctx context.Context, key string, opts storage.ListOption
 			&objectRecord.Object,
 		)
 		if err != nil {
-			return storage.NewInternalError(err)
+			return newInternalError(ctx, err)
+		}
+		// The query reads one more row than the limit, to tell whether there is a next page.
+		if opts.Predicate.Limit > 0 && int64(len(objs)) == opts.Predicate.Limit {
+			hasMore = true
+			break
 		}
 
 		obj := s.newFunc()
-		if err = json.Unmarshal(objectRecord.Object, obj); err != nil {
-			return storage.NewInternalError(err)
+		var ref *documentReference
+		if ref, err = s.decodeObject(objectRecord.Object, obj); err != nil {
+			return newInternalError(ctx, err)
+		}
+
+		// The lists are bounded before the offloaded documents are read: the unpaginated lists are rejected,
+		// and the pages end early, with at least one object so that the pagination always progresses.
+		size += int64(len(objectRecord.Object))
+		if ref != nil {
+			size += ref.Size
+		}
+		if s.maxListBytes > 0 && size > s.maxListBytes {
+			if opts.Predicate.Limit == 0 {
+				return newListTooLargeError(s.table, s.maxListBytes)
+			}
+			if len(objs) > 0 {
+				hasMore = true
+				break
+			}
+		}
+
+		objs = append(objs, obj)
+		refs = append(refs, ref)
+		last = objectRecord
+	}
+
+	if err = rows.Err(); err != nil {
+		return newInternalError(ctx, err)
+	}
+	rows.Close()
+
+	// The offloaded documents are read once the rows are released, so that the connection is not held meanwhile.
+	if s.documents != nil {
+		if err = s.loadDocuments(ctx, objs, refs); err != nil {
+			return newInternalError(ctx, err)
 		}
+	}
 
+	for _, obj := range objs {
 		// Append the object to the items slice
 		itemsValue.Set(reflect.Append(itemsValue, reflect.ValueOf(obj).Elem()))
 	}
 
-	if err = rows.Err(); err != nil {
-		return storage.NewInternalError(err)
+	continueValue := ""
+	if hasMore {
+		if continueValue, err = encodeListContinue(key, last.Namespace, last.Name); err != nil {
+			return newInternalError(ctx, err)
+		}
 	}
 
-	// TODO: Implement pagination and use a proper resourceVersion
-	if err = s.Versioner().UpdateList(listObj, 1, "", nil); err != nil {
-		return storage.NewInternalError(err)
+	// TODO: use a proper resourceVersion
+	if err = s.Versioner().UpdateList(listObj, listResourceVersion, continueValue, nil); err != nil {
+		return newInternalError(ctx, err)
 	}
 
 	return nil
 }
 
+// listQuery builds the query listing the objects at key matching the predicate.
+func (s *store) listQuery(ctx context.Context, key string, predicate storage.SelectionPredicate) (string, []any, error) {
+	queryBuilder := psql.Select(
+		sm.From(psql.Quote(s.table)),
+		sm.Columns("name", "namespace", "object"),
+	)
+
+	// The cluster-wide lists have no namespace predicate, the authorizer of the API server
+	// only lets them through for the users allowed to list the resource in all the namespaces.
+	namespace, err := extractNamespace(key)
+	if err != nil {
+		return "", nil, err
+	}
+	if namespace != "" {
+		queryBuilder.Apply(
+			sm.Where(psql.Quote("namespace").EQ(psql.Arg(namespace))),
+		)
+	}
+
+	if predicate.Label != nil {
+		labelSelectorExpressions, err := buildLabelSelectorExpressions(predicate.Label)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, expression := range labelSelectorExpressions {
+			queryBuilder.Apply(sm.Where(expression))
+		}
+	}
+
+	if predicate.Field != nil {
+		fieldSelectorExpressions, err := buildFieldSelectorExpressions(predicate.Field, s.computedFields)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, expression := range fieldSelectorExpressions {
+			queryBuilder.Apply(sm.Where(expression))
+		}
+	}
+
+	// The pages are ordered by namespace and name, and start after the last object of the previous page,
+	// so that the objects created or deleted meanwhile do not shift the next pages.
+	if predicate.Continue != "" {
+		afterNamespace, afterName, err := decodeListContinue(key, predicate.Continue)
+		if err != nil {
+			return "", nil, err
+		}
+		if namespace != "" {
+			queryBuilder.Apply(sm.Where(psql.Quote("name").GT(psql.Arg(afterName))))
+		} else {
+			queryBuilder.Apply(sm.Where(
+				psql.Group(psql.Quote("namespace"), psql.Quote("name")).GT(psql.ArgGroup(afterNamespace, afterName)),
+			))
+		}
+	}
+	if predicate.Limit > 0 || predicate.Continue != "" {
+		queryBuilder.Apply(
+			sm.OrderBy(psql.Quote("namespace")),
+			sm.OrderBy(psql.Quote("name")),
+		)
+	}
+	if predicate.Limit > 0 {
+		queryBuilder.Apply(sm.Limit(predicate.Limit + 1))
+	}
+
+	query, args, err := queryBuilder.Build(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("building list query: %w", err)
+	}
+
+	return query, args, nil
+}
+
 // GuaranteedUpdate keeps calling 'tryUpdate()' to update key 'key' (of type 'destination')
 // retrying the update until success if there is index conflict.
 // Note that object passed to tryUpdate may change across invocations of tryUpdate() if
@@ -443,6 +630,10 @@ func (s *store) GuaranteedUpdate(
 		return storage.NewInternalError(fmt.Errorf("invalid key: %s", key))
 	}
 
+	if s.readOnly.Enabled() {
+		return newReadOnlyError(s.table, name)
+	}
+
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return err
@@ -462,7 +653,7 @@ func (s *store) GuaranteedUpdate(
 			sm.Where(psql.Quote("namespace").EQ(psql.Arg(namespace))),
 		).Build(ctx)
 		if err != nil {
-			return storage.NewInternalError(err)
+			return newInternalError(ctx, err)
 		}
 
 		if err = runtime.SetZeroValue(destination); err != nil {
@@ -486,9 +677,13 @@ func (s *store) GuaranteedUpdate(
 		}
 
 		obj := s.newFunc()
-		err = json.Unmarshal(objectRecord.Object, obj)
+		var currentRef *documentReference
+		currentRef, err = s.decodeObject(objectRecord.Object, obj)
 		if err != nil {
-			return storage.NewInternalError(err)
+			return newInternalError(ctx, err)
+		}
+		if err = s.loadDocument(ctx, obj, currentRef); err != nil {
+			return newInternalError(ctx, err)
 		}
 
 		err = preconditions.Check(key, obj)
@@ -511,16 +706,17 @@ func (s *store) GuaranteedUpdate(
 		var version uint64
 		version, err = s.Versioner().ObjectResourceVersion(obj)
 		if err != nil {
-			return storage.NewInternalError(err)
+			return newInternalError(ctx, err)
 		}
 		if err = s.Versioner().UpdateObject(updatedObj, version+1); err != nil {
-			return storage.NewInternalError(err)
+			return newInternalError(ctx, err)
 		}
 
 		var bytes []byte
-		bytes, err = json.Marshal(updatedObj)
+		var updatedRef *documentReference
+		bytes, updatedRef, err = s.encodeObject(ctx, name, namespace, updatedObj)
 		if err != nil {
-			return storage.NewInternalError(err)
+			return newInternalError(ctx, err)
 		}
 
 		updateQuery, updateArgs, err := psql.Update(
@@ -530,20 +726,30 @@ func (s *store) GuaranteedUpdate(
 			um.Where(psql.Quote("namespace").EQ(psql.Arg(namespace))),
 		).Build(ctx)
 		if err != nil {
-			return storage.NewInternalError(err)
+			return newInternalError(ctx, err)
 		}
 
 		_, err = tx.Exec(ctx, updateQuery, updateArgs...)
 		if err != nil {
-			return storage.NewInternalError(err)
+			return newInternalError(ctx, err)
+		}
+
+		if s.writeHook != nil {
+			if err = s.writeHook(ctx, tx, name, namespace, updatedObj); err != nil {
+				return newInternalError(ctx, err)
+			}
 		}
 
 		if err = tx.Commit(ctx); err != nil {
-			return storage.NewInternalError(err)
+			return newInternalError(ctx, err)
+		}
+		s.queryCache.Invalidate(namespace)
+		if currentRef != nil && (updatedRef == nil || updatedRef.Key != currentRef.Key) {
+			s.deleteDocument(ctx, currentRef)
 		}
 
 		if err = s.broadcaster.Action(watch.Modified, updatedObj); err != nil {
-			return storage.NewInternalError(err)
+			return newInternalError(ctx, err)
 		}
 
 		if err = setValue(updatedObj, destination); err != nil {
@@ -560,7 +766,10 @@ func (s *store) GuaranteedUpdate(
 func (s *store) Count(key string) (int64, error) {
 	s.logger.Debug("Counting objects", "key", key)
 
-	namespace := extractNamespace(key)
+	namespace, err := extractNamespace(key)
+	if err != nil {
+		return 0, newInternalError(context.Background(), err)
+	}
 
 	queryBuilder := psql.Select(
 		sm.Columns("COUNT(*)"),
@@ -575,13 +784,13 @@ func (s *store) Count(key string) (int64, error) {
 
 	query, args, err := queryBuilder.Build(context.Background())
 	if err != nil {
-		return 0, storage.NewInternalError(err)
+		return 0, newInternalError(context.Background(), err)
 	}
 
 	var count int64
 	err = s.db.QueryRow(context.Background(), query, args...).Scan(&count)
 	if err != nil {
-		return 0, storage.NewInternalError(err)
+		return 0, newInternalError(context.Background(), err)
 	}
 
 	return count, nil
@@ -653,16 +862,22 @@ func extractNameAndNamespace(key string) (string, string) {
 }
 
 // extractNamespace extracts the namespace from the key.
-// Used for list operations.
-// Key format: /storage.sbomscanner.kubewarden.io/<resource>/<namespace>
-func extractNamespace(key string) string {
-	key = strings.TrimPrefix(key, "/")
-	parts := strings.Split(key, "/")
-	if len(parts) == 3 {
-		return parts[2]
+// Used for list operations, the namespace is empty for the cluster-wide lists.
+// Key format: /storage.sbomscanner.kubewarden.io/<resource>[/<namespace>]
+//
+// Any other key is rejected, so that a malformed namespaced key is never served as a cluster-wide list.
+func extractNamespace(key string) (string, error) {
+	parts := strings.Split(strings.TrimPrefix(key, "/"), "/")
+	switch len(parts) {
+	case 2:
+		return "", nil
+	case 3:
+		if parts[2] != "" {
+			return parts[2], nil
+		}
 	}
 
-	return ""
+	return "", fmt.Errorf("invalid list key %q", key)
 }
 
 // setValue sets the value of 'dest' to the value of 'source' after converting them to pointers.
@@ -734,23 +949,36 @@ func buildLabelSelectorExpressions(labelSelector labels.Selector) ([]psql.Expres
 
 // buildFieldSelectorExpressions builds SQL expressions from the provided k8s field selector
 // using PostgreSQL JSONB operators.
-func buildFieldSelectorExpressions(fieldSelector fields.Selector) ([]psql.Expression, error) {
+func buildFieldSelectorExpressions(fieldSelector fields.Selector, computedFields map[string]func() psql.Expression) ([]psql.Expression, error) {
 	var expressions []psql.Expression
 	requirements := fieldSelector.Requirements()
 
 	for _, req := range requirements {
-		// Convert dot notation to JSON path
-		// "metadata.name" -> {metadata,name}
-		pathParts := strings.Split(req.Field, ".")
-		jsonPath := "{" + strings.Join(pathParts, ",") + "}"
+		var field psql.Expression
+		computedField, computed := computedFields[req.Field]
+		switch {
+		case computed:
+			field = computedField()
+		case req.Field == "metadata.name":
+			field = psql.Quote("name")
+		case req.Field == "metadata.namespace":
+			field = psql.Quote("namespace")
+		default:
+			// Convert dot notation to JSON path
+			// "imageMetadata.registry" -> {imageMetadata,registry}
+			// The path is a literal, rather than an argument, so that the expression indexes can be used.
+			pathParts := strings.Split(req.Field, ".")
+			jsonPath := "{" + strings.Join(pathParts, ",") + "}"
+			field = psql.Quote("object").OP("#>>", psql.S(strings.ReplaceAll(jsonPath, "'", "''")))
+		}
 
 		var expression psql.Expression
 
 		switch req.Operator {
 		case selection.Equals, selection.DoubleEquals:
-			expression = psql.Raw("object #>> ?", jsonPath).EQ(psql.Arg(req.Value))
+			expression = field.EQ(psql.Arg(req.Value))
 		case selection.NotEquals:
-			expression = psql.Raw("object #>> ?", jsonPath).NE(psql.Arg(req.Value))
+			expression = field.NE(psql.Arg(req.Value))
 		case selection.In, selection.NotIn, selection.Exists, selection.DoesNotExist, selection.GreaterThan, selection.LessThan:
 			return nil, fmt.Errorf("unsupported field selector operator: %v", req.Operator)
 		}
@@ -6,10 +6,14 @@ import (
 	"log/slog"
 	"testing"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
@@ -111,6 +115,81 @@ func (suite *storeTestSuite) TestCreate() {
 	suite.Require().Equal(storage.NewKeyExistsError(key, 0).Error(), err.Error())
 }
 
+func (suite *storeTestSuite) TestReadOnly() {
+	sbom := &v1alpha1.SBOM{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+	}
+
+	key := keyPrefix + "/default/test"
+	err := suite.store.Create(context.Background(), key, sbom, &v1alpha1.SBOM{}, 0)
+	suite.Require().NoError(err)
+
+	suite.store.readOnly = NewReadOnlyMode(true, slog.Default())
+
+	err = suite.store.Create(context.Background(), keyPrefix+"/default/other", sbom, &v1alpha1.SBOM{}, 0)
+	suite.True(apierrors.IsForbidden(err))
+	suite.Contains(err.Error(), "storage in read-only mode")
+
+	err = suite.store.GuaranteedUpdate(context.Background(), key, &v1alpha1.SBOM{}, false, nil,
+		func(input runtime.Object, _ storage.ResponseMeta) (runtime.Object, *uint64, error) {
+			return input, nil, nil
+		}, nil)
+	suite.True(apierrors.IsForbidden(err))
+
+	err = suite.store.Delete(context.Background(), key, &v1alpha1.SBOM{}, &storage.Preconditions{},
+		func(_ context.Context, _ runtime.Object) error {
+			return nil
+		}, nil, storage.DeleteOptions{})
+	suite.True(apierrors.IsForbidden(err))
+
+	out := &v1alpha1.SBOM{}
+	err = suite.store.Get(context.Background(), key, storage.GetOptions{}, out)
+	suite.Require().NoError(err)
+	suite.Equal(sbom, out)
+}
+
+func (suite *storeTestSuite) TestWriteHook() {
+	sbom := &v1alpha1.SBOM{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+	}
+
+	key := keyPrefix + "/default/test"
+	hookErr := errors.New("hook failed")
+	var written []string
+	suite.store.writeHook = func(_ context.Context, _ pgx.Tx, name, namespace string, _ runtime.Object) error {
+		written = append(written, namespace+"/"+name)
+		return hookErr
+	}
+
+	// A failing hook must roll back the create
+	err := suite.store.Create(context.Background(), key, sbom, &v1alpha1.SBOM{}, 0)
+	suite.Require().Error(err)
+	err = suite.store.Get(context.Background(), key, storage.GetOptions{}, &v1alpha1.SBOM{})
+	suite.True(storage.IsNotFound(err))
+
+	suite.store.writeHook = func(_ context.Context, _ pgx.Tx, name, namespace string, _ runtime.Object) error {
+		written = append(written, namespace+"/"+name)
+		return nil
+	}
+
+	err = suite.store.Create(context.Background(), key, sbom, &v1alpha1.SBOM{}, 0)
+	suite.Require().NoError(err)
+
+	err = suite.store.GuaranteedUpdate(context.Background(), key, &v1alpha1.SBOM{}, false, nil,
+		func(input runtime.Object, _ storage.ResponseMeta) (runtime.Object, *uint64, error) {
+			return input, nil, nil
+		}, nil)
+	suite.Require().NoError(err)
+
+	suite.Equal([]string{"default/test", "default/test", "default/test"}, written)
+}
+
 func (suite *storeTestSuite) TestDelete() {
 	sbom := &v1alpha1.SBOM{
 		ObjectMeta: metav1.ObjectMeta{
@@ -315,6 +394,30 @@ func (suite *storeTestSuite) TestWatchWithLabelSelector() {
 	suite.Equal(sbom1, events[0].Object)
 }
 
+func (suite *storeTestSuite) TestWatchNamespace() {
+	sbom1 := &v1alpha1.SBOM{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+	sbom2 := &v1alpha1.SBOM{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "other"}}
+
+	namespaced, err := suite.store.Watch(context.Background(), keyPrefix+"/default", storage.ListOptions{})
+	suite.Require().NoError(err)
+	clusterWide, err := suite.store.Watch(context.Background(), keyPrefix, storage.ListOptions{})
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.store.Create(context.Background(), keyPrefix+"/default/test", sbom1, &v1alpha1.SBOM{}, 0))
+	suite.Require().NoError(suite.store.Create(context.Background(), keyPrefix+"/other/test", sbom2, &v1alpha1.SBOM{}, 0))
+
+	suite.broadcaster.Shutdown()
+
+	events := collectEvents(namespaced)
+	suite.Require().Len(events, 1, "the events of the other namespaces are not sent")
+	suite.Equal(sbom1, events[0].Object)
+
+	events = collectEvents(clusterWide)
+	suite.Require().Len(events, 2)
+	suite.Equal(sbom1, events[0].Object)
+	suite.Equal(sbom2, events[1].Object)
+}
+
 // collectEvents reads events from the watcher and returns them in a slice.
 func collectEvents(watcher watch.Interface) []watch.Event {
 	var events []watch.Event
@@ -443,6 +546,84 @@ func (suite *storeTestSuite) TestGetList() {
 	}
 }
 
+func (suite *storeTestSuite) TestGetListNamespaces() {
+	sbom1 := v1alpha1.SBOM{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+	sbom2 := v1alpha1.SBOM{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "other"}}
+	suite.Require().NoError(suite.store.Create(context.Background(), keyPrefix+"/default/test", &sbom1, nil, 0))
+	suite.Require().NoError(suite.store.Create(context.Background(), keyPrefix+"/other/test", &sbom2, nil, 0))
+
+	opts := storage.ListOptions{Predicate: matcher(labels.Everything(), fields.Everything())}
+
+	sbomList := &v1alpha1.SBOMList{}
+	suite.Require().NoError(suite.store.GetList(context.Background(), keyPrefix+"/default", opts, sbomList))
+	suite.ElementsMatch([]v1alpha1.SBOM{sbom1}, sbomList.Items, "a namespaced list only returns its namespace")
+
+	sbomList = &v1alpha1.SBOMList{}
+	suite.Require().NoError(suite.store.GetList(context.Background(), keyPrefix, opts, sbomList))
+	suite.ElementsMatch([]v1alpha1.SBOM{sbom1, sbom2}, sbomList.Items, "a cluster-wide list returns all the namespaces")
+
+	count, err := suite.store.Count(keyPrefix)
+	suite.Require().NoError(err)
+	suite.Equal(int64(2), count)
+}
+
+func (suite *storeTestSuite) TestGetListPagination() {
+	var sboms []v1alpha1.SBOM
+	for _, namespace := range []string{"default", "other"} {
+		for _, name := range []string{"test1", "test2"} {
+			sbom := v1alpha1.SBOM{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+			suite.Require().NoError(suite.store.Create(context.Background(), keyPrefix+"/"+namespace+"/"+name, &sbom, nil, 0))
+			sboms = append(sboms, sbom)
+		}
+	}
+
+	predicate := matcher(labels.Everything(), fields.Everything())
+	predicate.Limit = 3
+	sbomList := &v1alpha1.SBOMList{}
+	suite.Require().NoError(suite.store.GetList(context.Background(), keyPrefix, storage.ListOptions{Predicate: predicate}, sbomList))
+	suite.Equal(sboms[:3], sbomList.Items)
+	suite.Require().NotEmpty(sbomList.Continue)
+
+	predicate.Continue = sbomList.Continue
+	sbomList = &v1alpha1.SBOMList{}
+	suite.Require().NoError(suite.store.GetList(context.Background(), keyPrefix, storage.ListOptions{Predicate: predicate}, sbomList))
+	suite.Equal(sboms[3:], sbomList.Items)
+	suite.Empty(sbomList.Continue, "the last page has no continue token")
+
+	predicate.Continue = "invalid"
+	err := suite.store.GetList(context.Background(), keyPrefix, storage.ListOptions{Predicate: predicate}, sbomList)
+	suite.True(apierrors.IsBadRequest(err))
+}
+
+func (suite *storeTestSuite) TestGetListMaxBytes() {
+	sbom := v1alpha1.SBOM{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+	suite.Require().NoError(suite.store.Create(context.Background(), keyPrefix+"/default/test", &sbom, nil, 0))
+	suite.store.maxListBytes = 10
+
+	err := suite.store.GetList(context.Background(), keyPrefix, storage.ListOptions{
+		Predicate: matcher(labels.Everything(), fields.Everything()),
+	}, &v1alpha1.SBOMList{})
+	suite.Require().True(apierrors.IsBadRequest(err))
+	suite.Contains(err.Error(), "limit and continue")
+
+	// The pages end early, with at least one object.
+	other := v1alpha1.SBOM{ObjectMeta: metav1.ObjectMeta{Name: "test-2", Namespace: "default"}}
+	suite.Require().NoError(suite.store.Create(context.Background(), keyPrefix+"/default/test-2", &other, nil, 0))
+
+	predicate := matcher(labels.Everything(), fields.Everything())
+	predicate.Limit = 10
+	sbomList := &v1alpha1.SBOMList{}
+	suite.Require().NoError(suite.store.GetList(context.Background(), keyPrefix, storage.ListOptions{Predicate: predicate}, sbomList))
+	suite.Equal([]v1alpha1.SBOM{sbom}, sbomList.Items)
+	suite.Require().NotEmpty(sbomList.Continue, "the page ending early has a continue token")
+
+	predicate.Continue = sbomList.Continue
+	sbomList = &v1alpha1.SBOMList{}
+	suite.Require().NoError(suite.store.GetList(context.Background(), keyPrefix, storage.ListOptions{Predicate: predicate}, sbomList))
+	suite.Equal([]v1alpha1.SBOM{other}, sbomList.Items)
+	suite.Empty(sbomList.Continue)
+}
+
 func mustParseLabelSelector(selector string) labels.Selector {
 	labelSelector, err := labels.Parse(selector)
 	if err != nil {
@@ -667,3 +848,29 @@ func (suite *storeTestSuite) TestCount() {
 		})
 	}
 }
+
+func TestExtractNamespace(t *testing.T) {
+	tests := []struct {
+		key               string
+		expectedNamespace string
+		expectedError     bool
+	}{
+		{key: keyPrefix, expectedNamespace: ""},
+		{key: keyPrefix + "/default", expectedNamespace: "default"},
+		{key: keyPrefix + "/", expectedError: true},
+		{key: keyPrefix + "/default/test", expectedError: true},
+		{key: "/sboms", expectedError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			namespace, err := extractNamespace(test.key)
+			if test.expectedError {
+				require.Error(t, err, "a malformed key must not be listed cluster-wide")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedNamespace, namespace)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+// newTestDB starts a PostgreSQL container, applies the migrations, and returns a pool connected to it.
+// The container is terminated at the end of the test.
+func newTestDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	ctx := context.Background()
+
+	pgContainer, err := postgres.Run(ctx,
+		"postgres:16-alpine",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpassword"),
+		postgres.BasicWaitStrategies(),
+	)
+	require.NoError(t, err, "failed to start postgres container")
+	t.Cleanup(func() {
+		require.NoError(t, pgContainer.Terminate(context.Background()), "failed to terminate postgres container")
+	})
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err, "failed to get connection string")
+
+	db, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err, "failed to create connection pool")
+	t.Cleanup(db.Close)
+
+	require.NoError(t, RunMigrations(ctx, db, ""))
+
+	return db
+}
@@ -0,0 +1,479 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/sync/errgroup"
+)
+
+// IntegrityIssueKind is the kind of an inconsistency found by VerifyIntegrity.
+type IntegrityIssueKind string
+
+const (
+	// IntegrityIssueOrphanedSBOM is an SBOM whose Image does not exist anymore.
+	IntegrityIssueOrphanedSBOM IntegrityIssueKind = "OrphanedSBOM"
+	// IntegrityIssueOrphanedVulnerabilityReport is a VulnerabilityReport whose SBOM does not exist anymore,
+	// or is itself orphaned.
+	IntegrityIssueOrphanedVulnerabilityReport IntegrityIssueKind = "OrphanedVulnerabilityReport"
+	// IntegrityIssueImageWithoutSBOM is a scanned Image without SBOM.
+	IntegrityIssueImageWithoutSBOM IntegrityIssueKind = "ImageWithoutSBOM"
+	// IntegrityIssueImageWithoutVulnerabilityReport is a scanned Image with an SBOM but without VulnerabilityReport.
+	IntegrityIssueImageWithoutVulnerabilityReport IntegrityIssueKind = "ImageWithoutVulnerabilityReport"
+	// IntegrityIssueDocumentUnreadable is an offloaded document which cannot be read from the object storage.
+	IntegrityIssueDocumentUnreadable IntegrityIssueKind = "DocumentUnreadable"
+	// IntegrityIssueDocumentChecksumMismatch is an offloaded document which does not match the checksum of its row.
+	IntegrityIssueDocumentChecksumMismatch IntegrityIssueKind = "DocumentChecksumMismatch"
+	// IntegrityIssueDocumentUnreferenced is a document of the object storage which no row references,
+	// e.g. written by a transaction which was rolled back.
+	IntegrityIssueDocumentUnreferenced IntegrityIssueKind = "DocumentUnreferenced"
+)
+
+// unreferencedDocumentGracePeriod is the age of the unreferenced documents reported by VerifyIntegrity.
+// The documents are written before the transactions referencing them are committed, the recent ones may still be.
+const unreferencedDocumentGracePeriod = time.Hour
+
+// IntegrityIssue is an inconsistency of a row of the database.
+type IntegrityIssue struct {
+	Kind IntegrityIssueKind `json:"kind"`
+	// Table is the table of the row, e.g. "sboms".
+	Table     string `json:"table"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Message   string `json:"message"`
+	// Repaired is true when the row, or the unreferenced document, was deleted by the repair.
+	Repaired bool `json:"repaired"`
+}
+
+// IntegrityReport lists the inconsistencies found by VerifyIntegrity.
+type IntegrityReport struct {
+	Issues []IntegrityIssue `json:"issues"`
+}
+
+// Unrepaired returns the number of issues which were not repaired.
+func (r *IntegrityReport) Unrepaired() int {
+	unrepaired := 0
+	for _, issue := range r.Issues {
+		if !issue.Repaired {
+			unrepaired++
+		}
+	}
+
+	return unrepaired
+}
+
+// orphanedSBOMCondition matches the rows of the sboms table whose owner Image is not in the images table,
+// or was recreated with another UID. It is also used by orphanedVulnerabilityReportCondition,
+// so that the reports of the orphaned SBOMs are orphaned as well.
+const orphanedSBOMCondition = `EXISTS (
+    SELECT 1 FROM jsonb_array_elements(COALESCE(sboms.object #> '{metadata,ownerReferences}', '[]')) AS owner
+    WHERE owner->>'kind' = 'Image' AND NOT EXISTS (
+        SELECT 1 FROM images
+        WHERE images.namespace = sboms.namespace
+            AND images.name = owner->>'name'
+            AND images.object #>> '{metadata,uid}' = owner->>'uid'
+    )
+)`
+
+// orphanedVulnerabilityReportCondition matches the rows of the vulnerabilityreports table whose owner SBOM
+// is not in the sboms table, was recreated with another UID, or is orphaned.
+const orphanedVulnerabilityReportCondition = `EXISTS (
+    SELECT 1 FROM jsonb_array_elements(COALESCE(vulnerabilityreports.object #> '{metadata,ownerReferences}', '[]')) AS owner
+    WHERE owner->>'kind' = 'SBOM' AND NOT EXISTS (
+        SELECT 1 FROM sboms
+        WHERE sboms.namespace = vulnerabilityreports.namespace
+            AND sboms.name = owner->>'name'
+            AND sboms.object #>> '{metadata,uid}' = owner->>'uid'
+            AND NOT ` + orphanedSBOMCondition + `
+    )
+)`
+
+// scannedImagesWithoutReportSQL selects the images with a recorded scan, which are missing their SBOM or their report.
+// The SBOM and the VulnerabilityReport of an image have its name.
+const scannedImagesWithoutReportSQL = `
+SELECT
+    images.namespace,
+    images.name,
+    EXISTS (SELECT 1 FROM sboms WHERE sboms.namespace = images.namespace AND sboms.name = images.name)
+FROM images
+WHERE images.object #>> '{status,lastScannedAt}' IS NOT NULL
+    AND NOT EXISTS (
+        SELECT 1 FROM vulnerabilityreports
+        WHERE vulnerabilityreports.namespace = images.namespace AND vulnerabilityreports.name = images.name
+    )
+ORDER BY images.namespace, images.name
+`
+
+// referencedDocumentsSQL selects the keys of the offloaded documents of the rows of a table.
+const referencedDocumentsSQL = `
+SELECT object #>> '{documentReference,key}'
+FROM %s
+WHERE object->'documentReference' ? 'key'
+`
+
+// offloadedDocumentsSQL selects the references of the offloaded documents of the rows of a table.
+// The rows whose document was not retained have no document to check.
+const offloadedDocumentsSQL = `
+SELECT namespace, name, object->'documentReference'
+FROM %s
+WHERE object->'documentReference' ? 'key'
+ORDER BY namespace, name
+`
+
+// orphanCheck finds the rows of a table whose owner does not exist anymore.
+type orphanCheck struct {
+	table     string
+	condition string
+	kind      IntegrityIssueKind
+	message   string
+}
+
+// orphanChecks are run in order: the SBOMs are deleted first by the repair, so that their reports are orphaned
+// and deleted as well.
+var orphanChecks = []orphanCheck{
+	{
+		table:     "sboms",
+		condition: orphanedSBOMCondition,
+		kind:      IntegrityIssueOrphanedSBOM,
+		message:   "the Image owning the SBOM does not exist",
+	},
+	{
+		table:     "vulnerabilityreports",
+		condition: orphanedVulnerabilityReportCondition,
+		kind:      IntegrityIssueOrphanedVulnerabilityReport,
+		message:   "the SBOM owning the VulnerabilityReport does not exist or is orphaned",
+	},
+}
+
+// VerifyIntegrity checks the consistency of the rows of the database: the SBOMs and the VulnerabilityReports
+// whose owner was deleted, the scanned Images without SBOM or VulnerabilityReport, and, when documents is not nil,
+// the offloaded documents which cannot be read, do not match their checksum, or are not referenced by any row.
+// The database is only read, unless fix is true: the orphaned SBOMs and VulnerabilityReports are then deleted
+// in batches of their own transactions, run by the workers of the batch options, together with their offloaded documents,
+// and the unreferenced documents are deleted.
+// The other issues cannot be repaired by the storage, the images must be scanned again.
+func VerifyIntegrity(
+	ctx context.Context,
+	db *pgxpool.Pool,
+	documents DocumentStorage,
+	fix bool,
+	batch DeleteBatchOptions,
+	logger *slog.Logger,
+) (*IntegrityReport, error) {
+	report := &IntegrityReport{Issues: []IntegrityIssue{}}
+	var deletedDocuments []string
+	if fix {
+		for _, check := range orphanChecks {
+			issues, keys, err := deleteOrphans(ctx, db, check, batch, logger)
+			if err != nil {
+				return nil, err
+			}
+			report.Issues = append(report.Issues, issues...)
+			deletedDocuments = append(deletedDocuments, keys...)
+		}
+
+		// The documents are deleted once the rows are, the failures leaving unreferenced documents behind.
+		if documents != nil {
+			for _, key := range deletedDocuments {
+				if err := documents.Delete(ctx, key); err != nil {
+					logger.WarnContext(ctx, "Failed to delete offloaded document", "key", key, "error", err)
+				}
+			}
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly, IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	// The rows orphaned while the orphans were deleted are reported as not repaired.
+	for _, check := range orphanChecks {
+		issues, err := runOrphanCheck(ctx, tx, check)
+		if err != nil {
+			return nil, err
+		}
+		report.Issues = append(report.Issues, issues...)
+	}
+
+	issues, err := checkScannedImages(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	report.Issues = append(report.Issues, issues...)
+
+	if documents != nil {
+		for _, table := range []string{"sboms", "vulnerabilityreports"} {
+			issues, err := checkDocuments(ctx, tx, documents, table)
+			if err != nil {
+				return nil, err
+			}
+			report.Issues = append(report.Issues, issues...)
+		}
+
+		// The documents written after the snapshot of the transaction may be referenced by the rows committed since.
+		cutoff := time.Now().Add(-unreferencedDocumentGracePeriod)
+		for _, table := range []string{"sboms", "vulnerabilityreports"} {
+			issues, err := checkUnreferencedDocuments(ctx, tx, documents, table, cutoff, fix, logger)
+			if err != nil {
+				return nil, err
+			}
+			report.Issues = append(report.Issues, issues...)
+		}
+	}
+
+	return report, nil
+}
+
+// runOrphanCheck returns the orphaned rows of the table.
+func runOrphanCheck(ctx context.Context, tx pgx.Tx, check orphanCheck) ([]IntegrityIssue, error) {
+	table := pgx.Identifier{check.table}.Sanitize()
+	rows, err := tx.Query(ctx, "SELECT namespace, name FROM "+table+" WHERE "+check.condition+" ORDER BY namespace, name")
+	if err != nil {
+		return nil, fmt.Errorf("checking orphaned rows of table %s: %w", check.table, err)
+	}
+	defer rows.Close()
+
+	var issues []IntegrityIssue
+	for rows.Next() {
+		issue := IntegrityIssue{Kind: check.kind, Table: check.table, Message: check.message}
+		if err := rows.Scan(&issue.Namespace, &issue.Name); err != nil {
+			return nil, fmt.Errorf("scanning orphaned row of table %s: %w", check.table, err)
+		}
+		issues = append(issues, issue)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading orphaned rows of table %s: %w", check.table, err)
+	}
+
+	return issues, nil
+}
+
+// deleteOrphans deletes the orphaned rows of the table in batches, and returns them with the keys of their offloaded documents.
+// The batches lock the rows they delete with SKIP LOCKED, so that the concurrent batches delete disjoint rows,
+// and the rows cascading from them, and never wait for each other.
+func deleteOrphans(
+	ctx context.Context,
+	db *pgxpool.Pool,
+	check orphanCheck,
+	batch DeleteBatchOptions,
+	logger *slog.Logger,
+) ([]IntegrityIssue, []string, error) {
+	table := pgx.Identifier{check.table}.Sanitize()
+	query := "DELETE FROM " + table + " WHERE ctid IN (" +
+		"SELECT ctid FROM " + table + " WHERE " + check.condition + " LIMIT $1 FOR UPDATE SKIP LOCKED" +
+		") RETURNING namespace, name, object #>> '{documentReference,key}'"
+
+	var (
+		issues []IntegrityIssue
+		keys   []string
+	)
+	scan := func(rows pgx.Rows) error {
+		issue := IntegrityIssue{Kind: check.kind, Table: check.table, Message: check.message, Repaired: true}
+		var key *string
+		if err := rows.Scan(&issue.Namespace, &issue.Name, &key); err != nil {
+			return fmt.Errorf("scanning orphaned row of table %s: %w", check.table, err)
+		}
+		issues = append(issues, issue)
+		if key != nil {
+			keys = append(keys, *key)
+		}
+
+		return nil
+	}
+
+	deleted, err := deleteReturningInBatches(ctx, db, batch, logger.With("table", check.table), scan, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("deleting orphaned rows of table %s: %w", check.table, err)
+	}
+	logger.InfoContext(ctx, "Deleted orphaned rows", "table", check.table, "deleted", deleted)
+
+	// The batches return the rows in no particular order.
+	slices.SortFunc(issues, func(a, b IntegrityIssue) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name))
+	})
+
+	return issues, keys, nil
+}
+
+// checkScannedImages returns the images with a recorded scan which are missing their SBOM or their VulnerabilityReport.
+func checkScannedImages(ctx context.Context, tx pgx.Tx) ([]IntegrityIssue, error) {
+	rows, err := tx.Query(ctx, scannedImagesWithoutReportSQL)
+	if err != nil {
+		return nil, fmt.Errorf("checking scanned images: %w", err)
+	}
+	defer rows.Close()
+
+	var issues []IntegrityIssue
+	for rows.Next() {
+		issue := IntegrityIssue{Table: "images"}
+		var hasSBOM bool
+		if err := rows.Scan(&issue.Namespace, &issue.Name, &hasSBOM); err != nil {
+			return nil, fmt.Errorf("scanning image: %w", err)
+		}
+		if hasSBOM {
+			issue.Kind = IntegrityIssueImageWithoutVulnerabilityReport
+			issue.Message = "the image was scanned but has no VulnerabilityReport, scan it again"
+		} else {
+			issue.Kind = IntegrityIssueImageWithoutSBOM
+			issue.Message = "the image was scanned but has no SBOM, scan it again"
+		}
+		issues = append(issues, issue)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading scanned images: %w", err)
+	}
+
+	return issues, nil
+}
+
+// checkDocuments reads the offloaded documents of the rows of the table, and returns the ones which cannot be read
+// or do not match their checksum.
+func checkDocuments(ctx context.Context, tx pgx.Tx, documents DocumentStorage, table string) ([]IntegrityIssue, error) {
+	type offloadedRow struct {
+		namespace string
+		name      string
+		ref       documentReference
+	}
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(offloadedDocumentsSQL, pgx.Identifier{table}.Sanitize()))
+	if err != nil {
+		return nil, fmt.Errorf("listing offloaded documents of table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var offloaded []offloadedRow
+	for rows.Next() {
+		var (
+			row  offloadedRow
+			data []byte
+		)
+		if err := rows.Scan(&row.namespace, &row.name, &data); err != nil {
+			return nil, fmt.Errorf("scanning offloaded document of table %s: %w", table, err)
+		}
+		if err := json.Unmarshal(data, &row.ref); err != nil {
+			return nil, fmt.Errorf("decoding document reference of %s/%s: %w", row.namespace, row.name, err)
+		}
+		offloaded = append(offloaded, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading offloaded documents of table %s: %w", table, err)
+	}
+
+	// Each document sets its own item, so that the issues are in the order of the rows.
+	issues := make([]*IntegrityIssue, len(offloaded))
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(documentLoadConcurrency)
+	for i, row := range offloaded {
+		group.Go(func() error {
+			issue := &IntegrityIssue{Table: table, Namespace: row.namespace, Name: row.name}
+			document, err := documents.Get(ctx, row.ref.Key)
+			if err != nil {
+				// The errors of the cancelled context are not issues of the document.
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				issue.Kind = IntegrityIssueDocumentUnreadable
+				issue.Message = err.Error()
+			} else if sum := sha256.Sum256(document); hex.EncodeToString(sum[:]) != row.ref.SHA256 {
+				issue.Kind = IntegrityIssueDocumentChecksumMismatch
+				issue.Message = fmt.Sprintf("document %s does not match its checksum %s, scan the image again", row.ref.Key, row.ref.SHA256)
+			} else {
+				return nil
+			}
+
+			issues[i] = issue
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, fmt.Errorf("reading offloaded documents of table %s: %w", table, err)
+	}
+
+	var found []IntegrityIssue
+	for _, issue := range issues {
+		if issue != nil {
+			found = append(found, *issue)
+		}
+	}
+
+	return found, nil
+}
+
+// checkUnreferencedDocuments returns the documents of the table, last written before cutoff, which no row references.
+// They are deleted when fix is true.
+func checkUnreferencedDocuments(
+	ctx context.Context,
+	tx pgx.Tx,
+	documents DocumentStorage,
+	table string,
+	cutoff time.Time,
+	fix bool,
+	logger *slog.Logger,
+) ([]IntegrityIssue, error) {
+	rows, err := tx.Query(ctx, fmt.Sprintf(referencedDocumentsSQL, pgx.Identifier{table}.Sanitize()))
+	if err != nil {
+		return nil, fmt.Errorf("listing referenced documents of table %s: %w", table, err)
+	}
+	referenced := map[string]struct{}{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning referenced document of table %s: %w", table, err)
+		}
+		referenced[key] = struct{}{}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading referenced documents of table %s: %w", table, err)
+	}
+
+	stored, err := documents.List(ctx, table+"/")
+	if err != nil {
+		return nil, fmt.Errorf("listing documents of table %s: %w", table, err)
+	}
+
+	var issues []IntegrityIssue
+	for _, document := range stored {
+		if _, ok := referenced[document.Key]; ok || !document.LastModified.Before(cutoff) {
+			continue
+		}
+		issue := IntegrityIssue{
+			Kind:    IntegrityIssueDocumentUnreferenced,
+			Table:   table,
+			Message: fmt.Sprintf("document %s is not referenced by any row", document.Key),
+		}
+		// The keys are made of the table, the namespace and the name of the row, and the checksum of the document.
+		if parts := strings.Split(document.Key, "/"); len(parts) == 4 {
+			issue.Namespace, issue.Name = parts[1], parts[2]
+		}
+		if fix {
+			if err := documents.Delete(ctx, document.Key); err != nil {
+				logger.WarnContext(ctx, "Failed to delete unreferenced document", "key", document.Key, "error", err)
+			} else {
+				issue.Repaired = true
+			}
+		}
+		issues = append(issues, issue)
+	}
+
+	slices.SortFunc(issues, func(a, b IntegrityIssue) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name), cmp.Compare(a.Message, b.Message))
+	})
+
+	return issues, nil
+}
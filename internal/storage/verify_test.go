@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+func TestVerifyIntegrity(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := postgres.Run(ctx,
+		"postgres:16-alpine",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpassword"),
+		postgres.BasicWaitStrategies(),
+	)
+	require.NoError(t, err, "failed to start postgres container")
+	t.Cleanup(func() {
+		require.NoError(t, pgContainer.Terminate(context.Background()), "failed to terminate postgres container")
+	})
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err, "failed to get connection string")
+
+	db, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err, "failed to create connection pool")
+	t.Cleanup(db.Close)
+
+	require.NoError(t, RunMigrations(ctx, db, ""))
+
+	documents := newMemoryDocumentStorage()
+	insert := func(table, name string, uid types.UID, owner *metav1.OwnerReference, status *v1alpha1.ImageStatus, document []byte) {
+		objectMeta := metav1.ObjectMeta{Name: name, Namespace: "default", UID: uid}
+		if owner != nil {
+			objectMeta.OwnerReferences = []metav1.OwnerReference{*owner}
+		}
+		object := map[string]any{"metadata": objectMeta}
+		if status != nil {
+			object["status"] = status
+		}
+		if document != nil {
+			sum := sha256.Sum256(document)
+			ref := documentReference{Key: table + "/default/" + name, SHA256: hex.EncodeToString(sum[:])}
+			object[documentReferenceField] = ref
+			require.NoError(t, documents.Put(ctx, ref.Key, document))
+		}
+		data, err := json.Marshal(object)
+		require.NoError(t, err)
+		_, err = db.Exec(ctx, "INSERT INTO "+table+" (name, namespace, object) VALUES ($1, 'default', $2)", name, data)
+		require.NoError(t, err)
+	}
+	ownedBy := func(kind, name string, uid types.UID) *metav1.OwnerReference {
+		return &metav1.OwnerReference{Kind: kind, Name: name, UID: uid}
+	}
+	scanned := &v1alpha1.ImageStatus{LastScannedAt: &metav1.Time{Time: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}}
+
+	// A consistent image, with its SBOM and its report.
+	insert("images", "alive", "image-alive", nil, scanned, nil)
+	insert("sboms", "alive", "sbom-alive", ownedBy("Image", "alive", "image-alive"), nil, []byte(`{"spdxVersion":"SPDX-2.3"}`))
+	insert("vulnerabilityreports", "alive", "report-alive", ownedBy("SBOM", "alive", "sbom-alive"), nil, []byte(`[]`))
+	// An SBOM whose image was deleted, and its report.
+	insert("sboms", "deleted", "sbom-deleted", ownedBy("Image", "deleted", "image-deleted"), nil, []byte(`{}`))
+	insert("vulnerabilityreports", "deleted", "report-deleted", ownedBy("SBOM", "deleted", "sbom-deleted"), nil, nil)
+	// A report of a previous SBOM of the image, recreated since.
+	insert("vulnerabilityreports", "recreated", "report-recreated", ownedBy("SBOM", "alive", "sbom-previous"), nil, nil)
+	// A scanned image without SBOM, and a scanned image without report.
+	insert("images", "without-sbom", "image-without-sbom", nil, scanned, nil)
+	insert("images", "without-report", "image-without-report", nil, scanned, nil)
+	insert("sboms", "without-report", "sbom-without-report", ownedBy("Image", "without-report", "image-without-report"), nil, nil)
+	// An image which was not scanned yet.
+	insert("images", "pending", "image-pending", nil, nil, nil)
+	// A corrupted document.
+	insert("images", "corrupted", "image-corrupted", nil, scanned, nil)
+	insert("sboms", "corrupted", "sbom-corrupted", ownedBy("Image", "corrupted", "image-corrupted"), nil, []byte(`{}`))
+	insert("vulnerabilityreports", "corrupted", "report-corrupted", ownedBy("SBOM", "corrupted", "sbom-corrupted"), nil, []byte(`[]`))
+	require.NoError(t, documents.Put(ctx, "sboms/default/corrupted", []byte(`{"tampered":true}`)))
+	// A document written by a rolled back transaction, and one of a transaction which may still be running.
+	require.NoError(t, documents.Put(ctx, "sboms/default/rolledback/0123", []byte(`{}`)))
+	documents.modified["sboms/default/rolledback/0123"] = time.Now().Add(-2 * unreferencedDocumentGracePeriod)
+	require.NoError(t, documents.Put(ctx, "vulnerabilityreports/default/running/4567", []byte(`[]`)))
+
+	kinds := func(report *IntegrityReport) map[string]IntegrityIssueKind {
+		found := map[string]IntegrityIssueKind{}
+		for _, issue := range report.Issues {
+			found[issue.Table+"/"+issue.Name] = issue.Kind
+		}
+		return found
+	}
+	expectedOrphans := map[string]IntegrityIssueKind{
+		"sboms/deleted":                  IntegrityIssueOrphanedSBOM,
+		"vulnerabilityreports/deleted":   IntegrityIssueOrphanedVulnerabilityReport,
+		"vulnerabilityreports/recreated": IntegrityIssueOrphanedVulnerabilityReport,
+		"sboms/rolledback":               IntegrityIssueDocumentUnreferenced,
+	}
+	expectedUnrepairable := map[string]IntegrityIssueKind{
+		"images/without-sbom":   IntegrityIssueImageWithoutSBOM,
+		"images/without-report": IntegrityIssueImageWithoutVulnerabilityReport,
+		"sboms/corrupted":       IntegrityIssueDocumentChecksumMismatch,
+	}
+	expected := maps.Clone(expectedOrphans)
+	maps.Copy(expected, expectedUnrepairable)
+
+	report, err := VerifyIntegrity(ctx, db, documents, false, NewDeleteBatchOptions(), slog.Default())
+	require.NoError(t, err)
+	assert.Equal(t, expected, kinds(report))
+	assert.Equal(t, len(expected), report.Unrepaired(), "the issues are not repaired by default")
+
+	var count int
+	require.NoError(t, db.QueryRow(ctx, "SELECT COUNT(*) FROM sboms").Scan(&count))
+	assert.Equal(t, 4, count, "the database is only read by default")
+
+	report, err = VerifyIntegrity(ctx, db, documents, true, DeleteBatchOptions{Size: 1, Workers: 2}, slog.Default())
+	require.NoError(t, err)
+	assert.Equal(t, expected, kinds(report))
+	assert.Equal(t, len(expectedUnrepairable), report.Unrepaired(), "the orphaned rows are repaired")
+	_, err = documents.Get(ctx, "sboms/default/deleted")
+	require.Error(t, err, "the documents of the orphaned rows are deleted")
+	_, err = documents.Get(ctx, "sboms/default/rolledback/0123")
+	require.Error(t, err, "the unreferenced documents are deleted")
+	_, err = documents.Get(ctx, "vulnerabilityreports/default/running/4567")
+	require.NoError(t, err, "the recent documents are kept")
+
+	report, err = VerifyIntegrity(ctx, db, documents, false, NewDeleteBatchOptions(), slog.Default())
+	require.NoError(t, err)
+	assert.Equal(t, expectedUnrepairable, kinds(report))
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// CreateVulnerabilityFindingsTableSQL creates the table holding the vulnerabilities of the VulnerabilityReports,
+// one row per vulnerable package, so that they can be queried without parsing the report documents.
+// The findings are deleted together with their report.
+const CreateVulnerabilityFindingsTableSQL = `
+CREATE TABLE IF NOT EXISTS vulnerability_findings (
+    namespace VARCHAR(253) NOT NULL,
+    report_name VARCHAR(253) NOT NULL,
+    cve TEXT NOT NULL,
+    severity TEXT NOT NULL,
+    fixable BOOLEAN NOT NULL,
+    suppressed BOOLEAN NOT NULL,
+    FOREIGN KEY (report_name, namespace) REFERENCES vulnerabilityreports (name, namespace) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS vulnerability_findings_cve_idx ON vulnerability_findings (cve);
+CREATE INDEX IF NOT EXISTS vulnerability_findings_report_idx ON vulnerability_findings (namespace, report_name);
+`
+
+// addVulnerabilityFindingsDetailsSQL adds the package, the fixed versions and the CVSS score to the findings.
+// The findings written before these columns existed are deleted, to be backfilled again with all the details.
+const addVulnerabilityFindingsDetailsSQL = `
+ALTER TABLE vulnerability_findings
+    ADD COLUMN IF NOT EXISTS package_name TEXT,
+    ADD COLUMN IF NOT EXISTS purl TEXT,
+    ADD COLUMN IF NOT EXISTS installed_version TEXT,
+    ADD COLUMN IF NOT EXISTS fixed_versions TEXT[],
+    ADD COLUMN IF NOT EXISTS cvss_score REAL;
+CREATE INDEX IF NOT EXISTS vulnerability_findings_severity_idx ON vulnerability_findings (severity);
+DELETE FROM vulnerability_findings WHERE purl IS NULL;
+`
+
+// backfillVulnerabilityFindingsSQL populates the findings of the reports stored before the findings table existed.
+// It is superseded by backfillVulnerabilityFindingsDetailsSQL, and kept since it was applied by the existing databases.
+const backfillVulnerabilityFindingsSQL = `
+INSERT INTO vulnerability_findings (namespace, report_name, cve, severity, fixable, suppressed)
+SELECT
+    r.namespace,
+    r.name,
+    vulnerability->>'cve',
+    COALESCE(vulnerability->>'severity', ''),
+    jsonb_array_length(COALESCE(vulnerability->'fixedVersions', '[]'::jsonb)) > 0,
+    COALESCE((vulnerability->>'suppressed')::boolean, false)
+FROM vulnerabilityreports r,
+    jsonb_path_query(r.object, 'lax $.report.results[*].vulnerabilities[*]') AS vulnerability
+WHERE NOT EXISTS (
+    SELECT 1 FROM vulnerability_findings f
+    WHERE f.namespace = r.namespace AND f.report_name = r.name
+)
+`
+
+// backfillVulnerabilityFindingsDetailsSQL populates the findings of the reports, with their details,
+// after addVulnerabilityFindingsDetailsSQL deleted the findings written without them.
+// The CVSS score is the highest v3 score reported by the vulnerability data sources.
+const backfillVulnerabilityFindingsDetailsSQL = `
+INSERT INTO vulnerability_findings (
+    namespace, report_name, cve, severity, fixable, suppressed,
+    package_name, purl, installed_version, fixed_versions, cvss_score
+)
+SELECT
+    r.namespace,
+    r.name,
+    vulnerability->>'cve',
+    COALESCE(vulnerability->>'severity', ''),
+    jsonb_array_length(COALESCE(vulnerability->'fixedVersions', '[]'::jsonb)) > 0,
+    COALESCE((vulnerability->>'suppressed')::boolean, false),
+    COALESCE(vulnerability->>'packageName', ''),
+    COALESCE(vulnerability->>'purl', ''),
+    COALESCE(vulnerability->>'installedVersion', ''),
+    ARRAY(SELECT jsonb_array_elements_text(COALESCE(vulnerability->'fixedVersions', '[]'::jsonb))),
+    (
+        SELECT MAX((cvss.value->>'v3score')::real)
+        FROM jsonb_each(COALESCE(vulnerability->'cvss', '{}'::jsonb)) AS cvss
+        WHERE cvss.value->>'v3score' ~ '^[0-9]+(\.[0-9]+)?$'
+    )
+FROM vulnerabilityreports r,
+    jsonb_path_query(r.object, 'lax $.report.results[*].vulnerabilities[*]') AS vulnerability
+WHERE NOT EXISTS (
+    SELECT 1 FROM vulnerability_findings f
+    WHERE f.namespace = r.namespace AND f.report_name = r.name
+)
+`
+
+// deleteVulnerabilityFindingsSQL deletes the findings of a report, before they are written again.
+const deleteVulnerabilityFindingsSQL = `
+DELETE FROM vulnerability_findings WHERE namespace = $1 AND report_name = $2
+`
+
+// vulnerabilityFindingsColumns are the columns written by writeVulnerabilityFindings, in order.
+var vulnerabilityFindingsColumns = []string{
+	"namespace", "report_name", "cve", "severity", "fixable", "suppressed",
+	"package_name", "purl", "installed_version", "fixed_versions", "cvss_score",
+}
+
+// writeVulnerabilityFindings replaces the findings of the VulnerabilityReport in the write transaction.
+// Deleted reports do not need to be handled, since their findings are deleted in cascade.
+func writeVulnerabilityFindings(ctx context.Context, tx pgx.Tx, name, namespace string, obj runtime.Object) error {
+	report, ok := obj.(*v1alpha1.VulnerabilityReport)
+	if !ok {
+		return fmt.Errorf("unexpected type %T", obj)
+	}
+	// The report was read without its results, which were not retained, and is written back,
+	// e.g. to change its labels: its findings are kept.
+	if report.Report.Results == nil && report.Annotations[v1alpha1.AnnotationRawDocumentKey] == v1alpha1.RawDocumentNotRetained {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, deleteVulnerabilityFindingsSQL, namespace, name); err != nil {
+		return fmt.Errorf("failed to delete vulnerability findings: %w", err)
+	}
+
+	rows := vulnerabilityFindingsRows(name, namespace, report)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	_, err := tx.CopyFrom(ctx,
+		pgx.Identifier{"vulnerability_findings"},
+		vulnerabilityFindingsColumns,
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert vulnerability findings: %w", err)
+	}
+
+	return nil
+}
+
+// vulnerabilityFindingsRows returns one row per vulnerable package of the report.
+func vulnerabilityFindingsRows(name, namespace string, report *v1alpha1.VulnerabilityReport) [][]any {
+	rows := [][]any{}
+	for _, result := range report.Report.Results {
+		for _, vulnerability := range result.Vulnerabilities {
+			fixedVersions := vulnerability.FixedVersions
+			if fixedVersions == nil {
+				fixedVersions = []string{}
+			}
+			rows = append(rows, []any{
+				namespace,
+				name,
+				vulnerability.CVE,
+				vulnerability.Severity,
+				len(vulnerability.FixedVersions) > 0,
+				vulnerability.Suppressed,
+				vulnerability.PackageName,
+				vulnerability.PURL,
+				vulnerability.InstalledVersion,
+				fixedVersions,
+				cvssScore(vulnerability.CVSS),
+			})
+		}
+	}
+
+	return rows
+}
+
+// cvssScore returns the highest v3 score reported by the vulnerability data sources,
+// or nil when none of them provides a valid score.
+func cvssScore(cvss map[string]v1alpha1.CVSS) *float32 {
+	var score *float32
+	for _, source := range cvss {
+		value, err := strconv.ParseFloat(source.V3Score, 32)
+		if err != nil {
+			continue
+		}
+		if score == nil || float32(value) > *score {
+			score = ptr.To(float32(value))
+		}
+	}
+
+	return score
+}
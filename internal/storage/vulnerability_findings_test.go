@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/ptr"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+func TestCVSSScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		cvss     map[string]v1alpha1.CVSS
+		expected *float32
+	}{
+		{
+			name:     "no cvss",
+			cvss:     nil,
+			expected: nil,
+		},
+		{
+			name: "highest score",
+			cvss: map[string]v1alpha1.CVSS{
+				"nvd":    {V3Score: "7.5"},
+				"redhat": {V3Score: "9.8"},
+			},
+			expected: ptr.To(float32(9.8)),
+		},
+		{
+			name: "invalid scores are ignored",
+			cvss: map[string]v1alpha1.CVSS{
+				"nvd":    {V3Score: ""},
+				"redhat": {V3Score: "5.3"},
+			},
+			expected: ptr.To(float32(5.3)),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, cvssScore(test.cvss))
+		})
+	}
+}
+
+func TestVulnerabilityFindingsRows(t *testing.T) {
+	report := &v1alpha1.VulnerabilityReport{
+		Report: v1alpha1.Report{
+			Results: []v1alpha1.Result{
+				{
+					Vulnerabilities: []v1alpha1.Vulnerability{
+						{
+							CVE:              "CVE-2021-44228",
+							PackageName:      "org.apache.logging.log4j:log4j-core",
+							PURL:             "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1",
+							InstalledVersion: "2.14.1",
+							FixedVersions:    []string{"2.15.0"},
+							Severity:         "CRITICAL",
+							CVSS:             map[string]v1alpha1.CVSS{"nvd": {V3Score: "10"}},
+						},
+						{
+							CVE:              "CVE-2024-0001",
+							PackageName:      "openssl",
+							PURL:             "pkg:apk/alpine/openssl@3.1.0",
+							InstalledVersion: "3.1.0",
+							Severity:         "LOW",
+							Suppressed:       true,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	rows := vulnerabilityFindingsRows("report", "default", report)
+	require.Len(t, rows, 2)
+	for _, row := range rows {
+		require.Len(t, row, len(vulnerabilityFindingsColumns))
+	}
+
+	assert.Equal(t, []any{
+		"default", "report", "CVE-2021-44228", "CRITICAL", true, false,
+		"org.apache.logging.log4j:log4j-core", "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1", "2.14.1",
+		[]string{"2.15.0"}, ptr.To(float32(10)),
+	}, rows[0])
+	assert.Equal(t, []any{
+		"default", "report", "CVE-2024-0001", "LOW", false, true,
+		"openssl", "pkg:apk/alpine/openssl@3.1.0", "3.1.0",
+		[]string{}, (*float32)(nil),
+	}, rows[1])
+}
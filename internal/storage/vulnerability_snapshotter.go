@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// CreateVulnerabilitySnapshotTableSQL creates the table holding the history of the per-namespace vulnerability totals.
+const CreateVulnerabilitySnapshotTableSQL = `
+CREATE TABLE IF NOT EXISTS vulnerability_snapshots (
+    taken_at TIMESTAMPTZ NOT NULL,
+    namespace VARCHAR(253) NOT NULL,
+    reports INTEGER NOT NULL,
+    critical INTEGER NOT NULL,
+    high INTEGER NOT NULL,
+    medium INTEGER NOT NULL,
+    low INTEGER NOT NULL,
+    unknown INTEGER NOT NULL,
+    suppressed INTEGER NOT NULL,
+    PRIMARY KEY (taken_at, namespace)
+);
+`
+
+// createVulnerabilitySnapshotTimesTableSQL creates the table recording the time of every snapshot,
+// so that the snapshots taken while there were no VulnerabilityReports are recorded as well.
+// The times of the snapshots taken before it existed are backfilled.
+const createVulnerabilitySnapshotTimesTableSQL = `
+CREATE TABLE IF NOT EXISTS vulnerability_snapshot_times (
+    taken_at TIMESTAMPTZ PRIMARY KEY
+);
+
+INSERT INTO vulnerability_snapshot_times (taken_at)
+SELECT DISTINCT taken_at FROM vulnerability_snapshots
+ON CONFLICT (taken_at) DO NOTHING;
+`
+
+// insertVulnerabilitySnapshotSQL snapshots the per-namespace totals of the VulnerabilityReports.
+// The snapshot time is truncated to the snapshot interval ($1, in seconds),
+// so that multiple storage replicas record a single snapshot per interval:
+// the totals are only inserted by the replica recording the time of the snapshot.
+const insertVulnerabilitySnapshotSQL = `
+WITH snapshot AS (
+    INSERT INTO vulnerability_snapshot_times (taken_at)
+    VALUES (to_timestamp(floor(extract(epoch FROM now()) / $1) * $1))
+    ON CONFLICT (taken_at) DO NOTHING
+    RETURNING taken_at
+)
+INSERT INTO vulnerability_snapshots (taken_at, namespace, reports, critical, high, medium, low, unknown, suppressed)
+SELECT
+    snapshot.taken_at,
+    namespace,
+    COUNT(*),
+    COALESCE(SUM((object->'report'->'summary'->>'critical')::int), 0),
+    COALESCE(SUM((object->'report'->'summary'->>'high')::int), 0),
+    COALESCE(SUM((object->'report'->'summary'->>'medium')::int), 0),
+    COALESCE(SUM((object->'report'->'summary'->>'low')::int), 0),
+    COALESCE(SUM((object->'report'->'summary'->>'unknown')::int), 0),
+    COALESCE(SUM((object->'report'->'summary'->>'suppressed')::int), 0)
+FROM vulnerabilityreports, snapshot
+GROUP BY snapshot.taken_at, namespace
+ON CONFLICT (taken_at, namespace) DO NOTHING
+`
+
+// pruneVulnerabilitySnapshotsSQL deletes up to $2 snapshots older than the retention window ($1, in seconds).
+// The snapshots locked by a concurrent batch are skipped.
+const pruneVulnerabilitySnapshotsSQL = `
+DELETE FROM vulnerability_snapshots
+WHERE ctid IN (
+    SELECT ctid FROM vulnerability_snapshots
+    WHERE taken_at < now() - make_interval(secs => $1)
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+`
+
+// pruneVulnerabilitySnapshotTimesSQL deletes up to $2 snapshot times older than the retention window ($1, in seconds).
+const pruneVulnerabilitySnapshotTimesSQL = `
+DELETE FROM vulnerability_snapshot_times
+WHERE ctid IN (
+    SELECT ctid FROM vulnerability_snapshot_times
+    WHERE taken_at < now() - make_interval(secs => $1)
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+`
+
+// latestVulnerabilitySnapshotSQL returns the most recent snapshot of every namespace.
+// The namespaces without VulnerabilityReports at the time of the snapshot are not returned.
+const latestVulnerabilitySnapshotSQL = `
+SELECT namespace, reports, critical, high, medium, low, unknown, suppressed
+FROM vulnerability_snapshots
+WHERE taken_at = (SELECT MAX(taken_at) FROM vulnerability_snapshot_times)
+`
+
+var (
+	vulnerabilitiesGauge = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "sbomscanner",
+			Name:           "vulnerabilities",
+			Help:           "Number of vulnerabilities found in the VulnerabilityReports of a namespace, by severity, as of the latest snapshot.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"namespace", "severity"},
+	)
+	vulnerabilityReportsGauge = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "sbomscanner",
+			Name:           "vulnerability_reports",
+			Help:           "Number of VulnerabilityReports in a namespace, as of the latest snapshot.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"namespace"},
+	)
+
+	registerMetricsOnce sync.Once
+)
+
+// registerMetrics registers the vulnerability metrics in the registry served by the API server /metrics endpoint.
+func registerMetrics() {
+	registerMetricsOnce.Do(func() {
+		legacyregistry.MustRegister(vulnerabilitiesGauge, vulnerabilityReportsGauge)
+	})
+}
+
+// VulnerabilitySnapshotter periodically records the per-namespace vulnerability totals
+// in the vulnerability_snapshots table, prunes the snapshots older than the retention window
+// and exposes the latest snapshot as Prometheus gauges.
+type VulnerabilitySnapshotter struct {
+	db        *pgxpool.Pool
+	interval  time.Duration
+	retention time.Duration
+	batch     DeleteBatchOptions
+	readOnly  *ReadOnlyMode
+	logger    *slog.Logger
+}
+
+// NewVulnerabilitySnapshotter creates a new VulnerabilitySnapshotter.
+func NewVulnerabilitySnapshotter(
+	db *pgxpool.Pool,
+	interval, retention time.Duration,
+	batch DeleteBatchOptions,
+	readOnly *ReadOnlyMode,
+	logger *slog.Logger,
+) *VulnerabilitySnapshotter {
+	registerMetrics()
+
+	return &VulnerabilitySnapshotter{
+		db:        db,
+		interval:  interval,
+		retention: retention,
+		batch:     batch,
+		readOnly:  readOnly,
+		logger:    logger.With("component", "vulnerability_snapshotter"),
+	}
+}
+
+// Start takes a snapshot immediately and then on every interval, until the context is canceled.
+func (s *VulnerabilitySnapshotter) Start(ctx context.Context) {
+	s.logger.InfoContext(ctx, "Starting vulnerability snapshotter", "interval", s.interval, "retention", s.retention)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Snapshot(ctx); err != nil {
+			s.logger.ErrorContext(ctx, "Failed to snapshot vulnerabilities", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.logger.InfoContext(ctx, "Stopping vulnerability snapshotter")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Snapshot records the current per-namespace vulnerability totals, prunes the expired snapshots
+// and updates the metrics.
+// No snapshot is recorded while the storage is in read-only mode.
+func (s *VulnerabilitySnapshotter) Snapshot(ctx context.Context) error {
+	if s.readOnly.Enabled() {
+		s.logger.DebugContext(ctx, "Storage in read-only mode, skipping vulnerability snapshot")
+		return nil
+	}
+
+	if _, err := s.db.Exec(ctx, insertVulnerabilitySnapshotSQL, s.interval.Seconds()); err != nil {
+		return fmt.Errorf("failed to insert vulnerability snapshot: %w", err)
+	}
+
+	pruned, err := deleteInBatches(ctx, s.db, s.batch, s.logger.With("task", "prune"), pruneVulnerabilitySnapshotsSQL, s.retention.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to prune vulnerability snapshots: %w", err)
+	}
+	if pruned > 0 {
+		s.logger.DebugContext(ctx, "Pruned vulnerability snapshots", "count", pruned)
+	}
+	if _, err := deleteInBatches(ctx, s.db, s.batch, s.logger.With("task", "prune"), pruneVulnerabilitySnapshotTimesSQL, s.retention.Seconds()); err != nil {
+		return fmt.Errorf("failed to prune vulnerability snapshot times: %w", err)
+	}
+
+	if err := s.updateMetrics(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateMetrics sets the gauges to the values of the latest snapshot.
+func (s *VulnerabilitySnapshotter) updateMetrics(ctx context.Context) error {
+	rows, err := s.db.Query(ctx, latestVulnerabilitySnapshotSQL)
+	if err != nil {
+		return fmt.Errorf("failed to query latest vulnerability snapshot: %w", err)
+	}
+	defer rows.Close()
+
+	// Reset the gauges so that namespaces without reports are no longer exported.
+	vulnerabilitiesGauge.Reset()
+	vulnerabilityReportsGauge.Reset()
+
+	for rows.Next() {
+		var (
+			namespace                                                 string
+			reports, critical, high, medium, low, unknown, suppressed int
+		)
+		if err := rows.Scan(&namespace, &reports, &critical, &high, &medium, &low, &unknown, &suppressed); err != nil {
+			return fmt.Errorf("failed to scan vulnerability snapshot: %w", err)
+		}
+
+		vulnerabilityReportsGauge.WithLabelValues(namespace).Set(float64(reports))
+		vulnerabilitiesGauge.WithLabelValues(namespace, "critical").Set(float64(critical))
+		vulnerabilitiesGauge.WithLabelValues(namespace, "high").Set(float64(high))
+		vulnerabilitiesGauge.WithLabelValues(namespace, "medium").Set(float64(medium))
+		vulnerabilitiesGauge.WithLabelValues(namespace, "low").Set(float64(low))
+		vulnerabilitiesGauge.WithLabelValues(namespace, "unknown").Set(float64(unknown))
+		vulnerabilitiesGauge.WithLabelValues(namespace, "suppressed").Set(float64(suppressed))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read vulnerability snapshot: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics/testutil"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+func TestVulnerabilitySnapshotter(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	insertReport := func(name, namespace string, summary v1alpha1.Summary) {
+		data, err := json.Marshal(map[string]any{
+			"metadata": metav1.ObjectMeta{Name: name, Namespace: namespace},
+			"report":   map[string]any{"summary": summary},
+		})
+		require.NoError(t, err)
+		_, err = db.Exec(ctx, "INSERT INTO vulnerabilityreports (name, namespace, object) VALUES ($1, $2, $3)", name, namespace, data)
+		require.NoError(t, err)
+	}
+	countSnapshots := func() int {
+		var count int
+		require.NoError(t, db.QueryRow(ctx, "SELECT COUNT(*) FROM vulnerability_snapshot_times").Scan(&count))
+		return count
+	}
+	// waitNextInterval waits for the next snapshot interval, so that a new snapshot is recorded.
+	waitNextInterval := func() {
+		time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	}
+
+	insertReport("alpine", "default", v1alpha1.Summary{Critical: 2, High: 1})
+	insertReport("debian", "default", v1alpha1.Summary{Critical: 1, Suppressed: 3})
+	insertReport("ubuntu", "other", v1alpha1.Summary{Low: 4})
+
+	snapshotter := NewVulnerabilitySnapshotter(db, time.Second, time.Hour, NewDeleteBatchOptions(), NewReadOnlyMode(false, slog.Default()), slog.Default())
+	require.NoError(t, snapshotter.Snapshot(ctx))
+	// A second replica snapshotting in the same interval does not record another snapshot.
+	require.NoError(t, snapshotter.Snapshot(ctx))
+	assert.Equal(t, 1, countSnapshots())
+
+	require.NoError(t, testutil.CollectAndCompare(vulnerabilityReportsGauge, strings.NewReader(`
+# HELP sbomscanner_vulnerability_reports [ALPHA] Number of VulnerabilityReports in a namespace, as of the latest snapshot.
+# TYPE sbomscanner_vulnerability_reports gauge
+sbomscanner_vulnerability_reports{namespace="default"} 2
+sbomscanner_vulnerability_reports{namespace="other"} 1
+`), "sbomscanner_vulnerability_reports"))
+	require.NoError(t, testutil.CollectAndCompare(vulnerabilitiesGauge, strings.NewReader(`
+# HELP sbomscanner_vulnerabilities [ALPHA] Number of vulnerabilities found in the VulnerabilityReports of a namespace, by severity, as of the latest snapshot.
+# TYPE sbomscanner_vulnerabilities gauge
+sbomscanner_vulnerabilities{namespace="default",severity="critical"} 3
+sbomscanner_vulnerabilities{namespace="default",severity="high"} 1
+sbomscanner_vulnerabilities{namespace="default",severity="low"} 0
+sbomscanner_vulnerabilities{namespace="default",severity="medium"} 0
+sbomscanner_vulnerabilities{namespace="default",severity="suppressed"} 3
+sbomscanner_vulnerabilities{namespace="default",severity="unknown"} 0
+sbomscanner_vulnerabilities{namespace="other",severity="critical"} 0
+sbomscanner_vulnerabilities{namespace="other",severity="high"} 0
+sbomscanner_vulnerabilities{namespace="other",severity="low"} 4
+sbomscanner_vulnerabilities{namespace="other",severity="medium"} 0
+sbomscanner_vulnerabilities{namespace="other",severity="suppressed"} 0
+sbomscanner_vulnerabilities{namespace="other",severity="unknown"} 0
+`), "sbomscanner_vulnerabilities"))
+
+	// Once all the reports are deleted, the latest snapshot is empty instead of the last non-empty one.
+	_, err := db.Exec(ctx, "DELETE FROM vulnerabilityreports")
+	require.NoError(t, err)
+	waitNextInterval()
+	require.NoError(t, snapshotter.Snapshot(ctx))
+	assert.Equal(t, 2, countSnapshots())
+
+	require.NoError(t, testutil.CollectAndCompare(vulnerabilityReportsGauge, strings.NewReader(""), "sbomscanner_vulnerability_reports"))
+	require.NoError(t, testutil.CollectAndCompare(vulnerabilitiesGauge, strings.NewReader(""), "sbomscanner_vulnerabilities"))
+}
+
+func TestVulnerabilitySnapshotterReadOnly(t *testing.T) {
+	db := newTestDB(t)
+
+	snapshotter := NewVulnerabilitySnapshotter(db, time.Second, time.Hour, NewDeleteBatchOptions(), NewReadOnlyMode(true, slog.Default()), slog.Default())
+	require.NoError(t, snapshotter.Snapshot(t.Context()))
+
+	var count int
+	require.NoError(t, db.QueryRow(t.Context(), "SELECT COUNT(*) FROM vulnerability_snapshot_times").Scan(&count))
+	assert.Equal(t, 0, count)
+}
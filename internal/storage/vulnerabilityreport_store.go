@@ -2,11 +2,13 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	"github.com/stephenafamo/bob/dialect/psql"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
@@ -24,10 +26,19 @@ CREATE TABLE IF NOT EXISTS vulnerabilityreports (
 `
 
 // NewVulnerabilityReport returns a store registry that will work against API services.
+// The results of the reports are offloaded to documents when it is not nil,
+// their summaries are kept in the database for the aggregations.
+// The results are dropped once normalized in the findings table when retainResults is false.
+// The writes invalidate the results of queryCache.
 func NewVulnerabilityReport(
 	scheme *runtime.Scheme,
 	optsGetter generic.RESTOptionsGetter,
 	db *pgxpool.Pool,
+	readOnly *ReadOnlyMode,
+	documents DocumentStorage,
+	retainResults bool,
+	queryCache *QueryCache,
+	maxListBytes int64,
 	logger *slog.Logger,
 ) (*registry.Store, error) {
 	strategy := newVulnerabilityReportStrategy(scheme)
@@ -43,12 +54,20 @@ func NewVulnerabilityReport(
 		SingularQualifiedResource: v1alpha1.Resource("vulnerabilityreport"),
 		Storage: registry.DryRunnableStorage{
 			Storage: &store{
-				db:          db,
-				broadcaster: watch.NewBroadcaster(1000, watch.WaitIfChannelFull),
-				table:       "vulnerabilityreports",
-				newFunc:     newFunc,
-				newListFunc: newListFunc,
-				logger:      logger.With("store", "vulnerabilityreport"),
+				db:           db,
+				broadcaster:  watch.NewBroadcaster(1000, watch.WaitIfChannelFull),
+				table:        "vulnerabilityreports",
+				newFunc:      newFunc,
+				newListFunc:  newListFunc,
+				readOnly:     readOnly,
+				writeHook:    writeVulnerabilityFindings,
+				documents:    newVulnerabilityReportDocumentOffload(documents, retainResults),
+				queryCache:   queryCache,
+				maxListBytes: maxListBytes,
+				logger:       logger.With("store", "vulnerabilityreport"),
+				computedFields: map[string]func() psql.Expression{
+					"imageMetadata.osVersion": osVersionExpression,
+				},
 			},
 		},
 		CreateStrategy: strategy,
@@ -65,6 +84,48 @@ func NewVulnerabilityReport(
 	return store, nil
 }
 
+// newVulnerabilityReportDocumentOffload returns the offload of the results of the VulnerabilityReports,
+// nil when documents is nil and the results are retained. The results are dropped when they are not retained.
+func newVulnerabilityReportDocumentOffload(documents DocumentStorage, retainResults bool) *documentOffload {
+	if documents == nil && retainResults {
+		return nil
+	}
+	if !retainResults {
+		documents = nil
+	}
+
+	return &documentOffload{
+		storage: documents,
+		detach: func(obj runtime.Object) ([]byte, error) {
+			report, ok := obj.(*v1alpha1.VulnerabilityReport)
+			if !ok {
+				return nil, fmt.Errorf("expected VulnerabilityReport, got %T", obj)
+			}
+			if report.Report.Results == nil {
+				return nil, nil
+			}
+			document, err := json.Marshal(report.Report.Results)
+			if err != nil {
+				return nil, fmt.Errorf("marshaling results: %w", err)
+			}
+			report.Report.Results = nil
+
+			return document, nil
+		},
+		attach: func(obj runtime.Object, document []byte) error {
+			report, ok := obj.(*v1alpha1.VulnerabilityReport)
+			if !ok {
+				return fmt.Errorf("expected VulnerabilityReport, got %T", obj)
+			}
+			if err := json.Unmarshal(document, &report.Report.Results); err != nil {
+				return fmt.Errorf("unmarshaling results: %w", err)
+			}
+
+			return nil
+		},
+	}
+}
+
 type vulnerabilityReportTableConvertor struct{}
 
 func (c *vulnerabilityReportTableConvertor) ConvertToTable(_ context.Context, obj runtime.Object, _ runtime.Object) (*metav1.Table, error) {
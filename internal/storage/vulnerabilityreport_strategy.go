@@ -6,6 +6,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/apiserver/pkg/storage/names"
+
+	"github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
 )
 
 // newVulnerabilityReportStrategy creates and returns a vulnerabilityReportStrategy instance
@@ -22,14 +24,22 @@ func (vulnerabilityReportStrategy) NamespaceScoped() bool {
 	return true
 }
 
-func (vulnerabilityReportStrategy) PrepareForCreate(_ context.Context, _ runtime.Object) {
+// PrepareForCreate normalizes the platform of the image before it is stored.
+func (vulnerabilityReportStrategy) PrepareForCreate(_ context.Context, obj runtime.Object) {
+	report := obj.(*v1alpha1.VulnerabilityReport)
+	normalizeImageMetadata(&report.ImageMetadata)
 }
 
-func (vulnerabilityReportStrategy) PrepareForUpdate(_ context.Context, _, _ runtime.Object) {
+// PrepareForUpdate normalizes the platform of the image before it is stored.
+func (vulnerabilityReportStrategy) PrepareForUpdate(_ context.Context, obj, _ runtime.Object) {
+	report := obj.(*v1alpha1.VulnerabilityReport)
+	normalizeImageMetadata(&report.ImageMetadata)
 }
 
-func (vulnerabilityReportStrategy) Validate(_ context.Context, _ runtime.Object) field.ErrorList {
-	return field.ErrorList{}
+// Validate checks the OS version of the image of the report.
+func (vulnerabilityReportStrategy) Validate(_ context.Context, obj runtime.Object) field.ErrorList {
+	report := obj.(*v1alpha1.VulnerabilityReport)
+	return validateImageMetadata(&report.ImageMetadata, field.NewPath("imageMetadata"))
 }
 
 // WarningsOnCreate returns warnings for the creation of the given object.
@@ -48,8 +58,10 @@ func (vulnerabilityReportStrategy) AllowUnconditionalUpdate() bool {
 func (vulnerabilityReportStrategy) Canonicalize(_ runtime.Object) {
 }
 
-func (vulnerabilityReportStrategy) ValidateUpdate(_ context.Context, _, _ runtime.Object) field.ErrorList {
-	return field.ErrorList{}
+// ValidateUpdate checks the OS version of the image of the report.
+func (vulnerabilityReportStrategy) ValidateUpdate(_ context.Context, obj, _ runtime.Object) field.ErrorList {
+	report := obj.(*v1alpha1.VulnerabilityReport)
+	return validateImageMetadata(&report.ImageMetadata, field.NewPath("imageMetadata"))
 }
 
 // WarningsOnUpdate returns warnings for the given update.
@@ -0,0 +1,3 @@
+// Package vulndb manages the vulnerability database used by the worker to scan the SBOMs:
+// its source, its pinned version, the directory where it is cached and its periodic refresh.
+package vulndb
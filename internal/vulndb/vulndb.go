@@ -0,0 +1,280 @@
+package vulndb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aquasecurity/trivy-db/pkg/metadata"
+	trivyCommands "github.com/aquasecurity/trivy/pkg/commands"
+	trivydb "github.com/aquasecurity/trivy/pkg/db"
+	"github.com/google/go-containerregistry/pkg/name"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+)
+
+// sourceFile records the source of the cached database,
+// so that the database is downloaded again when the source or the pinned version change.
+const sourceFile = "sbomscanner-source"
+
+// Options configures the vulnerability database.
+type Options struct {
+	// Repository is the OCI repository of the database, e.g. a mirror of the default one.
+	Repository string
+	// Version pins the database to a tag or a digest of the repository, e.g. "sha256:...".
+	// The latest database of the schema supported by Trivy is used when empty.
+	Version string
+	// CacheDir is the directory where the database is downloaded.
+	CacheDir string
+	// RefreshInterval is the interval between the downloads of the database by the worker.
+	// When 0, Trivy downloads the database before a scan when it is outdated.
+	RefreshInterval time.Duration
+}
+
+// Source returns the OCI reference of the database, including the pinned version if any.
+func (o Options) Source() string {
+	switch {
+	case o.Version == "":
+		return o.Repository
+	case strings.Contains(o.Version, ":"):
+		return o.Repository + "@" + o.Version
+	default:
+		return o.Repository + ":" + o.Version
+	}
+}
+
+// Validate checks that the options are consistent.
+func (o Options) Validate() error {
+	if o.Repository == "" {
+		return errors.New("the vulnerability database repository is required")
+	}
+	if o.CacheDir == "" {
+		return errors.New("the vulnerability database cache directory is required")
+	}
+	if o.RefreshInterval < 0 {
+		return errors.New("the vulnerability database refresh interval must not be negative")
+	}
+	repository, err := name.ParseReference(o.Repository, name.WithDefaultTag(""))
+	if err != nil {
+		return fmt.Errorf("invalid vulnerability database repository %q: %w", o.Repository, err)
+	}
+	if o.Version != "" && repository.Identifier() != "" {
+		return fmt.Errorf("the vulnerability database repository %q must not include a tag or a digest when the version is pinned", o.Repository)
+	}
+	if _, err = name.ParseReference(o.Source()); err != nil {
+		return fmt.Errorf("invalid vulnerability database source %q: %w", o.Source(), err)
+	}
+
+	return nil
+}
+
+// DB is the vulnerability database used by the scans.
+// The database is not read by the scans while it is refreshed by the worker.
+type DB struct {
+	options Options
+	mu      sync.RWMutex
+	// refreshMu serializes the forced refreshes, so that the database is downloaded once for concurrent requests.
+	refreshMu sync.Mutex
+	logger    *slog.Logger
+}
+
+// New creates the cache directory of the database.
+// The cached database is removed when it was downloaded from another source,
+// e.g. when the pinned version changed, so that the scans do not use it.
+// When the database is refreshed by the worker, the scans wait for the first refresh done by Start.
+func New(options Options, logger *slog.Logger) (*DB, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	db := &DB{
+		options: options,
+		logger:  logger.With("component", "vulndb"),
+	}
+	if err := db.checkSource(); err != nil {
+		return nil, err
+	}
+	if options.RefreshInterval > 0 {
+		// Released by Start after the first refresh.
+		db.mu.Lock()
+	}
+
+	return db, nil
+}
+
+// checkSource removes the cached database if it was downloaded from another source, and records the current one.
+// A database without a recorded source, e.g. copied to the cache directory of an air-gapped worker, is kept.
+func (db *DB) checkSource() error {
+	dir := trivydb.Dir(db.options.CacheDir)
+	path := filepath.Join(dir, sourceFile)
+
+	recorded, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("cannot read the source of the vulnerability database: %w", err)
+	case string(recorded) != db.options.Source():
+		db.logger.Info("Removing the vulnerability database downloaded from another source",
+			"cachedSource", string(recorded), "source", db.options.Source())
+		if err = os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("cannot remove the vulnerability database %s: %w", dir, err)
+		}
+	}
+
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("cannot create the vulnerability database directory %s: %w", dir, err)
+	}
+	if err = os.WriteFile(path, []byte(db.options.Source()), 0o600); err != nil {
+		return fmt.Errorf("cannot record the source of the vulnerability database: %w", err)
+	}
+
+	return nil
+}
+
+// TrivyArgs returns the Trivy arguments selecting the database of the scans.
+// The database is not updated by the scans when it is refreshed by the worker.
+func (db *DB) TrivyArgs() []string {
+	args := []string{
+		"--cache-dir", db.options.CacheDir,
+		"--db-repository", db.options.Source(),
+	}
+	if db.options.RefreshInterval > 0 {
+		args = append(args, "--skip-db-update")
+	}
+
+	return args
+}
+
+// RLock locks the database for reading, it must be held while the database is used by a scan.
+func (db *DB) RLock() {
+	db.mu.RLock()
+}
+
+// RUnlock undoes a single RLock call.
+func (db *DB) RUnlock() {
+	db.mu.RUnlock()
+}
+
+// Info returns the source and the version of the cached database.
+// It must be called with the read lock held.
+func (db *DB) Info() (*storagev1alpha1.VulnerabilityDB, error) {
+	meta, err := metadata.NewClient(trivydb.Dir(db.options.CacheDir)).Get()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the metadata of the vulnerability database: %w", err)
+	}
+
+	return &storagev1alpha1.VulnerabilityDB{
+		Source:        db.options.Source(),
+		SchemaVersion: meta.Version,
+		UpdatedAt:     metav1.NewTime(meta.UpdatedAt),
+		DownloadedAt:  metav1.NewTime(meta.DownloadedAt),
+	}, nil
+}
+
+// Refresh downloads the database if a newer one is available.
+func (db *DB) Refresh(ctx context.Context) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.download(ctx, db.options.CacheDir)
+}
+
+// RefreshIfDownloadedBefore downloads the database again when the cached one was downloaded before the given time,
+// even if Trivy considers it up to date, e.g. to pick up the vulnerabilities published since.
+// The database is downloaded to a staging directory, so the scans keep using the cached one meanwhile.
+func (db *DB) RefreshIfDownloadedBefore(ctx context.Context, notBefore time.Time) error {
+	db.refreshMu.Lock()
+	defer db.refreshMu.Unlock()
+
+	db.mu.RLock()
+	info, err := db.Info()
+	db.mu.RUnlock()
+	if err == nil && !info.DownloadedAt.Time.Before(notBefore) {
+		return nil
+	}
+
+	stagingDir, err := os.MkdirTemp(db.options.CacheDir, ".refresh-")
+	if err != nil {
+		return fmt.Errorf("cannot create the staging directory of the vulnerability database: %w", err)
+	}
+	defer func() {
+		if removeErr := os.RemoveAll(stagingDir); removeErr != nil {
+			db.logger.ErrorContext(ctx, "Cannot remove the staging directory of the vulnerability database", "error", removeErr)
+		}
+	}()
+
+	db.logger.InfoContext(ctx, "Downloading the vulnerability database again", "source", db.options.Source(), "notBefore", notBefore)
+	if err = db.download(ctx, stagingDir); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	dir := trivydb.Dir(db.options.CacheDir)
+	if err = os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("cannot remove the vulnerability database %s: %w", dir, err)
+	}
+	if err = os.Rename(trivydb.Dir(stagingDir), dir); err != nil {
+		return fmt.Errorf("cannot replace the vulnerability database %s: %w", dir, err)
+	}
+
+	return db.checkSource()
+}
+
+// download downloads the database to the cache directory if a newer one is available.
+// It must be called with the write lock held when the cache directory is the one used by the scans.
+func (db *DB) download(ctx context.Context, cacheDir string) error {
+	app := trivyCommands.NewApp()
+	app.SetArgs([]string{
+		"image",
+		"--download-db-only",
+		"--skip-version-check",
+		"--disable-telemetry",
+		"--quiet",
+		"--cache-dir", cacheDir,
+		"--db-repository", db.options.Source(),
+	})
+	if err := app.ExecuteContext(ctx); err != nil {
+		return fmt.Errorf("failed to download the vulnerability database from %s: %w", db.options.Source(), err)
+	}
+
+	return nil
+}
+
+// Start refreshes the database right away and then periodically, until ctx is canceled.
+// It returns immediately when the database is not refreshed by the worker.
+// The refresh errors are logged, the scans keep using the cached database.
+func (db *DB) Start(ctx context.Context) {
+	if db.options.RefreshInterval <= 0 {
+		return
+	}
+
+	db.logger.InfoContext(ctx, "Downloading the vulnerability database", "source", db.options.Source())
+	if err := db.download(ctx, db.options.CacheDir); err != nil {
+		db.logger.ErrorContext(ctx, "Error downloading the vulnerability database, the cached one is used", "error", err)
+	}
+	db.mu.Unlock()
+
+	ticker := time.NewTicker(db.options.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			db.logger.InfoContext(ctx, "Refreshing the vulnerability database", "source", db.options.Source())
+			if err := db.Refresh(ctx); err != nil {
+				db.logger.ErrorContext(ctx, "Error refreshing the vulnerability database, the cached one is used", "error", err)
+			}
+		}
+	}
+}
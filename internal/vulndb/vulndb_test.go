@@ -0,0 +1,156 @@
+package vulndb
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/trivy-db/pkg/metadata"
+	trivydb "github.com/aquasecurity/trivy/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRepository = "registry.example.com/mirror/trivy-db"
+	sha256Hex      = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+)
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		name           string
+		options        Options
+		expectedSource string
+		expectedError  string
+	}{
+		{
+			name:           "latest",
+			options:        Options{Repository: testRepository, CacheDir: "/cache"},
+			expectedSource: testRepository,
+		},
+		{
+			name:           "pinned tag",
+			options:        Options{Repository: testRepository, Version: "2", CacheDir: "/cache"},
+			expectedSource: testRepository + ":2",
+		},
+		{
+			name:           "pinned digest",
+			options:        Options{Repository: testRepository, Version: "sha256:" + sha256Hex, CacheDir: "/cache"},
+			expectedSource: testRepository + "@sha256:" + sha256Hex,
+		},
+		{
+			name:          "pinned repository with a tag",
+			options:       Options{Repository: testRepository + ":2", Version: "sha256:" + sha256Hex, CacheDir: "/cache"},
+			expectedError: "must not include a tag or a digest when the version is pinned",
+		},
+		{
+			name:          "invalid digest",
+			options:       Options{Repository: testRepository, Version: "sha256:invalid", CacheDir: "/cache"},
+			expectedError: "invalid vulnerability database source",
+		},
+		{
+			name:          "negative refresh interval",
+			options:       Options{Repository: testRepository, CacheDir: "/cache", RefreshInterval: -time.Hour},
+			expectedError: "must not be negative",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.options.Validate()
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedSource, test.options.Source())
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	cacheDir := t.TempDir()
+	dbFile := filepath.Join(trivydb.Dir(cacheDir), "trivy.db")
+
+	// A database copied to the cache directory, without a recorded source, is kept.
+	require.NoError(t, os.MkdirAll(trivydb.Dir(cacheDir), 0o700))
+	require.NoError(t, os.WriteFile(dbFile, []byte("db"), 0o600))
+	_, err := New(Options{Repository: testRepository, CacheDir: cacheDir}, slog.Default())
+	require.NoError(t, err)
+	assert.FileExists(t, dbFile)
+
+	// The database is kept while the source does not change.
+	_, err = New(Options{Repository: testRepository, CacheDir: cacheDir}, slog.Default())
+	require.NoError(t, err)
+	assert.FileExists(t, dbFile)
+
+	// The database is removed when the pinned version changes.
+	_, err = New(Options{Repository: testRepository, Version: "sha256:" + sha256Hex, CacheDir: cacheDir}, slog.Default())
+	require.NoError(t, err)
+	assert.NoFileExists(t, dbFile)
+
+	source, err := os.ReadFile(filepath.Join(trivydb.Dir(cacheDir), sourceFile))
+	require.NoError(t, err)
+	assert.Equal(t, testRepository+"@sha256:"+sha256Hex, string(source))
+}
+
+func TestTrivyArgs(t *testing.T) {
+	db, err := New(Options{Repository: testRepository, CacheDir: t.TempDir()}, slog.Default())
+	require.NoError(t, err)
+	assert.NotContains(t, db.TrivyArgs(), "--skip-db-update")
+
+	cacheDir := t.TempDir()
+	db, err = New(Options{Repository: testRepository, Version: "2", CacheDir: cacheDir, RefreshInterval: time.Hour}, slog.Default())
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"--cache-dir", cacheDir,
+		"--db-repository", testRepository + ":2",
+		"--skip-db-update",
+	}, db.TrivyArgs())
+}
+
+func TestInfo(t *testing.T) {
+	cacheDir := t.TempDir()
+	db, err := New(Options{Repository: testRepository, Version: "2", CacheDir: cacheDir}, slog.Default())
+	require.NoError(t, err)
+
+	_, err = db.Info()
+	require.Error(t, err, "the metadata is missing until the database is downloaded")
+
+	updatedAt := time.Date(2025, 6, 15, 6, 0, 0, 0, time.UTC)
+	downloadedAt := time.Date(2025, 6, 15, 8, 0, 0, 0, time.UTC)
+	require.NoError(t, metadata.NewClient(trivydb.Dir(cacheDir)).Update(metadata.Metadata{
+		Version:      2,
+		UpdatedAt:    updatedAt,
+		DownloadedAt: downloadedAt,
+	}))
+
+	info, err := db.Info()
+	require.NoError(t, err)
+	assert.Equal(t, testRepository+":2", info.Source)
+	assert.Equal(t, 2, info.SchemaVersion)
+	assert.True(t, info.UpdatedAt.Time.Equal(updatedAt))
+	assert.True(t, info.DownloadedAt.Time.Equal(downloadedAt))
+}
+
+func TestRefreshIfDownloadedBefore(t *testing.T) {
+	cacheDir := t.TempDir()
+	db, err := New(Options{Repository: testRepository, CacheDir: cacheDir}, slog.Default())
+	require.NoError(t, err)
+
+	downloadedAt := time.Date(2025, 6, 15, 8, 0, 0, 0, time.UTC)
+	require.NoError(t, metadata.NewClient(trivydb.Dir(cacheDir)).Update(metadata.Metadata{
+		Version:      2,
+		DownloadedAt: downloadedAt,
+	}))
+
+	// The database downloaded after the requested time is kept, without reaching the repository.
+	require.NoError(t, db.RefreshIfDownloadedBefore(t.Context(), downloadedAt.Add(-time.Hour)))
+	require.NoError(t, db.RefreshIfDownloadedBefore(t.Context(), downloadedAt))
+
+	info, err := db.Info()
+	require.NoError(t, err)
+	assert.True(t, info.DownloadedAt.Time.Equal(downloadedAt))
+}
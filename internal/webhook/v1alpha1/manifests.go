@@ -0,0 +1,119 @@
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-logr/logr"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+// manifestBufferSize is the size of the buffer used to look for the start of the JSON manifests.
+const manifestBufferSize = 4096
+
+// ManifestResult is the result of the validation of a manifest.
+type ManifestResult struct {
+	// Index is the position of the manifest in the file, starting from 0.
+	Index     int
+	Kind      string
+	Namespace string
+	Name      string
+	// Err is the validation error, nil when the manifest is valid.
+	Err error
+	// Skipped is true when the kind of the manifest is not validated offline.
+	Skipped bool
+}
+
+// ValidateRegistry validates a Registry with the checks of the admission webhooks:
+// the Registry is defaulted first, as done by the mutating webhook.
+// The reachability check is not done, since it needs to reach the registry.
+func ValidateRegistry(registry *v1alpha1.Registry) error {
+	defaulter := &RegistryCustomDefaulter{logger: logr.Discard()}
+	if err := defaulter.Default(context.Background(), registry); err != nil {
+		return err
+	}
+
+	validator := &RegistryCustomValidator{logger: logr.Discard()}
+	_, err := validator.ValidateCreate(context.Background(), registry)
+
+	return err
+}
+
+// ValidateManifests validates the Registry manifests of a YAML or JSON stream, e.g. before applying them in CI,
+// without a running webhook. The manifests of the other kinds are skipped.
+// An error is returned when the stream cannot be decoded.
+func ValidateManifests(reader io.Reader) ([]ManifestResult, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(reader, manifestBufferSize)
+
+	var results []ManifestResult
+	for index := 0; ; {
+		object := &unstructured.Unstructured{}
+		if err := decoder.Decode(&object.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return results, nil
+			}
+			return results, fmt.Errorf("cannot decode manifest %d: %w", index, err)
+		}
+		if len(object.Object) == 0 {
+			// Empty YAML document.
+			continue
+		}
+
+		result := ManifestResult{
+			Index:     index,
+			Kind:      object.GetKind(),
+			Namespace: object.GetNamespace(),
+			Name:      object.GetName(),
+		}
+		index++
+		if object.GroupVersionKind() != v1alpha1.GroupVersion.WithKind("Registry") {
+			result.Skipped = true
+			results = append(results, result)
+			continue
+		}
+
+		registry := &v1alpha1.Registry{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object.Object, registry); err != nil {
+			result.Err = fmt.Errorf("cannot decode Registry: %w", err)
+		} else {
+			result.Err = ValidateRegistry(registry)
+		}
+		results = append(results, result)
+	}
+}
+
+// Causes returns the invalid fields of a validation error, or the error itself when it is not an API error.
+func (r ManifestResult) Causes() []string {
+	if r.Err == nil {
+		return nil
+	}
+
+	var statusErr apierrors.APIStatus
+	if !errors.As(r.Err, &statusErr) || statusErr.Status().Details == nil || len(statusErr.Status().Details.Causes) == 0 {
+		return []string{r.Err.Error()}
+	}
+
+	causes := make([]string, 0, len(statusErr.Status().Details.Causes))
+	for _, cause := range statusErr.Status().Details.Causes {
+		causes = append(causes, formatCause(cause))
+	}
+
+	return causes
+}
+
+func formatCause(cause metav1.StatusCause) string {
+	if cause.Field == "" {
+		return cause.Message
+	}
+
+	return fmt.Sprintf("%s: %s", cause.Field, cause.Message)
+}
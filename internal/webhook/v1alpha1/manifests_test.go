@@ -0,0 +1,80 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testManifests = `
+apiVersion: sbomscanner.kubewarden.io/v1alpha1
+kind: Registry
+metadata:
+  name: valid
+  namespace: default
+spec:
+  uri: ghcr.io
+  platforms:
+    - arch: amd64
+      os: linux
+---
+---
+apiVersion: sbomscanner.kubewarden.io/v1alpha1
+kind: Registry
+metadata:
+  name: invalid
+  namespace: default
+spec:
+  uri: ghcr.io
+  catalogType: NoCatalog
+  platforms:
+    - arch: armz
+      os: linux
+---
+apiVersion: sbomscanner.kubewarden.io/v1alpha1
+kind: ScanJob
+metadata:
+  name: scanjob
+  namespace: default
+spec:
+  registry: valid
+`
+
+func TestValidateManifests(t *testing.T) {
+	results, err := ValidateManifests(strings.NewReader(testManifests))
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, ManifestResult{Index: 0, Kind: "Registry", Namespace: "default", Name: "valid"}, results[0])
+
+	assert.Equal(t, 1, results[1].Index)
+	assert.Equal(t, "invalid", results[1].Name)
+	require.Error(t, results[1].Err)
+	causes := results[1].Causes()
+	require.Len(t, causes, 2)
+	assert.Contains(t, causes[0], "spec.repositories")
+	assert.Contains(t, causes[1], "spec.platforms")
+	assert.Contains(t, causes[1], "unsupported arch armz for OS linux")
+
+	assert.Equal(t, ManifestResult{Index: 2, Kind: "ScanJob", Namespace: "default", Name: "scanjob", Skipped: true}, results[2])
+}
+
+func TestValidateManifests_DecodeError(t *testing.T) {
+	_, err := ValidateManifests(strings.NewReader("kind: [Registry"))
+	require.ErrorContains(t, err, "cannot decode manifest 0")
+
+	results, err := ValidateManifests(strings.NewReader(`
+apiVersion: sbomscanner.kubewarden.io/v1alpha1
+kind: Registry
+metadata:
+  name: wrong-type
+spec:
+  uri: ghcr.io
+  repositories: ghcr.io/repo
+`))
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.ErrorContains(t, results[0].Err, "cannot decode Registry")
+}
@@ -1,44 +1,25 @@
 package v1alpha1
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"slices"
+	"strings"
+
+	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+	"github.com/kubewarden/sbomscanner/internal/platform"
 )
 
 // The current validator was developed based on the OCI image spec:
 // https://specs.opencontainers.org/image-spec/image-index/
 
-// validPlatforms maps OS to supported architectures
-var validPlatforms = map[string][]string{
-	"aix":       {"ppc64"},
-	"android":   {"386", "amd64", "arm", "arm64"},
-	"darwin":    {"amd64", "arm64"},
-	"dragonfly": {"amd64"},
-	"freebsd":   {"386", "amd64", "arm"},
-	"illumos":   {"amd64"},
-	"ios":       {"arm64"},
-	"js":        {"wasm"},
-	"linux":     {"386", "amd64", "arm", "arm64", "loong64", "mips", "mipsle", "mips64", "mips64le", "ppc64", "ppc64le", "riscv64", "s390x"},
-	"netbsd":    {"386", "amd64", "arm"},
-	"openbsd":   {"386", "amd64", "arm", "arm64"},
-	"plan9":     {"386", "amd64", "arm"},
-	"solaris":   {"amd64"},
-	"wasip1":    {"wasm"},
-	"windows":   {"386", "amd64", "arm", "arm64"},
-}
-
-// allowedVariants maps architecture to valid variants
-var allowedVariants = map[string][]string{
-	"arm":   {"v6", "v7", "v8"},
-	"arm64": {"v8"},
-}
-
 // validatePlatform checks if the platform is valid
 func validatePlatform(p v1alpha1.Platform) error {
 	// Check if OS is supported
-	arches, ok := validPlatforms[p.OS]
+	arches, ok := platform.ValidPlatforms[p.OS]
 	if !ok {
 		return fmt.Errorf("unsupported OS: %s", p.OS)
 	}
@@ -49,7 +30,7 @@ func validatePlatform(p v1alpha1.Platform) error {
 	}
 
 	// Check variant
-	variants, hasVariants := allowedVariants[p.Architecture]
+	variants, hasVariants := platform.AllowedVariants[p.Architecture]
 	if hasVariants {
 		// if the arch has a variant (but no variant is provided by the user),
 		// we consider it as a valid platform.
@@ -62,5 +43,149 @@ func validatePlatform(p v1alpha1.Platform) error {
 		return fmt.Errorf("arch %s does not support variants", p.Architecture)
 	}
 
+	if p.OSVersion != "" {
+		if err := platform.ValidateOSVersion(p.OSVersion); err != nil {
+			return fmt.Errorf("invalid OS version %s: %w", p.OSVersion, err)
+		}
+	}
+
+	if err := platform.ValidateOSFeatures(p.OS, p.OSFeatures); err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// ParsePlatform parses a platform in the <os>/<arch>[/<variant>][:<os version>] format,
+// returning an error when it is not accepted by the webhooks.
+func ParsePlatform(value string) (v1alpha1.Platform, error) {
+	value, osVersion := platform.SplitOSVersion(value)
+	parts := strings.Split(value, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return v1alpha1.Platform{}, fmt.Errorf("invalid platform %q, expected <os>/<arch>[/<variant>][:<os version>]", value)
+	}
+
+	p := v1alpha1.Platform{OS: parts[0], Architecture: parts[1], OSVersion: osVersion}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	if err := validatePlatform(p); err != nil {
+		return v1alpha1.Platform{}, fmt.Errorf("invalid platform %q: %w", value, err)
+	}
+
+	return p, nil
+}
+
+// isDeprecatedPlatform checks if the platform is one of the deprecated platforms.
+// A deprecated platform without a variant matches all the variants of its architecture,
+// a deprecated platform without an OS version matches all the versions of its OS,
+// and a deprecated platform without OS features matches all the features.
+func isDeprecatedPlatform(p v1alpha1.Platform, deprecatedPlatforms []v1alpha1.Platform) bool {
+	for _, deprecated := range deprecatedPlatforms {
+		if deprecated.OS != p.OS || deprecated.Architecture != p.Architecture ||
+			!platform.MatchOSVersion(p.OSVersion, deprecated.OSVersion) ||
+			!platform.MatchOSFeatures(p.OSFeatures, deprecated.OSFeatures) {
+			continue
+		}
+		// The platforms are normalized, so that the default variant of the architecture matches the platform without a variant.
+		if deprecated.Variant == "" || normalizeVariant(deprecated) == normalizeVariant(p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeVariant returns the normalized platform without its OS version, to compare the variants.
+func normalizeVariant(p v1alpha1.Platform) string {
+	p.OSVersion = ""
+	return platform.Normalize(p.String())
+}
+
+// duplicatePlatforms returns the indexes of the platforms that have the same
+// os/arch/variant tuple, OS version and OS features as a previous entry of the list.
+// The platforms are normalized, so that the default variant of the architecture matches the platform without a variant.
+func duplicatePlatforms(platforms []v1alpha1.Platform) []int {
+	var duplicates []int
+
+	seen := make(map[string]struct{}, len(platforms))
+	for i, p := range platforms {
+		key := platform.Normalize(p.String()) + "+" + strings.Join(platform.NormalizeOSFeatures(p.OSFeatures), "+")
+		if _, ok := seen[key]; ok {
+			duplicates = append(duplicates, i)
+			continue
+		}
+		seen[key] = struct{}{}
+	}
+
+	return duplicates
+}
+
+// SupportedPlatformsPath is the path of the webhook server endpoint listing the supported platforms.
+const SupportedPlatformsPath = "/platforms"
+
+// SupportedPlatform is an os/arch combination accepted by the webhooks.
+// The variant can be omitted, or set to one of the Variants.
+// When Variants is empty, the architecture does not support variants.
+// The OS features can be omitted, or set to some of the OSFeatures.
+type SupportedPlatform struct {
+	OS           string   `json:"os"`
+	Architecture string   `json:"arch"`
+	Variants     []string `json:"variants,omitempty"`
+	OSFeatures   []string `json:"osFeatures,omitempty"`
+}
+
+// SupportedPlatforms is the response of the supported platforms endpoint.
+type SupportedPlatforms struct {
+	Platforms []SupportedPlatform `json:"platforms"`
+}
+
+// supportedPlatforms returns the platforms accepted by validatePlatform, sorted by OS and architecture.
+func supportedPlatforms() SupportedPlatforms {
+	var platforms []SupportedPlatform
+	for osName, arches := range platform.ValidPlatforms {
+		for _, arch := range arches {
+			platforms = append(platforms, SupportedPlatform{
+				OS:           osName,
+				Architecture: arch,
+				Variants:     platform.AllowedVariants[arch],
+				OSFeatures:   platform.KnownOSFeatures[osName],
+			})
+		}
+	}
+
+	slices.SortFunc(platforms, func(a, b SupportedPlatform) int {
+		if a.OS != b.OS {
+			return strings.Compare(a.OS, b.OS)
+		}
+		return strings.Compare(a.Architecture, b.Architecture)
+	})
+
+	return SupportedPlatforms{Platforms: platforms}
+}
+
+// NewSupportedPlatformsHandler returns a read-only handler listing the platforms accepted by the webhooks,
+// so that clients can validate the platforms before submitting their resources.
+func NewSupportedPlatformsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := json.Marshal(supportedPlatforms())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to marshal the supported platforms: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	})
+}
+
+// SetupSupportedPlatformsEndpointWithManager registers the supported platforms endpoint on the webhook server.
+func SetupSupportedPlatformsEndpointWithManager(mgr ctrl.Manager) {
+	mgr.GetWebhookServer().Register(SupportedPlatformsPath, NewSupportedPlatformsHandler())
+}
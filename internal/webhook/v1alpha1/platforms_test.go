@@ -1,8 +1,14 @@
 package v1alpha1
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/kubewarden/sbomscanner/api/v1alpha1"
 )
 
@@ -46,6 +52,51 @@ func Test_validatePlatform(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "OS version",
+			p: v1alpha1.Platform{
+				Architecture: "amd64",
+				OS:           "windows",
+				OSVersion:    "10.0.17763",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid OS version",
+			p: v1alpha1.Platform{
+				Architecture: "amd64",
+				OS:           "windows",
+				OSVersion:    "ltsc2019",
+			},
+			wantErr: true,
+		},
+		{
+			name: "OS features",
+			p: v1alpha1.Platform{
+				Architecture: "amd64",
+				OS:           "windows",
+				OSFeatures:   []string{"win32k"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown OS feature",
+			p: v1alpha1.Platform{
+				Architecture: "amd64",
+				OS:           "windows",
+				OSFeatures:   []string{"sse4"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "OS feature of another OS",
+			p: v1alpha1.Platform{
+				Architecture: "amd64",
+				OS:           "linux",
+				OSFeatures:   []string{"win32k"},
+			},
+			wantErr: true,
+		},
 		{
 			// this test case highlight that if we provide
 			// linux/arm we consider it valid and let
@@ -74,3 +125,208 @@ func Test_validatePlatform(t *testing.T) {
 		})
 	}
 }
+
+func Test_duplicatePlatforms(t *testing.T) {
+	tests := []struct {
+		name      string
+		platforms []v1alpha1.Platform
+		want      []int
+	}{
+		{
+			name:      "no platforms",
+			platforms: nil,
+			want:      nil,
+		},
+		{
+			name: "no duplicates",
+			platforms: []v1alpha1.Platform{
+				{OS: "linux", Architecture: "amd64"},
+				{OS: "linux", Architecture: "arm", Variant: "v7"},
+				{OS: "linux", Architecture: "arm"},
+				{OS: "windows", Architecture: "amd64", OSVersion: "10.0.17763"},
+				{OS: "windows", Architecture: "amd64", OSVersion: "10.0.20348"},
+				{OS: "windows", Architecture: "amd64", OSVersion: "10.0.20348", OSFeatures: []string{"win32k"}},
+			},
+			want: nil,
+		},
+		{
+			name: "duplicates",
+			platforms: []v1alpha1.Platform{
+				{OS: "linux", Architecture: "amd64"},
+				{OS: "linux", Architecture: "arm64"},
+				{OS: "linux", Architecture: "amd64"},
+				{OS: "linux", Architecture: "amd64"},
+			},
+			want: []int{2, 3},
+		},
+		{
+			name: "duplicate OS features",
+			platforms: []v1alpha1.Platform{
+				{OS: "windows", Architecture: "amd64", OSFeatures: []string{"win32k"}},
+				{OS: "windows", Architecture: "amd64", OSFeatures: []string{"win32k", "win32k"}},
+			},
+			want: []int{1},
+		},
+		{
+			name: "duplicate default variant",
+			platforms: []v1alpha1.Platform{
+				{OS: "linux", Architecture: "arm64"},
+				{OS: "linux", Architecture: "arm64", Variant: "v8"},
+			},
+			want: []int{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, duplicatePlatforms(tt.platforms))
+		})
+	}
+}
+
+func TestSupportedPlatformsHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewSupportedPlatformsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, SupportedPlatformsPath, nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var response SupportedPlatforms
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.NotEmpty(t, response.Platforms)
+	assert.Contains(t, response.Platforms, SupportedPlatform{OS: "linux", Architecture: "amd64"})
+	assert.Contains(t, response.Platforms, SupportedPlatform{OS: "linux", Architecture: "arm", Variants: []string{"v6", "v7", "v8"}})
+	assert.Contains(t, response.Platforms, SupportedPlatform{OS: "windows", Architecture: "amd64", OSFeatures: []string{"win32k"}})
+
+	// Every platform listed must be accepted by the webhooks, with and without its variants.
+	for _, supported := range response.Platforms {
+		require.NoError(t, validatePlatform(v1alpha1.Platform{OS: supported.OS, Architecture: supported.Architecture}))
+		for _, variant := range supported.Variants {
+			require.NoError(t, validatePlatform(v1alpha1.Platform{OS: supported.OS, Architecture: supported.Architecture, Variant: variant}))
+		}
+		for _, osFeature := range supported.OSFeatures {
+			require.NoError(t, validatePlatform(v1alpha1.Platform{OS: supported.OS, Architecture: supported.Architecture, OSFeatures: []string{osFeature}}))
+		}
+		if len(supported.Variants) == 0 {
+			require.Error(t, validatePlatform(v1alpha1.Platform{OS: supported.OS, Architecture: supported.Architecture, Variant: "v1"}))
+		}
+	}
+}
+
+func TestSupportedPlatformsHandler_MethodNotAllowed(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewSupportedPlatformsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, SupportedPlatformsPath, nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	assert.Equal(t, "GET, HEAD", recorder.Header().Get("Allow"))
+}
+
+func TestParsePlatform(t *testing.T) {
+	tests := []struct {
+		value         string
+		expected      v1alpha1.Platform
+		expectedError string
+	}{
+		{
+			value:    "linux/amd64",
+			expected: v1alpha1.Platform{OS: "linux", Architecture: "amd64"},
+		},
+		{
+			value:    "linux/arm/v6",
+			expected: v1alpha1.Platform{OS: "linux", Architecture: "arm", Variant: "v6"},
+		},
+		{
+			value:    "windows/amd64:10.0.17763",
+			expected: v1alpha1.Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.17763"},
+		},
+		{
+			value:         "windows/amd64:ltsc2019",
+			expectedError: "invalid OS version ltsc2019",
+		},
+		{
+			value:         "linux",
+			expectedError: "expected <os>/<arch>[/<variant>]",
+		},
+		{
+			value:         "linux/arm/v6/extra",
+			expectedError: "expected <os>/<arch>[/<variant>]",
+		},
+		{
+			value:         "linux/armz",
+			expectedError: "unsupported arch armz for OS linux",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			platform, err := ParsePlatform(test.value)
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, platform)
+		})
+	}
+}
+
+func Test_isDeprecatedPlatform(t *testing.T) {
+	deprecatedPlatforms := []v1alpha1.Platform{
+		{OS: "linux", Architecture: "arm"},
+		{OS: "linux", Architecture: "arm64", Variant: "v8"},
+		{OS: "windows", Architecture: "arm", Variant: "v6"},
+		{OS: "windows", Architecture: "amd64", OSVersion: "10.0.14393"},
+	}
+
+	tests := []struct {
+		name     string
+		p        v1alpha1.Platform
+		expected bool
+	}{
+		{
+			name:     "architecture deprecated without a variant",
+			p:        v1alpha1.Platform{OS: "linux", Architecture: "arm"},
+			expected: true,
+		},
+		{
+			name:     "all the variants of the architecture deprecated",
+			p:        v1alpha1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"},
+			expected: true,
+		},
+		{
+			name:     "default variant deprecated",
+			p:        v1alpha1.Platform{OS: "linux", Architecture: "arm64"},
+			expected: true,
+		},
+		{
+			name:     "another variant",
+			p:        v1alpha1.Platform{OS: "windows", Architecture: "arm", Variant: "v7"},
+			expected: false,
+		},
+		{
+			name:     "OS version deprecated",
+			p:        v1alpha1.Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.14393.1066"},
+			expected: true,
+		},
+		{
+			name:     "OS version deprecated for all the OS features",
+			p:        v1alpha1.Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.14393", OSFeatures: []string{"win32k"}},
+			expected: true,
+		},
+		{
+			name:     "another OS version",
+			p:        v1alpha1.Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.17763"},
+			expected: false,
+		},
+		{
+			name:     "another OS",
+			p:        v1alpha1.Platform{OS: "freebsd", Architecture: "arm"},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, isDeprecatedPlatform(test.p, deprecatedPlatforms))
+		})
+	}
+}
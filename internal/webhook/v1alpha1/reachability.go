@@ -0,0 +1,92 @@
+package v1alpha1
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+const (
+	// ReachabilityCheckDisabled disables the registry reachability check.
+	ReachabilityCheckDisabled = "disabled"
+	// ReachabilityCheckWarn returns a warning when the registry is not reachable.
+	ReachabilityCheckWarn = "warn"
+	// ReachabilityCheckEnforce rejects the Registry when the registry is not reachable.
+	ReachabilityCheckEnforce = "enforce"
+)
+
+// ReachabilityCheckModes lists the allowed registry reachability check modes.
+var ReachabilityCheckModes = []string{ReachabilityCheckDisabled, ReachabilityCheckWarn, ReachabilityCheckEnforce}
+
+// reachabilityCheckTimeout bounds the registry ping, so that it completes within the admission webhook timeout.
+const reachabilityCheckTimeout = 5 * time.Second
+
+// pingRegistry checks that the registry answers to the /v2/ endpoint of the OCI distribution API.
+// The registry is reachable when it answers with 200 or 401, since the ping is not authenticated.
+func pingRegistry(ctx context.Context, registry *v1alpha1.Registry) error {
+	reg, err := name.NewRegistry(registry.Spec.URI)
+	if err != nil {
+		return fmt.Errorf("cannot parse registry URI: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, reachabilityCheckTimeout)
+	defer cancel()
+
+	url := fmt.Sprintf("%s://%s/v2/", reg.Scheme(), reg.RegistryStr())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("cannot create request: %w", err)
+	}
+
+	tlsConfig, err := pingTLSConfig(registry)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot reach %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnauthorized {
+		return fmt.Errorf("%s answered with status %d, it does not look like an OCI registry", url, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// pingTLSConfig returns the TLS configuration used to ping the registry,
+// honoring the insecure and CA bundle options of the Registry.
+func pingTLSConfig(registry *v1alpha1.Registry) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: registry.Spec.Insecure, //nolint:gosec // this a user provided option
+	}
+
+	if len(registry.Spec.CABundle) > 0 {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM([]byte(registry.Spec.CABundle)) {
+			return nil, errors.New("cannot load the CA bundle")
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	return tlsConfig, nil
+}
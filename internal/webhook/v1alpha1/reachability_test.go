@@ -0,0 +1,95 @@
+package v1alpha1
+
+import (
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+func TestPingRegistry(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		expectedError string
+	}{
+		{
+			name:   "anonymous registry",
+			status: http.StatusOK,
+		},
+		{
+			name:   "authenticated registry",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:          "not a registry",
+			status:        http.StatusNotFound,
+			expectedError: "it does not look like an OCI registry",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v2/", r.URL.Path)
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+
+			registry := &v1alpha1.Registry{
+				Spec: v1alpha1.RegistrySpec{
+					URI: strings.TrimPrefix(server.URL, "http://"),
+				},
+			}
+
+			err := pingRegistry(t.Context(), registry)
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPingRegistry_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	registry := &v1alpha1.Registry{
+		Spec: v1alpha1.RegistrySpec{
+			URI: address,
+		},
+	}
+
+	err = pingRegistry(t.Context(), registry)
+	require.ErrorContains(t, err, "cannot reach")
+}
+
+func TestPingTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	tlsConfig, err := pingTLSConfig(&v1alpha1.Registry{
+		Spec: v1alpha1.RegistrySpec{CABundle: string(caBundle)},
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, tlsConfig.RootCAs)
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+
+	_, err = pingTLSConfig(&v1alpha1.Registry{
+		Spec: v1alpha1.RegistrySpec{CABundle: "invalid"},
+	})
+	require.ErrorContains(t, err, "cannot load the CA bundle")
+}
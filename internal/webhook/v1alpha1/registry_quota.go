@@ -0,0 +1,70 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+// RegistryQuota limits the number of Registries per namespace.
+// A limit of 0 is unlimited.
+type RegistryQuota struct {
+	// MaxPerNamespace is the limit of the namespaces without an override.
+	MaxPerNamespace int
+	// Overrides maps the namespaces to their own limit.
+	Overrides map[string]int
+}
+
+// Limit returns the maximum number of Registries of the namespace, 0 when unlimited.
+func (q RegistryQuota) Limit(namespace string) int {
+	if limit, ok := q.Overrides[namespace]; ok {
+		return limit
+	}
+
+	return q.MaxPerNamespace
+}
+
+// ParseRegistryQuotaOverride parses a per-namespace limit in the <namespace>=<limit> format.
+func ParseRegistryQuotaOverride(value string) (string, int, error) {
+	namespace, limitValue, ok := strings.Cut(value, "=")
+	if !ok || namespace == "" {
+		return "", 0, fmt.Errorf("invalid registry quota override %q, expected <namespace>=<limit>", value)
+	}
+	limit, err := strconv.Atoi(limitValue)
+	if err != nil || limit < 0 {
+		return "", 0, fmt.Errorf("invalid limit of the registry quota override %q, must be a non-negative integer", value)
+	}
+
+	return namespace, limit, nil
+}
+
+// checkQuota rejects the creation of the Registry with 403 Forbidden
+// when its namespace already has the maximum number of Registries.
+func (v *RegistryCustomValidator) checkQuota(ctx context.Context, registry *v1alpha1.Registry) error {
+	limit := v.quota.Limit(registry.Namespace)
+	if limit == 0 {
+		return nil
+	}
+
+	registries := &v1alpha1.RegistryList{}
+	if err := v.client.List(ctx, registries, client.InNamespace(registry.Namespace)); err != nil {
+		return apierrors.NewInternalError(fmt.Errorf("failed to list the registries of namespace %s: %w", registry.Namespace, err))
+	}
+
+	if len(registries.Items) >= limit {
+		v.logger.Info("Registry quota exceeded", "name", registry.Name, "namespace", registry.Namespace, "limit", limit)
+		return apierrors.NewForbidden(
+			v1alpha1.GroupVersion.WithResource("registries").GroupResource(),
+			registry.Name,
+			fmt.Errorf("the namespace %s has reached its limit of %d Registries", registry.Namespace, limit),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+func newQuotaTestRegistry(name, namespace string) *v1alpha1.Registry {
+	return &v1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: v1alpha1.RegistrySpec{
+			URI:         "registry.test.local",
+			CatalogType: v1alpha1.CatalogTypeOCIDistribution,
+		},
+	}
+}
+
+func TestRegistryCustomValidator_Quota(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	client := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			newQuotaTestRegistry("registry-1", "team-a"),
+			newQuotaTestRegistry("registry-2", "team-a"),
+			newQuotaTestRegistry("registry-1", "team-b"),
+			newQuotaTestRegistry("registry-2", "team-b"),
+		).
+		Build()
+
+	validator := &RegistryCustomValidator{
+		client: client,
+		quota: RegistryQuota{
+			MaxPerNamespace: 2,
+			Overrides:       map[string]int{"team-b": 0, "team-c": 1},
+		},
+		logger: logr.Discard(),
+	}
+
+	tests := []struct {
+		name          string
+		namespace     string
+		expectedError string
+	}{
+		{
+			name:          "namespace at the global limit",
+			namespace:     "team-a",
+			expectedError: "the namespace team-a has reached its limit of 2 Registries",
+		},
+		{
+			name:      "namespace with an unlimited override",
+			namespace: "team-b",
+		},
+		{
+			name:      "namespace below its override",
+			namespace: "team-c",
+		},
+		{
+			name:      "empty namespace",
+			namespace: "team-d",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := validator.ValidateCreate(t.Context(), newQuotaTestRegistry("new-registry", test.namespace))
+
+			if test.expectedError != "" {
+				require.Error(t, err)
+				assert.True(t, apierrors.IsForbidden(err))
+				assert.Contains(t, err.Error(), test.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	// The updates of the existing registries are not limited.
+	registry := newQuotaTestRegistry("registry-1", "team-a")
+	_, err := validator.ValidateUpdate(t.Context(), registry.DeepCopy(), registry)
+	require.NoError(t, err)
+}
+
+func TestParseRegistryQuotaOverride(t *testing.T) {
+	namespace, limit, err := ParseRegistryQuotaOverride("team-a=10")
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", namespace)
+	assert.Equal(t, 10, limit)
+
+	for _, value := range []string{"team-a", "=10", "team-a=ten", "team-a=-1"} {
+		_, _, err = ParseRegistryQuotaOverride(value)
+		require.Error(t, err, value)
+	}
+}
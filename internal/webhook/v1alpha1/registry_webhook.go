@@ -5,18 +5,22 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+	"github.com/kubewarden/sbomscanner/internal/scanwindow"
 )
 
 const (
@@ -25,11 +29,26 @@ const (
 
 var availableCatalogTypes = []string{v1alpha1.CatalogTypeNoCatalog, v1alpha1.CatalogTypeOCIDistribution}
 
+var availableRevocationChecks = []string{v1alpha1.RevocationCheckWarn, v1alpha1.RevocationCheckEnforce}
+
 // SetupRegistryWebhookWithManager registers the webhook for Registry in the manager.
-func SetupRegistryWebhookWithManager(mgr ctrl.Manager) error {
+// reachabilityCheck is one of the ReachabilityCheckModes.
+// The Registries using one of the deprecatedPlatforms are accepted with a warning.
+// The creation of a Registry is rejected when its namespace has reached the limit of the quota.
+func SetupRegistryWebhookWithManager(
+	mgr ctrl.Manager,
+	reachabilityCheck string,
+	deprecatedPlatforms []v1alpha1.Platform,
+	quota RegistryQuota,
+) error {
 	err := ctrl.NewWebhookManagedBy(mgr).For(&v1alpha1.Registry{}).
 		WithValidator(&RegistryCustomValidator{
-			logger: mgr.GetLogger().WithName("registry_validator"),
+			client:              mgr.GetClient(),
+			reachabilityCheck:   reachabilityCheck,
+			deprecatedPlatforms: deprecatedPlatforms,
+			quota:               quota,
+			ping:                pingRegistry,
+			logger:              mgr.GetLogger().WithName("registry_validator"),
 		}).
 		WithDefaulter(&RegistryCustomDefaulter{
 			logger: mgr.GetLogger().WithName("registry_defaulter"),
@@ -68,13 +87,21 @@ func (d *RegistryCustomDefaulter) Default(_ context.Context, obj runtime.Object)
 // +kubebuilder:webhook:path=/validate-sbomscanner-kubewarden-io-v1alpha1-registry,mutating=false,failurePolicy=fail,sideEffects=None,groups=sbomscanner.kubewarden.io,resources=registries,verbs=create;update,versions=v1alpha1,name=vregistry.sbomscanner.kubewarden.io,admissionReviewVersions=v1
 
 type RegistryCustomValidator struct {
+	client client.Client
+	// reachabilityCheck is the mode of the registry reachability check, disabled when empty.
+	reachabilityCheck string
+	// deprecatedPlatforms are the platforms accepted with a warning, to guide the users to migrate.
+	deprecatedPlatforms []v1alpha1.Platform
+	// quota limits the number of Registries per namespace, unlimited when it is the zero value.
+	quota  RegistryQuota
+	ping   func(ctx context.Context, registry *v1alpha1.Registry) error
 	logger logr.Logger
 }
 
 var _ webhook.CustomValidator = &RegistryCustomValidator{}
 
 // ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Registry.
-func (v *RegistryCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+func (v *RegistryCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
 	registry, ok := obj.(*v1alpha1.Registry)
 	if !ok {
 		return nil, fmt.Errorf("expected a Registry object but got %T", obj)
@@ -82,6 +109,17 @@ func (v *RegistryCustomValidator) ValidateCreate(_ context.Context, obj runtime.
 	v.logger.Info("Validation for Registry upon creation", "name", registry.GetName())
 
 	allErrs := validateRegistry(registry)
+	if len(allErrs) == 0 {
+		if err := v.checkQuota(ctx, registry); err != nil {
+			return nil, err
+		}
+	}
+
+	var warnings admission.Warnings
+	if len(allErrs) == 0 {
+		warnings, allErrs = v.checkReachability(ctx, registry)
+	}
+	warnings = append(warnings, v.deprecatedPlatformWarnings(registry)...)
 
 	if len(allErrs) > 0 {
 		return nil, apierrors.NewInvalid(
@@ -91,19 +129,31 @@ func (v *RegistryCustomValidator) ValidateCreate(_ context.Context, obj runtime.
 		)
 	}
 
-	return nil, nil
+	return warnings, nil
 }
 
 // ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Registry.
-func (v *RegistryCustomValidator) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+func (v *RegistryCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
 	registry, ok := newObj.(*v1alpha1.Registry)
 	if !ok {
 		return nil, fmt.Errorf("expected a Registry object for the newObj but got %T", newObj)
 	}
+	oldRegistry, ok := oldObj.(*v1alpha1.Registry)
+	if !ok {
+		return nil, fmt.Errorf("expected a Registry object for the oldObj but got %T", oldObj)
+	}
 	v.logger.Info("Validation for Registry upon update", "name", registry.GetName())
 
 	allErrs := validateRegistry(registry)
 
+	// The registry is only pinged again when its URI changes,
+	// so that unrelated updates do not depend on the network.
+	var warnings admission.Warnings
+	if len(allErrs) == 0 && registry.Spec.URI != oldRegistry.Spec.URI {
+		warnings, allErrs = v.checkReachability(ctx, registry)
+	}
+	warnings = append(warnings, v.deprecatedPlatformWarnings(registry)...)
+
 	if len(allErrs) > 0 {
 		return nil, apierrors.NewInvalid(
 			v1alpha1.GroupVersion.WithKind("Registry").GroupKind(),
@@ -112,7 +162,7 @@ func (v *RegistryCustomValidator) ValidateUpdate(_ context.Context, _, newObj ru
 		)
 	}
 
-	return nil, nil
+	return warnings, nil
 }
 
 // ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Registry.
@@ -126,6 +176,45 @@ func (v *RegistryCustomValidator) ValidateDelete(_ context.Context, obj runtime.
 	return nil, nil
 }
 
+// checkReachability pings the registry according to the reachability check mode.
+// An unreachable registry results in a warning, or in an error when the check is enforced.
+func (v *RegistryCustomValidator) checkReachability(
+	ctx context.Context,
+	registry *v1alpha1.Registry,
+) (admission.Warnings, field.ErrorList) {
+	if v.reachabilityCheck == "" || v.reachabilityCheck == ReachabilityCheckDisabled {
+		return nil, nil
+	}
+
+	err := v.ping(ctx, registry)
+	if err == nil {
+		return nil, nil
+	}
+	v.logger.Info("Registry is not reachable", "name", registry.GetName(), "uri", registry.Spec.URI, "error", err.Error())
+
+	if v.reachabilityCheck == ReachabilityCheckEnforce {
+		fieldPath := field.NewPath("spec").Child("uri")
+		return nil, field.ErrorList{field.Invalid(fieldPath, registry.Spec.URI, "registry is not reachable: "+err.Error())}
+	}
+
+	return admission.Warnings{
+		fmt.Sprintf("registry %s is not reachable, it will not be scanned until it is: %s", registry.Spec.URI, err),
+	}, nil
+}
+
+// deprecatedPlatformWarnings returns a warning for each platform of the registry which is deprecated.
+func (v *RegistryCustomValidator) deprecatedPlatformWarnings(registry *v1alpha1.Registry) admission.Warnings {
+	var warnings admission.Warnings
+	for i, platform := range registry.Spec.Platforms {
+		if isDeprecatedPlatform(platform, v.deprecatedPlatforms) {
+			fieldPath := field.NewPath("spec").Child("platforms").Index(i)
+			warnings = append(warnings, fmt.Sprintf("%s: platform %s is deprecated, please migrate the images to another platform", fieldPath, platform.String()))
+		}
+	}
+
+	return warnings
+}
+
 func validateScanInterval(registry *v1alpha1.Registry) error {
 	if registry.Spec.ScanInterval == nil {
 		return nil
@@ -137,6 +226,31 @@ func validateScanInterval(registry *v1alpha1.Registry) error {
 	return nil
 }
 
+func validateScanTimeout(registry *v1alpha1.Registry) error {
+	if registry.Spec.ScanTimeout == nil {
+		return nil
+	}
+	if registry.Spec.ScanTimeout.Duration <= 0 {
+		return errors.New("scanTimeout must be greater than 0")
+	}
+
+	return nil
+}
+
+func validateRetention(registry *v1alpha1.Registry) error {
+	if registry.Spec.Retention == nil {
+		return nil
+	}
+	if registry.Spec.Retention.KeepLast < 0 {
+		return errors.New("keepLast must not be negative")
+	}
+	if registry.Spec.Retention.MaxAge != nil && registry.Spec.Retention.MaxAge.Duration <= 0 {
+		return errors.New("maxAge must be greater than 0")
+	}
+
+	return nil
+}
+
 func validateCatalogType(registry *v1alpha1.Registry) error {
 	// If the catalog type is empty, the Defaulter will set it to the default catalog type.
 	if registry.Spec.CatalogType == "" {
@@ -149,6 +263,20 @@ func validateCatalogType(registry *v1alpha1.Registry) error {
 	return nil
 }
 
+func validateRevocationCheck(registry *v1alpha1.Registry) error {
+	if registry.Spec.RevocationCheck == "" {
+		return nil
+	}
+	if !slices.Contains(availableRevocationChecks, registry.Spec.RevocationCheck) {
+		return fmt.Errorf("%s is not a valid RevocationCheck", registry.Spec.RevocationCheck)
+	}
+	if registry.Spec.Insecure {
+		return errors.New("revocationCheck cannot be enabled when insecure is true")
+	}
+
+	return nil
+}
+
 func validateRepositories(registry *v1alpha1.Registry) error {
 	if registry.Spec.CatalogType == v1alpha1.CatalogTypeNoCatalog && len(registry.Spec.Repositories) == 0 {
 		return errors.New("repositories must be explicitly provided when catalogType is NoCatalog")
@@ -168,6 +296,20 @@ func validatePlatforms(registry *v1alpha1.Registry) error {
 	return nil
 }
 
+func validateDefaultPlatform(registry *v1alpha1.Registry) error {
+	if registry.Spec.DefaultPlatform == nil {
+		return nil
+	}
+	if len(registry.Spec.Platforms) > 0 {
+		return errors.New("defaultPlatform cannot be set together with platforms")
+	}
+	if err := validatePlatform(*registry.Spec.DefaultPlatform); err != nil {
+		return fmt.Errorf("%s is not an allowed platform: %w", registry.Spec.DefaultPlatform.String(), err)
+	}
+
+	return nil
+}
+
 func validateRegistry(registry *v1alpha1.Registry) field.ErrorList {
 	var allErrs field.ErrorList
 
@@ -176,11 +318,33 @@ func validateRegistry(registry *v1alpha1.Registry) field.ErrorList {
 		allErrs = append(allErrs, field.Invalid(fieldPath, registry.Spec.ScanInterval, err.Error()))
 	}
 
+	if err := validateScanTimeout(registry); err != nil {
+		fieldPath := field.NewPath("spec").Child("scanTimeout")
+		allErrs = append(allErrs, field.Invalid(fieldPath, registry.Spec.ScanTimeout, err.Error()))
+	}
+
+	for i, scanWindow := range registry.Spec.ScanWindows {
+		if err := scanwindow.Validate(scanWindow); err != nil {
+			fieldPath := field.NewPath("spec").Child("scanWindows").Index(i)
+			allErrs = append(allErrs, field.Invalid(fieldPath, scanWindow, err.Error()))
+		}
+	}
+
+	if err := validateRetention(registry); err != nil {
+		fieldPath := field.NewPath("spec").Child("retention")
+		allErrs = append(allErrs, field.Invalid(fieldPath, registry.Spec.Retention, err.Error()))
+	}
+
 	if err := validateCatalogType(registry); err != nil {
 		fieldPath := field.NewPath("spec").Child("catalogType")
 		allErrs = append(allErrs, field.Invalid(fieldPath, registry.Spec.CatalogType, err.Error()))
 	}
 
+	if err := validateRevocationCheck(registry); err != nil {
+		fieldPath := field.NewPath("spec").Child("revocationCheck")
+		allErrs = append(allErrs, field.Invalid(fieldPath, registry.Spec.RevocationCheck, err.Error()))
+	}
+
 	if err := validateRepositories(registry); err != nil {
 		fieldPath := field.NewPath("spec").Child("repositories")
 		allErrs = append(allErrs, field.Invalid(fieldPath, registry.Spec.Repositories, err.Error()))
@@ -189,6 +353,26 @@ func validateRegistry(registry *v1alpha1.Registry) field.ErrorList {
 		filepath := field.NewPath("spec").Child("platforms")
 		allErrs = append(allErrs, field.Invalid(filepath, registry.Spec.Platforms, err.Error()))
 	}
+	if err := validateDefaultPlatform(registry); err != nil {
+		fieldPath := field.NewPath("spec").Child("defaultPlatform")
+		allErrs = append(allErrs, field.Invalid(fieldPath, registry.Spec.DefaultPlatform, err.Error()))
+	}
+	if err := validateSBOMScope(registry.Spec.SBOMScope); err != nil {
+		fieldPath := field.NewPath("spec").Child("sbomScope")
+		allErrs = append(allErrs, field.Invalid(fieldPath, registry.Spec.SBOMScope, err.Error()))
+	}
+	if registry.Spec.AuthSecret != "" {
+		// The Secret is read from the namespace of the Registry: a reference to another namespace, e.g. "namespace/name", is rejected.
+		if errs := validation.IsDNS1123Subdomain(registry.Spec.AuthSecret); len(errs) > 0 {
+			fieldPath := field.NewPath("spec").Child("authSecret")
+			allErrs = append(allErrs, field.Invalid(fieldPath, registry.Spec.AuthSecret,
+				"must be the name of a Secret in the namespace of the Registry: "+strings.Join(errs, ", ")))
+		}
+	}
+	for _, i := range duplicatePlatforms(registry.Spec.Platforms) {
+		fieldPath := field.NewPath("spec").Child("platforms").Index(i)
+		allErrs = append(allErrs, field.Duplicate(fieldPath, registry.Spec.Platforms[i].String()))
+	}
 
 	return allErrs
 }
@@ -1,6 +1,8 @@
 package v1alpha1
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -9,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	"github.com/kubewarden/sbomscanner/api/v1alpha1"
 )
@@ -102,6 +105,217 @@ var registryTestCases = []registryTestCase{
 		expectedField: "spec.scanInterval",
 		expectedError: "scanInterval must be at least 1 minute",
 	},
+	{
+		name: "should allow creation when scanTimeout is valid",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI:         "registry.test.local",
+				ScanTimeout: &metav1.Duration{Duration: 10 * time.Minute},
+			},
+		},
+	},
+	{
+		name: "should deny creation when scanTimeout is not greater than 0",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI:         "registry.test.local",
+				ScanTimeout: &metav1.Duration{Duration: 0},
+			},
+		},
+		expectedField: "spec.scanTimeout",
+		expectedError: "scanTimeout must be greater than 0",
+	},
+	{
+		name: "should allow creation when the scan windows are valid",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI: "registry.test.local",
+				ScanWindows: []v1alpha1.ScanWindow{
+					{Start: "00:00", End: "06:00"},
+					{Start: "22:00", End: "04:00", Days: []string{"Saturday", "Sunday"}, TimeZone: "Europe/Berlin"},
+				},
+			},
+		},
+	},
+	{
+		name: "should deny creation when a scan window has an unknown time zone",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI: "registry.test.local",
+				ScanWindows: []v1alpha1.ScanWindow{
+					{Start: "00:00", End: "06:00"},
+					{Start: "00:00", End: "06:00", TimeZone: "Europe/Atlantis"},
+				},
+			},
+		},
+		expectedField: "spec.scanWindows[1]",
+		expectedError: `timeZone "Europe/Atlantis" is not a known IANA time zone`,
+	},
+	{
+		name: "should deny creation when a scan window start is not a time of day",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI:         "registry.test.local",
+				ScanWindows: []v1alpha1.ScanWindow{{Start: "6pm", End: "23:00"}},
+			},
+		},
+		expectedField: "spec.scanWindows[0]",
+		expectedError: `start "6pm" must be a time of day in the HH:MM format`,
+	},
+	{
+		name: "should allow creation when the default platform is valid",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI:             "registry.test.local",
+				DefaultPlatform: &v1alpha1.Platform{OS: "linux", Architecture: "arm64"},
+			},
+		},
+	},
+	{
+		name: "should deny creation when the default platform is not allowed",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI:             "registry.test.local",
+				DefaultPlatform: &v1alpha1.Platform{OS: "linux", Architecture: "armz"},
+			},
+		},
+		expectedField: "spec.defaultPlatform",
+		expectedError: "linux/armz is not an allowed platform",
+	},
+	{
+		name: "should deny creation when the default platform is set together with platforms",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI:             "registry.test.local",
+				Platforms:       []v1alpha1.Platform{{OS: "linux", Architecture: "amd64"}},
+				DefaultPlatform: &v1alpha1.Platform{OS: "linux", Architecture: "arm64"},
+			},
+		},
+		expectedField: "spec.defaultPlatform",
+		expectedError: "defaultPlatform cannot be set together with platforms",
+	},
+	{
+		name: "should allow creation when retention is valid",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI: "registry.test.local",
+				Retention: &v1alpha1.RetentionPolicy{
+					KeepLast: 3,
+					MaxAge:   &metav1.Duration{Duration: 30 * 24 * time.Hour},
+				},
+			},
+		},
+	},
+	{
+		name: "should deny creation when retention keepLast is negative",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI:       "registry.test.local",
+				Retention: &v1alpha1.RetentionPolicy{KeepLast: -1},
+			},
+		},
+		expectedField: "spec.retention",
+		expectedError: "keepLast must not be negative",
+	},
+	{
+		name: "should deny creation when retention maxAge is not greater than 0",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI:       "registry.test.local",
+				Retention: &v1alpha1.RetentionPolicy{MaxAge: &metav1.Duration{Duration: 0}},
+			},
+		},
+		expectedField: "spec.retention",
+		expectedError: "maxAge must be greater than 0",
+	},
+	{
+		name: "should allow creation when revocationCheck is valid",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI:             "registry.test.local",
+				RevocationCheck: v1alpha1.RevocationCheckEnforce,
+			},
+		},
+	},
+	{
+		name: "should deny creation when revocationCheck is not valid",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI:             "registry.test.local",
+				RevocationCheck: "Strict",
+			},
+		},
+		expectedField: "spec.revocationCheck",
+		expectedError: "Strict is not a valid RevocationCheck",
+	},
+	{
+		name: "should deny creation when revocationCheck is enabled on an insecure registry",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI:             "registry.test.local",
+				Insecure:        true,
+				RevocationCheck: v1alpha1.RevocationCheckWarn,
+			},
+		},
+		expectedField: "spec.revocationCheck",
+		expectedError: "revocationCheck cannot be enabled when insecure is true",
+	},
 	{
 		name: "should allow creation when catalogType is NoCatalog and Repositories are provided",
 		registry: &v1alpha1.Registry{
@@ -201,6 +415,82 @@ var registryTestCases = []registryTestCase{
 		expectedField: "spec.platforms",
 		expectedError: "is not an allowed platform",
 	},
+	{
+		name: "should deny creation when platforms are duplicated",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI: "registry.test.local",
+				Platforms: []v1alpha1.Platform{
+					{
+						Architecture: "arm",
+						OS:           "linux",
+						Variant:      "v7",
+					},
+					{
+						Architecture: "amd64",
+						OS:           "linux",
+					},
+					{
+						Architecture: "arm",
+						OS:           "linux",
+						Variant:      "v7",
+					},
+				},
+			},
+		},
+		expectedField: "spec.platforms[2]",
+		expectedError: "Duplicate value: \"linux/arm/v7\"",
+	},
+	{
+		name: "should admit creation when authSecret is the name of a Secret",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI:        "registry.test.local",
+				AuthSecret: "registry-credentials",
+			},
+		},
+	},
+	{
+		name: "should deny creation when authSecret references another namespace",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI:        "registry.test.local",
+				AuthSecret: "other-namespace/registry-credentials",
+			},
+		},
+		expectedField: "spec.authSecret",
+		expectedError: "must be the name of a Secret in the namespace of the Registry",
+	},
+	{
+		name: "should deny creation when sbomScope is not valid",
+		registry: &v1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-registry",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RegistrySpec{
+				URI: "registry.test.local",
+				SBOMScope: &v1alpha1.SBOMScope{
+					Packages:   v1alpha1.SBOMScopePackagesOS,
+					Ecosystems: []string{"npm"},
+				},
+			},
+		},
+		expectedField: "spec.sbomScope",
+		expectedError: "ecosystems cannot be set when packages is os",
+	},
 }
 
 func TestRegistryCustomValidator_ValidateCreate(t *testing.T) {
@@ -255,3 +545,130 @@ func TestRegistryCustomValidator_ValidateUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestRegistryCustomValidator_ReachabilityCheck(t *testing.T) {
+	errUnreachable := errors.New("connection refused")
+
+	tests := []struct {
+		name              string
+		reachabilityCheck string
+		pingErr           error
+		oldURI            string
+		expectedWarnings  int
+		expectedError     string
+		expectedPings     int
+	}{
+		{
+			name:              "disabled",
+			reachabilityCheck: ReachabilityCheckDisabled,
+			pingErr:           errUnreachable,
+			expectedPings:     0,
+		},
+		{
+			name:              "reachable",
+			reachabilityCheck: ReachabilityCheckEnforce,
+			expectedPings:     1,
+		},
+		{
+			name:              "unreachable with warn",
+			reachabilityCheck: ReachabilityCheckWarn,
+			pingErr:           errUnreachable,
+			expectedWarnings:  1,
+			expectedPings:     1,
+		},
+		{
+			name:              "unreachable with enforce",
+			reachabilityCheck: ReachabilityCheckEnforce,
+			pingErr:           errUnreachable,
+			expectedError:     "registry is not reachable: connection refused",
+			expectedPings:     1,
+		},
+		{
+			name:              "update without URI change",
+			reachabilityCheck: ReachabilityCheckEnforce,
+			pingErr:           errUnreachable,
+			oldURI:            "registry.test.local",
+			expectedPings:     0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pings := 0
+			validator := &RegistryCustomValidator{
+				reachabilityCheck: test.reachabilityCheck,
+				ping: func(_ context.Context, _ *v1alpha1.Registry) error {
+					pings++
+					return test.pingErr
+				},
+				logger: logr.Discard(),
+			}
+			registry := &v1alpha1.Registry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-registry",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RegistrySpec{
+					URI:         "registry.test.local",
+					CatalogType: v1alpha1.CatalogTypeOCIDistribution,
+				},
+			}
+
+			var warnings admission.Warnings
+			var err error
+			if test.oldURI != "" {
+				oldRegistry := registry.DeepCopy()
+				oldRegistry.Spec.URI = test.oldURI
+				warnings, err = validator.ValidateUpdate(t.Context(), oldRegistry, registry)
+			} else {
+				warnings, err = validator.ValidateCreate(t.Context(), registry)
+			}
+
+			if test.expectedError != "" {
+				require.Error(t, err)
+				statusErr, ok := err.(interface{ Status() metav1.Status })
+				require.True(t, ok)
+				details := statusErr.Status().Details
+				require.NotNil(t, details)
+				require.Len(t, details.Causes, 1)
+				assert.Equal(t, "spec.uri", details.Causes[0].Field)
+				assert.Contains(t, details.Causes[0].Message, test.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Len(t, warnings, test.expectedWarnings)
+			assert.Equal(t, test.expectedPings, pings)
+		})
+	}
+}
+
+func TestRegistryCustomValidator_DeprecatedPlatforms(t *testing.T) {
+	validator := &RegistryCustomValidator{
+		deprecatedPlatforms: []v1alpha1.Platform{{OS: "linux", Architecture: "arm"}},
+		logger:              logr.Discard(),
+	}
+	registry := &v1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-registry",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.RegistrySpec{
+			URI:         "registry.test.local",
+			CatalogType: v1alpha1.CatalogTypeOCIDistribution,
+			Platforms: []v1alpha1.Platform{
+				{OS: "linux", Architecture: "amd64"},
+				{OS: "linux", Architecture: "arm", Variant: "v7"},
+			},
+		},
+	}
+
+	warnings, err := validator.ValidateCreate(t.Context(), registry)
+	require.NoError(t, err)
+	assert.Equal(t, admission.Warnings{
+		"spec.platforms[1]: platform linux/arm/v7 is deprecated, please migrate the images to another platform",
+	}, warnings)
+
+	warnings, err = validator.ValidateUpdate(t.Context(), registry.DeepCopy(), registry)
+	require.NoError(t, err)
+	assert.Len(t, warnings, 1)
+}
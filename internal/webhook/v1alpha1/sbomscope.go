@@ -0,0 +1,37 @@
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+var availableSBOMScopePackages = []string{v1alpha1.SBOMScopePackagesAll, v1alpha1.SBOMScopePackagesOS}
+
+// ecosystemPattern matches the Trivy package types, e.g. "node-pkg" or "gobinary".
+var ecosystemPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// validateSBOMScope checks that the packages kind is known and that the ecosystems are well-formed.
+// The ecosystems are not checked against the package types supported by Trivy,
+// since the controller does not depend on it.
+func validateSBOMScope(scope *v1alpha1.SBOMScope) error {
+	if scope == nil {
+		return nil
+	}
+	if scope.Packages != "" && !slices.Contains(availableSBOMScopePackages, scope.Packages) {
+		return fmt.Errorf("%s is not a valid packages kind, must be one of %v", scope.Packages, availableSBOMScopePackages)
+	}
+	if scope.Packages == v1alpha1.SBOMScopePackagesOS && len(scope.Ecosystems) > 0 {
+		return errors.New("ecosystems cannot be set when packages is os")
+	}
+	for _, ecosystem := range scope.Ecosystems {
+		if !ecosystemPattern.MatchString(ecosystem) {
+			return fmt.Errorf("%q is not a valid ecosystem", ecosystem)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kubewarden/sbomscanner/api/v1alpha1"
+)
+
+func Test_validateSBOMScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   *v1alpha1.SBOMScope
+		wantErr string
+	}{
+		{
+			name:  "no scope",
+			scope: nil,
+		},
+		{
+			name:  "OS packages only",
+			scope: &v1alpha1.SBOMScope{Packages: v1alpha1.SBOMScopePackagesOS},
+		},
+		{
+			name:  "ecosystems",
+			scope: &v1alpha1.SBOMScope{Packages: v1alpha1.SBOMScopePackagesAll, Ecosystems: []string{"npm", "node-pkg", "gobinary"}},
+		},
+		{
+			name:    "unknown packages kind",
+			scope:   &v1alpha1.SBOMScope{Packages: "language"},
+			wantErr: "is not a valid packages kind",
+		},
+		{
+			name:    "ecosystems with OS packages only",
+			scope:   &v1alpha1.SBOMScope{Packages: v1alpha1.SBOMScopePackagesOS, Ecosystems: []string{"npm"}},
+			wantErr: "ecosystems cannot be set when packages is os",
+		},
+		{
+			name:    "malformed ecosystem",
+			scope:   &v1alpha1.SBOMScope{Ecosystems: []string{"Node Pkg"}},
+			wantErr: "is not a valid ecosystem",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSBOMScope(tt.scope)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
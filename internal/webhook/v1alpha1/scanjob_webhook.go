@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
@@ -80,6 +81,16 @@ func (v *ScanJobCustomValidator) ValidateCreate(ctx context.Context, obj runtime
 
 	var allErrs field.ErrorList
 
+	if err := validateSBOMScope(scanJob.Spec.SBOMScope); err != nil {
+		fieldPath := field.NewPath("spec").Child("sbomScope")
+		allErrs = append(allErrs, field.Invalid(fieldPath, scanJob.Spec.SBOMScope, err.Error()))
+	}
+	if scanJob.Spec.Rescan && scanJob.Spec.SBOMScope != nil {
+		fieldPath := field.NewPath("spec").Child("sbomScope")
+		allErrs = append(allErrs, field.Forbidden(fieldPath, "the SBOM scope cannot be set when rescan is true"))
+	}
+	allErrs = append(allErrs, validateRegistryAnnotation(scanJob)...)
+
 	scanJobList := &v1alpha1.ScanJobList{}
 
 	if err := v.client.List(ctx, scanJobList,
@@ -124,6 +135,15 @@ func (v *ScanJobCustomValidator) ValidateUpdate(_ context.Context, oldObj, newOb
 		fieldPath := field.NewPath("spec").Child("registry")
 		allErrs = append(allErrs, field.Invalid(fieldPath, newJob.Spec.Registry, "field is immutable"))
 	}
+	if oldJob.Spec.Rescan != newJob.Spec.Rescan {
+		fieldPath := field.NewPath("spec").Child("rescan")
+		allErrs = append(allErrs, field.Invalid(fieldPath, newJob.Spec.Rescan, "field is immutable"))
+	}
+	if !equality.Semantic.DeepEqual(oldJob.Spec.SBOMScope, newJob.Spec.SBOMScope) {
+		fieldPath := field.NewPath("spec").Child("sbomScope")
+		allErrs = append(allErrs, field.Invalid(fieldPath, newJob.Spec.SBOMScope, "field is immutable"))
+	}
+	allErrs = append(allErrs, validateRegistryAnnotation(newJob)...)
 
 	if len(allErrs) > 0 {
 		return nil, apierrors.NewInvalid(
@@ -135,6 +155,21 @@ func (v *ScanJobCustomValidator) ValidateUpdate(_ context.Context, oldObj, newOb
 	return nil, nil
 }
 
+// validateRegistryAnnotation checks that the Registry snapshot stored in the annotation, when set, is in the namespace of the ScanJob.
+// The annotation is set by the controller, but it could be set by a user to make the workers use
+// the credentials of a Registry of another namespace.
+func validateRegistryAnnotation(scanJob *v1alpha1.ScanJob) field.ErrorList {
+	if _, ok := scanJob.Annotations[v1alpha1.AnnotationScanJobRegistryKey]; !ok {
+		return nil
+	}
+	if _, err := scanJob.GetRegistryFromAnnotation(); err != nil {
+		fieldPath := field.NewPath("metadata").Child("annotations").Key(v1alpha1.AnnotationScanJobRegistryKey)
+		return field.ErrorList{field.Forbidden(fieldPath, err.Error())}
+	}
+
+	return nil
+}
+
 // ValidateDelete validates the object on deletion.
 func (v *ScanJobCustomValidator) ValidateDelete(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
 	scanJob, ok := obj.(*v1alpha1.ScanJob)
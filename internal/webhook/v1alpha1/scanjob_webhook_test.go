@@ -167,6 +167,57 @@ func TestScanJobCustomValidator_ValidateCreate(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:            "should deny creation when the registry annotation is in another namespace",
+			existingScanJob: nil,
+			scanJob: &v1alpha1.ScanJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-scan-job",
+					Namespace: "default",
+					Annotations: map[string]string{
+						v1alpha1.AnnotationScanJobRegistryKey: `{"metadata":{"name":"registry","namespace":"other-namespace"},"spec":{"uri":"registry.example.com","authSecret":"registry-credentials"}}`,
+					},
+				},
+				Spec: v1alpha1.ScanJobSpec{
+					Registry: "registry.example.com",
+				},
+			},
+			expectedField: "metadata.annotations[sbomscanner.kubewarden.io/registry]",
+			expectedError: "is not in the namespace default of the ScanJob",
+		},
+		{
+			name:            "should deny creation when sbomScope is set with rescan",
+			existingScanJob: nil,
+			scanJob: &v1alpha1.ScanJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-scan-job",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ScanJobSpec{
+					Registry:  "registry.example.com",
+					Rescan:    true,
+					SBOMScope: &v1alpha1.SBOMScope{Packages: v1alpha1.SBOMScopePackagesOS},
+				},
+			},
+			expectedField: "spec.sbomScope",
+			expectedError: "cannot be set when rescan is true",
+		},
+		{
+			name:            "should deny creation when sbomScope is not valid",
+			existingScanJob: nil,
+			scanJob: &v1alpha1.ScanJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-scan-job",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ScanJobSpec{
+					Registry:  "registry.example.com",
+					SBOMScope: &v1alpha1.SBOMScope{Packages: "language"},
+				},
+			},
+			expectedField: "spec.sbomScope",
+			expectedError: "is not a valid packages kind",
+		},
 	}
 
 	for _, test := range tests {
@@ -250,3 +301,65 @@ func TestScanJobCustomValidator_ValidateUpdate(t *testing.T) {
 
 	assert.Empty(t, warnings)
 }
+
+func TestScanJobCustomValidator_ValidateUpdateRescan(t *testing.T) {
+	oldObj := &v1alpha1.ScanJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scan-job",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ScanJobSpec{
+			Registry: "registry.example.com",
+		},
+	}
+
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Rescan = true
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	client := fake.NewClientBuilder().WithScheme(scheme).Build()
+	validator := ScanJobCustomValidator{client: client}
+
+	_, err := validator.ValidateUpdate(t.Context(), oldObj, newObj)
+
+	require.Error(t, err)
+	statusErr, ok := err.(interface{ Status() metav1.Status })
+	require.True(t, ok)
+	details := statusErr.Status().Details
+	require.NotNil(t, details)
+	require.Len(t, details.Causes, 1)
+	assert.Equal(t, "spec.rescan", details.Causes[0].Field)
+	assert.Contains(t, details.Causes[0].Message, "immutable")
+}
+
+func TestScanJobCustomValidator_ValidateUpdateSBOMScope(t *testing.T) {
+	oldObj := &v1alpha1.ScanJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scan-job",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ScanJobSpec{
+			Registry: "registry.example.com",
+		},
+	}
+
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.SBOMScope = &v1alpha1.SBOMScope{Packages: v1alpha1.SBOMScopePackagesOS}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	client := fake.NewClientBuilder().WithScheme(scheme).Build()
+	validator := ScanJobCustomValidator{client: client}
+
+	_, err := validator.ValidateUpdate(t.Context(), oldObj, newObj)
+
+	require.Error(t, err)
+	statusErr, ok := err.(interface{ Status() metav1.Status })
+	require.True(t, ok)
+	details := statusErr.Status().Details
+	require.NotNil(t, details)
+	require.Len(t, details.Causes, 1)
+	assert.Equal(t, "spec.sbomScope", details.Causes[0].Field)
+	assert.Contains(t, details.Causes[0].Message, "immutable")
+}
@@ -0,0 +1,403 @@
+package writebuffer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultMaxEntries is the default maximum number of pending writes.
+	DefaultMaxEntries = 100
+	// DefaultBatchSize is the default maximum number of writes sent to the storage concurrently.
+	DefaultBatchSize = 10
+	// DefaultRate is the default maximum number of writes sent to the storage per second.
+	DefaultRate = 10
+	// DefaultMaxAttempts is the default maximum number of attempts of a write, about 25 minutes of retries
+	// when the storage is unavailable.
+	DefaultMaxAttempts = 30
+
+	// entryExtension is the extension of the files of the entries. The files are named after their sequence number,
+	// so that their names sort in the order of the writes.
+	entryExtension = ".json"
+	// retryDelay is the delay before retrying a batch whose writes all failed, doubled after each failure.
+	retryDelay    = time.Second
+	retryMaxDelay = time.Minute
+)
+
+// Options configures the write buffer.
+type Options struct {
+	// Dir is the directory where the pending writes are persisted. The writes are not buffered when empty.
+	Dir string
+	// MaxEntries is the maximum number of pending writes. Enqueue blocks while the buffer is full.
+	MaxEntries int
+	// BatchSize is the maximum number of writes sent to the storage concurrently.
+	BatchSize int
+	// Rate is the maximum number of writes sent to the storage per second. 0 means no limit.
+	Rate float64
+	// MaxAttempts is the maximum number of attempts of a write, which is dropped once they all failed. 0 means no limit.
+	MaxAttempts int
+}
+
+// NewOptions returns the default Options, with the buffering disabled.
+func NewOptions() Options {
+	return Options{
+		MaxEntries:  DefaultMaxEntries,
+		BatchSize:   DefaultBatchSize,
+		Rate:        DefaultRate,
+		MaxAttempts: DefaultMaxAttempts,
+	}
+}
+
+// Enabled returns true if the writes must be buffered.
+func (o Options) Enabled() bool {
+	return o.Dir != ""
+}
+
+// Validate returns an error if the options are not valid.
+func (o Options) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	if o.MaxEntries <= 0 {
+		return errors.New("write buffer max entries must be greater than zero")
+	}
+	if o.BatchSize <= 0 {
+		return errors.New("write buffer batch size must be greater than zero")
+	}
+	if o.Rate < 0 {
+		return errors.New("write buffer rate must not be negative")
+	}
+	if o.MaxAttempts < 0 {
+		return errors.New("write buffer max attempts must not be negative")
+	}
+
+	return nil
+}
+
+// WriteFunc writes the data of an entry to the storage.
+// The entries whose write fails are retried, unless the error is a PermanentError.
+type WriteFunc func(ctx context.Context, data []byte) error
+
+// Writers maps the kinds of the entries to their WriteFunc.
+type Writers map[string]WriteFunc
+
+// PermanentError is returned by a WriteFunc when retrying the write would fail again, e.g. the object is invalid.
+// The entry is then dropped.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// entry is the content of the file of a pending write.
+type entry struct {
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Buffer persists the pending writes to a directory, and writes them to the storage in rate-limited batches.
+type Buffer struct {
+	options Options
+	limiter *rate.Limiter
+	logger  *slog.Logger
+
+	mu sync.Mutex
+	// files are the names of the files of the pending writes, the oldest first.
+	files []string
+	// next is the sequence number of the next entry.
+	next uint64
+	// attempts are the numbers of failed attempts of the pending writes, only used by Run.
+	attempts map[string]int
+	// changed is closed and replaced whenever the pending writes change, waking up the waiting callers.
+	changed chan struct{}
+}
+
+// New returns a Buffer persisting its entries to the directory of the options,
+// loading the entries left by the previous run, so that they are written by Run.
+func New(options Options, logger *slog.Logger) (*Buffer, error) {
+	if err := os.MkdirAll(options.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create the write buffer directory: %w", err)
+	}
+
+	dirEntries, err := os.ReadDir(options.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the write buffer directory: %w", err)
+	}
+
+	buffer := &Buffer{
+		options:  options,
+		limiter:  rate.NewLimiter(rate.Inf, 0),
+		logger:   logger.With("component", "write_buffer"),
+		changed:  make(chan struct{}),
+		attempts: map[string]int{},
+	}
+	if options.Rate > 0 {
+		buffer.limiter = rate.NewLimiter(rate.Limit(options.Rate), max(1, int(options.Rate)))
+	}
+
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		sequence, ok := parseEntryName(name)
+		if !ok || !dirEntry.Type().IsRegular() {
+			// The temporary files of the interrupted enqueues are removed.
+			if strings.HasPrefix(name, ".") {
+				_ = os.Remove(filepath.Join(options.Dir, name))
+			}
+			continue
+		}
+		buffer.files = append(buffer.files, name)
+		buffer.next = max(buffer.next, sequence+1)
+	}
+	// The names are zero-padded, so that they sort in the order of the sequence numbers.
+	slices.Sort(buffer.files)
+	if len(buffer.files) > 0 {
+		buffer.logger.Info("Pending writes loaded", "count", len(buffer.files))
+	}
+
+	return buffer, nil
+}
+
+// Len returns the number of pending writes.
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.files)
+}
+
+// Enqueue persists the write of the data, encoded as JSON, to be written by the WriteFunc of the kind.
+// It blocks while the buffer is full, so that the scans slow down to the pace of the writes,
+// until the context is done.
+func (b *Buffer) Enqueue(ctx context.Context, kind string, data any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the %s write: %w", kind, err)
+	}
+	content, err := json.Marshal(entry{Kind: kind, Data: encoded})
+	if err != nil {
+		return fmt.Errorf("failed to marshal the %s write: %w", kind, err)
+	}
+
+	logged := false
+	for {
+		b.mu.Lock()
+		if len(b.files) < b.options.MaxEntries {
+			defer b.mu.Unlock()
+			return b.persistLocked(kind, content)
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		if !logged {
+			b.logger.InfoContext(ctx, "Write buffer full, waiting for the pending writes", "pending", b.options.MaxEntries)
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("write buffer full: %w", ctx.Err())
+		case <-changed:
+		}
+	}
+}
+
+// persistLocked writes the content to the file of the next entry, then adds it to the pending writes.
+// The file is written to a temporary file first, so that an interrupted write does not leave a truncated entry.
+func (b *Buffer) persistLocked(kind string, content []byte) error {
+	name := fmt.Sprintf("%020d%s", b.next, entryExtension)
+	tmp, err := os.CreateTemp(b.options.Dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to persist the %s write: %w", kind, err)
+	}
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(b.options.Dir, name))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to persist the %s write: %w", kind, err)
+	}
+
+	b.next++
+	b.files = append(b.files, name)
+	b.notifyLocked()
+
+	return nil
+}
+
+// notifyLocked wakes up the callers waiting for the pending writes to change.
+func (b *Buffer) notifyLocked() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// Run writes the pending writes with the writers, the oldest first, until the context is done.
+// Up to the batch size writes are sent concurrently, at the rate of the options, so they are not written in order.
+// The failed writes are retried after the other pending writes, with a backoff when no write of a batch succeeded,
+// and the writes left when the context is done are written by the next run.
+func (b *Buffer) Run(ctx context.Context, writers Writers) {
+	delay := retryDelay
+	for {
+		b.mu.Lock()
+		batch := slices.Clone(b.files[:min(len(b.files), b.options.BatchSize)])
+		changed := b.changed
+		b.mu.Unlock()
+
+		if len(batch) == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+				continue
+			}
+		}
+
+		if done, failed := b.writeBatch(ctx, writers, batch); failed == 0 || done > 0 {
+			delay = retryDelay
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, retryMaxDelay)
+	}
+}
+
+// writeBatch writes the entries concurrently and removes the written ones. The failed entries are moved after the other
+// pending writes, so that they do not hold them back, and dropped once they failed MaxAttempts times.
+// It returns the number of removed and failed writes.
+func (b *Buffer) writeBatch(ctx context.Context, writers Writers, batch []string) (int, int) {
+	var (
+		mu     sync.Mutex
+		done   []string
+		retry  []string
+		failed int
+	)
+	group := errgroup.Group{}
+	for _, name := range batch {
+		group.Go(func() error {
+			if err := b.limiter.Wait(ctx); err != nil {
+				mu.Lock()
+				failed++
+				mu.Unlock()
+				return nil
+			}
+
+			err := b.write(ctx, writers, name)
+			mu.Lock()
+			defer mu.Unlock()
+			var permanentErr *PermanentError
+			switch {
+			case err == nil:
+				done = append(done, name)
+			case errors.As(err, &permanentErr):
+				b.logger.ErrorContext(ctx, "Dropping the write, retrying would fail again", "entry", name, "error", err)
+				done = append(done, name)
+			case ctx.Err() != nil:
+				// The write was interrupted by the shutdown, it is not counted as an attempt.
+				failed++
+			default:
+				b.logger.WarnContext(ctx, "Write failed, retrying", "entry", name, "error", err)
+				retry = append(retry, name)
+				failed++
+			}
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	retry = slices.DeleteFunc(retry, func(name string) bool {
+		b.attempts[name]++
+		if b.options.MaxAttempts == 0 || b.attempts[name] < b.options.MaxAttempts {
+			return false
+		}
+		b.logger.ErrorContext(ctx, "Dropping the write, all its attempts failed", "entry", name, "attempts", b.attempts[name])
+		done = append(done, name)
+		return true
+	})
+
+	for _, name := range done {
+		delete(b.attempts, name)
+		if err := os.Remove(filepath.Join(b.options.Dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			b.logger.WarnContext(ctx, "Failed to remove the written entry", "entry", name, "error", err)
+		}
+	}
+
+	b.mu.Lock()
+	b.files = slices.DeleteFunc(b.files, func(name string) bool {
+		return slices.Contains(done, name) || slices.Contains(retry, name)
+	})
+	b.files = append(b.files, retry...)
+	pending := len(b.files)
+	if len(done) > 0 {
+		b.notifyLocked()
+	}
+	b.mu.Unlock()
+
+	if len(done) > 0 {
+		b.logger.DebugContext(ctx, "Write batch flushed", "written", len(done), "failed", failed, "pending", pending)
+	}
+
+	return len(done), failed
+}
+
+// write reads the entry and writes it with the WriteFunc of its kind.
+func (b *Buffer) write(ctx context.Context, writers Writers, name string) error {
+	content, err := os.ReadFile(filepath.Join(b.options.Dir, name))
+	if err != nil {
+		return &PermanentError{Err: fmt.Errorf("failed to read the entry: %w", err)}
+	}
+
+	var pending entry
+	if err := json.Unmarshal(content, &pending); err != nil {
+		return &PermanentError{Err: fmt.Errorf("failed to unmarshal the entry: %w", err)}
+	}
+
+	writer, found := writers[pending.Kind]
+	if !found {
+		return &PermanentError{Err: fmt.Errorf("no writer for the %s entries", pending.Kind)}
+	}
+
+	return writer(ctx, pending.Data)
+}
+
+// parseEntryName returns the sequence number of the file of an entry.
+func parseEntryName(name string) (uint64, bool) {
+	sequence, found := strings.CutSuffix(name, entryExtension)
+	if !found {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(sequence, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return value, true
+}
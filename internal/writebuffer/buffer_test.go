@@ -0,0 +1,288 @@
+package writebuffer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder records the data written by the WriteFunc it returns.
+type recorder struct {
+	mu      sync.Mutex
+	written []string
+	err     func(data string) error
+}
+
+func (r *recorder) write(_ context.Context, data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if r.err != nil {
+		if err := r.err(value); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.written = append(r.written, value)
+
+	return nil
+}
+
+func (r *recorder) values() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.written...)
+}
+
+func testOptions(t *testing.T) Options {
+	t.Helper()
+
+	options := NewOptions()
+	options.Dir = filepath.Join(t.TempDir(), "write-buffer")
+	options.BatchSize = 1
+	options.Rate = 0
+
+	return options
+}
+
+// run runs the buffer until the pending writes are written.
+func run(t *testing.T, buffer *Buffer, writers Writers) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		buffer.Run(ctx, writers)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	require.Eventually(t, func() bool {
+		return buffer.Len() == 0
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options func(*Options)
+		wantErr string
+	}{
+		{
+			name:    "disabled",
+			options: func(o *Options) { o.Dir = ""; o.MaxEntries = 0 },
+		},
+		{
+			name:    "valid",
+			options: func(o *Options) {},
+		},
+		{
+			name:    "no max entries",
+			options: func(o *Options) { o.MaxEntries = 0 },
+			wantErr: "write buffer max entries must be greater than zero",
+		},
+		{
+			name:    "no batch size",
+			options: func(o *Options) { o.BatchSize = 0 },
+			wantErr: "write buffer batch size must be greater than zero",
+		},
+		{
+			name:    "negative rate",
+			options: func(o *Options) { o.Rate = -1 },
+			wantErr: "write buffer rate must not be negative",
+		},
+		{
+			name:    "negative max attempts",
+			options: func(o *Options) { o.MaxAttempts = -1 },
+			wantErr: "write buffer max attempts must not be negative",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := NewOptions()
+			options.Dir = "/var/lib/worker/write-buffer"
+			test.options(&options)
+
+			err := options.Validate()
+			if test.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, test.wantErr)
+		})
+	}
+}
+
+func TestBufferRun(t *testing.T) {
+	buffer, err := New(testOptions(t), slog.Default())
+	require.NoError(t, err)
+
+	sboms := &recorder{}
+	reports := &recorder{}
+	for _, write := range []struct{ kind, value string }{
+		{"sbom", "sbom-1"},
+		{"report", "report-1"},
+		{"sbom", "sbom-2"},
+	} {
+		require.NoError(t, buffer.Enqueue(t.Context(), write.kind, write.value))
+	}
+	assert.Equal(t, 3, buffer.Len())
+
+	run(t, buffer, Writers{"sbom": sboms.write, "report": reports.write})
+
+	assert.Equal(t, []string{"sbom-1", "sbom-2"}, sboms.values())
+	assert.Equal(t, []string{"report-1"}, reports.values())
+	entries, err := os.ReadDir(buffer.options.Dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestNewLoadsPendingWrites(t *testing.T) {
+	options := testOptions(t)
+	buffer, err := New(options, slog.Default())
+	require.NoError(t, err)
+	for _, value := range []string{"sbom-1", "sbom-2", "sbom-3"} {
+		require.NoError(t, buffer.Enqueue(t.Context(), "sbom", value))
+	}
+	// An enqueue interrupted before its rename leaves a temporary file.
+	require.NoError(t, os.WriteFile(filepath.Join(options.Dir, ".entry-123"), []byte(`{"kind":`), 0o600))
+
+	restarted, err := New(options, slog.Default())
+	require.NoError(t, err)
+	assert.Equal(t, 3, restarted.Len())
+	assert.NoFileExists(t, filepath.Join(options.Dir, ".entry-123"))
+
+	require.NoError(t, restarted.Enqueue(t.Context(), "sbom", "sbom-4"))
+	sboms := &recorder{}
+	run(t, restarted, Writers{"sbom": sboms.write})
+
+	assert.Equal(t, []string{"sbom-1", "sbom-2", "sbom-3", "sbom-4"}, sboms.values())
+}
+
+func TestBufferEnqueueWaitsWhenFull(t *testing.T) {
+	options := testOptions(t)
+	options.MaxEntries = 2
+	buffer, err := New(options, slog.Default())
+	require.NoError(t, err)
+	require.NoError(t, buffer.Enqueue(t.Context(), "sbom", "sbom-1"))
+	require.NoError(t, buffer.Enqueue(t.Context(), "sbom", "sbom-2"))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	err = buffer.Enqueue(ctx, "sbom", "sbom-3")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 2, buffer.Len())
+
+	// The waiting enqueue proceeds once the pending writes are written.
+	enqueued := make(chan error, 1)
+	go func() {
+		enqueued <- buffer.Enqueue(t.Context(), "sbom", "sbom-3")
+	}()
+	sboms := &recorder{}
+	runCtx, stop := context.WithCancel(t.Context())
+	defer stop()
+	go buffer.Run(runCtx, Writers{"sbom": sboms.write})
+
+	select {
+	case err := <-enqueued:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the enqueue did not proceed once the pending writes were written")
+	}
+	require.Eventually(t, func() bool {
+		return len(sboms.values()) == 3
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"sbom-1", "sbom-2", "sbom-3"}, sboms.values())
+}
+
+func TestBufferRunRetriesFailedWrites(t *testing.T) {
+	buffer, err := New(testOptions(t), slog.Default())
+	require.NoError(t, err)
+	require.NoError(t, buffer.Enqueue(t.Context(), "sbom", "invalid"))
+	require.NoError(t, buffer.Enqueue(t.Context(), "sbom", "flaky"))
+	require.NoError(t, buffer.Enqueue(t.Context(), "unknown", "dropped"))
+
+	attempts := 0
+	sboms := &recorder{err: func(value string) error {
+		switch value {
+		case "invalid":
+			return &PermanentError{Err: errors.New("invalid SBOM")}
+		case "flaky":
+			attempts++
+			if attempts == 1 {
+				return errors.New("storage unavailable")
+			}
+		}
+		return nil
+	}}
+	run(t, buffer, Writers{"sbom": sboms.write})
+
+	assert.Equal(t, []string{"flaky"}, sboms.values())
+	assert.Equal(t, 2, attempts)
+}
+
+func TestBufferRunRetriesFailedWritesAfterTheOthers(t *testing.T) {
+	buffer, err := New(testOptions(t), slog.Default())
+	require.NoError(t, err)
+	for _, value := range []string{"flaky", "sbom-1", "sbom-2"} {
+		require.NoError(t, buffer.Enqueue(t.Context(), "sbom", value))
+	}
+
+	attempts := 0
+	sboms := &recorder{err: func(value string) error {
+		if value == "flaky" {
+			attempts++
+			if attempts < 3 {
+				return errors.New("storage unavailable")
+			}
+		}
+		return nil
+	}}
+	run(t, buffer, Writers{"sbom": sboms.write})
+
+	// The failing write does not hold back the writes enqueued after it.
+	assert.Equal(t, []string{"sbom-1", "sbom-2", "flaky"}, sboms.values())
+	assert.Equal(t, 3, attempts)
+}
+
+func TestBufferRunDropsWritesAfterMaxAttempts(t *testing.T) {
+	options := testOptions(t)
+	options.MaxAttempts = 2
+	buffer, err := New(options, slog.Default())
+	require.NoError(t, err)
+	require.NoError(t, buffer.Enqueue(t.Context(), "sbom", "failing"))
+	require.NoError(t, buffer.Enqueue(t.Context(), "sbom", "sbom-1"))
+
+	attempts := 0
+	sboms := &recorder{err: func(value string) error {
+		if value == "failing" {
+			attempts++
+			return errors.New("forbidden")
+		}
+		return nil
+	}}
+	run(t, buffer, Writers{"sbom": sboms.write})
+
+	assert.Equal(t, []string{"sbom-1"}, sboms.values())
+	assert.Equal(t, 2, attempts)
+	entries, err := os.ReadDir(options.Dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
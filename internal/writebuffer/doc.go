@@ -0,0 +1,4 @@
+// Package writebuffer decouples the scans of the worker from the writes of their results to the storage:
+// the completed writes are persisted to a local directory and replayed in rate-limited batches,
+// so that the bursts of scans do not overload the storage and the pending writes survive the restarts.
+package writebuffer
@@ -0,0 +1,45 @@
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EvaluationApplyConfiguration represents a declarative configuration of the Evaluation type for use
+// with apply.
+type EvaluationApplyConfiguration struct {
+	Reevaluation          *bool    `json:"reevaluation,omitempty"`
+	SBOMUID               *string  `json:"sbomUID,omitempty"`
+	SBOMCreationTimestamp *v1.Time `json:"sbomCreationTimestamp,omitempty"`
+}
+
+// EvaluationApplyConfiguration constructs a declarative configuration of the Evaluation type for use with
+// apply.
+func Evaluation() *EvaluationApplyConfiguration {
+	return &EvaluationApplyConfiguration{}
+}
+
+// WithReevaluation sets the Reevaluation field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Reevaluation field is set to the value of the last call.
+func (b *EvaluationApplyConfiguration) WithReevaluation(value bool) *EvaluationApplyConfiguration {
+	b.Reevaluation = &value
+	return b
+}
+
+// WithSBOMUID sets the SBOMUID field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the SBOMUID field is set to the value of the last call.
+func (b *EvaluationApplyConfiguration) WithSBOMUID(value string) *EvaluationApplyConfiguration {
+	b.SBOMUID = &value
+	return b
+}
+
+// WithSBOMCreationTimestamp sets the SBOMCreationTimestamp field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the SBOMCreationTimestamp field is set to the value of the last call.
+func (b *EvaluationApplyConfiguration) WithSBOMCreationTimestamp(value v1.Time) *EvaluationApplyConfiguration {
+	b.SBOMCreationTimestamp = &value
+	return b
+}
@@ -15,6 +15,7 @@ type ImageApplyConfiguration struct {
 	*v1.ObjectMetaApplyConfiguration `json:"metadata,omitempty"`
 	*ImageMetadataApplyConfiguration `json:"imageMetadata,omitempty"`
 	Layers                           []ImageLayerApplyConfiguration `json:"layers,omitempty"`
+	Status                           *ImageStatusApplyConfiguration `json:"status,omitempty"`
 }
 
 // Image constructs a declarative configuration of the Image type for use with
@@ -241,6 +242,26 @@ func (b *ImageApplyConfiguration) WithDigest(value string) *ImageApplyConfigurat
 	return b
 }
 
+// WithOSVersion sets the OSVersion field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the OSVersion field is set to the value of the last call.
+func (b *ImageApplyConfiguration) WithOSVersion(value string) *ImageApplyConfiguration {
+	b.ensureImageMetadataApplyConfigurationExists()
+	b.ImageMetadataApplyConfiguration.OSVersion = &value
+	return b
+}
+
+// WithOSFeatures adds the given value to the OSFeatures field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the OSFeatures field.
+func (b *ImageApplyConfiguration) WithOSFeatures(values ...string) *ImageApplyConfiguration {
+	b.ensureImageMetadataApplyConfigurationExists()
+	for i := range values {
+		b.ImageMetadataApplyConfiguration.OSFeatures = append(b.ImageMetadataApplyConfiguration.OSFeatures, values[i])
+	}
+	return b
+}
+
 func (b *ImageApplyConfiguration) ensureImageMetadataApplyConfigurationExists() {
 	if b.ImageMetadataApplyConfiguration == nil {
 		b.ImageMetadataApplyConfiguration = &ImageMetadataApplyConfiguration{}
@@ -260,6 +281,14 @@ func (b *ImageApplyConfiguration) WithLayers(values ...*ImageLayerApplyConfigura
 	return b
 }
 
+// WithStatus sets the Status field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Status field is set to the value of the last call.
+func (b *ImageApplyConfiguration) WithStatus(value *ImageStatusApplyConfiguration) *ImageApplyConfiguration {
+	b.Status = value
+	return b
+}
+
 // GetKind retrieves the value of the Kind field in the declarative configuration.
 func (b *ImageApplyConfiguration) GetKind() *string {
 	return b.TypeMetaApplyConfiguration.Kind
@@ -5,12 +5,14 @@ package v1alpha1
 // ImageMetadataApplyConfiguration represents a declarative configuration of the ImageMetadata type for use
 // with apply.
 type ImageMetadataApplyConfiguration struct {
-	Registry    *string `json:"registry,omitempty"`
-	RegistryURI *string `json:"registryURI,omitempty"`
-	Repository  *string `json:"repository,omitempty"`
-	Tag         *string `json:"tag,omitempty"`
-	Platform    *string `json:"platform,omitempty"`
-	Digest      *string `json:"digest,omitempty"`
+	Registry    *string  `json:"registry,omitempty"`
+	RegistryURI *string  `json:"registryURI,omitempty"`
+	Repository  *string  `json:"repository,omitempty"`
+	Tag         *string  `json:"tag,omitempty"`
+	Platform    *string  `json:"platform,omitempty"`
+	Digest      *string  `json:"digest,omitempty"`
+	OSVersion   *string  `json:"osVersion,omitempty"`
+	OSFeatures  []string `json:"osFeatures,omitempty"`
 }
 
 // ImageMetadataApplyConfiguration constructs a declarative configuration of the ImageMetadata type for use with
@@ -66,3 +68,21 @@ func (b *ImageMetadataApplyConfiguration) WithDigest(value string) *ImageMetadat
 	b.Digest = &value
 	return b
 }
+
+// WithOSVersion sets the OSVersion field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the OSVersion field is set to the value of the last call.
+func (b *ImageMetadataApplyConfiguration) WithOSVersion(value string) *ImageMetadataApplyConfiguration {
+	b.OSVersion = &value
+	return b
+}
+
+// WithOSFeatures adds the given value to the OSFeatures field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the OSFeatures field.
+func (b *ImageMetadataApplyConfiguration) WithOSFeatures(values ...string) *ImageMetadataApplyConfiguration {
+	for i := range values {
+		b.OSFeatures = append(b.OSFeatures, values[i])
+	}
+	return b
+}
@@ -0,0 +1,46 @@
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImageScanErrorApplyConfiguration represents a declarative configuration of the ImageScanError type for use
+// with apply.
+type ImageScanErrorApplyConfiguration struct {
+	Reason     *storagev1alpha1.ImageScanErrorReason `json:"reason,omitempty"`
+	Message    *string                               `json:"message,omitempty"`
+	OccurredAt *v1.Time                              `json:"occurredAt,omitempty"`
+}
+
+// ImageScanErrorApplyConfiguration constructs a declarative configuration of the ImageScanError type for use with
+// apply.
+func ImageScanError() *ImageScanErrorApplyConfiguration {
+	return &ImageScanErrorApplyConfiguration{}
+}
+
+// WithReason sets the Reason field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Reason field is set to the value of the last call.
+func (b *ImageScanErrorApplyConfiguration) WithReason(value storagev1alpha1.ImageScanErrorReason) *ImageScanErrorApplyConfiguration {
+	b.Reason = &value
+	return b
+}
+
+// WithMessage sets the Message field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Message field is set to the value of the last call.
+func (b *ImageScanErrorApplyConfiguration) WithMessage(value string) *ImageScanErrorApplyConfiguration {
+	b.Message = &value
+	return b
+}
+
+// WithOccurredAt sets the OccurredAt field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the OccurredAt field is set to the value of the last call.
+func (b *ImageScanErrorApplyConfiguration) WithOccurredAt(value v1.Time) *ImageScanErrorApplyConfiguration {
+	b.OccurredAt = &value
+	return b
+}
@@ -0,0 +1,36 @@
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImageStatusApplyConfiguration represents a declarative configuration of the ImageStatus type for use
+// with apply.
+type ImageStatusApplyConfiguration struct {
+	LastScannedAt *v1.Time                          `json:"lastScannedAt,omitempty"`
+	LastError     *ImageScanErrorApplyConfiguration `json:"lastError,omitempty"`
+}
+
+// ImageStatusApplyConfiguration constructs a declarative configuration of the ImageStatus type for use with
+// apply.
+func ImageStatus() *ImageStatusApplyConfiguration {
+	return &ImageStatusApplyConfiguration{}
+}
+
+// WithLastScannedAt sets the LastScannedAt field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the LastScannedAt field is set to the value of the last call.
+func (b *ImageStatusApplyConfiguration) WithLastScannedAt(value v1.Time) *ImageStatusApplyConfiguration {
+	b.LastScannedAt = &value
+	return b
+}
+
+// WithLastError sets the LastError field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the LastError field is set to the value of the last call.
+func (b *ImageStatusApplyConfiguration) WithLastError(value *ImageScanErrorApplyConfiguration) *ImageStatusApplyConfiguration {
+	b.LastError = value
+	return b
+}
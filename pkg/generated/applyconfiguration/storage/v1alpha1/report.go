@@ -5,8 +5,10 @@ package v1alpha1
 // ReportApplyConfiguration represents a declarative configuration of the Report type for use
 // with apply.
 type ReportApplyConfiguration struct {
-	Summary *SummaryApplyConfiguration `json:"summary,omitempty"`
-	Results []ResultApplyConfiguration `json:"results,omitempty"`
+	Summary         *SummaryApplyConfiguration         `json:"summary,omitempty"`
+	Results         []ResultApplyConfiguration         `json:"results,omitempty"`
+	VulnerabilityDB *VulnerabilityDBApplyConfiguration `json:"vulnerabilityDB,omitempty"`
+	Evaluation      *EvaluationApplyConfiguration      `json:"evaluation,omitempty"`
 }
 
 // ReportApplyConfiguration constructs a declarative configuration of the Report type for use with
@@ -35,3 +37,19 @@ func (b *ReportApplyConfiguration) WithResults(values ...*ResultApplyConfigurati
 	}
 	return b
 }
+
+// WithVulnerabilityDB sets the VulnerabilityDB field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the VulnerabilityDB field is set to the value of the last call.
+func (b *ReportApplyConfiguration) WithVulnerabilityDB(value *VulnerabilityDBApplyConfiguration) *ReportApplyConfiguration {
+	b.VulnerabilityDB = value
+	return b
+}
+
+// WithEvaluation sets the Evaluation field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Evaluation field is set to the value of the last call.
+func (b *ReportApplyConfiguration) WithEvaluation(value *EvaluationApplyConfiguration) *ReportApplyConfiguration {
+	b.Evaluation = value
+	return b
+}
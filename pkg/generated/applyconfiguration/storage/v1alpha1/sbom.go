@@ -16,6 +16,7 @@ type SBOMApplyConfiguration struct {
 	*v1.ObjectMetaApplyConfiguration `json:"metadata,omitempty"`
 	ImageMetadata                    *ImageMetadataApplyConfiguration `json:"imageMetadata,omitempty"`
 	SPDX                             *runtime.RawExtension            `json:"spdx,omitempty"`
+	PackageCount                     *int                             `json:"packageCount,omitempty"`
 }
 
 // SBOM constructs a declarative configuration of the SBOM type for use with
@@ -204,6 +205,14 @@ func (b *SBOMApplyConfiguration) WithSPDX(value runtime.RawExtension) *SBOMApply
 	return b
 }
 
+// WithPackageCount sets the PackageCount field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the PackageCount field is set to the value of the last call.
+func (b *SBOMApplyConfiguration) WithPackageCount(value int) *SBOMApplyConfiguration {
+	b.PackageCount = &value
+	return b
+}
+
 // GetKind retrieves the value of the Kind field in the declarative configuration.
 func (b *SBOMApplyConfiguration) GetKind() *string {
 	return b.TypeMetaApplyConfiguration.Kind
@@ -2,12 +2,17 @@
 
 package v1alpha1
 
+import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
 // VEXStatusApplyConfiguration represents a declarative configuration of the VEXStatus type for use
 // with apply.
 type VEXStatusApplyConfiguration struct {
-	Repository *string `json:"repository,omitempty"`
-	Status     *string `json:"status,omitempty"`
-	Statement  *string `json:"statement,omitempty"`
+	Repository *string  `json:"repository,omitempty"`
+	Status     *string  `json:"status,omitempty"`
+	Statement  *string  `json:"statement,omitempty"`
+	ExpiresAt  *v1.Time `json:"expiresAt,omitempty"`
 }
 
 // VEXStatusApplyConfiguration constructs a declarative configuration of the VEXStatus type for use with
@@ -39,3 +44,11 @@ func (b *VEXStatusApplyConfiguration) WithStatement(value string) *VEXStatusAppl
 	b.Statement = &value
 	return b
 }
+
+// WithExpiresAt sets the ExpiresAt field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ExpiresAt field is set to the value of the last call.
+func (b *VEXStatusApplyConfiguration) WithExpiresAt(value v1.Time) *VEXStatusApplyConfiguration {
+	b.ExpiresAt = &value
+	return b
+}
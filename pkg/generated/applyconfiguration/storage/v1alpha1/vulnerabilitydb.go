@@ -0,0 +1,54 @@
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VulnerabilityDBApplyConfiguration represents a declarative configuration of the VulnerabilityDB type for use
+// with apply.
+type VulnerabilityDBApplyConfiguration struct {
+	Source        *string  `json:"source,omitempty"`
+	SchemaVersion *int     `json:"schemaVersion,omitempty"`
+	UpdatedAt     *v1.Time `json:"updatedAt,omitempty"`
+	DownloadedAt  *v1.Time `json:"downloadedAt,omitempty"`
+}
+
+// VulnerabilityDBApplyConfiguration constructs a declarative configuration of the VulnerabilityDB type for use with
+// apply.
+func VulnerabilityDB() *VulnerabilityDBApplyConfiguration {
+	return &VulnerabilityDBApplyConfiguration{}
+}
+
+// WithSource sets the Source field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Source field is set to the value of the last call.
+func (b *VulnerabilityDBApplyConfiguration) WithSource(value string) *VulnerabilityDBApplyConfiguration {
+	b.Source = &value
+	return b
+}
+
+// WithSchemaVersion sets the SchemaVersion field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the SchemaVersion field is set to the value of the last call.
+func (b *VulnerabilityDBApplyConfiguration) WithSchemaVersion(value int) *VulnerabilityDBApplyConfiguration {
+	b.SchemaVersion = &value
+	return b
+}
+
+// WithUpdatedAt sets the UpdatedAt field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the UpdatedAt field is set to the value of the last call.
+func (b *VulnerabilityDBApplyConfiguration) WithUpdatedAt(value v1.Time) *VulnerabilityDBApplyConfiguration {
+	b.UpdatedAt = &value
+	return b
+}
+
+// WithDownloadedAt sets the DownloadedAt field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the DownloadedAt field is set to the value of the last call.
+func (b *VulnerabilityDBApplyConfiguration) WithDownloadedAt(value v1.Time) *VulnerabilityDBApplyConfiguration {
+	b.DownloadedAt = &value
+	return b
+}
@@ -18,12 +18,18 @@ func ForKind(kind schema.GroupVersionKind) interface{} {
 	// Group=storage.sbomscanner.kubewarden.io, Version=v1alpha1
 	case v1alpha1.SchemeGroupVersion.WithKind("CVSS"):
 		return &storagev1alpha1.CVSSApplyConfiguration{}
+	case v1alpha1.SchemeGroupVersion.WithKind("Evaluation"):
+		return &storagev1alpha1.EvaluationApplyConfiguration{}
 	case v1alpha1.SchemeGroupVersion.WithKind("Image"):
 		return &storagev1alpha1.ImageApplyConfiguration{}
 	case v1alpha1.SchemeGroupVersion.WithKind("ImageLayer"):
 		return &storagev1alpha1.ImageLayerApplyConfiguration{}
 	case v1alpha1.SchemeGroupVersion.WithKind("ImageMetadata"):
 		return &storagev1alpha1.ImageMetadataApplyConfiguration{}
+	case v1alpha1.SchemeGroupVersion.WithKind("ImageScanError"):
+		return &storagev1alpha1.ImageScanErrorApplyConfiguration{}
+	case v1alpha1.SchemeGroupVersion.WithKind("ImageStatus"):
+		return &storagev1alpha1.ImageStatusApplyConfiguration{}
 	case v1alpha1.SchemeGroupVersion.WithKind("Report"):
 		return &storagev1alpha1.ReportApplyConfiguration{}
 	case v1alpha1.SchemeGroupVersion.WithKind("Result"):
@@ -36,6 +42,8 @@ func ForKind(kind schema.GroupVersionKind) interface{} {
 		return &storagev1alpha1.VEXStatusApplyConfiguration{}
 	case v1alpha1.SchemeGroupVersion.WithKind("Vulnerability"):
 		return &storagev1alpha1.VulnerabilityApplyConfiguration{}
+	case v1alpha1.SchemeGroupVersion.WithKind("VulnerabilityDB"):
+		return &storagev1alpha1.VulnerabilityDBApplyConfiguration{}
 	case v1alpha1.SchemeGroupVersion.WithKind("VulnerabilityReport"):
 		return &storagev1alpha1.VulnerabilityReportApplyConfiguration{}
 
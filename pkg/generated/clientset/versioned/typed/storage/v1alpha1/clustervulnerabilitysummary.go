@@ -0,0 +1,48 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	context "context"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	scheme "github.com/kubewarden/sbomscanner/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// ClusterVulnerabilitySummariesGetter has a method to return a ClusterVulnerabilitySummaryInterface.
+// A group's client should implement this interface.
+type ClusterVulnerabilitySummariesGetter interface {
+	ClusterVulnerabilitySummaries() ClusterVulnerabilitySummaryInterface
+}
+
+// ClusterVulnerabilitySummaryInterface has methods to work with ClusterVulnerabilitySummary resources.
+type ClusterVulnerabilitySummaryInterface interface {
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*storagev1alpha1.ClusterVulnerabilitySummary, error)
+	List(ctx context.Context, opts v1.ListOptions) (*storagev1alpha1.ClusterVulnerabilitySummaryList, error)
+	ClusterVulnerabilitySummaryExpansion
+}
+
+// clusterVulnerabilitySummaries implements ClusterVulnerabilitySummaryInterface
+type clusterVulnerabilitySummaries struct {
+	*gentype.ClientWithList[*storagev1alpha1.ClusterVulnerabilitySummary, *storagev1alpha1.ClusterVulnerabilitySummaryList]
+}
+
+// newClusterVulnerabilitySummaries returns a ClusterVulnerabilitySummaries
+func newClusterVulnerabilitySummaries(c *StorageV1alpha1Client) *clusterVulnerabilitySummaries {
+	return &clusterVulnerabilitySummaries{
+		gentype.NewClientWithList[*storagev1alpha1.ClusterVulnerabilitySummary, *storagev1alpha1.ClusterVulnerabilitySummaryList](
+			"clustervulnerabilitysummaries",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			"",
+			func() *storagev1alpha1.ClusterVulnerabilitySummary {
+				return &storagev1alpha1.ClusterVulnerabilitySummary{}
+			},
+			func() *storagev1alpha1.ClusterVulnerabilitySummaryList {
+				return &storagev1alpha1.ClusterVulnerabilitySummaryList{}
+			},
+		),
+	}
+}
@@ -0,0 +1,42 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	context "context"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	scheme "github.com/kubewarden/sbomscanner/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// CVEImpactsGetter has a method to return a CVEImpactInterface.
+// A group's client should implement this interface.
+type CVEImpactsGetter interface {
+	CVEImpacts() CVEImpactInterface
+}
+
+// CVEImpactInterface has methods to work with CVEImpact resources.
+type CVEImpactInterface interface {
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*storagev1alpha1.CVEImpact, error)
+	CVEImpactExpansion
+}
+
+// cVEImpacts implements CVEImpactInterface
+type cVEImpacts struct {
+	*gentype.Client[*storagev1alpha1.CVEImpact]
+}
+
+// newCVEImpacts returns a CVEImpacts
+func newCVEImpacts(c *StorageV1alpha1Client) *cVEImpacts {
+	return &cVEImpacts{
+		gentype.NewClient[*storagev1alpha1.CVEImpact](
+			"cveimpacts",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			"",
+			func() *storagev1alpha1.CVEImpact { return &storagev1alpha1.CVEImpact{} },
+		),
+	}
+}
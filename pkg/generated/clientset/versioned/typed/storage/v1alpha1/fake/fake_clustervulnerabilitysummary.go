@@ -0,0 +1,36 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/pkg/generated/clientset/versioned/typed/storage/v1alpha1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeClusterVulnerabilitySummaries implements ClusterVulnerabilitySummaryInterface
+type fakeClusterVulnerabilitySummaries struct {
+	*gentype.FakeClientWithList[*v1alpha1.ClusterVulnerabilitySummary, *v1alpha1.ClusterVulnerabilitySummaryList]
+	Fake *FakeStorageV1alpha1
+}
+
+func newFakeClusterVulnerabilitySummaries(fake *FakeStorageV1alpha1) storagev1alpha1.ClusterVulnerabilitySummaryInterface {
+	return &fakeClusterVulnerabilitySummaries{
+		gentype.NewFakeClientWithList[*v1alpha1.ClusterVulnerabilitySummary, *v1alpha1.ClusterVulnerabilitySummaryList](
+			fake.Fake,
+			"",
+			v1alpha1.SchemeGroupVersion.WithResource("clustervulnerabilitysummaries"),
+			v1alpha1.SchemeGroupVersion.WithKind("ClusterVulnerabilitySummary"),
+			func() *v1alpha1.ClusterVulnerabilitySummary { return &v1alpha1.ClusterVulnerabilitySummary{} },
+			func() *v1alpha1.ClusterVulnerabilitySummaryList { return &v1alpha1.ClusterVulnerabilitySummaryList{} },
+			func(dst, src *v1alpha1.ClusterVulnerabilitySummaryList) { dst.ListMeta = src.ListMeta },
+			func(list *v1alpha1.ClusterVulnerabilitySummaryList) []*v1alpha1.ClusterVulnerabilitySummary {
+				return gentype.ToPointerSlice(list.Items)
+			},
+			func(list *v1alpha1.ClusterVulnerabilitySummaryList, items []*v1alpha1.ClusterVulnerabilitySummary) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
@@ -0,0 +1,28 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/pkg/generated/clientset/versioned/typed/storage/v1alpha1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeCVEImpacts implements CVEImpactInterface
+type fakeCVEImpacts struct {
+	*gentype.FakeClient[*v1alpha1.CVEImpact]
+	Fake *FakeStorageV1alpha1
+}
+
+func newFakeCVEImpacts(fake *FakeStorageV1alpha1) storagev1alpha1.CVEImpactInterface {
+	return &fakeCVEImpacts{
+		gentype.NewFakeClient[*v1alpha1.CVEImpact](
+			fake.Fake,
+			"",
+			v1alpha1.SchemeGroupVersion.WithResource("cveimpacts"),
+			v1alpha1.SchemeGroupVersion.WithKind("CVEImpact"),
+			func() *v1alpha1.CVEImpact { return &v1alpha1.CVEImpact{} },
+		),
+		fake,
+	}
+}
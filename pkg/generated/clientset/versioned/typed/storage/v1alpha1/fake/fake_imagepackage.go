@@ -0,0 +1,36 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/pkg/generated/clientset/versioned/typed/storage/v1alpha1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeImagePackages implements ImagePackageInterface
+type fakeImagePackages struct {
+	*gentype.FakeClientWithList[*v1alpha1.ImagePackage, *v1alpha1.ImagePackageList]
+	Fake *FakeStorageV1alpha1
+}
+
+func newFakeImagePackages(fake *FakeStorageV1alpha1, namespace string) storagev1alpha1.ImagePackageInterface {
+	return &fakeImagePackages{
+		gentype.NewFakeClientWithList[*v1alpha1.ImagePackage, *v1alpha1.ImagePackageList](
+			fake.Fake,
+			namespace,
+			v1alpha1.SchemeGroupVersion.WithResource("imagepackages"),
+			v1alpha1.SchemeGroupVersion.WithKind("ImagePackage"),
+			func() *v1alpha1.ImagePackage { return &v1alpha1.ImagePackage{} },
+			func() *v1alpha1.ImagePackageList { return &v1alpha1.ImagePackageList{} },
+			func(dst, src *v1alpha1.ImagePackageList) { dst.ListMeta = src.ListMeta },
+			func(list *v1alpha1.ImagePackageList) []*v1alpha1.ImagePackage {
+				return gentype.ToPointerSlice(list.Items)
+			},
+			func(list *v1alpha1.ImagePackageList, items []*v1alpha1.ImagePackage) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
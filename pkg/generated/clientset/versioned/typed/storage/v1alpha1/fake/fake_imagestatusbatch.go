@@ -0,0 +1,28 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/pkg/generated/clientset/versioned/typed/storage/v1alpha1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeImageStatusBatches implements ImageStatusBatchInterface
+type fakeImageStatusBatches struct {
+	*gentype.FakeClient[*v1alpha1.ImageStatusBatch]
+	Fake *FakeStorageV1alpha1
+}
+
+func newFakeImageStatusBatches(fake *FakeStorageV1alpha1, namespace string) storagev1alpha1.ImageStatusBatchInterface {
+	return &fakeImageStatusBatches{
+		gentype.NewFakeClient[*v1alpha1.ImageStatusBatch](
+			fake.Fake,
+			namespace,
+			v1alpha1.SchemeGroupVersion.WithResource("imagestatusbatches"),
+			v1alpha1.SchemeGroupVersion.WithKind("ImageStatusBatch"),
+			func() *v1alpha1.ImageStatusBatch { return &v1alpha1.ImageStatusBatch{} },
+		),
+		fake,
+	}
+}
@@ -0,0 +1,36 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/pkg/generated/clientset/versioned/typed/storage/v1alpha1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeRegistryImageCounts implements RegistryImageCountInterface
+type fakeRegistryImageCounts struct {
+	*gentype.FakeClientWithList[*v1alpha1.RegistryImageCount, *v1alpha1.RegistryImageCountList]
+	Fake *FakeStorageV1alpha1
+}
+
+func newFakeRegistryImageCounts(fake *FakeStorageV1alpha1, namespace string) storagev1alpha1.RegistryImageCountInterface {
+	return &fakeRegistryImageCounts{
+		gentype.NewFakeClientWithList[*v1alpha1.RegistryImageCount, *v1alpha1.RegistryImageCountList](
+			fake.Fake,
+			namespace,
+			v1alpha1.SchemeGroupVersion.WithResource("registryimagecounts"),
+			v1alpha1.SchemeGroupVersion.WithKind("RegistryImageCount"),
+			func() *v1alpha1.RegistryImageCount { return &v1alpha1.RegistryImageCount{} },
+			func() *v1alpha1.RegistryImageCountList { return &v1alpha1.RegistryImageCountList{} },
+			func(dst, src *v1alpha1.RegistryImageCountList) { dst.ListMeta = src.ListMeta },
+			func(list *v1alpha1.RegistryImageCountList) []*v1alpha1.RegistryImageCount {
+				return gentype.ToPointerSlice(list.Items)
+			},
+			func(list *v1alpha1.RegistryImageCountList, items []*v1alpha1.RegistryImageCount) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
@@ -12,10 +12,30 @@ type FakeStorageV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeStorageV1alpha1) CVEImpacts() v1alpha1.CVEImpactInterface {
+	return newFakeCVEImpacts(c)
+}
+
+func (c *FakeStorageV1alpha1) ClusterVulnerabilitySummaries() v1alpha1.ClusterVulnerabilitySummaryInterface {
+	return newFakeClusterVulnerabilitySummaries(c)
+}
+
 func (c *FakeStorageV1alpha1) Images(namespace string) v1alpha1.ImageInterface {
 	return newFakeImages(c, namespace)
 }
 
+func (c *FakeStorageV1alpha1) ImagePackages(namespace string) v1alpha1.ImagePackageInterface {
+	return newFakeImagePackages(c, namespace)
+}
+
+func (c *FakeStorageV1alpha1) ImageStatusBatches(namespace string) v1alpha1.ImageStatusBatchInterface {
+	return newFakeImageStatusBatches(c, namespace)
+}
+
+func (c *FakeStorageV1alpha1) RegistryImageCounts(namespace string) v1alpha1.RegistryImageCountInterface {
+	return newFakeRegistryImageCounts(c, namespace)
+}
+
 func (c *FakeStorageV1alpha1) SBOMs(namespace string) v1alpha1.SBOMInterface {
 	return newFakeSBOMs(c, namespace)
 }
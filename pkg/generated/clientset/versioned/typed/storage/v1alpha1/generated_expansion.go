@@ -2,8 +2,18 @@
 
 package v1alpha1
 
+type CVEImpactExpansion interface{}
+
+type ClusterVulnerabilitySummaryExpansion interface{}
+
 type ImageExpansion interface{}
 
+type ImagePackageExpansion interface{}
+
+type ImageStatusBatchExpansion interface{}
+
+type RegistryImageCountExpansion interface{}
+
 type SBOMExpansion interface{}
 
 type VulnerabilityReportExpansion interface{}
@@ -0,0 +1,43 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	context "context"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	scheme "github.com/kubewarden/sbomscanner/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// ImagePackagesGetter has a method to return a ImagePackageInterface.
+// A group's client should implement this interface.
+type ImagePackagesGetter interface {
+	ImagePackages(namespace string) ImagePackageInterface
+}
+
+// ImagePackageInterface has methods to work with ImagePackage resources.
+type ImagePackageInterface interface {
+	List(ctx context.Context, opts v1.ListOptions) (*storagev1alpha1.ImagePackageList, error)
+	ImagePackageExpansion
+}
+
+// imagePackages implements ImagePackageInterface
+type imagePackages struct {
+	*gentype.ClientWithList[*storagev1alpha1.ImagePackage, *storagev1alpha1.ImagePackageList]
+}
+
+// newImagePackages returns a ImagePackages
+func newImagePackages(c *StorageV1alpha1Client, namespace string) *imagePackages {
+	return &imagePackages{
+		gentype.NewClientWithList[*storagev1alpha1.ImagePackage, *storagev1alpha1.ImagePackageList](
+			"imagepackages",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			namespace,
+			func() *storagev1alpha1.ImagePackage { return &storagev1alpha1.ImagePackage{} },
+			func() *storagev1alpha1.ImagePackageList { return &storagev1alpha1.ImagePackageList{} },
+		),
+	}
+}
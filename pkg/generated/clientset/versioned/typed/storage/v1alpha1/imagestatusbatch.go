@@ -0,0 +1,42 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	context "context"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	scheme "github.com/kubewarden/sbomscanner/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// ImageStatusBatchesGetter has a method to return a ImageStatusBatchInterface.
+// A group's client should implement this interface.
+type ImageStatusBatchesGetter interface {
+	ImageStatusBatches(namespace string) ImageStatusBatchInterface
+}
+
+// ImageStatusBatchInterface has methods to work with ImageStatusBatch resources.
+type ImageStatusBatchInterface interface {
+	Create(ctx context.Context, imageStatusBatch *storagev1alpha1.ImageStatusBatch, opts v1.CreateOptions) (*storagev1alpha1.ImageStatusBatch, error)
+	ImageStatusBatchExpansion
+}
+
+// imageStatusBatches implements ImageStatusBatchInterface
+type imageStatusBatches struct {
+	*gentype.Client[*storagev1alpha1.ImageStatusBatch]
+}
+
+// newImageStatusBatches returns a ImageStatusBatches
+func newImageStatusBatches(c *StorageV1alpha1Client, namespace string) *imageStatusBatches {
+	return &imageStatusBatches{
+		gentype.NewClient[*storagev1alpha1.ImageStatusBatch](
+			"imagestatusbatches",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			namespace,
+			func() *storagev1alpha1.ImageStatusBatch { return &storagev1alpha1.ImageStatusBatch{} },
+		),
+	}
+}
@@ -0,0 +1,44 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	context "context"
+
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	scheme "github.com/kubewarden/sbomscanner/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// RegistryImageCountsGetter has a method to return a RegistryImageCountInterface.
+// A group's client should implement this interface.
+type RegistryImageCountsGetter interface {
+	RegistryImageCounts(namespace string) RegistryImageCountInterface
+}
+
+// RegistryImageCountInterface has methods to work with RegistryImageCount resources.
+type RegistryImageCountInterface interface {
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*storagev1alpha1.RegistryImageCount, error)
+	List(ctx context.Context, opts v1.ListOptions) (*storagev1alpha1.RegistryImageCountList, error)
+	RegistryImageCountExpansion
+}
+
+// registryImageCounts implements RegistryImageCountInterface
+type registryImageCounts struct {
+	*gentype.ClientWithList[*storagev1alpha1.RegistryImageCount, *storagev1alpha1.RegistryImageCountList]
+}
+
+// newRegistryImageCounts returns a RegistryImageCounts
+func newRegistryImageCounts(c *StorageV1alpha1Client, namespace string) *registryImageCounts {
+	return &registryImageCounts{
+		gentype.NewClientWithList[*storagev1alpha1.RegistryImageCount, *storagev1alpha1.RegistryImageCountList](
+			"registryimagecounts",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			namespace,
+			func() *storagev1alpha1.RegistryImageCount { return &storagev1alpha1.RegistryImageCount{} },
+			func() *storagev1alpha1.RegistryImageCountList { return &storagev1alpha1.RegistryImageCountList{} },
+		),
+	}
+}
@@ -12,7 +12,12 @@ import (
 
 type StorageV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	CVEImpactsGetter
+	ClusterVulnerabilitySummariesGetter
 	ImagesGetter
+	ImagePackagesGetter
+	ImageStatusBatchesGetter
+	RegistryImageCountsGetter
 	SBOMsGetter
 	VulnerabilityReportsGetter
 }
@@ -22,10 +27,30 @@ type StorageV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *StorageV1alpha1Client) CVEImpacts() CVEImpactInterface {
+	return newCVEImpacts(c)
+}
+
+func (c *StorageV1alpha1Client) ClusterVulnerabilitySummaries() ClusterVulnerabilitySummaryInterface {
+	return newClusterVulnerabilitySummaries(c)
+}
+
 func (c *StorageV1alpha1Client) Images(namespace string) ImageInterface {
 	return newImages(c, namespace)
 }
 
+func (c *StorageV1alpha1Client) ImagePackages(namespace string) ImagePackageInterface {
+	return newImagePackages(c, namespace)
+}
+
+func (c *StorageV1alpha1Client) ImageStatusBatches(namespace string) ImageStatusBatchInterface {
+	return newImageStatusBatches(c, namespace)
+}
+
+func (c *StorageV1alpha1Client) RegistryImageCounts(namespace string) RegistryImageCountInterface {
+	return newRegistryImageCounts(c, namespace)
+}
+
 func (c *StorageV1alpha1Client) SBOMs(namespace string) SBOMInterface {
 	return newSBOMs(c, namespace)
 }
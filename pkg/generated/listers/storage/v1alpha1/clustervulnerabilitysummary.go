@@ -0,0 +1,32 @@
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	storagev1alpha1 "github.com/kubewarden/sbomscanner/api/storage/v1alpha1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	listers "k8s.io/client-go/listers"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// ClusterVulnerabilitySummaryLister helps list ClusterVulnerabilitySummaries.
+// All objects returned here must be treated as read-only.
+type ClusterVulnerabilitySummaryLister interface {
+	// List lists all ClusterVulnerabilitySummaries in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*storagev1alpha1.ClusterVulnerabilitySummary, err error)
+	// Get retrieves the ClusterVulnerabilitySummary from the index for a given name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*storagev1alpha1.ClusterVulnerabilitySummary, error)
+	ClusterVulnerabilitySummaryListerExpansion
+}
+
+// clusterVulnerabilitySummaryLister implements the ClusterVulnerabilitySummaryLister interface.
+type clusterVulnerabilitySummaryLister struct {
+	listers.ResourceIndexer[*storagev1alpha1.ClusterVulnerabilitySummary]
+}
+
+// NewClusterVulnerabilitySummaryLister returns a new ClusterVulnerabilitySummaryLister.
+func NewClusterVulnerabilitySummaryLister(indexer cache.Indexer) ClusterVulnerabilitySummaryLister {
+	return &clusterVulnerabilitySummaryLister{listers.New[*storagev1alpha1.ClusterVulnerabilitySummary](indexer, storagev1alpha1.Resource("clustervulnerabilitysummary"))}
+}
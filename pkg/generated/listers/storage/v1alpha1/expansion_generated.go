@@ -2,6 +2,10 @@
 
 package v1alpha1
 
+// ClusterVulnerabilitySummaryListerExpansion allows custom methods to be added to
+// ClusterVulnerabilitySummaryLister.
+type ClusterVulnerabilitySummaryListerExpansion interface{}
+
 // ImageListerExpansion allows custom methods to be added to
 // ImageLister.
 type ImageListerExpansion interface{}